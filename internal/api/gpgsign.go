@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 
 	"github.com/nikicat/secrets-dispatcher/internal/approval"
@@ -51,6 +53,61 @@ func (d *defaultGPGRunner) RunGPG(gpgPath, keyID string, commitObject []byte) ([
 	return sigBuf.Bytes(), statusBuf.Bytes(), exitCode, nil
 }
 
+// SSHRunner finds and executes the real ssh-keygen, for requests with
+// approval.SSHFormat.
+type SSHRunner interface {
+	FindSSHKeygen() (string, error)
+	RunSSHKeygen(path string, key []byte, useAgent bool, payload []byte) (signature, status []byte, exitCode int, err error)
+}
+
+// defaultSSHRunner implements SSHRunner using the real ssh-keygen from PATH.
+// agentSocket, when set, replaces SSH_AUTH_SOCK: with the SSH agent proxy
+// enabled it is the upstream agent, so a signature the user just approved is
+// not prompted for a second time as an ssh_sign request.
+type defaultSSHRunner struct {
+	agentSocket string
+}
+
+// FindSSHKeygen delegates to gpgsign.FindRealSSHKeygen.
+func (d *defaultSSHRunner) FindSSHKeygen() (string, error) {
+	return gpgsign.FindRealSSHKeygen()
+}
+
+// RunSSHKeygen invokes `ssh-keygen -Y sign -n git -f <copy of key> [-U]`,
+// feeding payload to stdin. It writes the signature to stdout and its
+// messages to stderr.
+func (d *defaultSSHRunner) RunSSHKeygen(path string, key []byte, useAgent bool, payload []byte) ([]byte, []byte, int, error) {
+	if len(key) == 0 {
+		return nil, nil, 1, errors.New("the signing key file could not be read when the request was made")
+	}
+	var sigBuf, statusBuf bytes.Buffer
+	exitCode := 0
+	err := gpgsign.WithSSHKeyCopy(key, func(keyFile string) error {
+		args := []string{"-Y", "sign", "-n", "git", "-f", keyFile}
+		if useAgent {
+			args = append(args, "-U")
+		}
+		cmd := exec.Command(path, args...)
+		if d.agentSocket != "" {
+			cmd.Env = append(os.Environ(), "SSH_AUTH_SOCK="+d.agentSocket)
+		}
+		cmd.Stdin = bytes.NewReader(payload)
+		cmd.Stdout = &sigBuf
+		cmd.Stderr = &statusBuf
+		err := cmd.Run()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode()
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, statusBuf.Bytes(), 1, fmt.Errorf("ssh-keygen exec failed: %w", err)
+	}
+	return sigBuf.Bytes(), statusBuf.Bytes(), exitCode, nil
+}
+
 // GPGSignRequest is the POST body for /api/v1/gpg-sign/request.
 type GPGSignRequest struct {
 	Client      string                `json:"client"`
@@ -85,6 +142,20 @@ func (h *Handlers) HandleGPGSignRequest(w http.ResponseWriter, r *http.Request)
 		writeError(w, "gpg_sign_info.commit_object is required", http.StatusBadRequest)
 		return
 	}
+	switch req.GPGSignInfo.Format {
+	case "":
+	case approval.SSHFormat:
+		// The key file is handed to ssh-keygen's -f: only an absolute path
+		// can neither be taken for an option nor resolve against the
+		// daemon's own working directory.
+		if !filepath.IsAbs(req.GPGSignInfo.KeyID) {
+			writeError(w, "gpg_sign_info.key_id must be an absolute key file path for ssh signing", http.StatusBadRequest)
+			return
+		}
+	default:
+		writeError(w, "unknown gpg_sign_info.format", http.StatusBadRequest)
+		return
+	}
 	if req.Client == "" {
 		req.Client = "unknown"
 	}
@@ -98,24 +169,79 @@ func (h *Handlers) HandleGPGSignRequest(w http.ResponseWriter, r *http.Request)
 	// Every downstream path reads these fields off GPGSignInfo, so binding once
 	// here covers them all.
 	bindDisplayToSignedPayload(req.GPGSignInfo, senderInfo)
+	bindSSHKeyFingerprint(req.GPGSignInfo, senderInfo)
+	verifyAgainstRepo(req.GPGSignInfo, senderInfo)
+	bindSeries(req.GPGSignInfo)
 
 	commitSubject := req.GPGSignInfo.CommitMsg
 	if i := strings.IndexByte(commitSubject, '\n'); i >= 0 {
 		commitSubject = commitSubject[:i]
 	}
 
+	// Temporary rules. A deny rule (e.g. "Mute" on an earlier notification)
+	// refuses before any rule or trusted signer can approve.
+	autoRule := h.manager.CheckAutoApproveRules(senderInfo, nil, approval.RequestTypeGPGSign)
+	if autoRule != nil && autoRule.Action == "deny" {
+		id, err := h.manager.RecordDeniedGPGSign(req.Client, req.GPGSignInfo, senderInfo, approval.TemporaryDenyRule)
+		if err != nil {
+			writeError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		slog.Info("gpg sign denied by temporary deny rule",
+			"request_id", id,
+			"rule_id", autoRule.ID,
+			"repo", req.GPGSignInfo.RepoName,
+			"process", senderInfo.InvokerName,
+			"pid", senderInfo.PID,
+			"commit", commitSubject,
+		)
+		writeJSON(w, GPGSignResponse{RequestID: id})
+		return
+	}
+
+	// Trust rules: the first rule that opts in to gpg_sign and matches decides,
+	// allow or deny, without a notification.
+	if rule := h.manager.CheckSigningRules(senderInfo, req.GPGSignInfo); rule != nil {
+		if rule.Action == "deny" {
+			id, err := h.manager.RecordDeniedGPGSign(req.Client, req.GPGSignInfo, senderInfo, rule.Label())
+			if err != nil {
+				writeError(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			slog.Info("gpg sign denied by trust rule",
+				"request_id", id,
+				"rule_name", rule.Label(),
+				"repo", req.GPGSignInfo.RepoName,
+				"process", senderInfo.InvokerName,
+				"pid", senderInfo.PID,
+				"commit", commitSubject,
+			)
+			writeJSON(w, GPGSignResponse{RequestID: id})
+			return
+		}
+		h.signAndRecordAutoApproved(w, &req, senderInfo, commitSubject, "trust rule "+rule.Label(), rule.Label())
+		return
+	}
+
 	// Trusted signer: run gpg and record the result directly, bypassing the
 	// pending request flow so no desktop notification appears.
-	if h.manager.CheckTrustedSigner(senderInfo, req.GPGSignInfo.RepoName, req.GPGSignInfo.ChangedFiles) {
-		h.signAndRecordAutoApproved(w, &req, senderInfo, commitSubject, "trusted signer")
+	if !req.GPGSignInfo.Unverified() && h.manager.CheckTrustedSigner(senderInfo, req.GPGSignInfo.RepoName, req.GPGSignInfo.ChangedFiles) {
+		h.signAndRecordAutoApproved(w, &req, senderInfo, commitSubject, "trusted signer", "")
 		return
 	}
 
 	// Ephemeral auto-approve rule (created by "approve and auto-approve" on a
 	// prior notification). Same effect as trusted signer for the rule's TTL.
-	if rule := h.manager.CheckAutoApproveRules(senderInfo, nil, approval.RequestTypeGPGSign); rule != nil {
+	if autoRule != nil && !req.GPGSignInfo.Unverified() {
 		h.signAndRecordAutoApproved(w, &req, senderInfo, commitSubject,
-			fmt.Sprintf("auto-approve rule %s", rule.ID))
+			fmt.Sprintf("auto-approve rule %s", autoRule.ID), "")
+		return
+	}
+
+	// The rest of a rebase, cherry-pick or am the user approved as a series.
+	if h.manager.UseSeriesGrant(senderInfo, req.GPGSignInfo) {
+		h.signAndRecordAutoApproved(w, &req, senderInfo, commitSubject,
+			fmt.Sprintf("approved %s series", req.GPGSignInfo.Series.Operation), "")
 		return
 	}
 
@@ -136,6 +262,55 @@ func (h *Handlers) HandleGPGSignRequest(w http.ResponseWriter, r *http.Request)
 	writeJSON(w, GPGSignResponse{RequestID: id})
 }
 
+// gpgSignSummary returns info without the fields that run to hundreds of
+// kilobytes, the raw object and the patch, for request listings and WebSocket
+// messages: with them, every snapshot would repeat them for the whole history
+// and soon outgrow the thin client's read limit. The patch is served on its
+// own by HandleRequestPatch.
+func gpgSignSummary(info *approval.GPGSignInfo) *approval.GPGSignInfo {
+	if info == nil {
+		return nil
+	}
+	summary := *info
+	summary.CommitObject = ""
+	if info.Diff != nil {
+		diff := *info.Diff
+		diff.Patch = ""
+		summary.Diff = &diff
+	}
+	return &summary
+}
+
+// HandleRequestPatch handles GET /api/v1/requests/{id}/patch: the patch of a
+// pending or resolved gpg_sign request, left out of listings (see
+// gpgSignSummary). It is empty when the client sent none.
+func (h *Handlers) HandleRequestPatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := extractRequestID(r.URL.Path, "/api/v1/requests/", "/patch")
+	if id == "" {
+		writeError(w, "invalid request ID", http.StatusBadRequest)
+		return
+	}
+	req, err := h.manager.LookupRequest(id)
+	if err != nil {
+		if errors.Is(err, approval.ErrNotFound) {
+			writeError(w, "request not found", http.StatusNotFound)
+			return
+		}
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var resp PatchResponse
+	if info := req.GPGSignInfo; info != nil && info.Diff != nil {
+		resp.Patch = info.Diff.Patch
+	}
+	writeJSON(w, resp)
+}
+
 // bindDisplayToSignedPayload re-derives the human-visible metadata (kind,
 // author/tagger/pusher, committer, message, parent, tag name, target, pushee)
 // from the raw CommitObject bytes that will actually be fed to gpg, overwriting
@@ -146,9 +321,9 @@ func (h *Handlers) HandleGPGSignRequest(w http.ResponseWriter, r *http.Request)
 // means the caller tried to display metadata that does not match the signed
 // payload, which we log and then override.
 //
-// RepoName and ChangedFiles are NOT derivable from the signed bytes (they
-// describe the working tree, not the payload) and are deliberately left
-// untouched here; see CheckTrustedSigner for their (necessarily weaker) treatment.
+// RepoName and ChangedFiles are NOT derivable from the signed bytes alone
+// (they need the repository) and are left untouched here; verifyAgainstRepo
+// checks them against the caller's repository.
 func bindDisplayToSignedPayload(info *approval.GPGSignInfo, senderInfo approval.SenderInfo) {
 	p := gpgsign.ParseSignedPayload([]byte(info.CommitObject))
 	if p.Signer != info.Author || p.Committer != info.Committer ||
@@ -168,12 +343,75 @@ func bindDisplayToSignedPayload(info *approval.GPGSignInfo, senderInfo approval.
 	info.Pushee = p.Pushee
 }
 
+// bindSSHKeyFingerprint reads the SSH signing key file once, keeping its
+// content in SSHKey for ssh-keygen to sign with, and recomputes the
+// fingerprint from that content, overwriting the client's, so the prompt and
+// signing.key_id rules see the key actually used: the file itself may be
+// replaced before the request is approved. Both are cleared when the file
+// cannot be read, and signing then fails.
+func bindSSHKeyFingerprint(info *approval.GPGSignInfo, senderInfo approval.SenderInfo) {
+	if info.Format != approval.SSHFormat {
+		return
+	}
+	key, err := gpgsign.ReadSSHKey(info.KeyID)
+	var fpr string
+	if err == nil {
+		fpr, err = gpgsign.SSHKeyDataFingerprint(key)
+	}
+	if err != nil {
+		slog.Warn("ssh sign: cannot fingerprint signing key", "key", info.KeyID, "error", err)
+		key = nil
+	} else if info.Fingerprint != "" && info.Fingerprint != fpr {
+		slog.Warn("ssh sign: client-supplied key fingerprint does not match the key file; overriding",
+			"pid", senderInfo.PID,
+			"process", senderInfo.InvokerName,
+			"key", info.KeyID,
+		)
+	}
+	info.SSHKey, info.Fingerprint = key, fpr
+}
+
+// verifyAgainstRepo checks the request against the repository the caller runs
+// in (gpgsign.VerifyRequest), which is the working directory of the first
+// process in its chain — the gpg-sign helper, started by git in the worktree.
+// A request that fails is still prompted, with the problems shown, but never
+// auto-approved by a trust rule, trusted signer or auto-approve rule.
+func verifyAgainstRepo(info *approval.GPGSignInfo, senderInfo approval.SenderInfo) {
+	var dir string
+	for _, p := range senderInfo.ProcessChain {
+		if p.CWD != "" {
+			dir = p.CWD
+			break
+		}
+	}
+	gpgsign.VerifyRequest(dir, gpgsign.ParseSignedPayload([]byte(info.CommitObject)), info)
+	if info.Unverified() {
+		slog.Warn("gpg sign: request does not match the caller's repository",
+			"pid", senderInfo.PID,
+			"process", senderInfo.InvokerName,
+			"repo", info.RepoName,
+			"problems", info.Verification.Problems,
+		)
+	}
+}
+
+// bindSeries records the rebase, cherry-pick, revert or am a commit belongs
+// to, read from the caller's repository as verifyAgainstRepo found it. Any
+// series the client sent is discarded: series approvals are granted on it.
+func bindSeries(info *approval.GPGSignInfo) {
+	info.Series = nil
+	if info.Kind != string(gpgsign.KindCommit) || info.Verification == nil || !info.Verification.Verified {
+		return
+	}
+	info.Series = gpgsign.DetectSeries(info.Verification.Repo)
+}
+
 // signAndRecordAutoApproved runs gpg and records an auto-approved gpg_sign
-// request. Shared by the trusted-signer and ephemeral-auto-approve-rule paths;
-// both want the same outcome — sign without showing a notification — and differ
-// only in the log line.
-func (h *Handlers) signAndRecordAutoApproved(w http.ResponseWriter, req *GPGSignRequest, senderInfo approval.SenderInfo, commitSubject, reason string) {
-	gpgPath, findErr := h.resolver.GPGRunner.FindGPG()
+// request. Shared by the trust-rule, trusted-signer and ephemeral-auto-approve-rule
+// paths; all want the same outcome — sign without showing a notification — and
+// differ only in the log line and, for trust rules, the rule recorded in history.
+func (h *Handlers) signAndRecordAutoApproved(w http.ResponseWriter, req *GPGSignRequest, senderInfo approval.SenderInfo, commitSubject, reason, ruleName string) {
+	gpgPath, findErr := h.resolver.findSigner(req.GPGSignInfo)
 	if findErr != nil {
 		writeError(w, fmt.Sprintf("gpg exec failed: %v", findErr), http.StatusInternalServerError)
 		return
@@ -187,7 +425,7 @@ func (h *Handlers) signAndRecordAutoApproved(w http.ResponseWriter, req *GPGSign
 		slog.Error("auto-approved gpg failed", "reason", reason, "exit_code", res.exitCode)
 	}
 
-	id, err := h.manager.RecordAutoApprovedGPGSign(req.Client, req.GPGSignInfo, senderInfo, res.sig, res.status)
+	id, err := h.manager.RecordAutoApprovedGPGSign(req.Client, req.GPGSignInfo, senderInfo, res.sig, res.status, ruleName)
 	if err != nil {
 		writeError(w, err.Error(), http.StatusInternalServerError)
 		return
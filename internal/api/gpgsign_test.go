@@ -1,15 +1,21 @@
 package api
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/coder/websocket"
 	"github.com/nikicat/secrets-dispatcher/internal/approval"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -445,6 +451,42 @@ func TestHandleGPGSignRequest_NoMatchingRuleStaysPending(t *testing.T) {
 	}
 }
 
+// TestHandleGPGSignRequest_DenyRule verifies that a matching deny rule refuses
+// the signature without a prompt: the response still carries a request ID (the
+// thin client learns the outcome over the WebSocket) but nothing is pending and
+// the denial is in history. The rule matches on the author parsed from the
+// signed bytes, not the one the client claimed.
+func TestHandleGPGSignRequest_DenyRule(t *testing.T) {
+	mgr := approval.NewManager(approval.ManagerConfig{
+		Timeout:    5 * time.Second,
+		HistoryMax: 100,
+		TrustRules: []approval.TrustRule{{
+			Name:    "no-A",
+			Action:  "deny",
+			Signing: &approval.SigningMatcher{Kind: "commit", Author: "A"},
+		}},
+	})
+	handlers := testHandlers(t, mgr)
+	handlers.resolver.GPGRunner = &fakeGPGRunner{sig: []byte("FAKE_SIG")}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/gpg-sign/request",
+		strings.NewReader(strings.Replace(validGPGSignBody, `"author":        "A"`, `"author": "B"`, 1)))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	handlers.HandleGPGSignRequest(rr, req)
+
+	require.Equalf(t, http.StatusOK, rr.Code, "body: %s", rr.Body.String())
+	var resp GPGSignResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	assert.NotEmpty(t, resp.RequestID)
+	assert.Empty(t, mgr.List(), "denied request must not be pending")
+
+	require.Eventually(t, func() bool { return len(mgr.History()) == 1 }, time.Second, 10*time.Millisecond)
+	entry := mgr.History()[0]
+	assert.Equal(t, resp.RequestID, entry.Request.ID)
+	assert.Equal(t, approval.ResolutionDenied, entry.Resolution)
+}
+
 // TestHandleGPGSignRequest_WSSignatureOnApproval verifies Case 10:
 // When a gpg_sign request is approved, the resulting WSMessage has a non-empty
 // Signature field.
@@ -498,3 +540,233 @@ func TestHandleGPGSignRequest_WSSignatureOnApproval(t *testing.T) {
 		t.Error("decoded Signature is empty")
 	}
 }
+
+// fakeSSHRunner is a stub SSHRunner that records what it was asked to sign.
+type fakeSSHRunner struct {
+	key      []byte
+	useAgent bool
+	payload  []byte
+}
+
+func (f *fakeSSHRunner) FindSSHKeygen() (string, error) { return "/fake/ssh-keygen", nil }
+func (f *fakeSSHRunner) RunSSHKeygen(_ /* path */ string, key []byte, useAgent bool, payload []byte) ([]byte, []byte, int, error) {
+	f.key, f.useAgent, f.payload = key, useAgent, payload
+	return []byte("SSH_SIG"), nil, 0, nil
+}
+
+// TestResolverApprove_SSHFormat verifies that approving an SSH-format request
+// signs with ssh-keygen, never gpg, using the key and agent flag the request
+// carries.
+func TestResolverApprove_SSHFormat(t *testing.T) {
+	mgr := approval.NewManager(approval.ManagerConfig{Timeout: 5 * time.Second, HistoryMax: 100})
+	resolver := NewResolver(mgr, nil, 0)
+	gpg := &fakeGPGRunner{sig: []byte("GPG_SIG")}
+	ssh := &fakeSSHRunner{}
+	resolver.GPGRunner, resolver.SSHRunner = gpg, ssh
+
+	info := &approval.GPGSignInfo{
+		RepoName:     "myrepo",
+		Kind:         "commit",
+		Format:       approval.SSHFormat,
+		KeyID:        "/home/user/.ssh/id_ed25519.pub",
+		UseAgent:     true,
+		CommitObject: "tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\nauthor A\ncommitter A\n\nfix: thing\n",
+		SSHKey:       []byte("ssh-ed25519 AAAA test\n"),
+	}
+	id, err := mgr.CreateGPGSignRequest("test-client", info, approval.SenderInfo{})
+	require.NoError(t, err)
+	require.NoError(t, resolver.Approve(id))
+
+	assert.Equal(t, "ssh-ed25519 AAAA test\n", string(ssh.key))
+	assert.True(t, ssh.useAgent)
+	assert.Equal(t, info.CommitObject, string(ssh.payload))
+	require.Eventually(t, func() bool { return len(mgr.History()) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "SSH_SIG", string(mgr.History()[0].Request.Signature))
+}
+
+// TestHandleGPGSignRequest_SSHKeySwapped verifies that an SSH-format request
+// is signed with the key file as it was when the request arrived, the one
+// whose fingerprint was shown, even if the file is replaced before approval.
+func TestHandleGPGSignRequest_SSHKeySwapped(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not installed")
+	}
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "id_ed25519.pub")
+	for _, name := range []string{"a", "b"} {
+		require.NoError(t, exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-C", name, "-f", filepath.Join(dir, name)).Run())
+	}
+	keyA, err := os.ReadFile(filepath.Join(dir, "a.pub"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(keyFile, keyA, 0o644))
+	out, err := exec.Command("ssh-keygen", "-l", "-E", "sha256", "-f", keyFile).Output()
+	require.NoError(t, err)
+	fprA := strings.Fields(string(out))[1]
+
+	mgr := approval.NewManager(approval.ManagerConfig{Timeout: 5 * time.Second, HistoryMax: 100})
+	handlers := testHandlers(t, mgr)
+	ssh := &fakeSSHRunner{}
+	handlers.resolver.SSHRunner = ssh
+
+	body := strings.Replace(validGPGSignBody, `"key_id":        "ABCD1234"`,
+		`"format": "ssh", "use_agent": true, "key_id": "`+keyFile+`"`, 1)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/gpg-sign/request", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	handlers.HandleGPGSignRequest(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var resp GPGSignResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	pending := mgr.GetPending(resp.RequestID)
+	require.NotNil(t, pending)
+	assert.Equal(t, fprA, pending.GPGSignInfo.Fingerprint)
+
+	// The caller swaps in another key while the request waits.
+	require.NoError(t, os.Rename(filepath.Join(dir, "b.pub"), keyFile))
+	require.NoError(t, handlers.resolver.Approve(resp.RequestID))
+	assert.Equal(t, string(keyA), string(ssh.key), "signed with the key file as read at request time")
+
+	marshaled, err := json.Marshal(pending.GPGSignInfo)
+	require.NoError(t, err)
+	assert.NotContains(t, string(marshaled), strings.Fields(string(keyA))[1], "the key content is never serialized")
+}
+
+// TestHandleGPGSignRequest_SSHRelativeKeyFile verifies that an SSH-format
+// request must name its key by absolute path, so it can neither pass for an
+// ssh-keygen option nor resolve against the daemon's working directory.
+func TestHandleGPGSignRequest_SSHRelativeKeyFile(t *testing.T) {
+	mgr := approval.NewManager(approval.ManagerConfig{Timeout: 5 * time.Second, HistoryMax: 100})
+	handlers := testHandlers(t, mgr)
+
+	for _, key := range []string{"-Ohashalg=sha1", "id_ed25519"} {
+		body := strings.Replace(validGPGSignBody, `"key_id":        "ABCD1234"`,
+			`"format": "ssh", "key_id": "`+key+`"`, 1)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/gpg-sign/request", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		handlers.HandleGPGSignRequest(rr, req)
+
+		assert.Equalf(t, http.StatusBadRequest, rr.Code, "key %q: body: %s", key, rr.Body.String())
+	}
+	assert.Empty(t, mgr.List())
+}
+
+// TestHandleApproveSeries verifies that only a verified request that is part
+// of a signing series can be approved as one, and that doing so signs it.
+func TestHandleApproveSeries(t *testing.T) {
+	mgr := approval.NewManager(approval.ManagerConfig{Timeout: 5 * time.Second, HistoryMax: 100})
+	handlers := testHandlers(t, mgr)
+	handlers.resolver.GPGRunner = &fakeGPGRunner{sig: []byte("FAKE_SIG")}
+
+	sender := approval.SenderInfo{ProcessChain: []approval.ProcessInfo{
+		{Name: "secrets-dispatcher", PID: 300, StartTime: 9000},
+		{Name: "git", PID: 200, StartTime: 7000, Exe: "/usr/bin/git"},
+	}}
+	commits := []approval.SeriesCommit{{Hash: "1111111", Subject: "a"}, {Hash: "2222222", Subject: "b"}, {Hash: "3333333", Subject: "c"}}
+	newRequest := func(series *approval.SignSeries) string {
+		info := &approval.GPGSignInfo{
+			RepoName:     "myrepo",
+			Kind:         "commit",
+			KeyID:        "ABCD1234",
+			CommitObject: "tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\nauthor A\ncommitter A\n\nfix: thing\n",
+			Series:       series,
+			Verification: &approval.SignVerification{Repo: "/home/user/myrepo", Verified: true},
+		}
+		id, err := mgr.CreateGPGSignRequest("test-client", info, sender)
+		require.NoError(t, err)
+		return id
+	}
+	approveSeries := func(id string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/pending/"+id+"/approve-series", nil)
+		rr := httptest.NewRecorder()
+		handlers.HandleApproveSeries(rr, req)
+		return rr.Code
+	}
+
+	single := newRequest(nil)
+	assert.Equal(t, http.StatusBadRequest, approveSeries(single), "request outside a series")
+	last := newRequest(&approval.SignSeries{Operation: "rebase", Position: 3, Total: 3, Commits: commits})
+	assert.Equal(t, http.StatusBadRequest, approveSeries(last), "last commit of a series")
+	assert.Len(t, mgr.List(), 2, "refused requests stay pending")
+	assert.Equal(t, http.StatusNotFound, approveSeries("no-such-request"))
+
+	id := newRequest(&approval.SignSeries{Operation: "rebase", Position: 1, Total: 3, Commits: commits})
+	require.Equal(t, http.StatusOK, approveSeries(id))
+	require.Eventually(t, func() bool { return len(mgr.History()) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "FAKE_SIG", string(mgr.History()[0].Request.Signature))
+}
+
+// TestWSSnapshot_LargeDiffsFitThinClient checks that the snapshot stays within
+// the thin client's 1MB read limit however large the signed commits in the
+// history are: patches and commit objects are served per request instead.
+func TestWSSnapshot_LargeDiffsFitThinClient(t *testing.T) {
+	mgr := approval.NewManager(approval.ManagerConfig{Timeout: 5 * time.Second, HistoryMax: 100})
+	largeInfo := func(i int) *approval.GPGSignInfo {
+		return &approval.GPGSignInfo{
+			RepoName:     "myrepo",
+			CommitMsg:    fmt.Sprintf("commit %d", i),
+			KeyID:        "ABCD1234",
+			ChangedFiles: []string{"big.txt"},
+			CommitObject: strings.Repeat("o", 200<<10),
+			Diff: &approval.CommitDiff{
+				Files:      []approval.DiffFile{{Path: "big.txt", Additions: 5000}},
+				TotalFiles: 1,
+				Additions:  5000,
+				Patch:      fmt.Sprintf("+patch %d\n", i) + strings.Repeat("+line\n", 256<<10/6),
+			},
+		}
+	}
+	var ids []string
+	for i := range 6 {
+		id, err := mgr.RecordAutoApprovedGPGSign("test-client", largeInfo(i), approval.SenderInfo{}, []byte("sig"), nil, "")
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+	pendingID, err := mgr.CreateGPGSignRequest("test-client", largeInfo(6), approval.SenderInfo{})
+	require.NoError(t, err)
+
+	auth, err := NewAuth(t.TempDir())
+	require.NoError(t, err)
+	server := httptest.NewServer(http.HandlerFunc(NewWSHandler(mgr, nil, auth, "", "").HandleWS))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http"), &websocket.DialOptions{
+		HTTPHeader: http.Header{"Cookie": []string{mintSession(t, auth)}},
+	})
+	require.NoError(t, err)
+	defer conn.Close(websocket.StatusNormalClosure, "")
+	conn.SetReadLimit(1 << 20) // as gpgsign.DaemonClient.DialWebSocket
+
+	_, data, err := conn.Read(ctx)
+	require.NoError(t, err, "snapshot must fit the thin client's read limit")
+	var msg WSMessage
+	require.NoError(t, json.Unmarshal(data, &msg))
+	require.Equal(t, "snapshot", msg.Type)
+	require.Len(t, msg.History, 6)
+	require.Len(t, msg.Requests, 1)
+	for _, req := range append([]PendingRequest{msg.Requests[0]}, msg.History[0].Request) {
+		info := req.GPGSignInfo
+		require.NotNil(t, info)
+		assert.Empty(t, info.CommitObject)
+		require.NotNil(t, info.Diff)
+		assert.Empty(t, info.Diff.Patch)
+		assert.Equal(t, 5000, info.Diff.Additions, "the diff summary stays")
+	}
+
+	// The patch is served per request, pending or resolved.
+	handlers := testHandlers(t, mgr)
+	for i, id := range []string{ids[2], pendingID} {
+		rr := httptest.NewRecorder()
+		handlers.HandleRequestPatch(rr, httptest.NewRequest(http.MethodGet, "/api/v1/requests/"+id+"/patch", nil))
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var resp PatchResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		assert.True(t, strings.HasPrefix(resp.Patch, fmt.Sprintf("+patch %d\n", []int{2, 6}[i])), "patch of %s", id)
+	}
+
+	rr := httptest.NewRecorder()
+	handlers.HandleRequestPatch(rr, httptest.NewRequest(http.MethodGet, "/api/v1/requests/nonexistent/patch", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
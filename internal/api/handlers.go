@@ -2,12 +2,17 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/godbus/dbus/v5"
 	"github.com/nikicat/secrets-dispatcher/internal/approval"
 	"github.com/nikicat/secrets-dispatcher/internal/proxy"
+	"github.com/nikicat/secrets-dispatcher/internal/trash"
 )
 
 // ClientProvider is an interface for getting connected client information.
@@ -26,6 +31,17 @@ type Handlers struct {
 	clientName   string
 	auth         *Auth
 	testMode     bool // When true, enables test-only endpoints
+	// reloadConfig re-reads config.yaml and applies its trust rules; nil when
+	// serve was started without a reloadable config.
+	reloadConfig func() error
+	// addRule appends a trust rule to config.yaml and reloads it; nil when
+	// serve was started without a writable config path.
+	addRule func(approval.TrustRule) error
+	// trash keeps approved deletions for undo; nil when it is disabled.
+	trash        *trash.Store
+	restoreTrash func(*trash.Entry) ([]dbus.ObjectPath, error)
+	// upstreams reports whether each upstream is reachable; nil when unknown.
+	upstreams *proxy.UpstreamMonitor
 }
 
 // NewHandlers creates new API handlers for single-socket mode.
@@ -56,6 +72,35 @@ func (h *Handlers) SetTestMode(enabled bool) {
 	h.testMode = enabled
 }
 
+// SetConfigReloader sets the function used by POST /api/v1/config/reload.
+func (h *Handlers) SetConfigReloader(reload func() error) {
+	h.reloadConfig = reload
+}
+
+// SetUpstreamMonitor makes GET /api/v1/status report the upstreams' health.
+func (h *Handlers) SetUpstreamMonitor(m *proxy.UpstreamMonitor) {
+	h.upstreams = m
+}
+
+// HandleConfigReload handles POST /api/v1/config/reload.
+// A config that fails to load or validate is rejected with 422 and the
+// previously loaded rules stay in effect.
+func (h *Handlers) HandleConfigReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.reloadConfig == nil {
+		writeError(w, "config reload not available", http.StatusNotImplemented)
+		return
+	}
+	if err := h.reloadConfig(); err != nil {
+		writeError(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	writeJSON(w, ActionResponse{Status: "reloaded"})
+}
+
 // HandleStatus handles GET /api/v1/status.
 func (h *Handlers) HandleStatus(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -80,6 +125,9 @@ func (h *Handlers) HandleStatus(w http.ResponseWriter, r *http.Request) {
 		resp.Client = h.clientName
 		resp.RemoteSocket = h.remoteSocket
 	}
+	if h.upstreams != nil {
+		resp.Upstreams = h.upstreams.States()
+	}
 
 	writeJSON(w, resp)
 }
@@ -101,6 +149,7 @@ func (h *Handlers) HandlePendingList(w http.ResponseWriter, r *http.Request) {
 				Path:       item.Path,
 				Label:      item.Label,
 				Attributes: item.Attributes,
+				Backend:    item.Backend,
 			}
 		}
 		requests[i] = PendingRequest{
@@ -113,7 +162,10 @@ func (h *Handlers) HandlePendingList(w http.ResponseWriter, r *http.Request) {
 			Type:             string(req.Type),
 			SearchAttributes: req.SearchAttributes,
 			SenderInfo:       convertSenderInfo(req.SenderInfo),
-			GPGSignInfo:      req.GPGSignInfo,
+			GPGSignInfo:      gpgSignSummary(req.GPGSignInfo),
+			PairInfo:         req.PairInfo,
+			PerItem:          req.PerItem,
+			ApprovedItems:    req.ApprovedItems,
 		}
 	}
 
@@ -121,7 +173,8 @@ func (h *Handlers) HandlePendingList(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, resp)
 }
 
-// HandleApprove handles POST /api/v1/pending/{id}/approve.
+// HandleApprove handles POST /api/v1/pending/{id}/approve. An optional
+// ApproveRequest body grants only some items of a per-item request.
 func (h *Handlers) HandleApprove(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -134,12 +187,27 @@ func (h *Handlers) HandleApprove(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.resolver.Approve(id); err != nil {
-		if err == approval.ErrNotFound {
+	var body ApproveRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	if body.Items != nil {
+		err = h.manager.ApproveItems(id, body.Items)
+	} else {
+		err = h.resolver.Approve(id)
+	}
+	if err != nil {
+		switch {
+		case err == approval.ErrNotFound:
 			writeError(w, "request not found or expired", http.StatusNotFound)
-			return
+		case errors.Is(err, approval.ErrInvalidItems), errors.Is(err, approval.ErrNotPerItem):
+			writeError(w, err.Error(), http.StatusBadRequest)
+		default:
+			writeError(w, err.Error(), http.StatusInternalServerError)
 		}
-		writeError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
@@ -171,7 +239,73 @@ func (h *Handlers) HandleApproveAndAutoApprove(w http.ResponseWriter, r *http.Re
 	writeJSON(w, ActionResponse{Status: "approved"})
 }
 
-// HandleDeny handles POST /api/v1/pending/{id}/deny.
+// HandleApproveForProcess handles POST /api/v1/pending/{id}/approve-for-process.
+// The optional ApproveForProcessRequest body picks the process and whether
+// its descendants are covered.
+func (h *Handlers) HandleApproveForProcess(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := extractRequestID(r.URL.Path, "/api/v1/pending/", "/approve-for-process")
+	if id == "" {
+		writeError(w, "invalid request path", http.StatusBadRequest)
+		return
+	}
+
+	var body ApproveForProcessRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.resolver.ApproveForProcess(id, body.PID, body.Subtree); err != nil {
+		switch {
+		case err == approval.ErrNotFound:
+			writeError(w, "request not found or expired", http.StatusNotFound)
+		case errors.Is(err, approval.ErrInvalidProcess):
+			writeError(w, err.Error(), http.StatusBadRequest)
+		default:
+			writeError(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeJSON(w, ActionResponse{Status: "approved"})
+}
+
+// HandleApproveSeries handles POST /api/v1/pending/{id}/approve-series: it
+// approves a commit signing request and the rest of its signing series.
+func (h *Handlers) HandleApproveSeries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := extractRequestID(r.URL.Path, "/api/v1/pending/", "/approve-series")
+	if id == "" {
+		writeError(w, "invalid request path", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.resolver.ApproveSeries(id); err != nil {
+		switch {
+		case err == approval.ErrNotFound:
+			writeError(w, "request not found or expired", http.StatusNotFound)
+		case errors.Is(err, approval.ErrNoSeries):
+			writeError(w, err.Error(), http.StatusBadRequest)
+		default:
+			writeError(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeJSON(w, ActionResponse{Status: "approved"})
+}
+
+// HandleDeny handles POST /api/v1/pending/{id}/deny. An optional DenyRequest
+// body also adds a temporary deny rule for similar requests.
 func (h *Handlers) HandleDeny(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -184,12 +318,31 @@ func (h *Handlers) HandleDeny(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.manager.Deny(id); err != nil {
-		if err == approval.ErrNotFound {
+	var body DenyRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if body.ForSeconds < 0 {
+		writeError(w, "for_seconds must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	if body.ForSeconds > 0 || body.Mute {
+		err = h.manager.DenyFor(id, time.Duration(body.ForSeconds)*time.Second, body.Mute)
+	} else {
+		err = h.manager.Deny(id)
+	}
+	if err != nil {
+		switch {
+		case err == approval.ErrNotFound:
 			writeError(w, "request not found or expired", http.StatusNotFound)
-			return
+		case errors.Is(err, approval.ErrUnknownInvoker):
+			writeError(w, err.Error(), http.StatusBadRequest)
+		default:
+			writeError(w, err.Error(), http.StatusInternalServerError)
 		}
-		writeError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
@@ -288,13 +441,31 @@ func (h *Handlers) HandleAutoApproveDelete(w http.ResponseWriter, r *http.Reques
 }
 
 // HandleLog handles GET /api/v1/log.
+//
+// Without query parameters it returns the in-memory history window. The
+// optional since/until (RFC 3339, until exclusive) and limit parameters page
+// through the full persisted history instead.
 func (h *Handlers) HandleLog(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	history := h.manager.History()
+	var history []approval.HistoryEntry
+	if query := r.URL.Query(); query.Has("since") || query.Has("until") || query.Has("limit") {
+		q, err := parseHistoryQuery(query.Get("since"), query.Get("until"), query.Get("limit"))
+		if err != nil {
+			writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		history, err = h.manager.QueryHistory(q)
+		if err != nil {
+			writeError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	} else {
+		history = h.manager.History()
+	}
 	entries := make([]HistoryEntry, len(history))
 	for i, entry := range history {
 		entries[i] = convertHistoryEntry(entry)
@@ -304,6 +475,29 @@ func (h *Handlers) HandleLog(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, resp)
 }
 
+// parseHistoryQuery parses the /api/v1/log paging parameters. Empty values
+// leave the corresponding bound unset.
+func parseHistoryQuery(since, until, limit string) (approval.HistoryQuery, error) {
+	var q approval.HistoryQuery
+	var err error
+	if since != "" {
+		if q.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			return q, errors.New("invalid since: want RFC 3339 timestamp")
+		}
+	}
+	if until != "" {
+		if q.Until, err = time.Parse(time.RFC3339, until); err != nil {
+			return q, errors.New("invalid until: want RFC 3339 timestamp")
+		}
+	}
+	if limit != "" {
+		if q.Limit, err = strconv.Atoi(limit); err != nil || q.Limit < 0 {
+			return q, errors.New("invalid limit: want non-negative integer")
+		}
+	}
+	return q, nil
+}
+
 // convertSenderInfo converts approval.SenderInfo to api.SenderInfo.
 func convertSenderInfo(s approval.SenderInfo) SenderInfo {
 	info := SenderInfo{
@@ -313,16 +507,20 @@ func convertSenderInfo(s approval.SenderInfo) SenderInfo {
 		UserName:    s.UserName,
 		InvokerName: s.InvokerName,
 		SystemdUnit: s.SystemdUnit,
+		AppID:       s.AppID,
+		AppSandbox:  s.AppSandbox,
+		Container:   s.Container,
 	}
 	if len(s.ProcessChain) > 0 {
 		info.ProcessChain = make([]ProcessInfo, len(s.ProcessChain))
 		for i, p := range s.ProcessChain {
 			info.ProcessChain[i] = ProcessInfo{
-				Name: p.Name,
-				PID:  p.PID,
-				Exe:  p.Exe,
-				Args: p.Args,
-				CWD:  p.CWD,
+				Name:      p.Name,
+				PID:       p.PID,
+				StartTime: p.StartTime,
+				Exe:       p.Exe,
+				Args:      p.Args,
+				CWD:       p.CWD,
 			}
 		}
 	}
@@ -337,6 +535,7 @@ func convertHistoryEntry(entry approval.HistoryEntry) HistoryEntry {
 			Path:       item.Path,
 			Label:      item.Label,
 			Attributes: item.Attributes,
+			Backend:    item.Backend,
 		}
 	}
 	return HistoryEntry{
@@ -350,10 +549,14 @@ func convertHistoryEntry(entry approval.HistoryEntry) HistoryEntry {
 			Type:             string(entry.Request.Type),
 			SearchAttributes: entry.Request.SearchAttributes,
 			SenderInfo:       convertSenderInfo(entry.Request.SenderInfo),
-			GPGSignInfo:      entry.Request.GPGSignInfo,
+			GPGSignInfo:      gpgSignSummary(entry.Request.GPGSignInfo),
+			PairInfo:         entry.Request.PairInfo,
+			PerItem:          entry.Request.PerItem,
+			ApprovedItems:    entry.Request.ApprovedItems,
 		},
 		Resolution: string(entry.Resolution),
 		ResolvedAt: entry.ResolvedAt,
+		Rule:       entry.Rule,
 	}
 }
 
@@ -451,6 +654,7 @@ func (h *Handlers) HandleTestInjectHistory(w http.ResponseWriter, r *http.Reques
 			Path:       item.Path,
 			Label:      item.Label,
 			Attributes: item.Attributes,
+			Backend:    item.Backend,
 		}
 	}
 
@@ -460,16 +664,21 @@ func (h *Handlers) HandleTestInjectHistory(w http.ResponseWriter, r *http.Reques
 		UID:         entry.Request.SenderInfo.UID,
 		UserName:    entry.Request.SenderInfo.UserName,
 		InvokerName: entry.Request.SenderInfo.InvokerName,
+		SystemdUnit: entry.Request.SenderInfo.SystemdUnit,
+		AppID:       entry.Request.SenderInfo.AppID,
+		AppSandbox:  entry.Request.SenderInfo.AppSandbox,
+		Container:   entry.Request.SenderInfo.Container,
 	}
 	if len(entry.Request.SenderInfo.ProcessChain) > 0 {
 		approvalSender.ProcessChain = make([]approval.ProcessInfo, len(entry.Request.SenderInfo.ProcessChain))
 		for i, p := range entry.Request.SenderInfo.ProcessChain {
 			approvalSender.ProcessChain[i] = approval.ProcessInfo{
-				Name: p.Name,
-				PID:  p.PID,
-				Exe:  p.Exe,
-				Args: p.Args,
-				CWD:  p.CWD,
+				Name:      p.Name,
+				PID:       p.PID,
+				StartTime: p.StartTime,
+				Exe:       p.Exe,
+				Args:      p.Args,
+				CWD:       p.CWD,
 			}
 		}
 	}
@@ -3,12 +3,15 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/nikicat/secrets-dispatcher/internal/approval"
+	"github.com/nikicat/secrets-dispatcher/internal/proxy"
 )
 
 // mintSession mints a live browser session on auth and returns the matching
@@ -82,6 +85,23 @@ func TestHandleStatus(t *testing.T) {
 	}
 }
 
+func TestHandleStatus_Upstreams(t *testing.T) {
+	mgr := approval.NewManager(approval.ManagerConfig{Timeout: 5 * time.Minute, HistoryMax: 100})
+	handlers := testHandlers(t, mgr)
+	handlers.SetUpstreamMonitor(proxy.NewUpstreamMonitor([]string{"personal", "work"}))
+
+	rr := httptest.NewRecorder()
+	handlers.HandleStatus(rr, httptest.NewRequest(http.MethodGet, "/api/v1/status", nil))
+
+	var resp StatusResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Upstreams) != 2 || resp.Upstreams[1].Name != "work" || !resp.Upstreams[1].Connected {
+		t.Errorf("unexpected upstreams: %+v", resp.Upstreams)
+	}
+}
+
 func TestHandleStatus_WrongMethod(t *testing.T) {
 	mgr := approval.NewManager(approval.ManagerConfig{Timeout: 5 * time.Minute, HistoryMax: 100})
 	handlers := testHandlers(t, mgr)
@@ -168,6 +188,36 @@ func TestHandlePendingList_WithRequests(t *testing.T) {
 	}
 }
 
+func TestHandlePendingList_PairInfo(t *testing.T) {
+	mgr := approval.NewManager(approval.ManagerConfig{Timeout: 5 * time.Minute, HistoryMax: 100})
+	handlers := testHandlers(t, mgr)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		_ = mgr.RequirePairing(ctx, "build-01", &approval.PairInfo{Code: "123 456", Fingerprint: "SHA256:abc"}, approval.SenderInfo{})
+	}()
+	for range 100 {
+		if mgr.PendingCount() > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	rr := httptest.NewRecorder()
+	handlers.HandlePendingList(rr, httptest.NewRequest(http.MethodGet, "/api/v1/pending", nil))
+	var resp PendingListResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(resp.Requests))
+	}
+	if pi := resp.Requests[0].PairInfo; pi == nil || pi.Code != "123 456" {
+		t.Errorf("pair_info = %+v, want the comparison code", pi)
+	}
+}
+
 func TestHandleApprove_Success(t *testing.T) {
 	mgr := approval.NewManager(approval.ManagerConfig{Timeout: 5 * time.Minute, HistoryMax: 100})
 	handlers := testHandlers(t, mgr)
@@ -246,6 +296,145 @@ func TestHandleApprove_NotFound(t *testing.T) {
 	}
 }
 
+func TestHandleApprove_Items(t *testing.T) {
+	mgr := approval.NewManager(approval.ManagerConfig{Timeout: 5 * time.Minute, HistoryMax: 100})
+	handlers := testHandlers(t, mgr)
+
+	type result struct {
+		items []approval.ItemInfo
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		items, err := mgr.RequireItemsApproval(context.Background(), "test-client", []approval.ItemInfo{{Path: "/test/a"}, {Path: "/test/b"}}, "/session/1", approval.SenderInfo{})
+		done <- result{items, err}
+	}()
+
+	var reqID string
+	for range 100 {
+		if reqs := mgr.List(); len(reqs) > 0 {
+			reqID = reqs[0].ID
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if reqID == "" {
+		t.Fatal("request did not appear")
+	}
+
+	approve := func(body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/pending/"+reqID+"/approve", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		handlers.HandleApprove(rr, req)
+		return rr.Code
+	}
+	if code := approve(`{"items":["/test/c"]}`); code != http.StatusBadRequest {
+		t.Errorf("unknown item: expected status 400, got %d", code)
+	}
+	if code := approve(`{"items":["/test/b"]}`); code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", code)
+	}
+
+	select {
+	case r := <-done:
+		if r.err != nil || len(r.items) != 1 || r.items[0].Path != "/test/b" {
+			t.Errorf("granted = %v, %v; want only /test/b", r.items, r.err)
+		}
+	case <-time.After(time.Second):
+		t.Error("RequireItemsApproval did not unblock")
+	}
+}
+
+func TestHandleApproveForProcess(t *testing.T) {
+	mgr := approval.NewManager(approval.ManagerConfig{Timeout: 5 * time.Minute, HistoryMax: 100})
+	handlers := testHandlers(t, mgr)
+
+	sender := approval.SenderInfo{ProcessChain: []approval.ProcessInfo{
+		{Name: "terraform", PID: 200, StartTime: 7000, Exe: "/usr/bin/terraform"},
+		{Name: "agent", PID: 100, StartTime: 5000, Exe: "/usr/bin/agent"},
+	}}
+	done := make(chan error, 1)
+	go func() {
+		_, err := mgr.RequireApproval(context.Background(), "test-client", []approval.ItemInfo{{Path: "/test/item"}}, "/session/1", approval.RequestTypeGetSecret, nil, sender)
+		done <- err
+	}()
+
+	var reqID string
+	for range 100 {
+		if reqs := mgr.List(); len(reqs) > 0 {
+			reqID = reqs[0].ID
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if reqID == "" {
+		t.Fatal("request did not appear")
+	}
+
+	approve := func(body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/pending/"+reqID+"/approve-for-process", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		handlers.HandleApproveForProcess(rr, req)
+		return rr.Code
+	}
+	if code := approve(`{"pid":300}`); code != http.StatusBadRequest {
+		t.Errorf("PID outside the chain: expected status 400, got %d", code)
+	}
+	if code := approve(`{"pid":100,"subtree":true}`); code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", code)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("RequireApproval = %v", err)
+	}
+
+	rules := mgr.ListAutoApproveRules()
+	if len(rules) != 1 || rules[0].Process == nil || rules[0].Process.PID != 100 || !rules[0].Process.Subtree {
+		t.Errorf("rules = %+v, want a subtree rule on the agent", rules)
+	}
+}
+
+func TestHandleDeny_Mute(t *testing.T) {
+	mgr := approval.NewManager(approval.ManagerConfig{Timeout: 5 * time.Minute, HistoryMax: 100})
+	handlers := testHandlers(t, mgr)
+
+	sender := approval.SenderInfo{PID: 200, ProcessChain: []approval.ProcessInfo{{Name: "noisy", PID: 200, Exe: "/opt/noisy"}}}
+	done := make(chan error, 1)
+	go func() {
+		_, err := mgr.RequireApproval(context.Background(), "test-client", []approval.ItemInfo{{Path: "/test/item"}}, "/session/1", approval.RequestTypeGetSecret, nil, sender)
+		done <- err
+	}()
+
+	var reqID string
+	for range 100 {
+		if reqs := mgr.List(); len(reqs) > 0 {
+			reqID = reqs[0].ID
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if reqID == "" {
+		t.Fatal("request did not appear")
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/pending/"+reqID+"/deny", strings.NewReader(`{"mute":true,"for_seconds":600}`))
+	rr := httptest.NewRecorder()
+	handlers.HandleDeny(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body)
+	}
+	if err := <-done; !errors.Is(err, approval.ErrDenied) {
+		t.Errorf("RequireApproval = %v, want ErrDenied", err)
+	}
+
+	rules := mgr.ListAutoApproveRules()
+	if len(rules) != 1 || rules[0].Action != "deny" || rules[0].RequestType != "" {
+		t.Fatalf("rules = %+v, want one mute rule", rules)
+	}
+	if d := time.Until(rules[0].ExpiresAt); d <= 9*time.Minute || d > 10*time.Minute {
+		t.Errorf("mute expires in %v, want 10m", d)
+	}
+}
+
 func TestHandleApprove_WrongMethod(t *testing.T) {
 	mgr := approval.NewManager(approval.ManagerConfig{Timeout: 5 * time.Minute, HistoryMax: 100})
 	handlers := testHandlers(t, mgr)
@@ -475,6 +664,83 @@ func TestHandleLog_WithHistory(t *testing.T) {
 	}
 }
 
+func TestHandleLog_TimeRange(t *testing.T) {
+	mgr := approval.NewManager(approval.ManagerConfig{Timeout: 5 * time.Minute, HistoryMax: 100})
+	handlers := testHandlers(t, mgr)
+
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i, id := range []string{"old", "mid", "new"} {
+		mgr.AddHistoryEntry(approval.HistoryEntry{
+			Request:    &approval.Request{ID: id},
+			Resolution: approval.ResolutionApproved,
+			ResolvedAt: base.Add(time.Duration(i) * time.Hour),
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/log?since=2026-01-01T13:00:00Z&limit=1", nil)
+	rr := httptest.NewRecorder()
+	handlers.HandleLog(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rr.Code)
+	}
+	var resp HistoryResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Entries) != 1 || resp.Entries[0].Request.ID != "new" {
+		t.Errorf("expected [new], got %+v", resp.Entries)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/log?until=yesterday", nil)
+	rr = httptest.NewRecorder()
+	handlers.HandleLog(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for invalid until, got %d", rr.Code)
+	}
+}
+
+func TestHandleConfigReload(t *testing.T) {
+	mgr := approval.NewManager(approval.ManagerConfig{Timeout: 5 * time.Minute, HistoryMax: 100})
+	handlers := testHandlers(t, mgr)
+
+	// No reloader configured.
+	rr := httptest.NewRecorder()
+	handlers.HandleConfigReload(rr, httptest.NewRequest(http.MethodPost, "/api/v1/config/reload", nil))
+	if rr.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501 without reloader, got %d", rr.Code)
+	}
+
+	var reloadErr error
+	handlers.SetConfigReloader(func() error { return reloadErr })
+
+	rr = httptest.NewRecorder()
+	handlers.HandleConfigReload(rr, httptest.NewRequest(http.MethodPost, "/api/v1/config/reload", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rr.Code)
+	}
+
+	reloadErr = errors.New("rule 0: invalid action")
+	rr = httptest.NewRecorder()
+	handlers.HandleConfigReload(rr, httptest.NewRequest(http.MethodPost, "/api/v1/config/reload", nil))
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422 for invalid config, got %d", rr.Code)
+	}
+	var resp ErrorResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Error != reloadErr.Error() {
+		t.Errorf("error = %q, want %q", resp.Error, reloadErr.Error())
+	}
+
+	rr = httptest.NewRecorder()
+	handlers.HandleConfigReload(rr, httptest.NewRequest(http.MethodGet, "/api/v1/config/reload", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405, got %d", rr.Code)
+	}
+}
+
 func TestExtractRequestID(t *testing.T) {
 	tests := []struct {
 		path     string
@@ -92,19 +92,22 @@ func resolvePeerInfo(ctx context.Context, trimAtSessionLeader bool) approval.Sen
 			continue
 		}
 		processChain = append(processChain, approval.ProcessInfo{
-			Name: p.Comm,
-			PID:  uint32(p.PID),
-			Exe:  p.Exe,
-			Args: p.Args,
-			CWD:  p.CWD,
+			Name:      p.Comm,
+			PID:       uint32(p.PID),
+			StartTime: p.StartTime,
+			Exe:       p.Exe,
+			Args:      p.Args,
+			CWD:       p.CWD,
 		})
 	}
 
-	return approval.SenderInfo{
+	info := approval.SenderInfo{
 		PID:          uint32(invoker.PID),
 		UID:          uint32(cred.Uid),
 		InvokerName:  invoker.Comm,
 		ProcessChain: processChain,
 		PeerTrusted:  peerTrusted,
 	}
+	info.ReadSandbox(cred.Pid)
+	return info
 }
@@ -14,6 +14,7 @@ import (
 type Resolver struct {
 	Manager          *approval.Manager
 	GPGRunner        GPGRunner
+	SSHRunner        SSHRunner
 	UpstreamNotifier proxy.UpstreamNotifier
 	SlowThreshold    time.Duration
 }
@@ -23,11 +24,18 @@ func NewResolver(manager *approval.Manager, upstreamNotifier proxy.UpstreamNotif
 	return &Resolver{
 		Manager:          manager,
 		GPGRunner:        &defaultGPGRunner{},
+		SSHRunner:        &defaultSSHRunner{},
 		UpstreamNotifier: upstreamNotifier,
 		SlowThreshold:    slowThreshold,
 	}
 }
 
+// SetSSHAgent makes SSH signing use the agent at socket instead of the
+// daemon's SSH_AUTH_SOCK (see defaultSSHRunner).
+func (r *Resolver) SetSSHAgent(socket string) {
+	r.SSHRunner = &defaultSSHRunner{agentSocket: socket}
+}
+
 // Approve resolves a pending request. For GPG signing requests, it runs the
 // real gpg binary to produce the signature before approving.
 func (r *Resolver) Approve(id string) error {
@@ -37,7 +45,8 @@ func (r *Resolver) Approve(id string) error {
 	}
 
 	if req.Type == approval.RequestTypeGPGSign && req.GPGSignInfo != nil {
-		return r.approveGPGSign(id, req)
+		_, err := r.approveGPGSign(id, req)
+		return err
 	}
 
 	return r.Manager.Approve(id)
@@ -48,6 +57,12 @@ func (r *Resolver) Deny(id string) error {
 	return r.Manager.Deny(id)
 }
 
+// DenyAndMute denies a pending request and mutes its executable for
+// approval.DefaultDenyRuleDuration.
+func (r *Resolver) DenyAndMute(id string) error {
+	return r.Manager.DenyFor(id, approval.DefaultDenyRuleDuration, true)
+}
+
 // AutoApprove creates an auto-approve rule from a cancelled request.
 func (r *Resolver) AutoApprove(requestID string) error {
 	entry := r.Manager.GetHistoryEntry(requestID)
@@ -71,26 +86,58 @@ func (r *Resolver) ApproveAndAutoApprove(id string) error {
 	}
 
 	if req.Type == approval.RequestTypeGPGSign && req.GPGSignInfo != nil {
-		gpgPath, err := r.GPGRunner.FindGPG()
-		if err != nil {
-			slog.Error("failed to find real gpg", "error", err)
-			return r.Manager.ApproveGPGFailed(id, nil, 2)
+		signed, err := r.approveGPGSign(id, req)
+		if signed {
+			r.Manager.AddAutoApproveRule(req)
 		}
+		return err
+	}
 
-		res := r.runGPGWithNotify(gpgPath, req.GPGSignInfo.KeyID, []byte(req.GPGSignInfo.CommitObject), req.GPGSignInfo, req.SenderInfo)
-		if res.err != nil || res.exitCode != 0 {
-			slog.Error("gpg signing failed", "error", res.err, "exit_code", res.exitCode)
-			return r.Manager.ApproveGPGFailed(id, res.status, res.exitCode)
-		}
+	return r.Manager.ApproveAndAutoApprove(id)
+}
 
-		if err := r.Manager.ApproveWithSignature(id, res.sig, res.status); err != nil {
+// ApproveForProcess approves a pending request and auto-approves similar
+// requests from the chosen process instance (pid 0: the requester), or with
+// subtree from it and its descendants, until it exits. For GPG signing
+// requests the rule is only added when signing succeeded.
+func (r *Resolver) ApproveForProcess(id string, pid uint32, subtree bool) error {
+	req := r.Manager.GetPending(id)
+	if req == nil {
+		return approval.ErrNotFound
+	}
+
+	if req.Type == approval.RequestTypeGPGSign && req.GPGSignInfo != nil {
+		scope, err := approval.NewProcessScope(req, pid, subtree)
+		if err != nil {
 			return err
 		}
-		r.Manager.AddAutoApproveRule(req)
-		return nil
+		signed, err := r.approveGPGSign(id, req)
+		if signed {
+			r.Manager.AddProcessRule(req, scope)
+		}
+		return err
 	}
 
-	return r.Manager.ApproveAndAutoApprove(id)
+	return r.Manager.ApproveForProcess(id, pid, subtree)
+}
+
+// ApproveSeries approves a pending commit signing request and the rest of the
+// rebase, cherry-pick, revert or am it is part of (approval.SeriesGrant). The
+// grant is only added when signing succeeded.
+func (r *Resolver) ApproveSeries(id string) error {
+	req := r.Manager.GetPending(id)
+	if req == nil {
+		return approval.ErrNotFound
+	}
+	grant, err := approval.NewSeriesGrant(req)
+	if err != nil {
+		return err
+	}
+	signed, err := r.approveGPGSign(id, req)
+	if signed {
+		r.Manager.AddSeriesGrant(grant)
+	}
+	return err
 }
 
 // gpgResult bundles the return values of RunGPG for use with WithSlowNotify.
@@ -101,7 +148,17 @@ type gpgResult struct {
 	err      error
 }
 
-// runGPGWithNotify wraps RunGPG with slow upstream notification.
+// findSigner locates the program that signs info: ssh-keygen for SSH-format
+// requests, gpg otherwise.
+func (r *Resolver) findSigner(info *approval.GPGSignInfo) (string, error) {
+	if info.Format == approval.SSHFormat {
+		return r.SSHRunner.FindSSHKeygen()
+	}
+	return r.GPGRunner.FindGPG()
+}
+
+// runGPGWithNotify wraps RunGPG (RunSSHKeygen for SSH-format requests) with
+// slow upstream notification. gpgPath is what findSigner returned.
 func (r *Resolver) runGPGWithNotify(gpgPath, keyID string, commitObject []byte, info *approval.GPGSignInfo, senderInfo approval.SenderInfo) gpgResult {
 	items := gpgSignItems(info)
 	return proxy.WithSlowNotify(r.SlowThreshold, r.UpstreamNotifier, proxy.UpstreamCallContext{
@@ -109,6 +166,10 @@ func (r *Resolver) runGPGWithNotify(gpgPath, keyID string, commitObject []byte,
 		Items:       items,
 		SenderInfo:  senderInfo,
 	}, func() gpgResult {
+		if info.Format == approval.SSHFormat {
+			sig, status, exitCode, err := r.SSHRunner.RunSSHKeygen(gpgPath, info.SSHKey, info.UseAgent, commitObject)
+			return gpgResult{sig, status, exitCode, err}
+		}
 		sig, status, exitCode, err := r.GPGRunner.RunGPG(gpgPath, keyID, commitObject)
 		return gpgResult{sig, status, exitCode, err}
 	})
@@ -133,18 +194,24 @@ func gpgSignItems(info *approval.GPGSignInfo) []approval.ItemInfo {
 	return []approval.ItemInfo{{Label: label}}
 }
 
-func (r *Resolver) approveGPGSign(id string, req *approval.Request) error {
-	gpgPath, err := r.GPGRunner.FindGPG()
+// approveGPGSign runs the real gpg for a pending gpg_sign request and resolves
+// it with the signature, or with the failure for the thin client to report.
+// signed reports whether a signature was produced and delivered.
+func (r *Resolver) approveGPGSign(id string, req *approval.Request) (signed bool, err error) {
+	gpgPath, err := r.findSigner(req.GPGSignInfo)
 	if err != nil {
-		slog.Error("failed to find real gpg", "error", err)
-		return r.Manager.ApproveGPGFailed(id, nil, 2)
+		slog.Error("failed to find signing program", "format", req.GPGSignInfo.Format, "error", err)
+		return false, r.Manager.ApproveGPGFailed(id, nil, 2)
 	}
 
 	res := r.runGPGWithNotify(gpgPath, req.GPGSignInfo.KeyID, []byte(req.GPGSignInfo.CommitObject), req.GPGSignInfo, req.SenderInfo)
 	if res.err != nil || res.exitCode != 0 {
 		slog.Error("gpg signing failed", "error", res.err, "exit_code", res.exitCode)
-		return r.Manager.ApproveGPGFailed(id, res.status, res.exitCode)
+		return false, r.Manager.ApproveGPGFailed(id, res.status, res.exitCode)
 	}
 
-	return r.Manager.ApproveWithSignature(id, res.sig, res.status)
+	if err := r.Manager.ApproveWithSignature(id, res.sig, res.status); err != nil {
+		return false, err
+	}
+	return true, nil
 }
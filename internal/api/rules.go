@@ -0,0 +1,146 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/nikicat/secrets-dispatcher/internal/approval"
+)
+
+// RuleSuggestResponse is returned by GET /api/v1/rules/suggest.
+type RuleSuggestResponse struct {
+	Rule approval.TrustRule `json:"rule"`
+}
+
+// Defaults for the GET /api/v1/rules/stats flags.
+const (
+	defaultUnusedDays     = 30
+	defaultMaxExecutables = 5
+)
+
+// RuleStatsEntry is one trust rule's statistics with its review flags.
+type RuleStatsEntry struct {
+	approval.RuleStat
+	Unused bool `json:"unused,omitempty"` // no match for more than unused_days
+	Broad  bool `json:"broad,omitempty"`  // matched more than max_executables distinct executables
+}
+
+// RuleStatsResponse is returned by GET /api/v1/rules/stats.
+type RuleStatsResponse struct {
+	Rules []RuleStatsEntry `json:"rules"`
+}
+
+// SetRuleAdder sets the function used by POST /api/v1/rules to persist a
+// trust rule to config.yaml and load it.
+func (h *Handlers) SetRuleAdder(add func(approval.TrustRule) error) {
+	h.addRule = add
+}
+
+// HandleRuleSuggest handles GET /api/v1/rules/suggest?from={id}.
+// It derives a trust rule from a pending or historical request for the user to
+// narrow before saving it with POST /api/v1/rules.
+func (h *Handlers) HandleRuleSuggest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.URL.Query().Get("from")
+	if id == "" {
+		writeError(w, "missing from parameter", http.StatusBadRequest)
+		return
+	}
+	req, err := h.manager.LookupRequest(id)
+	if err != nil {
+		if errors.Is(err, approval.ErrNotFound) {
+			writeError(w, "request not found", http.StatusNotFound)
+			return
+		}
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	rule, err := approval.SuggestTrustRule(req)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	writeJSON(w, RuleSuggestResponse{Rule: rule})
+}
+
+// HandleRuleAdd handles POST /api/v1/rules.
+// The rule is appended to serve.rules in config.yaml and takes effect
+// immediately; a rule that would make the config invalid is rejected with 422.
+func (h *Handlers) HandleRuleAdd(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.addRule == nil {
+		writeError(w, "adding rules not available", http.StatusNotImplemented)
+		return
+	}
+
+	var rule approval.TrustRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		writeError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if rule.Client == "" && rule.Backend == "" && rule.Process == nil && rule.Secret == nil && rule.SSH == nil && rule.Signing == nil && len(rule.SearchAttributes) == 0 {
+		writeError(w, "rule must match on client, backend, process, secret, ssh key, signing or search attributes", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.addRule(rule); err != nil {
+		writeError(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	writeJSON(w, ActionResponse{Status: "added"})
+}
+
+// HandleRuleStats handles GET /api/v1/rules/stats?unused_days=N&max_executables=M.
+// It lists every trust rule with its hit count, last hit and the
+// executables it matched, flagging rules that look stale or too broad.
+func (h *Handlers) HandleRuleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	unusedDays, err := intParam(q.Get("unused_days"), defaultUnusedDays)
+	if err != nil {
+		writeError(w, "invalid unused_days parameter", http.StatusBadRequest)
+		return
+	}
+	maxExes, err := intParam(q.Get("max_executables"), defaultMaxExecutables)
+	if err != nil {
+		writeError(w, "invalid max_executables parameter", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	resp := RuleStatsResponse{Rules: []RuleStatsEntry{}}
+	for _, s := range h.manager.RuleStats() {
+		resp.Rules = append(resp.Rules, RuleStatsEntry{
+			RuleStat: s,
+			Unused:   unusedDays > 0 && s.Unused(now, time.Duration(unusedDays)*24*time.Hour),
+			Broad:    maxExes > 0 && s.Broad(maxExes),
+		})
+	}
+	writeJSON(w, resp)
+}
+
+// intParam parses a non-negative integer query parameter, returning def when
+// it is absent.
+func intParam(v string, def int) (int, error) {
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, errors.New("invalid integer")
+	}
+	return n, nil
+}
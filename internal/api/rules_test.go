@@ -0,0 +1,146 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nikicat/secrets-dispatcher/internal/approval"
+)
+
+func TestHandleRuleSuggest(t *testing.T) {
+	mgr := approval.NewManager(approval.ManagerConfig{Timeout: 5 * time.Minute, HistoryMax: 100})
+	handlers := testHandlers(t, mgr)
+
+	mgr.AddHistoryEntry(approval.HistoryEntry{
+		Request: &approval.Request{
+			ID:    "hist-1",
+			Type:  approval.RequestTypeGetSecret,
+			Items: []approval.ItemInfo{{Path: "/org/freedesktop/secrets/collection/login/1", Attributes: map[string]string{"service": "gh"}}},
+			SenderInfo: approval.SenderInfo{ProcessChain: []approval.ProcessInfo{
+				{Name: "gh", PID: 10, Exe: "/usr/bin/gh"},
+			}},
+		},
+		Resolution: approval.ResolutionApproved,
+		ResolvedAt: time.Now(),
+	})
+
+	rr := httptest.NewRecorder()
+	handlers.HandleRuleSuggest(rr, httptest.NewRequest(http.MethodGet, "/api/v1/rules/suggest?from=hist-1", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body)
+	}
+	var resp RuleSuggestResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Rule.Process == nil || resp.Rule.Process.Exe != "/usr/bin/gh" {
+		t.Errorf("process = %+v, want exe /usr/bin/gh", resp.Rule.Process)
+	}
+	if resp.Rule.Secret == nil || resp.Rule.Secret.Collection != "login" {
+		t.Errorf("secret = %+v, want collection login", resp.Rule.Secret)
+	}
+
+	rr = httptest.NewRecorder()
+	handlers.HandleRuleSuggest(rr, httptest.NewRequest(http.MethodGet, "/api/v1/rules/suggest?from=nope", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for unknown request, got %d", rr.Code)
+	}
+}
+
+func TestHandleRuleAdd(t *testing.T) {
+	mgr := approval.NewManager(approval.ManagerConfig{Timeout: 5 * time.Minute, HistoryMax: 100})
+	handlers := testHandlers(t, mgr)
+
+	body := `{"name":"gh","action":"approve","process":{"exe":"/usr/bin/gh"}}`
+
+	rr := httptest.NewRecorder()
+	handlers.HandleRuleAdd(rr, httptest.NewRequest(http.MethodPost, "/api/v1/rules", strings.NewReader(body)))
+	if rr.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501 without adder, got %d", rr.Code)
+	}
+
+	var added []approval.TrustRule
+	var addErr error
+	handlers.SetRuleAdder(func(r approval.TrustRule) error {
+		if addErr != nil {
+			return addErr
+		}
+		added = append(added, r)
+		return nil
+	})
+
+	rr = httptest.NewRecorder()
+	handlers.HandleRuleAdd(rr, httptest.NewRequest(http.MethodPost, "/api/v1/rules", strings.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body)
+	}
+	if len(added) != 1 || added[0].Process.Exe != "/usr/bin/gh" {
+		t.Errorf("added = %+v, want the posted rule", added)
+	}
+
+	// A rule without any matcher would approve everything.
+	rr = httptest.NewRecorder()
+	handlers.HandleRuleAdd(rr, httptest.NewRequest(http.MethodPost, "/api/v1/rules", strings.NewReader(`{"name":"all"}`)))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for matcher-less rule, got %d", rr.Code)
+	}
+
+	// A backend alone is a matcher, as in the config file.
+	rr = httptest.NewRecorder()
+	handlers.HandleRuleAdd(rr, httptest.NewRequest(http.MethodPost, "/api/v1/rules", strings.NewReader(`{"name":"ci","action":"deny","backend":"ci"}`)))
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status 200 for backend-only rule, got %d: %s", rr.Code, rr.Body)
+	}
+
+	addErr = errors.New(`rules[3]: action must be "approve", "ignore", or "deny", got "bogus"`)
+	rr = httptest.NewRecorder()
+	handlers.HandleRuleAdd(rr, httptest.NewRequest(http.MethodPost, "/api/v1/rules", strings.NewReader(body)))
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422 for invalid rule, got %d", rr.Code)
+	}
+}
+
+func TestHandleRuleStats(t *testing.T) {
+	mgr := approval.NewManager(approval.ManagerConfig{
+		Timeout:    5 * time.Minute,
+		HistoryMax: 100,
+		TrustRules: []approval.TrustRule{{Name: "any-shell", Process: &approval.ProcessMatcher{Exe: "/usr/bin/*"}}},
+	})
+	handlers := testHandlers(t, mgr)
+
+	item := []approval.ItemInfo{{Path: "/org/freedesktop/secrets/collection/default/1"}}
+	for _, exe := range []string{"/usr/bin/bash", "/usr/bin/zsh"} {
+		sender := approval.SenderInfo{ProcessChain: []approval.ProcessInfo{{Name: "sh", Exe: exe}}}
+		if _, err := mgr.RequireApproval(context.Background(), "local", item, "", approval.RequestTypeGetSecret, nil, sender); err != nil {
+			t.Fatalf("RequireApproval: %v", err)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	handlers.HandleRuleStats(rr, httptest.NewRequest(http.MethodGet, "/api/v1/rules/stats?max_executables=1", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body)
+	}
+	var resp RuleStatsResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Rules) != 1 {
+		t.Fatalf("got %d rules, want 1", len(resp.Rules))
+	}
+	if r := resp.Rules[0]; r.Rule != "any-shell" || r.Hits != 2 || !r.Broad || r.Unused {
+		t.Errorf("stats = %+v, want 2 hits, broad, not unused", r)
+	}
+
+	rr = httptest.NewRecorder()
+	handlers.HandleRuleStats(rr, httptest.NewRequest(http.MethodGet, "/api/v1/rules/stats?unused_days=x", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for a bad parameter, got %d", rr.Code)
+	}
+}
@@ -10,8 +10,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/godbus/dbus/v5"
 	"github.com/nikicat/secrets-dispatcher/internal/approval"
 	"github.com/nikicat/secrets-dispatcher/internal/proxy"
+	"github.com/nikicat/secrets-dispatcher/internal/trash"
 )
 
 // Server is the HTTP API server.
@@ -70,6 +72,13 @@ func newServerWithHandlers(addr string, handlers *Handlers, wsHandler *WSHandler
 		}
 	})
 	apiMux.HandleFunc("/api/v1/auto-approve/", handlers.HandleAutoApproveDelete)
+	apiMux.HandleFunc("/api/v1/config/reload", handlers.HandleConfigReload)
+	apiMux.HandleFunc("/api/v1/rules", handlers.HandleRuleAdd)
+	apiMux.HandleFunc("/api/v1/rules/suggest", handlers.HandleRuleSuggest)
+	apiMux.HandleFunc("/api/v1/rules/stats", handlers.HandleRuleStats)
+	apiMux.HandleFunc("/api/v1/trash", handlers.HandleTrash)
+	apiMux.HandleFunc("/api/v1/trash/", handlers.HandleTrashEntry)
+	apiMux.HandleFunc("/api/v1/requests/", handlers.HandleRequestPatch)
 
 	// Routes with path parameters need pattern matching
 	apiMux.HandleFunc("/api/v1/pending/", func(w http.ResponseWriter, r *http.Request) {
@@ -77,6 +86,10 @@ func newServerWithHandlers(addr string, handlers *Handlers, wsHandler *WSHandler
 		switch {
 		case strings.HasSuffix(path, "/approve-and-auto-approve"):
 			handlers.HandleApproveAndAutoApprove(w, r)
+		case strings.HasSuffix(path, "/approve-for-process"):
+			handlers.HandleApproveForProcess(w, r)
+		case strings.HasSuffix(path, "/approve-series"):
+			handlers.HandleApproveSeries(w, r)
 		case strings.HasSuffix(path, "/approve"):
 			handlers.HandleApprove(w, r)
 		case strings.HasSuffix(path, "/deny"):
@@ -186,6 +199,35 @@ func (s *Server) WSHandler() *WSHandler {
 	return s.wsHandler
 }
 
+// SetConfigReloader enables POST /api/v1/config/reload.
+func (s *Server) SetConfigReloader(reload func() error) {
+	s.handlers.SetConfigReloader(reload)
+}
+
+// SetRuleAdder enables POST /api/v1/rules.
+func (s *Server) SetRuleAdder(add func(approval.TrustRule) error) {
+	s.handlers.SetRuleAdder(add)
+}
+
+// SetTrash enables the /api/v1/trash endpoints.
+func (s *Server) SetTrash(store *trash.Store, restore func(*trash.Entry) ([]dbus.ObjectPath, error)) {
+	s.handlers.SetTrash(store, restore)
+}
+
+// SetUpstreamMonitor reports the upstreams' health in the status endpoint and
+// to WebSocket clients.
+func (s *Server) SetUpstreamMonitor(m *proxy.UpstreamMonitor) {
+	s.handlers.SetUpstreamMonitor(m)
+	s.wsHandler.upstreams = m
+	m.Subscribe(s.wsHandler)
+}
+
+// SetSSHAgent makes SSH-format commit signing use the agent at socket (see
+// Resolver.SetSSHAgent).
+func (s *Server) SetSSHAgent(socket string) {
+	s.handlers.resolver.SetSSHAgent(socket)
+}
+
 // SetTestMode enables test-only endpoints.
 func (s *Server) SetTestMode(enabled bool) {
 	s.testMode = enabled
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/nikicat/secrets-dispatcher/internal/trash"
+)
+
+// TrashItem is a deleted item as listed by the API; its secret is never sent.
+type TrashItem struct {
+	Path       string            `json:"path"`
+	Label      string            `json:"label"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// TrashEntry is one undoable deletion.
+type TrashEntry struct {
+	ID              string      `json:"id"`
+	DeletedAt       time.Time   `json:"deleted_at"`
+	ExpiresAt       time.Time   `json:"expires_at"`
+	Kind            string      `json:"kind"` // "item" or "collection"
+	Client          string      `json:"client"`
+	Backend         string      `json:"backend,omitempty"`
+	Collection      string      `json:"collection"`
+	CollectionLabel string      `json:"collection_label,omitempty"`
+	Items           []TrashItem `json:"items"`
+}
+
+// TrashListResponse is returned by GET /api/v1/trash.
+type TrashListResponse struct {
+	Entries []TrashEntry `json:"entries"`
+}
+
+// TrashRestoreResponse is returned by POST /api/v1/trash/{id}/restore.
+type TrashRestoreResponse struct {
+	Status string   `json:"status"`
+	Items  []string `json:"items"` // paths of the recreated items
+}
+
+// TrashPurgeResponse is returned by DELETE /api/v1/trash[/{id}].
+type TrashPurgeResponse struct {
+	Status string `json:"status"`
+	Count  int    `json:"count"`
+}
+
+// SetTrash enables the /api/v1/trash endpoints. restore recreates an entry's
+// items upstream.
+func (h *Handlers) SetTrash(store *trash.Store, restore func(*trash.Entry) ([]dbus.ObjectPath, error)) {
+	h.trash = store
+	h.restoreTrash = restore
+}
+
+// HandleTrash handles GET /api/v1/trash (list) and DELETE /api/v1/trash
+// (purge everything).
+func (h *Handlers) HandleTrash(w http.ResponseWriter, r *http.Request) {
+	if h.trash == nil {
+		writeError(w, "trash not enabled", http.StatusNotImplemented)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		entries, err := h.trash.List()
+		if err != nil {
+			writeError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		resp := TrashListResponse{Entries: make([]TrashEntry, 0, len(entries))}
+		for i := range entries {
+			resp.Entries = append(resp.Entries, h.convertTrashEntry(&entries[i]))
+		}
+		writeJSON(w, resp)
+	case http.MethodDelete:
+		n, err := h.trash.Purge()
+		if err != nil {
+			writeError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		slog.Info("trash purged", "entries", n)
+		writeJSON(w, TrashPurgeResponse{Status: "purged", Count: n})
+	default:
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleTrashEntry handles POST /api/v1/trash/{id}/restore and
+// DELETE /api/v1/trash/{id}. {id} may be a unique prefix. A restored entry
+// leaves the trash; one that fails part-way stays, with 502.
+func (h *Handlers) HandleTrashEntry(w http.ResponseWriter, r *http.Request) {
+	if h.trash == nil {
+		writeError(w, "trash not enabled", http.StatusNotImplemented)
+		return
+	}
+	rest := strings.TrimPrefix(r.URL.Path, "/api/v1/trash/")
+	id, action, _ := strings.Cut(rest, "/")
+	switch {
+	case id == "":
+		writeError(w, "invalid trash entry ID", http.StatusBadRequest)
+	case action == "restore" && r.Method == http.MethodPost:
+		h.restoreTrashEntry(w, id)
+	case action == "" && r.Method == http.MethodDelete:
+		if err := h.trash.Remove(id); err != nil {
+			writeTrashError(w, err)
+			return
+		}
+		slog.Info("trash entry purged", "id", id)
+		writeJSON(w, TrashPurgeResponse{Status: "purged", Count: 1})
+	case action == "restore" || action == "":
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+	default:
+		writeError(w, "not found", http.StatusNotFound)
+	}
+}
+
+func (h *Handlers) restoreTrashEntry(w http.ResponseWriter, id string) {
+	if h.restoreTrash == nil {
+		writeError(w, "restore not available", http.StatusNotImplemented)
+		return
+	}
+	entry, err := h.trash.Get(id)
+	if err != nil {
+		writeTrashError(w, err)
+		return
+	}
+	collection := entry.Collection
+	paths, err := h.restoreTrash(entry)
+	if err != nil {
+		slog.Warn("trash restore failed", "id", entry.ID, "restored", len(paths), "of", len(entry.Items), "error", err)
+		if len(paths) > 0 || entry.Collection != collection {
+			// Keep only what is still missing, and where the collection was
+			// recreated, so a retry does not duplicate what made it back.
+			entry.Items = entry.Items[len(paths):]
+			if err := h.trash.Put(entry); err != nil {
+				slog.Warn("failed to update partially restored trash entry", "id", entry.ID, "error", err)
+			}
+		}
+		writeError(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	if err := h.trash.Remove(entry.ID); err != nil {
+		slog.Warn("failed to remove restored trash entry", "id", entry.ID, "error", err)
+	}
+	slog.Info("trash entry restored", "id", entry.ID, "kind", entry.Kind, "items", len(paths))
+	resp := TrashRestoreResponse{Status: "restored", Items: make([]string, len(paths))}
+	for i, p := range paths {
+		resp.Items[i] = string(p)
+	}
+	writeJSON(w, resp)
+}
+
+func (h *Handlers) convertTrashEntry(e *trash.Entry) TrashEntry {
+	out := TrashEntry{
+		ID:              e.ID,
+		DeletedAt:       e.DeletedAt,
+		ExpiresAt:       e.DeletedAt.Add(h.trash.Retention()),
+		Kind:            e.Kind,
+		Client:          e.Client,
+		Backend:         e.Backend,
+		Collection:      e.Collection,
+		CollectionLabel: e.CollectionLabel,
+		Items:           make([]TrashItem, len(e.Items)),
+	}
+	for i, item := range e.Items {
+		out.Items[i] = TrashItem{Path: item.Path, Label: item.Label, Attributes: item.Attributes}
+	}
+	return out
+}
+
+func writeTrashError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, trash.ErrNotFound):
+		writeError(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, trash.ErrAmbiguous):
+		writeError(w, err.Error(), http.StatusBadRequest)
+	default:
+		writeError(w, err.Error(), http.StatusInternalServerError)
+	}
+}
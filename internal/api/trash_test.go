@@ -0,0 +1,159 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/nikicat/secrets-dispatcher/internal/approval"
+	"github.com/nikicat/secrets-dispatcher/internal/trash"
+)
+
+func TestHandleTrash(t *testing.T) {
+	mgr := approval.NewManager(approval.ManagerConfig{Timeout: 5 * time.Minute, HistoryMax: 100})
+	handlers := testHandlers(t, mgr)
+
+	rr := httptest.NewRecorder()
+	handlers.HandleTrash(rr, httptest.NewRequest(http.MethodGet, "/api/v1/trash", nil))
+	if rr.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501 without a trash, got %d", rr.Code)
+	}
+
+	store := trash.NewStore(t.TempDir(), time.Hour, trash.FileKey(filepath.Join(t.TempDir(), "key")))
+	entry := &trash.Entry{
+		Kind:       trash.KindItem,
+		Client:     "local",
+		Collection: "/org/freedesktop/secrets/collection/login",
+		Items:      []trash.Item{{Path: "/org/freedesktop/secrets/collection/login/1", Label: "gh", Secret: []byte("token")}},
+	}
+	if err := store.Put(entry); err != nil {
+		t.Fatal(err)
+	}
+	restoreErr := errors.New("upstream gone")
+	handlers.SetTrash(store, func(e *trash.Entry) ([]dbus.ObjectPath, error) {
+		if restoreErr != nil {
+			return nil, restoreErr
+		}
+		return []dbus.ObjectPath{"/org/freedesktop/secrets/collection/login/2"}, nil
+	})
+
+	rr = httptest.NewRecorder()
+	handlers.HandleTrash(rr, httptest.NewRequest(http.MethodGet, "/api/v1/trash", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body)
+	}
+	if body := rr.Body.String(); strings.Contains(body, `"secret"`) || strings.Contains(body, "token") {
+		t.Errorf("trash listing leaks the secret: %s", body)
+	}
+	var list TrashListResponse
+	if err := json.NewDecoder(rr.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(list.Entries) != 1 || list.Entries[0].ID != entry.ID || list.Entries[0].Items[0].Label != "gh" {
+		t.Fatalf("unexpected listing: %+v", list)
+	}
+	if want := entry.DeletedAt.Add(time.Hour); !list.Entries[0].ExpiresAt.Equal(want) {
+		t.Errorf("expires_at = %v, want %v", list.Entries[0].ExpiresAt, want)
+	}
+
+	// A failed restore keeps the entry.
+	rr = httptest.NewRecorder()
+	handlers.HandleTrashEntry(rr, httptest.NewRequest(http.MethodPost, "/api/v1/trash/"+entry.ID[:8]+"/restore", nil))
+	if rr.Code != http.StatusBadGateway {
+		t.Fatalf("expected status 502, got %d: %s", rr.Code, rr.Body)
+	}
+	if _, err := store.Get(entry.ID); err != nil {
+		t.Fatalf("entry gone after failed restore: %v", err)
+	}
+
+	restoreErr = nil
+	rr = httptest.NewRecorder()
+	handlers.HandleTrashEntry(rr, httptest.NewRequest(http.MethodPost, "/api/v1/trash/"+entry.ID[:8]+"/restore", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body)
+	}
+	var restored TrashRestoreResponse
+	if err := json.NewDecoder(rr.Body).Decode(&restored); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(restored.Items) != 1 {
+		t.Errorf("restored items = %v", restored.Items)
+	}
+	if _, err := store.Get(entry.ID); !errors.Is(err, trash.ErrNotFound) {
+		t.Errorf("entry still in trash after restore: %v", err)
+	}
+
+	rr = httptest.NewRecorder()
+	handlers.HandleTrashEntry(rr, httptest.NewRequest(http.MethodDelete, "/api/v1/trash/"+entry.ID, nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 purging a restored entry, got %d", rr.Code)
+	}
+}
+
+func TestHandleTrash_PartialRestore(t *testing.T) {
+	mgr := approval.NewManager(approval.ManagerConfig{Timeout: 5 * time.Minute, HistoryMax: 100})
+	handlers := testHandlers(t, mgr)
+
+	store := trash.NewStore(t.TempDir(), time.Hour, trash.FileKey(filepath.Join(t.TempDir(), "key")))
+	entry := &trash.Entry{
+		Kind:            trash.KindCollection,
+		Client:          "local",
+		Collection:      "/org/freedesktop/secrets/collection/work",
+		CollectionLabel: "work",
+		Items: []trash.Item{
+			{Path: "/org/freedesktop/secrets/collection/work/1", Label: "a", Secret: []byte("1")},
+			{Path: "/org/freedesktop/secrets/collection/work/2", Label: "b", Secret: []byte("2")},
+			{Path: "/org/freedesktop/secrets/collection/work/3", Label: "c", Secret: []byte("3")},
+		},
+	}
+	if err := store.Put(entry); err != nil {
+		t.Fatal(err)
+	}
+	var attempts []*trash.Entry
+	handlers.SetTrash(store, func(e *trash.Entry) ([]dbus.ObjectPath, error) {
+		attempts = append(attempts, e)
+		if len(attempts) == 1 {
+			// Recreate the collection and one item, then fail.
+			e.Collection = "/org/freedesktop/secrets/collection/work_1"
+			return []dbus.ObjectPath{"/org/freedesktop/secrets/collection/work_1/1"}, errors.New("prompt dismissed")
+		}
+		return []dbus.ObjectPath{"/org/freedesktop/secrets/collection/work_1/2", "/org/freedesktop/secrets/collection/work_1/3"}, nil
+	})
+
+	rr := httptest.NewRecorder()
+	handlers.HandleTrashEntry(rr, httptest.NewRequest(http.MethodPost, "/api/v1/trash/"+entry.ID+"/restore", nil))
+	if rr.Code != http.StatusBadGateway {
+		t.Fatalf("expected status 502, got %d: %s", rr.Code, rr.Body)
+	}
+	got, err := store.Get(entry.ID)
+	if err != nil {
+		t.Fatalf("entry gone after partial restore: %v", err)
+	}
+	if len(got.Items) != 2 || got.Items[0].Label != "b" || got.Items[1].Label != "c" {
+		t.Errorf("items left after partial restore = %+v, want b and c", got.Items)
+	}
+	if got.Collection != "/org/freedesktop/secrets/collection/work_1" {
+		t.Errorf("collection = %q, want the recreated one", got.Collection)
+	}
+	if !got.DeletedAt.Equal(entry.DeletedAt) {
+		t.Errorf("deleted_at changed from %v to %v", entry.DeletedAt, got.DeletedAt)
+	}
+
+	rr = httptest.NewRecorder()
+	handlers.HandleTrashEntry(rr, httptest.NewRequest(http.MethodPost, "/api/v1/trash/"+entry.ID+"/restore", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body)
+	}
+	if retry := attempts[1]; len(retry.Items) != 2 || retry.Items[0].Label != "b" {
+		t.Errorf("retry restored %+v, want only the remaining items", retry.Items)
+	}
+	if _, err := store.Get(entry.ID); !errors.Is(err, trash.ErrNotFound) {
+		t.Errorf("entry still in trash after restore: %v", err)
+	}
+}
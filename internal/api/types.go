@@ -12,6 +12,8 @@ type StatusResponse struct {
 	Running      bool               `json:"running"`
 	Clients      []proxy.ClientInfo `json:"clients"`
 	PendingCount int                `json:"pending_count"`
+	// Upstreams is the health of each upstream Secret Service.
+	Upstreams []proxy.UpstreamState `json:"upstreams,omitempty"`
 	// Deprecated: use Clients instead. Kept for backward compatibility.
 	Client string `json:"client,omitempty"`
 	// Deprecated: use Clients instead. Kept for backward compatibility.
@@ -28,26 +30,31 @@ type ItemInfo struct {
 	Path       string            `json:"path"`
 	Label      string            `json:"label"`
 	Attributes map[string]string `json:"attributes"`
+	Backend    string            `json:"backend,omitempty"` // upstream holding the item
 }
 
 // ProcessInfo represents a single process in the process chain.
 type ProcessInfo struct {
-	Name string   `json:"name"`
-	PID  uint32   `json:"pid"`
-	Exe  string   `json:"exe,omitempty"`
-	Args []string `json:"args,omitempty"`
-	CWD  string   `json:"cwd,omitempty"`
+	Name      string   `json:"name"`
+	PID       uint32   `json:"pid"`
+	StartTime uint64   `json:"start_time,omitempty"`
+	Exe       string   `json:"exe,omitempty"`
+	Args      []string `json:"args,omitempty"`
+	CWD       string   `json:"cwd,omitempty"`
 }
 
 // SenderInfo contains information about the D-Bus sender process.
 type SenderInfo struct {
-	Sender       string        `json:"sender"`                  // D-Bus unique name (":1.123")
-	PID          uint32        `json:"pid"`                     // Process ID
-	UID          uint32        `json:"uid"`                     // User ID
-	UserName     string        `json:"user_name"`               // Username (may be empty if lookup fails)
-	InvokerName  string        `json:"invoker_name"`            // Invoker process comm (display); spoofable — NOT the systemd unit
-	SystemdUnit  string        `json:"systemd_unit,omitempty"`  // Real systemd unit (from GetUnitByPID); authoritative
-	ProcessChain []ProcessInfo `json:"process_chain,omitempty"` // Full process chain from requestor to init
+	Sender       string                  `json:"sender"`                  // D-Bus unique name (":1.123")
+	PID          uint32                  `json:"pid"`                     // Process ID
+	UID          uint32                  `json:"uid"`                     // User ID
+	UserName     string                  `json:"user_name"`               // Username (may be empty if lookup fails)
+	InvokerName  string                  `json:"invoker_name"`            // Invoker process comm (display); spoofable — NOT the systemd unit
+	SystemdUnit  string                  `json:"systemd_unit,omitempty"`  // Real systemd unit (from GetUnitByPID); authoritative
+	AppID        string                  `json:"app_id,omitempty"`        // Flatpak/Snap application ID; claimable by unsandboxed processes
+	AppSandbox   string                  `json:"app_sandbox,omitempty"`   // "flatpak" or "snap" when AppID is set
+	Container    *approval.ContainerInfo `json:"container,omitempty"`     // Container the caller runs in; claimable by any process of the user
+	ProcessChain []ProcessInfo           `json:"process_chain,omitempty"` // Full process chain from requestor to init
 }
 
 // PendingRequest represents a pending approval request in API responses.
@@ -62,6 +69,34 @@ type PendingRequest struct {
 	SearchAttributes map[string]string     `json:"search_attributes,omitempty"`
 	SenderInfo       SenderInfo            `json:"sender_info"`
 	GPGSignInfo      *approval.GPGSignInfo `json:"gpg_sign_info,omitempty"`
+	PairInfo         *approval.PairInfo    `json:"pair_info,omitempty"`
+	PerItem          bool                  `json:"per_item,omitempty"`       // items may be approved individually
+	ApprovedItems    []string              `json:"approved_items,omitempty"` // paths granted by a partial approval
+}
+
+// ApproveRequest is the optional body of POST /api/v1/pending/{id}/approve.
+// Items lists the paths to grant from a per-item request; omitted, the whole
+// request is approved.
+type ApproveRequest struct {
+	Items []string `json:"items,omitempty"`
+}
+
+// ApproveForProcessRequest is the optional body of
+// POST /api/v1/pending/{id}/approve-for-process. PID picks the process from the
+// request's process chain (0: the requesting process); Subtree also covers its
+// descendants.
+type ApproveForProcessRequest struct {
+	PID     uint32 `json:"pid,omitempty"`
+	Subtree bool   `json:"subtree,omitempty"`
+}
+
+// DenyRequest is the optional body of POST /api/v1/pending/{id}/deny. With
+// ForSeconds or Mute set, a temporary deny rule refuses similar requests (with
+// Mute: every request from the same executable) for ForSeconds, or the default
+// of 15 minutes.
+type DenyRequest struct {
+	ForSeconds int  `json:"for_seconds,omitempty"`
+	Mute       bool `json:"mute,omitempty"`
 }
 
 // ActionResponse is returned by approve/deny endpoints.
@@ -69,6 +104,11 @@ type ActionResponse struct {
 	Status string `json:"status"`
 }
 
+// PatchResponse is the response for GET /api/v1/requests/{id}/patch.
+type PatchResponse struct {
+	Patch string `json:"patch"`
+}
+
 // ErrorResponse is returned on errors.
 type ErrorResponse struct {
 	Error string `json:"error"`
@@ -94,6 +134,7 @@ type HistoryEntry struct {
 	Request    PendingRequest `json:"request"`
 	Resolution string         `json:"resolution"` // approved, denied, expired, cancelled
 	ResolvedAt time.Time      `json:"resolved_at"`
+	Rule       string         `json:"rule,omitempty"` // trust rule that decided the request, if any
 }
 
 // HistoryResponse is returned by GET /api/v1/log.
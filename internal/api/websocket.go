@@ -43,6 +43,15 @@ type WSMessage struct {
 	AutoApproveDurationSeconds int                        `json:"auto_approve_duration_seconds,omitempty"`
 	NotificationDelayMS        int                        `json:"notification_delay_ms,omitempty"`
 
+	// For snapshot and config_error: why the last config reload was rejected
+	ConfigError string `json:"config_error,omitempty"`
+
+	// For snapshot: the health of each upstream Secret Service
+	Upstreams []proxy.UpstreamState `json:"upstreams,omitempty"`
+
+	// For upstream_changed
+	Upstream *proxy.UpstreamState `json:"upstream,omitempty"`
+
 	// For request_created
 	Request *PendingRequest `json:"request,omitempty"`
 
@@ -77,6 +86,7 @@ type WSHandler struct {
 	remoteSocket        string
 	clientName          string
 	notificationDelayMS int
+	upstreams           *proxy.UpstreamMonitor // nil = not reported
 
 	// Active connections
 	connsMu sync.RWMutex
@@ -177,7 +187,7 @@ func (wsc *wsConnection) OnEvent(event approval.Event) {
 			}
 		}
 		msgs = append(msgs, msg)
-		entry := makeHistoryEntry(event.Request, "approved")
+		entry := makeHistoryEntry(event.Request, "approved", event.RuleName)
 		msgs = append(msgs, WSMessage{
 			Type:         "history_entry",
 			HistoryEntry: &entry,
@@ -188,7 +198,7 @@ func (wsc *wsConnection) OnEvent(event approval.Event) {
 			ID:     event.Request.ID,
 			Result: "denied",
 		})
-		entry := makeHistoryEntry(event.Request, "denied")
+		entry := makeHistoryEntry(event.Request, "denied", event.RuleName)
 		msgs = append(msgs, WSMessage{
 			Type:         "history_entry",
 			HistoryEntry: &entry,
@@ -198,7 +208,7 @@ func (wsc *wsConnection) OnEvent(event approval.Event) {
 			Type: "request_expired",
 			ID:   event.Request.ID,
 		})
-		entry := makeHistoryEntry(event.Request, "expired")
+		entry := makeHistoryEntry(event.Request, "expired", event.RuleName)
 		msgs = append(msgs, WSMessage{
 			Type:         "history_entry",
 			HistoryEntry: &entry,
@@ -208,7 +218,7 @@ func (wsc *wsConnection) OnEvent(event approval.Event) {
 			Type: "request_cancelled",
 			ID:   event.Request.ID,
 		})
-		entry := makeHistoryEntry(event.Request, "cancelled")
+		entry := makeHistoryEntry(event.Request, "cancelled", event.RuleName)
 		msgs = append(msgs, WSMessage{
 			Type:         "history_entry",
 			HistoryEntry: &entry,
@@ -228,13 +238,13 @@ func (wsc *wsConnection) OnEvent(event approval.Event) {
 			}
 			msgs = append(msgs, msg)
 		}
-		entry := makeHistoryEntry(event.Request, "auto_approved")
+		entry := makeHistoryEntry(event.Request, "auto_approved", event.RuleName)
 		msgs = append(msgs, WSMessage{
 			Type:         "history_entry",
 			HistoryEntry: &entry,
 		})
 	case approval.EventRequestIgnored:
-		entry := makeHistoryEntry(event.Request, "ignored")
+		entry := makeHistoryEntry(event.Request, "ignored", event.RuleName)
 		msgs = append(msgs, WSMessage{
 			Type:         "history_entry",
 			HistoryEntry: &entry,
@@ -249,6 +259,25 @@ func (wsc *wsConnection) OnEvent(event approval.Event) {
 			Type: "auto_approve_rule_removed",
 			ID:   event.Rule.ID,
 		})
+	case approval.EventTrustConfigReloaded:
+		trustedSigners := wsc.handler.manager.ListTrustedSigners()
+		if trustedSigners == nil {
+			trustedSigners = []approval.TrustedSigner{}
+		}
+		trustRules := wsc.handler.manager.ListTrustRules()
+		if trustRules == nil {
+			trustRules = []approval.TrustRule{}
+		}
+		msgs = append(msgs, WSMessage{
+			Type:           "trust_config_reloaded",
+			TrustedSigners: trustedSigners,
+			TrustRules:     trustRules,
+		})
+	case approval.EventConfigError:
+		msgs = append(msgs, WSMessage{
+			Type:        "config_error",
+			ConfigError: event.Err.Error(),
+		})
 	default:
 		return
 	}
@@ -271,13 +300,14 @@ func (wsc *wsConnection) OnEvent(event approval.Event) {
 }
 
 // makeHistoryEntry creates a HistoryEntry for WebSocket messages.
-func makeHistoryEntry(req *approval.Request, resolution string) HistoryEntry {
+func makeHistoryEntry(req *approval.Request, resolution, rule string) HistoryEntry {
 	items := make([]ItemInfo, len(req.Items))
 	for i, item := range req.Items {
 		items[i] = ItemInfo{
 			Path:       item.Path,
 			Label:      item.Label,
 			Attributes: item.Attributes,
+			Backend:    item.Backend,
 		}
 	}
 	return HistoryEntry{
@@ -291,10 +321,14 @@ func makeHistoryEntry(req *approval.Request, resolution string) HistoryEntry {
 			Type:             string(req.Type),
 			SearchAttributes: req.SearchAttributes,
 			SenderInfo:       convertSenderInfo(req.SenderInfo),
-			GPGSignInfo:      req.GPGSignInfo,
+			GPGSignInfo:      gpgSignSummary(req.GPGSignInfo),
+			PairInfo:         req.PairInfo,
+			PerItem:          req.PerItem,
+			ApprovedItems:    req.ApprovedItems,
 		},
 		Resolution: resolution,
 		ResolvedAt: time.Now(),
+		Rule:       rule,
 	}
 }
 
@@ -355,6 +389,10 @@ func (wsc *wsConnection) sendSnapshot() error {
 		TrustRules:                 trustRules,
 		AutoApproveDurationSeconds: int(h.manager.AutoApproveDuration().Seconds()),
 		NotificationDelayMS:        h.notificationDelayMS,
+		ConfigError:                h.manager.ConfigError(),
+	}
+	if h.upstreams != nil {
+		msg.Upstreams = h.upstreams.States()
 	}
 
 	data, err := json.Marshal(msg)
@@ -451,6 +489,7 @@ func convertRequest(req *approval.Request) *PendingRequest {
 			Path:       item.Path,
 			Label:      item.Label,
 			Attributes: item.Attributes,
+			Backend:    item.Backend,
 		}
 	}
 	return &PendingRequest{
@@ -463,7 +502,10 @@ func convertRequest(req *approval.Request) *PendingRequest {
 		Type:             string(req.Type),
 		SearchAttributes: req.SearchAttributes,
 		SenderInfo:       convertSenderInfo(req.SenderInfo),
-		GPGSignInfo:      req.GPGSignInfo,
+		GPGSignInfo:      gpgSignSummary(req.GPGSignInfo),
+		PairInfo:         req.PairInfo,
+		PerItem:          req.PerItem,
+		ApprovedItems:    req.ApprovedItems,
 	}
 }
 
@@ -513,6 +555,14 @@ func (h *WSHandler) OnClientDisconnected(client proxy.ClientInfo) {
 	h.BroadcastClientDisconnected(client)
 }
 
+// OnUpstreamChanged implements proxy.UpstreamObserver.
+func (h *WSHandler) OnUpstreamChanged(state proxy.UpstreamState) {
+	h.broadcast(WSMessage{
+		Type:     "upstream_changed",
+		Upstream: &state,
+	})
+}
+
 // broadcast sends a message to all connected clients.
 func (h *WSHandler) broadcast(msg WSMessage) {
 	data, err := json.Marshal(msg)
@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -548,6 +549,72 @@ func TestWSHandler_AutoApproveRuleAdded(t *testing.T) {
 	}
 }
 
+func TestWSHandler_TrustConfigReload(t *testing.T) {
+	mgr := approval.NewManager(approval.ManagerConfig{Timeout: 5 * time.Second, HistoryMax: 100})
+	auth, err := NewAuth(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create auth: %v", err)
+	}
+
+	handler := NewWSHandler(mgr, nil, auth, "", "")
+	server := httptest.NewServer(http.HandlerFunc(handler.HandleWS))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, _, err := websocket.Dial(ctx, wsURL, &websocket.DialOptions{
+		HTTPHeader: http.Header{
+			"Cookie": []string{mintSession(t, auth)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "")
+
+	// Read and discard snapshot
+	_, _, _ = conn.Read(ctx)
+
+	readMsg := func() WSMessage {
+		t.Helper()
+		_, data, err := conn.Read(ctx)
+		if err != nil {
+			t.Fatalf("failed to read message: %v", err)
+		}
+		var msg WSMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatalf("failed to parse message: %v", err)
+		}
+		return msg
+	}
+
+	mgr.SetConfigError(errors.New("rule 0: invalid action"))
+	msg := readMsg()
+	if msg.Type != "config_error" {
+		t.Errorf("expected config_error, got %s", msg.Type)
+	}
+	if msg.ConfigError != "rule 0: invalid action" {
+		t.Errorf("expected config error text, got %q", msg.ConfigError)
+	}
+
+	mgr.SetTrustConfig(approval.TrustConfig{Rules: []approval.TrustRule{{Name: "firefox", Action: "approve"}}})
+	msg = readMsg()
+	if msg.Type != "trust_config_reloaded" {
+		t.Errorf("expected trust_config_reloaded, got %s", msg.Type)
+	}
+	if len(msg.TrustRules) != 1 || msg.TrustRules[0].Name != "firefox" {
+		t.Errorf("expected reloaded rule firefox, got %+v", msg.TrustRules)
+	}
+	if msg.TrustedSigners == nil {
+		t.Error("expected trusted_signers to be present")
+	}
+	if mgr.ConfigError() != "" {
+		t.Errorf("successful reload should clear config error, got %q", mgr.ConfigError())
+	}
+}
+
 func TestWSHandler_AutoApproveRuleRemoved(t *testing.T) {
 	mgr := approval.NewManager(approval.ManagerConfig{Timeout: 5 * time.Second, HistoryMax: 100, AutoApproveDuration: 2 * time.Minute})
 	auth, err := NewAuth(t.TempDir())
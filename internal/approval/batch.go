@@ -0,0 +1,207 @@
+package approval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrNotPerItem is returned by ApproveItems for a request whose caller cannot
+// use a partial grant.
+var ErrNotPerItem = errors.New("request cannot be approved per item")
+
+// ErrInvalidItems is returned by ApproveItems when the selection is empty or
+// names an item that is not part of the request.
+var ErrInvalidItems = errors.New("invalid item selection")
+
+// RequireItemsApproval decides a batch of get_secret items one by one and
+// returns the granted subset, in request order. Items covered by the approval
+// cache, an auto-approve rule or an approving trust rule are granted without a
+// prompt; items a temporary deny rule refuses, or a trust rule denies or
+// ignores, are refused; the rest go to the user as one per-item request, which
+// may be approved in full, in part (ApproveItems) or not at all.
+//
+// Each group of items decided the same way without a prompt is recorded in
+// history as its own request. An error is returned only when no item is
+// granted: the prompt's outcome, or ErrDeniedByRule / ErrIgnored when rules
+// refused everything.
+func (m *Manager) RequireItemsApproval(ctx context.Context, client string, items []ItemInfo,
+	session string, senderInfo SenderInfo) ([]ItemInfo, error) {
+	if m.disabled || len(items) == 0 {
+		return items, nil
+	}
+	const reqType = RequestTypeGetSecret
+
+	// group collects the items decided alike without a prompt, for history.
+	type group struct {
+		event    EventType
+		rule     string // trust rule name
+		autoRule string // ephemeral auto-approve rule ID
+		items    []ItemInfo
+	}
+	var groups []*group
+	record := func(event EventType, rule, autoRule string, item ItemInfo) {
+		for _, g := range groups {
+			if g.event == event && g.rule == rule && g.autoRule == autoRule {
+				g.items = append(g.items, item)
+				return
+			}
+		}
+		groups = append(groups, &group{event: event, rule: rule, autoRule: autoRule, items: []ItemInfo{item}})
+	}
+
+	granted := make(map[string]bool, len(items))
+	var prompt []ItemInfo
+	var refusal error
+	for _, item := range items {
+		one := []ItemInfo{item}
+		autoRule := m.checkAutoApproveRules(senderInfo, one, reqType)
+		if autoRule != nil && autoRule.Action == "deny" {
+			refusal = ErrDeniedByRule
+			record(EventRequestDenied, TemporaryDenyRule, autoRule.ID, item)
+			continue
+		}
+		if m.approvalWindow > 0 && m.checkApprovalCache(senderInfo.Sender, one) {
+			granted[item.Path] = true
+			continue
+		}
+		if autoRule != nil {
+			granted[item.Path] = true
+			record(EventRequestAutoApproved, "", autoRule.ID, item)
+			continue
+		}
+		if rule := m.CheckTrustRules(client, senderInfo, one, reqType, nil); rule != nil {
+			switch rule.Action {
+			case "deny":
+				refusal = ErrDeniedByRule
+				record(EventRequestDenied, rule.Label(), "", item)
+			case "ignore":
+				if refusal == nil {
+					refusal = ErrIgnored
+				}
+				record(EventRequestIgnored, rule.Label(), "", item)
+			default:
+				granted[item.Path] = true
+				record(EventRequestAutoApproved, rule.Label(), "", item)
+			}
+			continue
+		}
+		prompt = append(prompt, item)
+	}
+
+	now := time.Now()
+	for _, g := range groups {
+		slog.Info("batch items decided without prompt",
+			"approved", g.event == EventRequestAutoApproved,
+			"rule_name", g.rule,
+			"auto_approve_rule_id", g.autoRule,
+			"items", len(g.items))
+		m.notify(Event{Type: g.event, RuleName: g.rule, Request: &Request{
+			ID:         uuid.New().String(),
+			Client:     client,
+			Items:      g.items,
+			Session:    session,
+			CreatedAt:  now,
+			ExpiresAt:  now,
+			Type:       reqType,
+			SenderInfo: senderInfo,
+		}})
+	}
+
+	if len(prompt) > 0 {
+		req := &Request{
+			ID:         uuid.New().String(),
+			Client:     client,
+			Items:      prompt,
+			Session:    session,
+			CreatedAt:  now,
+			ExpiresAt:  now.Add(m.timeout),
+			Type:       reqType,
+			SenderInfo: senderInfo,
+			PerItem:    len(prompt) > 1,
+			done:       make(chan struct{}),
+		}
+		if _, err := m.await(ctx, req); err != nil {
+			refusal = err
+		} else {
+			for _, item := range req.GrantedItems() {
+				granted[item.Path] = true
+			}
+		}
+	}
+
+	approved := slices.DeleteFunc(slices.Clone(items), func(item ItemInfo) bool { return !granted[item.Path] })
+	if len(approved) == 0 {
+		return nil, refusal
+	}
+	return approved, nil
+}
+
+// GrantedItems returns the items an approval covers: all of them, or for a
+// partial approval the ones in ApprovedItems.
+func (r *Request) GrantedItems() []ItemInfo {
+	if r.ApprovedItems == nil {
+		return r.Items
+	}
+	return slices.DeleteFunc(slices.Clone(r.Items), func(item ItemInfo) bool {
+		return !slices.Contains(r.ApprovedItems, item.Path)
+	})
+}
+
+// ApproveItems approves only the listed items (by path) of a pending per-item
+// request; the rest are refused. Selecting every item is a plain approval.
+func (m *Manager) ApproveItems(id string, paths []string) error {
+	m.mu.Lock()
+	req, ok := m.pending[id]
+	if !ok {
+		m.mu.Unlock()
+		return ErrNotFound
+	}
+	if len(paths) == 0 {
+		m.mu.Unlock()
+		return fmt.Errorf("%w: no items selected", ErrInvalidItems)
+	}
+	var selected []string
+	for _, item := range req.Items {
+		if slices.Contains(paths, item.Path) && !slices.Contains(selected, item.Path) {
+			selected = append(selected, item.Path)
+		}
+	}
+	for _, p := range paths {
+		if !slices.Contains(selected, p) {
+			m.mu.Unlock()
+			return fmt.Errorf("%w: %s is not part of the request", ErrInvalidItems, p)
+		}
+	}
+	partial := len(selected) < len(uniquePaths(req.Items))
+	if partial && !req.PerItem {
+		m.mu.Unlock()
+		return ErrNotPerItem
+	}
+	if partial {
+		req.ApprovedItems = selected
+	}
+	req.result = true
+	delete(m.pending, id)
+	close(req.done)
+	m.mu.Unlock()
+
+	m.notify(Event{Type: EventRequestApproved, Request: req})
+	m.cacheApproval(req)
+	return nil
+}
+
+func uniquePaths(items []ItemInfo) []string {
+	var paths []string
+	for _, item := range items {
+		if !slices.Contains(paths, item.Path) {
+			paths = append(paths, item.Path)
+		}
+	}
+	return paths
+}
@@ -62,7 +62,7 @@ func TestBatchGetSecrets_DenyRuleFiresOnAnyItem(t *testing.T) {
 		itemInCollection("public", "x"),
 		itemInCollection("login", "github-token"),
 	}
-	rule := mgr.CheckTrustRules(SenderInfo{}, batch, RequestTypeGetSecret, nil)
+	rule := mgr.CheckTrustRules("local", SenderInfo{}, batch, RequestTypeGetSecret, nil)
 	require.NotNil(t, rule, "deny rule scoped to 'login' must fire on a batch containing a 'login' item")
 	assert.Equal(t, "deny-login", rule.Name)
 }
@@ -83,14 +83,43 @@ func TestBatchGetSecrets_ApproveRuleRequiresEveryItem(t *testing.T) {
 		itemInCollection("public", "x"),
 		itemInCollection("login", "github-token"),
 	}
-	assert.Nil(t, mgr.CheckTrustRules(SenderInfo{}, mixed, RequestTypeGetSecret, nil),
+	assert.Nil(t, mgr.CheckTrustRules("local", SenderInfo{}, mixed, RequestTypeGetSecret, nil),
 		"approve rule scoped to 'public' must not cover a batch that also reads 'login'")
 
 	allPublic := []ItemInfo{
 		itemInCollection("public", "x"),
 		itemInCollection("public", "y"),
 	}
-	rule := mgr.CheckTrustRules(SenderInfo{}, allPublic, RequestTypeGetSecret, nil)
+	rule := mgr.CheckTrustRules("local", SenderInfo{}, allPublic, RequestTypeGetSecret, nil)
 	require.NotNil(t, rule, "approve rule scoped to 'public' should cover an all-'public' batch")
 	assert.Equal(t, "approve-public", rule.Name)
 }
+
+// TestBatchGetSecrets_BackendRule verifies the backend matcher with the same
+// batch semantics: deny fires if any item is on the backend, approve needs
+// every item there.
+func TestBatchGetSecrets_BackendRule(t *testing.T) {
+	mgr := NewManager(ManagerConfig{Timeout: 5 * time.Second, HistoryMax: 100})
+	mgr.trustRules = []TrustRule{
+		{Name: "deny-work", Action: "deny", Backend: "work"},
+		{Name: "approve-personal", Action: "approve", Backend: "personal"},
+	}
+	onBackend := func(backend, name string) ItemInfo {
+		item := itemInCollection("login", name)
+		item.Backend = backend
+		return item
+	}
+
+	mixed := []ItemInfo{onBackend("personal", "x"), onBackend("work", "y")}
+	rule := mgr.CheckTrustRules("local", SenderInfo{}, mixed, RequestTypeGetSecret, nil)
+	require.NotNil(t, rule)
+	assert.Equal(t, "deny-work", rule.Name)
+
+	personal := []ItemInfo{onBackend("personal", "x"), onBackend("personal", "y")}
+	rule = mgr.CheckTrustRules("local", SenderInfo{}, personal, RequestTypeGetSecret, nil)
+	require.NotNil(t, rule)
+	assert.Equal(t, "approve-personal", rule.Name)
+
+	// Items of unknown origin never match a backend rule.
+	assert.Nil(t, mgr.CheckTrustRules("local", SenderInfo{}, []ItemInfo{itemInCollection("login", "z")}, RequestTypeGetSecret, nil))
+}
@@ -0,0 +1,157 @@
+package approval
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func batchItem(n string, tier string) ItemInfo {
+	return ItemInfo{Path: "/org/freedesktop/secrets/collection/default/" + n, Label: n, Attributes: map[string]string{"tier": tier}}
+}
+
+func newBatchManager() *Manager {
+	return NewManager(ManagerConfig{
+		Timeout:        5 * time.Second,
+		HistoryMax:     100,
+		ApprovalWindow: time.Minute,
+		TrustRules: []TrustRule{
+			{Name: "vault", Action: "deny", Secret: &SecretMatcher{Attributes: map[string]string{"tier": "vault"}}},
+			{Name: "open", Secret: &SecretMatcher{Attributes: map[string]string{"tier": "open"}}},
+		},
+	})
+}
+
+type batchResult struct {
+	items []ItemInfo
+	err   error
+}
+
+func requireItems(mgr *Manager, items []ItemInfo, sender SenderInfo) <-chan batchResult {
+	ch := make(chan batchResult, 1)
+	go func() {
+		got, err := mgr.RequireItemsApproval(context.Background(), "local", items, "/session/1", sender)
+		ch <- batchResult{got, err}
+	}()
+	return ch
+}
+
+func paths(items []ItemInfo) []string {
+	var out []string
+	for _, item := range items {
+		out = append(out, item.Label)
+	}
+	return out
+}
+
+func TestRequireItemsApproval_Partial(t *testing.T) {
+	mgr := newBatchManager()
+	open, vault, a, b := batchItem("1", "open"), batchItem("2", "vault"), batchItem("3", "ask"), batchItem("4", "ask")
+	sender := SenderInfo{Sender: ":1.42"}
+
+	ch := requireItems(mgr, []ItemInfo{open, vault, a, b}, sender)
+	req := waitPending(t, mgr)
+	if !req.PerItem || len(req.Items) != 2 || req.Items[0].Path != a.Path {
+		t.Fatalf("prompt = %+v, want per-item request for the 2 unruled items", req)
+	}
+	if err := mgr.ApproveItems(req.ID, []string{b.Path}); err != nil {
+		t.Fatalf("ApproveItems: %v", err)
+	}
+	r := <-ch
+	if r.err != nil {
+		t.Fatalf("RequireItemsApproval = %v", r.err)
+	}
+	if got := paths(r.items); len(got) != 2 || got[0] != "1" || got[1] != "4" {
+		t.Errorf("granted = %v, want [1 4] in request order", got)
+	}
+
+	// Newest first: the prompt, then the rule groups in item order.
+	history := mgr.History()
+	if len(history) != 3 {
+		t.Fatalf("got %d history entries, want 3", len(history))
+	}
+	if h := history[0]; h.Resolution != ResolutionApproved || len(h.Request.ApprovedItems) != 1 || h.Request.ApprovedItems[0] != b.Path {
+		t.Errorf("prompt entry = %+v, want approved with only item 4", h)
+	}
+	if h := history[1]; h.Resolution != ResolutionDenied || h.Rule != "vault" {
+		t.Errorf("deny entry = %s by %q", h.Resolution, h.Rule)
+	}
+	if h := history[2]; h.Resolution != ResolutionAutoApproved || h.Rule != "open" {
+		t.Errorf("approve entry = %s by %q", h.Resolution, h.Rule)
+	}
+
+	// Only the granted item was cached: a repeat prompts for item 3 alone.
+	ch = requireItems(mgr, []ItemInfo{a, b}, sender)
+	req = waitPending(t, mgr)
+	if len(req.Items) != 1 || req.Items[0].Path != a.Path || req.PerItem {
+		t.Fatalf("repeat prompt = %+v, want item 3 only", req.Items)
+	}
+	if err := mgr.Deny(req.ID); err != nil {
+		t.Fatalf("Deny: %v", err)
+	}
+	if r := <-ch; r.err != nil || len(r.items) != 1 || r.items[0].Path != b.Path {
+		t.Errorf("repeat = %v, %v; want the cached item only", paths(r.items), r.err)
+	}
+}
+
+func TestRequireItemsApproval_AllRefused(t *testing.T) {
+	mgr := newBatchManager()
+
+	_, err := mgr.RequireItemsApproval(context.Background(), "local", []ItemInfo{batchItem("1", "vault"), batchItem("2", "vault")}, "", SenderInfo{})
+	if !errors.Is(err, ErrDeniedByRule) {
+		t.Errorf("all denied by rule = %v, want ErrDeniedByRule", err)
+	}
+
+	ch := requireItems(mgr, []ItemInfo{batchItem("3", "ask"), batchItem("4", "vault")}, SenderInfo{})
+	req := waitPending(t, mgr)
+	if err := mgr.Deny(req.ID); err != nil {
+		t.Fatalf("Deny: %v", err)
+	}
+	if r := <-ch; !errors.Is(r.err, ErrDenied) || r.items != nil {
+		t.Errorf("user denied the rest = %v, %v; want ErrDenied", paths(r.items), r.err)
+	}
+}
+
+func TestApproveItems_Validation(t *testing.T) {
+	mgr := newBatchManager()
+	a, b := batchItem("3", "ask"), batchItem("4", "ask")
+
+	ch := requireItems(mgr, []ItemInfo{a, b}, SenderInfo{})
+	req := waitPending(t, mgr)
+	if err := mgr.ApproveItems(req.ID, nil); !errors.Is(err, ErrInvalidItems) {
+		t.Errorf("empty selection = %v, want ErrInvalidItems", err)
+	}
+	if err := mgr.ApproveItems(req.ID, []string{"/elsewhere"}); !errors.Is(err, ErrInvalidItems) {
+		t.Errorf("foreign item = %v, want ErrInvalidItems", err)
+	}
+	if err := mgr.ApproveItems("nope", []string{a.Path}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown request = %v, want ErrNotFound", err)
+	}
+	// Selecting everything is a plain approval.
+	if err := mgr.ApproveItems(req.ID, []string{b.Path, a.Path}); err != nil {
+		t.Fatalf("ApproveItems(all): %v", err)
+	}
+	if r := <-ch; r.err != nil || len(r.items) != 2 {
+		t.Errorf("full selection = %v, %v; want both items", paths(r.items), r.err)
+	}
+	if h := mgr.History()[0]; h.Request.ApprovedItems != nil {
+		t.Errorf("full selection recorded ApprovedItems %v, want nil", h.Request.ApprovedItems)
+	}
+
+	// A request whose caller expects all-or-nothing cannot be split.
+	a, b = batchItem("5", "ask"), batchItem("6", "ask")
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := mgr.RequireApproval(context.Background(), "local", []ItemInfo{a, b}, "", RequestTypeGetSecret, nil, SenderInfo{})
+		errCh <- err
+	}()
+	req = waitPending(t, mgr)
+	if err := mgr.ApproveItems(req.ID, []string{a.Path}); !errors.Is(err, ErrNotPerItem) {
+		t.Errorf("partial on whole request = %v, want ErrNotPerItem", err)
+	}
+	if err := mgr.Deny(req.ID); err != nil {
+		t.Fatalf("Deny: %v", err)
+	}
+	<-errCh
+}
@@ -0,0 +1,109 @@
+package approval
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// DefaultDenyRuleDuration is how long a temporary deny rule lasts when the
+// caller does not choose (e.g. the notification's "Mute" button).
+const DefaultDenyRuleDuration = 15 * time.Minute
+
+// TemporaryDenyRule is the rule name recorded in history for requests refused
+// by a temporary deny rule.
+const TemporaryDenyRule = "temporary deny"
+
+// ErrUnknownInvoker is returned by AddDenyRule when the requesting executable
+// is unknown, so no rule could ever match it.
+var ErrUnknownInvoker = errors.New("requesting executable is unknown")
+
+// AddDenyRule creates a temporary rule that refuses requests like req, without
+// a prompt, for d (DefaultDenyRuleDuration if d <= 0). It matches like an
+// auto-approve rule, on the invoker executable, request type and the secret's
+// collection and attributes; with mute it matches every request from the
+// executable instead. Returns the rule ID.
+func (m *Manager) AddDenyRule(req *Request, d time.Duration, mute bool) (string, error) {
+	rule, err := m.newDenyRule(req, d, mute)
+	if err != nil {
+		return "", err
+	}
+	return m.insertAutoApproveRule(rule), nil
+}
+
+func (m *Manager) newDenyRule(req *Request, d time.Duration, mute bool) (AutoApproveRule, error) {
+	if d <= 0 {
+		d = DefaultDenyRuleDuration
+	}
+	rule := m.newAutoApproveRule(req, nil)
+	if rule.InvokerExe == "" {
+		return AutoApproveRule{}, ErrUnknownInvoker
+	}
+	rule.Action = "deny"
+	rule.ExpiresAt = time.Now().Add(d)
+	if mute {
+		rule.RequestType = ""
+		rule.Collection = ""
+		rule.Attributes = nil
+	}
+	return rule, nil
+}
+
+// DenyFor denies a pending request and adds a temporary deny rule for similar
+// requests (see AddDenyRule). The request is left pending if no rule can be
+// made for it.
+func (m *Manager) DenyFor(id string, d time.Duration, mute bool) error {
+	req := m.GetPending(id)
+	if req == nil {
+		return ErrNotFound
+	}
+	rule, err := m.newDenyRule(req, d, mute)
+	if err != nil {
+		return err
+	}
+	if err := m.Deny(id); err != nil {
+		return err
+	}
+	ruleID := m.insertAutoApproveRule(rule)
+	slog.Info("request denied with a temporary deny rule",
+		"request_id", id,
+		"rule_id", ruleID,
+		"mute", mute)
+	return nil
+}
+
+// TemporarilyDenied reports whether a temporary deny rule refuses a request
+// that is decided without RequireApproval, such as a search.
+func (m *Manager) TemporarilyDenied(senderInfo SenderInfo, items []ItemInfo, reqType RequestType) bool {
+	rule := m.checkAutoApproveRules(senderInfo, items, reqType)
+	return rule != nil && rule.Action == "deny"
+}
+
+// hasDenyRule reports whether an active temporary deny rule applies to some
+// of the sender's requests of reqType, whichever items they are for.
+func (m *Manager) hasDenyRule(senderInfo SenderInfo, reqType RequestType) bool {
+	m.autoApproveMu.Lock()
+	defer m.autoApproveMu.Unlock()
+	now := time.Now()
+	for i := range m.autoApproveRules {
+		rule := &m.autoApproveRules[i]
+		if rule.Action == "deny" && !rule.expired(now) && rule.matches(senderInfo, reqType) {
+			return true
+		}
+	}
+	return false
+}
+
+// denyCoversAny reports whether a deny rule covers any item of the batch. An
+// empty batch is covered only when the rule has no secret scope.
+func denyCoversAny(rule *AutoApproveRule, items []ItemInfo) bool {
+	if len(items) == 0 {
+		return rule.Collection == "" && len(rule.Attributes) == 0
+	}
+	for _, it := range items {
+		if autoApproveCoversAll(rule, []ItemInfo{it}) {
+			return true
+		}
+	}
+	return false
+}
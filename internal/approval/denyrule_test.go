@@ -0,0 +1,121 @@
+package approval
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDenyFor(t *testing.T) {
+	mgr := NewManager(ManagerConfig{Timeout: 5 * time.Second, HistoryMax: 100})
+	login := []ItemInfo{{Path: "/org/freedesktop/secrets/collection/login/token", Attributes: map[string]string{"service": "gh"}}}
+	gh := testSender("gh", "/usr/bin/gh")
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := mgr.RequireApproval(context.Background(), "local", login, "", RequestTypeGetSecret, nil, gh)
+		done <- err
+	}()
+	req := waitPending(t, mgr)
+	if err := mgr.DenyFor(req.ID, time.Minute, false); err != nil {
+		t.Fatalf("DenyFor: %v", err)
+	}
+	if err := <-done; !errors.Is(err, ErrDenied) {
+		t.Fatalf("prompted request = %v, want ErrDenied", err)
+	}
+
+	rules := mgr.ListAutoApproveRules()
+	if len(rules) != 1 || rules[0].Action != "deny" || rules[0].RequestType != RequestTypeGetSecret {
+		t.Fatalf("rules = %+v, want one get_secret deny rule", rules)
+	}
+	if d := time.Until(rules[0].ExpiresAt); d <= 0 || d > time.Minute {
+		t.Errorf("deny rule expires in %v, want within a minute", d)
+	}
+
+	// The same request is refused without a prompt and recorded.
+	if _, err := mgr.RequireApproval(context.Background(), "local", login, "", RequestTypeGetSecret, nil, gh); !errors.Is(err, ErrDeniedByRule) {
+		t.Fatalf("repeat = %v, want ErrDeniedByRule", err)
+	}
+	if h := mgr.History()[0]; h.Resolution != ResolutionDenied || h.Rule != TemporaryDenyRule {
+		t.Errorf("history = %s by %q, want denied by %q", h.Resolution, h.Rule, TemporaryDenyRule)
+	}
+
+	// Other secrets, other request types and other executables are not covered.
+	other := []ItemInfo{{Path: "/org/freedesktop/secrets/collection/work/token"}}
+	if mgr.checkAutoApproveRules(gh, other, RequestTypeGetSecret) != nil {
+		t.Error("deny rule should keep the secret scope")
+	}
+	if mgr.checkAutoApproveRules(gh, login, RequestTypeWrite) != nil {
+		t.Error("deny rule should keep the request type")
+	}
+	if mgr.checkAutoApproveRules(testSender("git", "/usr/bin/git"), login, RequestTypeGetSecret) != nil {
+		t.Error("deny rule should keep the executable")
+	}
+	// In a batch, one covered item is enough to refuse.
+	if rule := mgr.checkAutoApproveRules(gh, append(other, login...), RequestTypeGetSecret); rule == nil || rule.Action != "deny" {
+		t.Error("deny rule should refuse a batch containing a covered item")
+	}
+}
+
+func TestAddDenyRule_Mute(t *testing.T) {
+	mgr := NewManager(ManagerConfig{Timeout: 5 * time.Second, HistoryMax: 100, ApprovalWindow: time.Minute})
+	noisy := testSender("noisy", "/opt/noisy")
+	item := []ItemInfo{{Path: "/org/freedesktop/secrets/collection/login/a"}}
+	req := &Request{Type: RequestTypeGetSecret, Items: item, SenderInfo: noisy}
+
+	// An approve rule and a cached approval both lose to the mute.
+	mgr.AddAutoApproveRule(req)
+	mgr.CacheItemForSender(noisy.Sender, item[0].Path)
+	if _, err := mgr.AddDenyRule(req, 0, true); err != nil {
+		t.Fatalf("AddDenyRule: %v", err)
+	}
+
+	for _, reqType := range []RequestType{RequestTypeGetSecret, RequestTypeSearch, RequestTypeWrite} {
+		if _, err := mgr.RequireApproval(context.Background(), "local", item, "", reqType, nil, noisy); !errors.Is(err, ErrDeniedByRule) {
+			t.Errorf("%s from a muted executable = %v, want ErrDeniedByRule", reqType, err)
+		}
+	}
+	if rule := mgr.CheckAutoApproveRules(noisy, nil, RequestTypeGPGSign); rule == nil || rule.Action != "deny" {
+		t.Error("mute should cover itemless gpg_sign requests")
+	}
+
+	var deny *AutoApproveRule
+	for _, r := range mgr.ListAutoApproveRules() {
+		if r.Action == "deny" {
+			deny = &r
+		}
+	}
+	if deny == nil || deny.RequestType != "" || deny.Collection != "" {
+		t.Fatalf("deny rule = %+v, want an unscoped mute", deny)
+	}
+	if d := time.Until(deny.ExpiresAt); d <= 14*time.Minute || d > DefaultDenyRuleDuration {
+		t.Errorf("mute expires in %v, want the default %v", d, DefaultDenyRuleDuration)
+	}
+
+	items, err := mgr.RequireItemsApproval(context.Background(), "local", item, "", noisy)
+	if !errors.Is(err, ErrDeniedByRule) || items != nil {
+		t.Errorf("batch from a muted executable = %v, %v; want ErrDeniedByRule", items, err)
+	}
+}
+
+func TestDenyFor_UnknownInvoker(t *testing.T) {
+	mgr := NewManager(ManagerConfig{Timeout: 5 * time.Second, HistoryMax: 100})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := mgr.RequireApproval(context.Background(), "local", []ItemInfo{{Path: "/a"}}, "", RequestTypeGetSecret, nil, SenderInfo{})
+		done <- err
+	}()
+	req := waitPending(t, mgr)
+	if err := mgr.DenyFor(req.ID, time.Minute, true); !errors.Is(err, ErrUnknownInvoker) {
+		t.Fatalf("DenyFor = %v, want ErrUnknownInvoker", err)
+	}
+	if mgr.GetPending(req.ID) == nil {
+		t.Fatal("request should stay pending when no rule can be made")
+	}
+	if err := mgr.Deny(req.ID); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+}
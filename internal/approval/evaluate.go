@@ -0,0 +1,73 @@
+package approval
+
+// Decision is what the config-defined policy does with a request.
+type Decision string
+
+const (
+	DecisionPrompt  Decision = "prompt"  // the user is asked
+	DecisionApprove Decision = "approve" // allowed without a prompt
+	DecisionDeny    Decision = "deny"    // refused without a prompt
+	DecisionIgnore  Decision = "ignore"  // silently dropped
+)
+
+// Evaluation is the result of Evaluate: the decision and what made it.
+type Evaluation struct {
+	Decision Decision `json:"decision"`
+	// Rule names the deciding rule: a trust rule name, "client default: <glob>",
+	// "trusted signer", or "ignore_chrome_dummy_secret". Empty when nothing
+	// matched, which means a prompt (or, for search and unlock, a pass-through).
+	Rule string `json:"rule,omitempty"`
+}
+
+// Evaluate reports what the manager's config-defined policy would do with req,
+// without prompting or recording anything. It follows the same paths as the
+// proxy, the SSH agent and the gpg-sign handler: the Chrome dummy write filter,
+// trust rules and client policies, and for gpg_sign the signing rules and
+// trusted signers. Searches and unlocks are only ever denied by a rule, never
+// prompted; pairing always prompts.
+//
+// Ephemeral auto-approve rules and the approval cache are not consulted: they
+// depend on earlier decisions, not on the configuration being evaluated.
+func (m *Manager) Evaluate(req *Request) Evaluation {
+	if m.disabled {
+		return Evaluation{Decision: DecisionApprove}
+	}
+
+	switch req.Type {
+	case RequestTypePair:
+		return Evaluation{Decision: DecisionPrompt}
+	case RequestTypeGPGSign:
+		if rule := m.CheckSigningRules(req.SenderInfo, req.GPGSignInfo); rule != nil {
+			return ruleEvaluation(rule)
+		}
+		if req.GPGSignInfo != nil && !req.GPGSignInfo.Unverified() && m.CheckTrustedSigner(req.SenderInfo, req.GPGSignInfo.RepoName, req.GPGSignInfo.ChangedFiles) {
+			return Evaluation{Decision: DecisionApprove, Rule: "trusted signer"}
+		}
+		return Evaluation{Decision: DecisionPrompt}
+	}
+
+	if m.ShouldIgnore(req.Items, req.Type) {
+		return Evaluation{Decision: DecisionIgnore, Rule: "ignore_chrome_dummy_secret"}
+	}
+	rule := m.CheckTrustRules(req.Client, req.SenderInfo, req.Items, req.Type, req.SearchAttributes)
+	if req.Type == RequestTypeSearch || req.Type == RequestTypeUnlock {
+		if rule != nil && rule.Action == "deny" {
+			return ruleEvaluation(rule)
+		}
+		return Evaluation{Decision: DecisionApprove}
+	}
+	if rule != nil {
+		return ruleEvaluation(rule)
+	}
+	return Evaluation{Decision: DecisionPrompt}
+}
+
+func ruleEvaluation(rule *TrustRule) Evaluation {
+	switch rule.Action {
+	case "deny":
+		return Evaluation{Decision: DecisionDeny, Rule: rule.Label()}
+	case "ignore":
+		return Evaluation{Decision: DecisionIgnore, Rule: rule.Label()}
+	}
+	return Evaluation{Decision: DecisionApprove, Rule: rule.Label()}
+}
@@ -0,0 +1,104 @@
+package approval
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEvaluate(t *testing.T) {
+	mgr := NewManager(ManagerConfig{
+		Timeout:           5 * time.Second,
+		HistoryMax:        100,
+		IgnoreChromeDummy: true,
+		TrustRules: []TrustRule{
+			{Name: "no-github", Action: "deny", Secret: &SecretMatcher{Attributes: map[string]string{"service": "github"}}},
+			{Name: "git", Process: &ProcessMatcher{Exe: "/usr/bin/git"}},
+		},
+		TrustedSigners: []TrustedSigner{{ExePath: "/usr/bin/nvim"}},
+		ClientPolicies: []ClientPolicy{{Client: "build-*", Default: "deny"}},
+	})
+	git := SenderInfo{ProcessChain: []ProcessInfo{{Name: "git", Exe: "/usr/bin/git"}}}
+	item := func(attrs map[string]string) []ItemInfo {
+		return []ItemInfo{{Path: "/org/freedesktop/secrets/collection/default/1", Attributes: attrs}}
+	}
+
+	tests := []struct {
+		name string
+		req  *Request
+		want Evaluation
+	}{
+		{
+			name: "approve rule",
+			req:  &Request{Client: "local", Type: RequestTypeGetSecret, Items: item(nil), SenderInfo: git},
+			want: Evaluation{Decision: DecisionApprove, Rule: "git"},
+		},
+		{
+			name: "deny rule first",
+			req:  &Request{Client: "local", Type: RequestTypeGetSecret, Items: item(map[string]string{"service": "github"}), SenderInfo: git},
+			want: Evaluation{Decision: DecisionDeny, Rule: "no-github"},
+		},
+		{
+			name: "no rule prompts",
+			req:  &Request{Client: "local", Type: RequestTypeGetSecret, Items: item(nil)},
+			want: Evaluation{Decision: DecisionPrompt},
+		},
+		{
+			name: "client default",
+			req:  &Request{Client: "build-01", Type: RequestTypeGetSecret, Items: item(nil)},
+			want: Evaluation{Decision: DecisionDeny, Rule: "client default: build-*"},
+		},
+		{
+			name: "search passes through",
+			req:  &Request{Client: "local", Type: RequestTypeSearch, SearchAttributes: map[string]string{"service": "x"}},
+			want: Evaluation{Decision: DecisionApprove},
+		},
+		{
+			name: "chrome dummy write",
+			req:  &Request{Client: "local", Type: RequestTypeWrite, Items: item(map[string]string{"xdg:schema": chromeDummySchema})},
+			want: Evaluation{Decision: DecisionIgnore, Rule: "ignore_chrome_dummy_secret"},
+		},
+		{
+			name: "trusted signer",
+			req: &Request{Type: RequestTypeGPGSign, GPGSignInfo: sampleGPGSignInfo(), SenderInfo: SenderInfo{
+				PeerTrusted:  true,
+				ProcessChain: []ProcessInfo{{Name: "nvim", Exe: "/usr/bin/nvim"}},
+			}},
+			want: Evaluation{Decision: DecisionApprove, Rule: "trusted signer"},
+		},
+		{
+			name: "trusted signer refuses unverified commit",
+			req: &Request{Type: RequestTypeGPGSign, GPGSignInfo: unverifiedGPGSignInfo(), SenderInfo: SenderInfo{
+				PeerTrusted:  true,
+				ProcessChain: []ProcessInfo{{Name: "nvim", Exe: "/usr/bin/nvim"}},
+			}},
+			want: Evaluation{Decision: DecisionPrompt},
+		},
+		{
+			name: "broad rule does not sign",
+			req:  &Request{Type: RequestTypeGPGSign, GPGSignInfo: sampleGPGSignInfo(), SenderInfo: git},
+			want: Evaluation{Decision: DecisionPrompt},
+		},
+		{
+			name: "pairing always prompts",
+			req:  &Request{Client: "build-01", Type: RequestTypePair, PairInfo: &PairInfo{Code: "123 456"}},
+			want: Evaluation{Decision: DecisionPrompt},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mgr.Evaluate(tt.req); got != tt.want {
+				t.Errorf("Evaluate = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if mgr.PendingCount() != 0 || len(mgr.History()) != 0 {
+		t.Error("Evaluate must not create requests or history")
+	}
+}
+
+func unverifiedGPGSignInfo() *GPGSignInfo {
+	info := sampleGPGSignInfo()
+	info.Verification = &SignVerification{Problems: []string{"tree 1111 is not in /src/myrepo"}}
+	return info
+}
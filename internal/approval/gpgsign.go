@@ -2,6 +2,9 @@ package approval
 
 import (
 	"errors"
+	"path"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,6 +13,9 @@ import (
 // RequestTypeGPGSign is the request type for GPG commit signing approval requests.
 const RequestTypeGPGSign RequestType = "gpg_sign"
 
+// SSHFormat is the GPGSignInfo.Format of requests signed with an SSH key.
+const SSHFormat = "ssh"
+
 // GPGSignInfo carries the signing context for a gpg_sign approval request.
 // All fields are supplied by the thin client; CommitObject is the raw object
 // bytes (UTF-8 text) that the daemon feeds to real gpg's stdin on approval.
@@ -21,14 +27,21 @@ const RequestTypeGPGSign RequestType = "gpg_sign"
 // message / pushed ref-update lines — while Kind and the kind-specific fields
 // below let the approval UI label them correctly. Committer and ParentHash are
 // commit-only; TagName/Target are tag-only; Pushee is push-only.
+//
+// With Format SSHFormat (git's gpg.format=ssh) the object is signed by
+// ssh-keygen instead of gpg: KeyID is the absolute path of the key file git
+// passed and Fingerprint its SHA256 fingerprint, recomputed by the daemon from
+// SSHKey, the file as the daemon read it when the request arrived.
 type GPGSignInfo struct {
 	RepoName     string   `json:"repo_name"`
 	Kind         string   `json:"kind,omitempty"`
+	Format       string   `json:"format,omitempty"` // "" (OpenPGP) or SSHFormat
 	CommitMsg    string   `json:"commit_msg"`
 	Author       string   `json:"author"`
 	Committer    string   `json:"committer"`
 	KeyID        string   `json:"key_id"`
 	Fingerprint  string   `json:"fingerprint,omitempty"`
+	UseAgent     bool     `json:"use_agent,omitempty"` // ssh-keygen -U: KeyID is a public key held by the agent
 	ChangedFiles []string `json:"changed_files"`
 	ParentHash   string   `json:"parent_hash,omitempty"`
 	TagName      string   `json:"tag_name,omitempty"`
@@ -36,14 +49,72 @@ type GPGSignInfo struct {
 	Pushee       string   `json:"pushee,omitempty"`
 	// CommitObject is the raw signed object bytes (UTF-8 text) fed to gpg's stdin.
 	CommitObject string `json:"commit_object,omitempty"`
+	// Diff is what a signed commit changes against its first parent (commit
+	// only; nil when the client could not compute it).
+	Diff *CommitDiff `json:"diff,omitempty"`
+	// Verification is the daemon's own check of the request against the
+	// caller's repository (commits and tags; nil when not checked).
+	Verification *SignVerification `json:"verification,omitempty"`
+	// Series is the rebase, cherry-pick, revert or am the commit is part of,
+	// read by the daemon from the caller's repository (nil when none).
+	Series *SignSeries `json:"series,omitempty"`
+	// SSHKey is the content of the key file KeyID names, read once by the
+	// daemon: ssh-keygen signs with a copy of it, not with the file, which
+	// the caller could replace after the approval. Never serialized, as it
+	// may be a private key.
+	SSHKey []byte `json:"-"`
+}
+
+// SignVerification records whether a signing request corresponds to the
+// repository its caller runs in: that the repository is the one RepoName
+// claims, that it holds the objects the payload references, and (commits) that
+// ChangedFiles are what the tree changes. Problems lists each mismatch for the
+// prompt.
+type SignVerification struct {
+	Repo     string   `json:"repo,omitempty"` // toplevel of the caller's repository
+	Verified bool     `json:"verified"`
+	Problems []string `json:"problems,omitempty"`
+}
+
+// Unverified reports whether the daemon checked the request against the
+// caller's repository and found a mismatch. Such requests are never
+// auto-approved.
+func (info *GPGSignInfo) Unverified() bool {
+	return info.Verification != nil && !info.Verification.Verified
+}
+
+// CommitDiff is the change a signed commit makes, computed by the thin client
+// from the commit's tree and its first parent's in the object database, so it
+// is exactly what the signature covers rather than the live index.
+type CommitDiff struct {
+	// Files lists at most the client's file limit; TotalFiles, Additions and
+	// Deletions always cover the whole commit.
+	Files      []DiffFile `json:"files"`
+	TotalFiles int        `json:"total_files"`
+	Additions  int        `json:"additions"`
+	Deletions  int        `json:"deletions"`
+	// Patch is the unified diff, cut at a line boundary when it exceeds the
+	// client's size limit (Truncated is then set, as it is when Files is cut).
+	Patch     string `json:"patch,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// DiffFile is one changed file of a CommitDiff. Binary files have no line
+// counts.
+type DiffFile struct {
+	Path      string `json:"path"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+	Binary    bool   `json:"binary,omitempty"`
 }
 
 // RecordAutoApprovedGPGSign creates a resolved gpg_sign request for history and
 // WebSocket delivery WITHOUT firing EventRequestCreated (so no desktop notification
 // appears). Fires EventRequestAutoApproved so the history entry shows "auto_approved".
 // The request is never added to pending and no timeout goroutine is started.
-// sig and status are the gpg output to deliver to the thin client via WebSocket.
-func (m *Manager) RecordAutoApprovedGPGSign(client string, info *GPGSignInfo, senderInfo SenderInfo, sig, status []byte) (string, error) {
+// sig and status are the gpg output to deliver to the thin client via WebSocket;
+// ruleName names the approving trust rule, if any.
+func (m *Manager) RecordAutoApprovedGPGSign(client string, info *GPGSignInfo, senderInfo SenderInfo, sig, status []byte, ruleName string) (string, error) {
 	if info == nil {
 		return "", errors.New("gpg sign info is required")
 	}
@@ -62,7 +133,32 @@ func (m *Manager) RecordAutoApprovedGPGSign(client string, info *GPGSignInfo, se
 		done:        make(chan struct{}),
 	}
 	close(req.done)
-	m.notify(Event{Type: EventRequestAutoApproved, Request: req})
+	m.notify(Event{Type: EventRequestAutoApproved, Request: req, RuleName: ruleName})
+	return req.ID, nil
+}
+
+// RecordDeniedGPGSign creates a resolved gpg_sign request denied by the named
+// trust rule, firing EventRequestDenied so the waiting thin client gets its answer
+// and the denial appears in history. Like RecordAutoApprovedGPGSign it never
+// enters pending.
+func (m *Manager) RecordDeniedGPGSign(client string, info *GPGSignInfo, senderInfo SenderInfo, ruleName string) (string, error) {
+	if info == nil {
+		return "", errors.New("gpg sign info is required")
+	}
+
+	now := time.Now()
+	req := &Request{
+		ID:          uuid.New().String(),
+		Client:      client,
+		CreatedAt:   now,
+		ExpiresAt:   now,
+		Type:        RequestTypeGPGSign,
+		GPGSignInfo: info,
+		SenderInfo:  senderInfo,
+		done:        make(chan struct{}),
+	}
+	close(req.done)
+	m.notify(Event{Type: EventRequestDenied, Request: req, RuleName: ruleName})
 	return req.ID, nil
 }
 
@@ -107,3 +203,132 @@ func (m *Manager) CreateGPGSignRequest(client string, info *GPGSignInfo, senderI
 	}()
 	return req.ID, nil
 }
+
+// signingClient is the client name gpg_sign requests are matched under. The
+// client field of a signing request is chosen by the caller, so rules see the
+// local signing socket instead.
+const signingClient = "local"
+
+// CheckSigningRules returns the first trust rule that decides a gpg_sign
+// request, or nil if none does and the request should be prompted.
+//
+// Only rules that opt in to signing are considered — a signing matcher or an
+// explicit "gpg_sign" in request_types — so a broad secret-access rule never
+// starts signing commits. Client policies do not apply to signing.
+//
+// Approve rules additionally require senderInfo.PeerTrusted, for the same
+// reason as CheckTrustedSigner: repo and changed files come from the client and
+// are only trustworthy when our own gpg-sign helper computed them. They are
+// also skipped for requests that failed verification (see Unverified). Deny
+// rules fire regardless.
+func (m *Manager) CheckSigningRules(senderInfo SenderInfo, info *GPGSignInfo) *TrustRule {
+	if info == nil {
+		return nil
+	}
+	m.trustMu.RLock()
+	trustRules := m.trustRules
+	m.trustMu.RUnlock()
+
+	for i := range trustRules {
+		rule := &trustRules[i]
+		if rule.Signing == nil && !slices.Contains(rule.RequestTypes, string(RequestTypeGPGSign)) {
+			continue
+		}
+		if rule.Secret != nil || rule.SSH != nil || len(rule.SearchAttributes) > 0 {
+			continue
+		}
+		if !matchTrustRule(rule, signingClient, senderInfo, nil, RequestTypeGPGSign, nil) {
+			continue
+		}
+		restrictive := rule.Action == "deny" || rule.Action == "ignore"
+		if !restrictive && (!senderInfo.PeerTrusted || info.Unverified()) {
+			continue
+		}
+		if rule.Signing != nil && !matchSigning(rule.Signing, info, restrictive) {
+			continue
+		}
+		return rule
+	}
+	return nil
+}
+
+// matchSigning checks a gpg_sign request against the signing matcher. Files
+// follow the any/every semantics of matchSecret: a deny fires if any changed
+// file is in scope, an approve only if every one is.
+func matchSigning(sm *SigningMatcher, info *GPGSignInfo, restrictive bool) bool {
+	if sm.Repo != "" {
+		if ok, _ := path.Match(sm.Repo, info.RepoName); !ok {
+			return false
+		}
+	}
+	if sm.Kind != "" && sm.Kind != signingKind(info) {
+		return false
+	}
+	if sm.KeyID != "" {
+		pattern := strings.ToUpper(sm.KeyID)
+		keyOK, _ := path.Match(pattern, strings.ToUpper(info.KeyID))
+		fprOK, _ := path.Match(pattern, strings.ToUpper(info.Fingerprint))
+		if !keyOK && !(fprOK && info.Fingerprint != "") {
+			return false
+		}
+	}
+	if sm.Author != "" {
+		if ok, _ := path.Match(sm.Author, signerIdentity(info.Author)); !ok {
+			return false
+		}
+	}
+	if sm.TagName != "" {
+		if ok, _ := path.Match(sm.TagName, info.TagName); !ok {
+			return false
+		}
+	}
+	if len(sm.Files) > 0 {
+		if restrictive {
+			return slices.ContainsFunc(info.ChangedFiles, func(f string) bool { return matchFile(sm.Files, f) })
+		}
+		if len(info.ChangedFiles) == 0 {
+			return false
+		}
+		for _, f := range info.ChangedFiles {
+			if !matchFile(sm.Files, f) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// signingKind returns the payload kind, defaulting to "commit" for requests
+// from clients that predate tag and push signing.
+func signingKind(info *GPGSignInfo) string {
+	if info.Kind == "" {
+		return "commit"
+	}
+	return info.Kind
+}
+
+// signerIdentity strips the timestamp git appends to an author, tagger or
+// pusher line, leaving "Name <email>".
+func signerIdentity(author string) string {
+	if i := strings.LastIndexByte(author, '>'); i >= 0 {
+		return author[:i+1]
+	}
+	return author
+}
+
+// matchFile reports whether file matches any of the patterns. A pattern ending
+// in "/**" matches every path below that directory.
+func matchFile(patterns []string, file string) bool {
+	for _, p := range patterns {
+		if dir, ok := strings.CutSuffix(p, "/**"); ok {
+			if strings.HasPrefix(file, dir+"/") {
+				return true
+			}
+			continue
+		}
+		if ok, _ := path.Match(p, file); ok {
+			return true
+		}
+	}
+	return false
+}
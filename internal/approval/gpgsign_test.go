@@ -308,7 +308,7 @@ func TestRecordAutoApprovedGPGSign_Resolution(t *testing.T) {
 	obs := &testObserver{}
 	mgr.Subscribe(obs)
 
-	id, err := mgr.RecordAutoApprovedGPGSign("test-client", sampleGPGSignInfo(), SenderInfo{}, []byte("sig"), []byte("status"))
+	id, err := mgr.RecordAutoApprovedGPGSign("test-client", sampleGPGSignInfo(), SenderInfo{}, []byte("sig"), []byte("status"), "")
 	if err != nil {
 		t.Fatalf("RecordAutoApprovedGPGSign returned unexpected error: %v", err)
 	}
@@ -340,3 +340,98 @@ func TestRecordAutoApprovedGPGSign_Resolution(t *testing.T) {
 		t.Errorf("expected resolution %q, got %q", ResolutionAutoApproved, history[0].Resolution)
 	}
 }
+
+func TestCheckSigningRules(t *testing.T) {
+	mgr := NewManager(ManagerConfig{
+		Timeout:    5 * time.Second,
+		HistoryMax: 100,
+		TrustRules: []TrustRule{
+			// A broad rule without gpg_sign must never sign.
+			{Name: "nvim-secrets", Process: &ProcessMatcher{Exe: "/usr/bin/nvim"}},
+			{Name: "no-release-tags", Action: "deny", Signing: &SigningMatcher{Kind: "tag", TagName: "v*"}},
+			{Name: "no-ci-changes", Action: "deny", Signing: &SigningMatcher{Files: []string{".github/**"}}},
+			{
+				Name:    "myrepo-alice",
+				Process: &ProcessMatcher{Exe: "/usr/bin/nvim"},
+				Signing: &SigningMatcher{Repo: "my*", Author: "* <alice@example.com>", KeyID: "abcd*", Files: []string{"*.go", "docs/**"}},
+			},
+		},
+	})
+	trusted := SenderInfo{PeerTrusted: true, ProcessChain: []ProcessInfo{{Name: "git", Exe: "/usr/bin/git"}, {Name: "nvim", Exe: "/usr/bin/nvim"}}}
+	commit := func(mod func(*GPGSignInfo)) *GPGSignInfo {
+		info := sampleGPGSignInfo()
+		info.Kind = "commit"
+		info.Author = "Alice <alice@example.com> 1700000000 +0100"
+		info.ChangedFiles = []string{"main.go", "docs/guide/intro.md"}
+		if mod != nil {
+			mod(info)
+		}
+		return info
+	}
+
+	if rule := mgr.CheckSigningRules(trusted, commit(nil)); rule == nil || rule.Name != "myrepo-alice" {
+		t.Errorf("matching commit: expected myrepo-alice, got %v", rule)
+	}
+	untrusted := trusted
+	untrusted.PeerTrusted = false
+	if rule := mgr.CheckSigningRules(untrusted, commit(nil)); rule != nil {
+		t.Errorf("approve rule must require a trusted peer, got %v", rule.Name)
+	}
+	mismatch := commit(func(i *GPGSignInfo) {
+		i.Verification = &SignVerification{Problems: []string{"claims repository \"myrepo\" but the caller is in \"other\""}}
+	})
+	if rule := mgr.CheckSigningRules(trusted, mismatch); rule != nil {
+		t.Errorf("approve rule must not match an unverified request, got %v", rule.Name)
+	}
+	if rule := mgr.CheckSigningRules(trusted, commit(func(i *GPGSignInfo) { i.Verification = &SignVerification{Verified: true} })); rule == nil {
+		t.Error("verified commit: expected myrepo-alice")
+	}
+	if rule := mgr.CheckSigningRules(trusted, commit(func(i *GPGSignInfo) { i.Author = "Mallory <m@evil.test> 1 +0000" })); rule != nil {
+		t.Errorf("other author: expected no rule, got %v", rule.Name)
+	}
+	if rule := mgr.CheckSigningRules(trusted, commit(func(i *GPGSignInfo) { i.KeyID = "FFFF0000" })); rule != nil {
+		t.Errorf("other key: expected no rule, got %v", rule.Name)
+	}
+	// Approve needs every file in scope.
+	if rule := mgr.CheckSigningRules(trusted, commit(func(i *GPGSignInfo) { i.ChangedFiles = append(i.ChangedFiles, "Makefile") })); rule != nil {
+		t.Errorf("out-of-scope file: expected no rule, got %v", rule.Name)
+	}
+	// Deny fires on any file in scope, for any caller.
+	if rule := mgr.CheckSigningRules(SenderInfo{}, commit(func(i *GPGSignInfo) { i.ChangedFiles = append(i.ChangedFiles, ".github/workflows/ci.yml") })); rule == nil || rule.Name != "no-ci-changes" {
+		t.Errorf("ci change: expected no-ci-changes, got %v", rule)
+	}
+	tag := commit(func(i *GPGSignInfo) { i.Kind = "tag"; i.TagName = "v1.2.0"; i.ChangedFiles = nil })
+	if rule := mgr.CheckSigningRules(trusted, tag); rule == nil || rule.Action != "deny" {
+		t.Errorf("release tag: expected deny, got %v", rule)
+	}
+
+	// Signing rules never apply to other request types.
+	if rule := mgr.CheckTrustRules("local", trusted, nil, RequestTypeGetSecret, nil); rule == nil || rule.Name != "nvim-secrets" {
+		t.Errorf("get_secret: expected nvim-secrets, got %v", rule)
+	}
+	mgr.SetTrustConfig(TrustConfig{Rules: []TrustRule{{Name: "sign-only", Signing: &SigningMatcher{}}}})
+	if rule := mgr.CheckTrustRules("local", trusted, nil, RequestTypeGetSecret, nil); rule != nil {
+		t.Errorf("signing rule matched get_secret: %v", rule.Name)
+	}
+}
+
+func TestRecordDeniedGPGSign(t *testing.T) {
+	mgr := NewManager(ManagerConfig{Timeout: 5 * time.Second, HistoryMax: 100})
+	obs := &testObserver{}
+	mgr.Subscribe(obs)
+
+	id, err := mgr.RecordDeniedGPGSign("test-client", sampleGPGSignInfo(), SenderInfo{}, "no-sign")
+	if err != nil {
+		t.Fatalf("RecordDeniedGPGSign returned unexpected error: %v", err)
+	}
+	ev := findEvent(obs.WaitForEvents(1, time.Second), EventRequestDenied)
+	if ev == nil || ev.Request.ID != id {
+		t.Fatalf("expected EventRequestDenied for %s, got %v", id, ev)
+	}
+	if mgr.PendingCount() != 0 {
+		t.Errorf("expected 0 pending requests, got %d", mgr.PendingCount())
+	}
+	if h := mgr.History(); len(h) != 1 || h[0].Rule != "no-sign" {
+		t.Errorf("expected history entry naming rule no-sign, got %+v", h)
+	}
+}
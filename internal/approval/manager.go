@@ -13,6 +13,7 @@ import (
 
 	"github.com/google/uuid"
 	dbustypes "github.com/nikicat/secrets-dispatcher/internal/dbus"
+	"github.com/nikicat/secrets-dispatcher/internal/procutil"
 )
 
 // ErrDenied is returned when a request is denied by the user.
@@ -43,6 +44,8 @@ const (
 	EventRequestIgnored
 	EventAutoApproveRuleAdded
 	EventAutoApproveRuleRemoved
+	EventTrustConfigReloaded
+	EventConfigError
 )
 
 // Event represents an approval event for observers.
@@ -50,6 +53,10 @@ type Event struct {
 	Type    EventType
 	Request *Request
 	Rule    *AutoApproveRule // For EventAutoApproveRuleAdded/Removed
+	Err     error            // For EventConfigError
+	// RuleName names the config trust rule (or client default) that decided a
+	// request without a prompt; recorded on the history entry.
+	RuleName string
 }
 
 // Observer receives notifications about approval events.
@@ -62,6 +69,7 @@ type ItemInfo struct {
 	Path       string            `json:"path"`
 	Label      string            `json:"label"`
 	Attributes map[string]string `json:"attributes"`
+	Backend    string            `json:"backend,omitempty"` // name of the upstream holding the item
 }
 
 // RequestType indicates the type of secret access request.
@@ -74,8 +82,34 @@ const (
 	RequestTypeWrite     RequestType = "write"
 	RequestTypeSSHSign   RequestType = "ssh_sign"
 	RequestTypeUnlock    RequestType = "unlock"
+
+	// SSH agent key management. Lock covers unlock as well; extension covers
+	// vendor extensions other than the informational ones OpenSSH sends itself.
+	RequestTypeSSHAdd       RequestType = "ssh_add"
+	RequestTypeSSHRemove    RequestType = "ssh_remove"
+	RequestTypeSSHLock      RequestType = "ssh_lock"
+	RequestTypeSSHExtension RequestType = "ssh_extension"
+
+	// gpg-agent private key operations. Export covers EXPORT_KEY, which hands
+	// out the secret key itself.
+	RequestTypePGPDecrypt RequestType = "pgp_decrypt"
+	RequestTypePGPSign    RequestType = "pgp_sign"
+	RequestTypePGPExport  RequestType = "pgp_export"
 )
 
+// alwaysPrompts reports whether requests of type t change state, or hand out a
+// secret key, and so must never be satisfied from the approval cache, which is
+// keyed only on sender and item path.
+func alwaysPrompts(t RequestType) bool {
+	switch t {
+	case RequestTypeDelete, RequestTypeWrite,
+		RequestTypeSSHAdd, RequestTypeSSHRemove, RequestTypeSSHLock, RequestTypeSSHExtension,
+		RequestTypePGPExport:
+		return true
+	}
+	return false
+}
+
 // Request represents a secret access request awaiting approval.
 type Request struct {
 	ID        string     `json:"id"`
@@ -97,6 +131,16 @@ type Request struct {
 	// GPGSignInfo contains signing context for gpg_sign requests; nil for other types.
 	GPGSignInfo *GPGSignInfo `json:"gpg_sign_info,omitempty"`
 
+	// PairInfo contains the key fingerprint and comparison code for pair requests.
+	PairInfo *PairInfo `json:"pair_info,omitempty"`
+
+	// PerItem marks a batched get_secret request whose items may be approved
+	// individually (ApproveItems).
+	PerItem bool `json:"per_item,omitempty"`
+	// ApprovedItems lists the paths granted by a partial approval; nil when the
+	// request was approved (or denied) as a whole.
+	ApprovedItems []string `json:"approved_items,omitempty"`
+
 	// Signature holds the ASCII-armored PGP signature bytes produced by real gpg
 	// on approval of a gpg_sign request. Set by ApproveWithSignature.
 	Signature []byte `json:"-"`
@@ -129,6 +173,36 @@ type HistoryEntry struct {
 	Request    *Request   `json:"request"`
 	Resolution Resolution `json:"resolution"`
 	ResolvedAt time.Time  `json:"resolved_at"`
+	Rule       string     `json:"rule,omitempty"` // the trust rule that decided the request, if any
+}
+
+// HistoryStore persists resolved requests beyond the in-memory history window,
+// so the audit trail survives restarts and is not capped by HistoryMax.
+type HistoryStore interface {
+	// Append durably records a resolved request.
+	Append(HistoryEntry) error
+	// Query returns the entries resolved within q's time range, newest first.
+	Query(q HistoryQuery) ([]HistoryEntry, error)
+	// Find returns the entry for a request ID, or nil if there is none.
+	Find(id string) (*HistoryEntry, error)
+}
+
+// HistoryQuery selects a page of history by resolution time.
+type HistoryQuery struct {
+	Since time.Time // inclusive lower bound; zero means unbounded
+	Until time.Time // exclusive upper bound; zero means unbounded
+	Limit int       // maximum number of entries; 0 means no limit
+}
+
+// Contains reports whether an entry resolved at t falls within the query's time range.
+func (q HistoryQuery) Contains(t time.Time) bool {
+	if !q.Since.IsZero() && t.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !t.Before(q.Until) {
+		return false
+	}
+	return true
 }
 
 // TrustedSigner defines a process auto-approved for GPG signing.
@@ -145,14 +219,29 @@ type TrustedSigner struct {
 // process's /proc/PID/exe path. InvokerName holds the caller's comm and is
 // retained for display/logging only — it is attacker-controllable
 // (prctl(PR_SET_NAME)) and must never be the basis for a match.
+//
+// A rule with a Process scope matches on that process instance instead of
+// InvokerExe and has no ExpiresAt: it lasts until the process exits.
+//
+// With Action "deny" the rule is a temporary deny rule (AddDenyRule): matching
+// requests are refused without a prompt. A deny rule without a RequestType
+// mutes the executable, matching any request it makes.
 type AutoApproveRule struct {
 	ID          string            `json:"id"`
+	Action      string            `json:"action,omitempty"` // "" (approve) or "deny"
 	InvokerName string            `json:"invoker_name"`
 	InvokerExe  string            `json:"invoker_exe,omitempty"`
 	RequestType RequestType       `json:"request_type"`
 	Collection  string            `json:"collection"`
 	Attributes  map[string]string `json:"attributes,omitempty"`
-	ExpiresAt   time.Time         `json:"expires_at"`
+	ExpiresAt   time.Time         `json:"expires_at,omitzero"`
+	Process     *ProcessScope     `json:"process,omitempty"`
+}
+
+// expired reports whether a timed rule has run out at now. Process-scoped
+// rules never expire; they are removed when their process exits.
+func (r *AutoApproveRule) expired(now time.Time) bool {
+	return !r.ExpiresAt.IsZero() && !r.ExpiresAt.After(now)
 }
 
 // Manager tracks pending approval requests and handles blocking until decision.
@@ -165,9 +254,10 @@ type Manager struct {
 	observersMu sync.RWMutex
 	observers   map[Observer]struct{}
 
-	historyMu  sync.RWMutex
-	history    []HistoryEntry
-	historyMax int
+	historyMu    sync.RWMutex
+	history      []HistoryEntry
+	historyMax   int
+	historyStore HistoryStore // nil = in-memory history only
 
 	approvalWindow time.Duration
 	cacheMu        sync.Mutex
@@ -176,9 +266,30 @@ type Manager struct {
 	autoApproveMu       sync.Mutex
 	autoApproveRules    []AutoApproveRule
 	autoApproveDuration time.Duration
-	trustedSigners      []TrustedSigner // exe+repo combos auto-approved for gpg_sign
-	ignoreChromeDummy   bool
-	trustRules          []TrustRule // persistent config-defined trust rules
+	processWatching     bool // reapProcessRules is running
+	// processStartTime and processCheckInterval drive reapProcessRules;
+	// fields so tests can simulate process exit.
+	processStartTime     func(pid uint32) uint64
+	processCheckInterval time.Duration
+	ignoreChromeDummy    bool
+
+	seriesMu     sync.Mutex
+	seriesGrants []SeriesGrant // approvals of the rest of a signing series
+
+	// trustMu guards the config-defined rules, which SetTrustConfig swaps on a
+	// config reload. The slices are replaced, never mutated in place, so a
+	// *TrustRule returned by CheckTrustRules stays valid after a swap.
+	trustMu        sync.RWMutex
+	trustedSigners []TrustedSigner // exe+repo combos auto-approved for gpg_sign
+	trustRules     []TrustRule     // persistent config-defined trust rules
+	clientPolicies []ClientPolicy  // per-client fallback when no trust rule matches
+	configError    string          // last config reload failure; "" when the loaded config is current
+
+	statsMu     sync.Mutex
+	ruleStats   map[string]*RuleStat // keyed by trust rule label
+	statsStore  RuleStatsStore       // nil = statistics are not persisted
+	statsTimer  *time.Timer          // pending debounced save
+	statsSaveMu sync.Mutex           // serializes saves
 }
 
 // ManagerConfig holds configuration for the approval Manager.
@@ -199,31 +310,57 @@ type ManagerConfig struct {
 	IgnoreChromeDummy bool
 	// TrustRules are persistent config-defined rules for auto-approve/ignore.
 	TrustRules []TrustRule
+	// ClientPolicies set the per-client default when no trust rule matches.
+	ClientPolicies []ClientPolicy
+	// HistoryStore, when set, receives every resolved request and seeds the
+	// in-memory history with its most recent HistoryMax entries at startup.
+	HistoryStore HistoryStore
+	// RuleStatsStore, when set, persists the per-rule hit statistics.
+	RuleStatsStore RuleStatsStore
 }
 
 // NewManager creates a new approval manager.
 func NewManager(cfg ManagerConfig) *Manager {
-	return &Manager{
-		pending:             make(map[string]*Request),
-		timeout:             cfg.Timeout,
-		observers:           make(map[Observer]struct{}),
-		historyMax:          cfg.HistoryMax,
-		approvalWindow:      cfg.ApprovalWindow,
-		cache:               make(map[string]time.Time),
-		autoApproveDuration: cfg.AutoApproveDuration,
-		trustedSigners:      cfg.TrustedSigners,
-		ignoreChromeDummy:   cfg.IgnoreChromeDummy,
-		trustRules:          cfg.TrustRules,
+	m := &Manager{
+		pending:              make(map[string]*Request),
+		timeout:              cfg.Timeout,
+		observers:            make(map[Observer]struct{}),
+		historyMax:           cfg.HistoryMax,
+		historyStore:         cfg.HistoryStore,
+		approvalWindow:       cfg.ApprovalWindow,
+		cache:                make(map[string]time.Time),
+		autoApproveDuration:  cfg.AutoApproveDuration,
+		processStartTime:     readProcessStartTime,
+		processCheckInterval: processScopeCheckInterval,
+		trustedSigners:       cfg.TrustedSigners,
+		ignoreChromeDummy:    cfg.IgnoreChromeDummy,
+		trustRules:           cfg.TrustRules,
+		clientPolicies:       cfg.ClientPolicies,
+		ruleStats:            make(map[string]*RuleStat),
+		statsStore:           cfg.RuleStatsStore,
+	}
+	m.loadRuleStats()
+	m.trackRules(cfg.TrustRules)
+	if m.historyStore != nil {
+		entries, err := m.historyStore.Query(HistoryQuery{Limit: m.historyMax})
+		if err != nil {
+			slog.Warn("failed to load persisted history", "error", err)
+		}
+		m.history = entries
 	}
+	return m
 }
 
 // NewDisabledManager creates a manager that auto-approves all requests.
 func NewDisabledManager() *Manager {
 	return &Manager{
-		pending:    make(map[string]*Request),
-		disabled:   true,
-		observers:  make(map[Observer]struct{}),
-		historyMax: 100,
+		pending:              make(map[string]*Request),
+		disabled:             true,
+		observers:            make(map[Observer]struct{}),
+		historyMax:           100,
+		ruleStats:            make(map[string]*RuleStat),
+		processStartTime:     readProcessStartTime,
+		processCheckInterval: processScopeCheckInterval,
 	}
 }
 
@@ -250,9 +387,7 @@ func (m *Manager) notify(event Event) {
 	}
 
 	// Record history for terminal request events (not rule-management events)
-	if event.Type != EventRequestCreated &&
-		event.Type != EventAutoApproveRuleAdded &&
-		event.Type != EventAutoApproveRuleRemoved {
+	if event.Request != nil && event.Type != EventRequestCreated {
 		m.addHistory(event)
 	}
 }
@@ -281,6 +416,10 @@ func (m *Manager) addHistory(event Event) {
 		Request:    event.Request,
 		Resolution: resolution,
 		ResolvedAt: time.Now(),
+		Rule:       event.RuleName,
+	}
+	if entry.Rule != "" {
+		m.recordRuleHit(entry.Rule, entry.Request, entry.ResolvedAt)
 	}
 
 	m.historyMu.Lock()
@@ -293,6 +432,14 @@ func (m *Manager) addHistory(event Event) {
 	if len(m.history) > m.historyMax {
 		m.history = m.history[:m.historyMax]
 	}
+
+	// Write through while still holding historyMu so the on-disk order matches
+	// the in-memory order.
+	if m.historyStore != nil {
+		if err := m.historyStore.Append(entry); err != nil {
+			slog.Warn("failed to persist history entry", "request_id", entry.Request.ID, "error", err)
+		}
+	}
 }
 
 // History returns a copy of the history entries, newest first.
@@ -302,6 +449,29 @@ func (m *Manager) History() []HistoryEntry {
 	return append([]HistoryEntry{}, m.history...)
 }
 
+// QueryHistory returns the history entries resolved within q's time range,
+// newest first. With a HistoryStore it pages through the full persisted log;
+// otherwise it filters the in-memory history.
+func (m *Manager) QueryHistory(q HistoryQuery) ([]HistoryEntry, error) {
+	if m.historyStore != nil {
+		return m.historyStore.Query(q)
+	}
+
+	m.historyMu.RLock()
+	defer m.historyMu.RUnlock()
+	var out []HistoryEntry
+	for _, entry := range m.history {
+		if !q.Contains(entry.ResolvedAt) {
+			continue
+		}
+		out = append(out, entry)
+		if q.Limit > 0 && len(out) >= q.Limit {
+			break
+		}
+	}
+	return out, nil
+}
+
 // GetHistoryEntry returns the history entry with the given request ID, or nil if not found.
 func (m *Manager) GetHistoryEntry(requestID string) *HistoryEntry {
 	m.historyMu.RLock()
@@ -314,6 +484,28 @@ func (m *Manager) GetHistoryEntry(requestID string) *HistoryEntry {
 	return nil
 }
 
+// LookupRequest returns the request with the given ID, whether it is still
+// pending or already resolved. Resolved requests older than the in-memory
+// history window are looked up in the persistent history store, if any.
+func (m *Manager) LookupRequest(id string) (*Request, error) {
+	if req := m.GetPending(id); req != nil {
+		return req, nil
+	}
+	if entry := m.GetHistoryEntry(id); entry != nil {
+		return entry.Request, nil
+	}
+	if m.historyStore != nil {
+		entry, err := m.historyStore.Find(id)
+		if err != nil {
+			return nil, err
+		}
+		if entry != nil {
+			return entry.Request, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
 // AddHistoryEntry adds an entry directly to history. For testing only.
 func (m *Manager) AddHistoryEntry(entry HistoryEntry) {
 	m.historyMu.Lock()
@@ -338,16 +530,41 @@ func (m *Manager) RequireApproval(ctx context.Context, client string, items []It
 		return true, nil
 	}
 
+	// Temporary rules. A deny rule refuses before anything can approve, even
+	// a cached approval.
+	rule := m.checkAutoApproveRules(senderInfo, items, reqType)
+	if rule != nil && rule.Action == "deny" {
+		slog.Info("temporary deny rule matched",
+			"rule_id", rule.ID,
+			"invoker", rule.InvokerName,
+			"type", reqType)
+		now := time.Now()
+		req := &Request{
+			ID:               uuid.New().String(),
+			Client:           client,
+			Items:            items,
+			Session:          session,
+			CreatedAt:        now,
+			ExpiresAt:        now,
+			Type:             reqType,
+			SearchAttributes: searchAttrs,
+			SenderInfo:       senderInfo,
+		}
+		m.notify(Event{Type: EventRequestDenied, Request: req, RuleName: TemporaryDenyRule})
+		return true, ErrDeniedByRule
+	}
+
 	// Check approval cache: if all items were recently approved for this sender, skip.
-	// Delete and write requests always require explicit approval — never use cached approvals.
-	if reqType != RequestTypeDelete && reqType != RequestTypeWrite && m.approvalWindow > 0 && len(items) > 0 {
+	// Delete, write, SSH agent management and secret key export requests always
+	// require explicit approval — never use cached approvals.
+	if !alwaysPrompts(reqType) && m.approvalWindow > 0 && len(items) > 0 {
 		if m.checkApprovalCache(senderInfo.Sender, items) {
 			return true, nil
 		}
 	}
 
 	// Check auto-approve rules (for timed-out client retries).
-	if rule := m.checkAutoApproveRules(senderInfo, items, reqType); rule != nil {
+	if rule != nil {
 		slog.Info("auto-approve rule matched",
 			"rule_id", rule.ID,
 			"invoker", rule.InvokerName,
@@ -369,13 +586,13 @@ func (m *Manager) RequireApproval(ctx context.Context, client string, items []It
 	}
 
 	// Check persistent trust rules from config.
-	if rule := m.CheckTrustRules(senderInfo, items, reqType, searchAttrs); rule != nil {
+	if rule := m.CheckTrustRules(client, senderInfo, items, reqType, searchAttrs); rule != nil {
 		action := rule.Action
 		if action == "" {
 			action = "approve"
 		}
 		slog.Info("trust rule matched",
-			"rule_name", rule.Name,
+			"rule_name", rule.Label(),
 			"action", action)
 		now := time.Now()
 		req := &Request{
@@ -390,14 +607,14 @@ func (m *Manager) RequireApproval(ctx context.Context, client string, items []It
 			SenderInfo:       senderInfo,
 		}
 		if action == "ignore" {
-			m.notify(Event{Type: EventRequestIgnored, Request: req})
+			m.notify(Event{Type: EventRequestIgnored, Request: req, RuleName: rule.Label()})
 			return true, ErrIgnored
 		}
 		if action == "deny" {
-			m.notify(Event{Type: EventRequestDenied, Request: req})
+			m.notify(Event{Type: EventRequestDenied, Request: req, RuleName: rule.Label()})
 			return true, ErrDeniedByRule
 		}
-		m.notify(Event{Type: EventRequestAutoApproved, Request: req})
+		m.notify(Event{Type: EventRequestAutoApproved, Request: req, RuleName: rule.Label()})
 		return true, nil
 	}
 
@@ -415,6 +632,12 @@ func (m *Manager) RequireApproval(ctx context.Context, client string, items []It
 		done:             make(chan struct{}),
 	}
 
+	return m.await(ctx, req)
+}
+
+// await adds req to pending, notifies observers and blocks until the user
+// decides, the request times out or ctx is cancelled.
+func (m *Manager) await(ctx context.Context, req *Request) (bool, error) {
 	m.mu.Lock()
 	m.pending[req.ID] = req
 	m.mu.Unlock()
@@ -665,17 +888,26 @@ var extractCollection = dbustypes.ExtractCollection
 // AddAutoApproveRule creates a temporary auto-approve rule from a cancelled request.
 // Returns the rule ID.
 func (m *Manager) AddAutoApproveRule(req *Request) string {
-	duration := m.autoApproveDuration
-	if duration <= 0 {
-		duration = 2 * time.Minute
-	}
+	return m.addAutoApproveRule(req, nil)
+}
+
+// addAutoApproveRule creates an auto-approve rule from req: timed when scope
+// is nil, otherwise bound to the scoped process.
+func (m *Manager) addAutoApproveRule(req *Request, scope *ProcessScope) string {
+	return m.insertAutoApproveRule(m.newAutoApproveRule(req, scope))
+}
 
+// newAutoApproveRule builds an approve rule matching requests like req.
+func (m *Manager) newAutoApproveRule(req *Request, scope *ProcessScope) AutoApproveRule {
 	rule := AutoApproveRule{
 		ID:          uuid.New().String(),
 		InvokerName: req.SenderInfo.InvokerName,
 		InvokerExe:  invokerExePath(req.SenderInfo),
 		RequestType: req.Type,
-		ExpiresAt:   time.Now().Add(duration),
+		Process:     scope,
+	}
+	if scope == nil {
+		rule.ExpiresAt = time.Now().Add(m.AutoApproveDuration())
 	}
 
 	// Extract collection and attributes from first item
@@ -688,12 +920,20 @@ func (m *Manager) AddAutoApproveRule(req *Request) string {
 	if req.Type == RequestTypeSearch && len(req.SearchAttributes) > 0 {
 		rule.Attributes = req.SearchAttributes
 	}
+	return rule
+}
 
+// insertAutoApproveRule adds rule, or refreshes the expiry of an identical
+// existing rule, and returns the ID of the rule in effect.
+func (m *Manager) insertAutoApproveRule(rule AutoApproveRule) string {
+	scope := rule.Process
 	m.autoApproveMu.Lock()
 	// Dedup: if a matching rule already exists, refresh its expiry
 	for i := range m.autoApproveRules {
 		existing := &m.autoApproveRules[i]
-		if existing.InvokerExe == rule.InvokerExe &&
+		if existing.Action == rule.Action &&
+			existing.InvokerExe == rule.InvokerExe &&
+			processScopeEqual(existing.Process, rule.Process) &&
 			existing.RequestType == rule.RequestType &&
 			existing.Collection == rule.Collection &&
 			attributesEqual(existing.Attributes, rule.Attributes) {
@@ -702,21 +942,36 @@ func (m *Manager) AddAutoApproveRule(req *Request) string {
 			m.notify(Event{Type: EventAutoApproveRuleAdded, Rule: existing})
 			slog.Info("auto-approve rule refreshed",
 				"rule_id", existing.ID,
+				"action", existing.Action,
 				"invoker", existing.InvokerName,
 				"expires_at", existing.ExpiresAt)
 			return existing.ID
 		}
 	}
 	m.autoApproveRules = append(m.autoApproveRules, rule)
+	if scope != nil {
+		m.watchProcessRules()
+	}
 	m.autoApproveMu.Unlock()
 
 	m.notify(Event{Type: EventAutoApproveRuleAdded, Rule: &rule})
-	slog.Info("auto-approve rule added",
-		"rule_id", rule.ID,
-		"invoker", rule.InvokerName,
-		"type", rule.RequestType,
-		"collection", rule.Collection,
-		"expires_at", rule.ExpiresAt)
+	if scope != nil {
+		slog.Info("auto-approve rule added",
+			"rule_id", rule.ID,
+			"invoker", rule.InvokerName,
+			"type", rule.RequestType,
+			"collection", rule.Collection,
+			"pid", scope.PID,
+			"subtree", scope.Subtree)
+	} else {
+		slog.Info("auto-approve rule added",
+			"rule_id", rule.ID,
+			"action", rule.Action,
+			"invoker", rule.InvokerName,
+			"type", rule.RequestType,
+			"collection", rule.Collection,
+			"expires_at", rule.ExpiresAt)
+	}
 
 	return rule.ID
 }
@@ -730,7 +985,7 @@ func (m *Manager) CheckAutoApproveRules(senderInfo SenderInfo, items []ItemInfo,
 }
 
 // checkAutoApproveRules checks if the request matches any active auto-approve rule.
-// Returns the matching rule or nil.
+// Returns the matching rule or nil. A matching deny rule wins over approve rules.
 func (m *Manager) checkAutoApproveRules(senderInfo SenderInfo, items []ItemInfo, reqType RequestType) *AutoApproveRule {
 	m.autoApproveMu.Lock()
 	defer m.autoApproveMu.Unlock()
@@ -742,32 +997,29 @@ func (m *Manager) checkAutoApproveRules(senderInfo SenderInfo, items []ItemInfo,
 
 	for i := range m.autoApproveRules {
 		rule := &m.autoApproveRules[i]
-		if rule.ExpiresAt.Before(now) {
+		if rule.expired(now) {
 			continue // expired, skip
 		}
 		active = append(active, *rule)
 
-		if match != nil {
-			continue // already found a match, just cleaning
+		if match != nil && (match.Action == "deny" || rule.Action != "deny") {
+			continue // already found the deciding match, just cleaning
 		}
 
-		// Match on the non-spoofable invoker exe path, never the caller's comm
-		// (InvokerName), which is attacker-controllable. Fail closed when either the
-		// rule or the caller lacks a resolved exe.
-		callerExe := invokerExePath(senderInfo)
-		if callerExe == "" || rule.InvokerExe != callerExe {
-			continue
-		}
-		// Match request type
-		if rule.RequestType != reqType {
+		if !rule.matches(senderInfo, reqType) {
 			continue
 		}
 		// Match collection + attributes for EVERY item in the batch. An
 		// auto-approve rule is permissive, so a single decision covers the whole
 		// batch only when every item falls within the rule's scope; matching just
 		// items[0] would let a batch smuggle an out-of-scope secret past a rule
-		// scoped to a benign collection.
-		if autoApproveCoversAll(rule, items) {
+		// scoped to a benign collection. A deny rule is restrictive: any item in
+		// scope refuses the batch.
+		covered := autoApproveCoversAll(rule, items)
+		if rule.Action == "deny" {
+			covered = denyCoversAny(rule, items)
+		}
+		if covered {
 			matched := *rule
 			match = &matched
 		}
@@ -777,6 +1029,28 @@ func (m *Manager) checkAutoApproveRules(senderInfo SenderInfo, items []ItemInfo,
 	return match
 }
 
+// matches reports whether the rule applies to requests of reqType from the
+// sender, leaving aside which items they are for.
+func (rule *AutoApproveRule) matches(senderInfo SenderInfo, reqType RequestType) bool {
+	if rule.Process != nil {
+		// A process-scoped rule matches the process instance (PID and
+		// start time) in the caller's chain, whatever its executable.
+		if !rule.Process.covers(senderInfo) {
+			return false
+		}
+	} else {
+		// Match on the non-spoofable invoker exe path, never the caller's comm
+		// (InvokerName), which is attacker-controllable. Fail closed when either the
+		// rule or the caller lacks a resolved exe.
+		callerExe := invokerExePath(senderInfo)
+		if callerExe == "" || rule.InvokerExe != callerExe {
+			return false
+		}
+	}
+	// Match request type; a deny rule without one (mute) matches any.
+	return rule.RequestType == reqType || (rule.Action == "deny" && rule.RequestType == "")
+}
+
 // autoApproveCoversAll reports whether an auto-approve rule covers every item in
 // the batch (collection and attributes). An auto-approve rule is permissive, so
 // it may only authorize a batch when all items are in scope. An empty batch is
@@ -832,7 +1106,7 @@ func (m *Manager) ListAutoApproveRules() []AutoApproveRule {
 	now := time.Now()
 	var active []AutoApproveRule
 	for _, rule := range m.autoApproveRules {
-		if rule.ExpiresAt.After(now) {
+		if !rule.expired(now) {
 			active = append(active, rule)
 		}
 	}
@@ -841,9 +1115,51 @@ func (m *Manager) ListAutoApproveRules() []AutoApproveRule {
 
 // ListTrustedSigners returns the configured trusted signers.
 func (m *Manager) ListTrustedSigners() []TrustedSigner {
+	m.trustMu.RLock()
+	defer m.trustMu.RUnlock()
 	return m.trustedSigners
 }
 
+// SetTrustConfig atomically replaces the config-defined trust rules, trusted
+// signers and client policies, e.g. after config.yaml was edited. Pending
+// requests, history and ephemeral auto-approve rules are untouched. Clears any
+// previous config error.
+func (m *Manager) SetTrustConfig(tc TrustConfig) {
+	m.trustMu.Lock()
+	m.trustRules = tc.Rules
+	m.trustedSigners = tc.TrustedSigners
+	m.clientPolicies = tc.ClientPolicies
+	m.configError = ""
+	m.trustMu.Unlock()
+	m.trackRules(tc.Rules)
+
+	slog.Info("trust config reloaded",
+		"rules", len(tc.Rules),
+		"trusted_signers", len(tc.TrustedSigners),
+		"client_policies", len(tc.ClientPolicies))
+	m.notify(Event{Type: EventTrustConfigReloaded})
+}
+
+// SetConfigError records that a config reload was rejected (parse or validation
+// failure). The previously loaded rules stay in effect; the error is surfaced to
+// observers so the UI can show why the edit did not apply.
+func (m *Manager) SetConfigError(err error) {
+	m.trustMu.Lock()
+	m.configError = err.Error()
+	m.trustMu.Unlock()
+
+	slog.Warn("config reload rejected, keeping previous rules", "error", err)
+	m.notify(Event{Type: EventConfigError, Err: err})
+}
+
+// ConfigError returns the last rejected config reload error, or "" if the
+// loaded config is current.
+func (m *Manager) ConfigError() string {
+	m.trustMu.RLock()
+	defer m.trustMu.RUnlock()
+	return m.configError
+}
+
 // RemoveAutoApproveRule removes an auto-approve rule by ID.
 func (m *Manager) RemoveAutoApproveRule(id string) error {
 	m.autoApproveMu.Lock()
@@ -884,7 +1200,11 @@ func (m *Manager) RemoveAutoApproveRule(id string) error {
 // object), so it must never take the silent path; it falls through to interactive
 // approval instead (which, per the WYSIWYS binding, shows the real committed bytes).
 func (m *Manager) CheckTrustedSigner(senderInfo SenderInfo, repoName string, changedFiles []string) bool {
-	if len(m.trustedSigners) == 0 {
+	m.trustMu.RLock()
+	trustedSigners := m.trustedSigners
+	m.trustMu.RUnlock()
+
+	if len(trustedSigners) == 0 {
 		return false
 	}
 	if !senderInfo.PeerTrusted {
@@ -895,7 +1215,7 @@ func (m *Manager) CheckTrustedSigner(senderInfo SenderInfo, repoName string, cha
 		if proc.Exe == "" {
 			continue
 		}
-		for _, ts := range m.trustedSigners {
+		for _, ts := range trustedSigners {
 			if proc.Exe != ts.ExePath {
 				continue
 			}
@@ -936,20 +1256,49 @@ func invokerExePath(s SenderInfo) string {
 }
 
 // CheckTrustRules checks if the request matches any configured trust rule.
-// Returns the first matching rule, or nil if no rules match.
-func (m *Manager) CheckTrustRules(senderInfo SenderInfo, items []ItemInfo, reqType RequestType, searchAttrs map[string]string) *TrustRule {
-	for i := range m.trustRules {
-		rule := &m.trustRules[i]
-		if !matchTrustRule(rule, senderInfo, items, reqType, searchAttrs) {
+// Returns the first matching rule. If none matches and the first client policy
+// for client has default "deny", returns a synthetic deny rule named after the
+// policy; otherwise nil.
+func (m *Manager) CheckTrustRules(client string, senderInfo SenderInfo, items []ItemInfo, reqType RequestType, searchAttrs map[string]string) *TrustRule {
+	m.trustMu.RLock()
+	trustRules := m.trustRules
+	clientPolicies := m.clientPolicies
+	m.trustMu.RUnlock()
+
+	for i := range trustRules {
+		rule := &trustRules[i]
+		if !matchTrustRule(rule, client, senderInfo, items, reqType, searchAttrs) {
 			continue
 		}
 		return rule
 	}
+
+	for _, p := range clientPolicies {
+		if ok, _ := path.Match(p.Client, client); !ok {
+			continue
+		}
+		if p.Default == "deny" {
+			return &TrustRule{Name: "client default: " + p.Client, Action: "deny", Client: p.Client}
+		}
+		break
+	}
 	return nil
 }
 
 // matchTrustRule returns true if the request matches a single trust rule.
-func matchTrustRule(rule *TrustRule, senderInfo SenderInfo, items []ItemInfo, reqType RequestType, searchAttrs map[string]string) bool {
+func matchTrustRule(rule *TrustRule, client string, senderInfo SenderInfo, items []ItemInfo, reqType RequestType, searchAttrs map[string]string) bool {
+	// Check client filter
+	if rule.Client != "" {
+		if ok, _ := path.Match(rule.Client, client); !ok {
+			return false
+		}
+	}
+
+	// A signing matcher only ever describes gpg_sign requests.
+	if rule.Signing != nil && reqType != RequestTypeGPGSign {
+		return false
+	}
+
 	// Check request_types filter
 	if len(rule.RequestTypes) > 0 {
 		found := slices.Contains(rule.RequestTypes, string(reqType))
@@ -965,14 +1314,25 @@ func matchTrustRule(rule *TrustRule, senderInfo SenderInfo, items []ItemInfo, re
 		}
 	}
 
-	// Check secret matcher. deny/ignore rules are restrictive (fire if ANY item
-	// is in scope); approve rules are permissive (fire only if EVERY item is).
+	// Check backend, secret and ssh matchers. deny/ignore rules are
+	// restrictive (fire if ANY item is in scope); approve rules are permissive
+	// (fire only if EVERY item is).
+	restrictive := rule.Action == "deny" || rule.Action == "ignore"
+	if rule.Backend != "" {
+		if !matchItems(items, restrictive, func(it ItemInfo) bool { return matchBackend(rule.Backend, it) }) {
+			return false
+		}
+	}
 	if rule.Secret != nil {
-		restrictive := rule.Action == "deny" || rule.Action == "ignore"
 		if !matchSecret(rule.Secret, items, restrictive) {
 			return false
 		}
 	}
+	if rule.SSH != nil {
+		if !matchSSH(rule.SSH, items, restrictive) {
+			return false
+		}
+	}
 
 	// Check search_attributes
 	if len(rule.SearchAttributes) > 0 {
@@ -987,10 +1347,13 @@ func matchTrustRule(rule *TrustRule, senderInfo SenderInfo, items []ItemInfo, re
 // matchProcess checks if the sender matches the process matcher.
 // At least one non-empty field must be set, and all non-empty fields must match.
 func matchProcess(pm *ProcessMatcher, senderInfo SenderInfo) bool {
-	if pm.Exe != "" {
+	if pm.Exe != "" || pm.ExeSHA256 != "" || pm.ExePackaged {
+		// One process of the chain must satisfy all three together, so
+		// "exe: /usr/bin/git, exe_packaged: true" is not met by a packaged
+		// shell whose child is a user-built git.
 		matched := false
 		for _, proc := range senderInfo.ProcessChain {
-			if ok, _ := path.Match(pm.Exe, proc.Exe); ok {
+			if matchExe(pm, proc) {
 				matched = true
 				break
 			}
@@ -1055,6 +1418,68 @@ func matchProcess(pm *ProcessMatcher, senderInfo SenderInfo) bool {
 		}
 	}
 
+	if pm.AppID != "" {
+		// The sandbox's application ID, which the app cannot change but an
+		// unsandboxed process can claim (see procutil.ReadApp). Callers
+		// without one never match.
+		if ok, _ := path.Match(pm.AppID, senderInfo.AppID); !ok {
+			return false
+		}
+	}
+
+	if pm.Container != nil && !matchContainer(pm.Container, senderInfo.Container) {
+		return false
+	}
+
+	return true
+}
+
+// matchExe checks the executable fields of pm against one process. The hash
+// and package checks read the running image through /proc, so they fail for
+// processes that exited or run as another user.
+func matchExe(pm *ProcessMatcher, proc ProcessInfo) bool {
+	if pm.Exe != "" {
+		if ok, _ := path.Match(pm.Exe, proc.Exe); !ok {
+			return false
+		}
+	}
+	if pm.ExeSHA256 != "" {
+		sum := procutil.ExeSHA256(int32(proc.PID), proc.StartTime)
+		if sum == "" || !strings.EqualFold(sum, pm.ExeSHA256) {
+			return false
+		}
+	}
+	if pm.ExePackaged && !procutil.ExePackaged(int32(proc.PID), proc.StartTime) {
+		return false
+	}
+	return true
+}
+
+// matchContainer checks the caller's container against cm. A caller outside a
+// container never matches.
+func matchContainer(cm *ContainerMatcher, c *ContainerInfo) bool {
+	if c == nil {
+		return false
+	}
+	for _, f := range []struct{ pattern, value string }{
+		{cm.Runtime, c.Runtime},
+		{cm.Name, c.Name},
+		{cm.Image, c.Image},
+	} {
+		if f.pattern == "" {
+			continue
+		}
+		if ok, _ := path.Match(f.pattern, f.value); !ok {
+			return false
+		}
+	}
+	if cm.ID != "" {
+		full, _ := path.Match(cm.ID, c.ID)
+		short, _ := path.Match(cm.ID, c.ID[:min(12, len(c.ID))])
+		if !full && !short {
+			return false
+		}
+	}
 	return true
 }
 
@@ -1091,6 +1516,25 @@ func matchSecret(sm *SecretMatcher, items []ItemInfo, restrictive bool) bool {
 	return true
 }
 
+// matchItems applies match to items with the any (restrictive) or every
+// (permissive) semantics of matchSecret.
+func matchItems(items []ItemInfo, restrictive bool, match func(ItemInfo) bool) bool {
+	if restrictive {
+		return slices.ContainsFunc(items, match)
+	}
+	return len(items) > 0 && !slices.ContainsFunc(items, func(it ItemInfo) bool { return !match(it) })
+}
+
+// matchBackend checks an item's upstream against a backend glob. Items not
+// held by an upstream (searches, SSH keys) never match.
+func matchBackend(pattern string, item ItemInfo) bool {
+	if item.Backend == "" {
+		return false
+	}
+	ok, _ := path.Match(pattern, item.Backend)
+	return ok
+}
+
 // matchSecretItem checks whether a single item matches the secret matcher.
 func matchSecretItem(sm *SecretMatcher, item ItemInfo) bool {
 	if sm.Collection != "" {
@@ -1115,8 +1559,50 @@ func matchSecretItem(sm *SecretMatcher, item ItemInfo) bool {
 	return true
 }
 
+// matchSSH checks the items of an SSH agent request against the ssh matcher,
+// with the same any/every semantics as matchSecret.
+func matchSSH(sm *SSHMatcher, items []ItemInfo, restrictive bool) bool {
+	if restrictive {
+		return slices.ContainsFunc(items, func(it ItemInfo) bool { return matchSSHItem(sm, it) })
+	}
+	if len(items) == 0 {
+		return false
+	}
+	for _, it := range items {
+		if !matchSSHItem(sm, it) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchSSHItem checks whether a single key item matches the ssh matcher. Items
+// without a fingerprint (secrets, agent lock/extension requests) never match.
+func matchSSHItem(sm *SSHMatcher, item ItemInfo) bool {
+	fingerprint := item.Attributes["fingerprint"]
+	if fingerprint == "" {
+		return false
+	}
+	if sm.Fingerprint != "" && sm.Fingerprint != fingerprint {
+		return false
+	}
+	if sm.Comment != "" {
+		if ok, _ := path.Match(sm.Comment, item.Attributes["comment"]); !ok {
+			return false
+		}
+	}
+	if sm.Destination != "" {
+		if ok, _ := path.Match(sm.Destination, item.Attributes["destination"]); !ok {
+			return false
+		}
+	}
+	return true
+}
+
 // ListTrustRules returns the configured trust rules.
 func (m *Manager) ListTrustRules() []TrustRule {
+	m.trustMu.RLock()
+	defer m.trustMu.RUnlock()
 	return m.trustRules
 }
 
@@ -1172,9 +1658,10 @@ func (m *Manager) RecordIgnored(client string, items []ItemInfo, session string,
 	m.notify(Event{Type: EventRequestIgnored, Request: req})
 }
 
-// RecordDenied creates a history entry for a request denied by a trust rule.
+// RecordDenied creates a history entry for a request denied by the trust rule
+// with the given label.
 func (m *Manager) RecordDenied(client string, items []ItemInfo, session string,
-	reqType RequestType, searchAttrs map[string]string, senderInfo SenderInfo) {
+	reqType RequestType, searchAttrs map[string]string, senderInfo SenderInfo, ruleName string) {
 	now := time.Now()
 	req := &Request{
 		ID:               uuid.New().String(),
@@ -1187,12 +1674,12 @@ func (m *Manager) RecordDenied(client string, items []ItemInfo, session string,
 		SearchAttributes: searchAttrs,
 		SenderInfo:       senderInfo,
 	}
-	m.notify(Event{Type: EventRequestDenied, Request: req})
+	m.notify(Event{Type: EventRequestDenied, Request: req, RuleName: ruleName})
 }
 
 // cacheApproval records approved (sender, item) pairs in the cache.
 func (m *Manager) cacheApproval(req *Request) {
-	if m.approvalWindow <= 0 {
+	if m.approvalWindow <= 0 || alwaysPrompts(req.Type) {
 		return
 	}
 	now := time.Now()
@@ -1200,7 +1687,7 @@ func (m *Manager) cacheApproval(req *Request) {
 	m.cacheMu.Lock()
 	defer m.cacheMu.Unlock()
 
-	for _, item := range req.Items {
+	for _, item := range req.GrantedItems() {
 		key := approvalCacheKey(req.SenderInfo.Sender, item.Path)
 		m.cache[key] = now
 	}
@@ -2,6 +2,9 @@ package approval
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -1327,15 +1330,104 @@ func TestProcessUnitMatchesSystemdUnitNotComm(t *testing.T) {
 
 	// Genuine caller: comm is "firefox", real systemd unit is "firefox.service".
 	genuine := SenderInfo{InvokerName: "firefox", SystemdUnit: "firefox.service"}
-	assert.NotNil(t, mgr.CheckTrustRules(genuine, items, RequestTypeGetSecret, nil),
+	assert.NotNil(t, mgr.CheckTrustRules("local", genuine, items, RequestTypeGetSecret, nil),
 		"unit rule should match the real systemd unit")
 
 	// Spoofer: sets its comm to "firefox.service" but has no such systemd unit.
 	spoofed := SenderInfo{InvokerName: "firefox.service", SystemdUnit: ""}
-	assert.Nil(t, mgr.CheckTrustRules(spoofed, items, RequestTypeGetSecret, nil),
+	assert.Nil(t, mgr.CheckTrustRules("local", spoofed, items, RequestTypeGetSecret, nil),
 		"unit rule must not match a comm spoofed to look like a unit name")
 }
 
+// TestProcessAppIDMatchesSandboxedApp checks that an `app_id` rule matches the
+// verified sandbox application ID whatever the exe inside the sandbox, and
+// never an unsandboxed process named after the app.
+func TestProcessAppIDMatchesSandboxedApp(t *testing.T) {
+	mgr := NewManager(ManagerConfig{Timeout: 5 * time.Second, HistoryMax: 100})
+	mgr.trustRules = []TrustRule{{
+		Name:    "approve-firefox",
+		Action:  "approve",
+		Process: &ProcessMatcher{AppID: "org.mozilla.firefox"},
+	}}
+	items := []ItemInfo{{Path: "/org/freedesktop/secrets/collection/default/1"}}
+
+	flatpak := SenderInfo{
+		InvokerName:  "firefox",
+		AppID:        "org.mozilla.firefox",
+		AppSandbox:   "flatpak",
+		ProcessChain: []ProcessInfo{{Name: "firefox", Exe: "/app/lib/firefox/firefox"}},
+	}
+	assert.NotNil(t, mgr.CheckTrustRules("local", flatpak, items, RequestTypeGetSecret, nil),
+		"app_id rule should match the sandboxed app")
+
+	unsandboxed := SenderInfo{
+		InvokerName:  "org.mozilla.firefox",
+		ProcessChain: []ProcessInfo{{Name: "org.mozilla.firefox", Exe: "/tmp/org.mozilla.firefox"}},
+	}
+	assert.Nil(t, mgr.CheckTrustRules("local", unsandboxed, items, RequestTypeGetSecret, nil),
+		"app_id rule must not match an unsandboxed process")
+}
+
+func TestMatchProcessContainer(t *testing.T) {
+	id := "3f4e1a2b5c6d" + strings.Repeat("0", 52)
+	inContainer := SenderInfo{Container: &ContainerInfo{Runtime: "docker", ID: id, Name: "web", Image: "nginx:1.27"}}
+	tests := []struct {
+		name    string
+		matcher ContainerMatcher
+		sender  SenderInfo
+		want    bool
+	}{
+		{"image glob", ContainerMatcher{Image: "nginx:*"}, inContainer, true},
+		{"runtime and name", ContainerMatcher{Runtime: "docker", Name: "web"}, inContainer, true},
+		{"short id", ContainerMatcher{ID: "3f4e1a2b5c6d"}, inContainer, true},
+		{"full id", ContainerMatcher{ID: id}, inContainer, true},
+		{"other runtime", ContainerMatcher{Runtime: "podman"}, inContainer, false},
+		{"other image", ContainerMatcher{Image: "node:*"}, inContainer, false},
+		{"host caller", ContainerMatcher{Runtime: "*"}, SenderInfo{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := matchProcess(&ProcessMatcher{Container: &tt.matcher}, tt.sender)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMatchProcessExeSHA256(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	self := SenderInfo{ProcessChain: []ProcessInfo{
+		{Name: "sh", PID: 1, Exe: "/bin/sh"},
+		{Name: "approval.test", PID: uint32(os.Getpid()), Exe: exe},
+	}}
+	tests := []struct {
+		name    string
+		matcher ProcessMatcher
+		want    bool
+	}{
+		{"hash", ProcessMatcher{ExeSHA256: hash}, true},
+		{"upper-case hash", ProcessMatcher{ExeSHA256: strings.ToUpper(hash)}, true},
+		{"hash and exe of the same process", ProcessMatcher{Exe: exe, ExeSHA256: hash}, true},
+		{"hash of another process's exe", ProcessMatcher{Exe: "/bin/sh", ExeSHA256: hash}, false},
+		{"other hash", ProcessMatcher{ExeSHA256: strings.Repeat("0", 64)}, false},
+		{"test binary is not packaged", ProcessMatcher{Exe: exe, ExePackaged: true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchProcess(&tt.matcher, self))
+		})
+	}
+}
+
 // TestCheckTrustedSigner_RejectsUntrustedPeer is the regression test for Vuln 5:
 // a request that did NOT arrive through our own thin client (PeerTrusted=false) must
 // never take the silent path, even with a trusted binary in its ancestry — because
@@ -1434,6 +1526,7 @@ func TestCheckTrustRules(t *testing.T) {
 
 	t.Run("match process name + request type", func(t *testing.T) {
 		rule := mgr.CheckTrustRules(
+			"local",
 			SenderInfo{ProcessChain: ghChain},
 			nil,
 			RequestTypeSearch,
@@ -1446,6 +1539,7 @@ func TestCheckTrustRules(t *testing.T) {
 
 	t.Run("no match wrong request type", func(t *testing.T) {
 		rule := mgr.CheckTrustRules(
+			"local",
 			SenderInfo{ProcessChain: ghChain},
 			nil,
 			RequestTypeGetSecret,
@@ -1465,6 +1559,7 @@ func TestCheckTrustRules(t *testing.T) {
 			Label: "Chrome Safe Storage",
 		}}
 		rule := mgr.CheckTrustRules(
+			"local",
 			SenderInfo{ProcessChain: chromeChain},
 			items,
 			RequestTypeWrite,
@@ -1481,6 +1576,7 @@ func TestCheckTrustRules(t *testing.T) {
 			Label: "Chrome Safe Storage",
 		}}
 		rule := mgr.CheckTrustRules(
+			"local",
 			SenderInfo{ProcessChain: chromeChain},
 			items,
 			RequestTypeWrite,
@@ -1494,6 +1590,7 @@ func TestCheckTrustRules(t *testing.T) {
 
 	t.Run("match unit glob", func(t *testing.T) {
 		rule := mgr.CheckTrustRules(
+			"local",
 			gopassSender,
 			[]ItemInfo{{Path: "/org/freedesktop/secrets/collection/default/1"}},
 			RequestTypeGetSecret,
@@ -1510,6 +1607,7 @@ func TestCheckTrustRules(t *testing.T) {
 			Label: "GitHub Token",
 		}}
 		rule := mgr.CheckTrustRules(
+			"local",
 			SenderInfo{ProcessChain: []ProcessInfo{{Name: "random-app", PID: 999}}},
 			items,
 			RequestTypeGetSecret,
@@ -1522,6 +1620,7 @@ func TestCheckTrustRules(t *testing.T) {
 
 	t.Run("match search attributes", func(t *testing.T) {
 		rule := mgr.CheckTrustRules(
+			"local",
 			SenderInfo{ProcessChain: []ProcessInfo{{Name: "app", PID: 1}}},
 			nil,
 			RequestTypeSearch,
@@ -1539,6 +1638,7 @@ func TestCheckTrustRules(t *testing.T) {
 			Attributes: map[string]string{"xdg:schema": "org.gnome.keyring.NetworkPassword", "ssid": "home"},
 		}}
 		rule := mgr.CheckTrustRules(
+			"local",
 			SenderInfo{ProcessChain: []ProcessInfo{{Name: "nm-applet", PID: 500}}},
 			items,
 			RequestTypeGetSecret,
@@ -1567,6 +1667,7 @@ func TestCheckTrustRules(t *testing.T) {
 			Attributes: map[string]string{"username": "kubelogin/tokencache/e91d908"},
 		}}
 		rule := m.CheckTrustRules(
+			"local",
 			SenderInfo{ProcessChain: []ProcessInfo{{Name: "kubectl", PID: 1}}},
 			items,
 			RequestTypeGetSecret,
@@ -1578,6 +1679,7 @@ func TestCheckTrustRules(t *testing.T) {
 		// Non-matching value
 		items[0].Attributes["username"] = "other/path"
 		rule = m.CheckTrustRules(
+			"local",
 			SenderInfo{ProcessChain: []ProcessInfo{{Name: "kubectl", PID: 1}}},
 			items,
 			RequestTypeGetSecret,
@@ -1591,6 +1693,7 @@ func TestCheckTrustRules(t *testing.T) {
 	t.Run("first match wins", func(t *testing.T) {
 		// gh doing search matches rule 0 (approve-gh-search), not rule 4 (approve-search-attrs)
 		rule := mgr.CheckTrustRules(
+			"local",
 			SenderInfo{ProcessChain: ghChain},
 			nil,
 			RequestTypeSearch,
@@ -1603,7 +1706,7 @@ func TestCheckTrustRules(t *testing.T) {
 
 	t.Run("empty rules", func(t *testing.T) {
 		m := NewManager(ManagerConfig{Timeout: time.Second, HistoryMax: 10})
-		rule := m.CheckTrustRules(SenderInfo{}, nil, RequestTypeGetSecret, nil)
+		rule := m.CheckTrustRules("local", SenderInfo{}, nil, RequestTypeGetSecret, nil)
 		if rule != nil {
 			t.Errorf("expected nil from empty rules, got %v", rule)
 		}
@@ -1788,6 +1891,121 @@ func TestTrustRules_RequireApproval_Deny(t *testing.T) {
 	mgr.Unsubscribe(obs)
 }
 
+func TestCheckTrustRules_Client(t *testing.T) {
+	mgr := NewManager(ManagerConfig{
+		Timeout:    5 * time.Second,
+		HistoryMax: 100,
+		TrustRules: []TrustRule{{
+			Name:   "build-ci",
+			Client: "build-*",
+			Secret: &SecretMatcher{Attributes: map[string]string{"service": "ci"}},
+		}},
+		ClientPolicies: []ClientPolicy{
+			{Client: "build-trusted", Default: "prompt"},
+			{Client: "build-*", Default: "deny"},
+		},
+	})
+	ci := []ItemInfo{{Path: "/org/freedesktop/secrets/collection/default/1", Attributes: map[string]string{"service": "ci"}}}
+	personal := []ItemInfo{{Path: "/org/freedesktop/secrets/collection/default/2", Attributes: map[string]string{"service": "bank"}}}
+
+	if rule := mgr.CheckTrustRules("build-01", SenderInfo{}, ci, RequestTypeGetSecret, nil); rule == nil || rule.Name != "build-ci" {
+		t.Errorf("build-01 ci secret: expected build-ci, got %v", rule)
+	}
+	if rule := mgr.CheckTrustRules("local", SenderInfo{}, ci, RequestTypeGetSecret, nil); rule != nil {
+		t.Errorf("client matcher should not match local, got %v", rule.Name)
+	}
+
+	rule := mgr.CheckTrustRules("build-01", SenderInfo{}, personal, RequestTypeGetSecret, nil)
+	if rule == nil || rule.Action != "deny" || rule.Name != "client default: build-*" {
+		t.Errorf("build-01 personal secret: expected client default deny, got %v", rule)
+	}
+	if rule := mgr.CheckTrustRules("build-01", SenderInfo{}, nil, RequestTypeSearch, map[string]string{"service": "bank"}); rule == nil || rule.Action != "deny" {
+		t.Errorf("build-01 search: expected client default deny, got %v", rule)
+	}
+
+	// First matching policy wins: build-trusted falls back to prompting.
+	if rule := mgr.CheckTrustRules("build-trusted", SenderInfo{}, personal, RequestTypeGetSecret, nil); rule != nil {
+		t.Errorf("build-trusted: expected no rule (prompt), got %v", rule.Name)
+	}
+	if rule := mgr.CheckTrustRules("local", SenderInfo{}, personal, RequestTypeGetSecret, nil); rule != nil {
+		t.Errorf("local: expected no rule (prompt), got %v", rule.Name)
+	}
+}
+
+func TestCheckTrustRules_SSH(t *testing.T) {
+	mgr := NewManager(ManagerConfig{
+		Timeout:    5 * time.Second,
+		HistoryMax: 100,
+		TrustRules: []TrustRule{
+			{
+				Name:         "deny-work-key-elsewhere",
+				Action:       "deny",
+				RequestTypes: []string{"ssh_sign"},
+				SSH:          &SSHMatcher{Comment: "*@work", Destination: "*.example.org"},
+			},
+			{
+				Name:         "git-github",
+				RequestTypes: []string{"ssh_sign"},
+				Process:      &ProcessMatcher{Exe: "/usr/bin/git"},
+				SSH:          &SSHMatcher{Fingerprint: "SHA256:abc", Destination: "github.com"},
+			},
+		},
+	})
+	git := SenderInfo{ProcessChain: []ProcessInfo{{Name: "ssh", Exe: "/usr/bin/ssh"}, {Name: "git", Exe: "/usr/bin/git"}}}
+	key := func(fp, comment, dest string) []ItemInfo {
+		return []ItemInfo{{Path: fp, Label: comment, Attributes: map[string]string{"fingerprint": fp, "comment": comment, "destination": dest}}}
+	}
+
+	if rule := mgr.CheckTrustRules("ssh-agent", git, key("SHA256:abc", "me@home", "github.com"), RequestTypeSSHSign, nil); rule == nil || rule.Name != "git-github" {
+		t.Errorf("matching key and destination: expected git-github, got %v", rule)
+	}
+	if rule := mgr.CheckTrustRules("ssh-agent", git, key("SHA256:other", "me@home", "github.com"), RequestTypeSSHSign, nil); rule != nil {
+		t.Errorf("other key: expected no rule, got %v", rule.Name)
+	}
+	if rule := mgr.CheckTrustRules("ssh-agent", git, key("SHA256:abc", "me@home", "gitlab.com"), RequestTypeSSHSign, nil); rule != nil {
+		t.Errorf("other destination: expected no rule, got %v", rule.Name)
+	}
+	if rule := mgr.CheckTrustRules("ssh-agent", SenderInfo{}, key("SHA256:w", "me@work", "host.example.org"), RequestTypeSSHSign, nil); rule == nil || rule.Action != "deny" {
+		t.Errorf("work key on example.org: expected deny, got %v", rule)
+	}
+
+	// Items without a fingerprint are not keys, so an ssh matcher never matches them.
+	secret := []ItemInfo{{Path: "/org/freedesktop/secrets/collection/default/1", Attributes: map[string]string{"destination": "github.com"}}}
+	if matchSSH(&SSHMatcher{Destination: "github.com"}, secret, false) {
+		t.Error("ssh matcher matched an item without a fingerprint")
+	}
+}
+
+func TestTrustRules_RequireApproval_ClientDefaultDeny(t *testing.T) {
+	mgr := NewManager(ManagerConfig{
+		Timeout:        5 * time.Second,
+		HistoryMax:     100,
+		ClientPolicies: []ClientPolicy{{Client: "build-*", Default: "deny"}},
+	})
+
+	_, err := mgr.RequireApproval(
+		context.Background(), "build-01",
+		[]ItemInfo{{Path: "/org/freedesktop/secrets/collection/personal/1"}}, "/s/1",
+		RequestTypeGetSecret, nil, SenderInfo{},
+	)
+	if err != ErrDeniedByRule {
+		t.Fatalf("expected ErrDeniedByRule, got %v", err)
+	}
+	if mgr.PendingCount() != 0 {
+		t.Error("client default deny should not create pending request")
+	}
+	history := mgr.History()
+	if len(history) != 1 || history[0].Resolution != ResolutionDenied || history[0].Request.Client != "build-01" {
+		t.Errorf("expected denied build-01 request in history, got %v", history)
+	}
+
+	// The policy is hot-reloadable along with the rules.
+	mgr.SetTrustConfig(TrustConfig{})
+	if rule := mgr.CheckTrustRules("build-01", SenderInfo{}, nil, RequestTypeGetSecret, nil); rule != nil {
+		t.Errorf("expected no rule after policies were cleared, got %v", rule.Name)
+	}
+}
+
 func TestTrustRules_RequireApproval_NoMatch(t *testing.T) {
 	mgr := NewManager(ManagerConfig{
 		Timeout:    100 * time.Millisecond,
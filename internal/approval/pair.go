@@ -0,0 +1,47 @@
+package approval
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RequestTypePair is the request type for pairing a new remote client.
+const RequestTypePair RequestType = "pair"
+
+// PairInfo carries what the user compares before approving a pairing: the
+// code shown on the remote host and the fingerprint of its identity key.
+type PairInfo struct {
+	Code        string `json:"code"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// RequirePairing asks the user to approve pairing client, blocking until they
+// decide, the request times out or ctx is cancelled; it returns nil only on
+// approval (ErrDenied, ErrTimeout or ctx.Err() otherwise). Unlike RequireApproval it
+// never consults the approval cache, auto-approve rules or trust rules: a
+// pairing is only ever granted by a person who compared the codes.
+func (m *Manager) RequirePairing(ctx context.Context, client string, info *PairInfo, senderInfo SenderInfo) error {
+	if info == nil {
+		return errors.New("pair info is required")
+	}
+	if m.disabled {
+		return errors.New("pairing requires an approval manager")
+	}
+
+	now := time.Now()
+	req := &Request{
+		ID:         uuid.New().String(),
+		Client:     client,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(m.timeout),
+		Type:       RequestTypePair,
+		PairInfo:   info,
+		SenderInfo: senderInfo,
+		done:       make(chan struct{}),
+	}
+	_, err := m.await(ctx, req)
+	return err
+}
@@ -0,0 +1,85 @@
+package approval
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitPending polls until the manager has a pending request and returns it.
+func waitPending(t *testing.T, mgr *Manager) *Request {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if pending := mgr.List(); len(pending) > 0 {
+			return pending[0]
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("no pending request")
+	return nil
+}
+
+// TestRequirePairing_IgnoresRulesAndPolicies verifies that a pairing always
+// reaches the user, even when a trust rule or client default would decide a
+// normal request for the same client without prompting.
+func TestRequirePairing_IgnoresRulesAndPolicies(t *testing.T) {
+	mgr := NewManager(ManagerConfig{
+		Timeout:        5 * time.Second,
+		HistoryMax:     100,
+		TrustRules:     []TrustRule{{Name: "allow-all", Action: "approve"}},
+		ClientPolicies: []ClientPolicy{{Client: "build-*", Default: "deny"}},
+	})
+	info := &PairInfo{Code: "123 456", Fingerprint: "SHA256:abc"}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- mgr.RequirePairing(context.Background(), "build-01", info, SenderInfo{})
+	}()
+
+	req := waitPending(t, mgr)
+	if req.Type != RequestTypePair {
+		t.Errorf("request type = %q, want %q", req.Type, RequestTypePair)
+	}
+	if req.PairInfo == nil || req.PairInfo.Code != "123 456" {
+		t.Errorf("pair info not carried on the request: %+v", req.PairInfo)
+	}
+	if err := mgr.Approve(req.ID); err != nil {
+		t.Fatalf("Approve: %v", err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("RequirePairing after approval = %v, want nil", err)
+	}
+}
+
+func TestRequirePairing_Denied(t *testing.T) {
+	mgr := NewManager(ManagerConfig{Timeout: 5 * time.Second, HistoryMax: 100})
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- mgr.RequirePairing(context.Background(), "build-01", &PairInfo{Code: "000 001"}, SenderInfo{})
+	}()
+
+	req := waitPending(t, mgr)
+	if err := mgr.Deny(req.ID); err != nil {
+		t.Fatalf("Deny: %v", err)
+	}
+	if err := <-errCh; !errors.Is(err, ErrDenied) {
+		t.Fatalf("RequirePairing after deny = %v, want ErrDenied", err)
+	}
+	history := mgr.History()
+	if len(history) != 1 || history[0].Request.Type != RequestTypePair || history[0].Resolution != ResolutionDenied {
+		t.Errorf("expected denied pair request in history, got %+v", history)
+	}
+}
+
+func TestRequirePairing_NilInfo(t *testing.T) {
+	mgr := NewManager(ManagerConfig{Timeout: 5 * time.Second, HistoryMax: 100})
+	if err := mgr.RequirePairing(context.Background(), "build-01", nil, SenderInfo{}); err == nil {
+		t.Fatal("expected error for nil PairInfo")
+	}
+	if mgr.PendingCount() != 0 {
+		t.Error("nil PairInfo should not create a pending request")
+	}
+}
@@ -0,0 +1,156 @@
+package approval
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/nikicat/secrets-dispatcher/internal/procutil"
+)
+
+// ErrInvalidProcess is returned when a process-scoped approval names a process
+// that is not in the request's process chain, or whose start time is unknown.
+var ErrInvalidProcess = errors.New("invalid process for a process-scoped approval")
+
+// processScopeCheckInterval is how often process-scoped auto-approve rules are
+// checked for exited processes.
+const processScopeCheckInterval = 2 * time.Second
+
+// readProcessStartTime returns the start time of the running process pid, or
+// 0 if it is gone.
+func readProcessStartTime(pid uint32) uint64 {
+	return procutil.ReadStartTime(int32(pid))
+}
+
+// ProcessScope binds an auto-approve rule to one process instance, identified
+// by PID and start time so a reused PID never inherits the grant. The rule
+// covers requests made by that process — or, with Subtree, by it and any of
+// its descendants — and is revoked when the process exits.
+type ProcessScope struct {
+	PID       uint32 `json:"pid"`
+	StartTime uint64 `json:"start_time"`
+	Name      string `json:"name"` // comm, for display only
+	Exe       string `json:"exe,omitempty"`
+	Subtree   bool   `json:"subtree,omitempty"`
+}
+
+// NewProcessScope returns a scope for the process pid of req's process chain;
+// pid 0 selects the requesting process itself. Only processes with a known
+// start time can be scoped.
+func NewProcessScope(req *Request, pid uint32, subtree bool) (*ProcessScope, error) {
+	chain := req.SenderInfo.ProcessChain
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("%w: request has no process chain", ErrInvalidProcess)
+	}
+	if pid == 0 {
+		pid = chain[0].PID
+	}
+	for _, p := range chain {
+		if p.PID != pid {
+			continue
+		}
+		if p.StartTime == 0 {
+			return nil, fmt.Errorf("%w: start time of PID %d is unknown", ErrInvalidProcess, pid)
+		}
+		return &ProcessScope{PID: p.PID, StartTime: p.StartTime, Name: p.Name, Exe: p.Exe, Subtree: subtree}, nil
+	}
+	return nil, fmt.Errorf("%w: PID %d is not in the request's process chain", ErrInvalidProcess, pid)
+}
+
+// covers reports whether sender is the scoped process or, for a subtree scope,
+// one of its descendants.
+func (s *ProcessScope) covers(sender SenderInfo) bool {
+	for i, p := range sender.ProcessChain {
+		if p.PID == s.PID && p.StartTime == s.StartTime {
+			return i == 0 || s.Subtree
+		}
+	}
+	return false
+}
+
+// alive reports whether the scoped process is still running, given a reader
+// of process start times.
+func (s *ProcessScope) alive(startTime func(pid uint32) uint64) bool {
+	return startTime(s.PID) == s.StartTime
+}
+
+func processScopeEqual(a, b *ProcessScope) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// ApproveForProcess approves a pending request and auto-approves similar
+// requests from the same process instance (or its subtree) until it exits.
+// pid selects the process from the request's chain; 0 means the requester.
+func (m *Manager) ApproveForProcess(id string, pid uint32, subtree bool) error {
+	req := m.GetPending(id)
+	if req == nil {
+		return ErrNotFound
+	}
+	scope, err := NewProcessScope(req, pid, subtree)
+	if err != nil {
+		return err
+	}
+	if err := m.Approve(id); err != nil {
+		return err
+	}
+	m.AddProcessRule(req, scope)
+	return nil
+}
+
+// AddProcessRule creates an auto-approve rule like AddAutoApproveRule, but
+// bound to scope instead of the invoker executable and lasting until the
+// process exits rather than for a fixed duration. Returns the rule ID.
+func (m *Manager) AddProcessRule(req *Request, scope *ProcessScope) string {
+	return m.addAutoApproveRule(req, scope)
+}
+
+// watchProcessRules starts the reaper of process-scoped rules unless it is
+// already running. Caller holds autoApproveMu.
+func (m *Manager) watchProcessRules() {
+	if m.processWatching {
+		return
+	}
+	m.processWatching = true
+	go m.reapProcessRules()
+}
+
+// reapProcessRules periodically removes the rules of exited processes. It
+// stops once no process-scoped rule is left.
+func (m *Manager) reapProcessRules() {
+	ticker := time.NewTicker(m.processCheckInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		m.autoApproveMu.Lock()
+		var exited []AutoApproveRule
+		scoped := false
+		kept := m.autoApproveRules[:0]
+		for _, rule := range m.autoApproveRules {
+			if rule.Process != nil && !rule.Process.alive(m.processStartTime) {
+				exited = append(exited, rule)
+				continue
+			}
+			scoped = scoped || rule.Process != nil
+			kept = append(kept, rule)
+		}
+		m.autoApproveRules = kept
+		if !scoped {
+			m.processWatching = false
+		}
+		m.autoApproveMu.Unlock()
+
+		for i := range exited {
+			slog.Info("auto-approve rule revoked, process exited",
+				"rule_id", exited[i].ID,
+				"pid", exited[i].Process.PID,
+				"process", exited[i].Process.Name)
+			m.notify(Event{Type: EventAutoApproveRuleRemoved, Rule: &exited[i]})
+		}
+		if !scoped {
+			return
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package approval
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// agentChain is a process chain for a tool run by an agent: the tool
+// (requester) and the agent that spawned it.
+func agentChain(toolPID uint32, toolStart uint64) SenderInfo {
+	return SenderInfo{
+		PID:         toolPID,
+		InvokerName: "terraform",
+		ProcessChain: []ProcessInfo{
+			{Name: "terraform", PID: toolPID, StartTime: toolStart, Exe: "/usr/bin/terraform"},
+			{Name: "agent", PID: 100, StartTime: 5000, Exe: "/usr/bin/agent"},
+		},
+	}
+}
+
+func TestNewProcessScope(t *testing.T) {
+	req := &Request{SenderInfo: agentChain(200, 7000)}
+
+	scope, err := NewProcessScope(req, 0, false)
+	if err != nil || scope.PID != 200 || scope.StartTime != 7000 || scope.Name != "terraform" {
+		t.Errorf("pid 0 = %+v, %v; want the requester", scope, err)
+	}
+	scope, err = NewProcessScope(req, 100, true)
+	if err != nil || scope.PID != 100 || !scope.Subtree {
+		t.Errorf("pid 100 = %+v, %v; want the agent subtree", scope, err)
+	}
+	if _, err := NewProcessScope(req, 300, false); !errors.Is(err, ErrInvalidProcess) {
+		t.Errorf("pid outside the chain = %v, want ErrInvalidProcess", err)
+	}
+	noStart := &Request{SenderInfo: testSender("gh", "/usr/bin/gh")}
+	if _, err := NewProcessScope(noStart, 0, false); !errors.Is(err, ErrInvalidProcess) {
+		t.Errorf("unknown start time = %v, want ErrInvalidProcess", err)
+	}
+	if _, err := NewProcessScope(&Request{}, 0, false); !errors.Is(err, ErrInvalidProcess) {
+		t.Errorf("no chain = %v, want ErrInvalidProcess", err)
+	}
+}
+
+func TestProcessRule_Matching(t *testing.T) {
+	mgr := NewManager(ManagerConfig{Timeout: 5 * time.Second, HistoryMax: 100})
+	mgr.processStartTime = func(uint32) uint64 { return 0 }
+	mgr.processCheckInterval = time.Hour
+	items := []ItemInfo{{Path: "/org/freedesktop/secrets/collection/default/aws"}}
+	req := &Request{Type: RequestTypeGetSecret, Items: items, SenderInfo: agentChain(200, 7000)}
+
+	scope, _ := NewProcessScope(req, 0, false)
+	processID := mgr.AddProcessRule(req, scope)
+
+	tests := []struct {
+		name   string
+		sender SenderInfo
+		want   bool
+	}{
+		{"same process", agentChain(200, 7000), true},
+		{"reused PID", agentChain(200, 9000), false},
+		{"next run of the same exe", agentChain(201, 7100), false},
+	}
+	for _, tt := range tests {
+		if got := mgr.checkAutoApproveRules(tt.sender, items, RequestTypeGetSecret) != nil; got != tt.want {
+			t.Errorf("%s: matched = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if mgr.checkAutoApproveRules(agentChain(200, 7000), items, RequestTypeSearch) != nil {
+		t.Error("process rule must keep the request type")
+	}
+
+	// A subtree rule on the agent covers every tool it runs.
+	if err := mgr.RemoveAutoApproveRule(processID); err != nil {
+		t.Fatal(err)
+	}
+	scope, _ = NewProcessScope(req, 100, true)
+	mgr.AddProcessRule(req, scope)
+	if mgr.checkAutoApproveRules(agentChain(201, 7100), items, RequestTypeGetSecret) == nil {
+		t.Error("subtree rule should cover another child of the agent")
+	}
+	other := agentChain(201, 7100)
+	other.ProcessChain[1].StartTime = 5500 // a different agent run with a reused PID
+	if mgr.checkAutoApproveRules(other, items, RequestTypeGetSecret) != nil {
+		t.Error("subtree rule must not cover children of another agent run")
+	}
+
+	rules := mgr.ListAutoApproveRules()
+	if len(rules) != 1 || !rules[0].ExpiresAt.IsZero() {
+		t.Errorf("rules = %+v, want one rule without expiry", rules)
+	}
+}
+
+func TestApproveForProcess(t *testing.T) {
+	mgr := NewManager(ManagerConfig{Timeout: 5 * time.Second, HistoryMax: 100})
+	mgr.processStartTime = func(uint32) uint64 { return 0 }
+	mgr.processCheckInterval = time.Hour
+	items := []ItemInfo{{Path: "/org/freedesktop/secrets/collection/default/aws"}}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := mgr.RequireApproval(context.Background(), "local", items, "", RequestTypeGetSecret, nil, agentChain(200, 7000))
+		done <- err
+	}()
+	req := waitPending(t, mgr)
+	if err := mgr.ApproveForProcess(req.ID, 999, false); !errors.Is(err, ErrInvalidProcess) {
+		t.Fatalf("foreign PID = %v, want ErrInvalidProcess", err)
+	}
+	if mgr.GetPending(req.ID) == nil {
+		t.Fatal("a rejected scope must leave the request pending")
+	}
+	if err := mgr.ApproveForProcess(req.ID, 0, false); err != nil {
+		t.Fatalf("ApproveForProcess: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("RequireApproval = %v", err)
+	}
+
+	other := []ItemInfo{{Path: "/org/freedesktop/secrets/collection/default/aws2"}}
+	auto, err := mgr.RequireApproval(context.Background(), "local", other, "", RequestTypeGetSecret, nil, agentChain(200, 7000))
+	if err != nil || !auto {
+		t.Errorf("same process = %v, %v; want auto-approved", auto, err)
+	}
+}
+
+func TestProcessRule_RevokedOnExit(t *testing.T) {
+	mgr := NewManager(ManagerConfig{Timeout: 5 * time.Second, HistoryMax: 100, AutoApproveDuration: time.Minute})
+	var mu sync.Mutex
+	running := map[uint32]uint64{200: 7000}
+	mgr.processStartTime = func(pid uint32) uint64 {
+		mu.Lock()
+		defer mu.Unlock()
+		return running[pid]
+	}
+	mgr.processCheckInterval = 10 * time.Millisecond
+	obs := &testObserver{}
+	mgr.Subscribe(obs)
+
+	req := &Request{Type: RequestTypeGetSecret, SenderInfo: agentChain(200, 7000)}
+	scope, _ := NewProcessScope(req, 0, false)
+	id := mgr.AddProcessRule(req, scope)
+	mgr.AddAutoApproveRule(&Request{Type: RequestTypeGetSecret, SenderInfo: testSender("gh", "/usr/bin/gh")})
+
+	time.Sleep(50 * time.Millisecond)
+	if n := len(mgr.ListAutoApproveRules()); n != 2 {
+		t.Fatalf("got %d rules while the process runs, want 2", n)
+	}
+
+	mu.Lock()
+	delete(running, 200)
+	mu.Unlock()
+
+	var removed *AutoApproveRule
+	for _, e := range obs.WaitForEvents(3, time.Second) {
+		if e.Type == EventAutoApproveRuleRemoved {
+			removed = e.Rule
+		}
+	}
+	if removed == nil || removed.ID != id {
+		t.Fatalf("removed = %+v, want the process rule", removed)
+	}
+	rules := mgr.ListAutoApproveRules()
+	if len(rules) != 1 || rules[0].Process != nil {
+		t.Errorf("rules = %+v, want only the timed rule", rules)
+	}
+}
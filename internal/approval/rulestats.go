@@ -0,0 +1,184 @@
+package approval
+
+import (
+	"log/slog"
+	"maps"
+	"slices"
+	"time"
+)
+
+const (
+	// maxStatExecutables caps the distinct executables remembered per rule;
+	// a rule that reaches it is broad enough to flag anyway.
+	maxStatExecutables = 20
+	// ruleStatsFlushDelay batches the writes of a burst of rule hits.
+	ruleStatsFlushDelay = 5 * time.Second
+)
+
+// RuleStat records how often a config trust rule has decided a request.
+type RuleStat struct {
+	Rule        string    `json:"rule"`
+	Hits        int       `json:"hits"`
+	LastHit     time.Time `json:"last_hit,omitzero"`
+	Since       time.Time `json:"since"`                 // when counting began for this rule
+	Executables []string  `json:"executables,omitempty"` // distinct requesting executables, at most maxStatExecutables
+}
+
+// RuleStatsStore persists rule statistics across restarts.
+type RuleStatsStore interface {
+	Load() ([]RuleStat, error)
+	Save([]RuleStat) error
+}
+
+// loadRuleStats seeds the statistics from the store, if any.
+func (m *Manager) loadRuleStats() {
+	if m.statsStore == nil {
+		return
+	}
+	stats, err := m.statsStore.Load()
+	if err != nil {
+		slog.Warn("failed to load rule statistics", "error", err)
+		return
+	}
+	for i := range stats {
+		m.ruleStats[stats[i].Rule] = &stats[i]
+	}
+}
+
+// trackRules starts counting for newly configured rules and forgets the
+// statistics of rules that are no longer configured.
+func (m *Manager) trackRules(rules []TrustRule) {
+	m.statsMu.Lock()
+	defer m.statsMu.Unlock()
+
+	now := time.Now()
+	configured := make(map[string]bool, len(rules))
+	changed := false
+	for _, r := range rules {
+		label := r.Label()
+		configured[label] = true
+		if m.ruleStats[label] == nil {
+			m.ruleStats[label] = &RuleStat{Rule: label, Since: now}
+			changed = true
+		}
+	}
+	for label := range m.ruleStats {
+		if !configured[label] {
+			delete(m.ruleStats, label)
+			changed = true
+		}
+	}
+	if changed {
+		m.scheduleStatsFlush()
+	}
+}
+
+// recordRuleHit counts a request decided by the trust rule with the given
+// label. Labels that are not configured rules (client defaults) are not
+// counted.
+func (m *Manager) recordRuleHit(label string, req *Request, at time.Time) {
+	m.statsMu.Lock()
+	defer m.statsMu.Unlock()
+
+	stat := m.ruleStats[label]
+	if stat == nil {
+		return
+	}
+	stat.Hits++
+	stat.LastHit = at
+	if exe := requestExe(req.SenderInfo); exe != "" && !slices.Contains(stat.Executables, exe) && len(stat.Executables) < maxStatExecutables {
+		stat.Executables = append(stat.Executables, exe)
+	}
+	m.scheduleStatsFlush()
+}
+
+// requestExe returns the executable a request is attributed to: the invoker's,
+// or the nearest process in the chain that has one.
+func requestExe(s SenderInfo) string {
+	if exe := invokerExePath(s); exe != "" {
+		return exe
+	}
+	for _, p := range s.ProcessChain {
+		if p.Exe != "" {
+			return p.Exe
+		}
+	}
+	return ""
+}
+
+// scheduleStatsFlush arranges for the statistics to be saved shortly. Caller
+// holds statsMu.
+func (m *Manager) scheduleStatsFlush() {
+	if m.statsStore != nil && m.statsTimer == nil {
+		m.statsTimer = time.AfterFunc(ruleStatsFlushDelay, m.FlushRuleStats)
+	}
+}
+
+// FlushRuleStats saves the rule statistics now. serve calls it on shutdown so
+// the last few seconds of hits are not lost.
+func (m *Manager) FlushRuleStats() {
+	if m.statsStore == nil {
+		return
+	}
+	m.statsSaveMu.Lock()
+	defer m.statsSaveMu.Unlock()
+
+	m.statsMu.Lock()
+	if m.statsTimer != nil {
+		m.statsTimer.Stop()
+		m.statsTimer = nil
+	}
+	stats := make([]RuleStat, 0, len(m.ruleStats))
+	for _, label := range slices.Sorted(maps.Keys(m.ruleStats)) {
+		stats = append(stats, copyRuleStat(m.ruleStats[label]))
+	}
+	m.statsMu.Unlock()
+
+	if err := m.statsStore.Save(stats); err != nil {
+		slog.Warn("failed to save rule statistics", "error", err)
+	}
+}
+
+// RuleStats returns the statistics of every configured trust rule, in config
+// order. Rules that never matched have zero hits.
+func (m *Manager) RuleStats() []RuleStat {
+	rules := m.ListTrustRules()
+
+	m.statsMu.Lock()
+	defer m.statsMu.Unlock()
+	out := make([]RuleStat, 0, len(rules))
+	seen := map[string]bool{}
+	for _, r := range rules {
+		label := r.Label()
+		if seen[label] {
+			continue
+		}
+		seen[label] = true
+		if stat := m.ruleStats[label]; stat != nil {
+			out = append(out, copyRuleStat(stat))
+		}
+	}
+	return out
+}
+
+func copyRuleStat(s *RuleStat) RuleStat {
+	c := *s
+	c.Executables = slices.Clone(s.Executables)
+	return c
+}
+
+// Unused reports whether the rule has not matched for longer than d. A rule is
+// never unused before it has existed for d, so new rules are not flagged.
+func (s RuleStat) Unused(now time.Time, d time.Duration) bool {
+	last := s.LastHit
+	if last.Before(s.Since) {
+		last = s.Since
+	}
+	return now.Sub(last) > d
+}
+
+// Broad reports whether more than max distinct executables matched the rule,
+// a sign that it is looser than intended.
+func (s RuleStat) Broad(max int) bool {
+	return len(s.Executables) > max
+}
@@ -0,0 +1,179 @@
+package approval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// memStatsStore is an in-memory RuleStatsStore.
+type memStatsStore struct {
+	stats []RuleStat
+	saves int
+}
+
+func (s *memStatsStore) Load() ([]RuleStat, error) { return s.stats, nil }
+
+func (s *memStatsStore) Save(stats []RuleStat) error {
+	s.stats = stats
+	s.saves++
+	return nil
+}
+
+func TestRuleStats_CountsHitsAndNamesRuleInHistory(t *testing.T) {
+	store := &memStatsStore{}
+	mgr := NewManager(ManagerConfig{
+		Timeout:    5 * time.Second,
+		HistoryMax: 100,
+		TrustRules: []TrustRule{
+			{Name: "no-github", Action: "deny", Secret: &SecretMatcher{Attributes: map[string]string{"service": "github"}}},
+			{Name: "shells", Process: &ProcessMatcher{Exe: "/usr/bin/*sh"}},
+			{Name: "never", Process: &ProcessMatcher{Exe: "/opt/never"}},
+		},
+		ClientPolicies: []ClientPolicy{{Client: "build-*", Default: "deny"}},
+		RuleStatsStore: store,
+	})
+	item := []ItemInfo{{Path: "/org/freedesktop/secrets/collection/default/1"}}
+	from := func(exe string) SenderInfo {
+		return SenderInfo{ProcessChain: []ProcessInfo{{Name: "x", Exe: exe}}}
+	}
+
+	for _, exe := range []string{"/usr/bin/bash", "/usr/bin/zsh", "/usr/bin/bash"} {
+		if _, err := mgr.RequireApproval(context.Background(), "local", item, "", RequestTypeGetSecret, nil, from(exe)); err != nil {
+			t.Fatalf("RequireApproval(%s) = %v", exe, err)
+		}
+	}
+	github := []ItemInfo{{Path: item[0].Path, Attributes: map[string]string{"service": "github"}}}
+	if _, err := mgr.RequireApproval(context.Background(), "local", github, "", RequestTypeGetSecret, nil, from("/usr/bin/bash")); !errors.Is(err, ErrDeniedByRule) {
+		t.Fatalf("github secret = %v, want ErrDeniedByRule", err)
+	}
+	if _, err := mgr.RequireApproval(context.Background(), "build-01", item, "", RequestTypeGetSecret, nil, SenderInfo{}); !errors.Is(err, ErrDeniedByRule) {
+		t.Fatalf("client default = %v, want ErrDeniedByRule", err)
+	}
+
+	history := mgr.History()
+	if len(history) != 5 {
+		t.Fatalf("got %d history entries, want 5", len(history))
+	}
+	if history[0].Rule != "client default: build-*" || history[1].Rule != "no-github" || history[2].Rule != "shells" {
+		t.Errorf("history rules = %q, %q, %q", history[0].Rule, history[1].Rule, history[2].Rule)
+	}
+
+	stats := mgr.RuleStats()
+	if len(stats) != 3 {
+		t.Fatalf("got %d rule stats, want 3 (client defaults are not counted): %+v", len(stats), stats)
+	}
+	byName := map[string]RuleStat{}
+	for _, s := range stats {
+		byName[s.Rule] = s
+	}
+	if s := byName["shells"]; s.Hits != 3 || len(s.Executables) != 2 || s.LastHit.IsZero() {
+		t.Errorf("shells = %+v, want 3 hits from 2 executables", s)
+	}
+	if s := byName["no-github"]; s.Hits != 1 {
+		t.Errorf("no-github hits = %d, want 1", s.Hits)
+	}
+	if s := byName["never"]; s.Hits != 0 || !s.LastHit.IsZero() || s.Since.IsZero() {
+		t.Errorf("never = %+v, want no hits and a start time", s)
+	}
+
+	mgr.FlushRuleStats()
+	if store.saves == 0 || len(store.stats) != 3 {
+		t.Fatalf("flush saved %d stats in %d saves", len(store.stats), store.saves)
+	}
+
+	// A restarted manager picks the counts up again.
+	restarted := NewManager(ManagerConfig{
+		Timeout:        5 * time.Second,
+		HistoryMax:     100,
+		TrustRules:     []TrustRule{{Name: "shells", Process: &ProcessMatcher{Exe: "/usr/bin/*sh"}}},
+		RuleStatsStore: store,
+	})
+	if got := restarted.RuleStats(); len(got) != 1 || got[0].Hits != 3 {
+		t.Errorf("after restart = %+v, want shells with 3 hits", got)
+	}
+}
+
+func TestRuleStats_ReloadTracksRules(t *testing.T) {
+	mgr := NewManager(ManagerConfig{
+		Timeout:    5 * time.Second,
+		HistoryMax: 100,
+		TrustRules: []TrustRule{{Name: "a"}, {Name: "b"}},
+	})
+	mgr.recordRuleHit("a", &Request{}, time.Now())
+
+	mgr.SetTrustConfig(TrustConfig{Rules: []TrustRule{{Name: "a"}, {Name: "c"}, {}}})
+	stats := mgr.RuleStats()
+	if len(stats) != 3 || stats[0].Rule != "a" || stats[0].Hits != 1 || stats[1].Rule != "c" || stats[2].Rule != (&TrustRule{}).Label() {
+		t.Errorf("after reload = %+v, want a (1 hit), c, then the unnamed rule", stats)
+	}
+}
+
+func TestRuleStats_UnnamedRules(t *testing.T) {
+	shells := TrustRule{Process: &ProcessMatcher{Exe: "/usr/bin/*sh"}}
+	mgr := NewManager(ManagerConfig{Timeout: 5 * time.Second, HistoryMax: 100, TrustRules: []TrustRule{shells}})
+	item := []ItemInfo{{Path: "/org/freedesktop/secrets/collection/default/1"}}
+	sender := SenderInfo{ProcessChain: []ProcessInfo{{Name: "bash", Exe: "/usr/bin/bash"}}}
+	if _, err := mgr.RequireApproval(context.Background(), "local", item, "", RequestTypeGetSecret, nil, sender); err != nil {
+		t.Fatal(err)
+	}
+
+	label := shells.Label()
+	if len(label) != 9 || label[0] != '#' {
+		t.Fatalf("label = %q, want # and 8 hex digits", label)
+	}
+	if rule := mgr.History()[0].Rule; rule != label {
+		t.Errorf("history rule = %q, want %q", rule, label)
+	}
+	if stats := mgr.RuleStats(); len(stats) != 1 || stats[0].Rule != label || stats[0].Hits != 1 {
+		t.Errorf("stats = %+v, want one hit for %s", stats, label)
+	}
+
+	// Reloading the same rule keeps its count; editing it starts afresh.
+	mgr.SetTrustConfig(TrustConfig{Rules: []TrustRule{{Process: &ProcessMatcher{Exe: "/usr/bin/*sh"}}}})
+	if stats := mgr.RuleStats(); len(stats) != 1 || stats[0].Hits != 1 {
+		t.Errorf("after reload = %+v, want the hit kept", stats)
+	}
+	mgr.SetTrustConfig(TrustConfig{Rules: []TrustRule{{Process: &ProcessMatcher{Exe: "/usr/bin/bash"}}}})
+	if stats := mgr.RuleStats(); len(stats) != 1 || stats[0].Rule == label || stats[0].Hits != 0 {
+		t.Errorf("after edit = %+v, want a new rule with no hits", stats)
+	}
+}
+
+func TestRuleStats_ExecutablesCapped(t *testing.T) {
+	mgr := NewManager(ManagerConfig{Timeout: 5 * time.Second, HistoryMax: 100, TrustRules: []TrustRule{{Name: "any"}}})
+	for i := range maxStatExecutables + 5 {
+		mgr.recordRuleHit("any", &Request{SenderInfo: SenderInfo{ProcessChain: []ProcessInfo{{Exe: fmt.Sprintf("/bin/p%d", i)}}}}, time.Now())
+	}
+	s := mgr.RuleStats()[0]
+	if s.Hits != maxStatExecutables+5 || len(s.Executables) != maxStatExecutables {
+		t.Errorf("hits = %d, executables = %d", s.Hits, len(s.Executables))
+	}
+}
+
+func TestRuleStat_Flags(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	month := 30 * 24 * time.Hour
+	tests := []struct {
+		name string
+		stat RuleStat
+		want bool
+	}{
+		{"new rule", RuleStat{Since: now.Add(-time.Hour)}, false},
+		{"old rule never hit", RuleStat{Since: now.Add(-2 * month)}, true},
+		{"recent hit", RuleStat{Since: now.Add(-2 * month), LastHit: now.Add(-time.Hour)}, false},
+		{"stale hit", RuleStat{Since: now.Add(-3 * month), LastHit: now.Add(-2 * month)}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.stat.Unused(now, month); got != tt.want {
+			t.Errorf("%s: Unused = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	s := RuleStat{Executables: []string{"/a", "/b", "/c"}}
+	if s.Broad(3) || !s.Broad(2) {
+		t.Errorf("Broad(3) = %v, Broad(2) = %v; want false, true", s.Broad(3), s.Broad(2))
+	}
+}
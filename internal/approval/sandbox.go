@@ -0,0 +1,15 @@
+package approval
+
+import "github.com/nikicat/secrets-dispatcher/internal/procutil"
+
+// ReadSandbox records the Flatpak/Snap app and the container of process pid,
+// the one that opened the caller's connection. Host processes get neither.
+func (s *SenderInfo) ReadSandbox(pid int32) {
+	if app, ok := procutil.ReadApp(pid); ok {
+		s.AppID = app.ID
+		s.AppSandbox = app.Sandbox
+	}
+	if c, ok := procutil.ReadContainer(pid); ok {
+		s.Container = &ContainerInfo{Runtime: c.Runtime, ID: c.ID, Name: c.Name, Image: c.Image}
+	}
+}
@@ -0,0 +1,158 @@
+package approval
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+)
+
+// ErrNoSeries is returned when a series approval is asked for a request that
+// is not part of a signing series with commits left to sign.
+var ErrNoSeries = errors.New("request is not part of a signing series with commits left")
+
+// SignSeries is the multi-commit operation a commit signing request belongs
+// to. git signs each commit of a rebase, a multi-commit cherry-pick or revert,
+// or an am with a separate gpg.program call, all from the one git process
+// running the operation.
+type SignSeries struct {
+	Operation string `json:"operation"` // "rebase", "cherry-pick", "revert" or "am"
+	// Position is the 1-based place of the commit being signed among Total.
+	// cherry-pick and revert do not record the commits they have finished,
+	// so for them the series starts at the current commit.
+	Position int `json:"position"`
+	Total    int `json:"total"`
+	// Commits lists the series in order, cut after the first few hundred.
+	Commits []SeriesCommit `json:"commits"`
+	// Steps lists the other lines of the todo still to run, as written: exec,
+	// break, reset and the like create no commit, but run commands or move
+	// HEAD between the commits a series approval lets through.
+	Steps []string `json:"steps,omitempty"`
+}
+
+// SeriesCommit is one commit of a SignSeries: the original being rebased,
+// picked or reverted (Hash is empty for an am patch without one).
+type SeriesCommit struct {
+	Hash    string `json:"hash,omitempty"`
+	Subject string `json:"subject"`
+}
+
+// Remaining is the number of commits the series will still sign after the
+// current one.
+func (s *SignSeries) Remaining() int {
+	return max(s.Total-s.Position, 0)
+}
+
+// Current returns the commit being signed, if Commits lists it.
+func (s *SignSeries) Current() (SeriesCommit, bool) {
+	if s.Position < 1 || s.Position > len(s.Commits) {
+		return SeriesCommit{}, false
+	}
+	return s.Commits[s.Position-1], true
+}
+
+// SeriesGrant lets the rest of one signing series through without a prompt:
+// the Commits of Operation in Repo that were shown when it was approved, each
+// in its turn, signed from the git process that runs it. That process exits
+// when the operation finishes or stops (a conflict, an edit), so the grant
+// never outlives it.
+type SeriesGrant struct {
+	Operation string       `json:"operation"`
+	Repo      string       `json:"repo"`
+	Process   ProcessScope `json:"process"`
+	// Commits are the commits still to sign, next first.
+	Commits []SeriesCommit `json:"commits"`
+}
+
+// NewSeriesGrant returns the grant that approving req as a series would add.
+// req must be a verified commit signing request that is part of a series with
+// commits left, made directly by a git process whose start time is known.
+func NewSeriesGrant(req *Request) (*SeriesGrant, error) {
+	info := req.GPGSignInfo
+	if req.Type != RequestTypeGPGSign || info == nil || info.Series == nil || info.Series.Remaining() == 0 {
+		return nil, ErrNoSeries
+	}
+	if info.Verification == nil || !info.Verification.Verified {
+		return nil, fmt.Errorf("%w: the request is not verified against the caller's repository", ErrNoSeries)
+	}
+	chain := req.SenderInfo.ProcessChain
+	if len(chain) < 2 || chain[1].StartTime == 0 {
+		return nil, fmt.Errorf("%w: the git process running it is unknown", ErrNoSeries)
+	}
+	// Only the commits listed, and so shown, are granted.
+	if info.Series.Position >= len(info.Series.Commits) {
+		return nil, fmt.Errorf("%w: the commits left are not listed", ErrNoSeries)
+	}
+	git := chain[1]
+	return &SeriesGrant{
+		Operation: info.Series.Operation,
+		Repo:      info.Verification.Repo,
+		Process:   ProcessScope{PID: git.PID, StartTime: git.StartTime, Name: git.Name, Exe: git.Exe},
+		Commits:   slices.Clone(info.Series.Commits[info.Series.Position:]),
+	}, nil
+}
+
+// AddSeriesGrant records grant, replacing any earlier grant for the same
+// process and repository.
+func (m *Manager) AddSeriesGrant(grant *SeriesGrant) {
+	m.seriesMu.Lock()
+	m.seriesGrants = slices.DeleteFunc(m.seriesGrants, func(g SeriesGrant) bool {
+		return g.Process == grant.Process && g.Repo == grant.Repo || !g.Process.alive(m.processStartTime)
+	})
+	m.seriesGrants = append(m.seriesGrants, *grant)
+	m.seriesMu.Unlock()
+
+	slog.Info("signing series approved",
+		"operation", grant.Operation,
+		"repo", grant.Repo,
+		"pid", grant.Process.PID,
+		"remaining", len(grant.Commits))
+}
+
+// UseSeriesGrant reports whether a grant covers a commit signing request and,
+// if so, uses up one of its commits. The request must be verified, be part of
+// the same operation in the same repository, come directly from the granted
+// git process while it still runs, and sign the grant's next commit. A grant
+// whose process signs anything else is revoked: the series is no longer the
+// one approved.
+func (m *Manager) UseSeriesGrant(senderInfo SenderInfo, info *GPGSignInfo) bool {
+	if info == nil || info.Series == nil || info.Verification == nil || !info.Verification.Verified {
+		return false
+	}
+	current, ok := info.Series.Current()
+	if !ok {
+		return false
+	}
+	chain := senderInfo.ProcessChain
+	if len(chain) < 2 {
+		return false
+	}
+
+	m.seriesMu.Lock()
+	defer m.seriesMu.Unlock()
+	m.seriesGrants = slices.DeleteFunc(m.seriesGrants, func(g SeriesGrant) bool {
+		return !g.Process.alive(m.processStartTime)
+	})
+	for i := range m.seriesGrants {
+		g := &m.seriesGrants[i]
+		if g.Process.PID != chain[1].PID || g.Process.StartTime != chain[1].StartTime ||
+			g.Repo != info.Verification.Repo || g.Operation != info.Series.Operation {
+			continue
+		}
+		if g.Commits[0] != current {
+			slog.Warn("signing series revoked: not the approved next commit",
+				"repo", g.Repo,
+				"pid", g.Process.PID,
+				"want", g.Commits[0].Hash,
+				"got", current.Hash)
+			m.seriesGrants = slices.Delete(m.seriesGrants, i, i+1)
+			return false
+		}
+		g.Commits = g.Commits[1:]
+		if len(g.Commits) == 0 {
+			m.seriesGrants = slices.Delete(m.seriesGrants, i, i+1)
+		}
+		return true
+	}
+	return false
+}
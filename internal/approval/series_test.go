@@ -0,0 +1,164 @@
+package approval
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// seriesRequest is a verified request to sign commit 2 of a 4-commit rebase,
+// made by gpg.program (PID 300) under git (PID 200).
+func seriesRequest() *Request {
+	return &Request{
+		Type: RequestTypeGPGSign,
+		GPGSignInfo: &GPGSignInfo{
+			Series: &SignSeries{Operation: "rebase", Position: 2, Total: 4, Commits: []SeriesCommit{
+				{Hash: "1111111", Subject: "first"},
+				{Hash: "2222222", Subject: "second"},
+				{Hash: "3333333", Subject: "third"},
+				{Hash: "4444444", Subject: "fourth"},
+			}},
+			Verification: &SignVerification{Repo: "/home/u/repo", Verified: true},
+		},
+		SenderInfo: SenderInfo{
+			PID: 300,
+			ProcessChain: []ProcessInfo{
+				{Name: "secrets-dispatcher", PID: 300, StartTime: 9000},
+				{Name: "git", PID: 200, StartTime: 7000, Exe: "/usr/bin/git"},
+			},
+		},
+	}
+}
+
+func TestNewSeriesGrant(t *testing.T) {
+	grant, err := NewSeriesGrant(seriesRequest())
+	if err != nil {
+		t.Fatalf("NewSeriesGrant: %v", err)
+	}
+	want := SeriesGrant{
+		Operation: "rebase",
+		Repo:      "/home/u/repo",
+		Process:   ProcessScope{PID: 200, StartTime: 7000, Name: "git", Exe: "/usr/bin/git"},
+		Commits:   []SeriesCommit{{Hash: "3333333", Subject: "third"}, {Hash: "4444444", Subject: "fourth"}},
+	}
+	if !reflect.DeepEqual(*grant, want) {
+		t.Errorf("grant = %+v, want %+v", *grant, want)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*Request)
+	}{
+		{"not gpg_sign", func(r *Request) { r.Type = RequestTypeGetSecret }},
+		{"no series", func(r *Request) { r.GPGSignInfo.Series = nil }},
+		{"last commit", func(r *Request) { r.GPGSignInfo.Series.Position = 4 }},
+		{"unverified", func(r *Request) { r.GPGSignInfo.Verification.Verified = false }},
+		{"no verification", func(r *Request) { r.GPGSignInfo.Verification = nil }},
+		{"no git process", func(r *Request) { r.SenderInfo.ProcessChain = r.SenderInfo.ProcessChain[:1] }},
+		{"unknown start time", func(r *Request) { r.SenderInfo.ProcessChain[1].StartTime = 0 }},
+		{"commits left not listed", func(r *Request) { r.GPGSignInfo.Series.Commits = r.GPGSignInfo.Series.Commits[:2] }},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := seriesRequest()
+			tc.modify(req)
+			if _, err := NewSeriesGrant(req); !errors.Is(err, ErrNoSeries) {
+				t.Errorf("err = %v, want ErrNoSeries", err)
+			}
+		})
+	}
+}
+
+func TestUseSeriesGrant(t *testing.T) {
+	mgr := NewManager(ManagerConfig{Timeout: 5 * time.Second, HistoryMax: 100})
+	gitAlive := true
+	mgr.processStartTime = func(pid uint32) uint64 {
+		if pid == 200 && gitAlive {
+			return 7000
+		}
+		return 0
+	}
+
+	req := seriesRequest()
+	grant, err := NewSeriesGrant(req)
+	if err != nil {
+		t.Fatalf("NewSeriesGrant: %v", err)
+	}
+	mgr.AddSeriesGrant(grant)
+
+	at := func(position int) *Request {
+		r := seriesRequest()
+		r.GPGSignInfo.Series.Position = position
+		return r
+	}
+
+	other := seriesRequest()
+	other.GPGSignInfo.Verification.Repo = "/home/u/other"
+	if mgr.UseSeriesGrant(other.SenderInfo, other.GPGSignInfo) {
+		t.Error("grant covered another repository")
+	}
+	other = seriesRequest()
+	other.GPGSignInfo.Series.Operation = "cherry-pick"
+	if mgr.UseSeriesGrant(other.SenderInfo, other.GPGSignInfo) {
+		t.Error("grant covered another operation")
+	}
+	other = seriesRequest()
+	other.SenderInfo.ProcessChain[1].StartTime = 8000
+	if mgr.UseSeriesGrant(other.SenderInfo, other.GPGSignInfo) {
+		t.Error("grant covered a reused PID")
+	}
+	other = seriesRequest()
+	other.GPGSignInfo.Verification.Verified = false
+	if mgr.UseSeriesGrant(other.SenderInfo, other.GPGSignInfo) {
+		t.Error("grant covered an unverified request")
+	}
+
+	for _, position := range []int{3, 4} {
+		next := at(position)
+		if !mgr.UseSeriesGrant(next.SenderInfo, next.GPGSignInfo) {
+			t.Fatalf("commit %d of the series not covered", position)
+		}
+	}
+	if next := at(4); mgr.UseSeriesGrant(next.SenderInfo, next.GPGSignInfo) {
+		t.Error("grant covered more commits than the series had left")
+	}
+
+	mgr.AddSeriesGrant(grant)
+	gitAlive = false
+	if next := at(3); mgr.UseSeriesGrant(next.SenderInfo, next.GPGSignInfo) {
+		t.Error("grant outlived the git process")
+	}
+}
+
+func TestUseSeriesGrant_PinnedToApprovedCommits(t *testing.T) {
+	mgr := NewManager(ManagerConfig{Timeout: 5 * time.Second, HistoryMax: 100})
+	mgr.processStartTime = func(pid uint32) uint64 { return 7000 }
+
+	grant, err := NewSeriesGrant(seriesRequest())
+	if err != nil {
+		t.Fatalf("NewSeriesGrant: %v", err)
+	}
+
+	// The todo was rewritten after the approval: another commit comes next.
+	mgr.AddSeriesGrant(grant)
+	swapped := seriesRequest()
+	swapped.GPGSignInfo.Series.Position = 3
+	swapped.GPGSignInfo.Series.Commits[2] = SeriesCommit{Hash: "9999999", Subject: "third"}
+	if mgr.UseSeriesGrant(swapped.SenderInfo, swapped.GPGSignInfo) {
+		t.Error("grant covered a commit that was not approved")
+	}
+	next := seriesRequest()
+	next.GPGSignInfo.Series.Position = 3
+	if mgr.UseSeriesGrant(next.SenderInfo, next.GPGSignInfo) {
+		t.Error("grant survived signing a commit that was not approved")
+	}
+
+	// Skipping ahead does not use the grant for a later commit either.
+	mgr.AddSeriesGrant(grant)
+	skipped := seriesRequest()
+	skipped.GPGSignInfo.Series.Position = 4
+	if mgr.UseSeriesGrant(skipped.SenderInfo, skipped.GPGSignInfo) {
+		t.Error("grant covered a commit out of order")
+	}
+}
@@ -0,0 +1,240 @@
+package approval
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// wrapperExes are generic launchers that sit between the user and the real
+// application in a process chain. A rule keyed on one of them would match
+// nearly everything, so SuggestTrustRule walks past them.
+var wrapperExes = map[string]bool{
+	"sh": true, "bash": true, "dash": true, "zsh": true, "fish": true, "ksh": true,
+	"env": true, "sudo": true, "doas": true, "nohup": true, "timeout": true,
+	"xargs": true, "flock": true, "setsid": true, "secret-tool": true,
+	"gpg": true, "gpg2": true,
+}
+
+// sessionExes are session infrastructure (terminals, multiplexers, service
+// managers). Reaching one while walking up the chain means every process
+// above the invoker was a wrapper, so the suggestion falls back to the
+// invoker itself rather than trusting the whole terminal or session.
+var sessionExes = map[string]bool{
+	"systemd": true, "init": true, "sshd": true, "login": true, "tmux": true, "screen": true,
+	"gnome-terminal-server": true, "konsole": true, "kitty": true, "alacritty": true,
+	"foot": true, "wezterm-gui": true, "xterm": true, "ptyxis-agent": true,
+}
+
+// SuggestTrustRule derives an approve rule from a pending or historical request,
+// for the user to narrow and save to config.yaml.
+//
+// The process is matched on the kernel-resolved exe of the application that
+// made the request: the chain is walked up from the invoker past generic
+// wrappers (shells, secret-tool, sudo). A sandboxed app is matched on its
+// Flatpak or Snap application ID as well, which any unsandboxed process can
+// claim and so does not do alone. The secret scope is the collection and
+// the attributes shared by every requested item (the search criteria for
+// search requests), with glob metacharacters escaped so they match literally.
+// For SSH signing it is the key fingerprint and the destination host; for
+// gpg-agent decryption and signing, the keygrip.
+func SuggestTrustRule(req *Request) (TrustRule, error) {
+	switch req.Type {
+	case RequestTypeGetSecret, RequestTypeSearch, RequestTypeDelete, RequestTypeWrite, RequestTypeUnlock,
+		RequestTypeSSHSign, RequestTypePGPDecrypt, RequestTypePGPSign:
+	default:
+		return TrustRule{}, fmt.Errorf("request type %s is not covered by trust rules", req.Type)
+	}
+
+	rule := TrustRule{
+		Action:       "approve",
+		RequestTypes: []string{string(req.Type)},
+	}
+
+	if appID := req.SenderInfo.AppID; appID != "" {
+		rule.Process = &ProcessMatcher{AppID: escapeGlob(appID), Exe: escapeGlob(applicationExe(req.SenderInfo.ProcessChain))}
+		rule.Name = appID
+	} else if exe := applicationExe(req.SenderInfo.ProcessChain); exe != "" {
+		rule.Process = &ProcessMatcher{Exe: escapeGlob(exe)}
+		rule.Name = filepath.Base(exe)
+	} else if unit := req.SenderInfo.SystemdUnit; unit != "" {
+		rule.Process = &ProcessMatcher{Unit: escapeGlob(unit)}
+		rule.Name = strings.TrimSuffix(unit, filepath.Ext(unit))
+	} else {
+		return TrustRule{}, fmt.Errorf("request %s has no process executable or systemd unit to match", req.ID)
+	}
+
+	// A process in a container is pinned to that container's image (or name),
+	// so a rule made for its /usr/bin/python3 never also trusts the host's.
+	if c := req.SenderInfo.Container; c != nil {
+		rule.Process.Container = containerScope(c)
+	}
+
+	// Requests arriving over a remote socket are pinned to that client, so a
+	// rule made for a build host never also trusts the same exe locally.
+	if req.Client != "" && req.Client != "local" {
+		rule.Client = escapeGlob(req.Client)
+	}
+
+	if req.Type == RequestTypeSSHSign {
+		if sm := commonSSHScope(req.Items); sm != nil {
+			rule.SSH = sm
+			if sm.Destination != "" {
+				rule.Name += "-" + req.Items[0].Attributes["destination"]
+			}
+		}
+		return rule, nil
+	}
+
+	if req.Type == RequestTypePGPDecrypt || req.Type == RequestTypePGPSign {
+		if grip := commonKeygrip(req.Items); grip != "" {
+			rule.Secret = &SecretMatcher{Attributes: map[string]string{"keygrip": grip}}
+		}
+		return rule, nil
+	}
+
+	if req.Type == RequestTypeSearch {
+		if len(req.SearchAttributes) > 0 {
+			rule.SearchAttributes = escapeGlobValues(req.SearchAttributes)
+		}
+		return rule, nil
+	}
+
+	if sm := commonSecretScope(req.Items); sm != nil {
+		rule.Secret = sm
+		if sm.Collection != "" {
+			rule.Name += "-" + sm.Collection
+		}
+	}
+	return rule, nil
+}
+
+// applicationExe returns the exe of the first process in the chain (invoker
+// first) that is not a generic wrapper. If only wrappers precede session
+// infrastructure, or no process qualifies, it falls back to the invoker's exe.
+func applicationExe(chain []ProcessInfo) string {
+	invoker := ""
+	for _, proc := range chain {
+		if proc.Exe == "" {
+			continue
+		}
+		if invoker == "" {
+			invoker = proc.Exe
+		}
+		base := filepath.Base(proc.Exe)
+		if sessionExes[base] {
+			break
+		}
+		if !wrapperExes[base] {
+			return proc.Exe
+		}
+	}
+	return invoker
+}
+
+// containerScope returns a matcher for the most stable identity of container
+// c known: its image, else its name, else its ID.
+func containerScope(c *ContainerInfo) *ContainerMatcher {
+	cm := &ContainerMatcher{Runtime: c.Runtime}
+	switch {
+	case c.Image != "":
+		cm.Image = escapeGlob(c.Image)
+	case c.Name != "":
+		cm.Name = escapeGlob(c.Name)
+	default:
+		cm.ID = c.ID
+	}
+	return cm
+}
+
+// commonSecretScope returns a matcher for the collection and attributes shared
+// by all items, or nil if the items have nothing in common.
+func commonSecretScope(items []ItemInfo) *SecretMatcher {
+	if len(items) == 0 {
+		return nil
+	}
+	collection := extractCollection(items[0].Path)
+	attrs := make(map[string]string, len(items[0].Attributes))
+	for k, v := range items[0].Attributes {
+		attrs[k] = v
+	}
+	for _, it := range items[1:] {
+		if extractCollection(it.Path) != collection {
+			collection = ""
+		}
+		for k, v := range attrs {
+			if it.Attributes[k] != v {
+				delete(attrs, k)
+			}
+		}
+	}
+	if collection == "" && len(attrs) == 0 {
+		return nil
+	}
+	sm := &SecretMatcher{Collection: escapeGlob(collection)}
+	if len(attrs) > 0 {
+		sm.Attributes = escapeGlobValues(attrs)
+	}
+	return sm
+}
+
+// commonKeygrip returns the keygrip shared by all items of a gpg-agent
+// request, or "" if they have none in common.
+func commonKeygrip(items []ItemInfo) string {
+	if len(items) == 0 {
+		return ""
+	}
+	grip := items[0].Attributes["keygrip"]
+	for _, it := range items[1:] {
+		if it.Attributes["keygrip"] != grip {
+			return ""
+		}
+	}
+	return grip
+}
+
+// commonSSHScope returns a matcher for the key fingerprint and destination
+// shared by all items, or nil if they have neither in common.
+func commonSSHScope(items []ItemInfo) *SSHMatcher {
+	if len(items) == 0 {
+		return nil
+	}
+	fingerprint := items[0].Attributes["fingerprint"]
+	destination := items[0].Attributes["destination"]
+	for _, it := range items[1:] {
+		if it.Attributes["fingerprint"] != fingerprint {
+			fingerprint = ""
+		}
+		if it.Attributes["destination"] != destination {
+			destination = ""
+		}
+	}
+	if fingerprint == "" && destination == "" {
+		return nil
+	}
+	return &SSHMatcher{Fingerprint: fingerprint, Destination: escapeGlob(destination)}
+}
+
+// escapeGlob quotes path.Match metacharacters so s matches only itself.
+func escapeGlob(s string) string {
+	if !strings.ContainsAny(s, `*?[\`) {
+		return s
+	}
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func escapeGlobValues(m map[string]string) map[string]string {
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[k] = escapeGlob(v)
+	}
+	return out
+}
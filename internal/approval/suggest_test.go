@@ -0,0 +1,218 @@
+package approval
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSuggestTrustRule_WalksPastWrappers(t *testing.T) {
+	req := &Request{
+		ID:   "req-1",
+		Type: RequestTypeGetSecret,
+		Items: []ItemInfo{
+			{Path: "/org/freedesktop/secrets/collection/login/1", Attributes: map[string]string{"service": "gh:github.com", "username": "alice"}},
+			{Path: "/org/freedesktop/secrets/collection/login/2", Attributes: map[string]string{"service": "gh:github.com", "username": "bob"}},
+		},
+		SenderInfo: SenderInfo{ProcessChain: []ProcessInfo{
+			{Name: "secret-tool", PID: 30, Exe: "/usr/bin/secret-tool"},
+			{Name: "bash", PID: 20, Exe: "/usr/bin/bash"},
+			{Name: "gh", PID: 10, Exe: "/usr/bin/gh"},
+			{Name: "systemd", PID: 1, Exe: "/usr/lib/systemd/systemd"},
+		}},
+	}
+
+	rule, err := SuggestTrustRule(req)
+	if err != nil {
+		t.Fatalf("SuggestTrustRule: %v", err)
+	}
+	if rule.Process == nil || rule.Process.Exe != "/usr/bin/gh" {
+		t.Errorf("process = %+v, want exe /usr/bin/gh", rule.Process)
+	}
+	if rule.Name != "gh-login" {
+		t.Errorf("name = %q, want gh-login", rule.Name)
+	}
+	if rule.Secret == nil || rule.Secret.Collection != "login" {
+		t.Fatalf("secret = %+v, want collection login", rule.Secret)
+	}
+	// Only the attributes shared by every item are kept.
+	if len(rule.Secret.Attributes) != 1 || rule.Secret.Attributes["service"] != "gh:github.com" {
+		t.Errorf("attributes = %v, want only service", rule.Secret.Attributes)
+	}
+	if !matchTrustRule(&rule, req.Client, req.SenderInfo, req.Items, req.Type, nil) {
+		t.Error("suggested rule does not match the request it was derived from")
+	}
+}
+
+func TestSuggestTrustRule_StopsAtTerminal(t *testing.T) {
+	req := &Request{
+		Type: RequestTypeGetSecret,
+		SenderInfo: SenderInfo{ProcessChain: []ProcessInfo{
+			{Name: "secret-tool", Exe: "/usr/bin/secret-tool"},
+			{Name: "bash", Exe: "/usr/bin/bash"},
+			{Name: "gnome-terminal-", Exe: "/usr/libexec/gnome-terminal-server"},
+		}},
+	}
+	rule, err := SuggestTrustRule(req)
+	if err != nil {
+		t.Fatalf("SuggestTrustRule: %v", err)
+	}
+	// Never trust the whole terminal; fall back to the invoker.
+	if rule.Process.Exe != "/usr/bin/secret-tool" {
+		t.Errorf("exe = %q, want /usr/bin/secret-tool", rule.Process.Exe)
+	}
+}
+
+func TestSuggestTrustRule_SearchEscapesGlobs(t *testing.T) {
+	req := &Request{
+		Type:             RequestTypeSearch,
+		SearchAttributes: map[string]string{"url": "https://example.com/*"},
+		SenderInfo:       testSender("firefox", "/usr/lib/firefox/firefox"),
+	}
+	rule, err := SuggestTrustRule(req)
+	if err != nil {
+		t.Fatalf("SuggestTrustRule: %v", err)
+	}
+	if rule.Secret != nil {
+		t.Errorf("search rule should not carry a secret matcher, got %+v", rule.Secret)
+	}
+	if got := rule.SearchAttributes["url"]; got != `https://example.com/\*` {
+		t.Errorf("search attribute = %q, want escaped glob", got)
+	}
+	if !matchTrustRule(&rule, req.Client, req.SenderInfo, nil, req.Type, req.SearchAttributes) {
+		t.Error("suggested rule does not match the request it was derived from")
+	}
+	if matchTrustRule(&rule, req.Client, req.SenderInfo, nil, req.Type, map[string]string{"url": "https://example.com/other"}) {
+		t.Error("escaped glob should match only the literal value")
+	}
+}
+
+func TestSuggestTrustRule_PinsRemoteClient(t *testing.T) {
+	req := &Request{
+		Type:       RequestTypeGetSecret,
+		Client:     "build-01",
+		SenderInfo: testSender("make", "/usr/bin/make"),
+	}
+	rule, err := SuggestTrustRule(req)
+	if err != nil {
+		t.Fatalf("SuggestTrustRule: %v", err)
+	}
+	if rule.Client != "build-01" {
+		t.Errorf("client = %q, want build-01", rule.Client)
+	}
+	if matchTrustRule(&rule, "local", req.SenderInfo, nil, req.Type, nil) {
+		t.Error("rule derived from a remote request should not match locally")
+	}
+
+	req.Client = "local"
+	if rule, _ := SuggestTrustRule(req); rule.Client != "" {
+		t.Errorf("local request: client = %q, want empty", rule.Client)
+	}
+}
+
+func TestSuggestTrustRule_SandboxedApp(t *testing.T) {
+	sender := testSender("firefox", "/app/lib/firefox/firefox")
+	sender.AppID = "org.mozilla.firefox"
+	sender.AppSandbox = "flatpak"
+	rule, err := SuggestTrustRule(&Request{Type: RequestTypeGetSecret, SenderInfo: sender})
+	if err != nil {
+		t.Fatalf("SuggestTrustRule: %v", err)
+	}
+	if rule.Process == nil || rule.Process.AppID != "org.mozilla.firefox" || rule.Process.Exe != "/app/lib/firefox/firefox" {
+		t.Errorf("process = %+v, want app_id and exe", rule.Process)
+	}
+	if rule.Name != "org.mozilla.firefox" {
+		t.Errorf("name = %q", rule.Name)
+	}
+}
+
+func TestSuggestTrustRule_PinsContainer(t *testing.T) {
+	sender := testSender("python3", "/usr/bin/python3")
+	sender.Container = &ContainerInfo{Runtime: "podman", ID: strings.Repeat("a", 64), Name: "toolbox", Image: "registry.fedoraproject.org/fedora-toolbox:40"}
+	req := &Request{Type: RequestTypeGetSecret, SenderInfo: sender}
+	rule, err := SuggestTrustRule(req)
+	if err != nil {
+		t.Fatalf("SuggestTrustRule: %v", err)
+	}
+	want := ContainerMatcher{Runtime: "podman", Image: "registry.fedoraproject.org/fedora-toolbox:40"}
+	if rule.Process.Container == nil || *rule.Process.Container != want {
+		t.Fatalf("container = %+v, want %+v", rule.Process.Container, want)
+	}
+	if !matchTrustRule(&rule, "local", req.SenderInfo, nil, req.Type, nil) {
+		t.Error("rule should match the request it was derived from")
+	}
+	host := testSender("python3", "/usr/bin/python3")
+	if matchTrustRule(&rule, "local", host, nil, req.Type, nil) {
+		t.Error("rule derived in a container should not match the host")
+	}
+}
+
+func TestSuggestTrustRule_Unsupported(t *testing.T) {
+	if _, err := SuggestTrustRule(&Request{Type: RequestTypeGPGSign, SenderInfo: testSender("git", "/usr/bin/git")}); err == nil {
+		t.Error("expected error for gpg_sign request")
+	}
+	if _, err := SuggestTrustRule(&Request{Type: RequestTypeGetSecret}); err == nil {
+		t.Error("expected error for request without process info")
+	}
+}
+
+func TestSuggestTrustRule_SSHSign(t *testing.T) {
+	req := &Request{
+		ID:     "req-ssh",
+		Client: "ssh-agent",
+		Type:   RequestTypeSSHSign,
+		Items: []ItemInfo{{
+			Path:       "SHA256:abc",
+			Label:      "me@laptop",
+			Attributes: map[string]string{"fingerprint": "SHA256:abc", "comment": "me@laptop", "destination": "github.com"},
+		}},
+		SenderInfo: testSender("git", "/usr/bin/git"),
+	}
+	rule, err := SuggestTrustRule(req)
+	if err != nil {
+		t.Fatalf("SuggestTrustRule: %v", err)
+	}
+	if rule.SSH == nil || rule.SSH.Fingerprint != "SHA256:abc" || rule.SSH.Destination != "github.com" {
+		t.Fatalf("ssh = %+v, want fingerprint and destination from the request", rule.SSH)
+	}
+	if rule.Secret != nil {
+		t.Errorf("secret = %+v, want nil for ssh_sign", rule.Secret)
+	}
+	if rule.Name != "git-github.com" {
+		t.Errorf("name = %q, want git-github.com", rule.Name)
+	}
+	if !matchTrustRule(&rule, req.Client, req.SenderInfo, req.Items, req.Type, nil) {
+		t.Error("suggested rule does not match the request it was derived from")
+	}
+}
+
+func TestSuggestTrustRule_PGPDecrypt(t *testing.T) {
+	grip := "3BA5AB3CFA7E23E1740E326AEE26A4E329E0DCDC"
+	req := &Request{
+		ID:     "req-pgp",
+		Client: "gpg-agent",
+		Type:   RequestTypePGPDecrypt,
+		Items: []ItemInfo{{
+			Path:       grip,
+			Label:      "Alice <alice@example.com>",
+			Attributes: map[string]string{"operation": "decrypt", "keygrip": grip, "user_id": "Alice <alice@example.com>"},
+		}},
+		SenderInfo: SenderInfo{ProcessChain: []ProcessInfo{
+			{Name: "gpg", Exe: "/usr/bin/gpg"},
+			{Name: "gopass", Exe: "/usr/bin/gopass"},
+			{Name: "systemd", Exe: "/usr/lib/systemd/systemd"},
+		}},
+	}
+	rule, err := SuggestTrustRule(req)
+	if err != nil {
+		t.Fatalf("SuggestTrustRule: %v", err)
+	}
+	if rule.Process == nil || rule.Process.Exe != "/usr/bin/gopass" {
+		t.Errorf("process = %+v, want exe /usr/bin/gopass past gpg", rule.Process)
+	}
+	if rule.Secret == nil || len(rule.Secret.Attributes) != 1 || rule.Secret.Attributes["keygrip"] != grip {
+		t.Errorf("secret = %+v, want only the keygrip", rule.Secret)
+	}
+	if !matchTrustRule(&rule, req.Client, req.SenderInfo, req.Items, req.Type, nil) {
+		t.Error("suggested rule does not match the request it was derived from")
+	}
+}
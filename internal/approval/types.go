@@ -1,32 +1,92 @@
 // Package approval manages pending secret access requests requiring user approval.
 package approval
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
 // ProcessInfo represents a single process in the process chain.
 type ProcessInfo struct {
-	Name string   `json:"name"`
-	PID  uint32   `json:"pid"`
-	Exe  string   `json:"exe,omitempty"`
-	Args []string `json:"args,omitempty"`
-	CWD  string   `json:"cwd,omitempty"`
+	Name string `json:"name"`
+	PID  uint32 `json:"pid"`
+	// StartTime is the /proc/PID/stat start time; with PID it identifies the
+	// process instance. 0 when unknown.
+	StartTime uint64   `json:"start_time,omitempty"`
+	Exe       string   `json:"exe,omitempty"`
+	Args      []string `json:"args,omitempty"`
+	CWD       string   `json:"cwd,omitempty"`
 }
 
 // TrustRule defines a persistent declarative rule from config for auto-approving, ignoring, or denying requests.
 type TrustRule struct {
 	Name             string            `json:"name,omitempty"`
 	Action           string            `json:"action,omitempty"`
+	Client           string            `json:"client,omitempty"`  // glob against the downstream client name ("local", socket name)
+	Backend          string            `json:"backend,omitempty"` // glob against the upstream holding the items
 	RequestTypes     []string          `json:"request_types,omitempty"`
 	Process          *ProcessMatcher   `json:"process,omitempty"`
 	Secret           *SecretMatcher    `json:"secret,omitempty"`
+	SSH              *SSHMatcher       `json:"ssh,omitempty"`
+	Signing          *SigningMatcher   `json:"signing,omitempty"`
 	SearchAttributes map[string]string `json:"search_attributes,omitempty"`
 }
 
+// Label identifies the rule in history, statistics and errors: its name, or
+// for an unnamed rule "#" and a short hash of its content, which stays the
+// same across reloads as long as the rule is not edited.
+func (r *TrustRule) Label() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	data, _ := json.Marshal(r) //nolint:errcheck // plain struct, cannot fail
+	sum := sha256.Sum256(data)
+	return "#" + hex.EncodeToString(sum[:4])
+}
+
+// ClientPolicy sets what happens to requests from matching downstream clients
+// when no trust rule matches. Default "prompt" asks the user as usual; "deny"
+// refuses everything that no rule explicitly allows.
+type ClientPolicy struct {
+	Client  string `json:"client"`  // glob against the downstream client name
+	Default string `json:"default"` // "prompt" (default) or "deny"
+}
+
+// TrustConfig is the config-defined, hot-reloadable part of the Manager's
+// policy, swapped as a unit by Manager.SetTrustConfig.
+type TrustConfig struct {
+	Rules          []TrustRule
+	TrustedSigners []TrustedSigner
+	ClientPolicies []ClientPolicy
+}
+
 // ProcessMatcher matches against sender process attributes.
 type ProcessMatcher struct {
-	Exe  string `json:"exe,omitempty"`
-	Name string `json:"name,omitempty"`
-	Args string `json:"args,omitempty"`
-	CWD  string `json:"cwd,omitempty"`
-	Unit string `json:"unit,omitempty"`
+	Exe string `json:"exe,omitempty"`
+	// ExeSHA256 pins the SHA-256 (hex) of the running executable, and
+	// ExePackaged requires it to be installed by the system (see
+	// procutil.ExePackaged). Both are checked on the same process as Exe.
+	ExeSHA256   string `json:"exe_sha256,omitempty"`
+	ExePackaged bool   `json:"exe_packaged,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Args        string `json:"args,omitempty"`
+	CWD         string `json:"cwd,omitempty"`
+	Unit        string `json:"unit,omitempty"`
+	// AppID matches the Flatpak or Snap application ID of the caller.
+	AppID string `json:"app_id,omitempty"`
+	// Container matches the container the caller runs in; callers outside
+	// a container never match. Claimable by any process of the user.
+	Container *ContainerMatcher `json:"container,omitempty"`
+}
+
+// ContainerMatcher matches the container of the caller. All fields are globs;
+// ID also matches the 12-character short ID.
+type ContainerMatcher struct {
+	Runtime string `json:"runtime,omitempty"`
+	ID      string `json:"id,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Image   string `json:"image,omitempty"`
 }
 
 // SecretMatcher matches against secret/item attributes.
@@ -36,15 +96,50 @@ type SecretMatcher struct {
 	Attributes map[string]string `json:"attributes,omitempty"`
 }
 
+// SSHMatcher matches the keys of SSH agent requests (ssh_sign, ssh_add,
+// ssh_remove) by their fingerprint, comment and, for signing, the resolved
+// destination host. Requests without a key never match.
+type SSHMatcher struct {
+	Fingerprint string `json:"fingerprint,omitempty"` // exact, "SHA256:..."
+	Comment     string `json:"comment,omitempty"`     // glob
+	Destination string `json:"destination,omitempty"` // glob
+}
+
+// SigningMatcher matches gpg_sign requests by what is being signed. Kind,
+// Author and TagName are parsed from the signed bytes; Repo and Files come from
+// the gpg-sign client. All fields but Kind are globs; a Files pattern ending in
+// "/**" matches everything below that directory.
+type SigningMatcher struct {
+	Repo    string   `json:"repo,omitempty"`
+	Kind    string   `json:"kind,omitempty"`   // commit, tag or push
+	KeyID   string   `json:"key_id,omitempty"` // matched against the key ID and fingerprint, case-insensitive
+	Author  string   `json:"author,omitempty"` // "Name <email>" of the author, tagger or pusher
+	Files   []string `json:"files,omitempty"`
+	TagName string   `json:"tag_name,omitempty"`
+}
+
+// ContainerInfo identifies the container a caller runs in. Runtime and ID come
+// from the caller's cgroup; Name and Image from the runtime's state files, and
+// may be empty.
+type ContainerInfo struct {
+	Runtime string `json:"runtime"`
+	ID      string `json:"id,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Image   string `json:"image,omitempty"`
+}
+
 // SenderInfo contains information about the D-Bus sender process.
 type SenderInfo struct {
-	Sender       string        `json:"sender"`                  // D-Bus unique name (":1.123")
-	PID          uint32        `json:"pid"`                     // Process ID
-	UID          uint32        `json:"uid"`                     // User ID
-	UserName     string        `json:"user_name"`               // Username (may be empty if lookup fails)
-	InvokerName  string        `json:"invoker_name"`            // Invoker process comm (display); spoofable — NOT the systemd unit
-	SystemdUnit  string        `json:"systemd_unit,omitempty"`  // Real systemd unit (from GetUnitByPID); authoritative, matched by the `unit` rule
-	ProcessChain []ProcessInfo `json:"process_chain,omitempty"` // Full process chain from requestor to init
+	Sender       string         `json:"sender"`                  // D-Bus unique name (":1.123")
+	PID          uint32         `json:"pid"`                     // Process ID
+	UID          uint32         `json:"uid"`                     // User ID
+	UserName     string         `json:"user_name"`               // Username (may be empty if lookup fails)
+	InvokerName  string         `json:"invoker_name"`            // Invoker process comm (display); spoofable — NOT the systemd unit
+	SystemdUnit  string         `json:"systemd_unit,omitempty"`  // Real systemd unit (from GetUnitByPID); authoritative, matched by the `unit` rule
+	AppID        string         `json:"app_id,omitempty"`        // Flatpak/Snap application ID (procutil.ReadApp), matched by the `app_id` rule; claimable by unsandboxed processes
+	AppSandbox   string         `json:"app_sandbox,omitempty"`   // "flatpak" or "snap" when AppID is set
+	Container    *ContainerInfo `json:"container,omitempty"`     // Container the caller runs in (procutil.ReadContainer), matched by the `container` rule; claimable by any process of the user
+	ProcessChain []ProcessInfo  `json:"process_chain,omitempty"` // Full process chain from requestor to init
 	// PeerTrusted reports whether the process that opened the connection is a
 	// trusted transport for this request — one whose self-reported, server-
 	// unverifiable fields (repo name, changed files, commit object) we can rely on
@@ -0,0 +1,58 @@
+package approval
+
+import (
+	"path"
+	"slices"
+)
+
+// HidesItems reports whether some deny trust rule, temporary deny rule (or a
+// deny client default) could keep client from reading items, i.e. whether
+// search results and collection listings for this sender need filtering with
+// Visible. It lets the proxy skip fetching item metadata in the common case.
+func (m *Manager) HidesItems(client string, senderInfo SenderInfo) bool {
+	if m.hasDenyRule(senderInfo, RequestTypeGetSecret) {
+		return true
+	}
+
+	m.trustMu.RLock()
+	trustRules := m.trustRules
+	clientPolicies := m.clientPolicies
+	m.trustMu.RUnlock()
+
+	for i := range trustRules {
+		rule := &trustRules[i]
+		if rule.Action != "deny" || rule.Signing != nil || len(rule.SearchAttributes) > 0 {
+			continue
+		}
+		if rule.Client != "" {
+			if ok, _ := path.Match(rule.Client, client); !ok {
+				continue
+			}
+		}
+		if len(rule.RequestTypes) > 0 && !slices.Contains(rule.RequestTypes, string(RequestTypeGetSecret)) {
+			continue
+		}
+		if rule.Process != nil && !matchProcess(rule.Process, senderInfo) {
+			continue
+		}
+		return true
+	}
+	for _, p := range clientPolicies {
+		if ok, _ := path.Match(p.Client, client); ok {
+			return p.Default == "deny"
+		}
+	}
+	return false
+}
+
+// Visible reports whether client may see item at all: items it could never
+// read, because a trust rule or a temporary deny rule denies it get_secret on
+// them, are hidden from search results and collection listings so their
+// labels and attributes do not leak either.
+func (m *Manager) Visible(client string, senderInfo SenderInfo, item ItemInfo) bool {
+	if m.TemporarilyDenied(senderInfo, []ItemInfo{item}, RequestTypeGetSecret) {
+		return false
+	}
+	rule := m.CheckTrustRules(client, senderInfo, []ItemInfo{item}, RequestTypeGetSecret, nil)
+	return rule == nil || rule.Action != "deny"
+}
@@ -0,0 +1,94 @@
+package approval
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHidesItems(t *testing.T) {
+	gh := testSender("gh", "/usr/bin/gh")
+	vault := &SecretMatcher{Attributes: map[string]string{"tier": "vault"}}
+	tests := []struct {
+		name     string
+		rules    []TrustRule
+		policies []ClientPolicy
+		want     bool
+	}{
+		{"no rules", nil, nil, false},
+		{"approve rule", []TrustRule{{Name: "a", Secret: vault}}, nil, false},
+		{"deny rule", []TrustRule{{Name: "d", Action: "deny", Secret: vault}}, nil, true},
+		{"deny get_secret", []TrustRule{{Name: "d", Action: "deny", RequestTypes: []string{"get_secret"}}}, nil, true},
+		{"deny other type", []TrustRule{{Name: "d", Action: "deny", RequestTypes: []string{"search", "write"}}}, nil, false},
+		{"deny other client", []TrustRule{{Name: "d", Action: "deny", Client: "remote-*"}}, nil, false},
+		{"deny other process", []TrustRule{{Name: "d", Action: "deny", Process: &ProcessMatcher{Exe: "/usr/bin/git"}}}, nil, false},
+		{"deny search attributes", []TrustRule{{Name: "d", Action: "deny", SearchAttributes: map[string]string{"a": "b"}}}, nil, false},
+		{"client default deny", nil, []ClientPolicy{{Client: "local", Default: "deny"}}, true},
+		{"client default prompt", nil, []ClientPolicy{{Client: "local", Default: "prompt"}, {Client: "*", Default: "deny"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr := NewManager(ManagerConfig{Timeout: time.Second, HistoryMax: 10, TrustRules: tt.rules, ClientPolicies: tt.policies})
+			if got := mgr.HidesItems("local", gh); got != tt.want {
+				t.Errorf("HidesItems = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVisible(t *testing.T) {
+	gh := testSender("gh", "/usr/bin/gh")
+	mgr := NewManager(ManagerConfig{Timeout: time.Second, HistoryMax: 10, TrustRules: []TrustRule{
+		{Name: "gh token", Process: &ProcessMatcher{Name: "gh"}, Secret: &SecretMatcher{Attributes: map[string]string{"service": "gh"}}},
+		{Name: "vault", Action: "deny", Secret: &SecretMatcher{Collection: "vault"}},
+		{Name: "quiet", Action: "ignore", Secret: &SecretMatcher{Collection: "noise"}},
+	}})
+
+	tests := []struct {
+		name string
+		item ItemInfo
+		want bool
+	}{
+		{"unruled", ItemInfo{Path: "/org/freedesktop/secrets/collection/login/1"}, true},
+		{"denied", ItemInfo{Path: "/org/freedesktop/secrets/collection/vault/1"}, false},
+		{"approved before the deny", ItemInfo{Path: "/org/freedesktop/secrets/collection/vault/2", Attributes: map[string]string{"service": "gh"}}, true},
+		{"ignored", ItemInfo{Path: "/org/freedesktop/secrets/collection/noise/1"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mgr.Visible("local", gh, tt.item); got != tt.want {
+				t.Errorf("Visible(%s) = %v, want %v", tt.item.Path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVisible_TemporaryDenyRules(t *testing.T) {
+	gh := testSender("gh", "/usr/bin/gh")
+	git := testSender("git", "/usr/bin/git")
+	vault := ItemInfo{Path: "/org/freedesktop/secrets/collection/vault/1", Attributes: map[string]string{"tier": "vault"}}
+	login := ItemInfo{Path: "/org/freedesktop/secrets/collection/login/1"}
+
+	mgr := NewManager(ManagerConfig{Timeout: time.Second, HistoryMax: 10})
+	if _, err := mgr.AddDenyRule(&Request{Type: RequestTypeGetSecret, Items: []ItemInfo{vault}, SenderInfo: gh}, 0, false); err != nil {
+		t.Fatal(err)
+	}
+	if !mgr.HidesItems("local", gh) || mgr.HidesItems("local", git) {
+		t.Error("a deny rule should hide items from its executable only")
+	}
+	if mgr.Visible("local", gh, vault) || !mgr.Visible("local", gh, login) {
+		t.Error("a scoped deny rule should hide only the items in its scope")
+	}
+	if mgr.TemporarilyDenied(gh, nil, RequestTypeSearch) {
+		t.Error("a get_secret deny rule should not refuse searches")
+	}
+
+	if _, err := mgr.AddDenyRule(&Request{Type: RequestTypeGetSecret, Items: []ItemInfo{login}, SenderInfo: git}, 0, true); err != nil {
+		t.Fatal(err)
+	}
+	if !mgr.TemporarilyDenied(git, []ItemInfo{{Label: "app=x"}}, RequestTypeSearch) {
+		t.Error("a mute should refuse searches")
+	}
+	if mgr.Visible("local", git, login) || mgr.Visible("local", git, vault) {
+		t.Error("a mute should hide every item")
+	}
+}
@@ -2,10 +2,13 @@
 package cli
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -52,6 +55,13 @@ type ItemInfo struct {
 	Path       string            `json:"path"`
 	Label      string            `json:"label"`
 	Attributes map[string]string `json:"attributes"`
+	Backend    string            `json:"backend,omitempty"`
+}
+
+// PairInfo mirrors approval.PairInfo for pair requests.
+type PairInfo struct {
+	Code        string `json:"code"`
+	Fingerprint string `json:"fingerprint"`
 }
 
 // GPGSignInfo carries signing context for a gpg_sign approval request.
@@ -59,18 +69,64 @@ type ItemInfo struct {
 // deliberately does not import internal/approval or internal/api. Keep the JSON
 // tags in sync with that struct.
 type GPGSignInfo struct {
-	RepoName     string   `json:"repo_name"`
-	Kind         string   `json:"kind,omitempty"`
-	CommitMsg    string   `json:"commit_msg"`
-	Author       string   `json:"author"`
-	Committer    string   `json:"committer"`
-	KeyID        string   `json:"key_id"`
-	Fingerprint  string   `json:"fingerprint,omitempty"`
-	ChangedFiles []string `json:"changed_files"`
-	ParentHash   string   `json:"parent_hash,omitempty"`
-	TagName      string   `json:"tag_name,omitempty"`
-	Target       string   `json:"target,omitempty"`
-	Pushee       string   `json:"pushee,omitempty"`
+	RepoName     string            `json:"repo_name"`
+	Kind         string            `json:"kind,omitempty"`
+	Format       string            `json:"format,omitempty"`
+	CommitMsg    string            `json:"commit_msg"`
+	Author       string            `json:"author"`
+	Committer    string            `json:"committer"`
+	KeyID        string            `json:"key_id"`
+	Fingerprint  string            `json:"fingerprint,omitempty"`
+	UseAgent     bool              `json:"use_agent,omitempty"`
+	ChangedFiles []string          `json:"changed_files"`
+	ParentHash   string            `json:"parent_hash,omitempty"`
+	TagName      string            `json:"tag_name,omitempty"`
+	Target       string            `json:"target,omitempty"`
+	Pushee       string            `json:"pushee,omitempty"`
+	Diff         *CommitDiff       `json:"diff,omitempty"`
+	Verification *SignVerification `json:"verification,omitempty"`
+	Series       *SignSeries       `json:"series,omitempty"`
+}
+
+// SignSeries mirrors approval.SignSeries.
+type SignSeries struct {
+	Operation string         `json:"operation"`
+	Position  int            `json:"position"`
+	Total     int            `json:"total"`
+	Commits   []SeriesCommit `json:"commits"`
+	Steps     []string       `json:"steps,omitempty"`
+}
+
+// SeriesCommit mirrors approval.SeriesCommit.
+type SeriesCommit struct {
+	Hash    string `json:"hash,omitempty"`
+	Subject string `json:"subject"`
+}
+
+// SignVerification mirrors approval.SignVerification.
+type SignVerification struct {
+	Repo     string   `json:"repo,omitempty"`
+	Verified bool     `json:"verified"`
+	Problems []string `json:"problems,omitempty"`
+}
+
+// CommitDiff mirrors approval.CommitDiff. Listings leave Patch out; Client.Patch
+// fetches it.
+type CommitDiff struct {
+	Files      []DiffFile `json:"files"`
+	TotalFiles int        `json:"total_files"`
+	Additions  int        `json:"additions"`
+	Deletions  int        `json:"deletions"`
+	Patch      string     `json:"patch,omitempty"`
+	Truncated  bool       `json:"truncated,omitempty"`
+}
+
+// DiffFile mirrors approval.DiffFile.
+type DiffFile struct {
+	Path      string `json:"path"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+	Binary    bool   `json:"binary,omitempty"`
 }
 
 // PendingRequest represents a pending approval request.
@@ -84,7 +140,10 @@ type PendingRequest struct {
 	Type             string            `json:"type"`
 	SearchAttributes map[string]string `json:"search_attributes,omitempty"`
 	GPGSignInfo      *GPGSignInfo      `json:"gpg_sign_info,omitempty"`
+	PairInfo         *PairInfo         `json:"pair_info,omitempty"`
 	SenderInfo       SenderInfo        `json:"sender_info"`
+	PerItem          bool              `json:"per_item,omitempty"`       // items may be approved individually
+	ApprovedItems    []string          `json:"approved_items,omitempty"` // paths granted by a partial approval
 }
 
 // HistoryEntry represents a resolved approval request.
@@ -92,6 +151,7 @@ type HistoryEntry struct {
 	Request    PendingRequest `json:"request"`
 	Resolution string         `json:"resolution"`
 	ResolvedAt time.Time      `json:"resolved_at"`
+	Rule       string         `json:"rule,omitempty"` // trust rule that decided the request, if any
 }
 
 // ShowResult represents the result of showing a request (pending or resolved).
@@ -99,6 +159,7 @@ type ShowResult struct {
 	Request    PendingRequest `json:"request"`
 	Resolution string         `json:"resolution,omitempty"`
 	ResolvedAt time.Time      `json:"resolved_at"`
+	Rule       string         `json:"rule,omitempty"`
 }
 
 // PendingResponse is the response from the pending endpoint.
@@ -106,6 +167,11 @@ type PendingResponse struct {
 	Requests []PendingRequest `json:"requests"`
 }
 
+// PatchResponse is the response from the request patch endpoint.
+type PatchResponse struct {
+	Patch string `json:"patch"`
+}
+
 // HistoryResponse is the response from the history endpoint.
 type HistoryResponse struct {
 	Entries []HistoryEntry `json:"entries"`
@@ -121,6 +187,141 @@ type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
+// TrustRule is a persistent trust rule as written under serve.rules in
+// config.yaml. The yaml tags match the config file so a suggested rule can be
+// shown exactly as it will be saved.
+type TrustRule struct {
+	Name             string            `json:"name,omitempty" yaml:"name,omitempty"`
+	Action           string            `json:"action,omitempty" yaml:"action,omitempty"`
+	Client           string            `json:"client,omitempty" yaml:"client,omitempty"`
+	Backend          string            `json:"backend,omitempty" yaml:"backend,omitempty"`
+	RequestTypes     []string          `json:"request_types,omitempty" yaml:"request_types,omitempty,flow"`
+	Process          *ProcessMatcher   `json:"process,omitempty" yaml:"process,omitempty"`
+	Secret           *SecretMatcher    `json:"secret,omitempty" yaml:"secret,omitempty"`
+	SSH              *SSHMatcher       `json:"ssh,omitempty" yaml:"ssh,omitempty"`
+	Signing          *SigningMatcher   `json:"signing,omitempty" yaml:"signing,omitempty"`
+	SearchAttributes map[string]string `json:"search_attributes,omitempty" yaml:"search_attributes,omitempty"`
+}
+
+// ProcessMatcher matches against sender process attributes.
+type ProcessMatcher struct {
+	Exe         string            `json:"exe,omitempty" yaml:"exe,omitempty"`
+	ExeSHA256   string            `json:"exe_sha256,omitempty" yaml:"exe_sha256,omitempty"`
+	ExePackaged bool              `json:"exe_packaged,omitempty" yaml:"exe_packaged,omitempty"`
+	Name        string            `json:"name,omitempty" yaml:"name,omitempty"`
+	Args        string            `json:"args,omitempty" yaml:"args,omitempty"`
+	CWD         string            `json:"cwd,omitempty" yaml:"cwd,omitempty"`
+	Unit        string            `json:"unit,omitempty" yaml:"unit,omitempty"`
+	AppID       string            `json:"app_id,omitempty" yaml:"app_id,omitempty"`
+	Container   *ContainerMatcher `json:"container,omitempty" yaml:"container,omitempty"`
+}
+
+// ContainerMatcher matches the container of the caller.
+type ContainerMatcher struct {
+	Runtime string `json:"runtime,omitempty" yaml:"runtime,omitempty"`
+	ID      string `json:"id,omitempty" yaml:"id,omitempty"`
+	Name    string `json:"name,omitempty" yaml:"name,omitempty"`
+	Image   string `json:"image,omitempty" yaml:"image,omitempty"`
+}
+
+// SecretMatcher matches against secret/item attributes.
+type SecretMatcher struct {
+	Collection string            `json:"collection,omitempty" yaml:"collection,omitempty"`
+	Label      string            `json:"label,omitempty" yaml:"label,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+}
+
+// SSHMatcher matches the key of SSH agent requests.
+type SSHMatcher struct {
+	Fingerprint string `json:"fingerprint,omitempty" yaml:"fingerprint,omitempty"`
+	Comment     string `json:"comment,omitempty" yaml:"comment,omitempty"`
+	Destination string `json:"destination,omitempty" yaml:"destination,omitempty"`
+}
+
+// SigningMatcher matches gpg_sign requests by what is being signed.
+type SigningMatcher struct {
+	Repo    string   `json:"repo,omitempty" yaml:"repo,omitempty"`
+	Kind    string   `json:"kind,omitempty" yaml:"kind,omitempty"`
+	KeyID   string   `json:"key_id,omitempty" yaml:"key_id,omitempty"`
+	Author  string   `json:"author,omitempty" yaml:"author,omitempty"`
+	Files   []string `json:"files,omitempty" yaml:"files,omitempty"`
+	TagName string   `json:"tag_name,omitempty" yaml:"tag_name,omitempty"`
+}
+
+// RuleTestResult is one request evaluated against the current and a candidate
+// config by `rule test`. Decisions are "prompt", "approve", "deny" or "ignore".
+type RuleTestResult struct {
+	Request       PendingRequest `json:"request"`
+	Recorded      string         `json:"recorded,omitempty"` // resolution in history; empty for a synthetic request
+	Current       string         `json:"current"`
+	CurrentRule   string         `json:"current_rule,omitempty"`
+	Candidate     string         `json:"candidate"`
+	CandidateRule string         `json:"candidate_rule,omitempty"`
+}
+
+// Changed reports whether the candidate config decides the request differently.
+func (r RuleTestResult) Changed() bool {
+	return r.Current != r.Candidate
+}
+
+// RuleStat is one trust rule's hit statistics from the rule stats endpoint.
+type RuleStat struct {
+	Rule        string    `json:"rule"`
+	Hits        int       `json:"hits"`
+	LastHit     time.Time `json:"last_hit,omitzero"`
+	Since       time.Time `json:"since"`
+	Executables []string  `json:"executables,omitempty"`
+	Unused      bool      `json:"unused,omitempty"` // no match within the unused window
+	Broad       bool      `json:"broad,omitempty"`  // matched more than the allowed number of executables
+}
+
+// RuleStatsResponse is the response from the rule stats endpoint.
+type RuleStatsResponse struct {
+	Rules []RuleStat `json:"rules"`
+}
+
+// RuleSuggestResponse is the response from the rule suggest endpoint.
+type RuleSuggestResponse struct {
+	Rule TrustRule `json:"rule"`
+}
+
+// TrashItem is a deleted item kept in the trash (without its secret).
+type TrashItem struct {
+	Path       string            `json:"path"`
+	Label      string            `json:"label"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// TrashEntry is one undoable deletion.
+type TrashEntry struct {
+	ID              string      `json:"id"`
+	DeletedAt       time.Time   `json:"deleted_at"`
+	ExpiresAt       time.Time   `json:"expires_at"`
+	Kind            string      `json:"kind"`
+	Client          string      `json:"client"`
+	Backend         string      `json:"backend,omitempty"`
+	Collection      string      `json:"collection"`
+	CollectionLabel string      `json:"collection_label,omitempty"`
+	Items           []TrashItem `json:"items"`
+}
+
+// TrashListResponse is the response from the trash endpoint.
+type TrashListResponse struct {
+	Entries []TrashEntry `json:"entries"`
+}
+
+// TrashRestoreResponse is the response from the trash restore endpoint.
+type TrashRestoreResponse struct {
+	Status string   `json:"status"`
+	Items  []string `json:"items"`
+}
+
+// TrashPurgeResponse is the response from the trash purge endpoints.
+type TrashPurgeResponse struct {
+	Status string `json:"status"`
+	Count  int    `json:"count"`
+}
+
 // List returns all pending requests.
 func (c *Client) List() ([]PendingRequest, error) {
 	resp, err := c.get("/api/v1/pending")
@@ -159,6 +360,53 @@ func (c *Client) History() ([]HistoryEntry, error) {
 	return result.Entries, nil
 }
 
+// QueryHistory returns resolved requests within [since, until), newest first,
+// paging through the server's persisted history. Zero times leave that bound
+// open; limit 0 means no limit.
+func (c *Client) QueryHistory(since, until time.Time, limit int) ([]HistoryEntry, error) {
+	q := url.Values{}
+	if !since.IsZero() {
+		q.Set("since", since.Format(time.RFC3339))
+	}
+	if !until.IsZero() {
+		q.Set("until", until.Format(time.RFC3339))
+	}
+	q.Set("limit", strconv.Itoa(limit))
+
+	resp, err := c.get("/api/v1/log?" + q.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.parseError(resp)
+	}
+
+	var result HistoryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return result.Entries, nil
+}
+
+// ParseTimeBound parses a history range bound: either an RFC 3339 timestamp
+// or a duration (e.g. "24h") meaning that long before now. Empty yields the
+// zero time (unbounded).
+func ParseTimeBound(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: want a duration (e.g. 24h) or RFC 3339 timestamp", s)
+	}
+	return t, nil
+}
+
 // Approve approves a request by ID (supports partial ID).
 func (c *Client) Approve(id string) error {
 	fullID, err := c.resolveID(id)
@@ -168,6 +416,79 @@ func (c *Client) Approve(id string) error {
 	return c.action(fullID, "approve")
 }
 
+// ApproveItems approves only some items of a per-item request (supports
+// partial ID). numbers are 1-based positions in the request's item list, as
+// printed by show; the other items are refused.
+func (c *Client) ApproveItems(id string, numbers []int) error {
+	result, err := c.Show(id)
+	if err != nil {
+		return err
+	}
+	if result.Resolution != "" {
+		return fmt.Errorf("request %s is already resolved", result.Request.ID)
+	}
+	paths := make([]string, 0, len(numbers))
+	for _, n := range numbers {
+		if n < 1 || n > len(result.Request.Items) {
+			return fmt.Errorf("item %d out of range: request has %d items", n, len(result.Request.Items))
+		}
+		paths = append(paths, result.Request.Items[n-1].Path)
+	}
+
+	body, err := json.Marshal(map[string][]string{"items": paths})
+	if err != nil {
+		return err
+	}
+	resp, err := c.postJSON("/api/v1/pending/"+result.Request.ID+"/approve", body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return c.parseError(resp)
+	}
+	return nil
+}
+
+// ApproveForProcess approves a request (supports partial ID) and keeps
+// auto-approving similar requests from the chosen process until it exits. pid
+// selects a process of the request's process chain, 0 the requester; subtree
+// also covers its descendants.
+func (c *Client) ApproveForProcess(id string, pid uint32, subtree bool) error {
+	fullID, err := c.resolveID(id)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(struct {
+		PID     uint32 `json:"pid,omitempty"`
+		Subtree bool   `json:"subtree,omitempty"`
+	}{pid, subtree})
+	if err != nil {
+		return err
+	}
+	resp, err := c.postJSON("/api/v1/pending/"+fullID+"/approve-for-process", body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return c.parseError(resp)
+	}
+	return nil
+}
+
+// ApproveSeries approves a commit signing request (supports partial ID) and
+// the rest of the rebase, cherry-pick, revert or am it is part of.
+func (c *Client) ApproveSeries(id string) error {
+	fullID, err := c.resolveID(id)
+	if err != nil {
+		return err
+	}
+	return c.action(fullID, "approve-series")
+}
+
 // Deny denies a request by ID (supports partial ID).
 func (c *Client) Deny(id string) error {
 	fullID, err := c.resolveID(id)
@@ -177,6 +498,33 @@ func (c *Client) Deny(id string) error {
 	return c.action(fullID, "deny")
 }
 
+// DenyFor denies a request (supports partial ID) and adds a temporary deny
+// rule for similar requests for d (the server default when 0); with mute the
+// rule covers every request from the same executable.
+func (c *Client) DenyFor(id string, d time.Duration, mute bool) error {
+	fullID, err := c.resolveID(id)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(struct {
+		ForSeconds int  `json:"for_seconds,omitempty"`
+		Mute       bool `json:"mute,omitempty"`
+	}{int((d + time.Second - 1) / time.Second), mute})
+	if err != nil {
+		return err
+	}
+	resp, err := c.postJSON("/api/v1/pending/"+fullID+"/deny", body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return c.parseError(resp)
+	}
+	return nil
+}
+
 // Show returns a single request by ID (supports partial ID).
 // Searches pending requests first, then history.
 func (c *Client) Show(id string) (*ShowResult, error) {
@@ -208,6 +556,7 @@ func (c *Client) Show(id string) (*ShowResult, error) {
 				Request:    entries[i].Request,
 				Resolution: entries[i].Resolution,
 				ResolvedAt: entries[i].ResolvedAt,
+				Rule:       entries[i].Rule,
 			}, nil
 		}
 		if strings.HasPrefix(entries[i].Request.ID, id) {
@@ -215,6 +564,7 @@ func (c *Client) Show(id string) (*ShowResult, error) {
 				Request:    entries[i].Request,
 				Resolution: entries[i].Resolution,
 				ResolvedAt: entries[i].ResolvedAt,
+				Rule:       entries[i].Rule,
 			})
 		}
 	}
@@ -229,6 +579,172 @@ func (c *Client) Show(id string) (*ShowResult, error) {
 	}
 }
 
+// Patch returns the patch of a gpg_sign request, which listings leave out
+// (full ID).
+func (c *Client) Patch(id string) (string, error) {
+	resp, err := c.get("/api/v1/requests/" + url.PathEscape(id) + "/patch")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", c.parseError(resp)
+	}
+
+	var result PatchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("decode response: %w", err)
+	}
+	return result.Patch, nil
+}
+
+// SuggestRule returns the trust rule the service derives from a pending or
+// resolved request (supports partial ID). IDs that are not in the in-memory
+// history are passed through for the service to look up in persisted history.
+func (c *Client) SuggestRule(id string) (*TrustRule, error) {
+	if result, err := c.Show(id); err == nil {
+		id = result.Request.ID
+	}
+
+	resp, err := c.get("/api/v1/rules/suggest?from=" + url.QueryEscape(id))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.parseError(resp)
+	}
+
+	var result RuleSuggestResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result.Rule, nil
+}
+
+// RuleStats returns the hit statistics of the service's trust rules, flagging
+// rules unmatched for more than unusedDays and rules matched by more than
+// maxExes distinct executables (0 disables either flag).
+func (c *Client) RuleStats(unusedDays, maxExes int) ([]RuleStat, error) {
+	q := url.Values{}
+	q.Set("unused_days", strconv.Itoa(unusedDays))
+	q.Set("max_executables", strconv.Itoa(maxExes))
+
+	resp, err := c.get("/api/v1/rules/stats?" + q.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.parseError(resp)
+	}
+
+	var result RuleStatsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return result.Rules, nil
+}
+
+// AddRule saves rule to the service's config.yaml and loads it.
+func (c *Client) AddRule(rule TrustRule) error {
+	body, err := json.Marshal(rule)
+	if err != nil {
+		return err
+	}
+	resp, err := c.postJSON("/api/v1/rules", body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return c.parseError(resp)
+	}
+	return nil
+}
+
+// ReloadConfig asks the running service to re-read config.yaml. A config that
+// fails validation is rejected and the service keeps its previous rules.
+func (c *Client) ReloadConfig() error {
+	resp, err := c.post("/api/v1/config/reload")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return c.parseError(resp)
+	}
+	return nil
+}
+
+// Trash returns the deletions that can still be undone, newest first.
+func (c *Client) Trash() ([]TrashEntry, error) {
+	resp, err := c.get("/api/v1/trash")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.parseError(resp)
+	}
+
+	var result TrashListResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return result.Entries, nil
+}
+
+// RestoreTrash recreates the items of a trash entry (supports partial ID) and
+// returns their new paths.
+func (c *Client) RestoreTrash(id string) ([]string, error) {
+	resp, err := c.post("/api/v1/trash/" + url.PathEscape(id) + "/restore")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.parseError(resp)
+	}
+
+	var result TrashRestoreResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return result.Items, nil
+}
+
+// PurgeTrash permanently deletes a trash entry (supports partial ID), or every
+// entry if id is empty, and returns how many were deleted.
+func (c *Client) PurgeTrash(id string) (int, error) {
+	path := "/api/v1/trash"
+	if id != "" {
+		path += "/" + url.PathEscape(id)
+	}
+	resp, err := c.delete(path)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, c.parseError(resp)
+	}
+
+	var result TrashPurgeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("decode response: %w", err)
+	}
+	return result.Count, nil
+}
+
 func (c *Client) action(id, action string) error {
 	resp, err := c.post("/api/v1/pending/" + id + "/" + action)
 	if err != nil {
@@ -286,6 +802,25 @@ func (c *Client) post(path string) (*http.Response, error) {
 	return c.httpClient.Do(req)
 }
 
+func (c *Client) delete(path string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodDelete, c.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	return c.httpClient.Do(req)
+}
+
+func (c *Client) postJSON(path string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodPost, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+	return c.httpClient.Do(req)
+}
+
 func (c *Client) parseError(resp *http.Response) error {
 	body, _ := io.ReadAll(resp.Body)
 	var errResp ErrorResponse
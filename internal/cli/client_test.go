@@ -77,6 +77,116 @@ func TestClient_History(t *testing.T) {
 	}
 }
 
+func TestClient_QueryHistory(t *testing.T) {
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("since") != "2026-01-01T00:00:00Z" {
+			t.Errorf("unexpected since: %q", q.Get("since"))
+		}
+		if q.Has("until") {
+			t.Errorf("until should be omitted when zero, got %q", q.Get("until"))
+		}
+		if q.Get("limit") != "50" {
+			t.Errorf("unexpected limit: %q", q.Get("limit"))
+		}
+		json.NewEncoder(w).Encode(HistoryResponse{Entries: []HistoryEntry{{Request: PendingRequest{ID: "req-1"}}}})
+	}))
+	defer server.Close()
+
+	client := NewClient(strings.TrimPrefix(server.URL, "http://"), "test-token")
+	result, err := client.QueryHistory(since, time.Time{}, 50)
+	if err != nil {
+		t.Fatalf("QueryHistory failed: %v", err)
+	}
+	if len(result) != 1 || result[0].Request.ID != "req-1" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
+
+func TestClient_ReloadConfig(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/config/reload" || r.Method != http.MethodPost {
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "rule 0: invalid action"})
+	}))
+	defer server.Close()
+
+	client := NewClient(strings.TrimPrefix(server.URL, "http://"), "test-token")
+	err := client.ReloadConfig()
+	if err == nil || err.Error() != "rule 0: invalid action" {
+		t.Errorf("expected validation error, got %v", err)
+	}
+}
+
+func TestClient_SuggestAndAddRule(t *testing.T) {
+	var added TrustRule
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/pending":
+			json.NewEncoder(w).Encode(PendingResponse{Requests: []PendingRequest{{ID: "abc-123"}}})
+		case "/api/v1/log":
+			json.NewEncoder(w).Encode(HistoryResponse{})
+		case "/api/v1/rules/suggest":
+			if got := r.URL.Query().Get("from"); got != "abc-123" {
+				t.Errorf("suggest from = %q, want full ID abc-123", got)
+			}
+			json.NewEncoder(w).Encode(RuleSuggestResponse{Rule: TrustRule{Name: "gh", Process: &ProcessMatcher{Exe: "/usr/bin/gh"}}})
+		case "/api/v1/rules":
+			if r.Method != http.MethodPost {
+				t.Errorf("expected POST, got %s", r.Method)
+			}
+			json.NewDecoder(r.Body).Decode(&added)
+			json.NewEncoder(w).Encode(ActionResponse{Status: "added"})
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(strings.TrimPrefix(server.URL, "http://"), "test-token")
+	rule, err := client.SuggestRule("abc")
+	if err != nil {
+		t.Fatalf("SuggestRule failed: %v", err)
+	}
+	if rule.Process == nil || rule.Process.Exe != "/usr/bin/gh" {
+		t.Fatalf("unexpected rule: %+v", rule)
+	}
+	rule.Name = "narrowed"
+	if err := client.AddRule(*rule); err != nil {
+		t.Fatalf("AddRule failed: %v", err)
+	}
+	if added.Name != "narrowed" || added.Process.Exe != "/usr/bin/gh" {
+		t.Errorf("server received %+v", added)
+	}
+}
+
+func TestParseTimeBound(t *testing.T) {
+	now := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{"", time.Time{}, false},
+		{"24h", now.Add(-24 * time.Hour), false},
+		{"2026-01-01T08:00:00Z", time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC), false},
+		{"yesterday", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseTimeBound(tt.in, now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseTimeBound(%q) err = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseTimeBound(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
 func TestClient_Approve(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
@@ -276,6 +386,31 @@ func TestClient_Show_History(t *testing.T) {
 	}
 }
 
+func TestClient_Patch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/requests/req-111/patch" {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "request not found"})
+			return
+		}
+		json.NewEncoder(w).Encode(PatchResponse{Patch: "+added\n"})
+	}))
+	defer server.Close()
+
+	client := NewClient(strings.TrimPrefix(server.URL, "http://"), "test-token")
+
+	patch, err := client.Patch("req-111")
+	if err != nil {
+		t.Fatalf("Patch failed: %v", err)
+	}
+	if patch != "+added\n" {
+		t.Errorf("patch = %q", patch)
+	}
+	if _, err := client.Patch("req-999"); err == nil {
+		t.Error("expected error for unknown request")
+	}
+}
+
 func TestClient_Unauthorized(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusUnauthorized)
@@ -4,11 +4,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"slices"
 	"sort"
 	"strings"
 	"time"
 
 	dbustypes "github.com/nikicat/secrets-dispatcher/internal/dbus"
+	"gopkg.in/yaml.v3"
 )
 
 // Formatter outputs data in various formats.
@@ -63,6 +65,9 @@ func truncate(s string, maxLen int) string {
 }
 
 func requestSummary(req PendingRequest) string {
+	if req.PairInfo != nil {
+		return "pair " + req.PairInfo.Code
+	}
 	if req.GPGSignInfo != nil {
 		switch req.GPGSignInfo.Kind {
 		case "tag":
@@ -118,6 +123,9 @@ func (f *Formatter) FormatShowResult(result *ShowResult) error {
 	f.formatRequest(&result.Request)
 	if result.Resolution != "" {
 		fmt.Fprintf(f.w, "Result:  %s\n", result.Resolution)
+		if result.Rule != "" {
+			fmt.Fprintf(f.w, "Rule:    %s\n", result.Rule)
+		}
 		fmt.Fprintf(f.w, "Resolved: %s (%s)\n", result.ResolvedAt.Format(time.RFC3339), formatAgo(result.ResolvedAt))
 	}
 	return nil
@@ -163,9 +171,19 @@ func (f *Formatter) formatRequest(req *PendingRequest) {
 		}
 	}
 
-	if req.GPGSignInfo != nil {
+	if req.PairInfo != nil {
+		fmt.Fprintf(f.w, "Code:    %s\n", req.PairInfo.Code)
+		fmt.Fprintf(f.w, "Key:     %s\n", req.PairInfo.Fingerprint)
+		fmt.Fprintln(f.w, "\nApprove only if `secrets-dispatcher pair` on the remote host shows the same code.")
+	} else if req.GPGSignInfo != nil {
 		info := req.GPGSignInfo
 		fmt.Fprintf(f.w, "Repo:    %s\n", info.RepoName)
+		if v := info.Verification; v != nil && !v.Verified {
+			fmt.Fprintln(f.w, "WARNING: this request does not match the caller's repository:")
+			for _, p := range v.Problems {
+				fmt.Fprintf(f.w, "  - %s\n", p)
+			}
+		}
 		// git signs commits, annotated tags, and push certificates through the
 		// same path; label each with its own fields so the human approving on
 		// the trusted VT sees exactly what kind of object they are signing.
@@ -176,7 +194,7 @@ func (f *Formatter) formatRequest(req *PendingRequest) {
 				fmt.Fprintf(f.w, "Tag:     %s\n", info.TagName)
 			}
 			fmt.Fprintf(f.w, "Tagger:  %s\n", info.Author)
-			fmt.Fprintf(f.w, "Key:     %s\n", info.KeyID)
+			fmt.Fprintf(f.w, "Key:     %s\n", signingKey(info))
 			if info.Target != "" {
 				fmt.Fprintf(f.w, "Target:  %s\n", info.Target)
 			}
@@ -187,7 +205,7 @@ func (f *Formatter) formatRequest(req *PendingRequest) {
 			if info.Pushee != "" {
 				fmt.Fprintf(f.w, "Pushee:  %s\n", info.Pushee)
 			}
-			fmt.Fprintf(f.w, "Key:     %s\n", info.KeyID)
+			fmt.Fprintf(f.w, "Key:     %s\n", signingKey(info))
 			fmt.Fprintln(f.w, "\nRef updates:")
 			for line := range strings.SplitSeq(info.CommitMsg, "\n") {
 				if line != "" {
@@ -196,7 +214,7 @@ func (f *Formatter) formatRequest(req *PendingRequest) {
 			}
 		default: // commit — and the safe generic fallback for an empty/unknown kind
 			fmt.Fprintf(f.w, "Author:  %s\n", info.Author)
-			fmt.Fprintf(f.w, "Key:     %s\n", info.KeyID)
+			fmt.Fprintf(f.w, "Key:     %s\n", signingKey(info))
 			f.writeSignMessage(info.CommitMsg)
 			fmt.Fprintln(f.w)
 			fmt.Fprintf(f.w, "Changed files (%d):\n", len(info.ChangedFiles))
@@ -209,6 +227,12 @@ func (f *Formatter) formatRequest(req *PendingRequest) {
 			if info.ParentHash != "" {
 				fmt.Fprintf(f.w, "Parent:    %s\n", info.ParentHash)
 			}
+			if sr := info.Series; sr != nil {
+				f.writeSeries(sr)
+			}
+			if d := info.Diff; d != nil {
+				f.writeDiff(d)
+			}
 		}
 	} else {
 		if len(req.Items) == 1 {
@@ -217,9 +241,23 @@ func (f *Formatter) formatRequest(req *PendingRequest) {
 			formatItemAttrs(f.w, req.Items[0].Attributes, "  ")
 		} else if len(req.Items) > 1 {
 			fmt.Fprintf(f.w, "Secrets: %d items\n", len(req.Items))
-			for _, item := range req.Items {
-				fmt.Fprintf(f.w, "  - %s  %s\n", item.Label, item.Path)
-				formatItemAttrs(f.w, item.Attributes, "    ")
+			// Per-item requests are numbered for approve --items.
+			numbered := req.PerItem || req.ApprovedItems != nil
+			for i, item := range req.Items {
+				mark := ""
+				if req.ApprovedItems != nil && !slices.Contains(req.ApprovedItems, item.Path) {
+					mark = "  (refused)"
+				}
+				if numbered {
+					fmt.Fprintf(f.w, "  %d. %s  %s%s\n", i+1, item.Label, item.Path, mark)
+					formatItemAttrs(f.w, item.Attributes, "     ")
+				} else {
+					fmt.Fprintf(f.w, "  - %s  %s\n", item.Label, item.Path)
+					formatItemAttrs(f.w, item.Attributes, "    ")
+				}
+			}
+			if req.PerItem && req.ApprovedItems == nil {
+				fmt.Fprintln(f.w, "         (approve some with: approve <id> --items 1,3)")
 			}
 		}
 
@@ -270,6 +308,82 @@ func (f *Formatter) writeSignMessage(msg string) {
 	}
 }
 
+// signingKey describes the key a signing request asks for: the gpg key ID,
+// or for SSH signing the key's fingerprint and file.
+func signingKey(info *GPGSignInfo) string {
+	if info.Format != "ssh" {
+		return info.KeyID
+	}
+	fpr := info.Fingerprint
+	if fpr == "" {
+		fpr = "unknown fingerprint"
+	}
+	return fmt.Sprintf("SSH %s (%s)", fpr, info.KeyID)
+}
+
+// writeSeries lists the commits of the rebase, cherry-pick, revert or am a
+// commit being signed is part of, marking the current one.
+func (f *Formatter) writeSeries(sr *SignSeries) {
+	fmt.Fprintf(f.w, "\nSeries (%s, commit %d of %d):\n", sr.Operation, sr.Position, sr.Total)
+	for i, c := range sr.Commits {
+		mark := " "
+		switch {
+		case i+1 == sr.Position:
+			mark = ">"
+		case i+1 < sr.Position:
+			mark = "✓"
+		}
+		hash := c.Hash
+		if len(hash) > 12 {
+			hash = hash[:12]
+		}
+		fmt.Fprintf(f.w, "  %s %-12s %s\n", mark, hash, c.Subject)
+	}
+	if n := sr.Total - len(sr.Commits); n > 0 {
+		fmt.Fprintf(f.w, "  … and %d more\n", n)
+	}
+	if len(sr.Steps) > 0 {
+		fmt.Fprintln(f.w, "Also runs:")
+		for _, step := range sr.Steps {
+			fmt.Fprintf(f.w, "  %s\n", step)
+		}
+	}
+	if rest := sr.Total - sr.Position; rest > 0 {
+		fmt.Fprintf(f.w, "Approve with --series to also sign the %d remaining commits of this %s.\n", rest, sr.Operation)
+	}
+}
+
+// writeDiff prints the diffstat and patch of a commit being signed. The patch
+// is printed unindented so it can be piped to a pager or `git apply --stat`.
+func (f *Formatter) writeDiff(d *CommitDiff) {
+	fmt.Fprintf(f.w, "\nDiff (%s):\n", diffStat(d))
+	for _, file := range d.Files {
+		if file.Binary {
+			fmt.Fprintf(f.w, "  %s  (binary)\n", file.Path)
+		} else {
+			fmt.Fprintf(f.w, "  %s  +%d -%d\n", file.Path, file.Additions, file.Deletions)
+		}
+	}
+	if d.Patch != "" {
+		fmt.Fprintf(f.w, "\n%s", d.Patch)
+		if !strings.HasSuffix(d.Patch, "\n") {
+			fmt.Fprintln(f.w)
+		}
+	}
+	if d.Truncated {
+		fmt.Fprintln(f.w, "[diff truncated]")
+	}
+}
+
+// diffStat summarizes a diff as "N files, +A -D".
+func diffStat(d *CommitDiff) string {
+	files := "1 file"
+	if d.TotalFiles != 1 {
+		files = fmt.Sprintf("%d files", d.TotalFiles)
+	}
+	return fmt.Sprintf("%s, +%d -%d", files, d.Additions, d.Deletions)
+}
+
 // FormatHistory outputs history entries as a table.
 func (f *Formatter) FormatHistory(entries []HistoryEntry) error {
 	if f.asJSON {
@@ -312,6 +426,135 @@ func formatAgo(t time.Time) string {
 	return ago.String() + " ago"
 }
 
+// FormatRule outputs a trust rule as the serve.rules list item it becomes in
+// config.yaml.
+func (f *Formatter) FormatRule(rule TrustRule) error {
+	if f.asJSON {
+		return json.NewEncoder(f.w).Encode(rule)
+	}
+	enc := yaml.NewEncoder(f.w)
+	enc.SetIndent(2)
+	if err := enc.Encode([]TrustRule{rule}); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// FormatRuleTest outputs `rule test` results, marking with "*" the requests
+// whose decision the candidate config changes, followed by a count.
+func (f *Formatter) FormatRuleTest(results []RuleTestResult) error {
+	if f.asJSON {
+		return json.NewEncoder(f.w).Encode(results)
+	}
+
+	if len(results) == 0 {
+		fmt.Fprintln(f.w, "No requests to test")
+		return nil
+	}
+
+	fmt.Fprintf(f.w, "  %-8s  %-12s  %-15s  %-20s  %-13s  %-30s  %s\n", "ID", "TYPE", "COLLECTION", "SUMMARY", "RECORDED", "CURRENT", "CANDIDATE")
+	fmt.Fprintf(f.w, "  %-8s  %-12s  %-15s  %-20s  %-13s  %-30s  %s\n", "--------", "------------", "---------------", "--------------------", "-------------", "------------------------------", "---------")
+
+	changed := 0
+	for _, r := range results {
+		mark := " "
+		if r.Changed() {
+			mark = "*"
+			changed++
+		}
+		id := truncate(r.Request.ID, 8)
+		if id == "" {
+			id = "-"
+		}
+		coll := truncate(extractCollection(r.Request), 15)
+		if coll == "" {
+			coll = "-"
+		}
+		recorded := r.Recorded
+		if recorded == "" {
+			recorded = "-"
+		}
+		fmt.Fprintf(f.w, "%s %-8s  %-12s  %-15s  %-20s  %-13s  %-30s  %s\n", mark, id, truncate(r.Request.Type, 12), coll,
+			truncate(requestSummary(r.Request), 20), truncate(recorded, 13),
+			truncate(formatDecision(r.Current, r.CurrentRule), 30), formatDecision(r.Candidate, r.CandidateRule))
+	}
+	fmt.Fprintf(f.w, "\n%d of %d decisions change\n", changed, len(results))
+	return nil
+}
+
+// FormatRuleStats outputs trust rule hit statistics, one rule per line, with
+// the reason a rule deserves review in the FLAG column.
+func (f *Formatter) FormatRuleStats(stats []RuleStat) error {
+	if f.asJSON {
+		return json.NewEncoder(f.w).Encode(stats)
+	}
+
+	if len(stats) == 0 {
+		fmt.Fprintln(f.w, "No trust rules")
+		return nil
+	}
+
+	fmt.Fprintf(f.w, "%-30s  %6s  %-16s  %4s  %s\n", "RULE", "HITS", "LAST HIT", "EXES", "FLAG")
+	fmt.Fprintf(f.w, "%-30s  %6s  %-16s  %4s  %s\n", "------------------------------", "------", "----------------", "----", "----")
+
+	for _, s := range stats {
+		last := "never"
+		if !s.LastHit.IsZero() {
+			last = s.LastHit.Local().Format("2006-01-02 15:04")
+		}
+		var flags []string
+		if s.Unused {
+			flags = append(flags, "unused")
+		}
+		if s.Broad {
+			flags = append(flags, "broad")
+		}
+		flag := strings.Join(flags, ",")
+		if flag == "" {
+			flag = "-"
+		}
+		fmt.Fprintf(f.w, "%-30s  %6d  %-16s  %4d  %s\n", truncate(s.Rule, 30), s.Hits, last, len(s.Executables), flag)
+	}
+	return nil
+}
+
+// FormatTrash outputs the undoable deletions as a table.
+func (f *Formatter) FormatTrash(entries []TrashEntry) error {
+	if f.asJSON {
+		return json.NewEncoder(f.w).Encode(entries)
+	}
+
+	if len(entries) == 0 {
+		fmt.Fprintln(f.w, "Trash is empty")
+		return nil
+	}
+
+	fmt.Fprintf(f.w, "%-8s  %-20s  %-10s  %-15s  %-25s  %-14s  %s\n", "ID", "CLIENT", "KIND", "COLLECTION", "SUMMARY", "DELETED", "EXPIRES")
+	fmt.Fprintf(f.w, "%-8s  %-20s  %-10s  %-15s  %-25s  %-14s  %s\n", "--------", "--------------------", "----------", "---------------", "-------------------------", "--------------", "-------")
+
+	for _, e := range entries {
+		coll := e.CollectionLabel
+		if coll == "" {
+			coll = dbustypes.ExtractCollection(e.Collection)
+		}
+		summary := fmt.Sprintf("%d items", len(e.Items))
+		if len(e.Items) == 1 {
+			summary = e.Items[0].Label
+		}
+		fmt.Fprintf(f.w, "%-8s  %-20s  %-10s  %-15s  %-25s  %-14s  %s\n",
+			truncate(e.ID, 8), truncate(e.Client, 20), e.Kind, truncate(coll, 15),
+			truncate(summary, 25), truncate(formatAgo(e.DeletedAt), 14), formatRemaining(e.ExpiresAt))
+	}
+	return nil
+}
+
+func formatDecision(decision, rule string) string {
+	if rule == "" {
+		return decision
+	}
+	return decision + " (" + rule + ")"
+}
+
 // FormatAction outputs an action result.
 func (f *Formatter) FormatAction(action, id string) error {
 	if f.asJSON {
@@ -120,6 +120,121 @@ func TestFormatRequest_GPGSign(t *testing.T) {
 	mustNotContain(t, out, "Query:")
 }
 
+func TestFormatRequest_GPGSign_Diff(t *testing.T) {
+	req := &PendingRequest{
+		ID:        "abc-123",
+		Type:      "gpg_sign",
+		ExpiresAt: time.Now().Add(5 * time.Minute),
+		GPGSignInfo: &GPGSignInfo{
+			RepoName:     "myrepo",
+			CommitMsg:    "feat: add logo",
+			ChangedFiles: []string{"main.go", "logo.png"},
+			Diff: &CommitDiff{
+				Files: []DiffFile{
+					{Path: "main.go", Additions: 2, Deletions: 1},
+					{Path: "logo.png", Binary: true},
+				},
+				TotalFiles: 2,
+				Additions:  2,
+				Deletions:  1,
+				Patch:      "diff --git a/main.go b/main.go\n-old\n+new\n+more\n",
+				Truncated:  true,
+			},
+		},
+	}
+
+	var buf strings.Builder
+	if err := NewFormatter(&buf, false).FormatShowResult(&ShowResult{Request: *req}); err != nil {
+		t.Fatalf("FormatShowResult failed: %v", err)
+	}
+	out := buf.String()
+	mustContain(t, out, "Diff (2 files, +2 -1):")
+	mustContain(t, out, "  main.go  +2 -1")
+	mustContain(t, out, "  logo.png  (binary)")
+	mustContain(t, out, "\ndiff --git a/main.go b/main.go\n-old\n+new\n")
+	mustContain(t, out, "[diff truncated]")
+}
+
+func TestFormatRequest_GPGSign_Unverified(t *testing.T) {
+	req := &PendingRequest{
+		ID:        "abc-123",
+		Type:      "gpg_sign",
+		ExpiresAt: time.Now().Add(5 * time.Minute),
+		GPGSignInfo: &GPGSignInfo{
+			RepoName:     "myrepo",
+			CommitMsg:    "fix: typo",
+			Verification: &SignVerification{Problems: []string{`claims repository "myrepo" but the caller is in "dotfiles"`}},
+		},
+	}
+
+	var buf strings.Builder
+	if err := NewFormatter(&buf, false).FormatShowResult(&ShowResult{Request: *req}); err != nil {
+		t.Fatalf("FormatShowResult failed: %v", err)
+	}
+	out := buf.String()
+	mustContain(t, out, "WARNING: this request does not match the caller's repository:")
+	mustContain(t, out, `  - claims repository "myrepo" but the caller is in "dotfiles"`)
+
+	req.GPGSignInfo.Verification = &SignVerification{Verified: true}
+	buf.Reset()
+	if err := NewFormatter(&buf, false).FormatShowResult(&ShowResult{Request: *req}); err != nil {
+		t.Fatalf("FormatShowResult failed: %v", err)
+	}
+	mustNotContain(t, buf.String(), "WARNING")
+}
+
+func TestFormatRequest_GPGSign_SSHKey(t *testing.T) {
+	req := &PendingRequest{
+		ID:        "abc-123",
+		Type:      "gpg_sign",
+		ExpiresAt: time.Now().Add(5 * time.Minute),
+		GPGSignInfo: &GPGSignInfo{
+			RepoName:    "myrepo",
+			Kind:        "commit",
+			Format:      "ssh",
+			CommitMsg:   "fix: typo",
+			KeyID:       "/home/user/.ssh/id_ed25519",
+			Fingerprint: "SHA256:2Tf0rV8c1bN4",
+		},
+	}
+
+	var buf strings.Builder
+	if err := NewFormatter(&buf, false).FormatShowResult(&ShowResult{Request: *req}); err != nil {
+		t.Fatalf("FormatShowResult failed: %v", err)
+	}
+	mustContain(t, buf.String(), "Key:     SSH SHA256:2Tf0rV8c1bN4 (/home/user/.ssh/id_ed25519)")
+}
+
+func TestFormatRequest_GPGSign_Series(t *testing.T) {
+	req := &PendingRequest{
+		ID:        "abc-123",
+		Type:      "gpg_sign",
+		ExpiresAt: time.Now().Add(5 * time.Minute),
+		GPGSignInfo: &GPGSignInfo{
+			RepoName:  "myrepo",
+			Kind:      "commit",
+			CommitMsg: "second",
+			Series: &SignSeries{Operation: "rebase", Position: 2, Total: 3, Commits: []SeriesCommit{
+				{Hash: "68009ddcc0feb02084c3e23cc2d9fcc0205a7e1e", Subject: "first"},
+				{Hash: "18704a2c068c25fcf14140ee556c25875650a7b3", Subject: "second"},
+				{Hash: "cc90da9512761411910e283250da4b5b49d278cb", Subject: "third"},
+			}, Steps: []string{"exec make test"}},
+		},
+	}
+
+	var buf strings.Builder
+	if err := NewFormatter(&buf, false).FormatShowResult(&ShowResult{Request: *req}); err != nil {
+		t.Fatalf("FormatShowResult failed: %v", err)
+	}
+	out := buf.String()
+	mustContain(t, out, "Series (rebase, commit 2 of 3):")
+	mustContain(t, out, "  ✓ 68009ddcc0fe first")
+	mustContain(t, out, "  > 18704a2c068c second")
+	mustContain(t, out, "    cc90da951276 third")
+	mustContain(t, out, "Also runs:\n  exec make test\n")
+	mustContain(t, out, "--series to also sign the 1 remaining commits")
+}
+
 func TestFormatRequest_GPGSign_SameCommitter(t *testing.T) {
 	req := &PendingRequest{
 		ID:        "abc-123",
@@ -238,6 +353,37 @@ func TestFormatRequest_MultipleItemsShowAttributes(t *testing.T) {
 	mustContain(t, out, "- Secret2  /org/secrets/2")
 }
 
+func TestFormatRequest_PerItemNumbered(t *testing.T) {
+	req := &PendingRequest{
+		ID:      "xyz-790",
+		Client:  "myapp",
+		Type:    "get_secret",
+		PerItem: true,
+		Items: []ItemInfo{
+			{Label: "Secret1", Path: "/org/secrets/1"},
+			{Label: "Secret2", Path: "/org/secrets/2"},
+		},
+		ExpiresAt: time.Now().Add(5 * time.Minute),
+	}
+
+	var buf strings.Builder
+	f := NewFormatter(&buf, false)
+	if err := f.FormatShowResult(&ShowResult{Request: *req}); err != nil {
+		t.Fatalf("FormatRequest failed: %v", err)
+	}
+	mustContain(t, buf.String(), "  1. Secret1  /org/secrets/1\n")
+	mustContain(t, buf.String(), "  2. Secret2  /org/secrets/2\n")
+	mustContain(t, buf.String(), "--items 1,3")
+
+	req.ApprovedItems = []string{"/org/secrets/2"}
+	buf.Reset()
+	if err := f.FormatShowResult(&ShowResult{Request: *req}); err != nil {
+		t.Fatalf("FormatRequest failed: %v", err)
+	}
+	mustContain(t, buf.String(), "  1. Secret1  /org/secrets/1  (refused)\n")
+	mustContain(t, buf.String(), "  2. Secret2  /org/secrets/2\n")
+}
+
 func TestFormatRequest_ShowsCollection(t *testing.T) {
 	req := &PendingRequest{
 		ID:        "xyz-456",
@@ -384,3 +530,29 @@ func mustNotContain(t *testing.T, s, substr string) {
 		t.Errorf("expected output NOT to contain %q\nfull output:\n%s", substr, s)
 	}
 }
+
+func TestFormatRule(t *testing.T) {
+	var buf strings.Builder
+	f := NewFormatter(&buf, false)
+	err := f.FormatRule(TrustRule{
+		Name:         "gh-login",
+		Action:       "approve",
+		RequestTypes: []string{"get_secret"},
+		Process:      &ProcessMatcher{Exe: "/usr/bin/gh"},
+		Secret:       &SecretMatcher{Collection: "login"},
+	})
+	if err != nil {
+		t.Fatalf("FormatRule: %v", err)
+	}
+	want := `- name: gh-login
+  action: approve
+  request_types: [get_secret]
+  process:
+    exe: /usr/bin/gh
+  secret:
+    collection: login
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
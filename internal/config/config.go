@@ -2,12 +2,17 @@ package config
 
 import (
 	"bytes"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/nikicat/secrets-dispatcher/internal/procutil"
 	"gopkg.in/yaml.v3"
 )
 
@@ -22,12 +27,15 @@ const (
 	DefaultAutoApproveDuration   = 2 * time.Minute
 	DefaultNotificationDelay     = 500 * time.Millisecond
 	DefaultUpstreamSlowThreshold = 1500 * time.Millisecond
+	DefaultTrashRetention        = 7 * 24 * time.Hour
 )
 
 var defaultNotifications = true
+var defaultHistoryPersist = true
 var defaultShowPIDs = false
 var defaultTrimProcessChain = true
 var defaultIgnoreChromeDummySecret = true
+var defaultRequirePairing = true
 
 // BusConfig describes a D-Bus endpoint (upstream backend or downstream front).
 type BusConfig struct {
@@ -35,6 +43,32 @@ type BusConfig struct {
 	Path string `yaml:"path,omitempty"` // required for "socket" and "sockets" types
 }
 
+// DefaultUpstreamName names the backend of a single-upstream config, as
+// matched by the backend field of trust rules.
+const DefaultUpstreamName = "default"
+
+// UpstreamConfig is one backend of serve.upstreams. The first one is the
+// default backend: it keeps its object paths and aliases as they are and gets
+// new collections. Every other backend is mounted under Prefix, which is
+// prepended to its collection names on the front (collection "default" of a
+// backend with prefix "team_" is seen as "team_default"), and Aliases maps
+// front alias names to its own.
+type UpstreamConfig struct {
+	Name      string            `yaml:"name"`
+	BusConfig `yaml:",inline"`  // "session_bus" or "socket"
+	Prefix    string            `yaml:"prefix,omitempty"`
+	Aliases   map[string]string `yaml:"aliases,omitempty"` // front alias -> backend alias
+}
+
+// UpstreamList returns the configured backends: serve.upstreams, or the single
+// serve.upstream named DefaultUpstreamName.
+func (s *ServeConfig) UpstreamList() []UpstreamConfig {
+	if len(s.Upstreams) > 0 {
+		return s.Upstreams
+	}
+	return []UpstreamConfig{{Name: DefaultUpstreamName, BusConfig: s.Upstream}}
+}
+
 // WithDefaults returns a copy of cfg with zero-value fields filled from program defaults.
 func (cfg *Config) WithDefaults() *Config {
 	out := *cfg
@@ -54,6 +88,9 @@ func (cfg *Config) WithDefaults() *Config {
 	if s.HistoryLimit == 0 {
 		s.HistoryLimit = DefaultHistoryLimit
 	}
+	if s.HistoryPersist == nil {
+		s.HistoryPersist = &defaultHistoryPersist
+	}
 	if s.Notifications == nil {
 		s.Notifications = &defaultNotifications
 	}
@@ -66,6 +103,9 @@ func (cfg *Config) WithDefaults() *Config {
 	if s.IgnoreChromeDummySecret == nil {
 		s.IgnoreChromeDummySecret = &defaultIgnoreChromeDummySecret
 	}
+	if s.RequirePairing == nil {
+		s.RequirePairing = &defaultRequirePairing
+	}
 	if s.ApprovalWindow == 0 {
 		s.ApprovalWindow = Duration(DefaultApprovalWindow)
 	}
@@ -79,7 +119,11 @@ func (cfg *Config) WithDefaults() *Config {
 		d := Duration(DefaultUpstreamSlowThreshold)
 		s.UpstreamSlowThreshold = &d
 	}
-	if s.Upstream.Type == "" {
+	if s.TrashRetention == nil {
+		d := Duration(DefaultTrashRetention)
+		s.TrashRetention = &d
+	}
+	if s.Upstream.Type == "" && len(s.Upstreams) == 0 {
 		s.Upstream = BusConfig{Type: "session_bus"}
 	}
 	if len(s.Downstream) == 0 {
@@ -92,13 +136,22 @@ func (cfg *Config) WithDefaults() *Config {
 func (cfg *Config) Validate() error {
 	s := &cfg.Serve
 
-	switch s.Upstream.Type {
-	case "session_bus", "socket":
-	default:
-		return fmt.Errorf("upstream type must be \"session_bus\" or \"socket\", got %q", s.Upstream.Type)
-	}
-	if s.Upstream.Type == "socket" && s.Upstream.Path == "" {
-		return fmt.Errorf("upstream type \"socket\" requires a non-empty path")
+	if len(s.Upstreams) > 0 {
+		if s.Upstream.Type != "" {
+			return fmt.Errorf("set either upstream or upstreams, not both")
+		}
+		if err := validateUpstreams(s.Upstreams); err != nil {
+			return err
+		}
+	} else {
+		switch s.Upstream.Type {
+		case "session_bus", "socket":
+		default:
+			return fmt.Errorf("upstream type must be \"session_bus\" or \"socket\", got %q", s.Upstream.Type)
+		}
+		if s.Upstream.Type == "socket" && s.Upstream.Path == "" {
+			return fmt.Errorf("upstream type \"socket\" requires a non-empty path")
+		}
 	}
 
 	hasSessionBusDown := false
@@ -118,13 +171,102 @@ func (cfg *Config) Validate() error {
 		}
 	}
 
-	if s.Upstream.Type == "session_bus" && hasSessionBusDown {
+	sessionBusUp := slices.ContainsFunc(s.UpstreamList(), func(u UpstreamConfig) bool { return u.Type == "session_bus" })
+	if sessionBusUp && hasSessionBusDown {
 		return fmt.Errorf("upstream and downstream cannot both be session_bus (same bus)")
 	}
+	if s.TrashRetention != nil && *s.TrashRetention < 0 {
+		return fmt.Errorf("trash_retention must not be negative")
+	}
 
-	// Validate trust rules
+	return s.ValidatePolicy()
+}
+
+// validateUpstreams checks the backends of serve.upstreams: unique names, a
+// reachable bus for each, and prefixes and aliases that route every front
+// path to exactly one backend.
+func validateUpstreams(upstreams []UpstreamConfig) error {
+	names := map[string]bool{}
+	prefixes := map[string]bool{}
+	aliases := map[string]string{}
+	sessionBus := false
+	for i, u := range upstreams {
+		if u.Name == "" {
+			return fmt.Errorf("upstreams[%d]: name is required", i)
+		}
+		if names[u.Name] {
+			return fmt.Errorf("upstreams[%d]: duplicate name %q", i, u.Name)
+		}
+		names[u.Name] = true
+		switch u.Type {
+		case "session_bus":
+			if sessionBus {
+				return fmt.Errorf("upstreams[%d]: at most one session_bus upstream is allowed", i)
+			}
+			sessionBus = true
+		case "socket":
+			if u.Path == "" {
+				return fmt.Errorf("upstreams[%d]: type \"socket\" requires a non-empty path", i)
+			}
+		default:
+			return fmt.Errorf("upstreams[%d]: type must be \"session_bus\" or \"socket\", got %q", i, u.Type)
+		}
+		switch {
+		case i == 0 && u.Prefix != "":
+			return fmt.Errorf("upstreams[0]: the default (first) upstream takes no prefix")
+		case i > 0 && u.Prefix == "":
+			return fmt.Errorf("upstreams[%d]: prefix is required for all but the first upstream", i)
+		case i > 0 && !isPathElement(u.Prefix):
+			return fmt.Errorf("upstreams[%d]: prefix %q may only contain letters, digits and underscores", i, u.Prefix)
+		case i > 0 && !strings.HasSuffix(u.Prefix, "_"):
+			return fmt.Errorf("upstreams[%d]: prefix %q must end in \"_\"", i, u.Prefix)
+		case i > 0 && strings.HasPrefix(u.Prefix, "_"):
+			// A leading "_" marks the default upstream's collections that
+			// are named like another upstream's.
+			return fmt.Errorf("upstreams[%d]: prefix %q must not start with \"_\"", i, u.Prefix)
+		}
+		// A front collection name must belong to exactly one upstream.
+		for other := range prefixes {
+			if other != "" && (strings.HasPrefix(u.Prefix, other) || strings.HasPrefix(other, u.Prefix)) {
+				return fmt.Errorf("upstreams[%d]: prefix %q overlaps prefix %q of another upstream", i, u.Prefix, other)
+			}
+		}
+		prefixes[u.Prefix] = true
+		for front, backend := range u.Aliases {
+			if !isPathElement(front) || !isPathElement(backend) {
+				return fmt.Errorf("upstreams[%d]: alias %q -> %q: names may only contain letters, digits and underscores", i, front, backend)
+			}
+			if other, ok := aliases[front]; ok {
+				return fmt.Errorf("upstreams[%d]: alias %q is already mapped by upstream %q", i, front, other)
+			}
+			aliases[front] = u.Name
+		}
+	}
+	return nil
+}
+
+// isPathElement reports whether s is a valid, non-empty D-Bus object path
+// element.
+func isPathElement(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidatePolicy checks the trust rules and client policies alone, for tools
+// that evaluate a config's policy without serving it.
+func (s *ServeConfig) ValidatePolicy() error {
 	validRequestTypes := map[string]bool{
 		"get_secret": true, "search": true, "delete": true, "write": true, "unlock": true,
+		"ssh_sign": true, "ssh_add": true, "ssh_remove": true, "ssh_lock": true, "ssh_extension": true,
+		"pgp_decrypt": true, "pgp_sign": true, "pgp_export": true,
+		"gpg_sign": true,
 	}
 	for i, rule := range s.Rules {
 		action := rule.Action
@@ -151,13 +293,26 @@ func (cfg *Config) Validate() error {
 		}
 		// Validate glob patterns
 		for _, pat := range []struct{ name, val string }{
+			{"client", rule.Client},
+			{"backend", rule.Backend},
 			{"process.exe", strFromProcessMatcher(rule.Process, "exe")},
 			{"process.name", strFromProcessMatcher(rule.Process, "name")},
 			{"process.args", strFromProcessMatcher(rule.Process, "args")},
 			{"process.cwd", strFromProcessMatcher(rule.Process, "cwd")},
 			{"process.unit", strFromProcessMatcher(rule.Process, "unit")},
+			{"process.app_id", strFromProcessMatcher(rule.Process, "app_id")},
+			{"process.container.runtime", strFromProcessMatcher(rule.Process, "container.runtime")},
+			{"process.container.id", strFromProcessMatcher(rule.Process, "container.id")},
+			{"process.container.name", strFromProcessMatcher(rule.Process, "container.name")},
+			{"process.container.image", strFromProcessMatcher(rule.Process, "container.image")},
 			{"secret.collection", strFromSecretMatcher(rule.Secret, "collection")},
 			{"secret.label", strFromSecretMatcher(rule.Secret, "label")},
+			{"ssh.comment", strFromSSHMatcher(rule.SSH, "comment")},
+			{"ssh.destination", strFromSSHMatcher(rule.SSH, "destination")},
+			{"signing.repo", strFromSigningMatcher(rule.Signing, "repo")},
+			{"signing.key_id", strFromSigningMatcher(rule.Signing, "key_id")},
+			{"signing.author", strFromSigningMatcher(rule.Signing, "author")},
+			{"signing.tag_name", strFromSigningMatcher(rule.Signing, "tag_name")},
 		} {
 			if pat.val != "" {
 				if _, err := path.Match(pat.val, "test"); err != nil {
@@ -177,11 +332,95 @@ func (cfg *Config) Validate() error {
 				return fmt.Errorf("rules[%d]: invalid glob in search_attributes[%s]: %w", i, k, err)
 			}
 		}
+		if p := rule.Process; p != nil && p.ExeSHA256 != "" && !isSHA256Hex(p.ExeSHA256) {
+			return fmt.Errorf("rules[%d]: process.exe_sha256 must be 64 hex digits (see sha256sum), got %q", i, p.ExeSHA256)
+		}
+		if rule.SSH != nil && rule.SSH.Fingerprint != "" && !strings.HasPrefix(rule.SSH.Fingerprint, "SHA256:") {
+			return fmt.Errorf("rules[%d]: ssh.fingerprint must be a SHA256 fingerprint (\"SHA256:...\", see ssh-add -l), got %q", i, rule.SSH.Fingerprint)
+		}
+		if rule.Signing != nil {
+			if err := validateSigningRule(rule); err != nil {
+				return fmt.Errorf("rules[%d]: %w", i, err)
+			}
+		}
+	}
+
+	for i, p := range s.ClientPolicies {
+		if p.Client == "" {
+			return fmt.Errorf("client_policies[%d]: client is required", i)
+		}
+		if _, err := path.Match(p.Client, "test"); err != nil {
+			return fmt.Errorf("client_policies[%d]: invalid glob in client: %w", i, err)
+		}
+		if p.Default != "" && p.Default != "prompt" && p.Default != "deny" {
+			return fmt.Errorf("client_policies[%d]: default must be \"prompt\" or \"deny\", got %q", i, p.Default)
+		}
 	}
 
 	return nil
 }
 
+// Warnings returns policy problems that are legal but probably unintended:
+//   - an approve rule whose process.exe is a path a regular user can replace
+//     (their home, /tmp, a non-root-owned prefix) without exe_sha256 or
+//     exe_packaged pinning the executable. Anything running as that user can
+//     swap the binary and inherit the rule.
+//   - an approve rule matching the process on app_id without exe. Any
+//     unsandboxed process of the user can claim an app ID.
+func (s *ServeConfig) Warnings() []string {
+	var warnings []string
+	for i, rule := range s.Rules {
+		p := rule.Process
+		if rule.Action != "" && rule.Action != "approve" || p == nil {
+			continue
+		}
+		if p.AppID != "" && p.Exe == "" {
+			warnings = append(warnings, fmt.Sprintf(
+				"rules[%d]: process.app_id %q can be claimed by any unsandboxed process of the user; add process.exe, or use app_id in deny rules only",
+				i, p.AppID))
+		}
+		if p.Container != nil && p.Exe == "" {
+			warnings = append(warnings, fmt.Sprintf(
+				"rules[%d]: process.container can be claimed by any process of the user; add process.exe, or use container in deny rules only",
+				i))
+		}
+		if p.Exe == "" || p.ExeSHA256 != "" || p.ExePackaged {
+			continue
+		}
+		if exeWritableByUsers(p.Exe) {
+			warnings = append(warnings, fmt.Sprintf(
+				"rules[%d]: process.exe %q can be replaced by a non-root user; pin it with process.exe_sha256 or process.exe_packaged",
+				i, p.Exe))
+		}
+	}
+	return warnings
+}
+
+// exeWritableByUsers reports whether a non-root user can put a file at a path
+// matching the process.exe glob: for a glob, the directory before the first
+// wildcard decides.
+func exeWritableByUsers(exe string) bool {
+	if i := strings.IndexAny(exe, "*?[\\"); i >= 0 {
+		exe = exe[:i]
+		if !strings.HasSuffix(exe, "/") {
+			exe = path.Dir(exe)
+		}
+	}
+	if !path.IsAbs(exe) {
+		return true
+	}
+	return procutil.WritableByUsers(exe)
+}
+
+// isSHA256Hex reports whether s is a hex-encoded SHA-256 digest.
+func isSHA256Hex(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
 func strFromProcessMatcher(p *ProcessMatcher, field string) string {
 	if p == nil {
 		return ""
@@ -197,6 +436,20 @@ func strFromProcessMatcher(p *ProcessMatcher, field string) string {
 		return p.CWD
 	case "unit":
 		return p.Unit
+	case "app_id":
+		return p.AppID
+	}
+	if c := p.Container; c != nil {
+		switch field {
+		case "container.runtime":
+			return c.Runtime
+		case "container.id":
+			return c.ID
+		case "container.name":
+			return c.Name
+		case "container.image":
+			return c.Image
+		}
 	}
 	return ""
 }
@@ -214,6 +467,58 @@ func strFromSecretMatcher(s *SecretMatcher, field string) string {
 	return ""
 }
 
+func strFromSSHMatcher(s *SSHMatcher, field string) string {
+	if s == nil {
+		return ""
+	}
+	switch field {
+	case "comment":
+		return s.Comment
+	case "destination":
+		return s.Destination
+	}
+	return ""
+}
+
+func strFromSigningMatcher(s *SigningMatcher, field string) string {
+	if s == nil {
+		return ""
+	}
+	switch field {
+	case "repo":
+		return s.Repo
+	case "key_id":
+		return s.KeyID
+	case "author":
+		return s.Author
+	case "tag_name":
+		return s.TagName
+	}
+	return ""
+}
+
+// validateSigningRule rejects signing rules that could never match: a signing
+// matcher only applies to gpg_sign, which has no secrets, keys or search.
+func validateSigningRule(rule TrustRule) error {
+	if len(rule.RequestTypes) > 0 && !slices.Contains(rule.RequestTypes, "gpg_sign") {
+		return errors.New("signing requires request_types to include \"gpg_sign\"")
+	}
+	if rule.Secret != nil || rule.SSH != nil || len(rule.SearchAttributes) > 0 {
+		return errors.New("signing cannot be combined with secret, ssh or search_attributes")
+	}
+	switch rule.Signing.Kind {
+	case "", "commit", "tag", "push":
+	default:
+		return fmt.Errorf("signing.kind must be \"commit\", \"tag\", or \"push\", got %q", rule.Signing.Kind)
+	}
+	for _, f := range rule.Signing.Files {
+		if _, err := path.Match(f, "test"); err != nil {
+			return fmt.Errorf("invalid glob in signing.files: %w", err)
+		}
+	}
+	return nil
+}
+
 // defaultSocketsDir returns $XDG_RUNTIME_DIR/secrets-dispatcher/sockets.
 func defaultSocketsDir() string {
 	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
@@ -245,23 +550,28 @@ func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
 
 // ServeConfig holds serve-subcommand settings.
 type ServeConfig struct {
-	Upstream                BusConfig       `yaml:"upstream"`
-	Downstream              []BusConfig     `yaml:"downstream"`
-	LogLevel                string          `yaml:"log_level"`
-	LogFormat               string          `yaml:"log_format"`
-	Timeout                 Duration        `yaml:"timeout"`
-	HistoryLimit            int             `yaml:"history_limit"`
-	Notifications           *bool           `yaml:"notifications"`
-	ShowPIDs                *bool           `yaml:"show_pids"`
-	TrimProcessChain        *bool           `yaml:"trim_process_chain"`
-	ApprovalWindow          Duration        `yaml:"approval_window"`
-	AutoApproveDuration     Duration        `yaml:"auto_approve_duration"`
-	NotificationDelay       Duration        `yaml:"notification_delay"`
-	TrustedSigners          []TrustedSigner `yaml:"trusted_signers,omitempty"`
-	IgnoreChromeDummySecret *bool           `yaml:"ignore_chrome_dummy_secret"`
-	UpstreamSlowThreshold   *Duration       `yaml:"upstream_slow_threshold"`        // 0 disables; default 1.5s
-	UpstreamSlowAlways      *bool           `yaml:"upstream_slow_always,omitempty"` // show for all requests, not just auto-approved
-	Rules                   []TrustRule     `yaml:"rules,omitempty"`
+	Upstream                BusConfig        `yaml:"upstream,omitempty"`
+	Upstreams               []UpstreamConfig `yaml:"upstreams,omitempty"` // several backends merged behind one front; replaces upstream
+	Downstream              []BusConfig      `yaml:"downstream"`
+	LogLevel                string           `yaml:"log_level"`
+	LogFormat               string           `yaml:"log_format"`
+	Timeout                 Duration         `yaml:"timeout"`
+	HistoryLimit            int              `yaml:"history_limit"`
+	HistoryPersist          *bool            `yaml:"history_persist"` // append resolved requests to state_dir/history; default true
+	Notifications           *bool            `yaml:"notifications"`
+	ShowPIDs                *bool            `yaml:"show_pids"`
+	TrimProcessChain        *bool            `yaml:"trim_process_chain"`
+	ApprovalWindow          Duration         `yaml:"approval_window"`
+	AutoApproveDuration     Duration         `yaml:"auto_approve_duration"`
+	NotificationDelay       Duration         `yaml:"notification_delay"`
+	TrustedSigners          []TrustedSigner  `yaml:"trusted_signers,omitempty"`
+	IgnoreChromeDummySecret *bool            `yaml:"ignore_chrome_dummy_secret"`
+	UpstreamSlowThreshold   *Duration        `yaml:"upstream_slow_threshold"`        // 0 disables; default 1.5s
+	UpstreamSlowAlways      *bool            `yaml:"upstream_slow_always,omitempty"` // show for all requests, not just auto-approved
+	RequirePairing          *bool            `yaml:"require_pairing"`                // socket downstreams get secrets only once paired (clients.yaml); default true
+	TrashRetention          *Duration        `yaml:"trash_retention"`                // keep approved deletions this long for undo (state_dir/trash); 0 disables; default 168h
+	Rules                   []TrustRule      `yaml:"rules,omitempty"`
+	ClientPolicies          []ClientPolicy   `yaml:"client_policies,omitempty"` // per-client default when no rule matches; first match wins
 }
 
 // TrustedSigner defines a process that is auto-approved for GPG signing.
@@ -275,13 +585,26 @@ type TrustedSigner struct {
 // TrustRule defines a declarative rule for auto-approving or ignoring requests.
 type TrustRule struct {
 	Name             string            `yaml:"name,omitempty"`
-	Action           string            `yaml:"action,omitempty"` // "approve" (default), "ignore", or "deny"
+	Action           string            `yaml:"action,omitempty"`  // "approve" (default), "ignore", or "deny"
+	Client           string            `yaml:"client,omitempty"`  // glob, matches the downstream client name ("local" for the session bus)
+	Backend          string            `yaml:"backend,omitempty"` // glob, matches the upstream name of the items (see upstreams)
 	RequestTypes     []string          `yaml:"request_types,omitempty"`
 	Process          *ProcessMatcher   `yaml:"process,omitempty"`
 	Secret           *SecretMatcher    `yaml:"secret,omitempty"`
+	SSH              *SSHMatcher       `yaml:"ssh,omitempty"`
+	Signing          *SigningMatcher   `yaml:"signing,omitempty"`
 	SearchAttributes map[string]string `yaml:"search_attributes,omitempty"`
 }
 
+// ClientPolicy sets the default for requests from matching downstream clients
+// that no trust rule matches. "deny" blocks them outright — including searches
+// and unlocks, which are otherwise passed through — so only what a rule
+// explicitly allows gets through.
+type ClientPolicy struct {
+	Client  string `yaml:"client"`            // glob, matches the downstream client name
+	Default string `yaml:"default,omitempty"` // "prompt" (default) or "deny"
+}
+
 // ProcessMatcher matches against sender process attributes.
 //
 // Exe is the security-grade matcher: it compares the kernel-resolved
@@ -289,13 +612,35 @@ type TrustRule struct {
 // attacker-controllable (prctl(PR_SET_NAME)) — it is advisory only and must not
 // be relied on for deny rules. Args matches individual cmdline arguments, which
 // are equally self-reported (a process can rewrite its argv after exec) — also
-// advisory only. Unit matches the caller's real systemd unit.
+// advisory only. Unit matches the caller's real systemd unit. AppID matches the
+// Flatpak or Snap application ID, which the app itself cannot change but any
+// unsandboxed process of the user can claim (see procutil.ReadApp): approve
+// rules should not rely on it alone. Container matches the container the
+// caller runs in, which any process of the user can claim in the same way (see
+// procutil.ReadContainer). ExeSHA256 and ExePackaged pin the contents of the executable
+// Exe names, so replacing the file at that path does not inherit the rule.
 type ProcessMatcher struct {
-	Exe  string `yaml:"exe,omitempty"`  // glob, matches any process's /proc/exe in the chain (non-spoofable)
-	Name string `yaml:"name,omitempty"` // glob, matches any process comm in the chain — ADVISORY: comm is spoofable
-	Args string `yaml:"args,omitempty"` // glob, matches any single cmdline arg of any process in the chain — ADVISORY: argv is spoofable
-	CWD  string `yaml:"cwd,omitempty"`  // glob, matches any process's CWD in the chain
-	Unit string `yaml:"unit,omitempty"` // glob, matches the caller's real systemd unit (from GetUnitByPID)
+	Exe         string `yaml:"exe,omitempty"`          // glob, matches any process's /proc/exe in the chain (non-spoofable)
+	ExeSHA256   string `yaml:"exe_sha256,omitempty"`   // hex SHA-256 of the running executable, checked on the same process as exe
+	ExePackaged bool   `yaml:"exe_packaged,omitempty"` // the same process's executable is installed by a system package (or on a read-only mount) and only root can replace it
+	Name        string `yaml:"name,omitempty"`         // glob, matches any process comm in the chain — ADVISORY: comm is spoofable
+	Args        string `yaml:"args,omitempty"`         // glob, matches any single cmdline arg of any process in the chain — ADVISORY: argv is spoofable
+	CWD         string `yaml:"cwd,omitempty"`          // glob, matches any process's CWD in the chain
+	Unit        string `yaml:"unit,omitempty"`         // glob, matches the caller's real systemd unit (from GetUnitByPID)
+	AppID       string `yaml:"app_id,omitempty"`       // glob, matches the caller's Flatpak/Snap application ID — claimable by unsandboxed processes, pair with exe to approve
+	// Container matches the Docker/Podman/containerd/nspawn container of the
+	// caller; callers outside a container never match. Claimable by any
+	// process of the user, so pair it with exe to approve.
+	Container *ContainerMatcher `yaml:"container,omitempty"`
+}
+
+// ContainerMatcher matches the container of the caller. Runtime and ID come
+// from the caller's cgroup; Name and Image from the runtime's state files.
+type ContainerMatcher struct {
+	Runtime string `yaml:"runtime,omitempty"` // glob: docker, podman, containerd, cri-o, systemd-nspawn
+	ID      string `yaml:"id,omitempty"`      // glob, matches the full or 12-character short ID
+	Name    string `yaml:"name,omitempty"`    // glob
+	Image   string `yaml:"image,omitempty"`   // glob, e.g. "docker.io/library/node:*"
 }
 
 // SecretMatcher matches against secret/item attributes.
@@ -305,18 +650,42 @@ type SecretMatcher struct {
 	Attributes map[string]string `yaml:"attributes,omitempty"` // exact subset match
 }
 
+// SSHMatcher matches the key of SSH agent requests (ssh_sign, ssh_add, ssh_remove).
+type SSHMatcher struct {
+	Fingerprint string `yaml:"fingerprint,omitempty"` // exact, as printed by ssh-add -l ("SHA256:...")
+	Comment     string `yaml:"comment,omitempty"`     // glob
+	Destination string `yaml:"destination,omitempty"` // glob, the host ssh is connecting to (signing only)
+}
+
+// SigningMatcher matches gpg_sign requests by what is being signed.
+type SigningMatcher struct {
+	Repo    string   `yaml:"repo,omitempty"`     // glob, repository basename
+	Kind    string   `yaml:"kind,omitempty"`     // "commit", "tag", or "push"
+	KeyID   string   `yaml:"key_id,omitempty"`   // glob, key ID or fingerprint, case-insensitive
+	Author  string   `yaml:"author,omitempty"`   // glob, "Name <email>" of the author, tagger or pusher
+	Files   []string `yaml:"files,omitempty"`    // globs over changed files; "dir/**" matches everything below dir
+	TagName string   `yaml:"tag_name,omitempty"` // glob, tags only
+}
+
 // SSHConfig configures the SSH agent proxy. Nil means disabled.
 type SSHConfig struct {
 	Upstream string `yaml:"upstream"` // path to real agent socket; empty = $SSH_AUTH_SOCK at startup
 	Listen   string `yaml:"listen"`   // proxy socket path; empty = $XDG_RUNTIME_DIR/secrets-dispatcher/ssh-agent.sock
 }
 
+// GPGAgentConfig configures the gpg-agent proxy. Nil means disabled.
+type GPGAgentConfig struct {
+	Upstream string `yaml:"upstream"` // path to real agent socket; empty = `gpgconf --list-dirs agent-socket` at startup
+	Listen   string `yaml:"listen"`   // proxy socket path; empty = $XDG_RUNTIME_DIR/secrets-dispatcher/gpg-agent.sock
+}
+
 // Config is the top-level configuration file structure.
 type Config struct {
-	StateDir string      `yaml:"state_dir"`
-	Listen   string      `yaml:"listen"`
-	Serve    ServeConfig `yaml:"serve"`
-	SSH      *SSHConfig  `yaml:"ssh,omitempty"`
+	StateDir string          `yaml:"state_dir"`
+	Listen   string          `yaml:"listen"`
+	Serve    ServeConfig     `yaml:"serve"`
+	SSH      *SSHConfig      `yaml:"ssh,omitempty"`
+	GPGAgent *GPGAgentConfig `yaml:"gpg_agent,omitempty"`
 }
 
 // DefaultPath returns the default config file path using XDG_CONFIG_HOME.
@@ -342,7 +711,11 @@ func Load(path string) (*Config, error) {
 		}
 		return nil, err
 	}
+	return parse(data, path)
+}
 
+// parse decodes config YAML, rejecting unknown keys. path is used in errors only.
+func parse(data []byte, path string) (*Config, error) {
 	var cfg Config
 	dec := yaml.NewDecoder(bytes.NewReader(data))
 	dec.KnownFields(true)
@@ -215,6 +215,9 @@ func TestWithDefaults(t *testing.T) {
 	if out.Serve.Downstream[0].Path != "/run/user/1000/secrets-dispatcher/sockets" {
 		t.Errorf("Downstream[0].Path = %q", out.Serve.Downstream[0].Path)
 	}
+	if out.Serve.RequirePairing == nil || !*out.Serve.RequirePairing {
+		t.Error("RequirePairing should default to true")
+	}
 }
 
 func TestValidate(t *testing.T) {
@@ -277,6 +280,126 @@ func TestValidate(t *testing.T) {
 			}},
 			wantErr: "at most one session_bus downstream",
 		},
+		{
+			name: "valid upstreams",
+			cfg: Config{Serve: ServeConfig{
+				Upstreams: []UpstreamConfig{
+					{Name: "personal", BusConfig: BusConfig{Type: "session_bus"}},
+					{Name: "work", BusConfig: BusConfig{Type: "socket", Path: "/run/work.sock"}, Prefix: "work_", Aliases: map[string]string{"work": "login"}},
+				},
+				Downstream: []BusConfig{{Type: "sockets", Path: "/run/socks"}},
+			}},
+		},
+		{
+			name: "upstream and upstreams",
+			cfg: Config{Serve: ServeConfig{
+				Upstream:   BusConfig{Type: "session_bus"},
+				Upstreams:  []UpstreamConfig{{Name: "personal", BusConfig: BusConfig{Type: "session_bus"}}},
+				Downstream: []BusConfig{{Type: "sockets", Path: "/run/socks"}},
+			}},
+			wantErr: "not both",
+		},
+		{
+			name: "second upstream without prefix",
+			cfg: Config{Serve: ServeConfig{
+				Upstreams: []UpstreamConfig{
+					{Name: "personal", BusConfig: BusConfig{Type: "session_bus"}},
+					{Name: "work", BusConfig: BusConfig{Type: "socket", Path: "/run/work.sock"}},
+				},
+				Downstream: []BusConfig{{Type: "sockets", Path: "/run/socks"}},
+			}},
+			wantErr: "prefix",
+		},
+		{
+			name: "upstream prefix not a path element",
+			cfg: Config{Serve: ServeConfig{
+				Upstreams: []UpstreamConfig{
+					{Name: "personal", BusConfig: BusConfig{Type: "session_bus"}},
+					{Name: "work", BusConfig: BusConfig{Type: "socket", Path: "/run/work.sock"}, Prefix: "work-"},
+				},
+				Downstream: []BusConfig{{Type: "sockets", Path: "/run/socks"}},
+			}},
+			wantErr: "prefix",
+		},
+		{
+			name: "upstream prefix without separator",
+			cfg: Config{Serve: ServeConfig{
+				Upstreams: []UpstreamConfig{
+					{Name: "personal", BusConfig: BusConfig{Type: "session_bus"}},
+					{Name: "work", BusConfig: BusConfig{Type: "socket", Path: "/run/work.sock"}, Prefix: "work"},
+				},
+				Downstream: []BusConfig{{Type: "sockets", Path: "/run/socks"}},
+			}},
+			wantErr: "must end in",
+		},
+		{
+			name: "upstream prefix with leading underscore",
+			cfg: Config{Serve: ServeConfig{
+				Upstreams: []UpstreamConfig{
+					{Name: "personal", BusConfig: BusConfig{Type: "session_bus"}},
+					{Name: "work", BusConfig: BusConfig{Type: "socket", Path: "/run/work.sock"}, Prefix: "_work_"},
+				},
+				Downstream: []BusConfig{{Type: "sockets", Path: "/run/socks"}},
+			}},
+			wantErr: "must not start with",
+		},
+		{
+			name: "upstream prefixes overlap",
+			cfg: Config{Serve: ServeConfig{
+				Upstreams: []UpstreamConfig{
+					{Name: "personal", BusConfig: BusConfig{Type: "session_bus"}},
+					{Name: "work", BusConfig: BusConfig{Type: "socket", Path: "/run/work.sock"}, Prefix: "work_"},
+					{Name: "work-ci", BusConfig: BusConfig{Type: "socket", Path: "/run/ci.sock"}, Prefix: "work_ci_"},
+				},
+				Downstream: []BusConfig{{Type: "sockets", Path: "/run/socks"}},
+			}},
+			wantErr: "overlaps",
+		},
+		{
+			name: "duplicate upstream prefix",
+			cfg: Config{Serve: ServeConfig{
+				Upstreams: []UpstreamConfig{
+					{Name: "personal", BusConfig: BusConfig{Type: "session_bus"}},
+					{Name: "work", BusConfig: BusConfig{Type: "socket", Path: "/run/work.sock"}, Prefix: "work_"},
+					{Name: "work2", BusConfig: BusConfig{Type: "socket", Path: "/run/work2.sock"}, Prefix: "work_"},
+				},
+				Downstream: []BusConfig{{Type: "sockets", Path: "/run/socks"}},
+			}},
+			wantErr: "overlaps",
+		},
+		{
+			name: "duplicate upstream name",
+			cfg: Config{Serve: ServeConfig{
+				Upstreams: []UpstreamConfig{
+					{Name: "personal", BusConfig: BusConfig{Type: "session_bus"}},
+					{Name: "personal", BusConfig: BusConfig{Type: "socket", Path: "/run/work.sock"}, Prefix: "work_"},
+				},
+				Downstream: []BusConfig{{Type: "sockets", Path: "/run/socks"}},
+			}},
+			wantErr: "duplicate",
+		},
+		{
+			name: "alias mapped twice",
+			cfg: Config{Serve: ServeConfig{
+				Upstreams: []UpstreamConfig{
+					{Name: "personal", BusConfig: BusConfig{Type: "session_bus"}, Aliases: map[string]string{"work": "login"}},
+					{Name: "work", BusConfig: BusConfig{Type: "socket", Path: "/run/work.sock"}, Prefix: "work_", Aliases: map[string]string{"work": "login"}},
+				},
+				Downstream: []BusConfig{{Type: "sockets", Path: "/run/socks"}},
+			}},
+			wantErr: "alias",
+		},
+		{
+			name: "session_bus upstream among upstreams and session_bus downstream",
+			cfg: Config{Serve: ServeConfig{
+				Upstreams: []UpstreamConfig{
+					{Name: "vault", BusConfig: BusConfig{Type: "socket", Path: "/run/vault.sock"}},
+					{Name: "personal", BusConfig: BusConfig{Type: "session_bus"}, Prefix: "p_"},
+				},
+				Downstream: []BusConfig{{Type: "session_bus"}},
+			}},
+			wantErr: "cannot both be session_bus",
+		},
 		{
 			name: "valid deny rule",
 			cfg: Config{Serve: ServeConfig{
@@ -313,6 +436,142 @@ func TestValidate(t *testing.T) {
 				}},
 			}},
 		},
+		{
+			name: "valid ssh agent management rule",
+			cfg: Config{Serve: ServeConfig{
+				Upstream:   BusConfig{Type: "session_bus"},
+				Downstream: []BusConfig{{Type: "sockets", Path: "/run/socks"}},
+				Rules: []TrustRule{{
+					Name:         "ssh-add",
+					RequestTypes: []string{"ssh_add", "ssh_lock"},
+					Process:      &ProcessMatcher{Exe: "/usr/bin/ssh-add"},
+				}},
+			}},
+		},
+		{
+			name: "valid gpg-agent rule",
+			cfg: Config{Serve: ServeConfig{
+				Upstream:   BusConfig{Type: "session_bus"},
+				Downstream: []BusConfig{{Type: "sockets", Path: "/run/socks"}},
+				Rules: []TrustRule{{
+					Name:         "pass",
+					RequestTypes: []string{"pgp_decrypt"},
+					Process:      &ProcessMatcher{Exe: "/usr/bin/gopass"},
+					Secret:       &SecretMatcher{Attributes: map[string]string{"keygrip": "3BA5AB3CFA7E23E1740E326AEE26A4E329E0DCDC"}},
+				}},
+			}},
+		},
+		{
+			name: "valid ssh_sign rule",
+			cfg: Config{Serve: ServeConfig{
+				Upstream:   BusConfig{Type: "session_bus"},
+				Downstream: []BusConfig{{Type: "sockets", Path: "/run/socks"}},
+				Rules: []TrustRule{{
+					Name:         "git-github",
+					RequestTypes: []string{"ssh_sign"},
+					Process:      &ProcessMatcher{Exe: "/usr/bin/git"},
+					SSH:          &SSHMatcher{Fingerprint: "SHA256:abc", Comment: "*@laptop", Destination: "github.com"},
+				}},
+			}},
+		},
+		{
+			name: "invalid ssh fingerprint",
+			cfg: Config{Serve: ServeConfig{
+				Upstream:   BusConfig{Type: "session_bus"},
+				Downstream: []BusConfig{{Type: "sockets", Path: "/run/socks"}},
+				Rules: []TrustRule{{
+					Name: "bad-fp",
+					SSH:  &SSHMatcher{Fingerprint: "ab:cd:ef"},
+				}},
+			}},
+			wantErr: "ssh.fingerprint must be a SHA256 fingerprint",
+		},
+		{
+			name: "valid signing rule",
+			cfg: Config{Serve: ServeConfig{
+				Upstream:   BusConfig{Type: "session_bus"},
+				Downstream: []BusConfig{{Type: "sockets", Path: "/run/socks"}},
+				Rules: []TrustRule{{
+					Name:         "notes-docs",
+					RequestTypes: []string{"gpg_sign"},
+					Signing:      &SigningMatcher{Repo: "notes", Kind: "commit", Author: "* <me@example.com>", Files: []string{"docs/**"}},
+				}},
+			}},
+		},
+		{
+			name: "invalid signing kind",
+			cfg: Config{Serve: ServeConfig{
+				Upstream:   BusConfig{Type: "session_bus"},
+				Downstream: []BusConfig{{Type: "sockets", Path: "/run/socks"}},
+				Rules: []TrustRule{{
+					Name:    "bad-kind",
+					Signing: &SigningMatcher{Kind: "blob"},
+				}},
+			}},
+			wantErr: "signing.kind must be",
+		},
+		{
+			name: "signing rule without gpg_sign request type",
+			cfg: Config{Serve: ServeConfig{
+				Upstream:   BusConfig{Type: "session_bus"},
+				Downstream: []BusConfig{{Type: "sockets", Path: "/run/socks"}},
+				Rules: []TrustRule{{
+					Name:         "dead",
+					RequestTypes: []string{"get_secret"},
+					Signing:      &SigningMatcher{Repo: "notes"},
+				}},
+			}},
+			wantErr: "signing requires request_types to include",
+		},
+		{
+			name: "signing combined with secret",
+			cfg: Config{Serve: ServeConfig{
+				Upstream:   BusConfig{Type: "session_bus"},
+				Downstream: []BusConfig{{Type: "sockets", Path: "/run/socks"}},
+				Rules: []TrustRule{{
+					Name:    "mixed",
+					Secret:  &SecretMatcher{Collection: "ci"},
+					Signing: &SigningMatcher{Repo: "notes"},
+				}},
+			}},
+			wantErr: "signing cannot be combined",
+		},
+		{
+			name: "invalid signing files glob",
+			cfg: Config{Serve: ServeConfig{
+				Upstream:   BusConfig{Type: "session_bus"},
+				Downstream: []BusConfig{{Type: "sockets", Path: "/run/socks"}},
+				Rules: []TrustRule{{
+					Name:    "bad-files",
+					Signing: &SigningMatcher{Files: []string{"["}},
+				}},
+			}},
+			wantErr: "invalid glob in signing.files",
+		},
+		{
+			name: "invalid ssh destination glob",
+			cfg: Config{Serve: ServeConfig{
+				Upstream:   BusConfig{Type: "session_bus"},
+				Downstream: []BusConfig{{Type: "sockets", Path: "/run/socks"}},
+				Rules: []TrustRule{{
+					Name: "bad-dest",
+					SSH:  &SSHMatcher{Destination: "["},
+				}},
+			}},
+			wantErr: "invalid glob in ssh.destination",
+		},
+		{
+			name: "invalid request type",
+			cfg: Config{Serve: ServeConfig{
+				Upstream:   BusConfig{Type: "session_bus"},
+				Downstream: []BusConfig{{Type: "sockets", Path: "/run/socks"}},
+				Rules: []TrustRule{{
+					Name:         "bad-type",
+					RequestTypes: []string{"ssh_nuke"},
+				}},
+			}},
+			wantErr: `invalid request_type "ssh_nuke"`,
+		},
 		{
 			name: "invalid args glob",
 			cfg: Config{Serve: ServeConfig{
@@ -339,6 +598,57 @@ func TestValidate(t *testing.T) {
 			}},
 			wantErr: "invalid glob in process.cwd",
 		},
+		{
+			name: "invalid client glob",
+			cfg: Config{Serve: ServeConfig{
+				Upstream:   BusConfig{Type: "session_bus"},
+				Downstream: []BusConfig{{Type: "sockets", Path: "/run/socks"}},
+				Rules: []TrustRule{{
+					Name:   "bad-client",
+					Action: "approve",
+					Client: "[",
+				}},
+			}},
+			wantErr: "invalid glob in client",
+		},
+		{
+			name: "valid client policy",
+			cfg: Config{Serve: ServeConfig{
+				Upstream:       BusConfig{Type: "session_bus"},
+				Downstream:     []BusConfig{{Type: "sockets", Path: "/run/socks"}},
+				ClientPolicies: []ClientPolicy{{Client: "build-*", Default: "deny"}, {Client: "*"}},
+			}},
+		},
+		{
+			name: "client policy missing client",
+			cfg: Config{Serve: ServeConfig{
+				Upstream:       BusConfig{Type: "session_bus"},
+				Downstream:     []BusConfig{{Type: "sockets", Path: "/run/socks"}},
+				ClientPolicies: []ClientPolicy{{Default: "deny"}},
+			}},
+			wantErr: "client_policies[0]: client is required",
+		},
+		{
+			name: "invalid exe_sha256",
+			cfg: Config{Serve: ServeConfig{
+				Upstream:   BusConfig{Type: "session_bus"},
+				Downstream: []BusConfig{{Type: "sockets", Path: "/run/socks"}},
+				Rules: []TrustRule{{
+					Name:    "bad-hash",
+					Process: &ProcessMatcher{Exe: "/usr/bin/git", ExeSHA256: "deadbeef"},
+				}},
+			}},
+			wantErr: "process.exe_sha256 must be 64 hex digits",
+		},
+		{
+			name: "invalid client policy default",
+			cfg: Config{Serve: ServeConfig{
+				Upstream:       BusConfig{Type: "session_bus"},
+				Downstream:     []BusConfig{{Type: "sockets", Path: "/run/socks"}},
+				ClientPolicies: []ClientPolicy{{Client: "build-*", Default: "approve"}},
+			}},
+			wantErr: `default must be "prompt" or "deny"`,
+		},
 	}
 
 	for _, tc := range tests {
@@ -359,3 +669,56 @@ func TestValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestWarnings_UserWritableExe(t *testing.T) {
+	dir := t.TempDir()
+	hash := strings.Repeat("ab", 32)
+	s := ServeConfig{Rules: []TrustRule{
+		{Name: "user-built", Process: &ProcessMatcher{Exe: dir + "/bin/tool"}},
+		{Name: "user-glob", Action: "approve", Process: &ProcessMatcher{Exe: dir + "/bin/*"}},
+		{Name: "any-exe", Process: &ProcessMatcher{Exe: "*"}},
+		{Name: "pinned", Process: &ProcessMatcher{Exe: dir + "/bin/tool", ExeSHA256: hash}},
+		{Name: "packaged", Process: &ProcessMatcher{Exe: dir + "/bin/tool", ExePackaged: true}},
+		{Name: "deny", Action: "deny", Process: &ProcessMatcher{Exe: dir + "/bin/tool"}},
+		{Name: "root-only", Process: &ProcessMatcher{Exe: "/"}},
+		{Name: "no-exe", Process: &ProcessMatcher{Name: "tool"}},
+	}}
+
+	warnings := s.Warnings()
+	want := []string{"rules[0]", "rules[1]", "rules[2]"}
+	if len(warnings) != len(want) {
+		t.Fatalf("Warnings() = %q, want %d warnings", warnings, len(want))
+	}
+	for i, w := range warnings {
+		if !strings.HasPrefix(w, want[i]+":") || !strings.Contains(w, "exe_sha256") {
+			t.Errorf("warning %d = %q, want one for %s suggesting exe_sha256", i, w, want[i])
+		}
+	}
+}
+
+func TestWarnings_ContainerAlone(t *testing.T) {
+	node := &ContainerMatcher{Runtime: "podman", Image: "docker.io/library/node:*"}
+	s := ServeConfig{Rules: []TrustRule{
+		{Name: "node", Process: &ProcessMatcher{Container: node}},
+		{Name: "node-npm", Process: &ProcessMatcher{Container: node, Exe: "/usr/local/bin/node", ExePackaged: true}},
+		{Name: "deny-node", Action: "deny", Process: &ProcessMatcher{Container: node}},
+	}}
+
+	warnings := s.Warnings()
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0], "rules[0]:") || !strings.Contains(warnings[0], "container") {
+		t.Errorf("Warnings() = %q, want one for rules[0] about container", warnings)
+	}
+}
+
+func TestWarnings_AppIDAlone(t *testing.T) {
+	s := ServeConfig{Rules: []TrustRule{
+		{Name: "firefox", Process: &ProcessMatcher{AppID: "org.mozilla.firefox"}},
+		{Name: "firefox-exe", Process: &ProcessMatcher{AppID: "org.mozilla.firefox", Exe: "/app/lib/firefox/firefox", ExePackaged: true}},
+		{Name: "deny-app", Action: "deny", Process: &ProcessMatcher{AppID: "com.example.*"}},
+	}}
+
+	warnings := s.Warnings()
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0], "rules[0]:") || !strings.Contains(warnings[0], "app_id") {
+		t.Errorf("Warnings() = %q, want one for rules[0] about app_id", warnings)
+	}
+}
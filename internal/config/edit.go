@@ -0,0 +1,131 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AppendRule adds rule to the end of serve.rules in the config file at path,
+// creating the file, the serve section or the rules list as needed.
+//
+// The file is edited as a YAML node tree rather than re-marshalled from
+// Config, so comments, key order and commented-out examples survive. The
+// result is validated before it replaces the original (atomically, via a
+// temp file in the same directory); an invalid result leaves the file as is.
+func AppendRule(path string, rule TrustRule) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("config %s: top level is not a mapping", path)
+	}
+
+	serve := mappingValue(root, "serve", yaml.MappingNode)
+	if serve.Kind != yaml.MappingNode {
+		return fmt.Errorf("config %s: serve is not a mapping", path)
+	}
+	rules := mappingValue(serve, "rules", yaml.SequenceNode)
+	if rules.Kind != yaml.SequenceNode {
+		return fmt.Errorf("config %s: serve.rules is not a list", path)
+	}
+	// `rules: []` is a flow sequence; appending a mapping to it would render
+	// the whole list inline, so switch it to block style.
+	rules.Style = 0
+
+	var ruleNode yaml.Node
+	if err := ruleNode.Encode(rule); err != nil {
+		return fmt.Errorf("encode rule: %w", err)
+	}
+	flowScalarLists(&ruleNode)
+	rules.Content = append(rules.Content, &ruleNode)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("encode config: %w", err)
+	}
+	enc.Close()
+
+	cfg, err := parse(buf.Bytes(), path)
+	if err != nil {
+		return err
+	}
+	if err := cfg.WithDefaults().Validate(); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, buf.Bytes())
+}
+
+// mappingValue returns the value node for key in m, appending an empty node of
+// the given kind if the key is absent or null.
+func mappingValue(m *yaml.Node, key string, kind yaml.Kind) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value != key {
+			continue
+		}
+		v := m.Content[i+1]
+		if v.Kind == yaml.ScalarNode && v.Tag == "!!null" {
+			*v = yaml.Node{Kind: kind, HeadComment: v.HeadComment, LineComment: v.LineComment, FootComment: v.FootComment}
+		}
+		return v
+	}
+	v := &yaml.Node{Kind: kind}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, v)
+	return v
+}
+
+// flowScalarLists renders lists of scalars inline (request_types: [get_secret]),
+// matching how rules are written by hand.
+func flowScalarLists(n *yaml.Node) {
+	if n.Kind == yaml.SequenceNode && !slices.ContainsFunc(n.Content, func(c *yaml.Node) bool { return c.Kind != yaml.ScalarNode }) {
+		n.Style = yaml.FlowStyle
+		return
+	}
+	for _, c := range n.Content {
+		flowScalarLists(c)
+	}
+}
+
+// writeFileAtomic replaces path with data, keeping the existing file mode.
+func writeFileAtomic(path string, data []byte) error {
+	mode := os.FileMode(0o644)
+	if st, err := os.Stat(path); err == nil {
+		mode = st.Mode().Perm()
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // no-op after a successful rename
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAppendRule(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte(`# my config
+serve:
+  log_level: debug # keep this comment
+  rules: []
+    # - name: example
+    #   action: deny
+`), 0o600)
+
+	rule := TrustRule{
+		Name:         "gh",
+		Action:       "approve",
+		RequestTypes: []string{"get_secret"},
+		Process:      &ProcessMatcher{Exe: "/usr/bin/gh"},
+		Secret:       &SecretMatcher{Collection: "login"},
+	}
+	if err := AppendRule(path, rule); err != nil {
+		t.Fatalf("AppendRule: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	out := string(data)
+	for _, want := range []string{"# my config", "# keep this comment", "#   action: deny", "request_types: [get_secret]"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	st, _ := os.Stat(path)
+	if st.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %o, want 600", st.Mode().Perm())
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Serve.LogLevel != "debug" {
+		t.Errorf("LogLevel = %q, want debug", cfg.Serve.LogLevel)
+	}
+	if len(cfg.Serve.Rules) != 1 || cfg.Serve.Rules[0].Process.Exe != "/usr/bin/gh" {
+		t.Fatalf("Rules = %+v, want the appended rule", cfg.Serve.Rules)
+	}
+
+	// A second rule goes after the first.
+	if err := AppendRule(path, TrustRule{Name: "second", Process: &ProcessMatcher{Exe: "/usr/bin/x"}}); err != nil {
+		t.Fatalf("AppendRule: %v", err)
+	}
+	cfg, _ = Load(path)
+	if len(cfg.Serve.Rules) != 2 || cfg.Serve.Rules[1].Name != "second" {
+		t.Errorf("Rules = %+v, want [gh second]", cfg.Serve.Rules)
+	}
+}
+
+func TestAppendRule_CreatesFile(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	path := filepath.Join(t.TempDir(), "sub", "config.yaml")
+	if err := AppendRule(path, TrustRule{Name: "firefox", Process: &ProcessMatcher{Exe: "/usr/lib/firefox/firefox"}}); err != nil {
+		t.Fatalf("AppendRule: %v", err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(cfg.Serve.Rules) != 1 || cfg.Serve.Rules[0].Name != "firefox" {
+		t.Errorf("Rules = %+v, want [firefox]", cfg.Serve.Rules)
+	}
+}
+
+func TestAppendRule_InvalidLeavesFileUntouched(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	orig := []byte("serve:\n  rules: []\n")
+	os.WriteFile(path, orig, 0o644)
+
+	err := AppendRule(path, TrustRule{Action: "bogus", Process: &ProcessMatcher{Exe: "/usr/bin/gh"}})
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != string(orig) {
+		t.Errorf("file changed after rejected append:\n%s", data)
+	}
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultWatchDebounce coalesces the burst of events an editor produces when
+// saving (truncate+write, or write-temp+rename) into a single reload.
+const DefaultWatchDebounce = 200 * time.Millisecond
+
+// Watch calls onChange whenever the file at path is written, created or
+// replaced, until ctx is cancelled. The parent directory is watched rather than
+// the file itself so that editors which save by renaming a temp file over the
+// original (vim, most IDEs) keep triggering events, and so that a config file
+// created after serve started is picked up too. Events within debounce of each
+// other are delivered as one call.
+func Watch(ctx context.Context, path string, debounce time.Duration, onChange func()) error {
+	path = filepath.Clean(path)
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("create config watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("watch config dir: %w", err)
+	}
+
+	var timer *time.Timer
+	var fire <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return ctx.Err()
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(event.Name) != path {
+				continue
+			}
+			if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) && !event.Has(fsnotify.Rename) {
+				continue
+			}
+			if timer == nil {
+				timer = time.NewTimer(debounce)
+			} else {
+				timer.Reset(debounce)
+			}
+			fire = timer.C
+
+		case <-fire:
+			fire = nil
+			onChange()
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			slog.Error("config watcher error", "error", err)
+		}
+	}
+}
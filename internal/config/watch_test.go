@@ -0,0 +1,62 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	os.WriteFile(path, []byte("listen: 127.0.0.1:1\n"), 0o644)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changed := make(chan struct{}, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- Watch(ctx, path, 20*time.Millisecond, func() { changed <- struct{}{} })
+	}()
+	// Give the watcher time to register before generating events.
+	time.Sleep(50 * time.Millisecond)
+
+	// Unrelated files in the same directory are ignored.
+	os.WriteFile(filepath.Join(dir, "other.yaml"), []byte("x"), 0o644)
+
+	// Save via temp file + rename, as most editors do.
+	tmp := filepath.Join(dir, ".config.yaml.swp")
+	os.WriteFile(tmp, []byte("listen: 127.0.0.1:2\n"), 0o644)
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-changed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no change notification after rename")
+	}
+
+	// An in-place write burst is debounced into one call.
+	for range 3 {
+		os.WriteFile(path, []byte("listen: 127.0.0.1:3\n"), 0o644)
+	}
+	select {
+	case <-changed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no change notification after write")
+	}
+	select {
+	case <-changed:
+		t.Error("write burst produced more than one notification")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Watch returned %v, want context.Canceled", err)
+	}
+}
@@ -2,6 +2,7 @@
 package dbus
 
 import (
+	"errors"
 	"strings"
 
 	"github.com/godbus/dbus/v5"
@@ -40,6 +41,8 @@ const (
 	ErrAlreadyExists    = "org.freedesktop.Secret.Error.AlreadyExists"
 	ErrNotSupported     = "org.freedesktop.DBus.Error.NotSupported"
 	ErrInvalidSignature = "org.freedesktop.DBus.Error.InvalidSignature"
+	ErrServiceUnknown   = "org.freedesktop.DBus.Error.ServiceUnknown"
+	ErrUnknownObject    = "org.freedesktop.DBus.Error.UnknownObject"
 )
 
 // NewDBusError creates a D-Bus error with the given name and message.
@@ -65,6 +68,12 @@ func ErrObjectNotFound(path string) *dbus.Error {
 	return NewDBusError(ErrNoSuchObject, "Object "+path+" does not exist")
 }
 
+// ErrObjectUnknown returns the UnknownObject error a bus peer answers for a
+// path it does not export.
+func ErrObjectUnknown(path string) *dbus.Error {
+	return NewDBusError(ErrUnknownObject, "No such object path '"+path+"'")
+}
+
 // ErrUnsupportedAlgorithm returns a NotSupported error for unknown algorithms.
 func ErrUnsupportedAlgorithm(algo string) *dbus.Error {
 	return NewDBusError(ErrNotSupported, "Algorithm "+algo+" is not supported")
@@ -76,10 +85,23 @@ func ErrAccessDenied(message string) *dbus.Error {
 }
 
 // ErrFailed returns a generic Failed error wrapping the underlying error.
+// An unavailable upstream (see ErrUpstreamUnavailable) is reported as such.
 func ErrFailed(err error) *dbus.Error {
+	if derr, ok := errors.AsType[dbus.Error](err); ok && derr.Name == ErrServiceUnknown {
+		return &derr
+	}
+	if derr, ok := errors.AsType[*dbus.Error](err); ok && derr.Name == ErrServiceUnknown {
+		return derr
+	}
 	return NewDBusError("org.freedesktop.DBus.Error.Failed", err.Error())
 }
 
+// ErrUpstreamUnavailable returns the error for calls made while the connection
+// to the named upstream Secret Service is lost.
+func ErrUpstreamUnavailable(backend string) *dbus.Error {
+	return NewDBusError(ErrServiceUnknown, "Upstream Secret Service "+backend+" is unavailable, reconnecting")
+}
+
 // ExtractCollection extracts the collection name from a Secret Service item path.
 // Handles both /org/freedesktop/secrets/collection/X/... and /org/freedesktop/secrets/aliases/X/...
 // Returns "" if the path doesn't match either format.
@@ -0,0 +1,122 @@
+package gpgagent
+
+import (
+	"bufio"
+	"bytes"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/nikicat/secrets-dispatcher/internal/gpgsign"
+)
+
+// keyInfo is the OpenPGP key a keygrip belongs to.
+type keyInfo struct {
+	KeyID  string // long key ID of the (sub)key with that keygrip
+	UserID string // primary user ID of its certificate
+}
+
+// keyring maps keygrips to keys using the daemon's own public keyring. It is
+// the daemon's view, not the client's: a client cannot name its key to the
+// prompt, only the keygrip the agent will use.
+type keyring struct {
+	mu   sync.Mutex
+	keys map[string]keyInfo
+	list func() (map[string]keyInfo, error)
+}
+
+func newKeyring() *keyring {
+	return &keyring{list: listGPGKeys}
+}
+
+// lookup returns the key for keygrip, listing the keyring again when it is
+// not known yet, e.g. because it was imported since.
+func (k *keyring) lookup(keygrip string) (keyInfo, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if key, ok := k.keys[keygrip]; ok {
+		return key, true
+	}
+	keys, err := k.list()
+	if err != nil {
+		return keyInfo{}, false
+	}
+	k.keys = keys
+	key, ok := k.keys[keygrip]
+	return key, ok
+}
+
+// listGPGKeys runs `gpg --list-keys --with-keygrip --with-colons`.
+func listGPGKeys() (map[string]keyInfo, error) {
+	path, err := gpgsign.FindRealGPG()
+	if err != nil {
+		return nil, err
+	}
+	out, err := exec.Command(path, "--batch", "--with-colons", "--with-keygrip", "--list-keys").Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseColons(out), nil
+}
+
+// parseColons reads gpg's --with-colons key listing. Each certificate is a
+// pub record followed by fpr and grp records for the primary key, its uid
+// records, then sub/fpr/grp for each subkey.
+func parseColons(out []byte) map[string]keyInfo {
+	keys := map[string]keyInfo{}
+	var userID, fpr string
+	var grips, keyIDs []string
+	flush := func() {
+		for i, grip := range grips {
+			keys[grip] = keyInfo{KeyID: keyIDs[i], UserID: userID}
+		}
+		userID, fpr, grips, keyIDs = "", "", nil, nil
+	}
+
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		fields := strings.Split(sc.Text(), ":")
+		if len(fields) < 10 {
+			continue
+		}
+		switch fields[0] {
+		case "pub":
+			flush()
+		case "fpr":
+			fpr = fields[9]
+		case "grp":
+			keyID := fpr
+			if len(keyID) > 16 {
+				keyID = keyID[len(keyID)-16:]
+			}
+			grips = append(grips, fields[9])
+			keyIDs = append(keyIDs, keyID)
+		case "uid":
+			if userID == "" {
+				userID = unescapeColons(fields[9])
+			}
+		}
+	}
+	flush()
+	return keys
+}
+
+// unescapeColons decodes the \xNN escapes gpg uses in --with-colons fields.
+func unescapeColons(s string) string {
+	if !strings.Contains(s, `\x`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) && s[i+1] == 'x' {
+			if n, err := strconv.ParseUint(s[i+2:i+4], 16, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
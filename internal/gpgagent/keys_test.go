@@ -0,0 +1,61 @@
+package gpgagent
+
+import (
+	"errors"
+	"testing"
+)
+
+const testColons = `tru:o:1:1792139745:1:3:1:5
+pub:u:3072:1:AEE5848E72F373A0:1792139743:::u:::scESC::::::23::0:
+fpr:::::::::B50780BB2C690FB54F9413A1AEE5848E72F373A0:
+grp:::::::::EE940BD183F46180B96EB8908B43BB31B71EA749:
+uid:u::::1792139743::E275056101A1B2246FBFBE8E2C85F1925D919036::Alice Example <alice@example.com>::::::::::0:
+uid:u::::1792139743::0000000000000000000000000000000000000000::Alice (work\x3a ops) <alice@work.example>::::::::::0:
+sub:u:3072:1:9C7650EE1D6C93E8:1792139743::::::e::::::23:
+fpr:::::::::0ED121F8B57313484CDDCA4F9C7650EE1D6C93E8:
+grp:::::::::3BA5AB3CFA7E23E1740E326AEE26A4E329E0DCDC:
+pub:u:255:22:1111222233334444:1792139743:::u:::scESC::::::ed25519::0:
+fpr:::::::::AAAABBBBCCCCDDDDEEEEFFFF1111222233334444:
+grp:::::::::0000000000000000000000000000000000000001:
+uid:u::::1792139743::1111111111111111111111111111111111111111::Bob \x3cbob\x3e::::::::::0:
+`
+
+func TestParseColons(t *testing.T) {
+	keys := parseColons([]byte(testColons))
+	want := map[string]keyInfo{
+		"EE940BD183F46180B96EB8908B43BB31B71EA749": {KeyID: "AEE5848E72F373A0", UserID: "Alice Example <alice@example.com>"},
+		"3BA5AB3CFA7E23E1740E326AEE26A4E329E0DCDC": {KeyID: "9C7650EE1D6C93E8", UserID: "Alice Example <alice@example.com>"},
+		"0000000000000000000000000000000000000001": {KeyID: "1111222233334444", UserID: "Bob <bob>"},
+	}
+	if len(keys) != len(want) {
+		t.Fatalf("got %d keys, want %d: %+v", len(keys), len(want), keys)
+	}
+	for grip, w := range want {
+		if keys[grip] != w {
+			t.Errorf("key %s = %+v, want %+v", grip, keys[grip], w)
+		}
+	}
+}
+
+func TestKeyring_RelistsUnknownKeys(t *testing.T) {
+	lists := 0
+	k := &keyring{list: func() (map[string]keyInfo, error) {
+		lists++
+		if lists == 1 {
+			return map[string]keyInfo{"A": {KeyID: "1"}}, nil
+		}
+		return nil, errors.New("gpg failed")
+	}}
+	if key, ok := k.lookup("A"); !ok || key.KeyID != "1" {
+		t.Errorf("lookup(A) = %+v, %v", key, ok)
+	}
+	if _, ok := k.lookup("A"); !ok || lists != 1 {
+		t.Errorf("known key listed again (%d lists)", lists)
+	}
+	if _, ok := k.lookup("B"); ok || lists != 2 {
+		t.Errorf("unknown key: ok=%v after %d lists, want a failed relist", ok, lists)
+	}
+	if _, ok := k.lookup("A"); !ok {
+		t.Error("failed relist dropped the known keys")
+	}
+}
@@ -0,0 +1,322 @@
+// Package gpgagent implements a gpg-agent proxy that gates private key
+// operations (decryption, signing, secret key export) through the approval
+// flow and passes the rest of the Assuan protocol through.
+package gpgagent
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/nikicat/secrets-dispatcher/internal/approval"
+)
+
+// maxLineLen bounds an Assuan line. The protocol limits lines to 1000 bytes;
+// anything much longer is not a client we understand.
+const maxLineLen = 4096
+
+// errCanceled is the reply to a denied operation: GPG_ERR_CANCELED from the
+// gpg-agent error source, the code the agent itself returns when the user
+// cancels its pinentry, so gpg reports "Operation cancelled".
+const errCanceled = "ERR 67108963 Operation cancelled <secrets-dispatcher>\n"
+
+// errInvalidKeygrip is the reply to a SETKEY, SIGKEY or EXPORT_KEY that does
+// not name exactly one keygrip: GPG_ERR_ASS_PARAMETER, as the agent answers
+// a malformed one.
+const errInvalidKeygrip = "ERR 67109144 Invalid keygrip <secrets-dispatcher>\n"
+
+// keyLookup returns the OpenPGP key a keygrip belongs to, if known.
+type keyLookup func(keygrip string) (keyInfo, bool)
+
+// session relays one client connection to the upstream agent. Assuan is
+// strictly request/response: the client sends a command line, the agent
+// answers with status (S), data (D) and comment (#) lines ending in OK or
+// ERR, and may interrupt with INQUIRE, to which the client sends D lines and
+// END (or CAN). The session follows that exchange line by line and holds back
+// the commands that use a private key until they are approved.
+type session struct {
+	client     io.Writer
+	clientIn   *bufio.Reader
+	upstream   io.Writer
+	upstreamIn *bufio.Reader
+	approval   *approval.Manager
+	senderInfo approval.SenderInfo
+	lookup     keyLookup
+	logger     *slog.Logger
+
+	// keygrip is the key set by the last SETKEY/SIGKEY the agent accepted,
+	// which PKDECRYPT and PKSIGN use. The agent clears it on RESET.
+	keygrip string
+}
+
+func newSession(client, upstream io.ReadWriter, approvalMgr *approval.Manager, senderInfo approval.SenderInfo, lookup keyLookup, logger *slog.Logger) *session {
+	return &session{
+		client:     client,
+		clientIn:   bufio.NewReaderSize(client, maxLineLen),
+		upstream:   upstream,
+		upstreamIn: bufio.NewReaderSize(upstream, maxLineLen),
+		approval:   approvalMgr,
+		senderInfo: senderInfo,
+		lookup:     lookup,
+		logger:     logger,
+	}
+}
+
+// serve relays the agent's greeting and then the client's commands until
+// either side closes the connection or the client says BYE.
+func (s *session) serve(ctx context.Context) error {
+	if _, err := s.relayResponse(); err != nil {
+		return fmt.Errorf("greeting: %w", err)
+	}
+	for {
+		line, err := readLine(s.clientIn)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		// Comments and empty lines get no response.
+		if trimmed := strings.TrimRight(line, "\r\n"); trimmed == "" || trimmed[0] == '#' {
+			if _, err := io.WriteString(s.upstream, line); err != nil {
+				return err
+			}
+			continue
+		}
+
+		cmd, args := parseCommand(line)
+		var grip string
+		switch cmd {
+		case "SETKEY", "SIGKEY", "EXPORT_KEY":
+			// The agent reads the first word and ignores the rest, so only a
+			// lone keygrip is passed on: anything else could show one key in
+			// the approval request and use another.
+			var ok bool
+			if grip, ok = keygripArg(args, cmd == "EXPORT_KEY"); !ok {
+				if _, err := io.WriteString(s.client, errInvalidKeygrip); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if op, reqType, key, gated := classify(cmd, args, s.keygrip); gated {
+			if err := s.requireApproval(ctx, op, reqType, key); err != nil {
+				if _, err := io.WriteString(s.client, errCanceled); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if _, err := io.WriteString(s.upstream, line); err != nil {
+			return err
+		}
+		ok, err := s.relayResponse()
+		if err != nil {
+			return err
+		}
+		switch cmd {
+		case "SETKEY", "SIGKEY":
+			// A key the agent refused is not selected; which one it kept
+			// then is unknown, so none is shown.
+			s.keygrip = ""
+			if ok {
+				s.keygrip = grip
+			}
+		case "RESET":
+			s.keygrip = ""
+		case "BYE":
+			return nil
+		}
+	}
+}
+
+// relayResponse copies the agent's response to the current command to the
+// client, up to and including its final OK or ERR line, answering INQUIREs
+// from the client on the way. ok reports whether the agent answered OK.
+func (s *session) relayResponse() (ok bool, err error) {
+	for {
+		line, err := readLine(s.upstreamIn)
+		if err != nil {
+			return false, fmt.Errorf("upstream: %w", err)
+		}
+		if _, err := io.WriteString(s.client, line); err != nil {
+			return false, err
+		}
+		switch word, _ := parseCommand(line); word {
+		case "OK", "ERR":
+			return word == "OK", nil
+		case "INQUIRE":
+			if err := s.relayInquiry(); err != nil {
+				return false, err
+			}
+		}
+	}
+}
+
+// relayInquiry copies the client's answer to an INQUIRE to the agent: D
+// lines up to END, or CAN.
+func (s *session) relayInquiry() error {
+	for {
+		line, err := readLine(s.clientIn)
+		if err != nil {
+			return fmt.Errorf("inquiry: %w", err)
+		}
+		if _, err := io.WriteString(s.upstream, line); err != nil {
+			return err
+		}
+		switch word, _ := parseCommand(line); word {
+		case "END", "CAN":
+			return nil
+		}
+	}
+}
+
+// classify reports whether a command uses a private key and so needs
+// approval, and with which request type and key. current is the key set by
+// SETKEY/SIGKEY. scdaemon's own signing and decryption, reachable through
+// the agent's SCD command, are gated like the agent's; the key is then a card
+// key reference such as OPENPGP.2.
+func classify(cmd, args, current string) (op string, reqType approval.RequestType, key string, gated bool) {
+	switch cmd {
+	case "PKDECRYPT":
+		return "decrypt", approval.RequestTypePGPDecrypt, current, true
+	case "PKSIGN":
+		return "sign", approval.RequestTypePGPSign, current, true
+	case "EXPORT_KEY":
+		// EXPORT_KEY [--openpgp] [--cache-nonce=N] <keygrip>
+		key, _ := keygripArg(args, true)
+		return "export", approval.RequestTypePGPExport, key, true
+	case "SCD":
+		// SCD PKSIGN [--hash=ALGO] <key reference>
+		sub, subArgs := parseCommand(args)
+		switch sub {
+		case "PKDECRYPT":
+			return "card decrypt", approval.RequestTypePGPDecrypt, firstArg(subArgs, true), true
+		case "PKSIGN", "PKAUTH":
+			return "card sign", approval.RequestTypePGPSign, firstArg(subArgs, true), true
+		}
+	}
+	return "", "", "", false
+}
+
+// requireApproval runs a private key operation through the approval flow.
+func (s *session) requireApproval(ctx context.Context, op string, reqType approval.RequestType, key string) error {
+	item := s.keyItem(op, key)
+	s.logger.Info("gpg-agent request received", "op", op, "item", item.Label, "invoker", s.senderInfo.InvokerName)
+
+	if _, err := s.approval.RequireApproval(
+		ctx,
+		"gpg-agent",
+		[]approval.ItemInfo{item},
+		"",
+		reqType,
+		nil,
+		s.senderInfo,
+	); err != nil {
+		s.logger.Info("gpg-agent request denied", "op", op, "error", err)
+		return fmt.Errorf("%s request denied: %w", op, err)
+	}
+
+	s.logger.Info("gpg-agent request approved", "op", op)
+	return nil
+}
+
+// keyItem describes a key for an approval request: the path is its keygrip,
+// the label the user ID of the OpenPGP key it belongs to (or the keygrip if
+// the daemon's keyring does not have it). A card key reference is shown as is.
+func (s *session) keyItem(op, key string) approval.ItemInfo {
+	attrs := map[string]string{"operation": op}
+	switch {
+	case key == "":
+		return approval.ItemInfo{Path: op, Label: "No key selected", Attributes: attrs}
+	case !isKeygrip(key):
+		attrs["card_key"] = key
+		return approval.ItemInfo{Path: key, Label: key, Attributes: attrs}
+	}
+
+	attrs["keygrip"] = key
+	label := key
+	if info, ok := s.lookup(key); ok {
+		if info.UserID != "" {
+			attrs["user_id"] = info.UserID
+			label = info.UserID
+		}
+		attrs["key_id"] = info.KeyID
+	}
+	return approval.ItemInfo{
+		Path:       key,
+		Label:      label,
+		Attributes: attrs,
+	}
+}
+
+// isKeygrip reports whether s is a keygrip: 40 upper-case hex digits.
+func isKeygrip(s string) bool {
+	if len(s) != 40 {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// readLine reads one Assuan line, newline included.
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		return "", fmt.Errorf("line longer than %d bytes", maxLineLen)
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(line), nil
+}
+
+// firstArg returns the first word of a command's arguments, after any
+// "--" options if the command takes them: the word the agent and scdaemon
+// read the key from.
+func firstArg(args string, options bool) string {
+	fields := argFields(args, options)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// keygripArg returns the keygrip a command names, upper-cased. ok is false
+// unless the arguments, after any "--" options if the command takes them,
+// are that keygrip alone: 40 hex digits in either case.
+func keygripArg(args string, options bool) (grip string, ok bool) {
+	fields := argFields(args, options)
+	if len(fields) != 1 {
+		return "", false
+	}
+	grip = strings.ToUpper(fields[0])
+	return grip, isKeygrip(grip)
+}
+
+// argFields splits a command's arguments into words, dropping the leading
+// "--" options if the command takes them.
+func argFields(args string, options bool) []string {
+	fields := strings.Fields(args)
+	for options && len(fields) > 0 && strings.HasPrefix(fields[0], "--") {
+		fields = fields[1:]
+	}
+	return fields
+}
+
+// parseCommand splits a line into its first word, upper-cased as Assuan
+// commands are case-insensitive, and the rest.
+func parseCommand(line string) (word, args string) {
+	line = strings.TrimRight(line, "\r\n")
+	word, args, _ = strings.Cut(line, " ")
+	return strings.ToUpper(word), strings.TrimLeft(args, " ")
+}
@@ -0,0 +1,307 @@
+package gpgagent
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nikicat/secrets-dispatcher/internal/approval"
+)
+
+const testGrip = "3BA5AB3CFA7E23E1740E326AEE26A4E329E0DCDC"
+
+// refusedGrip is a keygrip the fake agent answers ERR to.
+const refusedGrip = "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF"
+
+// fakeAgent answers like gpg-agent: a greeting, OK to most commands, and for
+// PKDECRYPT an inquiry for the ciphertext followed by the plaintext. Commands
+// naming refusedGrip get ERR. Every command it receives is sent on cmds.
+func fakeAgent(conn net.Conn, cmds chan<- string) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	io.WriteString(conn, "OK Pleased to meet you\n") //nolint:errcheck
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\n")
+		cmds <- line
+		switch {
+		case strings.HasPrefix(line, "#"):
+			// comments get no reply
+		case strings.HasPrefix(line, "PKDECRYPT"):
+			io.WriteString(conn, "INQUIRE CIPHERTEXT\n") //nolint:errcheck
+			for {
+				d, err := r.ReadString('\n')
+				if err != nil || d == "END\n" {
+					break
+				}
+			}
+			io.WriteString(conn, "S PADDING 0\nD plaintext\nOK\n") //nolint:errcheck
+		case strings.HasSuffix(line, refusedGrip):
+			io.WriteString(conn, "ERR 67108881 No secret key\n") //nolint:errcheck
+		case strings.HasPrefix(line, "GETINFO version"):
+			io.WriteString(conn, "D 2.2.40\nOK\n") //nolint:errcheck
+		case line == "BYE":
+			io.WriteString(conn, "OK closing connection\n") //nolint:errcheck
+			return
+		default:
+			io.WriteString(conn, "OK\n") //nolint:errcheck
+		}
+	}
+}
+
+// testClient starts a session between a fake agent and a client pipe, and
+// returns the client end after reading the greeting.
+func testClient(t *testing.T, mgr *approval.Manager) (net.Conn, *bufio.Reader, chan string) {
+	t.Helper()
+	client, proxyClient := net.Pipe()
+	proxyUpstream, agent := net.Pipe()
+	cmds := make(chan string, 16)
+	go fakeAgent(agent, cmds)
+
+	lookup := func(keygrip string) (keyInfo, bool) {
+		if keygrip == testGrip {
+			return keyInfo{KeyID: "9C7650EE1D6C93E8", UserID: "Alice <alice@example.com>"}, true
+		}
+		return keyInfo{}, false
+	}
+	sess := newSession(proxyClient, proxyUpstream, mgr, approval.SenderInfo{InvokerName: "pass"}, lookup, slog.Default())
+	go func() {
+		sess.serve(context.Background()) //nolint:errcheck
+		proxyClient.Close()
+		proxyUpstream.Close()
+	}()
+	t.Cleanup(func() { client.Close() })
+
+	r := bufio.NewReader(client)
+	if greeting := readReply(t, r); greeting != "OK Pleased to meet you\n" {
+		t.Fatalf("greeting = %q", greeting)
+	}
+	return client, r, cmds
+}
+
+// send writes a line from the client.
+func send(t *testing.T, conn net.Conn, line string) {
+	t.Helper()
+	if _, err := io.WriteString(conn, line+"\n"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readReply reads lines up to and including OK, ERR or INQUIRE.
+func readReply(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+	var b strings.Builder
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read reply: %v (so far %q)", err, b.String())
+		}
+		b.WriteString(line)
+		if strings.HasPrefix(line, "OK") || strings.HasPrefix(line, "ERR") || strings.HasPrefix(line, "INQUIRE") {
+			return b.String()
+		}
+	}
+}
+
+// waitForPending polls until the manager has a pending request and returns it.
+func waitForPending(t *testing.T, mgr *approval.Manager) *approval.Request {
+	t.Helper()
+	for range 100 {
+		if pending := mgr.List(); len(pending) > 0 {
+			return pending[0]
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("no pending request appeared")
+	return nil
+}
+
+func TestSession_Passthrough(t *testing.T) {
+	mgr := approval.NewManager(approval.ManagerConfig{Timeout: 5 * time.Second, HistoryMax: 100})
+	client, r, cmds := testClient(t, mgr)
+
+	send(t, client, "# a comment gets no reply")
+	send(t, client, "GETINFO version")
+	if reply := readReply(t, r); reply != "D 2.2.40\nOK\n" {
+		t.Errorf("GETINFO reply = %q", reply)
+	}
+	send(t, client, "HAVEKEY "+testGrip)
+	if reply := readReply(t, r); reply != "OK\n" {
+		t.Errorf("HAVEKEY reply = %q", reply)
+	}
+	for _, want := range []string{"# a comment gets no reply", "GETINFO version", "HAVEKEY " + testGrip} {
+		if got := <-cmds; got != want {
+			t.Errorf("agent received %q, want %q", got, want)
+		}
+	}
+	if len(mgr.List()) != 0 {
+		t.Error("pass-through commands created a request")
+	}
+}
+
+func TestSession_DecryptApproved(t *testing.T) {
+	mgr := approval.NewManager(approval.ManagerConfig{Timeout: 5 * time.Second, HistoryMax: 100})
+	client, r, cmds := testClient(t, mgr)
+
+	send(t, client, "setkey "+strings.ToLower(testGrip))
+	readReply(t, r)
+	<-cmds
+
+	send(t, client, "PKDECRYPT")
+	req := waitForPending(t, mgr)
+	if req.Type != approval.RequestTypePGPDecrypt {
+		t.Errorf("expected type %q, got %q", approval.RequestTypePGPDecrypt, req.Type)
+	}
+	if len(req.Items) != 1 || req.Items[0].Path != testGrip || req.Items[0].Label != "Alice <alice@example.com>" {
+		t.Fatalf("expected the key as the only item, got %+v", req.Items)
+	}
+	if id := req.Items[0].Attributes["key_id"]; id != "9C7650EE1D6C93E8" {
+		t.Errorf("key_id = %q", id)
+	}
+	select {
+	case cmd := <-cmds:
+		t.Fatalf("agent received %q before approval", cmd)
+	default:
+	}
+
+	if err := mgr.Approve(req.ID); err != nil {
+		t.Fatal(err)
+	}
+	if reply := readReply(t, r); reply != "INQUIRE CIPHERTEXT\n" {
+		t.Fatalf("expected the agent's inquiry, got %q", reply)
+	}
+	send(t, client, "D (7:enc-val)")
+	send(t, client, "END")
+	if reply := readReply(t, r); reply != "S PADDING 0\nD plaintext\nOK\n" {
+		t.Errorf("PKDECRYPT reply = %q", reply)
+	}
+	if cmd := <-cmds; cmd != "PKDECRYPT" {
+		t.Errorf("agent received %q, want PKDECRYPT", cmd)
+	}
+}
+
+func TestSession_SignDenied(t *testing.T) {
+	mgr := approval.NewManager(approval.ManagerConfig{Timeout: 5 * time.Second, HistoryMax: 100})
+	client, r, cmds := testClient(t, mgr)
+
+	send(t, client, "SIGKEY 0000000000000000000000000000000000000001")
+	readReply(t, r)
+	<-cmds
+	send(t, client, "PKSIGN")
+	req := waitForPending(t, mgr)
+	if req.Type != approval.RequestTypePGPSign {
+		t.Errorf("expected type %q, got %q", approval.RequestTypePGPSign, req.Type)
+	}
+	if req.Items[0].Label != "0000000000000000000000000000000000000001" {
+		t.Errorf("unknown key should be labelled by its keygrip, got %q", req.Items[0].Label)
+	}
+	if err := mgr.Deny(req.ID); err != nil {
+		t.Fatal(err)
+	}
+	if reply := readReply(t, r); reply != errCanceled {
+		t.Errorf("PKSIGN reply = %q, want %q", reply, errCanceled)
+	}
+
+	// The connection stays usable, and the agent never saw PKSIGN.
+	send(t, client, "BYE")
+	readReply(t, r)
+	if cmd := <-cmds; cmd != "BYE" {
+		t.Errorf("agent received %q after the denial, want BYE", cmd)
+	}
+}
+
+func TestSession_KeygripOnlyAlone(t *testing.T) {
+	mgr := approval.NewManager(approval.ManagerConfig{Timeout: 5 * time.Second, HistoryMax: 100})
+	client, r, cmds := testClient(t, mgr)
+
+	// The agent would sign with the first keygrip; the approval request must
+	// not be shown the second.
+	for _, line := range []string{
+		"SIGKEY 0000000000000000000000000000000000000001 " + testGrip,
+		"SETKEY " + testGrip + "00",
+		"SETKEY --x " + testGrip,
+		"SIGKEY",
+		"EXPORT_KEY --openpgp 0000000000000000000000000000000000000001 " + testGrip,
+		"EXPORT_KEY OPENPGP.1",
+	} {
+		send(t, client, line)
+		if reply := readReply(t, r); reply != errInvalidKeygrip {
+			t.Errorf("%q reply = %q, want %q", line, reply, errInvalidKeygrip)
+		}
+	}
+
+	send(t, client, "SIGKEY "+strings.ToLower(testGrip))
+	if reply := readReply(t, r); reply != "OK\n" {
+		t.Errorf("SIGKEY reply = %q", reply)
+	}
+	if cmd := <-cmds; cmd != "SIGKEY "+strings.ToLower(testGrip) {
+		t.Errorf("agent received %q, want only the valid SIGKEY", cmd)
+	}
+	send(t, client, "PKSIGN")
+	req := waitForPending(t, mgr)
+	if req.Items[0].Path != testGrip {
+		t.Errorf("PKSIGN key = %q, want %q", req.Items[0].Path, testGrip)
+	}
+	if err := mgr.Deny(req.ID); err != nil {
+		t.Fatal(err)
+	}
+	readReply(t, r)
+}
+
+func TestSession_KeygripRefusedByAgent(t *testing.T) {
+	mgr := approval.NewManager(approval.ManagerConfig{Timeout: 5 * time.Second, HistoryMax: 100})
+	client, r, cmds := testClient(t, mgr)
+
+	send(t, client, "SIGKEY "+testGrip)
+	readReply(t, r)
+	<-cmds
+	send(t, client, "SETKEY "+refusedGrip)
+	if reply := readReply(t, r); !strings.HasPrefix(reply, "ERR") {
+		t.Errorf("SETKEY reply = %q, want the agent's ERR", reply)
+	}
+	<-cmds
+
+	send(t, client, "PKSIGN")
+	req := waitForPending(t, mgr)
+	if req.Items[0].Label != "No key selected" {
+		t.Errorf("PKSIGN after a refused SETKEY shows %q, want no key", req.Items[0].Label)
+	}
+	if err := mgr.Deny(req.ID); err != nil {
+		t.Fatal(err)
+	}
+	readReply(t, r)
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		line    string
+		op      string
+		reqType approval.RequestType
+		key     string
+	}{
+		{"PKDECRYPT", "decrypt", approval.RequestTypePGPDecrypt, "CURRENT"},
+		{"pksign --hash=sha256", "sign", approval.RequestTypePGPSign, "CURRENT"},
+		{"EXPORT_KEY --openpgp " + strings.ToLower(testGrip), "export", approval.RequestTypePGPExport, testGrip},
+		{"SCD PKDECRYPT OPENPGP.2", "card decrypt", approval.RequestTypePGPDecrypt, "OPENPGP.2"},
+		{"SCD PKAUTH OPENPGP.3", "card sign", approval.RequestTypePGPSign, "OPENPGP.3"},
+		{"SCD PKSIGN --hash=sha256 OPENPGP.1 OPENPGP.3", "card sign", approval.RequestTypePGPSign, "OPENPGP.1"},
+		{"SCD SERIALNO", "", "", ""},
+		{"KEYINFO --list", "", "", ""},
+		{"GET_PASSPHRASE x", "", "", ""},
+	}
+	for _, tc := range tests {
+		cmd, args := parseCommand(tc.line)
+		op, reqType, key, gated := classify(cmd, args, "CURRENT")
+		if op != tc.op || reqType != tc.reqType || key != tc.key || gated != (tc.op != "") {
+			t.Errorf("classify(%q) = %q, %q, %q, %v; want %q, %q, %q", tc.line, op, reqType, key, gated, tc.op, tc.reqType, tc.key)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package gpgagent
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/nikicat/secrets-dispatcher/internal/approval"
+	"github.com/nikicat/secrets-dispatcher/internal/procutil"
+)
+
+// gnupgTools are the GnuPG programs that talk to the agent on another
+// program's behalf; the invoker shown in the prompt is the program above them.
+var gnupgTools = map[string]bool{"gpg": true, "gpg2": true, "gpgsm": true}
+
+// Server listens on a Unix socket and proxies gpg-agent connections,
+// gating private key operations through the approval manager.
+type Server struct {
+	listenPath       string
+	upstreamPath     string
+	approval         *approval.Manager
+	trimProcessChain bool
+	keys             *keyring
+	logger           *slog.Logger
+}
+
+// NewServer creates a new gpg-agent proxy server.
+func NewServer(listenPath, upstreamPath string, approvalMgr *approval.Manager, trimProcessChain bool, logger *slog.Logger) *Server {
+	return &Server{
+		listenPath:       listenPath,
+		upstreamPath:     upstreamPath,
+		approval:         approvalMgr,
+		trimProcessChain: trimProcessChain,
+		keys:             newKeyring(),
+		logger:           logger,
+	}
+}
+
+// DefaultUpstream returns the socket of the user's gpg-agent, as reported by
+// `gpgconf --list-dirs agent-socket`.
+func DefaultUpstream() (string, error) {
+	out, err := exec.Command("gpgconf", "--list-dirs", "agent-socket").Output()
+	if err != nil {
+		return "", fmt.Errorf("gpgconf --list-dirs agent-socket: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Run starts the proxy server. It blocks until ctx is cancelled.
+func (s *Server) Run(ctx context.Context) error {
+	listener, err := net.Listen("unix", s.listenPath)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", s.listenPath, err)
+	}
+
+	s.logger.Info("gpg-agent proxy listening", "socket", s.listenPath, "upstream", s.upstreamPath)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	// Close listener when context is done to unblock Accept
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			s.logger.Error("accept error", "error", err)
+			continue
+		}
+
+		wg.Go(func() {
+			s.handleConnection(ctx, conn)
+		})
+	}
+}
+
+func (s *Server) handleConnection(ctx context.Context, clientConn net.Conn) {
+	defer clientConn.Close()
+
+	senderInfo := s.extractSenderInfo(clientConn)
+
+	s.logger.Debug("new gpg-agent connection",
+		"pid", senderInfo.PID,
+		"invoker", senderInfo.InvokerName)
+
+	upstreamConn, err := s.dialUpstream()
+	if err != nil {
+		s.logger.Error("failed to connect to upstream gpg-agent", "path", s.upstreamPath, "error", err)
+		return
+	}
+	defer upstreamConn.Close()
+
+	sess := newSession(clientConn, upstreamConn, s.approval, senderInfo, s.keys.lookup, s.logger)
+	if err := sess.serve(ctx); err != nil && ctx.Err() == nil {
+		s.logger.Debug("gpg-agent connection ended", "error", err)
+	}
+}
+
+// dialUpstream connects to the real agent, starting it first if it is not
+// running: gpg would have started it itself, had it not been pointed here.
+func (s *Server) dialUpstream() (net.Conn, error) {
+	conn, err := net.Dial("unix", s.upstreamPath)
+	if err == nil {
+		return conn, nil
+	}
+	if launchErr := exec.Command("gpgconf", "--launch", "gpg-agent").Run(); launchErr != nil {
+		return nil, err
+	}
+	return net.Dial("unix", s.upstreamPath)
+}
+
+// extractSenderInfo builds a SenderInfo from the Unix socket peer credentials.
+// The peer is usually gpg itself; the invoker is the program that ran it
+// (pass, sops, git-crypt), skipping shells.
+func (s *Server) extractSenderInfo(conn net.Conn) approval.SenderInfo {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return approval.SenderInfo{}
+	}
+
+	raw, err := uc.SyscallConn()
+	if err != nil {
+		return approval.SenderInfo{}
+	}
+
+	var cred *unix.Ucred
+	var credErr error
+	raw.Control(func(fd uintptr) { //nolint:errcheck
+		cred, credErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	})
+	if credErr != nil || cred == nil {
+		return approval.SenderInfo{}
+	}
+
+	chain := procutil.ReadProcessChain(cred.Pid, s.trimProcessChain)
+	processChain := make([]approval.ProcessInfo, len(chain))
+	for i, entry := range chain {
+		processChain[i] = approval.ProcessInfo{
+			Name:      entry.Comm,
+			PID:       uint32(entry.PID),
+			StartTime: entry.StartTime,
+			Exe:       entry.Exe,
+			Args:      entry.Args,
+			CWD:       entry.CWD,
+		}
+	}
+
+	invokerFrom := uint32(cred.Pid)
+	if len(chain) > 1 && gnupgTools[chain[0].Comm] {
+		invokerFrom = uint32(chain[1].PID)
+	}
+	comm, invokerPID := procutil.ResolveInvoker(invokerFrom)
+
+	info := approval.SenderInfo{
+		PID:          invokerPID,
+		UID:          uint32(cred.Uid),
+		InvokerName:  comm,
+		ProcessChain: processChain,
+	}
+	info.ReadSandbox(cred.Pid)
+	return info
+}
@@ -33,6 +33,8 @@ type SignedPayload struct {
 	// Message is the commit message, the tag message, or — for a push
 	// certificate — the ref-update lines being pushed.
 	Message string
+	// Tree is the hash of the commit's tree (commit only).
+	Tree string
 	// ParentHash is the commit's first parent (commit only).
 	ParentHash string
 	// TagName and Target describe an annotated tag: the tag's name and the hash
@@ -101,6 +103,8 @@ func ParseSignedPayload(data []byte) SignedPayload {
 			p.Signer = strings.TrimPrefix(h, "tagger ")
 		case strings.HasPrefix(h, "pusher "): // push certificate
 			p.Signer = strings.TrimPrefix(h, "pusher ")
+		case strings.HasPrefix(h, "tree ") && p.Tree == "":
+			p.Tree = strings.TrimPrefix(h, "tree ")
 		case strings.HasPrefix(h, "committer "):
 			p.Committer = strings.TrimPrefix(h, "committer ")
 		case strings.HasPrefix(h, "parent ") && p.ParentHash == "":
@@ -48,8 +48,9 @@ func (c *DaemonClient) DialWebSocket(ctx context.Context) (*websocket.Conn, erro
 	if err != nil {
 		return nil, err
 	}
-	// The default read limit (32KB) is too small for snapshot messages
-	// containing large commit objects. 1MB accommodates any realistic commit.
+	// The default read limit (32KB) is too small for a snapshot of a long
+	// history. Commit objects and patches are left out of snapshots and
+	// events, so 1MB is plenty however large the commits are.
 	conn.SetReadLimit(1 << 20)
 	return conn, nil
 }
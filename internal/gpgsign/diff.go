@@ -0,0 +1,171 @@
+package gpgsign
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/nikicat/secrets-dispatcher/internal/approval"
+)
+
+// Limits on the diff sent with a commit signing request. The daemon accepts
+// request bodies up to 1MB, which must also hold the commit object.
+const (
+	maxDiffPatch = 256 << 10
+	maxDiffFiles = 500
+)
+
+// collectCommitDiff returns what the commit being signed changes (see
+// CommitDiff), or nil on error.
+func collectCommitDiff(tree, parent string, debug bool) *approval.CommitDiff {
+	diff, err := CommitDiff("", tree, parent)
+	if err != nil {
+		if debug {
+			fmt.Fprintf(os.Stderr, "secrets-dispatcher: debug: collectCommitDiff error: %v\n", err)
+		}
+		return nil
+	}
+	return diff
+}
+
+// CommitDiff diffs tree against the first parent commit (the empty tree for a
+// root commit) in the repository at dir ("" for the current directory). Both
+// are read from the object database — git writes the tree before asking for
+// the signature — so the diff is exactly what gets signed, even for amends or
+// `commit -a`, where the index tells a different story.
+func CommitDiff(dir, tree, parent string) (*approval.CommitDiff, error) {
+	parent, err := parentTree(dir, tree, parent)
+	if err != nil {
+		return nil, err
+	}
+	numstat, err := runGitIn(dir, "diff-tree", "-r", "--no-renames", "--numstat", "-z", parent, tree)
+	if err != nil {
+		return nil, fmt.Errorf("diff-tree --numstat: %w", err)
+	}
+	diff := parseNumstat(numstat)
+
+	// diff-tree is plumbing: no pager, color, external diff or textconv.
+	patch := limitedBuffer{limit: maxDiffPatch}
+	cmd := exec.Command("git", "diff-tree", "-r", "--no-renames", "-p", parent, tree)
+	cmd.Dir = dir
+	cmd.Stdout = &patch
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("diff-tree -p: %w", err)
+	}
+	diff.Patch = patch.String()
+	if patch.overflow {
+		// Cut at the last complete line so the preview never ends mid-line.
+		if i := strings.LastIndexByte(diff.Patch, '\n'); i >= 0 {
+			diff.Patch = diff.Patch[:i+1]
+		}
+		diff.Truncated = true
+	}
+	return diff, nil
+}
+
+// ChangedFiles lists every path that tree changes against the first parent
+// commit in the repository at dir, like CommitDiff but uncapped.
+func ChangedFiles(dir, tree, parent string) ([]string, error) {
+	parent, err := parentTree(dir, tree, parent)
+	if err != nil {
+		return nil, err
+	}
+	out, err := runGitIn(dir, "diff-tree", "-r", "--no-renames", "--name-only", "-z", parent, tree)
+	if err != nil {
+		return nil, fmt.Errorf("diff-tree --name-only: %w", err)
+	}
+	files := []string{}
+	for f := range strings.SplitSeq(out, "\x00") {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// parentTree returns what to diff tree against: parent, or the empty tree of
+// the repository's hash algorithm for a root commit.
+func parentTree(dir, tree, parent string) (string, error) {
+	if !isObjectID(tree) {
+		return "", fmt.Errorf("invalid tree %q", tree)
+	}
+	if parent != "" {
+		if !isObjectID(parent) {
+			return "", fmt.Errorf("invalid parent %q", parent)
+		}
+		return parent, nil
+	}
+	out, err := runGitIn(dir, "hash-object", "-t", "tree", os.DevNull)
+	if err != nil {
+		return "", fmt.Errorf("empty tree: %w", err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// isObjectID reports whether s is a full SHA-1 or SHA-256 object name. Hashes
+// come from the signed payload and end up on git's command line, where
+// anything else could be taken for an option or a revision expression.
+func isObjectID(s string) bool {
+	if len(s) != 40 && len(s) != 64 {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// parseNumstat parses `git diff-tree --numstat -z` output: one
+// "added<TAB>deleted<TAB>path<NUL>" record per file, with "-" counts for
+// binary files.
+func parseNumstat(out string) *approval.CommitDiff {
+	diff := &approval.CommitDiff{Files: []approval.DiffFile{}}
+	for rec := range strings.SplitSeq(out, "\x00") {
+		added, rest, ok := strings.Cut(rec, "\t")
+		if !ok {
+			continue
+		}
+		deleted, name, ok := strings.Cut(rest, "\t")
+		if !ok || name == "" {
+			continue
+		}
+		f := approval.DiffFile{Path: name}
+		if added == "-" && deleted == "-" {
+			f.Binary = true
+		} else {
+			f.Additions, _ = strconv.Atoi(added)
+			f.Deletions, _ = strconv.Atoi(deleted)
+		}
+		diff.TotalFiles++
+		diff.Additions += f.Additions
+		diff.Deletions += f.Deletions
+		if len(diff.Files) < maxDiffFiles {
+			diff.Files = append(diff.Files, f)
+		} else {
+			diff.Truncated = true
+		}
+	}
+	return diff
+}
+
+// limitedBuffer keeps the first limit bytes written to it and discards the
+// rest, so a huge diff neither fills memory nor blocks the writing process.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); len(p) > room {
+		b.overflow = true
+		b.Buffer.Write(p[:max(room, 0)])
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
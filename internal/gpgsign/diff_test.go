@@ -0,0 +1,111 @@
+package gpgsign
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikicat/secrets-dispatcher/internal/approval"
+)
+
+func TestParseNumstat(t *testing.T) {
+	out := "3\t1\tmain.go\x00-\t-\tlogo.png\x000\t12\tdir/old name.txt\x00"
+	diff := parseNumstat(out)
+	assert.Equal(t, []approval.DiffFile{
+		{Path: "main.go", Additions: 3, Deletions: 1},
+		{Path: "logo.png", Binary: true},
+		{Path: "dir/old name.txt", Deletions: 12},
+	}, diff.Files)
+	assert.Equal(t, 3, diff.TotalFiles)
+	assert.Equal(t, 3, diff.Additions)
+	assert.Equal(t, 13, diff.Deletions)
+	assert.False(t, diff.Truncated)
+}
+
+func TestParseNumstat_FileLimit(t *testing.T) {
+	var b strings.Builder
+	for range maxDiffFiles + 2 {
+		b.WriteString("1\t0\tf\x00")
+	}
+	diff := parseNumstat(b.String())
+	assert.Len(t, diff.Files, maxDiffFiles)
+	assert.Equal(t, maxDiffFiles+2, diff.TotalFiles)
+	assert.Equal(t, maxDiffFiles+2, diff.Additions)
+	assert.True(t, diff.Truncated)
+}
+
+// TestCollectCommitDiff diffs a tree that was never staged against HEAD, the
+// way git asks for a signature: the tree object exists, the index and working
+// copy say something else.
+func TestCollectCommitDiff(t *testing.T) {
+	dir, git := newTestRepo(t)
+	t.Chdir(dir)
+	write := func(name, content string) {
+		t.Helper()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+
+	write("main.go", "package main\n")
+	git("add", "main.go")
+	git("commit", "-q", "-m", "initial")
+	root := git("rev-parse", "HEAD^{tree}")
+
+	write("main.go", "package main\n\nfunc main() {}\n")
+	write("logo.png", "\x89PNG\r\n\x1a\n\x00\x00")
+	git("add", "main.go", "logo.png")
+	tree := git("write-tree")
+	git("reset", "-q")
+
+	diff := collectCommitDiff(tree, git("rev-parse", "HEAD"), false)
+	require.NotNil(t, diff)
+	assert.Equal(t, 2, diff.TotalFiles)
+	assert.Equal(t, 2, diff.Additions)
+	assert.Contains(t, diff.Files, approval.DiffFile{Path: "logo.png", Binary: true})
+	assert.Contains(t, diff.Patch, "+func main() {}")
+	assert.Contains(t, diff.Patch, "Binary files")
+	assert.False(t, diff.Truncated)
+
+	// A root commit is diffed against the empty tree.
+	diff = collectCommitDiff(root, "", false)
+	require.NotNil(t, diff)
+	assert.Equal(t, []approval.DiffFile{{Path: "main.go", Additions: 1}}, diff.Files)
+
+	assert.Nil(t, collectCommitDiff(strings.Repeat("0", 40), "", false), "unknown tree")
+}
+
+func TestLimitedBuffer(t *testing.T) {
+	b := limitedBuffer{limit: 8}
+	n, err := b.Write([]byte("line 1\nline 2\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 14, n, "writes past the limit are swallowed, not failed")
+	assert.Equal(t, "line 1\nl", b.String())
+	assert.True(t, b.overflow)
+}
+
+// newTestRepo creates an empty git repository and returns its directory and a
+// function running git in it with a fixed identity and no user config.
+func newTestRepo(t *testing.T) (string, func(args ...string) string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1",
+			"GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@example.com",
+			"GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@example.com")
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, "git %v: %s", args, out)
+		return strings.TrimSpace(string(out))
+	}
+	git("init", "-q")
+	return dir, git
+}
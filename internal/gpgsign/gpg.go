@@ -18,6 +18,17 @@ import (
 // Returns the absolute path to the real gpg binary, or an error if none is
 // found.
 func FindRealGPG() (string, error) {
+	return findReal("gpg")
+}
+
+// FindRealSSHKeygen locates the real ssh-keygen binary in PATH, skipping self
+// like FindRealGPG.
+func FindRealSSHKeygen() (string, error) {
+	return findReal("ssh-keygen")
+}
+
+// findReal returns the first name in PATH that is not the running executable.
+func findReal(name string) (string, error) {
 	self, err := os.Executable()
 	if err != nil {
 		return "", err
@@ -28,7 +39,7 @@ func FindRealGPG() (string, error) {
 	}
 
 	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
-		candidate := filepath.Join(dir, "gpg")
+		candidate := filepath.Join(dir, name)
 		info, err := os.Stat(candidate)
 		if err != nil {
 			continue
@@ -38,7 +49,7 @@ func FindRealGPG() (string, error) {
 		}
 		return candidate, nil
 	}
-	return "", errors.New("gpg not found in PATH")
+	return "", fmt.Errorf("%s not found in PATH", name)
 }
 
 // isSignRequest reports whether the args git handed to gpg.program ask it to
@@ -82,7 +93,13 @@ func passThroughToGPG(args []string, stdin io.Reader) int {
 		fmt.Fprintf(os.Stderr, "secrets-dispatcher: cannot locate real gpg for pass-through: %v\n", err)
 		return 2
 	}
-	cmd := exec.Command(gpgPath, args...)
+	return passThrough(gpgPath, args, stdin)
+}
+
+// passThrough runs the program at path with args and the standard streams
+// wired straight through, and returns its exit code.
+func passThrough(path string, args []string, stdin io.Reader) int {
+	cmd := exec.Command(path, args...)
 	cmd.Stdin = stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -91,7 +108,7 @@ func passThroughToGPG(args []string, stdin io.Reader) int {
 		if errors.As(err, &exitErr) {
 			return exitErr.ExitCode()
 		}
-		fmt.Fprintf(os.Stderr, "secrets-dispatcher: gpg pass-through failed: %v\n", err)
+		fmt.Fprintf(os.Stderr, "secrets-dispatcher: %s pass-through failed: %v\n", filepath.Base(path), err)
 		return 2
 	}
 	return 0
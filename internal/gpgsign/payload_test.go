@@ -18,6 +18,7 @@ func TestParseSignedPayload(t *testing.T) {
 		assert.Equal(t, KindCommit, p.Kind)
 		assert.Equal(t, "Alice <alice@example.com> 1771936651 +0200", p.Signer)
 		assert.Equal(t, "Bob <bob@example.com> 1771936651 +0200", p.Committer)
+		assert.Equal(t, "8754a964a0ce1b6c5f7a88202174955bdcd58a98", p.Tree)
 		assert.Equal(t, "aaa111", p.ParentHash)
 		assert.Equal(t, "feat: add files", p.Message)
 		assert.Empty(t, p.TagName)
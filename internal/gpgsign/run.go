@@ -55,26 +55,71 @@ func Run(args []string, stdin io.Reader) int {
 		return 2
 	}
 
+	// 3-11. Ask the daemon for the signature.
+	info := newSignInfo(commitBytes, debug)
+	info.KeyID = keyID
+	signature, gpgStatus, exitCode := requestSignature(info, debug)
+	if exitCode != 0 {
+		// ERR-02: propagate gpg exit code.
+		if len(gpgStatus) > 0 {
+			os.Stderr.Write(gpgStatus) //nolint:errcheck
+		}
+		return exitCode
+	}
+
+	// SIGN-08: Write signature to stdout, status to stderr.
+	os.Stdout.Write(signature) //nolint:errcheck
+	if len(gpgStatus) > 0 {
+		os.Stderr.Write(gpgStatus) //nolint:errcheck
+	}
+	return 0
+}
+
+// newSignInfo describes the object git asked to sign for the approval prompt.
+func newSignInfo(commitBytes []byte, debug bool) *approval.GPGSignInfo {
 	// 3. Parse the signed payload for display context (SIGN-02). git signs
 	// commits, annotated tags, and push certificates through this same path;
 	// detect which so the approval prompt shows the right fields.
 	payload := ParseSignedPayload(commitBytes)
 
 	// 4. Collect git context (SIGN-03, SIGN-04). Changed files describe a
-	// commit's staged tree — they are meaningless for tag/push signing and
-	// could mislead both the prompt and trusted-signer matching, so gather them
-	// for commits only.
+	// commit's tree — they are meaningless for tag/push signing and could
+	// mislead both the prompt and trusted-signer matching, so gather them for
+	// commits only. The diff previews the content being signed.
 	repoName := resolveRepoName(debug)
 	var changedFiles []string
+	var diff *approval.CommitDiff
 	if payload.Kind == KindCommit {
-		changedFiles = collectChangedFiles(debug)
+		changedFiles = collectChangedFiles(payload.Tree, payload.ParentHash, debug)
+		diff = collectCommitDiff(payload.Tree, payload.ParentHash, debug)
 	}
+	return &approval.GPGSignInfo{
+		RepoName:     repoName,
+		Kind:         string(payload.Kind),
+		CommitMsg:    payload.Message,
+		Author:       payload.Signer,
+		Committer:    payload.Committer,
+		ChangedFiles: changedFiles,
+		ParentHash:   payload.ParentHash,
+		TagName:      payload.TagName,
+		Target:       payload.Target,
+		Pushee:       payload.Pushee,
+		CommitObject: string(commitBytes),
+		Diff:         diff,
+	}
+}
 
+// requestSignature posts info as a signing request to the daemon and blocks
+// until it is resolved. It returns the signature and the signer's status
+// output, or a non-zero exit code: the signer's own when it failed, 1 when the
+// request was denied or timed out and 2 on a system error (reported on stderr
+// here).
+func requestSignature(info *approval.GPGSignInfo, debug bool) (signature, status []byte, exitCode int) {
 	// 5. Load auth token (ERR-01 if missing).
 	token, err := loadAuthToken()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "secrets-dispatcher: daemon not running (cannot read auth token): %v\n", err)
-		return 2
+		return nil, nil, 2
 	}
 
 	// 6. Determine socket path.
@@ -92,28 +137,15 @@ func Run(args []string, stdin io.Reader) int {
 	wsConn, err := client.DialWebSocket(ctx)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "secrets-dispatcher: daemon unreachable at %s. Is secrets-dispatcher running?\n", socketPath)
-		return 2
+		return nil, nil, 2
 	}
 	defer wsConn.CloseNow()
 
 	// 9. POST signing request to daemon (SIGN-05).
-	reqID, err := client.PostSigningRequest(ctx, repoName, &approval.GPGSignInfo{
-		RepoName:     repoName,
-		Kind:         string(payload.Kind),
-		CommitMsg:    payload.Message,
-		Author:       payload.Signer,
-		Committer:    payload.Committer,
-		KeyID:        keyID,
-		ChangedFiles: changedFiles,
-		ParentHash:   payload.ParentHash,
-		TagName:      payload.TagName,
-		Target:       payload.Target,
-		Pushee:       payload.Pushee,
-		CommitObject: string(commitBytes),
-	})
+	reqID, err := client.PostSigningRequest(ctx, info.RepoName, info)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "secrets-dispatcher: failed to send signing request: %v\n", err)
-		return 2
+		return nil, nil, 2
 	}
 
 	if debug {
@@ -144,31 +176,17 @@ func Run(args []string, stdin io.Reader) int {
 	}()
 
 	// 11. Block until resolution (SIGN-08, ERR-01, ERR-02).
-	signature, gpgStatus, exitCode, denied, err := client.WaitForResolution(wsCtx, wsConn, reqID)
+	signature, status, exitCode, denied, err := client.WaitForResolution(wsCtx, wsConn, reqID)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "secrets-dispatcher: %v\n", err)
-		return 1 // timeout is exit 1 per spec
+		return nil, nil, 1 // timeout is exit 1 per spec
 	}
 
 	if denied {
 		fmt.Fprintln(os.Stderr, "secrets-dispatcher: signing request denied by user")
-		return 1
+		return nil, nil, 1
 	}
-
-	if exitCode != 0 {
-		// ERR-02: propagate gpg exit code.
-		if len(gpgStatus) > 0 {
-			os.Stderr.Write(gpgStatus) //nolint:errcheck
-		}
-		return exitCode
-	}
-
-	// SIGN-08: Write signature to stdout, status to stderr.
-	os.Stdout.Write(signature) //nolint:errcheck
-	if len(gpgStatus) > 0 {
-		os.Stderr.Write(gpgStatus) //nolint:errcheck
-	}
-	return 0
+	return signature, status, exitCode
 }
 
 // resolveRepoName runs git rev-parse --show-toplevel and returns the base name.
@@ -184,22 +202,16 @@ func resolveRepoName(debug bool) string {
 	return filepath.Base(strings.TrimSpace(out))
 }
 
-// collectChangedFiles runs git diff --cached --name-only and returns the file list.
-// Returns nil on error.
-func collectChangedFiles(debug bool) []string {
-	out, err := runGitCommand("diff", "--cached", "--name-only")
+// collectChangedFiles returns the files the commit being signed changes (see
+// ChangedFiles). Returns nil on error.
+func collectChangedFiles(tree, parent string, debug bool) []string {
+	files, err := ChangedFiles("", tree, parent)
 	if err != nil {
 		if debug {
 			fmt.Fprintf(os.Stderr, "secrets-dispatcher: debug: collectChangedFiles error: %v\n", err)
 		}
 		return nil
 	}
-	var files []string
-	for f := range strings.SplitSeq(out, "\n") {
-		if f != "" {
-			files = append(files, f)
-		}
-	}
 	return files
 }
 
@@ -242,9 +254,17 @@ func unixSocketPath() string {
 	return filepath.Join(runtimeDir, "secrets-dispatcher", "api.sock")
 }
 
-// runGitCommand runs a git subcommand and returns trimmed stdout output.
+// runGitCommand runs a git subcommand in the current directory and returns
+// its stdout.
 func runGitCommand(args ...string) (string, error) {
+	return runGitIn("", args...)
+}
+
+// runGitIn runs a git subcommand in dir ("" for the current directory) and
+// returns its stdout.
+func runGitIn(dir string, args ...string) (string, error) {
 	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
 	var out bytes.Buffer
 	cmd.Stdout = &out
 	if err := cmd.Run(); err != nil {
@@ -0,0 +1,215 @@
+package gpgsign
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/nikicat/secrets-dispatcher/internal/approval"
+)
+
+// maxSeriesCommits caps SignSeries.Commits; Total still counts them all.
+const maxSeriesCommits = 200
+
+// DetectSeries returns the multi-commit operation in progress in the
+// repository at dir — the one a commit being signed there belongs to — or nil
+// when there is none. It reads git's own state: rebase-merge for rebases,
+// sequencer for multi-commit cherry-picks and reverts, and rebase-apply for
+// am and apply-backend rebases.
+func DetectSeries(dir string) *approval.SignSeries {
+	out, err := runGitIn(dir, "rev-parse", "--git-path", "rebase-merge", "--git-path", "rebase-apply", "--git-path", "sequencer")
+	if err != nil {
+		return nil
+	}
+	paths := strings.Split(strings.TrimSpace(out), "\n")
+	if len(paths) != 3 {
+		return nil
+	}
+	for i, p := range paths {
+		if !filepath.IsAbs(p) {
+			paths[i] = filepath.Join(dir, p)
+		}
+	}
+	if s := rebaseMergeSeries(paths[0]); s != nil {
+		return s
+	}
+	if s := rebaseApplySeries(paths[1]); s != nil {
+		return s
+	}
+	return sequencerSeries(paths[2])
+}
+
+// rebaseMergeSeries reads a rebase in progress: "done" ends with the commit
+// being signed, "git-rebase-todo" holds the rest.
+func rebaseMergeSeries(state string) *approval.SignSeries {
+	done, err := readTodo(filepath.Join(state, "done"))
+	if err != nil || len(done) == 0 {
+		return nil
+	}
+	todo, _, steps, err := readTodoCommands(filepath.Join(state, "git-rebase-todo"))
+	if err != nil {
+		return nil
+	}
+	s := newSeries("rebase", len(done), append(done, todo...))
+	s.Steps = steps
+	return s
+}
+
+// sequencerSeries reads a multi-commit cherry-pick or revert: "todo" starts
+// with the commit being signed.
+func sequencerSeries(state string) *approval.SignSeries {
+	todo, cmds, steps, err := readTodoCommands(filepath.Join(state, "todo"))
+	if err != nil || len(todo) == 0 {
+		return nil
+	}
+	op := "cherry-pick"
+	if cmds[0] == "revert" {
+		op = "revert"
+	}
+	s := newSeries(op, 1, todo)
+	s.Steps = steps
+	return s
+}
+
+// rebaseApplySeries reads an am, or a rebase with the apply backend: patches
+// 0001… up to "last", of which "next" is being applied.
+func rebaseApplySeries(state string) *approval.SignSeries {
+	next, err1 := readInt(filepath.Join(state, "next"))
+	last, err2 := readInt(filepath.Join(state, "last"))
+	if err1 != nil || err2 != nil || next < 1 || next > last {
+		return nil
+	}
+	op := "am"
+	if _, err := os.Stat(filepath.Join(state, "rebasing")); err == nil {
+		op = "rebase"
+	}
+	commits := make([]approval.SeriesCommit, 0, min(last, maxSeriesCommits))
+	for i := 1; i <= last && i <= maxSeriesCommits; i++ {
+		commits = append(commits, readPatchHeader(filepath.Join(state, fmt.Sprintf("%04d", i))))
+	}
+	s := newSeries(op, next, commits)
+	s.Total = last
+	return s
+}
+
+func newSeries(op string, position int, commits []approval.SeriesCommit) *approval.SignSeries {
+	s := &approval.SignSeries{Operation: op, Position: position, Total: len(commits), Commits: commits}
+	if len(s.Commits) > maxSeriesCommits {
+		s.Commits = s.Commits[:maxSeriesCommits]
+	}
+	return s
+}
+
+// readTodo returns the commits a rebase or sequencer todo file creates, in
+// order; see readTodoCommands.
+func readTodo(path string) ([]approval.SeriesCommit, error) {
+	commits, _, _, err := readTodoCommands(path)
+	return commits, err
+}
+
+// readTodoCommands parses a todo file ("pick <hash> <subject>" lines) and
+// returns the lines that create a commit, with their commands, and the other
+// lines (exec, label, reset, break, update-ref, drop) as written. Comments and
+// noop are skipped.
+func readTodoCommands(path string) (commits []approval.SeriesCommit, cmds, steps []string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") || fields[0] == "noop" {
+			continue
+		}
+		cmd, args := fields[0], fields[1:]
+		if len(args) == 0 {
+			steps = append(steps, line)
+			continue
+		}
+		var c approval.SeriesCommit
+		switch cmd {
+		case "pick", "p", "reword", "r", "edit", "e", "squash", "s", "revert":
+			c = approval.SeriesCommit{Hash: args[0], Subject: strings.Join(args[1:], " ")}
+		case "fixup", "f":
+			if args[0] == "-C" || args[0] == "-c" {
+				args = args[1:]
+			}
+			if len(args) == 0 {
+				steps = append(steps, line)
+				continue
+			}
+			c = approval.SeriesCommit{Hash: args[0], Subject: strings.Join(args[1:], " ")}
+		case "merge", "m":
+			// merge [-C <hash>] <label> [# <subject>]
+			if (args[0] == "-C" || args[0] == "-c") && len(args) > 2 {
+				c.Hash, args = args[1], args[2:]
+			}
+			c.Subject = args[0]
+			if _, subject, ok := strings.Cut(strings.Join(args, " "), "# "); ok {
+				c.Subject = subject
+			}
+		default:
+			steps = append(steps, line)
+			continue
+		}
+		commits = append(commits, c)
+		cmds = append(cmds, cmd)
+	}
+	return commits, cmds, steps, sc.Err()
+}
+
+// readPatchHeader returns the original commit and subject of a patch in
+// git format-patch form: a "From <hash> …" line and a Subject header, less
+// its "[PATCH n/m]" prefix.
+func readPatchHeader(path string) approval.SeriesCommit {
+	var c approval.SeriesCommit
+	f, err := os.Open(path)
+	if err != nil {
+		return c
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	inSubject := false
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			break // end of the headers
+		}
+		switch {
+		case inSubject && (line[0] == ' ' || line[0] == '\t'):
+			c.Subject += " " + strings.TrimSpace(line)
+			continue
+		case strings.HasPrefix(line, "From "):
+			if hash, _, _ := strings.Cut(line[len("From "):], " "); isObjectID(hash) {
+				c.Hash = hash
+			}
+		case strings.HasPrefix(line, "Subject: "):
+			c.Subject = strings.TrimSpace(line[len("Subject: "):])
+			inSubject = true
+			continue
+		}
+		inSubject = false
+	}
+	if strings.HasPrefix(c.Subject, "[") {
+		if _, rest, ok := strings.Cut(c.Subject, "] "); ok {
+			c.Subject = rest
+		}
+	}
+	return c
+}
+
+func readInt(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
@@ -0,0 +1,97 @@
+package gpgsign
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikicat/secrets-dispatcher/internal/approval"
+)
+
+// writeState writes git operation state files under .git of the repo at dir.
+func writeState(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, ".git", name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+func TestDetectSeries(t *testing.T) {
+	t.Run("no operation", func(t *testing.T) {
+		dir, _ := newTestRepo(t)
+		assert.Nil(t, DetectSeries(dir))
+	})
+
+	t.Run("rebase", func(t *testing.T) {
+		dir, _ := newTestRepo(t)
+		writeState(t, dir, map[string]string{
+			"rebase-merge/done":            "pick 1111111 first\npick 2222222 second\n",
+			"rebase-merge/git-rebase-todo": "exec make test\npick 3333333 third\n\n# Rebase 0000000..3333333 onto 0000000\n",
+		})
+		s := DetectSeries(dir)
+		require.NotNil(t, s)
+		assert.Equal(t, "rebase", s.Operation)
+		assert.Equal(t, 2, s.Position)
+		assert.Equal(t, 3, s.Total)
+		assert.Equal(t, 1, s.Remaining())
+		assert.Equal(t, approval.SeriesCommit{Hash: "2222222", Subject: "second"}, s.Commits[1])
+		assert.Equal(t, []string{"exec make test"}, s.Steps, "lines creating no commit are shown too")
+	})
+
+	t.Run("revert", func(t *testing.T) {
+		dir, _ := newTestRepo(t)
+		writeState(t, dir, map[string]string{
+			"sequencer/todo": "revert 1111111 first\nrevert 2222222 second\n",
+		})
+		s := DetectSeries(dir)
+		require.NotNil(t, s)
+		assert.Equal(t, "revert", s.Operation)
+		assert.Equal(t, 1, s.Position)
+		assert.Equal(t, 2, s.Total)
+	})
+
+	t.Run("am", func(t *testing.T) {
+		dir, _ := newTestRepo(t)
+		hash := "0123456789abcdef0123456789abcdef01234567"
+		writeState(t, dir, map[string]string{
+			"rebase-apply/next":     "1\n",
+			"rebase-apply/last":     "2\n",
+			"rebase-apply/applying": "",
+			"rebase-apply/0001": "From " + hash + " Mon Sep 17 00:00:00 2001\n" +
+				"From: t <t@example.com>\n" +
+				"Subject: [PATCH 1/2] fix a long\n and folded subject\n\nbody\n",
+		})
+		s := DetectSeries(dir)
+		require.NotNil(t, s)
+		assert.Equal(t, "am", s.Operation)
+		assert.Equal(t, 1, s.Position)
+		assert.Equal(t, 2, s.Total)
+		require.Len(t, s.Commits, 2)
+		assert.Equal(t, approval.SeriesCommit{Hash: hash, Subject: "fix a long and folded subject"}, s.Commits[0])
+		assert.Equal(t, approval.SeriesCommit{}, s.Commits[1], "missing patch")
+	})
+}
+
+func TestReadTodoCommands(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todo")
+	require.NoError(t, os.WriteFile(path, []byte(
+		"label onto\n"+
+			"fixup -C 1111111 amend subject\n"+
+			"reset onto\n"+
+			"merge -C 2222222 topic # Merge branch 'topic'\n"+
+			"break\n"), 0o644))
+
+	commits, cmds, steps, err := readTodoCommands(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"fixup", "merge"}, cmds)
+	assert.Equal(t, []string{"label onto", "reset onto", "break"}, steps)
+	assert.Equal(t, []approval.SeriesCommit{
+		{Hash: "1111111", Subject: "amend subject"},
+		{Hash: "2222222", Subject: "Merge branch 'topic'"},
+	}, commits)
+}
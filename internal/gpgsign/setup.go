@@ -8,7 +8,9 @@ import (
 )
 
 // SetupGitConfig writes a shell wrapper script and configures git's gpg.program.
-// scope is "global" (default) or "local" (per-repo).
+// scope is "global" (default) or "local" (per-repo). format is "openpgp"
+// (default) or "ssh", which sets gpg.ssh.program to a wrapper around the
+// ssh-sign subcommand instead (see RunSSH).
 //
 // A wrapper script is required because git does NOT shell-split gpg.program —
 // it uses execvp, so "secrets-dispatcher gpg-sign" (with a space) would fail.
@@ -17,7 +19,16 @@ import (
 // Per CONTEXT.md locked decisions:
 //   - Setup only sets gpg.program; does NOT enable commit.gpgsign=true
 //   - Defaults to --global; caller can pass "local" for per-repo config
-func SetupGitConfig(scope string) error {
+func SetupGitConfig(scope, format string) error {
+	wrapperName, subcommand, configKey := "secrets-dispatcher-gpg", "gpg-sign", "gpg.program"
+	switch format {
+	case "", "openpgp":
+	case "ssh":
+		wrapperName, subcommand, configKey = "secrets-dispatcher-ssh-keygen", "ssh-sign", "gpg.ssh.program"
+	default:
+		return fmt.Errorf("unknown signing format %q (want openpgp or ssh)", format)
+	}
+
 	self, err := os.Executable()
 	if err != nil {
 		return fmt.Errorf("find executable: %w", err)
@@ -28,38 +39,41 @@ func SetupGitConfig(scope string) error {
 		return fmt.Errorf("resolve executable: %w", err)
 	}
 
-	// Write shell wrapper to ~/.local/bin/secrets-dispatcher-gpg.
+	// Write shell wrapper to ~/.local/bin.
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return fmt.Errorf("get home dir: %w", err)
 	}
 	wrapperDir := filepath.Join(home, ".local", "bin")
-	wrapperPath := filepath.Join(wrapperDir, "secrets-dispatcher-gpg")
+	wrapperPath := filepath.Join(wrapperDir, wrapperName)
 
 	if err := os.MkdirAll(wrapperDir, 0755); err != nil {
 		return fmt.Errorf("create wrapper dir: %w", err)
 	}
 
-	content := fmt.Sprintf("#!/bin/sh\nexec %s gpg-sign \"$@\"\n", self)
+	content := fmt.Sprintf("#!/bin/sh\nexec %s %s \"$@\"\n", self, subcommand)
 	if err := os.WriteFile(wrapperPath, []byte(content), 0755); err != nil {
 		return fmt.Errorf("write wrapper: %w", err)
 	}
 
-	// Configure git gpg.program to point at the wrapper.
+	// Configure git gpg.program (or gpg.ssh.program) to point at the wrapper.
 	gitArgs := []string{"config"}
 	if scope == "local" {
 		gitArgs = append(gitArgs, "--local")
 	} else {
 		gitArgs = append(gitArgs, "--global")
 	}
-	gitArgs = append(gitArgs, "gpg.program", wrapperPath)
+	gitArgs = append(gitArgs, configKey, wrapperPath)
 	if err := exec.Command("git", gitArgs...).Run(); err != nil {
 		return fmt.Errorf("git config: %w", err)
 	}
 
 	fmt.Printf("Wrote wrapper: %s\n", wrapperPath)
-	fmt.Printf("Configured git %s gpg.program = %s\n", scope, wrapperPath)
+	fmt.Printf("Configured git %s %s = %s\n", scope, configKey, wrapperPath)
 	fmt.Println("\nNote: Ensure ~/.local/bin is in your PATH.")
+	if format == "ssh" {
+		fmt.Println("This does NOT set gpg.format — use 'git config --global gpg.format ssh' if it is not set already.")
+	}
 	fmt.Println("This does NOT enable commit.gpgsign — use 'git config --global commit.gpgsign true' to auto-sign all commits.")
 	return nil
 }
@@ -0,0 +1,239 @@
+package gpgsign
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/nikicat/secrets-dispatcher/internal/approval"
+)
+
+// sshSignArgs is a parsed `ssh-keygen -Y sign` invocation.
+type sshSignArgs struct {
+	namespace string
+	keyFile   string
+	useAgent  bool   // -U
+	file      string // "" for stdin
+}
+
+// RunSSH is the entry point for the ssh-sign subcommand, the gpg.ssh.program
+// counterpart of Run for repositories with gpg.format=ssh. git calls it as:
+//
+//	ssh-keygen -Y sign -n git -f <key file> [-U] <payload file>
+//
+// and reads the signature from "<payload file>.sig". The payload is the same
+// commit, tag or push certificate Run receives on stdin, so the request the
+// daemon sees is the same; on approval the daemon runs the real ssh-keygen
+// and the signature is written where git expects it. git also calls
+// gpg.ssh.program to verify signatures (-Y verify, find-principals,
+// check-novalidate); those go straight to the real ssh-keygen.
+//
+// Exit codes are Run's, with ssh-keygen's own in place of gpg's.
+func RunSSH(args []string, stdin io.Reader) int {
+	debug := os.Getenv("SECRETS_DISPATCHER_DEBUG") == "1"
+
+	if !isSSHSignRequest(args) {
+		if debug {
+			fmt.Fprintf(os.Stderr, "secrets-dispatcher: debug: non-signing invocation, delegating to real ssh-keygen: %v\n", args)
+		}
+		path, err := FindRealSSHKeygen()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "secrets-dispatcher: cannot locate real ssh-keygen for pass-through: %v\n", err)
+			return 2
+		}
+		return passThrough(path, args, stdin)
+	}
+
+	sa, err := parseSSHSignArgs(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "secrets-dispatcher: %v\n", err)
+		return 2
+	}
+	keyFile, err := filepath.Abs(sa.keyFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "secrets-dispatcher: resolve key file: %v\n", err)
+		return 2
+	}
+	if debug {
+		fmt.Fprintf(os.Stderr, "secrets-dispatcher: debug: key=%s agent=%v file=%q\n", keyFile, sa.useAgent, sa.file)
+	}
+
+	var payload []byte
+	if sa.file != "" {
+		payload, err = os.ReadFile(sa.file)
+	} else {
+		payload, err = io.ReadAll(stdin)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "secrets-dispatcher: failed to read payload: %v\n", err)
+		return 2
+	}
+
+	info := newSignInfo(payload, debug)
+	info.Format = approval.SSHFormat
+	info.KeyID = keyFile
+	info.UseAgent = sa.useAgent
+	// For the prompt only; the daemon reads the key file and recomputes it.
+	info.Fingerprint, _ = SSHKeyFingerprint(keyFile)
+
+	signature, status, exitCode := requestSignature(info, debug)
+	if len(status) > 0 {
+		os.Stderr.Write(status) //nolint:errcheck
+	}
+	if exitCode != 0 {
+		return exitCode
+	}
+	if sa.file == "" {
+		os.Stdout.Write(signature) //nolint:errcheck
+		return 0
+	}
+	if err := os.WriteFile(sa.file+".sig", signature, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "secrets-dispatcher: write signature: %v\n", err)
+		return 2
+	}
+	return 0
+}
+
+// isSSHSignRequest reports whether args are an ssh-keygen `-Y sign`
+// invocation, as opposed to -Y verify and the rest.
+func isSSHSignRequest(args []string) bool {
+	for i, a := range args {
+		if a == "-Ysign" || a == "-Y" && i+1 < len(args) && args[i+1] == "sign" {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSSHSignArgs parses the options git passes to `ssh-keygen -Y sign`.
+// Anything else is refused rather than passed on: the daemon signs with a
+// fixed command line, so an option it would drop must not be silently
+// ignored.
+func parseSSHSignArgs(args []string) (sshSignArgs, error) {
+	var sa sshSignArgs
+	var files []string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			files = append(files, args[i+1:]...)
+			break
+		}
+		if len(a) < 2 || a[0] != '-' {
+			files = append(files, a)
+			continue
+		}
+		opt, val := a[1], a[2:]
+		switch opt {
+		case 'U':
+			if val != "" {
+				return sa, fmt.Errorf("unsupported ssh-keygen option %s", a)
+			}
+			sa.useAgent = true
+			continue
+		case 'Y', 'n', 'f':
+		default:
+			return sa, fmt.Errorf("unsupported ssh-keygen option %s", a)
+		}
+		if val == "" {
+			if i+1 == len(args) {
+				return sa, fmt.Errorf("ssh-keygen option -%c needs a value", opt)
+			}
+			i++
+			val = args[i]
+		}
+		switch opt {
+		case 'n':
+			sa.namespace = val
+		case 'f':
+			sa.keyFile = val
+		}
+	}
+	if sa.namespace != "git" {
+		return sa, fmt.Errorf("only git signatures can be requested (-n git), not -n %q", sa.namespace)
+	}
+	if sa.keyFile == "" {
+		return sa, errors.New("no signing key (-f)")
+	}
+	if len(files) > 1 {
+		return sa, errors.New("only one file can be signed at a time")
+	}
+	if len(files) == 1 {
+		sa.file = files[0]
+	}
+	return sa, nil
+}
+
+// maxSSHKeySize bounds the key file ReadSSHKey reads; SSH keys, even RSA
+// private keys, are a few kilobytes.
+const maxSSHKeySize = 64 << 10
+
+// ReadSSHKey reads the SSH key file a signing request names.
+func ReadSSHKey(keyFile string) ([]byte, error) {
+	f, err := os.Open(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	key, err := io.ReadAll(io.LimitReader(f, maxSSHKeySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(key) > maxSSHKeySize {
+		return nil, fmt.Errorf("%s: larger than %d bytes, not an SSH key", keyFile, maxSSHKeySize)
+	}
+	return key, nil
+}
+
+// WithSSHKeyCopy writes key to a file of its own, 0600 in a new 0700
+// directory, and calls fn with its path, removing both afterwards. ssh-keygen
+// then reads exactly these bytes, whatever becomes of the file they came from.
+func WithSSHKeyCopy(key []byte, fn func(keyFile string) error) error {
+	dir, err := os.MkdirTemp("", "secrets-dispatcher-ssh-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	keyFile := filepath.Join(dir, "key")
+	if err := os.WriteFile(keyFile, key, 0o600); err != nil {
+		return err
+	}
+	return fn(keyFile)
+}
+
+// SSHKeyDataFingerprint is SSHKeyFingerprint for a key read by ReadSSHKey.
+func SSHKeyDataFingerprint(key []byte) (string, error) {
+	var fpr string
+	err := WithSSHKeyCopy(key, func(keyFile string) error {
+		var err error
+		fpr, err = SSHKeyFingerprint(keyFile)
+		return err
+	})
+	return fpr, err
+}
+
+// SSHKeyFingerprint returns the SHA256 fingerprint ("SHA256:…") of the SSH
+// key in keyFile, public or private, as printed by `ssh-keygen -l`.
+func SSHKeyFingerprint(keyFile string) (string, error) {
+	path, err := FindRealSSHKeygen()
+	if err != nil {
+		return "", err
+	}
+	cmd := exec.Command(path, "-l", "-E", "sha256", "-f", keyFile)
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("ssh-keygen -l: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	// "256 SHA256:… comment (ED25519)"
+	fields := strings.Fields(out.String())
+	if len(fields) < 2 || !strings.HasPrefix(fields[1], "SHA256:") {
+		return "", fmt.Errorf("ssh-keygen -l: unexpected output %q", out.String())
+	}
+	return fields[1], nil
+}
@@ -0,0 +1,111 @@
+package gpgsign
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsSSHSignRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want bool
+	}{
+		// git's signing invocation, with and without a literal (agent) key.
+		{"git sign", []string{"-Y", "sign", "-n", "git", "-f", "/home/u/.ssh/id_ed25519", "/tmp/.git_signing_buffer_tmpX"}, true},
+		{"git sign -U", []string{"-Y", "sign", "-n", "git", "-f", "/tmp/.git_signing_key_tmpX", "-U", "/tmp/.git_signing_buffer_tmpX"}, true},
+		{"joined -Ysign", []string{"-Ysign", "-ngit", "-fkey"}, true},
+
+		// git's verification invocations go to the real ssh-keygen.
+		{"verify", []string{"-Y", "verify", "-n", "git", "-f", "allowed_signers", "-I", "me@example.com", "-s", "/tmp/sig"}, false},
+		{"find-principals", []string{"-Y", "find-principals", "-f", "allowed_signers", "-s", "/tmp/sig"}, false},
+		{"check-novalidate", []string{"-Y", "check-novalidate", "-n", "git", "-s", "/tmp/sig"}, false},
+		{"fingerprint", []string{"-l", "-f", "key"}, false},
+		{"empty", nil, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, isSSHSignRequest(tc.args), "args=%v", tc.args)
+		})
+	}
+}
+
+func TestParseSSHSignArgs(t *testing.T) {
+	sa, err := parseSSHSignArgs([]string{"-Y", "sign", "-n", "git", "-f", "/tmp/key", "-U", "/tmp/buf"})
+	require.NoError(t, err)
+	assert.Equal(t, sshSignArgs{namespace: "git", keyFile: "/tmp/key", useAgent: true, file: "/tmp/buf"}, sa)
+
+	sa, err = parseSSHSignArgs([]string{"-Ysign", "-ngit", "-f/tmp/key"})
+	require.NoError(t, err)
+	assert.Equal(t, sshSignArgs{namespace: "git", keyFile: "/tmp/key"}, sa, "no file means stdin")
+
+	for _, args := range [][]string{
+		{"-Y", "sign", "-n", "file", "-f", "/tmp/key", "/tmp/buf"},          // not a git signature
+		{"-Y", "sign", "-n", "git", "/tmp/buf"},                             // no key
+		{"-Y", "sign", "-n", "git", "-f", "/tmp/key", "/tmp/a", "/tmp/b"},   // several files
+		{"-Y", "sign", "-n", "git", "-f", "/tmp/key", "-O", "hashalg=sha1"}, // option the daemon would drop
+		{"-Y", "sign", "-n", "git", "-f"},                                   // missing value
+	} {
+		_, err := parseSSHSignArgs(args)
+		assert.Error(t, err, "args=%v", args)
+	}
+}
+
+func TestSSHKeyFingerprint(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not installed")
+	}
+	key := filepath.Join(t.TempDir(), "id_ed25519")
+	require.NoError(t, exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-C", "test", "-f", key).Run())
+	out, err := exec.Command("ssh-keygen", "-l", "-E", "sha256", "-f", key+".pub").Output()
+	require.NoError(t, err)
+	want := strings.Fields(string(out))[1]
+
+	got, err := SSHKeyFingerprint(key)
+	require.NoError(t, err)
+	assert.Equal(t, want, got, "private key file")
+	got, err = SSHKeyFingerprint(key + ".pub")
+	require.NoError(t, err)
+	assert.Equal(t, want, got, "public key file")
+
+	_, err = SSHKeyFingerprint(key + ".missing")
+	assert.Error(t, err)
+
+	data, err := ReadSSHKey(key + ".pub")
+	require.NoError(t, err)
+	got, err = SSHKeyDataFingerprint(data)
+	require.NoError(t, err)
+	assert.Equal(t, want, got, "key read once")
+	_, err = SSHKeyDataFingerprint([]byte("not a key"))
+	assert.Error(t, err)
+}
+
+// TestRunSSHDelegatesVerification checks that git's -Y verify calls reach the
+// real ssh-keygen with argv and stdin intact and its exit code propagated,
+// like Run's gpg pass-through.
+func TestRunSSHDelegatesVerification(t *testing.T) {
+	dir := t.TempDir()
+	capture := filepath.Join(dir, "capture")
+	script := "#!/bin/sh\n" +
+		"{ printf 'ARGS: %s\\n' \"$*\"; cat; } > '" + capture + "'\n" +
+		"exit 3\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ssh-keygen"), []byte(script), 0o755))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("XDG_STATE_HOME", filepath.Join(dir, "no-state"))
+	t.Setenv("XDG_RUNTIME_DIR", filepath.Join(dir, "no-run"))
+
+	args := []string{"-Y", "verify", "-n", "git", "-f", "allowed_signers", "-I", "me@example.com", "-s", "/tmp/sig"}
+	exit := RunSSH(args, strings.NewReader("signed payload\n"))
+	assert.Equal(t, 3, exit)
+
+	got, err := os.ReadFile(capture)
+	require.NoError(t, err, "real ssh-keygen was never called")
+	assert.Contains(t, string(got), "ARGS: -Y verify -n git")
+	assert.Contains(t, string(got), "signed payload")
+}
@@ -0,0 +1,105 @@
+package gpgsign
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/nikicat/secrets-dispatcher/internal/approval"
+)
+
+// VerifyRequest checks a signing request against the repository at dir, the
+// working directory of the calling process, instead of trusting what the
+// client reported. It confirms that dir is inside a repository whose name is
+// info.RepoName and that the objects the payload references exist there; for
+// a commit it recomputes the changed files and diff from the tree objects and
+// replaces the client's with them, recording a problem if the file lists
+// differ. Push certificates reference no objects and are not checked.
+func VerifyRequest(dir string, p SignedPayload, info *approval.GPGSignInfo) {
+	if p.Kind != KindCommit && p.Kind != KindTag {
+		return
+	}
+	v := &approval.SignVerification{}
+	info.Verification = v
+	defer func() { v.Verified = len(v.Problems) == 0 }()
+
+	if dir == "" {
+		v.Problems = append(v.Problems, "the caller's working directory is unknown")
+		return
+	}
+	top, err := runGitIn(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		v.Problems = append(v.Problems, fmt.Sprintf("the caller is not in a git repository (%s)", dir))
+		return
+	}
+	v.Repo = strings.TrimSpace(top)
+	if name := filepath.Base(v.Repo); name != info.RepoName {
+		v.Problems = append(v.Problems, fmt.Sprintf("claims repository %q but the caller is in %q", info.RepoName, name))
+	}
+
+	want := map[string]string{}
+	switch p.Kind {
+	case KindCommit:
+		want[p.Tree] = "tree"
+		if p.ParentHash != "" {
+			want[p.ParentHash] = "commit"
+		}
+	case KindTag:
+		want[p.Target] = ""
+	}
+	missing := missingObjects(v.Repo, want)
+	for _, m := range missing {
+		v.Problems = append(v.Problems, fmt.Sprintf("%s is not in %s", m, v.Repo))
+	}
+	if p.Kind != KindCommit || len(missing) > 0 {
+		return
+	}
+
+	files, err := ChangedFiles(v.Repo, p.Tree, p.ParentHash)
+	if err != nil {
+		v.Problems = append(v.Problems, fmt.Sprintf("cannot compute the commit's changed files: %v", err))
+		return
+	}
+	if !sameFiles(files, info.ChangedFiles) {
+		v.Problems = append(v.Problems, fmt.Sprintf("reported %d changed files, but the commit changes %d: %s",
+			len(info.ChangedFiles), len(files), strings.Join(files, ", ")))
+	}
+	info.ChangedFiles = files
+	if diff, err := CommitDiff(v.Repo, p.Tree, p.ParentHash); err == nil {
+		info.Diff = diff
+	}
+}
+
+// missingObjects returns a description of each object in want (hash → type,
+// "" for any type) that the repository at dir does not hold.
+func missingObjects(dir string, want map[string]string) []string {
+	var missing []string
+	for hash, typ := range want {
+		what := typ
+		if what == "" {
+			what = "object"
+		}
+		if !isObjectID(hash) {
+			missing = append(missing, fmt.Sprintf("%s %q (not an object name)", what, hash))
+			continue
+		}
+		got, err := runGitIn(dir, "cat-file", "-t", hash)
+		if err != nil || typ != "" && strings.TrimSpace(got) != typ {
+			missing = append(missing, fmt.Sprintf("%s %s", what, hash))
+		}
+	}
+	slices.Sort(missing)
+	return missing
+}
+
+// sameFiles reports whether a and b hold the same paths, in any order.
+func sameFiles(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
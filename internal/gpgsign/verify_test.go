@@ -0,0 +1,96 @@
+package gpgsign
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nikicat/secrets-dispatcher/internal/approval"
+)
+
+func TestVerifyRequest(t *testing.T) {
+	dir, git := newTestRepo(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n"), 0o644))
+	git("add", "a.go")
+	git("commit", "-q", "-m", "initial")
+	parent := git("rev-parse", "HEAD")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.go"), []byte("package a\n"), 0o644))
+	git("add", "b.go")
+	tree := git("write-tree")
+	repo := filepath.Base(dir)
+
+	commit := func(tree, parent string) SignedPayload {
+		return ParseSignedPayload([]byte("tree " + tree + "\nparent " + parent +
+			"\nauthor t <t@example.com> 1 +0000\ncommitter t <t@example.com> 1 +0000\n\nadd b\n"))
+	}
+
+	t.Run("matching commit", func(t *testing.T) {
+		info := &approval.GPGSignInfo{RepoName: repo, ChangedFiles: []string{"b.go"}}
+		VerifyRequest(filepath.Join(dir, "."), commit(tree, parent), info)
+		require.NotNil(t, info.Verification)
+		assert.True(t, info.Verification.Verified, "problems: %v", info.Verification.Problems)
+		assert.Equal(t, []string{"b.go"}, info.ChangedFiles)
+		require.NotNil(t, info.Diff)
+		assert.Contains(t, info.Diff.Patch, "+package a")
+	})
+
+	t.Run("forged changed files and diff", func(t *testing.T) {
+		info := &approval.GPGSignInfo{
+			RepoName:     repo,
+			ChangedFiles: []string{"README.md"},
+			Diff:         &approval.CommitDiff{Patch: "+harmless\n"},
+		}
+		VerifyRequest(dir, commit(tree, parent), info)
+		assert.True(t, info.Unverified())
+		assert.Contains(t, strings.Join(info.Verification.Problems, "\n"), "the commit changes 1: b.go")
+		assert.Equal(t, []string{"b.go"}, info.ChangedFiles, "replaced with the recomputed files")
+		assert.NotContains(t, info.Diff.Patch, "harmless", "replaced with the recomputed diff")
+	})
+
+	t.Run("other repository", func(t *testing.T) {
+		info := &approval.GPGSignInfo{RepoName: "dotfiles", ChangedFiles: []string{"b.go"}}
+		VerifyRequest(dir, commit(tree, parent), info)
+		assert.True(t, info.Unverified())
+		assert.Contains(t, info.Verification.Problems[0], `claims repository "dotfiles"`)
+	})
+
+	t.Run("objects not in the repository", func(t *testing.T) {
+		info := &approval.GPGSignInfo{RepoName: repo}
+		VerifyRequest(dir, commit(strings.Repeat("1", 40), "--output=/tmp/x"), info)
+		assert.True(t, info.Unverified())
+		assert.Len(t, info.Verification.Problems, 2)
+	})
+
+	t.Run("caller outside a repository", func(t *testing.T) {
+		info := &approval.GPGSignInfo{RepoName: repo}
+		VerifyRequest(t.TempDir(), commit(tree, parent), info)
+		assert.True(t, info.Unverified())
+
+		info = &approval.GPGSignInfo{RepoName: repo}
+		VerifyRequest("", commit(tree, parent), info)
+		assert.True(t, info.Unverified())
+	})
+
+	t.Run("tag", func(t *testing.T) {
+		tag := func(target string) SignedPayload {
+			return ParseSignedPayload([]byte("object " + target + "\ntype commit\ntag v1\ntagger t <t@example.com> 1 +0000\n\nv1\n"))
+		}
+		info := &approval.GPGSignInfo{RepoName: repo}
+		VerifyRequest(dir, tag(parent), info)
+		assert.True(t, info.Verification.Verified, "problems: %v", info.Verification.Problems)
+
+		info = &approval.GPGSignInfo{RepoName: repo}
+		VerifyRequest(dir, tag(strings.Repeat("2", 40)), info)
+		assert.True(t, info.Unverified())
+	})
+
+	t.Run("push certificate is not checked", func(t *testing.T) {
+		info := &approval.GPGSignInfo{RepoName: repo}
+		VerifyRequest(dir, ParseSignedPayload([]byte("certificate version 0.1\npusher t\n\n")), info)
+		assert.Nil(t, info.Verification)
+	})
+}
@@ -0,0 +1,67 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/nikicat/secrets-dispatcher/internal/approval"
+)
+
+// RuleStatsFile is a JSON-backed approval.RuleStatsStore. The whole file is
+// rewritten on every save; it holds one small record per trust rule.
+type RuleStatsFile struct {
+	path string
+}
+
+// NewRuleStatsFile returns a store backed by path. The file is created on the
+// first save.
+func NewRuleStatsFile(path string) *RuleStatsFile {
+	return &RuleStatsFile{path: path}
+}
+
+// Path returns the path of the statistics file.
+func (f *RuleStatsFile) Path() string {
+	return f.path
+}
+
+// Load reads the saved statistics; a missing file yields none.
+func (f *RuleStatsFile) Load() ([]approval.RuleStat, error) {
+	data, err := os.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var stats []approval.RuleStat
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", f.path, err)
+	}
+	return stats, nil
+}
+
+// Save replaces the file with stats, atomically via a temp file.
+func (f *RuleStatsFile) Save(stats []approval.RuleStat) error {
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), "."+filepath.Base(f.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // no-op after a successful rename
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
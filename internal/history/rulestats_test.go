@@ -0,0 +1,43 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nikicat/secrets-dispatcher/internal/approval"
+)
+
+func TestRuleStatsFile_RoundTrip(t *testing.T) {
+	f := NewRuleStatsFile(filepath.Join(t.TempDir(), "state", "rule-stats.json"))
+
+	if stats, err := f.Load(); err != nil || stats != nil {
+		t.Fatalf("Load on missing file = %v, %v; want nil, nil", stats, err)
+	}
+
+	at := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	want := []approval.RuleStat{
+		{Rule: "git", Hits: 7, LastHit: at, Since: at.Add(-time.Hour), Executables: []string{"/usr/bin/git"}},
+		{Rule: "unused", Since: at},
+	}
+	if err := f.Save(want); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	got, err := NewRuleStatsFile(f.Path()).Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(got) != 2 || got[0].Rule != "git" || got[0].Hits != 7 || !got[0].LastHit.Equal(at) ||
+		len(got[0].Executables) != 1 || !got[1].LastHit.IsZero() {
+		t.Errorf("Load = %+v, want %+v", got, want)
+	}
+
+	if err := os.WriteFile(f.Path(), []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Load(); err == nil {
+		t.Error("corrupt stats file accepted")
+	}
+}
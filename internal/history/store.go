@@ -0,0 +1,328 @@
+// Package history persists resolved approval requests as an append-only,
+// size-rotated JSON Lines log under the state directory, so the audit trail
+// survives restarts and is not limited to the in-memory history window.
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/nikicat/secrets-dispatcher/internal/approval"
+)
+
+const (
+	// DefaultMaxFileSize is the size at which the active log is rotated.
+	DefaultMaxFileSize = 10 << 20
+	// DefaultKeepFiles is the number of rotated logs kept besides the active one.
+	DefaultKeepFiles = 5
+
+	fileName = "history.jsonl"
+
+	// maxLineSize bounds a single decoded entry; gpg_sign entries carry the
+	// whole commit object, so this is well above the default scanner limit.
+	maxLineSize = 4 << 20
+)
+
+// Store is a JSONL-backed approval.HistoryStore.
+//
+// Entries are appended to <dir>/history.jsonl in resolution order. When the
+// active file exceeds maxSize it is renamed to history.jsonl.1 (shifting older
+// rotations up to history.jsonl.<keep>, dropping the oldest) and a fresh file is
+// started, bounding disk usage to roughly (keep+1)*maxSize.
+type Store struct {
+	dir     string
+	maxSize int64
+	keep    int
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+// Open opens (creating if needed) the history log in dir. maxSize <= 0 and
+// keep <= 0 select DefaultMaxFileSize and DefaultKeepFiles.
+func Open(dir string, maxSize int64, keep int) (*Store, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxFileSize
+	}
+	if keep <= 0 {
+		keep = DefaultKeepFiles
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("create history dir: %w", err)
+	}
+	s := &Store{dir: dir, maxSize: maxSize, keep: keep}
+	if err := s.openActive(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Path returns the path of the active log file.
+func (s *Store) Path() string {
+	return filepath.Join(s.dir, fileName)
+}
+
+// rotatedPath returns the path of the n-th rotated log (1 = most recent).
+func (s *Store) rotatedPath(n int) string {
+	return fmt.Sprintf("%s.%d", s.Path(), n)
+}
+
+func (s *Store) openActive() error {
+	f, err := os.OpenFile(s.Path(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("open history log: %w", err)
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("stat history log: %w", err)
+	}
+	s.f = f
+	s.size = st.Size()
+	return nil
+}
+
+// Append implements approval.HistoryStore.
+func (s *Store) Append(entry approval.HistoryEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encode history entry: %w", err)
+	}
+	data = append(data, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.f == nil {
+		return os.ErrClosed
+	}
+	if s.size > 0 && s.size+int64(len(data)) > s.maxSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := s.f.Write(data)
+	s.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("write history entry: %w", err)
+	}
+	return nil
+}
+
+// rotate shifts the rotated logs up by one and starts a fresh active file.
+// Caller must hold s.mu.
+func (s *Store) rotate() error {
+	if err := s.f.Close(); err != nil {
+		return fmt.Errorf("close history log: %w", err)
+	}
+	s.f = nil
+	os.Remove(s.rotatedPath(s.keep)) //nolint:errcheck
+	for n := s.keep - 1; n >= 1; n-- {
+		os.Rename(s.rotatedPath(n), s.rotatedPath(n+1)) //nolint:errcheck
+	}
+	if err := os.Rename(s.Path(), s.rotatedPath(1)); err != nil {
+		return fmt.Errorf("rotate history log: %w", err)
+	}
+	return s.openActive()
+}
+
+// Query implements approval.HistoryStore. It reads the active log and then
+// the rotated ones, each from its end, so the entries come out newest first
+// and the scan stops once q.Limit of them match. Lines that fail to decode
+// (e.g. a torn write after a crash) are skipped.
+//
+// The logs are opened under the writer lock, so a rotation cannot shift them
+// mid-scan, but read without it: appends go on while a query runs, and are
+// not part of its result.
+func (s *Store) Query(q approval.HistoryQuery) ([]approval.HistoryEntry, error) {
+	logs, err := s.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	defer logs.close()
+	return logs.query(q)
+}
+
+// Find implements approval.HistoryStore. Like Query it scans newest first,
+// and only decodes the lines that mention id.
+func (s *Store) Find(id string) (*approval.HistoryEntry, error) {
+	logs, err := s.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	defer logs.close()
+	return logs.find(id)
+}
+
+// snapshot opens the active and rotated logs as they are now.
+func (s *Store) snapshot() (logFiles, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return openLogs(s.dir, s.keep)
+}
+
+// Read queries the history log in dir like Store.Query, without opening it for
+// writing. It lets other commands inspect the history of a running service.
+func Read(dir string, q approval.HistoryQuery) ([]approval.HistoryEntry, error) {
+	logs, err := openLogs(dir, DefaultKeepFiles)
+	if err != nil {
+		return nil, err
+	}
+	defer logs.close()
+	return logs.query(q)
+}
+
+// logFile is an open log and the size it had when opened; anything appended
+// later is not read.
+type logFile struct {
+	f    *os.File
+	size int64
+}
+
+// logFiles are the logs of a history directory, newest first.
+type logFiles []logFile
+
+// openLogs opens the active log and up to keep rotated ones in dir, newest
+// first. Missing files are left out.
+func openLogs(dir string, keep int) (logFiles, error) {
+	active := filepath.Join(dir, fileName)
+	var logs logFiles
+	for n := 0; n <= keep; n++ {
+		name := active
+		if n > 0 {
+			name = fmt.Sprintf("%s.%d", active, n)
+		}
+		f, err := os.Open(name)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			logs.close()
+			return nil, fmt.Errorf("open history log: %w", err)
+		}
+		st, err := f.Stat()
+		if err != nil {
+			f.Close()
+			logs.close()
+			return nil, fmt.Errorf("stat history log: %w", err)
+		}
+		logs = append(logs, logFile{f: f, size: st.Size()})
+	}
+	return logs, nil
+}
+
+func (logs logFiles) close() {
+	for _, l := range logs {
+		l.f.Close()
+	}
+}
+
+// query returns the entries within q's range, newest first, up to q.Limit.
+func (logs logFiles) query(q approval.HistoryQuery) ([]approval.HistoryEntry, error) {
+	var out []approval.HistoryEntry
+	err := logs.scan(func(line []byte) bool {
+		entry, ok := decodeEntry(line)
+		if ok && q.Contains(entry.ResolvedAt) {
+			out = append(out, entry)
+		}
+		return q.Limit > 0 && len(out) >= q.Limit
+	})
+	return out, err
+}
+
+// find returns the newest entry for request id, or nil.
+func (logs logFiles) find(id string) (*approval.HistoryEntry, error) {
+	quoted, err := json.Marshal(id)
+	if err != nil {
+		return nil, err
+	}
+	var found *approval.HistoryEntry
+	err = logs.scan(func(line []byte) bool {
+		if !bytes.Contains(line, quoted) {
+			return false
+		}
+		if entry, ok := decodeEntry(line); ok && entry.Request.ID == id {
+			found = &entry
+			return true
+		}
+		return false
+	})
+	return found, err
+}
+
+// scan calls fn with the lines of the logs, newest first, until it returns
+// true.
+func (logs logFiles) scan(fn func(line []byte) (stop bool)) error {
+	for _, l := range logs {
+		stopped, err := scanBackward(l.f, l.size, fn)
+		if err != nil {
+			return fmt.Errorf("read history log %s: %w", l.f.Name(), err)
+		}
+		if stopped {
+			return nil
+		}
+	}
+	return nil
+}
+
+// scanBackward calls fn with the non-empty lines of the first size bytes of
+// r, last line first, until it returns true. It reports whether fn stopped
+// the scan. A line longer than maxLineSize is an error.
+func scanBackward(r io.ReaderAt, size int64, fn func(line []byte) (stop bool)) (stopped bool, err error) {
+	const chunkSize = 64 << 10
+	var partial []byte // the end of a line whose start has not been read yet
+	for off := size; off > 0; {
+		n := min(chunkSize, off)
+		off -= n
+		buf := make([]byte, n, n+int64(len(partial)))
+		if _, err := r.ReadAt(buf, off); err != nil && !errors.Is(err, io.EOF) {
+			return false, err
+		}
+		buf = append(buf, partial...)
+		for {
+			i := bytes.LastIndexByte(buf, '\n')
+			if i < 0 {
+				break
+			}
+			if line := buf[i+1:]; len(line) > 0 && fn(line) {
+				return true, nil
+			}
+			buf = buf[:i]
+		}
+		if len(buf) > maxLineSize {
+			return false, bufio.ErrTooLong
+		}
+		partial = buf
+	}
+	return len(partial) > 0 && fn(partial), nil
+}
+
+// decodeEntry decodes one log line. ok is false for a malformed line.
+func decodeEntry(line []byte) (entry approval.HistoryEntry, ok bool) {
+	if err := json.Unmarshal(line, &entry); err != nil || entry.Request == nil {
+		slog.Debug("skipping malformed history line", "error", err)
+		return approval.HistoryEntry{}, false
+	}
+	return entry, true
+}
+
+// Close closes the active log file.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
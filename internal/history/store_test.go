@@ -0,0 +1,288 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nikicat/secrets-dispatcher/internal/approval"
+)
+
+func entryAt(id string, t time.Time) approval.HistoryEntry {
+	return approval.HistoryEntry{
+		Request: &approval.Request{
+			ID:     id,
+			Client: "test-client",
+			Type:   approval.RequestTypeGetSecret,
+			Items:  []approval.ItemInfo{{Path: "/org/freedesktop/secrets/collection/default/1", Label: "item"}},
+		},
+		Resolution: approval.ResolutionApproved,
+		ResolvedAt: t,
+	}
+}
+
+func TestStore_AppendAndQuery(t *testing.T) {
+	s, err := Open(t.TempDir(), 0, 0)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := range 5 {
+		if err := s.Append(entryAt(fmt.Sprintf("req-%d", i), base.Add(time.Duration(i)*time.Hour))); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+
+	all, err := s.Query(approval.HistoryQuery{})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(all) != 5 {
+		t.Fatalf("got %d entries, want 5", len(all))
+	}
+	if all[0].Request.ID != "req-4" || all[4].Request.ID != "req-0" {
+		t.Errorf("entries not newest first: %s .. %s", all[0].Request.ID, all[4].Request.ID)
+	}
+
+	// [1h, 3h) selects req-1 and req-2.
+	page, err := s.Query(approval.HistoryQuery{Since: base.Add(time.Hour), Until: base.Add(3 * time.Hour)})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(page) != 2 || page[0].Request.ID != "req-2" || page[1].Request.ID != "req-1" {
+		t.Errorf("range query = %v, want [req-2 req-1]", ids(page))
+	}
+
+	limited, err := s.Query(approval.HistoryQuery{Limit: 2})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(limited) != 2 || limited[0].Request.ID != "req-4" {
+		t.Errorf("limited query = %v, want [req-4 req-3]", ids(limited))
+	}
+}
+
+func TestStore_ReopenKeepsEntries(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := s.Append(entryAt("persisted", time.Now())); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	s.Close()
+
+	s, err = Open(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer s.Close()
+	got, err := s.Query(approval.HistoryQuery{})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(got) != 1 || got[0].Request.ID != "persisted" {
+		t.Errorf("after reopen got %v, want [persisted]", ids(got))
+	}
+}
+
+func TestRead(t *testing.T) {
+	dir := t.TempDir()
+	if got, err := Read(dir, approval.HistoryQuery{}); err != nil || len(got) != 0 {
+		t.Fatalf("Read of empty dir = %v, %v; want no entries", ids(got), err)
+	}
+
+	s, err := Open(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+	base := time.Now()
+	for i := range 3 {
+		if err := s.Append(entryAt(fmt.Sprintf("req-%d", i), base.Add(time.Duration(i)*time.Second))); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+
+	// Reading alongside the open store sees what it wrote.
+	got, err := Read(dir, approval.HistoryQuery{Limit: 2})
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(got) != 2 || got[0].Request.ID != "req-2" || got[1].Request.ID != "req-1" {
+		t.Errorf("Read = %v, want [req-2 req-1]", ids(got))
+	}
+}
+
+func TestStore_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	// Tiny max size so every append rotates; keep two rotations.
+	s, err := Open(dir, 1, 2)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+
+	base := time.Now()
+	for i := range 5 {
+		if err := s.Append(entryAt(fmt.Sprintf("req-%d", i), base.Add(time.Duration(i)*time.Second))); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+
+	if _, err := os.Stat(s.rotatedPath(3)); !os.IsNotExist(err) {
+		t.Errorf("rotation beyond keep limit should not exist, stat err = %v", err)
+	}
+	got, err := s.Query(approval.HistoryQuery{})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	// Active + 2 rotated files, one entry each: the three newest survive.
+	if want := []string{"req-4", "req-3", "req-2"}; fmt.Sprint(ids(got)) != fmt.Sprint(want) {
+		t.Errorf("after rotation got %v, want %v", ids(got), want)
+	}
+
+	if e, err := s.Find("req-3"); err != nil || e == nil || e.Request.ID != "req-3" {
+		t.Errorf("Find(req-3) = %v, %v; want the rotated entry", e, err)
+	}
+	if e, err := s.Find("req-0"); err != nil || e != nil {
+		t.Errorf("Find(req-0) = %v, %v; want nil for a dropped entry", e, err)
+	}
+}
+
+func TestStore_LongLines(t *testing.T) {
+	s, err := Open(t.TempDir(), 0, 0)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+
+	// Lines longer than a read chunk, as gpg_sign entries with their commit
+	// objects are, come back whole and in order.
+	base := time.Now()
+	for i := range 3 {
+		e := entryAt(fmt.Sprintf("req-%d", i), base.Add(time.Duration(i)*time.Second))
+		e.Request.Items[0].Label = strings.Repeat(strconv.Itoa(i), 100<<10)
+		if err := s.Append(e); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	got, err := s.Query(approval.HistoryQuery{})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if want := []string{"req-2", "req-1", "req-0"}; fmt.Sprint(ids(got)) != fmt.Sprint(want) {
+		t.Fatalf("got %v, want %v", ids(got), want)
+	}
+	for i, e := range got {
+		if label := e.Request.Items[0].Label; label != strings.Repeat(strconv.Itoa(2-i), 100<<10) {
+			t.Errorf("%s: label of %d bytes mangled", e.Request.ID, len(label))
+		}
+	}
+}
+
+func TestStore_QueryStopsAtLimit(t *testing.T) {
+	s, err := Open(t.TempDir(), 0, 0)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+
+	// An older log that cannot be read is not reached by queries the newer
+	// entries already satisfy.
+	if err := os.WriteFile(s.rotatedPath(1), []byte(strings.Repeat("x", maxLineSize+1)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	base := time.Now()
+	for i := range 2 {
+		if err := s.Append(entryAt(fmt.Sprintf("req-%d", i), base.Add(time.Duration(i)*time.Second))); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+
+	if got, err := s.Query(approval.HistoryQuery{Limit: 2}); err != nil || len(got) != 2 {
+		t.Errorf("Query(limit 2) = %v, %v; want the two newest", ids(got), err)
+	}
+	if e, err := s.Find("req-0"); err != nil || e == nil {
+		t.Errorf("Find(req-0) = %v, %v; want the entry", e, err)
+	}
+	if _, err := s.Query(approval.HistoryQuery{}); err == nil {
+		t.Error("Query without a limit should read the older log and fail")
+	}
+}
+
+func TestStore_SkipsMalformedLines(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+	if err := s.Append(entryAt("good", time.Now())); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	// Simulate a torn write from a crash.
+	f, err := os.OpenFile(s.Path(), os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"request":{"id":"tor` + "\n")
+	f.Close()
+
+	got, err := s.Query(approval.HistoryQuery{})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(got) != 1 || got[0].Request.ID != "good" {
+		t.Errorf("got %v, want [good]", ids(got))
+	}
+}
+
+func TestManager_LoadsPersistedHistory(t *testing.T) {
+	s, err := Open(t.TempDir(), 0, 0)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+	base := time.Now()
+	for i := range 3 {
+		s.Append(entryAt(fmt.Sprintf("req-%d", i), base.Add(time.Duration(i)*time.Second)))
+	}
+
+	mgr := approval.NewManager(approval.ManagerConfig{Timeout: time.Minute, HistoryMax: 2, HistoryStore: s})
+	hist := mgr.History()
+	if len(hist) != 2 || hist[0].Request.ID != "req-2" {
+		t.Errorf("in-memory history = %v, want [req-2 req-1]", ids(hist))
+	}
+	if req, err := mgr.LookupRequest("req-0"); err != nil || req.ID != "req-0" {
+		t.Errorf("LookupRequest beyond the in-memory window = %v, %v", req, err)
+	}
+	if _, err := mgr.LookupRequest("nope"); !errors.Is(err, approval.ErrNotFound) {
+		t.Errorf("LookupRequest(nope) error = %v, want ErrNotFound", err)
+	}
+
+	// New resolutions are written through to the store.
+	mgr.RecordPassthrough("c", nil, "", approval.RequestTypeSearch, nil, approval.SenderInfo{})
+	all, err := mgr.QueryHistory(approval.HistoryQuery{})
+	if err != nil {
+		t.Fatalf("QueryHistory: %v", err)
+	}
+	if len(all) != 4 {
+		t.Errorf("store has %d entries, want 4", len(all))
+	}
+}
+
+func ids(entries []approval.HistoryEntry) []string {
+	out := make([]string, len(entries))
+	for i, e := range entries {
+		out[i] = e.Request.ID
+	}
+	return out
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"log/slog"
 	"os/exec"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -37,6 +38,8 @@ type Approver interface {
 	Deny(id string) error
 	AutoApprove(requestID string) error
 	ApproveAndAutoApprove(id string) error
+	ApproveSeries(id string) error
+	DenyAndMute(id string) error
 }
 
 // Action represents a user interaction with a notification button.
@@ -438,8 +441,12 @@ func (h *Handler) handleAction(action Action) {
 		err = h.approver.Approve(reqID)
 	case "approve_and_auto_approve":
 		err = h.approver.ApproveAndAutoApprove(reqID)
+	case "approve_series":
+		err = h.approver.ApproveSeries(reqID)
 	case "deny":
 		err = h.approver.Deny(reqID)
+	case "mute":
+		err = h.approver.DenyAndMute(reqID)
 	case "auto_approve":
 		err = h.approver.AutoApprove(reqID)
 	case "dismiss":
@@ -487,6 +494,22 @@ func (h *Handler) notificationMeta(req *approval.Request) (summary, icon string)
 		return "Secret write requested", "dialog-warning"
 	case approval.RequestTypeSSHSign:
 		return "SSH key requested", "dialog-password"
+	case approval.RequestTypeSSHAdd:
+		return "SSH key add requested", "dialog-warning"
+	case approval.RequestTypeSSHRemove:
+		return "SSH key removal requested", "dialog-warning"
+	case approval.RequestTypeSSHLock:
+		return "SSH agent lock requested", "dialog-warning"
+	case approval.RequestTypeSSHExtension:
+		return "SSH agent extension requested", "dialog-warning"
+	case approval.RequestTypePGPDecrypt:
+		return "PGP decryption requested", "dialog-password"
+	case approval.RequestTypePGPSign:
+		return "PGP signature requested", "dialog-password"
+	case approval.RequestTypePGPExport:
+		return "PGP secret key export requested", "dialog-warning"
+	case approval.RequestTypePair:
+		return "Pair new client", "security-high"
 	default:
 		return "Secret requested", "dialog-password"
 	}
@@ -527,6 +550,19 @@ func (h *Handler) sendNotification(req *approval.Request) {
 		"approve", "Approve",
 		"approve_and_auto_approve", "Approve " + durLabel,
 		"deny", "Deny",
+		"mute", "Mute " + formatDurationShort(approval.DefaultDenyRuleDuration),
+	}
+	if req.Type == approval.RequestTypePair {
+		// A pairing is never "similar" to another; there is nothing to
+		// auto-approve or mute.
+		actions = slices.Delete(actions, 8, 10)
+		actions = slices.Delete(actions, 4, 6)
+	}
+	if grant, err := approval.NewSeriesGrant(req); err == nil {
+		// For a rebase or cherry-pick the rest of the series is what the user
+		// wants approved, not similar requests for a while.
+		actions[4] = "approve_series"
+		actions[5] = fmt.Sprintf("Approve %s (+%d)", grant.Operation, len(grant.Commits))
 	}
 
 	id, err := h.notifier.Notify(summary, body, icon, actions)
@@ -646,6 +682,15 @@ func commitSubject(msg string) string {
 	return msg
 }
 
+// diffStat summarizes a signed commit's diff as "N files, +A −D".
+func diffStat(d *approval.CommitDiff) string {
+	files := "1 file"
+	if d.TotalFiles != 1 {
+		files = fmt.Sprintf("%d files", d.TotalFiles)
+	}
+	return fmt.Sprintf("%s, +%d −%d", files, d.Additions, d.Deletions)
+}
+
 func (h *Handler) formatBody(req *approval.Request) string {
 	var b strings.Builder
 
@@ -685,8 +730,24 @@ func (h *Handler) formatBody(req *approval.Request) string {
 	case approval.RequestTypeGPGSign:
 		if req.GPGSignInfo != nil {
 			fmt.Fprintf(&b, "<b>%s</b>: <i>%s</i>", esc(req.GPGSignInfo.RepoName), esc(commitSubject(req.GPGSignInfo.CommitMsg)))
+			if d := req.GPGSignInfo.Diff; d != nil {
+				b.WriteString("\n" + diffStat(d))
+			}
+			if req.GPGSignInfo.Unverified() {
+				b.WriteString("\n<b>⚠ Does not match the caller's repository</b>")
+			}
+			if sr := req.GPGSignInfo.Series; sr != nil {
+				fmt.Fprintf(&b, "\n%s: commit %d of %d", sr.Operation, sr.Position, sr.Total)
+				if len(sr.Steps) > 0 {
+					fmt.Fprintf(&b, "\nAlso runs: <i>%s</i>", esc(strings.Join(sr.Steps, "; ")))
+				}
+			}
 			writeChain(req.SenderInfo.ProcessChain)
 		}
+	case approval.RequestTypePair:
+		if req.PairInfo != nil {
+			fmt.Fprintf(&b, "<b>%s</b> code <b>%s</b>\nApprove only if the remote shows the same code", esc(req.Client), esc(req.PairInfo.Code))
+		}
 	case approval.RequestTypeSSHSign:
 		// Show key label and destination
 		if len(req.Items) > 0 {
@@ -696,6 +757,23 @@ func (h *Handler) formatBody(req *approval.Request) string {
 			}
 		}
 		writeChain(req.SenderInfo.ProcessChain)
+	case approval.RequestTypeSSHAdd, approval.RequestTypeSSHRemove,
+		approval.RequestTypeSSHLock, approval.RequestTypeSSHExtension:
+		if len(req.Items) == 1 {
+			fmt.Fprintf(&b, "<b>%s</b>", esc(req.Items[0].Label))
+		} else {
+			fmt.Fprintf(&b, "<b>%d keys</b>", len(req.Items))
+		}
+		writeChain(req.SenderInfo.ProcessChain)
+	case approval.RequestTypePGPDecrypt, approval.RequestTypePGPSign, approval.RequestTypePGPExport:
+		// Show the key's user ID and key ID
+		if len(req.Items) > 0 {
+			fmt.Fprintf(&b, "<b>%s</b>", esc(req.Items[0].Label))
+			if keyID := req.Items[0].Attributes["key_id"]; keyID != "" {
+				fmt.Fprintf(&b, " (%s)", esc(keyID))
+			}
+		}
+		writeChain(req.SenderInfo.ProcessChain)
 	default:
 		if len(req.SenderInfo.ProcessChain) > 0 {
 			// New format: item label, then process chain (parent → child order)
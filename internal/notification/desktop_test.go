@@ -137,6 +137,26 @@ func (a *mockApprover) ApproveAndAutoApprove(id string) error {
 	return nil
 }
 
+func (a *mockApprover) ApproveSeries(id string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.err != nil {
+		return a.err
+	}
+	a.approved = append(a.approved, "series:"+id)
+	return nil
+}
+
+func (a *mockApprover) DenyAndMute(id string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.err != nil {
+		return a.err
+	}
+	a.denied = append(a.denied, "mute:"+id)
+	return nil
+}
+
 func newTestHandler() (*Handler, *mockNotifier, *mockApprover) {
 	mock := &mockNotifier{}
 	approver := &mockApprover{}
@@ -192,7 +212,7 @@ func TestHandler_OnEvent_RequestCreated_Actions(t *testing.T) {
 	h.OnEvent(approval.Event{Type: approval.EventRequestCreated, Request: req})
 
 	call := mock.lastNotify()
-	wantActions := []string{"default", "", "approve", "Approve", "approve_and_auto_approve", "Approve 2m", "deny", "Deny"}
+	wantActions := []string{"default", "", "approve", "Approve", "approve_and_auto_approve", "Approve 2m", "deny", "Deny", "mute", "Mute 15m"}
 	if len(call.actions) != len(wantActions) {
 		t.Fatalf("expected %d actions, got %d: %v", len(wantActions), len(call.actions), call.actions)
 	}
@@ -389,6 +409,90 @@ func TestHandler_OnEvent_GPGSignRequest(t *testing.T) {
 	}
 }
 
+func TestHandler_FormatBody_GPGSign_DiffStat(t *testing.T) {
+	h, mock, _ := newTestHandler()
+
+	req := &approval.Request{
+		ID:   "gpg-diff-1",
+		Type: approval.RequestTypeGPGSign,
+		GPGSignInfo: &approval.GPGSignInfo{
+			RepoName:  "my-project",
+			CommitMsg: "Add feature",
+			Diff:      &approval.CommitDiff{TotalFiles: 3, Additions: 42, Deletions: 7},
+		},
+		SenderInfo: approval.SenderInfo{PID: 1234},
+	}
+
+	h.OnEvent(approval.Event{Type: approval.EventRequestCreated, Request: req})
+
+	if call := mock.lastNotify(); !contains(call.body, "<i>Add feature</i>\n3 files, +42 −7") {
+		t.Errorf("body should summarize the diff under the subject: %s", call.body)
+	}
+}
+
+func TestHandler_FormatBody_GPGSign_Unverified(t *testing.T) {
+	h, mock, _ := newTestHandler()
+
+	req := &approval.Request{
+		ID:   "gpg-unverified-1",
+		Type: approval.RequestTypeGPGSign,
+		GPGSignInfo: &approval.GPGSignInfo{
+			RepoName:     "my-project",
+			CommitMsg:    "Add feature",
+			Verification: &approval.SignVerification{Problems: []string{"tree 1111 is not in /src/my-project"}},
+		},
+		SenderInfo: approval.SenderInfo{PID: 1234},
+	}
+
+	h.OnEvent(approval.Event{Type: approval.EventRequestCreated, Request: req})
+
+	if call := mock.lastNotify(); !contains(call.body, "Does not match the caller's repository") {
+		t.Errorf("body should flag the unverified request: %s", call.body)
+	}
+}
+
+func TestHandler_OnEvent_GPGSignSeries(t *testing.T) {
+	h, mock, approver := newTestHandler()
+
+	req := &approval.Request{
+		ID:   "gpg-series-1",
+		Type: approval.RequestTypeGPGSign,
+		GPGSignInfo: &approval.GPGSignInfo{
+			RepoName:     "my-project",
+			CommitMsg:    "Add feature",
+			Verification: &approval.SignVerification{Repo: "/src/my-project", Verified: true},
+			Series: &approval.SignSeries{Operation: "rebase", Position: 2, Total: 5, Commits: []approval.SeriesCommit{
+				{Subject: "one"}, {Subject: "two"}, {Subject: "three"}, {Subject: "four"}, {Subject: "five"},
+			}, Steps: []string{"exec make test"}},
+		},
+		SenderInfo: approval.SenderInfo{PID: 1234, ProcessChain: []approval.ProcessInfo{
+			{Name: "secrets-dispatcher", PID: 1234, StartTime: 10},
+			{Name: "git", PID: 1200, StartTime: 9},
+		}},
+	}
+
+	h.OnEvent(approval.Event{Type: approval.EventRequestCreated, Request: req})
+
+	call := mock.lastNotify()
+	if !contains(call.body, "rebase: commit 2 of 5") {
+		t.Errorf("body should show the series position: %s", call.body)
+	}
+	if !contains(call.body, "exec make test") {
+		t.Errorf("body should show the todo lines that create no commit: %s", call.body)
+	}
+	if call.actions[4] != "approve_series" || call.actions[5] != "Approve rebase (+3)" {
+		t.Fatalf("expected the series approval in place of the timed one, got %v", call.actions)
+	}
+
+	h.mu.Lock()
+	nID := h.notifications["gpg-series-1"]
+	h.mu.Unlock()
+	h.handleAction(Action{NotificationID: nID, ActionKey: "approve_series"})
+	if len(approver.approved) != 1 || approver.approved[0] != "series:gpg-series-1" {
+		t.Errorf("approved = %v, want the series approval", approver.approved)
+	}
+}
+
 func TestHandler_FormatBody_GPGSign_PIDOnly(t *testing.T) {
 	h, mock, _ := newTestHandler()
 
@@ -677,6 +781,42 @@ func TestHandler_ListenActions_Deny(t *testing.T) {
 	}
 }
 
+func TestHandler_ListenActions_Mute(t *testing.T) {
+	h, _, approver := newTestHandler()
+
+	req := &approval.Request{
+		ID:     "action-mute-1",
+		Client: "user@host",
+		Type:   approval.RequestTypeGetSecret,
+		Items:  []approval.ItemInfo{{Label: "Secret"}},
+	}
+	h.OnEvent(approval.Event{Type: approval.EventRequestCreated, Request: req})
+
+	h.mu.Lock()
+	nID := h.notifications["action-mute-1"]
+	h.mu.Unlock()
+
+	actions := make(chan Action, 1)
+	actions <- Action{NotificationID: nID, ActionKey: "mute"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		h.ListenActions(ctx, actions)
+		close(done)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	<-done
+
+	approver.mu.Lock()
+	defer approver.mu.Unlock()
+	if len(approver.denied) != 1 || approver.denied[0] != "mute:action-mute-1" {
+		t.Errorf("expected mute for 'action-mute-1', got %v", approver.denied)
+	}
+}
+
 func TestHandler_ListenActions_DefaultOpensURL(t *testing.T) {
 	h, _, approver := newTestHandler()
 
@@ -0,0 +1,96 @@
+// Package pairing binds remote socket downstreams to a host identity.
+//
+// A remote host pairs once: it holds an Ed25519 identity key and runs a
+// commit-then-reveal handshake with the dispatcher over its own session bus,
+// after which both ends show the same six-digit code (a short authentication
+// string). The user compares the codes and approves the request; the paired
+// client name and public key are stored in clients.yaml. On every later
+// connection the dispatcher challenges the remote host to sign a fresh nonce
+// with that key before offering the Secret Service on the socket.
+//
+// The commitment stops a man in the middle from choosing its nonce after
+// seeing the remote's, so it cannot steer both ends to the same code; with six
+// digits an undetected substitution succeeds with probability 10⁻⁶ per attempt.
+package pairing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// D-Bus names for the two halves of the protocol. The dispatcher owns BusName
+// on every socket bus it serves and exports the pairing interface there; the
+// remote host's responder (started by D-Bus activation) owns ResponderBusName.
+const (
+	BusName    = "io.github.nikicat.SecretsDispatcher1"
+	ObjectPath = dbus.ObjectPath("/io/github/nikicat/SecretsDispatcher1")
+	Interface  = "io.github.nikicat.SecretsDispatcher1.Pairing"
+
+	ResponderBusName   = "io.github.nikicat.SecretsDispatcher1.Client"
+	ResponderPath      = dbus.ObjectPath("/io/github/nikicat/SecretsDispatcher1/Client")
+	ResponderInterface = "io.github.nikicat.SecretsDispatcher1.Client"
+)
+
+// NonceSize is the length of the handshake and challenge nonces.
+const NonceSize = 32
+
+// Domain-separation prefixes, so no hash or signature from one step can be
+// replayed as another.
+const (
+	commitDomain = "secrets-dispatcher pair commit v1\x00"
+	codeDomain   = "secrets-dispatcher pair code v1\x00"
+	proofDomain  = "secrets-dispatcher client proof v1\x00"
+)
+
+// NewNonce returns NonceSize random bytes.
+func NewNonce() ([]byte, error) {
+	n := make([]byte, NonceSize)
+	if _, err := rand.Read(n); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// Commit returns the commitment the remote host sends before it learns the
+// dispatcher's nonce: a hash binding its identity key to its own nonce.
+func Commit(identity ed25519.PublicKey, nonce []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte(commitDomain))
+	h.Write(identity)
+	h.Write(nonce)
+	return h.Sum(nil)
+}
+
+// Code derives the six-digit comparison code, formatted "123 456", from the
+// remote identity and both nonces.
+func Code(identity ed25519.PublicKey, remoteNonce, localNonce []byte) string {
+	h := sha256.New()
+	h.Write([]byte(codeDomain))
+	h.Write(identity)
+	h.Write(remoteNonce)
+	h.Write(localNonce)
+	n := binary.BigEndian.Uint32(h.Sum(nil)) % 1_000_000
+	return fmt.Sprintf("%03d %03d", n/1000, n%1000)
+}
+
+// Fingerprint formats a public key the way OpenSSH does (SHA256:base64).
+func Fingerprint(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// Prove signs a dispatcher challenge with the identity key.
+func Prove(key ed25519.PrivateKey, nonce []byte) []byte {
+	return ed25519.Sign(key, append([]byte(proofDomain), nonce...))
+}
+
+// Verify checks a proof produced by Prove.
+func Verify(pub ed25519.PublicKey, nonce, sig []byte) bool {
+	return len(pub) == ed25519.PublicKeySize && ed25519.Verify(pub, append([]byte(proofDomain), nonce...), sig)
+}
@@ -0,0 +1,94 @@
+package pairing
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"regexp"
+	"testing"
+)
+
+func newKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	return pub, key
+}
+
+func mustNonce(t *testing.T) []byte {
+	t.Helper()
+	n, err := NewNonce()
+	if err != nil {
+		t.Fatalf("nonce: %v", err)
+	}
+	return n
+}
+
+func TestCode_FormatAndDeterminism(t *testing.T) {
+	pub, _ := newKey(t)
+	remote, local := mustNonce(t), mustNonce(t)
+
+	code := Code(pub, remote, local)
+	if !regexp.MustCompile(`^\d{3} \d{3}$`).MatchString(code) {
+		t.Fatalf("code %q is not formatted as six digits", code)
+	}
+	if again := Code(pub, remote, local); again != code {
+		t.Errorf("code not deterministic: %q then %q", code, again)
+	}
+	// Swapping the nonces must change the code; otherwise a man in the middle
+	// could reflect one side's nonce back to it.
+	if swapped := Code(pub, local, remote); swapped == code {
+		t.Errorf("code unchanged with nonces swapped: %q", code)
+	}
+}
+
+func TestCommit_BindsIdentityAndNonce(t *testing.T) {
+	pub, _ := newKey(t)
+	other, _ := newKey(t)
+	nonce := mustNonce(t)
+
+	c := Commit(pub, nonce)
+	if !bytes.Equal(c, Commit(pub, nonce)) {
+		t.Fatal("commitment not deterministic")
+	}
+	if bytes.Equal(c, Commit(other, nonce)) {
+		t.Error("commitment does not depend on the identity")
+	}
+	if bytes.Equal(c, Commit(pub, mustNonce(t))) {
+		t.Error("commitment does not depend on the nonce")
+	}
+}
+
+func TestProveVerify(t *testing.T) {
+	pub, key := newKey(t)
+	other, _ := newKey(t)
+	nonce := mustNonce(t)
+
+	sig := Prove(key, nonce)
+	if !Verify(pub, nonce, sig) {
+		t.Fatal("valid proof rejected")
+	}
+	if Verify(other, nonce, sig) {
+		t.Error("proof accepted for a different key")
+	}
+	if Verify(pub, mustNonce(t), sig) {
+		t.Error("proof accepted for a different nonce")
+	}
+	if Verify(pub[:10], nonce, sig) {
+		t.Error("proof accepted for a truncated key")
+	}
+	// A plain signature over the nonce must not pass as a proof.
+	if Verify(pub, nonce, ed25519.Sign(key, nonce)) {
+		t.Error("proof accepted without the domain prefix")
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	pub, _ := newKey(t)
+	fp := Fingerprint(pub)
+	if !regexp.MustCompile(`^SHA256:[A-Za-z0-9+/]{43}$`).MatchString(fp) {
+		t.Errorf("unexpected fingerprint format %q", fp)
+	}
+}
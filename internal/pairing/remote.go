@@ -0,0 +1,115 @@
+package pairing
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Pair runs the remote side of the handshake over conn, the remote host's
+// session bus, where the dispatcher owns BusName. showCode is called with the
+// comparison code before Pair blocks waiting for the user's decision on the
+// dispatcher side; Pair returns nil once the pairing is approved.
+func Pair(ctx context.Context, conn *dbus.Conn, key ed25519.PrivateKey, showCode func(code string)) error {
+	pub := key.Public().(ed25519.PublicKey)
+	nonce, err := NewNonce()
+	if err != nil {
+		return err
+	}
+
+	obj := conn.Object(BusName, ObjectPath)
+	var session string
+	var dispatcherNonce []byte
+	if err := obj.CallWithContext(ctx, Interface+".Begin", 0, []byte(pub), Commit(pub, nonce)).Store(&session, &dispatcherNonce); err != nil {
+		return fmt.Errorf("begin pairing (is secrets-dispatcher serving this bus?): %w", err)
+	}
+	if len(dispatcherNonce) != NonceSize {
+		return fmt.Errorf("begin pairing: dispatcher sent a %d-byte nonce", len(dispatcherNonce))
+	}
+
+	showCode(Code(pub, nonce, dispatcherNonce))
+
+	if err := obj.CallWithContext(ctx, Interface+".Confirm", 0, session, nonce).Err; err != nil {
+		return fmt.Errorf("pairing not approved: %w", err)
+	}
+	return nil
+}
+
+// responder answers the dispatcher's per-connection challenge.
+type responder struct {
+	key      ed25519.PrivateKey
+	mu       sync.Mutex
+	lastCall time.Time
+}
+
+// Prove implements the ResponderInterface method of the same name.
+func (r *responder) Prove(nonce []byte) ([]byte, *dbus.Error) {
+	r.mu.Lock()
+	r.lastCall = time.Now()
+	r.mu.Unlock()
+	if len(nonce) != NonceSize {
+		return nil, dbus.MakeFailedError(fmt.Errorf("nonce must be %d bytes", NonceSize))
+	}
+	return Prove(r.key, nonce), nil
+}
+
+// Respond owns ResponderBusName on conn and answers challenges until ctx is
+// done or no challenge has arrived for idle. It is what the D-Bus activation
+// file installed by InstallActivation starts.
+func Respond(ctx context.Context, conn *dbus.Conn, key ed25519.PrivateKey, idle time.Duration) error {
+	r := &responder{key: key, lastCall: time.Now()}
+	if err := conn.Export(r, ResponderPath, ResponderInterface); err != nil {
+		return fmt.Errorf("export responder: %w", err)
+	}
+	reply, err := conn.RequestName(ResponderBusName, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return fmt.Errorf("request bus name: %w", err)
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		return fmt.Errorf("%s is already owned", ResponderBusName)
+	}
+
+	ticker := time.NewTicker(idle / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			r.mu.Lock()
+			last := r.lastCall
+			r.mu.Unlock()
+			if time.Since(last) >= idle {
+				return nil
+			}
+		}
+	}
+}
+
+// ActivationPath returns where the session bus looks for the responder's
+// activation file: $XDG_DATA_HOME/dbus-1/services/<ResponderBusName>.service.
+func ActivationPath() string {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, _ := os.UserHomeDir()
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataHome, "dbus-1", "services", ResponderBusName+".service")
+}
+
+// InstallActivation writes the D-Bus activation file that starts
+// `<exe> pair --respond` when the dispatcher challenges this host.
+func InstallActivation(exe string) (string, error) {
+	path := ActivationPath()
+	content := fmt.Sprintf("[D-BUS Service]\nName=%s\nExec=%s pair --respond\n", ResponderBusName, exe)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, []byte(content), 0o644)
+}
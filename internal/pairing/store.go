@@ -0,0 +1,168 @@
+package pairing
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrNotPaired is returned for a client name with no paired key.
+var ErrNotPaired = errors.New("client not paired")
+
+// Client is a paired remote host.
+type Client struct {
+	Name      string    `yaml:"name" json:"name"`             // downstream client name (socket name without .sock)
+	PublicKey string    `yaml:"public_key" json:"public_key"` // base64 Ed25519 identity key
+	PairedAt  time.Time `yaml:"paired_at" json:"paired_at"`
+}
+
+// Key decodes the client's public key.
+func (c *Client) Key() (ed25519.PublicKey, error) {
+	b, err := base64.StdEncoding.DecodeString(c.PublicKey)
+	if err != nil || len(b) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("client %s: invalid public key", c.Name)
+	}
+	return ed25519.PublicKey(b), nil
+}
+
+// Fingerprint returns the OpenSSH-style fingerprint of the client's key, or ""
+// if the key is malformed.
+func (c *Client) Fingerprint() string {
+	key, err := c.Key()
+	if err != nil {
+		return ""
+	}
+	return Fingerprint(key)
+}
+
+type storeFile struct {
+	Clients []Client `yaml:"clients"`
+}
+
+// Store is the clients.yaml file of paired clients. It is re-read on every
+// call, so edits by `clients remove` (or by hand) apply to the next connection
+// without a reload.
+type Store struct {
+	path string
+	mu   sync.Mutex // serialises read-modify-write within this process
+}
+
+// NewStore returns a store backed by the file at path, which need not exist.
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Path returns the backing file path.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// List returns all paired clients in file order.
+func (s *Store) List() ([]Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return f.Clients, nil
+}
+
+// Lookup returns the paired client with the given name, or nil if there is none.
+func (s *Store) Lookup(name string) (*Client, error) {
+	clients, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	for i := range clients {
+		if clients[i].Name == name {
+			return &clients[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// Add records c, replacing any existing client with the same name (re-pairing
+// a host that lost its key).
+func (s *Store) Add(c Client) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := s.load()
+	if err != nil {
+		return err
+	}
+	f.Clients = slices.DeleteFunc(f.Clients, func(e Client) bool { return e.Name == c.Name })
+	f.Clients = append(f.Clients, c)
+	return s.save(f)
+}
+
+// Remove forgets the client with the given name.
+func (s *Store) Remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := s.load()
+	if err != nil {
+		return err
+	}
+	n := len(f.Clients)
+	f.Clients = slices.DeleteFunc(f.Clients, func(e Client) bool { return e.Name == name })
+	if len(f.Clients) == n {
+		return ErrNotPaired
+	}
+	return s.save(f)
+}
+
+func (s *Store) load() (*storeFile, error) {
+	var f storeFile
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return &f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", s.path, err)
+	}
+	return &f, nil
+}
+
+func (s *Store) save(f *storeFile) error {
+	data, err := yaml.Marshal(f)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, data, 0o600)
+}
+
+// writeFileAtomic replaces path with data via a temp file in the same directory.
+func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // no-op after a successful rename
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,131 @@
+package pairing
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore_AddLookupRemove(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clients.yaml")
+	s := NewStore(path)
+
+	if c, err := s.Lookup("build-01"); err != nil || c != nil {
+		t.Fatalf("Lookup on missing file = %v, %v; want nil, nil", c, err)
+	}
+
+	pub, _ := newKey(t)
+	if err := s.Add(Client{Name: "build-01", PublicKey: base64.StdEncoding.EncodeToString(pub), PairedAt: time.Now()}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := s.Add(Client{Name: "laptop", PublicKey: base64.StdEncoding.EncodeToString(pub), PairedAt: time.Now()}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("clients file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	// A fresh store reads the same file.
+	c, err := NewStore(path).Lookup("build-01")
+	if err != nil || c == nil {
+		t.Fatalf("Lookup = %v, %v", c, err)
+	}
+	key, err := c.Key()
+	if err != nil {
+		t.Fatalf("Key: %v", err)
+	}
+	if !key.Equal(pub) {
+		t.Error("stored key does not round-trip")
+	}
+	if c.Fingerprint() != Fingerprint(pub) {
+		t.Errorf("Fingerprint = %q, want %q", c.Fingerprint(), Fingerprint(pub))
+	}
+
+	if err := s.Remove("build-01"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if err := s.Remove("build-01"); !errors.Is(err, ErrNotPaired) {
+		t.Errorf("second Remove = %v, want ErrNotPaired", err)
+	}
+	clients, err := s.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(clients) != 1 || clients[0].Name != "laptop" {
+		t.Errorf("List = %+v, want only laptop", clients)
+	}
+}
+
+func TestStore_AddReplacesByName(t *testing.T) {
+	s := NewStore(filepath.Join(t.TempDir(), "clients.yaml"))
+	oldKey, _ := newKey(t)
+	newPub, _ := newKey(t)
+
+	for _, pub := range [][]byte{oldKey, newPub} {
+		if err := s.Add(Client{Name: "build-01", PublicKey: base64.StdEncoding.EncodeToString(pub)}); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+
+	clients, err := s.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(clients) != 1 {
+		t.Fatalf("got %d clients after re-pairing, want 1", len(clients))
+	}
+	if clients[0].Fingerprint() != Fingerprint(newPub) {
+		t.Error("re-pairing did not replace the key")
+	}
+}
+
+func TestClient_KeyRejectsMalformed(t *testing.T) {
+	for _, pk := range []string{"", "not base64!", base64.StdEncoding.EncodeToString([]byte("short"))} {
+		c := Client{Name: "x", PublicKey: pk}
+		if _, err := c.Key(); err == nil {
+			t.Errorf("Key(%q) succeeded, want error", pk)
+		}
+		if fp := c.Fingerprint(); fp != "" {
+			t.Errorf("Fingerprint(%q) = %q, want empty", pk, fp)
+		}
+	}
+}
+
+func TestLoadOrCreateIdentity(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "client.key")
+
+	key, err := LoadOrCreateIdentity(path)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("key file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	again, err := LoadOrCreateIdentity(path)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if !again.Equal(key) {
+		t.Error("reloaded key differs from the created one")
+	}
+
+	if err := os.WriteFile(path, []byte("garbage"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadOrCreateIdentity(path); err == nil {
+		t.Error("corrupt key file accepted")
+	}
+}
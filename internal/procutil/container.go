@@ -0,0 +1,226 @@
+package procutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Container runtimes reported by ReadContainer.
+const (
+	RuntimeDocker     = "docker"
+	RuntimePodman     = "podman"
+	RuntimeContainerd = "containerd"
+	RuntimeCRIO       = "cri-o"
+	RuntimeNspawn     = "systemd-nspawn"
+)
+
+// Container identifies the container a process runs in.
+type Container struct {
+	Runtime string
+	ID      string // full container ID; empty for systemd-nspawn machines
+	Name    string // from the runtime's state, or the nspawn machine name
+	Image   string // from the runtime's state; empty when unreadable
+}
+
+// containerScopes maps the cgroup unit or directory names runtimes create for
+// a container to the runtime. Podman's "libpod-conmon-<id>" is the monitor
+// process on the host side, not the container.
+var containerScopes = []struct {
+	re      *regexp.Regexp
+	runtime string
+}{
+	{regexp.MustCompile(`^docker-([0-9a-f]{64})\.scope$`), RuntimeDocker},
+	{regexp.MustCompile(`^libpod-([0-9a-f]{64})\.scope$`), RuntimePodman},
+	{regexp.MustCompile(`^cri-containerd-([0-9a-f]{64})\.scope$`), RuntimeContainerd},
+	{regexp.MustCompile(`^crio-([0-9a-f]{64})\.scope$`), RuntimeCRIO},
+}
+
+// containerDirs are cgroupfs-driver parents whose child is a container ID.
+var containerDirs = map[string]string{
+	"docker":        RuntimeDocker,
+	"libpod_parent": RuntimePodman,
+}
+
+var containerID = regexp.MustCompile(`^(?:libpod-)?([0-9a-f]{64})$`)
+
+// Runtime state consulted for container names and images. Variables so tests
+// can point them at fixtures.
+var (
+	dockerStateDir  = "/var/lib/docker/containers"
+	podmanStateDirs = func() []string {
+		dirs := []string{"/var/lib/containers/storage"}
+		if home, err := os.UserHomeDir(); err == nil {
+			dirs = append(dirs, filepath.Join(home, ".local/share/containers/storage"))
+		}
+		return dirs
+	}
+)
+
+// ReadContainer reports the container pid runs in, if any. The runtime and ID
+// come from the cgroup the runtime put the process in. A process sharing our
+// PID namespace is on the host whatever its cgroup says; when the namespace
+// cannot be read (a container running as another user) the cgroup decides.
+// Name and image are looked up in the runtime's state files and stay empty
+// when those are not readable.
+//
+// None of this is proof of the container: any process of the user can enter a
+// PID namespace of its own and start itself in a scope named like a real
+// container's (systemd-run --user --scope --unit docker-<id>.scope), and is
+// then reported with that container's name and image. Like ReadApp, the result
+// is good enough to deny a process but not to trust it on its own.
+func ReadContainer(pid int32) (Container, bool) {
+	if sameNamespace(pid, "pid") {
+		return Container{}, false
+	}
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return Container{}, false
+	}
+	c, ok := containerFromCgroup(data)
+	if !ok {
+		return Container{}, false
+	}
+	switch c.Runtime {
+	case RuntimeDocker:
+		c.Name, c.Image = dockerState(dockerStateDir, c.ID)
+	case RuntimePodman:
+		for _, dir := range podmanStateDirs() {
+			if c.Name, c.Image = podmanState(dir, c.ID); c.Name != "" {
+				break
+			}
+		}
+	}
+	return c, true
+}
+
+// sameNamespace reports whether pid is in our namespace of the given type
+// (an entry of /proc/PID/ns). False when either cannot be read.
+func sameNamespace(pid int32, ns string) bool {
+	theirs, err := os.Readlink(fmt.Sprintf("/proc/%d/ns/%s", pid, ns))
+	if err != nil {
+		return false
+	}
+	ours, err := os.Readlink("/proc/self/ns/" + ns)
+	return err == nil && theirs == ours
+}
+
+// containerFromCgroup finds the container in the contents of /proc/PID/cgroup.
+func containerFromCgroup(data []byte) (Container, bool) {
+	for _, line := range strings.Split(string(data), "\n") {
+		// hierarchy-ID:controllers:path
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		elems := strings.Split(parts[2], "/")
+		for i, elem := range elems {
+			for _, s := range containerScopes {
+				if m := s.re.FindStringSubmatch(elem); m != nil {
+					return Container{Runtime: s.runtime, ID: m[1]}, true
+				}
+			}
+			if runtime, ok := containerDirs[elem]; ok && i+1 < len(elems) {
+				if m := containerID.FindStringSubmatch(elems[i+1]); m != nil {
+					return Container{Runtime: runtime, ID: m[1]}, true
+				}
+			}
+			if name, ok := nspawnMachine(elem); ok {
+				return Container{Runtime: RuntimeNspawn, Name: name}, true
+			}
+		}
+	}
+	return Container{}, false
+}
+
+// nspawnMachine returns the machine name of a systemd-nspawn cgroup element:
+// "systemd-nspawn@<name>.service" or, for machinectl/machined, "machine-<name>.scope".
+func nspawnMachine(elem string) (string, bool) {
+	if name, ok := strings.CutPrefix(elem, "systemd-nspawn@"); ok {
+		if name, ok := strings.CutSuffix(name, ".service"); ok && name != "" {
+			return unescapeUnit(name), true
+		}
+	}
+	if name, ok := strings.CutPrefix(elem, "machine-"); ok {
+		if name, ok := strings.CutSuffix(name, ".scope"); ok && name != "" {
+			return unescapeUnit(name), true
+		}
+	}
+	return "", false
+}
+
+// unescapeUnit undoes systemd's \xNN escaping of unit name parts.
+func unescapeUnit(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) && s[i+1] == 'x' {
+			if c, err := strconv.ParseUint(s[i+2:i+4], 16, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// dockerState reads the name and image of container id from Docker's state.
+func dockerState(dir, id string) (name, image string) {
+	data, err := os.ReadFile(filepath.Join(dir, id, "config.v2.json"))
+	if err != nil {
+		return "", ""
+	}
+	var cfg struct {
+		Name   string
+		Config struct{ Image string }
+	}
+	if json.Unmarshal(data, &cfg) != nil {
+		return "", ""
+	}
+	return strings.TrimPrefix(cfg.Name, "/"), cfg.Config.Image
+}
+
+// podmanState reads the name and image of container id from the containers
+// storage under dir (Podman, Buildah, CRI-O).
+func podmanState(dir, id string) (name, image string) {
+	var containers []struct {
+		ID    string   `json:"id"`
+		Names []string `json:"names"`
+		Image string   `json:"image"`
+	}
+	if !readJSON(filepath.Join(dir, "overlay-containers/containers.json"), &containers) {
+		return "", ""
+	}
+	for _, c := range containers {
+		if c.ID != id {
+			continue
+		}
+		if len(c.Names) > 0 {
+			name = c.Names[0]
+		}
+		image = c.Image
+		var images []struct {
+			ID    string   `json:"id"`
+			Names []string `json:"names"`
+		}
+		if readJSON(filepath.Join(dir, "overlay-images/images.json"), &images) {
+			for _, img := range images {
+				if img.ID == c.Image && len(img.Names) > 0 {
+					image = img.Names[0]
+				}
+			}
+		}
+		return name, image
+	}
+	return "", ""
+}
+
+func readJSON(name string, v any) bool {
+	data, err := os.ReadFile(name)
+	return err == nil && json.Unmarshal(data, v) == nil
+}
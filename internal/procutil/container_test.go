@@ -0,0 +1,84 @@
+package procutil
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testContainerID = "3f4e1a2b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f9a0b1c2d3e4f5a6b"
+
+func TestContainerFromCgroup(t *testing.T) {
+	tests := []struct {
+		cgroup string
+		want   Container
+	}{
+		{"0::/system.slice/docker-" + testContainerID + ".scope\n", Container{Runtime: RuntimeDocker, ID: testContainerID}},
+		{"12:pids:/docker/" + testContainerID + "\n", Container{Runtime: RuntimeDocker, ID: testContainerID}},
+		{"0::/user.slice/user-1000.slice/user@1000.service/user.slice/libpod-" + testContainerID + ".scope/container\n", Container{Runtime: RuntimePodman, ID: testContainerID}},
+		{"0::/libpod_parent/libpod-" + testContainerID + "\n", Container{Runtime: RuntimePodman, ID: testContainerID}},
+		{"0::/kubepods.slice/kubepods-besteffort.slice/cri-containerd-" + testContainerID + ".scope\n", Container{Runtime: RuntimeContainerd, ID: testContainerID}},
+		{"0::/machine.slice/systemd-nspawn@build\\x2dbox.service/payload\n", Container{Runtime: RuntimeNspawn, Name: "build-box"}},
+		{"0::/machine.slice/machine-debian.scope/payload\n", Container{Runtime: RuntimeNspawn, Name: "debian"}},
+	}
+	for _, tt := range tests {
+		got, ok := containerFromCgroup([]byte(tt.cgroup))
+		if !ok || got != tt.want {
+			t.Errorf("containerFromCgroup(%q) = %+v, %v; want %+v", tt.cgroup, got, ok, tt.want)
+		}
+	}
+
+	for _, cgroup := range []string{
+		"0::/user.slice/user-1000.slice/session-2.scope\n",
+		"0::/user.slice/user-1000.slice/user@1000.service/user.slice/libpod-conmon-" + testContainerID + ".scope\n",
+		"0::/system.slice/docker.service\n",
+	} {
+		if got, ok := containerFromCgroup([]byte(cgroup)); ok {
+			t.Errorf("containerFromCgroup(%q) = %+v, want none", cgroup, got)
+		}
+	}
+}
+
+func TestDockerState(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, testContainerID, "config.v2.json"),
+		`{"ID":"`+testContainerID+`","Name":"/web","Config":{"Image":"nginx:latest"}}`)
+
+	if name, image := dockerState(dir, testContainerID); name != "web" || image != "nginx:latest" {
+		t.Errorf("dockerState = %q, %q", name, image)
+	}
+	if name, image := dockerState(dir, strings.Repeat("0", 64)); name != "" || image != "" {
+		t.Errorf("dockerState(unknown) = %q, %q", name, image)
+	}
+}
+
+func TestPodmanState(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "overlay-containers/containers.json"),
+		`[{"id":"`+testContainerID+`","names":["toolbox"],"image":"img1"}]`)
+	writeFile(t, filepath.Join(dir, "overlay-images/images.json"),
+		`[{"id":"img1","names":["registry.fedoraproject.org/fedora-toolbox:40"]}]`)
+
+	name, image := podmanState(dir, testContainerID)
+	if name != "toolbox" || image != "registry.fedoraproject.org/fedora-toolbox:40" {
+		t.Errorf("podmanState = %q, %q", name, image)
+	}
+}
+
+func TestReadContainer_Self(t *testing.T) {
+	// The test runs in our own PID namespace, so never in a container.
+	if c, ok := ReadContainer(int32(os.Getpid())); ok {
+		t.Errorf("ReadContainer(self) = %+v", c)
+	}
+}
+
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,201 @@
+package procutil
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// exeKey identifies the contents of an executable file: any write to it
+// changes its ctime, which unlike mtime cannot be set back.
+type exeKey struct {
+	dev, ino, size uint64
+	ctime          int64
+}
+
+// maxExeCache bounds the hash and package caches; they are dropped when full.
+const maxExeCache = 256
+
+var (
+	exeCacheMu sync.Mutex
+	hashCache  = make(map[exeKey]string)
+	ownedCache = make(map[exeKey]bool)
+)
+
+// packageDBs are the package manager queries that succeed when an installed
+// package owns the path appended to them. Variables so tests can stub them.
+var (
+	packageDBs = [][]string{{"dpkg-query", "-S"}, {"rpm", "-qf"}, {"pacman", "-Qqo"}}
+	lookPath   = exec.LookPath
+)
+
+// openExe opens the executable process pid runs, through /proc/PID/exe so it
+// is the running image even if its path has been replaced since. startTime,
+// when non-zero, must match the process's, so a reused PID is not read
+// instead.
+func openExe(pid int32, startTime uint64) (*os.File, exeKey, error) {
+	if startTime != 0 && ReadStartTime(pid) != startTime {
+		return nil, exeKey{}, fmt.Errorf("process %d is gone", pid)
+	}
+	f, err := os.Open(fmt.Sprintf("/proc/%d/exe", pid))
+	if err != nil {
+		return nil, exeKey{}, err
+	}
+	key, err := fileKey(f)
+	if err != nil {
+		f.Close()
+		return nil, exeKey{}, err
+	}
+	return f, key, nil
+}
+
+func fileKey(f *os.File) (exeKey, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return exeKey{}, err
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return exeKey{}, fmt.Errorf("stat %s: no inode", f.Name())
+	}
+	return exeKey{dev: st.Dev, ino: st.Ino, size: uint64(st.Size), ctime: st.Ctim.Nano()}, nil
+}
+
+// ExeSHA256 returns the hex SHA-256 of the executable process pid runs, or ""
+// when it cannot be read. See openExe for startTime.
+func ExeSHA256(pid int32, startTime uint64) string {
+	f, key, err := openExe(pid, startTime)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	exeCacheMu.Lock()
+	sum, ok := hashCache[key]
+	exeCacheMu.Unlock()
+	if ok {
+		return sum
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return ""
+	}
+	sum = hex.EncodeToString(h.Sum(nil))
+
+	exeCacheMu.Lock()
+	if len(hashCache) >= maxExeCache {
+		clear(hashCache)
+	}
+	hashCache[key] = sum
+	exeCacheMu.Unlock()
+	return sum
+}
+
+// ExePackaged reports whether the executable process pid runs is installed by
+// the system: only root can replace it (see WritableByUsers), its path still
+// names the running image, and a package database (dpkg, rpm, pacman) owns
+// that path or it is on a read-only filesystem (/nix/store, an image-based
+// /usr). See openExe for startTime.
+func ExePackaged(pid int32, startTime uint64) bool {
+	f, key, err := openExe(pid, startTime)
+	if err != nil {
+		return false
+	}
+	f.Close()
+
+	exeCacheMu.Lock()
+	owned, ok := ownedCache[key]
+	exeCacheMu.Unlock()
+	if ok {
+		return owned
+	}
+
+	owned = exePackaged(ReadExe(pid), key)
+
+	exeCacheMu.Lock()
+	if len(ownedCache) >= maxExeCache {
+		clear(ownedCache)
+	}
+	ownedCache[key] = owned
+	exeCacheMu.Unlock()
+	return owned
+}
+
+func exePackaged(path string, running exeKey) bool {
+	if !filepath.IsAbs(path) || WritableByUsers(path) {
+		return false
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	key, err := fileKey(f)
+	f.Close()
+	if err != nil || key.dev != running.dev || key.ino != running.ino {
+		return false
+	}
+	var st unix.Statfs_t
+	if unix.Statfs(path, &st) == nil && st.Flags&unix.ST_RDONLY != 0 {
+		return true
+	}
+	return queryPackageDBs(path)
+}
+
+// queryPackageDBs asks the installed package managers whether one of their
+// packages owns path. With merged /usr, dpkg may list /usr/bin/x as /bin/x.
+func queryPackageDBs(path string) bool {
+	candidates := []string{path}
+	if rest, ok := strings.CutPrefix(path, "/usr/"); ok {
+		candidates = append(candidates, "/"+rest)
+	}
+	for _, db := range packageDBs {
+		bin, err := lookPath(db[0])
+		if err != nil {
+			continue
+		}
+		for _, p := range candidates {
+			if exec.Command(bin, append(db[1:], p)...).Run() == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WritableByUsers reports whether someone other than root could replace the
+// file at path: it, or a directory above it, is owned by another user or is
+// writable by group or others. A path that does not exist yet is judged by
+// its nearest existing parent.
+func WritableByUsers(path string) bool {
+	path = filepath.Clean(path)
+	for {
+		info, err := os.Stat(path)
+		if err == nil {
+			st, ok := info.Sys().(*syscall.Stat_t)
+			if !ok || st.Uid != 0 {
+				return true
+			}
+			// A sticky directory (/tmp) still lets anyone create the entry.
+			if info.Mode().Perm()&0o022 != 0 {
+				return true
+			}
+		} else if !os.IsNotExist(err) {
+			return true
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return false
+		}
+		path = parent
+	}
+}
@@ -0,0 +1,73 @@
+package procutil
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExeSHA256_Self(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+	want := hex.EncodeToString(sum[:])
+
+	pid := int32(os.Getpid())
+	if got := ExeSHA256(pid, ReadStartTime(pid)); got != want {
+		t.Errorf("ExeSHA256(self) = %q, want %q", got, want)
+	}
+	if got := ExeSHA256(pid, 1); got != "" {
+		t.Errorf("ExeSHA256 with wrong start time = %q, want empty", got)
+	}
+	if got := ExeSHA256(999999999, 0); got != "" {
+		t.Errorf("ExeSHA256(invalid) = %q, want empty", got)
+	}
+}
+
+func TestExePackaged_Self(t *testing.T) {
+	// The test binary is built under a temp dir that users can write to.
+	pid := int32(os.Getpid())
+	if ExePackaged(pid, ReadStartTime(pid)) {
+		t.Error("test binary reported as packaged")
+	}
+}
+
+func TestWritableByUsers(t *testing.T) {
+	dir := t.TempDir()
+	if !WritableByUsers(filepath.Join(dir, "bin/tool")) {
+		t.Errorf("%s/bin/tool not writable by users", dir)
+	}
+	if WritableByUsers("/") {
+		t.Error("/ writable by users")
+	}
+}
+
+func TestQueryPackageDBs_SkipsMissing(t *testing.T) {
+	var asked []string
+	defer func(dbs [][]string, look func(string) (string, error)) {
+		packageDBs, lookPath = dbs, look
+	}(packageDBs, lookPath)
+	packageDBs = [][]string{{"false"}, {"missing-package-manager"}}
+	lookPath = func(name string) (string, error) {
+		if name == "missing-package-manager" {
+			return "", os.ErrNotExist
+		}
+		asked = append(asked, name)
+		return "/bin/false", nil
+	}
+
+	if queryPackageDBs("/usr/bin/tool") {
+		t.Error("path owned when every database says no")
+	}
+	if len(asked) != 1 {
+		t.Errorf("looked up %v, want only the installed manager", asked)
+	}
+}
@@ -70,13 +70,28 @@ func IsSessionLeader(pid int32) bool {
 	return sid == pid
 }
 
+// ReadStartTime reads the process start time, in clock ticks since boot,
+// from /proc/<pid>/stat. Together with the PID it identifies a process
+// instance: a reused PID gets a later start time. Returns 0 on any error.
+func ReadStartTime(pid int32) uint64 {
+	fields := readStatFields(pid)
+	// fields[0] is stat field 3 (state); starttime is field 22.
+	if len(fields) < 20 {
+		return 0
+	}
+	var start uint64
+	fmt.Sscanf(fields[19], "%d", &start)
+	return start
+}
+
 // ProcEntry represents a single process in the process chain.
 type ProcEntry struct {
-	Comm string
-	PID  int32
-	Exe  string   // readlink /proc/PID/exe
-	Args []string // /proc/PID/cmdline
-	CWD  string   // readlink /proc/PID/cwd
+	Comm      string
+	PID       int32
+	StartTime uint64   // /proc/PID/stat starttime
+	Exe       string   // readlink /proc/PID/exe
+	Args      []string // /proc/PID/cmdline
+	CWD       string   // readlink /proc/PID/cwd
 }
 
 // ReadExe reads the executable path from /proc/<pid>/exe.
@@ -113,11 +128,12 @@ func ReadProcessChain(pid int32, trimAtSessionLeader bool) []ProcEntry {
 			break
 		}
 		chain = append(chain, ProcEntry{
-			Comm: comm,
-			PID:  p,
-			Exe:  ReadExe(p),
-			Args: ReadCmdline(p),
-			CWD:  ReadCWD(p),
+			Comm:      comm,
+			PID:       p,
+			StartTime: ReadStartTime(p),
+			Exe:       ReadExe(p),
+			Args:      ReadCmdline(p),
+			CWD:       ReadCWD(p),
 		})
 		if trimAtSessionLeader && IsSessionLeader(p) {
 			// Include the parent of the session leader to show
@@ -127,11 +143,12 @@ func ReadProcessChain(pid int32, trimAtSessionLeader bool) []ProcEntry {
 				pcomm := ReadComm(parent)
 				if pcomm != "" {
 					chain = append(chain, ProcEntry{
-						Comm: pcomm,
-						PID:  parent,
-						Exe:  ReadExe(parent),
-						Args: ReadCmdline(parent),
-						CWD:  ReadCWD(parent),
+						Comm:      pcomm,
+						PID:       parent,
+						StartTime: ReadStartTime(parent),
+						Exe:       ReadExe(parent),
+						Args:      ReadCmdline(parent),
+						CWD:       ReadCWD(parent),
 					})
 				}
 			}
@@ -38,6 +38,26 @@ func TestReadPPID_InvalidPID(t *testing.T) {
 	}
 }
 
+func TestReadStartTime_Self(t *testing.T) {
+	self := int32(os.Getpid())
+	start := ReadStartTime(self)
+	if start == 0 {
+		t.Fatal("ReadStartTime on self returned 0")
+	}
+	if parent := ReadStartTime(int32(os.Getppid())); parent == 0 || parent > start {
+		t.Errorf("parent start time %d should be non-zero and not after ours (%d)", parent, start)
+	}
+	if again := ReadStartTime(self); again != start {
+		t.Errorf("start time changed from %d to %d", start, again)
+	}
+}
+
+func TestReadStartTime_InvalidPID(t *testing.T) {
+	if start := ReadStartTime(-1); start != 0 {
+		t.Errorf("expected 0 for invalid PID, got %d", start)
+	}
+}
+
 func TestIsShell(t *testing.T) {
 	for _, name := range []string{"sh", "bash", "zsh", "fish", "dash", "csh", "tcsh", "ksh"} {
 		if !IsShell(name) {
@@ -0,0 +1,103 @@
+package procutil
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// Sandbox kinds reported by ReadApp.
+const (
+	SandboxFlatpak = "flatpak"
+	SandboxSnap    = "snap"
+)
+
+// App identifies a sandboxed application by its application ID, which unlike
+// the exe path inside the sandbox stays the same across app updates.
+type App struct {
+	ID      string // "org.mozilla.firefox" (Flatpak) or "firefox" (Snap)
+	Sandbox string // SandboxFlatpak or SandboxSnap
+}
+
+// ReadApp reports the sandboxed application pid belongs to, if any, from
+// state a sandboxed app cannot change:
+//   - Flatpak: the [Application] name in /.flatpak-info of the process's root,
+//     which flatpak mounts read-only into every sandbox (including the D-Bus
+//     proxy of the app) — the same check xdg-desktop-portal makes.
+//   - Snap: a snap.<name>.* cgroup, and an exe inside /snap/<name>/, which
+//     only snapd can write. The cgroup alone is not enough: any process can
+//     start itself in a scope with that name.
+//
+// Both are read through the process's own mount namespace, so an unsandboxed
+// process of the same user can claim any app: in a user and mount namespace
+// of its own it can mount a fake /.flatpak-info or /snap/<name>/. The result
+// therefore names the app a process says it is, good enough to deny it but not
+// to trust it on its own.
+func ReadApp(pid int32) (App, bool) {
+	if data, err := os.ReadFile(fmt.Sprintf("/proc/%d/root/.flatpak-info", pid)); err == nil {
+		if id := parseFlatpakInfo(data); id != "" {
+			return App{ID: id, Sandbox: SandboxFlatpak}, true
+		}
+	}
+	if data, err := os.ReadFile(fmt.Sprintf("/proc/%d/cgroup", pid)); err == nil {
+		if name := snapFromCgroup(data); name != "" && snapOwnsExe(name, ReadExe(pid)) {
+			return App{ID: name, Sandbox: SandboxSnap}, true
+		}
+	}
+	return App{}, false
+}
+
+// parseFlatpakInfo returns the application name from a .flatpak-info keyfile,
+// or "" for runtime-only sandboxes.
+func parseFlatpakInfo(data []byte) string {
+	section := ""
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if line[0] == '[' && line[len(line)-1] == ']' {
+			section = line[1 : len(line)-1]
+			continue
+		}
+		if section != "Application" {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok && strings.TrimSpace(key) == "name" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// snapFromCgroup returns the snap (instance) name from the cgroup membership
+// in /proc/PID/cgroup, where snapd runs apps in units named
+// "snap.<name>.<app>[-<uuid>].scope" or "snap.<name>.<app>.service".
+func snapFromCgroup(data []byte) string {
+	for _, line := range strings.Split(string(data), "\n") {
+		// hierarchy-ID:controllers:path
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		unit, ok := strings.CutPrefix(path.Base(parts[2]), "snap.")
+		if !ok {
+			continue
+		}
+		if name, _, ok := strings.Cut(unit, "."); ok && name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// snapOwnsExe reports whether exe is inside the mounted snap called name. A
+// parallel instance "name_key" runs the files of snap "name".
+func snapOwnsExe(name, exe string) bool {
+	snap, _, _ := strings.Cut(name, "_")
+	return strings.HasPrefix(exe, "/snap/"+snap+"/")
+}
@@ -0,0 +1,57 @@
+package procutil
+
+import "testing"
+
+func TestParseFlatpakInfo(t *testing.T) {
+	app := []byte(`[Application]
+name=org.mozilla.firefox
+runtime=runtime/org.freedesktop.Platform/x86_64/23.08
+
+[Instance]
+instance-id=1234
+`)
+	if got := parseFlatpakInfo(app); got != "org.mozilla.firefox" {
+		t.Errorf("parseFlatpakInfo(app) = %q", got)
+	}
+
+	runtime := []byte("[Runtime]\nname=org.freedesktop.Platform\n")
+	if got := parseFlatpakInfo(runtime); got != "" {
+		t.Errorf("parseFlatpakInfo(runtime) = %q, want empty", got)
+	}
+}
+
+func TestSnapFromCgroup(t *testing.T) {
+	tests := []struct {
+		cgroup string
+		want   string
+	}{
+		{"0::/user.slice/user-1000.slice/user@1000.service/app.slice/snap.firefox.firefox-0f1e.scope\n", "firefox"},
+		{"0::/system.slice/snap.lxd.daemon.service\n", "lxd"},
+		{"12:pids:/user.slice\n0::/user.slice/user-1000.slice/user@1000.service/app.slice/snap.chromium_work.chromium.scope\n", "chromium_work"},
+		{"0::/user.slice/user-1000.slice/session-2.scope\n", ""},
+		{"0::/user.slice/snap.scope\n", ""},
+	}
+	for _, tt := range tests {
+		if got := snapFromCgroup([]byte(tt.cgroup)); got != tt.want {
+			t.Errorf("snapFromCgroup(%q) = %q, want %q", tt.cgroup, got, tt.want)
+		}
+	}
+}
+
+func TestSnapOwnsExe(t *testing.T) {
+	if !snapOwnsExe("firefox", "/snap/firefox/4173/usr/lib/firefox/firefox") {
+		t.Error("firefox exe not owned by firefox snap")
+	}
+	if !snapOwnsExe("chromium_work", "/snap/chromium/2614/usr/lib/chromium-browser/chrome") {
+		t.Error("parallel instance exe not owned")
+	}
+	if snapOwnsExe("firefox", "/usr/bin/python3") || snapOwnsExe("firefox", "/snap/firefox-fake/1/x") {
+		t.Error("foreign exe owned by firefox snap")
+	}
+}
+
+func TestReadApp_InvalidPID(t *testing.T) {
+	if app, ok := ReadApp(999999999); ok {
+		t.Errorf("ReadApp(invalid) = %+v", app)
+	}
+}
@@ -0,0 +1,363 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/godbus/dbus/v5"
+	dbustypes "github.com/nikicat/secrets-dispatcher/internal/dbus"
+)
+
+// DefaultBackend names the backend of a proxy with a single upstream.
+const DefaultBackend = "default"
+
+// Object path namespaces whose first element is mangled per backend.
+const (
+	collectionPrefix = "/org/freedesktop/secrets/collection/"
+	aliasPrefix      = "/org/freedesktop/secrets/aliases/"
+	promptPrefix     = "/org/freedesktop/secrets/prompt/"
+)
+
+// escapeMark is prepended on the front to the default backend's collection
+// and prompt names that would otherwise read as another backend's, or as
+// escaped themselves.
+const escapeMark = "_"
+
+// Upstream describes one Secret Service backend and how it is mounted on the
+// front bus.
+type Upstream struct {
+	Name string
+	Addr string // D-Bus address; empty = session bus
+	// Prefix is prepended to the backend's collection and prompt names on
+	// the front. Empty for the default (first) backend only; the others end
+	// in "_", do not start with it, and none is a prefix of another.
+	Prefix string
+	// Aliases maps front alias names to the backend's own aliases. Aliases
+	// not mapped by any backend go to the default backend unchanged.
+	Aliases map[string]string
+}
+
+// Backend is a connected Upstream.
+type Backend struct {
+	Upstream
+	// Conn is the connection to the upstream. The proxy replaces it when it
+	// reconnects, so once connected it is read with conn().
+	Conn *dbus.Conn
+
+	mu   sync.RWMutex // guards Conn and lost
+	lost error        // why the connection is down; nil while connected
+	// epoch counts the times the upstream's sessions became invalid (the
+	// service restarted or the connection was lost).
+	epoch atomic.Uint64
+}
+
+// DialUpstreams connects to every upstream. If one fails, the connections
+// already made are closed.
+func DialUpstreams(upstreams []Upstream) ([]Backend, error) {
+	backends := make([]Backend, 0, len(upstreams))
+	for _, u := range upstreams {
+		conn, err := dialUpstream(u)
+		if err != nil {
+			for i := range backends {
+				backends[i].Conn.Close()
+			}
+			return nil, fmt.Errorf("connect to upstream %s: %w", u.Name, err)
+		}
+		backends = append(backends, Backend{Upstream: u, Conn: conn})
+	}
+	return backends, nil
+}
+
+// dialUpstream opens a private connection to the bus of u.
+func dialUpstream(u Upstream) (*dbus.Conn, error) {
+	if u.Addr == "" {
+		return dbus.ConnectSessionBus()
+	}
+	return dbus.Connect(u.Addr)
+}
+
+// conn returns the current connection to the upstream.
+func (b *Backend) conn() *dbus.Conn {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.Conn
+}
+
+// object returns the Secret Service object at path on the upstream. While the
+// connection is lost it returns one whose calls fail at once, so clients get
+// a clear error instead of waiting on a dead bus.
+func (b *Backend) object(path dbus.ObjectPath) dbus.BusObject {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.lost != nil {
+		return unavailableObject{backend: b.Name, path: path}
+	}
+	return b.Conn.Object(dbustypes.BusName, path)
+}
+
+// setLost marks the connection as down with the reason err.
+func (b *Backend) setLost(err error) {
+	b.mu.Lock()
+	b.lost = err
+	b.mu.Unlock()
+	b.epoch.Add(1)
+}
+
+// setConn installs a new connection after a reconnect.
+func (b *Backend) setConn(conn *dbus.Conn) {
+	b.mu.Lock()
+	b.Conn = conn
+	b.lost = nil
+	b.mu.Unlock()
+}
+
+// unavailableObject stands in for the objects of an upstream whose connection
+// is lost: every call fails with ErrUpstreamUnavailable.
+type unavailableObject struct {
+	backend string
+	path    dbus.ObjectPath
+}
+
+func (o unavailableObject) err() error {
+	return *dbustypes.ErrUpstreamUnavailable(o.backend)
+}
+
+func (o unavailableObject) Call(method string, flags dbus.Flags, args ...any) *dbus.Call {
+	return &dbus.Call{Destination: dbustypes.BusName, Path: o.path, Method: method, Args: args, Err: o.err()}
+}
+
+func (o unavailableObject) CallWithContext(_ context.Context, method string, flags dbus.Flags, args ...any) *dbus.Call {
+	return o.Call(method, flags, args...)
+}
+
+func (o unavailableObject) Go(method string, flags dbus.Flags, ch chan *dbus.Call, args ...any) *dbus.Call {
+	call := o.Call(method, flags, args...)
+	if ch == nil {
+		ch = make(chan *dbus.Call, 1)
+	}
+	call.Done = ch
+	select {
+	case ch <- call:
+	default:
+	}
+	return call
+}
+
+func (o unavailableObject) GoWithContext(_ context.Context, method string, flags dbus.Flags, ch chan *dbus.Call, args ...any) *dbus.Call {
+	return o.Go(method, flags, ch, args...)
+}
+
+func (o unavailableObject) AddMatchSignal(iface, member string, options ...dbus.MatchOption) *dbus.Call {
+	return &dbus.Call{Err: o.err()}
+}
+
+func (o unavailableObject) RemoveMatchSignal(iface, member string, options ...dbus.MatchOption) *dbus.Call {
+	return &dbus.Call{Err: o.err()}
+}
+
+func (o unavailableObject) GetProperty(p string) (dbus.Variant, error) {
+	return dbus.Variant{}, o.err()
+}
+
+func (o unavailableObject) StoreProperty(p string, value any) error {
+	return o.err()
+}
+
+func (o unavailableObject) SetProperty(p string, v any) error {
+	return o.err()
+}
+
+func (o unavailableObject) Destination() string {
+	return dbustypes.BusName
+}
+
+func (o unavailableObject) Path() dbus.ObjectPath {
+	return o.path
+}
+
+// backendSet routes object paths between the front bus and the backends. The
+// first backend is the default: paths no other backend claims are its own.
+// With several backends, a default collection or prompt whose name starts
+// with another backend's prefix, or with escapeMark, is shown with escapeMark
+// prepended, so that every name on the front belongs to one backend.
+type backendSet []*Backend
+
+// primary returns the default backend.
+func (bs backendSet) primary() *Backend {
+	return bs[0]
+}
+
+// byName returns the backend called name, or nil.
+func (bs backendSet) byName(name string) *Backend {
+	for _, b := range bs {
+		if b.Name == name {
+			return b
+		}
+	}
+	return nil
+}
+
+// route returns the backend owning the front path p and p as that backend
+// knows it. A collection or prompt name starts with the prefix of at most one
+// backend, as the configured prefixes end in "_" and none is a prefix of
+// another; names starting with none belong to the default backend, less the
+// escapeMark toFront added.
+func (bs backendSet) route(p dbus.ObjectPath) (*Backend, dbus.ObjectPath) {
+	s := string(p)
+	for _, ns := range []string{collectionPrefix, promptPrefix} {
+		name, ok := strings.CutPrefix(s, ns)
+		if !ok {
+			continue
+		}
+		if len(bs) > 1 {
+			if rest, ok := strings.CutPrefix(name, escapeMark); ok && rest != "" {
+				return bs.primary(), dbus.ObjectPath(ns + rest)
+			}
+		}
+		for _, b := range bs[1:] {
+			if rest, ok := strings.CutPrefix(name, b.Prefix); ok && rest != "" {
+				return b, dbus.ObjectPath(ns + rest)
+			}
+		}
+		return bs.primary(), p
+	}
+	if rest, ok := strings.CutPrefix(s, aliasPrefix); ok {
+		alias, tail, _ := strings.Cut(rest, "/")
+		if tail != "" {
+			tail = "/" + tail
+		}
+		for _, b := range bs {
+			if target, ok := b.Aliases[alias]; ok {
+				return b, dbus.ObjectPath(aliasPrefix + target + tail)
+			}
+		}
+	}
+	return bs.primary(), p
+}
+
+// routeAlias returns the backend serving the front alias name and the alias
+// as that backend knows it.
+func (bs backendSet) routeAlias(name string) (*Backend, string) {
+	for _, b := range bs {
+		if target, ok := b.Aliases[name]; ok {
+			return b, target
+		}
+	}
+	return bs.primary(), name
+}
+
+// toFront translates a path returned by backend b to the front.
+func (bs backendSet) toFront(b *Backend, p dbus.ObjectPath) dbus.ObjectPath {
+	s := string(p)
+	if rest, ok := strings.CutPrefix(s, aliasPrefix); ok {
+		alias, tail, _ := strings.Cut(rest, "/")
+		for front, target := range b.Aliases {
+			if target == alias {
+				if tail != "" {
+					return dbus.ObjectPath(aliasPrefix + front + "/" + tail)
+				}
+				return dbus.ObjectPath(aliasPrefix + front)
+			}
+		}
+		return p
+	}
+	if b.Prefix == "" && len(bs) < 2 {
+		return p
+	}
+	for _, ns := range []string{collectionPrefix, promptPrefix} {
+		name, ok := strings.CutPrefix(s, ns)
+		if !ok || name == "" {
+			continue
+		}
+		if b.Prefix == "" {
+			if bs.claimed(name) {
+				return dbus.ObjectPath(ns + escapeMark + name)
+			}
+			return p
+		}
+		return dbus.ObjectPath(ns + b.Prefix + name)
+	}
+	return p
+}
+
+// claimed reports whether a collection or prompt name of the default backend
+// would route elsewhere if shown as is: it starts with escapeMark or with the
+// prefix of another backend.
+func (bs backendSet) claimed(name string) bool {
+	if strings.HasPrefix(name, escapeMark) {
+		return true
+	}
+	for _, b := range bs[1:] {
+		if strings.HasPrefix(name, b.Prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// pathsToFront translates a list of paths returned by backend b.
+func (bs backendSet) pathsToFront(b *Backend, paths []dbus.ObjectPath) []dbus.ObjectPath {
+	out := make([]dbus.ObjectPath, len(paths))
+	for i, p := range paths {
+		out[i] = bs.toFront(b, p)
+	}
+	return out
+}
+
+// valueToFront translates the object paths in a D-Bus value returned by
+// backend b (a path, a path list, a variant holding either, or a property
+// map), leaving other values unchanged.
+func (bs backendSet) valueToFront(b *Backend, v any) any {
+	switch v := v.(type) {
+	case dbus.ObjectPath:
+		return bs.toFront(b, v)
+	case []dbus.ObjectPath:
+		return bs.pathsToFront(b, v)
+	case dbus.Variant:
+		inner := v.Value()
+		switch inner.(type) {
+		case dbus.ObjectPath, []dbus.ObjectPath, map[string]dbus.Variant:
+			return dbus.MakeVariant(bs.valueToFront(b, inner))
+		}
+		return v
+	case map[string]dbus.Variant:
+		out := make(map[string]dbus.Variant, len(v))
+		for k, val := range v {
+			out[k] = bs.valueToFront(b, val).(dbus.Variant)
+		}
+		return out
+	}
+	return v
+}
+
+// backendGroup is a set of front paths owned by one backend.
+type backendGroup struct {
+	backend *Backend
+	front   []dbus.ObjectPath
+	paths   []dbus.ObjectPath // front, as the backend knows them
+}
+
+// group splits front paths by owning backend, in order of first appearance.
+func (bs backendSet) group(front []dbus.ObjectPath) []*backendGroup {
+	var groups []*backendGroup
+	for _, p := range front {
+		b, bp := bs.route(p)
+		var g *backendGroup
+		for _, existing := range groups {
+			if existing.backend == b {
+				g = existing
+				break
+			}
+		}
+		if g == nil {
+			g = &backendGroup{backend: b}
+			groups = append(groups, g)
+		}
+		g.front = append(g.front, p)
+		g.paths = append(g.paths, bp)
+	}
+	return groups
+}
@@ -0,0 +1,96 @@
+package proxy
+
+import (
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func testBackendSet() backendSet {
+	return backendSet{
+		{Upstream: Upstream{Name: "personal"}},
+		{Upstream: Upstream{Name: "work", Prefix: "work_", Aliases: map[string]string{"work": "login"}}},
+		{Upstream: Upstream{Name: "ci", Prefix: "ci_"}},
+	}
+}
+
+func TestBackendSetRoute(t *testing.T) {
+	bs := testBackendSet()
+	tests := []struct {
+		front   dbus.ObjectPath
+		backend string
+		path    dbus.ObjectPath
+	}{
+		{"/org/freedesktop/secrets", "personal", "/org/freedesktop/secrets"},
+		{"/org/freedesktop/secrets/collection/login/3", "personal", "/org/freedesktop/secrets/collection/login/3"},
+		{"/org/freedesktop/secrets/collection/work_login/3", "work", "/org/freedesktop/secrets/collection/login/3"},
+		{"/org/freedesktop/secrets/collection/ci_deploy", "ci", "/org/freedesktop/secrets/collection/deploy"},
+		{"/org/freedesktop/secrets/prompt/work_p1", "work", "/org/freedesktop/secrets/prompt/p1"},
+		{"/org/freedesktop/secrets/aliases/work/2", "work", "/org/freedesktop/secrets/aliases/login/2"},
+		{"/org/freedesktop/secrets/aliases/default", "personal", "/org/freedesktop/secrets/aliases/default"},
+		// Default collections named like another backend's are escaped.
+		{"/org/freedesktop/secrets/collection/_work_notes/1", "personal", "/org/freedesktop/secrets/collection/work_notes/1"},
+		{"/org/freedesktop/secrets/collection/_work_", "personal", "/org/freedesktop/secrets/collection/work_"},
+		{"/org/freedesktop/secrets/collection/__private", "personal", "/org/freedesktop/secrets/collection/_private"},
+		{"/org/freedesktop/secrets/prompt/_ci_p2", "personal", "/org/freedesktop/secrets/prompt/ci_p2"},
+	}
+	for _, tt := range tests {
+		b, path := bs.route(tt.front)
+		if b.Name != tt.backend || path != tt.path {
+			t.Errorf("route(%s) = %s %s, want %s %s", tt.front, b.Name, path, tt.backend, tt.path)
+		}
+		if back := bs.toFront(b, path); back != tt.front {
+			t.Errorf("toFront(%s, %s) = %s, want %s", b.Name, path, back, tt.front)
+		}
+	}
+
+	// A bare prefix names no collection on the backend.
+	if b, _ := bs.route("/org/freedesktop/secrets/collection/work_"); b.Name != "personal" {
+		t.Errorf("bare prefix routed to %s", b.Name)
+	}
+
+	// With a single backend nothing is escaped.
+	single := bs[:1]
+	for _, p := range []dbus.ObjectPath{"/org/freedesktop/secrets/collection/work_notes", "/org/freedesktop/secrets/collection/_x"} {
+		if b, path := single.route(p); b.Name != "personal" || path != p {
+			t.Errorf("single backend: route(%s) = %s %s", p, b.Name, path)
+		}
+		if front := single.toFront(single[0], p); front != p {
+			t.Errorf("single backend: toFront(%s) = %s", p, front)
+		}
+	}
+}
+
+func TestBackendSetGroup(t *testing.T) {
+	bs := testBackendSet()
+	groups := bs.group([]dbus.ObjectPath{
+		"/org/freedesktop/secrets/collection/work_login/1",
+		"/org/freedesktop/secrets/collection/login/1",
+		"/org/freedesktop/secrets/collection/work_login/2",
+	})
+	if len(groups) != 2 {
+		t.Fatalf("got %d groups, want 2", len(groups))
+	}
+	if groups[0].backend.Name != "work" || len(groups[0].paths) != 2 || groups[0].paths[1] != "/org/freedesktop/secrets/collection/login/2" {
+		t.Errorf("first group = %s %v", groups[0].backend.Name, groups[0].paths)
+	}
+	if groups[1].backend.Name != "personal" || len(groups[1].paths) != 1 {
+		t.Errorf("second group = %s %v", groups[1].backend.Name, groups[1].paths)
+	}
+}
+
+func TestBackendSetValueToFront(t *testing.T) {
+	bs := testBackendSet()
+	props := map[string]dbus.Variant{
+		"Items": dbus.MakeVariant([]dbus.ObjectPath{"/org/freedesktop/secrets/collection/login/1"}),
+		"Label": dbus.MakeVariant("Login"),
+	}
+	got := bs.valueToFront(bs[1], props).(map[string]dbus.Variant)
+	items := got["Items"].Value().([]dbus.ObjectPath)
+	if len(items) != 1 || items[0] != "/org/freedesktop/secrets/collection/work_login/1" {
+		t.Errorf("Items = %v", items)
+	}
+	if got["Label"].Value() != "Login" {
+		t.Errorf("Label = %v", got["Label"])
+	}
+}
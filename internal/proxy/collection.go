@@ -10,12 +10,13 @@ import (
 	"github.com/nikicat/secrets-dispatcher/internal/approval"
 	dbustypes "github.com/nikicat/secrets-dispatcher/internal/dbus"
 	"github.com/nikicat/secrets-dispatcher/internal/logging"
+	"github.com/nikicat/secrets-dispatcher/internal/trash"
 )
 
 // CollectionHandler handles Collection interface calls for collection objects.
 // It is exported as a subtree handler for /org/freedesktop/secrets/collection/*.
 type CollectionHandler struct {
-	localConn        *dbus.Conn
+	backends         backendSet
 	sessions         *SessionManager
 	logger           *logging.Logger
 	approval         *approval.Manager
@@ -24,12 +25,13 @@ type CollectionHandler struct {
 	resolver         *SenderInfoResolver
 	upstreamNotifier UpstreamNotifier
 	slowThreshold    time.Duration
+	trash            *trash.Store // nil = deletions are not kept
 }
 
 // NewCollectionHandler creates a new CollectionHandler.
-func NewCollectionHandler(localConn *dbus.Conn, sessions *SessionManager, logger *logging.Logger, approvalMgr *approval.Manager, clientName string, tracker *clientTracker, resolver *SenderInfoResolver, upstreamNotifier UpstreamNotifier, slowThreshold time.Duration) *CollectionHandler {
+func NewCollectionHandler(backends backendSet, sessions *SessionManager, logger *logging.Logger, approvalMgr *approval.Manager, clientName string, tracker *clientTracker, resolver *SenderInfoResolver, upstreamNotifier UpstreamNotifier, slowThreshold time.Duration, trashStore *trash.Store) *CollectionHandler {
 	return &CollectionHandler{
-		localConn:        localConn,
+		backends:         backends,
 		sessions:         sessions,
 		logger:           logger,
 		approval:         approvalMgr,
@@ -38,12 +40,14 @@ func NewCollectionHandler(localConn *dbus.Conn, sessions *SessionManager, logger
 		resolver:         resolver,
 		upstreamNotifier: upstreamNotifier,
 		slowThreshold:    slowThreshold,
+		trash:            trashStore,
 	}
 }
 
 // upstream returns the backend object at path on the upstream Secret Service bus.
 func (c *CollectionHandler) upstream(path dbus.ObjectPath) dbus.BusObject {
-	return c.localConn.Object(dbustypes.BusName, path)
+	b, bp := c.backends.route(path)
+	return b.object(bp)
 }
 
 // senderResolver returns a lazy resolver of sender's SenderInfo, suitable for an
@@ -117,13 +121,32 @@ func (c *CollectionHandler) Delete(msg dbus.Message) (dbus.ObjectPath, *dbus.Err
 		return "/", dbustypes.ErrAccessDenied(err.Error())
 	}
 
-	obj := c.upstream(path)
+	// Keep a copy of every item first, so that an approval given by mistake
+	// can be undone.
+	var trashID string
+	b, backendPath := c.backends.route(path)
+	if c.trash != nil {
+		itemPaths, err := trash.CollectionItems(b.conn(), backendPath)
+		if err == nil {
+			entry := trash.Entry{Kind: trash.KindCollection, Client: c.clientName, Backend: b.Name, Collection: string(backendPath), CollectionLabel: collectionInfo.Label}
+			trashID, err = keepDeleted(c.trash, b.conn(), entry, itemPaths)
+		}
+		if err != nil {
+			c.logger.LogMethod(ctx, "Collection.Delete", map[string]any{"collection": string(path)}, "error", err)
+			return "/", dbustypes.ErrFailed(err)
+		}
+	}
+
+	obj := b.object(backendPath)
 	call := c.upstreamWithContext(UpstreamCallContext{
 		RequestType: approval.RequestTypeDelete,
 		Items:       items,
 		SenderInfo:  senderInfo,
 	}, func() *dbus.Call { return obj.Call(dbustypes.CollectionInterface+".Delete", 0) })
 	if call.Err != nil {
+		if trashID != "" {
+			c.trash.Remove(trashID) //nolint:errcheck // the collection was not deleted
+		}
 		return "/", dbustypes.ErrFailed(call.Err)
 	}
 
@@ -134,16 +157,18 @@ func (c *CollectionHandler) Delete(msg dbus.Message) (dbus.ObjectPath, *dbus.Err
 
 	c.logger.LogMethod(context.Background(), "Collection.Delete", map[string]any{
 		"collection": string(path),
+		"trash":      trashID,
 	}, "ok", nil)
 
-	return prompt, nil
+	return c.backends.toFront(b, prompt), nil
 }
 
 // getCollectionInfo fetches label for a collection from D-Bus.
 func (c *CollectionHandler) getCollectionInfo(path dbus.ObjectPath, ctx UpstreamCallContext) approval.ItemInfo {
-	info := approval.ItemInfo{Path: string(path)}
+	b, bp := c.backends.route(path)
+	info := approval.ItemInfo{Path: string(path), Backend: b.Name}
 
-	obj := c.upstream(path)
+	obj := b.object(bp)
 
 	// Get Label property
 	if v, err := c.upstreamGetProperty(obj, dbustypes.CollectionInterface+".Label", ctx); err == nil {
@@ -173,6 +198,38 @@ func extractItemInfo(collectionPath string, properties map[string]dbus.Variant)
 	return info
 }
 
+// visibleItems drops from items the ones senderInfo's client may never read.
+func (c *CollectionHandler) visibleItems(senderInfo approval.SenderInfo, items []dbus.ObjectPath) []dbus.ObjectPath {
+	itemCtx := UpstreamCallContext{SenderInfo: senderInfo}
+	return visiblePaths(c.approval, c.clientName, senderInfo, items, func(p dbus.ObjectPath) approval.ItemInfo {
+		return c.getItemInfo(p, itemCtx)
+	})
+}
+
+// getItemInfo fetches item label and attributes for visibility checks.
+func (c *CollectionHandler) getItemInfo(path dbus.ObjectPath, ctx UpstreamCallContext) approval.ItemInfo {
+	b, bp := c.backends.route(path)
+	info := approval.ItemInfo{Path: string(path), Backend: b.Name}
+
+	obj := b.object(bp)
+
+	// Get Label property
+	if v, err := c.upstreamGetProperty(obj, dbustypes.ItemInterface+".Label", ctx); err == nil {
+		if label, ok := v.Value().(string); ok {
+			info.Label = label
+		}
+	}
+
+	// Get Attributes property
+	if v, err := c.upstreamGetProperty(obj, dbustypes.ItemInterface+".Attributes", ctx); err == nil {
+		if attrs, ok := v.Value().(map[string]string); ok {
+			info.Attributes = attrs
+		}
+	}
+
+	return info
+}
+
 // SearchItems searches for items in this collection matching the given attributes.
 // Signature: SearchItems(attributes Dict<String,String>) -> (results Array<ObjectPath>)
 func (c *CollectionHandler) SearchItems(msg dbus.Message, attributes map[string]string) ([]dbus.ObjectPath, *dbus.Error) {
@@ -181,15 +238,20 @@ func (c *CollectionHandler) SearchItems(msg dbus.Message, attributes map[string]
 		return nil, dbustypes.ErrObjectNotFound(string(path))
 	}
 
-	obj := c.upstream(path)
+	b, backendPath := c.backends.route(path)
+	obj := b.object(backendPath)
 	infos := searchAttributesToItemInfo(attributes)
 	sender := senderOf(msg)
 	senderInfo := c.resolver.Resolve(sender)
 
 	// Check if request should be denied by a trust rule
-	if rule := c.approval.CheckTrustRules(senderInfo, infos, approval.RequestTypeSearch, attributes); rule != nil && rule.Action == "deny" {
-		c.approval.RecordDenied(c.clientName, infos, "", approval.RequestTypeSearch, attributes, senderInfo)
-		return nil, dbustypes.ErrAccessDenied("denied by trust rule: " + rule.Name)
+	if rule := c.approval.CheckTrustRules(c.clientName, senderInfo, infos, approval.RequestTypeSearch, attributes); rule != nil && rule.Action == "deny" {
+		c.approval.RecordDenied(c.clientName, infos, "", approval.RequestTypeSearch, attributes, senderInfo, rule.Label())
+		return nil, dbustypes.ErrAccessDenied("denied by trust rule: " + rule.Label())
+	}
+	if c.approval.TemporarilyDenied(senderInfo, infos, approval.RequestTypeSearch) {
+		c.approval.RecordDenied(c.clientName, infos, "", approval.RequestTypeSearch, attributes, senderInfo, approval.TemporaryDenyRule)
+		return nil, dbustypes.ErrAccessDenied("denied by a temporary deny rule")
 	}
 
 	c.approval.RecordPassthrough(c.clientName, infos, "", approval.RequestTypeSearch, attributes, senderInfo)
@@ -206,11 +268,15 @@ func (c *CollectionHandler) SearchItems(msg dbus.Message, attributes map[string]
 	if err := call.Store(&results); err != nil {
 		return nil, dbustypes.ErrFailed(err)
 	}
+	results = c.backends.pathsToFront(b, results)
+	found := len(results)
+	results = c.visibleItems(senderInfo, results)
 
 	c.logger.LogMethod(context.Background(), "Collection.SearchItems", map[string]any{
 		"collection": string(path),
 		"attributes": attributes,
 		"count":      len(results),
+		"hidden":     found - len(results),
 	}, "ok", nil)
 
 	return results, nil
@@ -225,7 +291,9 @@ func (c *CollectionHandler) CreateItem(msg dbus.Message, properties map[string]d
 	}
 
 	// Extract item info from properties for the approval prompt
+	b, backendPath := c.backends.route(path)
 	itemInfo := extractItemInfo(string(path), properties)
+	itemInfo.Backend = b.Name
 
 	// Get a context that will be cancelled if the client disconnects
 	sender := senderOf(msg)
@@ -259,7 +327,7 @@ func (c *CollectionHandler) CreateItem(msg dbus.Message, properties map[string]d
 
 	// Map the remote session to the upstream session, decrypting the value for
 	// DH sessions before forwarding it to the (plain) upstream service.
-	localSecret, ok, err := c.sessions.ForUpstream(secret)
+	localSecret, ok, err := c.sessions.ForUpstream(secret, b)
 	if !ok {
 		return "/", "/", dbustypes.ErrSessionNotFound(string(secret.Session))
 	}
@@ -268,7 +336,7 @@ func (c *CollectionHandler) CreateItem(msg dbus.Message, properties map[string]d
 		return "/", "/", dbustypes.ErrFailed(err)
 	}
 
-	obj := c.upstream(path)
+	obj := b.object(backendPath)
 	call := c.upstreamWithContext(UpstreamCallContext{
 		RequestType: approval.RequestTypeWrite,
 		Items:       items,
@@ -284,6 +352,7 @@ func (c *CollectionHandler) CreateItem(msg dbus.Message, properties map[string]d
 	if err := call.Store(&item, &prompt); err != nil {
 		return "/", "/", dbustypes.ErrFailed(err)
 	}
+	item, prompt = c.backends.toFront(b, item), c.backends.toFront(b, prompt)
 
 	// Cache the new item path so immediate read-back (e.g., gh verification) is auto-approved.
 	c.approval.CacheItemForSender(string(sender), string(item))
@@ -303,7 +372,8 @@ func (c *CollectionHandler) Get(msg dbus.Message, iface, property string) (dbus.
 		return dbus.Variant{}, dbustypes.ErrObjectNotFound(string(path))
 	}
 
-	obj := c.upstream(path)
+	b, backendPath := c.backends.route(path)
+	obj := b.object(backendPath)
 	sender := senderOf(msg)
 	r := WithSlowNotify(c.slowThreshold, c.upstreamNotifier, UpstreamCallContext{
 		ResolveSender: c.senderResolver(sender),
@@ -315,7 +385,7 @@ func (c *CollectionHandler) Get(msg dbus.Message, iface, property string) (dbus.
 		return dbus.Variant{}, dbustypes.ErrFailed(r.err)
 	}
 
-	return r.v, nil
+	return c.backends.valueToFront(b, r.v).(dbus.Variant), nil
 }
 
 // GetAll implements org.freedesktop.DBus.Properties.GetAll for collections.
@@ -325,7 +395,8 @@ func (c *CollectionHandler) GetAll(msg dbus.Message, iface string) (map[string]d
 		return nil, dbustypes.ErrObjectNotFound(string(path))
 	}
 
-	obj := c.upstream(path)
+	b, backendPath := c.backends.route(path)
+	obj := b.object(backendPath)
 	sender := senderOf(msg)
 	call := c.upstreamWithContext(UpstreamCallContext{
 		ResolveSender: c.senderResolver(sender),
@@ -339,7 +410,7 @@ func (c *CollectionHandler) GetAll(msg dbus.Message, iface string) (map[string]d
 		return nil, dbustypes.ErrFailed(err)
 	}
 
-	return props, nil
+	return c.backends.valueToFront(b, props).(map[string]dbus.Variant), nil
 }
 
 // Set implements org.freedesktop.DBus.Properties.Set for collections.
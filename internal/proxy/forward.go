@@ -46,7 +46,12 @@ type callForwarder struct {
 // forward re-issues msg on the destination connection and returns the reply
 // body, propagating a remote D-Bus error faithfully.
 func (f callForwarder) forward(msg dbus.Message) ([]any, *dbus.Error) {
-	obj := f.dst.Object(f.dstName, pathOf(msg))
+	return f.forwardAt(pathOf(msg), msg)
+}
+
+// forwardAt is forward addressed to path instead of the message's own path.
+func (f callForwarder) forwardAt(path dbus.ObjectPath, msg dbus.Message) ([]any, *dbus.Error) {
+	obj := f.dst.Object(f.dstName, path)
 	call := obj.Call(interfaceOf(msg)+"."+memberOf(msg), 0, msg.Body...)
 	if call.Err != nil {
 		if derr, ok := errors.AsType[dbus.Error](call.Err); ok {
@@ -10,12 +10,13 @@ import (
 	"github.com/nikicat/secrets-dispatcher/internal/approval"
 	dbustypes "github.com/nikicat/secrets-dispatcher/internal/dbus"
 	"github.com/nikicat/secrets-dispatcher/internal/logging"
+	"github.com/nikicat/secrets-dispatcher/internal/trash"
 )
 
 // ItemHandler handles Item interface calls for item objects.
 // It is exported as a subtree handler for /org/freedesktop/secrets/collection/*/*.
 type ItemHandler struct {
-	localConn        *dbus.Conn
+	backends         backendSet
 	sessions         *SessionManager
 	logger           *logging.Logger
 	approval         *approval.Manager
@@ -24,12 +25,13 @@ type ItemHandler struct {
 	resolver         *SenderInfoResolver
 	upstreamNotifier UpstreamNotifier
 	slowThreshold    time.Duration
+	trash            *trash.Store // nil = deletions are not kept
 }
 
 // NewItemHandler creates a new ItemHandler.
-func NewItemHandler(localConn *dbus.Conn, sessions *SessionManager, logger *logging.Logger, approvalMgr *approval.Manager, clientName string, tracker *clientTracker, resolver *SenderInfoResolver, upstreamNotifier UpstreamNotifier, slowThreshold time.Duration) *ItemHandler {
+func NewItemHandler(backends backendSet, sessions *SessionManager, logger *logging.Logger, approvalMgr *approval.Manager, clientName string, tracker *clientTracker, resolver *SenderInfoResolver, upstreamNotifier UpstreamNotifier, slowThreshold time.Duration, trashStore *trash.Store) *ItemHandler {
 	return &ItemHandler{
-		localConn:        localConn,
+		backends:         backends,
 		sessions:         sessions,
 		logger:           logger,
 		approval:         approvalMgr,
@@ -38,12 +40,14 @@ func NewItemHandler(localConn *dbus.Conn, sessions *SessionManager, logger *logg
 		resolver:         resolver,
 		upstreamNotifier: upstreamNotifier,
 		slowThreshold:    slowThreshold,
+		trash:            trashStore,
 	}
 }
 
 // upstream returns the backend object at path on the upstream Secret Service bus.
 func (i *ItemHandler) upstream(path dbus.ObjectPath) dbus.BusObject {
-	return i.localConn.Object(dbustypes.BusName, path)
+	b, bp := i.backends.route(path)
+	return b.object(bp)
 }
 
 // senderResolver returns a lazy resolver of sender's SenderInfo, suitable for an
@@ -112,13 +116,28 @@ func (i *ItemHandler) Delete(msg dbus.Message) (dbus.ObjectPath, *dbus.Error) {
 		return "/", dbustypes.ErrAccessDenied(err.Error())
 	}
 
-	obj := i.upstream(path)
+	// Keep a copy first, so that an approval given by mistake can be undone.
+	var trashID string
+	b, backendPath := i.backends.route(path)
+	if i.trash != nil {
+		entry := trash.Entry{Kind: trash.KindItem, Client: i.clientName, Backend: b.Name, Collection: parentCollection(backendPath)}
+		var err error
+		if trashID, err = keepDeleted(i.trash, b.conn(), entry, []dbus.ObjectPath{backendPath}); err != nil {
+			i.logger.LogMethod(ctx, "Item.Delete", map[string]any{"item": string(path)}, "error", err)
+			return "/", dbustypes.ErrFailed(err)
+		}
+	}
+
+	obj := b.object(backendPath)
 	call := i.upstreamWithContext(UpstreamCallContext{
 		RequestType: approval.RequestTypeDelete,
 		Items:       items,
 		SenderInfo:  senderInfo,
 	}, func() *dbus.Call { return obj.Call(dbustypes.ItemInterface+".Delete", 0) })
 	if call.Err != nil {
+		if trashID != "" {
+			i.trash.Remove(trashID) //nolint:errcheck // the item was not deleted
+		}
 		return "/", dbustypes.ErrFailed(call.Err)
 	}
 
@@ -128,10 +147,11 @@ func (i *ItemHandler) Delete(msg dbus.Message) (dbus.ObjectPath, *dbus.Error) {
 	}
 
 	i.logger.LogMethod(context.Background(), "Item.Delete", map[string]any{
-		"item": string(path),
+		"item":  string(path),
+		"trash": trashID,
 	}, "ok", nil)
 
-	return prompt, nil
+	return i.backends.toFront(b, prompt), nil
 }
 
 // GetSecret retrieves the secret for this item.
@@ -165,10 +185,11 @@ func (i *ItemHandler) GetSecret(msg dbus.Message, session dbus.ObjectPath) (dbus
 	}
 
 	// Map remote session to local session
-	localSession, ok := i.sessions.GetLocalSession(session)
-	if !ok {
-		i.logger.LogItemGetSecret(context.Background(), string(path), "error", dbustypes.ErrSessionNotFound(string(session)))
-		return dbustypes.Secret{}, dbustypes.ErrSessionNotFound(string(session))
+	b, _ := i.backends.route(path)
+	localSession, derr := i.sessions.GetLocalSession(session, b)
+	if derr != nil {
+		i.logger.LogItemGetSecret(context.Background(), string(path), "error", derr)
+		return dbustypes.Secret{}, derr
 	}
 
 	obj := i.upstream(path)
@@ -236,7 +257,8 @@ func (i *ItemHandler) SetSecret(msg dbus.Message, secret dbustypes.Secret) *dbus
 
 	// Map the remote session to the upstream session, decrypting the value for
 	// DH sessions before forwarding it to the (plain) upstream service.
-	localSecret, ok, err := i.sessions.ForUpstream(secret)
+	b, _ := i.backends.route(path)
+	localSecret, ok, err := i.sessions.ForUpstream(secret, b)
 	if !ok {
 		return dbustypes.ErrSessionNotFound(string(secret.Session))
 	}
@@ -331,9 +353,10 @@ func (i *ItemHandler) Set(msg dbus.Message, iface, property string, value dbus.V
 
 // getItemInfo fetches label and attributes for a secret item from D-Bus.
 func (i *ItemHandler) getItemInfo(path dbus.ObjectPath, ctx UpstreamCallContext) approval.ItemInfo {
-	info := approval.ItemInfo{Path: string(path)}
+	b, bp := i.backends.route(path)
+	info := approval.ItemInfo{Path: string(path), Backend: b.Name}
 
-	obj := i.upstream(path)
+	obj := b.object(bp)
 
 	// Get Label property
 	if v, err := i.upstreamGetProperty(obj, dbustypes.ItemInterface+".Label", ctx); err == nil {
@@ -13,6 +13,8 @@ import (
 	"github.com/fsnotify/fsnotify"
 	"github.com/godbus/dbus/v5"
 	"github.com/nikicat/secrets-dispatcher/internal/approval"
+	"github.com/nikicat/secrets-dispatcher/internal/pairing"
+	"github.com/nikicat/secrets-dispatcher/internal/trash"
 )
 
 // ClientInfo represents information about a connected client.
@@ -37,6 +39,7 @@ type proxyInstance struct {
 type Manager struct {
 	socketsDir            string
 	upstreamAddr          string                    // D-Bus address for upstream (empty = session bus)
+	upstreams             []Upstream                // replaces upstreamAddr when set
 	proxies               map[string]*proxyInstance // socketPath -> proxyInstance
 	mu                    sync.RWMutex
 	watcher               *fsnotify.Watcher
@@ -45,6 +48,9 @@ type Manager struct {
 	trimProcessChain      bool
 	upstreamNotifier      UpstreamNotifier
 	upstreamSlowThreshold time.Duration
+	clients               *pairing.Store // nil = sockets are not gated on pairing
+	trash                 *trash.Store   // nil = deletions are not kept
+	upstreamMonitor       *UpstreamMonitor
 
 	observersMu sync.RWMutex
 	observers   []ClientObserver
@@ -76,6 +82,32 @@ func NewManager(socketsDir, upstreamAddr string, approval *approval.Manager, log
 	}, nil
 }
 
+// RequirePairing gates every socket on client pairing against store: a socket
+// is only offered the Secret Service once its remote host is paired and proves
+// it. Must be called before Run.
+func (m *Manager) RequirePairing(store *pairing.Store) {
+	m.clients = store
+}
+
+// KeepDeleted saves every approved deletion on the sockets to store, so it can
+// be undone. Must be called before Run.
+func (m *Manager) KeepDeleted(store *trash.Store) {
+	m.trash = store
+}
+
+// UseUpstreams proxies every socket to upstreams, the first being the default
+// backend, instead of the single upstream given to NewManager. Must be called
+// before Run.
+func (m *Manager) UseUpstreams(upstreams []Upstream) {
+	m.upstreams = upstreams
+}
+
+// MonitorUpstreams reports to monitor when an upstream of a socket's proxy is
+// lost and reconnected. Must be called before Run.
+func (m *Manager) MonitorUpstreams(monitor *UpstreamMonitor) {
+	m.upstreamMonitor = monitor
+}
+
 // Run starts watching for sockets and managing proxies.
 // It blocks until the context is cancelled.
 func (m *Manager) Run(ctx context.Context) error {
@@ -211,8 +243,9 @@ func isSocketFile(name string) bool {
 	return strings.HasSuffix(name, ".sock")
 }
 
-// clientNameFromSocket derives a client name from a socket filename.
-func clientNameFromSocket(socketPath string) string {
+// ClientNameFromSocket derives a client name from a socket filename. It is the
+// name trust rules' client matcher and client policies are compared against.
+func ClientNameFromSocket(socketPath string) string {
 	base := filepath.Base(socketPath)
 	return strings.TrimSuffix(base, ".sock")
 }
@@ -226,7 +259,7 @@ func (m *Manager) startProxy(ctx context.Context, socketPath string) {
 	}
 	m.mu.Unlock()
 
-	clientName := clientNameFromSocket(socketPath)
+	clientName := ClientNameFromSocket(socketPath)
 
 	p := New(Config{
 		ClientName:            clientName,
@@ -235,18 +268,19 @@ func (m *Manager) startProxy(ctx context.Context, socketPath string) {
 		TrimProcessChain:      m.trimProcessChain,
 		UpstreamNotifier:      m.upstreamNotifier,
 		UpstreamSlowThreshold: m.upstreamSlowThreshold,
+		Clients:               m.clients,
+		Trash:                 m.trash,
+		Upstreams:             m.upstreamMonitor,
 	})
 
 	proxyCtx, cancel := context.WithCancel(ctx)
 
 	// Connect to upstream (backend)
-	var backendConn *dbus.Conn
-	var err error
-	if m.upstreamAddr == "" {
-		backendConn, err = dbus.ConnectSessionBus()
-	} else {
-		backendConn, err = dbus.Connect(m.upstreamAddr)
+	upstreams := m.upstreams
+	if len(upstreams) == 0 {
+		upstreams = []Upstream{{Name: DefaultBackend, Addr: m.upstreamAddr}}
 	}
+	backends, err := DialUpstreams(upstreams)
 	if err != nil {
 		slog.Error("failed to connect to upstream",
 			"upstream", m.upstreamAddr,
@@ -258,7 +292,9 @@ func (m *Manager) startProxy(ctx context.Context, socketPath string) {
 	// Connect to downstream socket (front)
 	frontConn, err := dbus.Connect("unix:path=" + socketPath)
 	if err != nil {
-		backendConn.Close()
+		for i := range backends {
+			backends[i].Conn.Close()
+		}
 		slog.Error("failed to connect to downstream socket",
 			"socket", socketPath,
 			"error", err)
@@ -266,7 +302,7 @@ func (m *Manager) startProxy(ctx context.Context, socketPath string) {
 		return
 	}
 
-	if err := p.ConnectWith(frontConn, backendConn); err != nil {
+	if err := p.ConnectBackends(frontConn, backends); err != nil {
 		slog.Error("failed to connect proxy",
 			"socket", socketPath,
 			"client", clientName,
@@ -23,9 +23,9 @@ func TestClientNameFromSocket(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.path, func(t *testing.T) {
-			result := clientNameFromSocket(tc.path)
+			result := ClientNameFromSocket(tc.path)
 			if result != tc.expected {
-				t.Errorf("clientNameFromSocket(%q) = %q, want %q", tc.path, result, tc.expected)
+				t.Errorf("ClientNameFromSocket(%q) = %q, want %q", tc.path, result, tc.expected)
 			}
 		})
 	}
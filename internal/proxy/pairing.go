@@ -0,0 +1,138 @@
+package proxy
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/google/uuid"
+	"github.com/nikicat/secrets-dispatcher/internal/approval"
+	dbustypes "github.com/nikicat/secrets-dispatcher/internal/dbus"
+	"github.com/nikicat/secrets-dispatcher/internal/pairing"
+)
+
+// authTimeout bounds the per-connection challenge, including the time the
+// remote session bus takes to activate the responder.
+const authTimeout = 15 * time.Second
+
+// pairSession is a handshake between Begin and Confirm.
+type pairSession struct {
+	id       string
+	sender   senderName
+	identity ed25519.PublicKey
+	commit   []byte
+	nonce    []byte
+}
+
+// pairingHandler implements pairing.Interface on a socket downstream's front
+// bus. A single handshake may be in flight at a time; a new Begin replaces it.
+type pairingHandler struct {
+	clientName string
+	store      *pairing.Store
+	approval   *approval.Manager
+	resolver   *SenderInfoResolver
+	ctx        context.Context // cancelled when the front connection closes
+	onPaired   func()
+
+	mu      sync.Mutex
+	session *pairSession
+}
+
+// Begin starts a handshake: it records the remote identity key and its
+// commitment and returns a session ID and the dispatcher's nonce.
+func (h *pairingHandler) Begin(msg dbus.Message, identity, commitment []byte) (string, []byte, *dbus.Error) {
+	if len(identity) != ed25519.PublicKeySize {
+		return "", nil, dbustypes.ErrFailed(errors.New("identity must be an Ed25519 public key"))
+	}
+	nonce, err := pairing.NewNonce()
+	if err != nil {
+		return "", nil, dbustypes.ErrFailed(err)
+	}
+	s := &pairSession{
+		id:       uuid.New().String(),
+		sender:   senderOf(msg),
+		identity: ed25519.PublicKey(identity),
+		commit:   commitment,
+		nonce:    nonce,
+	}
+	h.mu.Lock()
+	h.session = s
+	h.mu.Unlock()
+	return s.id, nonce, nil
+}
+
+// Confirm reveals the remote nonce, checks it against the commitment and asks
+// the user to compare codes. It blocks until the user decides; on approval the
+// client is stored and the Secret Service is offered on this bus.
+func (h *pairingHandler) Confirm(msg dbus.Message, sessionID string, nonce []byte) *dbus.Error {
+	sender := senderOf(msg)
+	h.mu.Lock()
+	s := h.session
+	if s == nil || s.id != sessionID || s.sender != sender {
+		h.mu.Unlock()
+		return dbustypes.ErrFailed(errors.New("no such pairing session"))
+	}
+	h.session = nil
+	h.mu.Unlock()
+
+	if subtle.ConstantTimeCompare(pairing.Commit(s.identity, nonce), s.commit) != 1 {
+		return dbustypes.ErrAccessDenied("pairing commitment mismatch")
+	}
+
+	info := &approval.PairInfo{
+		Code:        pairing.Code(s.identity, nonce, s.nonce),
+		Fingerprint: pairing.Fingerprint(s.identity),
+	}
+	if err := h.approval.RequirePairing(h.ctx, h.clientName, info, h.resolver.Resolve(sender)); err != nil {
+		return dbustypes.ErrAccessDenied("pairing not approved: " + err.Error())
+	}
+
+	if err := h.store.Add(pairing.Client{
+		Name:      h.clientName,
+		PublicKey: base64.StdEncoding.EncodeToString(s.identity),
+		PairedAt:  time.Now(),
+	}); err != nil {
+		return dbustypes.ErrFailed(fmt.Errorf("save paired client: %w", err))
+	}
+	h.onPaired()
+	return nil
+}
+
+// authenticate challenges the remote host to prove it holds the key paired
+// under clientName. It returns an error if the client is not paired, the
+// responder is unreachable or the proof does not verify.
+func authenticate(ctx context.Context, conn *dbus.Conn, store *pairing.Store, clientName string) error {
+	client, err := store.Lookup(clientName)
+	if err != nil {
+		return err
+	}
+	if client == nil {
+		return pairing.ErrNotPaired
+	}
+	key, err := client.Key()
+	if err != nil {
+		return err
+	}
+	nonce, err := pairing.NewNonce()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, authTimeout)
+	defer cancel()
+	var sig []byte
+	obj := conn.Object(pairing.ResponderBusName, pairing.ResponderPath)
+	if err := obj.CallWithContext(ctx, pairing.ResponderInterface+".Prove", 0, nonce).Store(&sig); err != nil {
+		return fmt.Errorf("challenge remote host: %w", err)
+	}
+	if !pairing.Verify(key, nonce, sig) {
+		return fmt.Errorf("proof does not match the key paired as %s (%s)", clientName, client.Fingerprint())
+	}
+	return nil
+}
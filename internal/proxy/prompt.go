@@ -11,23 +11,31 @@ import (
 // PromptHandler handles Prompt interface calls for prompt objects.
 // It is exported as a subtree handler for /org/freedesktop/secrets/prompt/*.
 //
-// Prompt paths returned by the backend (from Unlock, Lock, CreateCollection,
-// CreateItem, Delete) are passed through to clients 1:1, so forwarding only
-// needs to relay the method calls; the Completed signal is already forwarded
-// by the signal forwarder.
+// Prompt paths returned by a backend (from Unlock, Lock, CreateCollection,
+// CreateItem, Delete) reach clients with only the backend's prefix added, so
+// forwarding only needs to route and relay the method calls; the Completed
+// signal is already forwarded by the signal forwarder.
 type PromptHandler struct {
-	toBackend callForwarder
-	logger    *logging.Logger
+	backends backendSet
+	logger   *logging.Logger
 }
 
 // NewPromptHandler creates a new PromptHandler.
-func NewPromptHandler(localConn *dbus.Conn, logger *logging.Logger) *PromptHandler {
+func NewPromptHandler(backends backendSet, logger *logging.Logger) *PromptHandler {
 	return &PromptHandler{
-		toBackend: callForwarder{dst: localConn, dstName: dbustypes.BusName},
-		logger:    logger,
+		backends: backends,
+		logger:   logger,
 	}
 }
 
+// forward relays msg to the prompt on the backend that owns it.
+func (h *PromptHandler) forward(msg dbus.Message) *dbus.Error {
+	b, path := h.backends.route(pathOf(msg))
+	f := callForwarder{dst: b.conn(), dstName: dbustypes.BusName}
+	_, err := f.forwardAt(path, msg)
+	return err
+}
+
 // isPromptPath checks if the path is a prompt object.
 // Prompt paths: /org/freedesktop/secrets/prompt/xxx
 func isPromptPath(path dbus.ObjectPath) bool {
@@ -46,7 +54,7 @@ func (h *PromptHandler) Prompt(msg dbus.Message, windowID string) *dbus.Error {
 
 	h.logger.Info("forwarding prompt", "path", path, "sender", senderOf(msg))
 
-	return h.toBackend.forwardVoid(msg)
+	return h.forward(msg)
 }
 
 // Dismiss dismisses the prompt.
@@ -58,5 +66,5 @@ func (h *PromptHandler) Dismiss(msg dbus.Message) *dbus.Error {
 
 	h.logger.Info("dismissing prompt", "path", path, "sender", senderOf(msg))
 
-	return h.toBackend.forwardVoid(msg)
+	return h.forward(msg)
 }
@@ -95,7 +95,7 @@ func TestPrompterBridge(t *testing.T) {
 	p := New(Config{ClientName: "prompter-test", LogLevel: slog.LevelDebug})
 	require.NoError(t, p.ConnectWith(proxyFrontConn, proxyBackendConn))
 	t.Cleanup(func() { p.Close() })
-	require.NotNil(t, p.prompter, "bridge should activate when the front bus has a prompter and the backend does not")
+	require.Len(t, p.prompters, 1, "bridge should activate when the front bus has a prompter and the backend does not")
 
 	// The backend gnome-keyring: a connection that exports a callback object
 	// and calls the SystemPrompter (now the bridge) on the backend bus.
@@ -166,7 +166,7 @@ func TestPrompterBridgeClaimsBackendBeforePrompter(t *testing.T) {
 
 	// It must own the backend name straight away — before any front prompter —
 	// so the gcr-prompter fallback has no window to claim it.
-	require.NotNil(t, p.prompter, "bridge must be created in the local-takeover topology")
+	require.Len(t, p.prompters, 1, "bridge must be created in the local-takeover topology")
 	assert.True(t, nameHasOwner(proxyBackendConn, systemPrompterName),
 		"bridge must claim the backend name at startup, before a front prompter exists")
 
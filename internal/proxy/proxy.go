@@ -3,27 +3,35 @@ package proxy
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/godbus/dbus/v5"
 	"github.com/nikicat/secrets-dispatcher/internal/approval"
 	dbustypes "github.com/nikicat/secrets-dispatcher/internal/dbus"
 	"github.com/nikicat/secrets-dispatcher/internal/logging"
+	"github.com/nikicat/secrets-dispatcher/internal/pairing"
+	"github.com/nikicat/secrets-dispatcher/internal/trash"
 )
 
-// Proxy connects to a front-facing D-Bus (where clients connect) and a backend
-// D-Bus (where the real Secret Service lives), registering as
-// org.freedesktop.secrets on the front bus and proxying requests to the backend.
+// Proxy connects to a front-facing D-Bus (where clients connect) and one or more
+// backend D-Buses (where the real Secret Services live), registering as
+// org.freedesktop.secrets on the front bus and proxying requests to the
+// backend that owns each object.
 type Proxy struct {
 	clientName            string
 	trimProcessChain      bool
 	upstreamNotifier      UpstreamNotifier
 	upstreamSlowThreshold time.Duration
+	clients               *pairing.Store // nil = no pairing gate
+	trash                 *trash.Store   // nil = deletions are not kept
+	upstreams             *UpstreamMonitor
 
-	frontConn   *dbus.Conn // clients connect here (session bus or remote socket)
-	backendConn *dbus.Conn // real Secret Service lives here (session bus or private bus)
+	frontConn *dbus.Conn // clients connect here (session bus or remote socket)
+	backends  backendSet // real Secret Services live here (session bus or private buses)
 
 	sessions *SessionManager
 	logger   *logging.Logger
@@ -36,8 +44,20 @@ type Proxy struct {
 	item              *ItemHandler
 	prompt            *PromptHandler
 	subtreeProperties *SubtreePropertiesHandler
-	signals           *signalForwarder
-	prompter          *prompterBridge
+	pairing           *pairingHandler
+
+	forwardMu sync.Mutex
+	signals   map[string]*signalForwarder // by backend name
+	prompters map[string]*prompterBridge  // by backend name; only where needed
+
+	stop     chan struct{} // closed by Close
+	stopOnce sync.Once
+	watchers sync.WaitGroup // backend watchers and the pairing check
+
+	serveMu  sync.Mutex
+	serving  bool // Secret Service exported and bus name owned
+	serveErr error
+	closed   bool // set by Close; nothing is exported after it
 }
 
 // Config holds configuration for the proxy.
@@ -48,6 +68,15 @@ type Config struct {
 	TrimProcessChain      bool
 	UpstreamNotifier      UpstreamNotifier
 	UpstreamSlowThreshold time.Duration
+	// Clients, when set, gates the front bus on client pairing: only the
+	// pairing interface is offered until the remote host proves it holds the
+	// key paired under ClientName, or completes a new pairing.
+	Clients *pairing.Store
+	// Trash, when set, keeps a copy of every item an approved Delete removes,
+	// so the deletion can be undone.
+	Trash *trash.Store
+	// Upstreams, when set, is told when an upstream is lost and reconnected.
+	Upstreams *UpstreamMonitor
 }
 
 // New creates a new Proxy with the given configuration.
@@ -71,14 +100,30 @@ func New(cfg Config) *Proxy {
 		approval:              approvalMgr,
 		upstreamNotifier:      cfg.UpstreamNotifier,
 		upstreamSlowThreshold: cfg.UpstreamSlowThreshold,
+		clients:               cfg.Clients,
+		trash:                 cfg.Trash,
+		upstreams:             cfg.Upstreams,
+		signals:               make(map[string]*signalForwarder),
+		prompters:             make(map[string]*prompterBridge),
+		stop:                  make(chan struct{}),
 	}
 }
 
 // ConnectWith sets up the proxy using pre-created D-Bus connections.
 // frontConn is where clients connect; backendConn is where the real Secret Service lives.
+// If backendConn is lost, the session bus is dialed in its place.
 func (p *Proxy) ConnectWith(frontConn, backendConn *dbus.Conn) error {
+	return p.ConnectBackends(frontConn, []Backend{{Upstream: Upstream{Name: DefaultBackend}, Conn: backendConn}})
+}
+
+// ConnectBackends is ConnectWith for several backends, the first being the
+// default one. The proxy takes ownership of every connection.
+func (p *Proxy) ConnectBackends(frontConn *dbus.Conn, backends []Backend) error {
 	p.frontConn = frontConn
-	p.backendConn = backendConn
+	p.backends = make(backendSet, len(backends))
+	for i := range backends {
+		p.backends[i] = &backends[i]
+	}
 
 	// Create client tracker to detect disconnects
 	var err error
@@ -92,25 +137,121 @@ func (p *Proxy) ConnectWith(frontConn, backendConn *dbus.Conn) error {
 	p.resolver = NewSenderInfoResolver(p.frontConn, p.trimProcessChain)
 
 	// Create handlers — they talk to the backend
-	p.service = NewService(p.backendConn, p.sessions, p.logger, p.approval, p.clientName, p.tracker, p.resolver, p.upstreamNotifier, p.upstreamSlowThreshold)
-	p.collection = NewCollectionHandler(p.backendConn, p.sessions, p.logger, p.approval, p.clientName, p.tracker, p.resolver, p.upstreamNotifier, p.upstreamSlowThreshold)
-	p.item = NewItemHandler(p.backendConn, p.sessions, p.logger, p.approval, p.clientName, p.tracker, p.resolver, p.upstreamNotifier, p.upstreamSlowThreshold)
-	p.prompt = NewPromptHandler(p.backendConn, p.logger)
-	p.subtreeProperties = NewSubtreePropertiesHandler(p.backendConn, p.sessions, p.logger)
+	p.service = NewService(p.backends, p.sessions, p.logger, p.approval, p.clientName, p.tracker, p.resolver, p.upstreamNotifier, p.upstreamSlowThreshold)
+	p.collection = NewCollectionHandler(p.backends, p.sessions, p.logger, p.approval, p.clientName, p.tracker, p.resolver, p.upstreamNotifier, p.upstreamSlowThreshold, p.trash)
+	p.item = NewItemHandler(p.backends, p.sessions, p.logger, p.approval, p.clientName, p.tracker, p.resolver, p.upstreamNotifier, p.upstreamSlowThreshold, p.trash)
+	p.prompt = NewPromptHandler(p.backends, p.logger)
+	p.subtreeProperties = NewSubtreePropertiesHandler(p.backends, p.sessions, p.logger, p.collection)
+
+	if p.clients == nil {
+		if err := p.serveSecretService(); err != nil {
+			p.Close()
+			return err
+		}
+		return nil
+	}
+
+	if err := p.exportPairing(); err != nil {
+		p.Close()
+		return err
+	}
+	p.watchers.Add(1)
+	go func() {
+		defer p.watchers.Done()
+		p.authenticatePaired()
+	}()
+	return nil
+}
+
+// exportPairing offers the pairing interface under pairing.BusName, so an
+// unpaired remote host can pair while the Secret Service stays withheld.
+func (p *Proxy) exportPairing() error {
+	p.pairing = &pairingHandler{
+		clientName: p.clientName,
+		store:      p.clients,
+		approval:   p.approval,
+		resolver:   p.resolver,
+		ctx:        p.frontConn.Context(),
+		onPaired: func() {
+			p.logger.Info("client paired")
+			if err := p.serveSecretService(); err != nil {
+				p.logger.Error("failed to serve Secret Service after pairing", "error", err)
+			}
+		},
+	}
+	if err := p.frontConn.Export(p.pairing, pairing.ObjectPath, pairing.Interface); err != nil {
+		return fmt.Errorf("export pairing interface: %w", err)
+	}
+	reply, err := p.frontConn.RequestName(pairing.BusName, dbus.NameFlagReplaceExisting)
+	if err != nil {
+		return fmt.Errorf("request bus name %s: %w", pairing.BusName, err)
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		return fmt.Errorf("failed to become primary owner of %s (reply=%d)", pairing.BusName, reply)
+	}
+	return nil
+}
+
+// authenticatePaired challenges a previously paired remote host and, if it
+// proves its key, starts serving the Secret Service. Unpaired or failing
+// clients are left with only the pairing interface.
+func (p *Proxy) authenticatePaired() {
+	ctx, cancel := context.WithCancel(p.frontConn.Context())
+	defer cancel()
+	go func() {
+		select {
+		case <-p.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	err := authenticate(ctx, p.frontConn, p.clients, p.clientName)
+	switch {
+	case ctx.Err() != nil:
+		return
+	case errors.Is(err, pairing.ErrNotPaired):
+		p.logger.Warn("client not paired; refusing Secret Service until `secrets-dispatcher pair` is run on the remote host")
+		return
+	case err != nil:
+		p.logger.Warn("paired client failed authentication; refusing Secret Service", "error", err)
+		return
+	}
+	if err := p.serveSecretService(); err != nil {
+		p.logger.Error("failed to serve Secret Service", "error", err)
+	}
+}
+
+// serveSecretService exports the Secret Service objects on the front bus and
+// takes org.freedesktop.secrets. It runs at most once per proxy, and not
+// after Close.
+func (p *Proxy) serveSecretService() error {
+	p.serveMu.Lock()
+	defer p.serveMu.Unlock()
+	if p.closed {
+		return errors.New("proxy closed")
+	}
+	if p.serving || p.serveErr != nil {
+		return p.serveErr
+	}
+	p.serveErr = p.exportSecretService()
+	p.serving = p.serveErr == nil
+	return p.serveErr
+}
+
+func (p *Proxy) exportSecretService() error {
+	var err error
 
 	// Export interfaces on the front connection (where clients call us)
 	if err := p.frontConn.Export(p.service, dbustypes.ServicePath, dbustypes.ServiceInterface); err != nil {
-		p.Close()
 		return fmt.Errorf("export Service interface: %w", err)
 	}
 
 	if err := p.frontConn.Export(p.service, dbustypes.ServicePath, "org.freedesktop.DBus.Properties"); err != nil {
-		p.Close()
 		return fmt.Errorf("export Properties interface for Service: %w", err)
 	}
 
 	if err := p.frontConn.Export(introspectable{p.service.Introspect}, dbustypes.ServicePath, "org.freedesktop.DBus.Introspectable"); err != nil {
-		p.Close()
 		return fmt.Errorf("export Introspectable interface: %w", err)
 	}
 
@@ -119,17 +260,14 @@ func (p *Proxy) ConnectWith(frontConn, backendConn *dbus.Conn) error {
 	// /org/freedesktop/secrets/aliases/default directly.
 	for _, prefix := range []string{"/org/freedesktop/secrets/collection", "/org/freedesktop/secrets/aliases"} {
 		if err := p.frontConn.ExportSubtree(p.collection, dbus.ObjectPath(prefix), dbustypes.CollectionInterface); err != nil {
-			p.Close()
 			return fmt.Errorf("export Collection subtree at %s: %w", prefix, err)
 		}
 
 		if err := p.frontConn.ExportSubtree(p.subtreeProperties, dbus.ObjectPath(prefix), "org.freedesktop.DBus.Properties"); err != nil {
-			p.Close()
 			return fmt.Errorf("export Properties subtree at %s: %w", prefix, err)
 		}
 
 		if err := p.frontConn.ExportSubtree(p.item, dbus.ObjectPath(prefix), dbustypes.ItemInterface); err != nil {
-			p.Close()
 			return fmt.Errorf("export Item subtree at %s: %w", prefix, err)
 		}
 	}
@@ -139,41 +277,78 @@ func (p *Proxy) ConnectWith(frontConn, backendConn *dbus.Conn) error {
 	// collection is a dead end: the client gets a prompt path from us but the
 	// object "does not implement org.freedesktop.Secret.Prompt".
 	if err := p.frontConn.ExportSubtree(p.prompt, "/org/freedesktop/secrets/prompt", dbustypes.PromptInterface); err != nil {
-		p.Close()
 		return fmt.Errorf("export Prompt subtree: %w", err)
 	}
 
 	// Request the bus name on the front connection
 	reply, err := p.frontConn.RequestName(dbustypes.BusName, dbus.NameFlagReplaceExisting)
 	if err != nil {
-		p.Close()
 		return fmt.Errorf("request bus name: %w", err)
 	}
 	if reply != dbus.RequestNameReplyPrimaryOwner {
-		p.Close()
 		return fmt.Errorf("failed to become primary owner of %s (reply=%d)", dbustypes.BusName, reply)
 	}
 
+	for _, b := range p.backends {
+		if err := p.startForwarding(b); err != nil {
+			return err
+		}
+	}
+
+	// Follow every backend, to recover from an upstream restart.
+	for _, b := range p.backends {
+		p.watchers.Add(1)
+		go func() {
+			defer p.watchers.Done()
+			p.watchBackend(b)
+		}()
+	}
+
+	return nil
+}
+
+// startForwarding starts relaying signals and unlock prompts between backend
+// b and the front.
+func (p *Proxy) startForwarding(b *Backend) error {
+	p.forwardMu.Lock()
+	defer p.forwardMu.Unlock()
+
 	// Forward signals from backend to frontend so clients see live updates
-	p.signals, err = newSignalForwarder(p.backendConn, p.frontConn, p.logger)
+	toFront := func(v any) any { return p.backends.valueToFront(b, v) }
+	signals, err := newSignalForwarder(b.conn(), p.frontConn, toFront, p.logger)
 	if err != nil {
-		p.Close()
-		return fmt.Errorf("start signal forwarder: %w", err)
+		return fmt.Errorf("start signal forwarder for %s: %w", b.Name, err)
 	}
+	p.signals[b.Name] = signals
 
 	// Bridge the backend keyring's unlock prompter to the session prompter
 	// (gnome-shell), so a locked-collection unlock renders a real dialog
 	// instead of hanging on the display-less fallback. No-op when the
 	// topology doesn't need it (returns nil, nil).
-	p.prompter, err = newPrompterBridge(p.frontConn, p.backendConn, p.logger)
+	prompter, err := newPrompterBridge(p.frontConn, b.conn(), p.logger)
 	if err != nil {
-		p.Close()
-		return fmt.Errorf("start prompter bridge: %w", err)
+		return fmt.Errorf("start prompter bridge for %s: %w", b.Name, err)
+	}
+	if prompter != nil {
+		p.prompters[b.Name] = prompter
 	}
-
 	return nil
 }
 
+// stopForwarding undoes startForwarding for backend b.
+func (p *Proxy) stopForwarding(b *Backend) {
+	p.forwardMu.Lock()
+	defer p.forwardMu.Unlock()
+	if prompter, ok := p.prompters[b.Name]; ok {
+		prompter.close()
+		delete(p.prompters, b.Name)
+	}
+	if signals, ok := p.signals[b.Name]; ok {
+		signals.close()
+		delete(p.signals, b.Name)
+	}
+}
+
 // Run blocks until the context is cancelled or the front connection is closed.
 func (p *Proxy) Run(ctx context.Context) error {
 	if p.frontConn == nil {
@@ -200,27 +375,31 @@ func (p *Proxy) Run(ctx context.Context) error {
 func (p *Proxy) Close() error {
 	p.logger.Info("shutting down")
 
-	if p.prompter != nil {
-		p.prompter.close()
-	}
+	p.stopOnce.Do(func() { close(p.stop) })
+	// Let an export in progress finish and keep later ones (a pairing
+	// completing now) from starting, so no watcher is added after Wait.
+	p.serveMu.Lock()
+	p.closed = true
+	p.serveMu.Unlock()
+	p.watchers.Wait()
 
-	if p.signals != nil {
-		p.signals.close()
+	for _, b := range p.backends {
+		p.stopForwarding(b)
 	}
 
 	if p.tracker != nil {
 		p.tracker.close()
 	}
 
-	if p.sessions != nil && p.backendConn != nil {
-		p.sessions.CloseAll(p.backendConn)
+	if p.sessions != nil && len(p.backends) > 0 {
+		p.sessions.CloseAll(p.backends)
 	}
 
 	if p.frontConn != nil {
 		p.frontConn.Close()
 	}
-	if p.backendConn != nil {
-		p.backendConn.Close()
+	for _, b := range p.backends {
+		b.conn().Close()
 	}
 
 	return nil
@@ -0,0 +1,196 @@
+package proxy
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	dbustypes "github.com/nikicat/secrets-dispatcher/internal/dbus"
+)
+
+// Bounds of the wait between attempts to redial a lost upstream connection.
+const (
+	reconnectMinBackoff = time.Second
+	reconnectMaxBackoff = 30 * time.Second
+)
+
+var (
+	errConnectionLost = errors.New("connection to the upstream bus lost")
+	errServiceStopped = errors.New("upstream Secret Service stopped")
+)
+
+// UpstreamState is the health of one upstream as last reported by a proxy.
+type UpstreamState struct {
+	Name      string    `json:"name"`
+	Connected bool      `json:"connected"`
+	Since     time.Time `json:"since"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// UpstreamObserver is told when an upstream goes down or comes back.
+type UpstreamObserver interface {
+	OnUpstreamChanged(state UpstreamState)
+}
+
+// UpstreamMonitor collects the health of the upstreams from every proxy that
+// talks to them, for the status endpoint and the web UI.
+type UpstreamMonitor struct {
+	mu        sync.RWMutex
+	states    []UpstreamState
+	observers []UpstreamObserver
+}
+
+// NewUpstreamMonitor returns a monitor for the named upstreams, all initially
+// connected.
+func NewUpstreamMonitor(names []string) *UpstreamMonitor {
+	m := &UpstreamMonitor{states: make([]UpstreamState, len(names))}
+	now := time.Now()
+	for i, name := range names {
+		m.states[i] = UpstreamState{Name: name, Connected: true, Since: now}
+	}
+	return m
+}
+
+// States returns the current state of every upstream.
+func (m *UpstreamMonitor) States() []UpstreamState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return slices.Clone(m.states)
+}
+
+// Subscribe registers an observer for state changes.
+func (m *UpstreamMonitor) Subscribe(o UpstreamObserver) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.observers = append(m.observers, o)
+}
+
+// report records that the named upstream is connected (err == nil) or down,
+// and tells the observers if that changed its state.
+func (m *UpstreamMonitor) report(name string, err error) {
+	state := UpstreamState{Name: name, Connected: err == nil, Since: time.Now()}
+	if err != nil {
+		state.Error = err.Error()
+	}
+
+	m.mu.Lock()
+	i := slices.IndexFunc(m.states, func(s UpstreamState) bool { return s.Name == name })
+	if i < 0 {
+		m.states = append(m.states, state)
+	} else if m.states[i].Connected == state.Connected && m.states[i].Error == state.Error {
+		m.mu.Unlock()
+		return
+	} else {
+		m.states[i] = state
+	}
+	observers := slices.Clone(m.observers)
+	m.mu.Unlock()
+
+	for _, o := range observers {
+		o.OnUpstreamChanged(state)
+	}
+}
+
+// reportUpstream passes b's state to the monitor, if any.
+func (p *Proxy) reportUpstream(b *Backend, err error) {
+	if p.upstreams != nil {
+		p.upstreams.report(b.Name, err)
+	}
+}
+
+// watchBackend follows backend b until the proxy closes. When the Secret
+// Service on b restarts, the upstream sessions become invalid and are
+// reopened on next use (see SessionManager.GetLocalSession). When the
+// connection itself is lost, calls fail fast with ErrUpstreamUnavailable
+// while it is redialed, and the signal forwarding is restarted on the new
+// connection.
+func (p *Proxy) watchBackend(b *Backend) {
+	for {
+		if !p.watchOwner(b, b.conn()) {
+			return
+		}
+		p.logger.Warn("upstream connection lost, reconnecting", "backend", b.Name)
+		b.setLost(errConnectionLost)
+		p.reportUpstream(b, errConnectionLost)
+		p.stopForwarding(b)
+
+		conn, ok := p.redial(b)
+		if !ok {
+			return
+		}
+		b.setConn(conn)
+		if err := p.startForwarding(b); err != nil {
+			p.logger.Warn("failed to restart upstream forwarding", "backend", b.Name, "error", err)
+		}
+		p.logger.Info("upstream reconnected", "backend", b.Name)
+		p.reportUpstream(b, nil)
+	}
+}
+
+// watchOwner follows the owner of the Secret Service name on conn until conn
+// is lost (true) or the proxy closes (false).
+func (p *Proxy) watchOwner(b *Backend, conn *dbus.Conn) bool {
+	ch := make(chan *dbus.Signal, 16)
+	conn.Signal(ch)
+	defer conn.RemoveSignal(ch)
+	if err := conn.AddMatchSignal(
+		dbus.WithMatchSender("org.freedesktop.DBus"),
+		dbus.WithMatchMember("NameOwnerChanged"),
+		dbus.WithMatchArg(0, dbustypes.BusName),
+	); err != nil {
+		p.logger.Warn("failed to watch the upstream Secret Service", "backend", b.Name, "error", err)
+	}
+
+	for {
+		select {
+		case <-p.stop:
+			return false
+		case <-conn.Context().Done():
+			select {
+			case <-p.stop:
+				return false
+			default:
+				return true
+			}
+		case sig, ok := <-ch:
+			if !ok {
+				continue // conn closed; its context is done too
+			}
+			if sig.Name != "org.freedesktop.DBus.NameOwnerChanged" || len(sig.Body) != 3 {
+				continue
+			}
+			if name, _ := sig.Body[0].(string); name != dbustypes.BusName {
+				continue
+			}
+			b.epoch.Add(1)
+			if owner, _ := sig.Body[2].(string); owner == "" {
+				p.logger.Warn("upstream Secret Service stopped", "backend", b.Name)
+				p.reportUpstream(b, errServiceStopped)
+			} else {
+				p.logger.Info("upstream Secret Service started", "backend", b.Name, "owner", owner)
+				p.reportUpstream(b, nil)
+			}
+		}
+	}
+}
+
+// redial connects to b's bus again, backing off between attempts, until it
+// succeeds or the proxy closes.
+func (p *Proxy) redial(b *Backend) (*dbus.Conn, bool) {
+	backoff := reconnectMinBackoff
+	for {
+		select {
+		case <-p.stop:
+			return nil, false
+		case <-time.After(backoff):
+		}
+		conn, err := dialUpstream(b.Upstream)
+		if err == nil {
+			return conn, true
+		}
+		p.logger.Debug("upstream redial failed", "backend", b.Name, "error", err, "retry_in", backoff)
+		backoff = min(backoff*2, reconnectMaxBackoff)
+	}
+}
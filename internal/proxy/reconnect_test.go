@@ -0,0 +1,39 @@
+package proxy
+
+import (
+	"errors"
+	"testing"
+)
+
+type recordingObserver []UpstreamState
+
+func (r *recordingObserver) OnUpstreamChanged(state UpstreamState) {
+	*r = append(*r, state)
+}
+
+func TestUpstreamMonitorReport(t *testing.T) {
+	m := NewUpstreamMonitor([]string{"personal", "work"})
+	var seen recordingObserver
+	m.Subscribe(&seen)
+
+	m.report("work", nil) // already connected
+	if len(seen) != 0 {
+		t.Fatalf("unchanged state notified: %+v", seen)
+	}
+
+	m.report("work", errConnectionLost)
+	m.report("work", errConnectionLost)
+	if len(seen) != 1 || seen[0].Name != "work" || seen[0].Connected || seen[0].Error != errConnectionLost.Error() {
+		t.Fatalf("notifications = %+v", seen)
+	}
+	states := m.States()
+	if !states[0].Connected || states[1].Connected {
+		t.Errorf("States = %+v", states)
+	}
+
+	m.report("work", errors.New("other"))
+	m.report("work", nil)
+	if len(seen) != 3 || !seen[2].Connected || seen[2].Error != "" {
+		t.Errorf("notifications = %+v", seen)
+	}
+}
@@ -80,6 +80,14 @@ func (r *SenderInfoResolver) Resolve(sender senderName) approval.SenderInfo {
 		}
 	}
 
+	// Sandboxed and containerized callers are identified by their app ID or
+	// container: their exe is a path inside the sandbox that says little.
+	// Checked on the connection's own PID — for Flatpak that is the app's
+	// D-Bus proxy, which runs in the app's sandbox.
+	if info.PID != 0 {
+		info.ReadSandbox(int32(info.PID))
+	}
+
 	// Resolve the user-facing invoker process via /proc.
 	// Falls back to the systemd unit as the display name if /proc walking fails.
 	if info.PID != 0 {
@@ -92,11 +100,12 @@ func (r *SenderInfoResolver) Resolve(sender senderName) approval.SenderInfo {
 					continue
 				}
 				info.ProcessChain = append(info.ProcessChain, approval.ProcessInfo{
-					Name: entry.Comm,
-					PID:  uint32(entry.PID),
-					Exe:  entry.Exe,
-					Args: entry.Args,
-					CWD:  entry.CWD,
+					Name:      entry.Comm,
+					PID:       uint32(entry.PID),
+					StartTime: entry.StartTime,
+					Exe:       entry.Exe,
+					Args:      entry.Args,
+					CWD:       entry.CWD,
 				})
 			}
 			// Resolve invoker (skip shells) for the display InvokerName (comm).
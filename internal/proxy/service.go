@@ -2,6 +2,7 @@ package proxy
 
 import (
 	"context"
+	"fmt"
 	"sort"
 	"time"
 
@@ -13,7 +14,7 @@ import (
 
 // Service implements org.freedesktop.Secret.Service.
 type Service struct {
-	localConn        *dbus.Conn
+	backends         backendSet
 	sessions         *SessionManager
 	logger           *logging.Logger
 	approval         *approval.Manager
@@ -25,9 +26,9 @@ type Service struct {
 }
 
 // NewService creates a new Service handler.
-func NewService(localConn *dbus.Conn, sessions *SessionManager, logger *logging.Logger, approvalMgr *approval.Manager, clientName string, tracker *clientTracker, resolver *SenderInfoResolver, upstreamNotifier UpstreamNotifier, slowThreshold time.Duration) *Service {
+func NewService(backends backendSet, sessions *SessionManager, logger *logging.Logger, approvalMgr *approval.Manager, clientName string, tracker *clientTracker, resolver *SenderInfoResolver, upstreamNotifier UpstreamNotifier, slowThreshold time.Duration) *Service {
 	return &Service{
-		localConn:        localConn,
+		backends:         backends,
 		sessions:         sessions,
 		logger:           logger,
 		approval:         approvalMgr,
@@ -39,9 +40,15 @@ func NewService(localConn *dbus.Conn, sessions *SessionManager, logger *logging.
 	}
 }
 
-// upstream returns the backend object at path on the upstream Secret Service bus.
+// upstream returns the object at the front path on the backend that owns it.
 func (s *Service) upstream(path dbus.ObjectPath) dbus.BusObject {
-	return s.localConn.Object(dbustypes.BusName, path)
+	b, bp := s.backends.route(path)
+	return b.object(bp)
+}
+
+// serviceOf returns the Service object of backend b.
+func serviceOf(b *Backend) dbus.BusObject {
+	return b.object(dbustypes.ServicePath)
 }
 
 // senderResolver returns a lazy resolver of sender's SenderInfo, suitable for an
@@ -70,7 +77,7 @@ func (s *Service) upstreamGetProperty(obj dbus.BusObject, prop string, ctx Upstr
 // OpenSession opens a session for secret transfer.
 // Signature: OpenSession(algorithm String, input Variant) -> (output Variant, result ObjectPath)
 func (s *Service) OpenSession(algorithm string, input dbus.Variant) (dbus.Variant, dbus.ObjectPath, *dbus.Error) {
-	output, sessionPath, err := s.sessions.CreateSession(s.localConn, algorithm, input)
+	output, sessionPath, err := s.sessions.CreateSession(s.backends, algorithm, input)
 	if err != nil {
 		if dbusErr, ok := err.(*dbus.Error); ok {
 			s.logger.LogOpenSession(context.Background(), algorithm, "", "error", err)
@@ -84,37 +91,57 @@ func (s *Service) OpenSession(algorithm string, input dbus.Variant) (dbus.Varian
 	return output, sessionPath, nil
 }
 
-// SearchItems searches for items matching the given attributes.
+// SearchItems searches every backend for items matching the given attributes.
+// A backend that fails is left out of the results as long as another answers.
 // Signature: SearchItems(attributes Dict<String,String>) -> (unlocked Array<ObjectPath>, locked Array<ObjectPath>)
 func (s *Service) SearchItems(msg dbus.Message, attributes map[string]string) ([]dbus.ObjectPath, []dbus.ObjectPath, *dbus.Error) {
-	obj := s.upstream(dbustypes.ServicePath)
 	infos := searchAttributesToItemInfo(attributes)
 	sender := senderOf(msg)
 	senderInfo := s.resolver.Resolve(sender)
 
 	// Check if request should be denied by a trust rule
-	if rule := s.approval.CheckTrustRules(senderInfo, infos, approval.RequestTypeSearch, attributes); rule != nil && rule.Action == "deny" {
-		s.approval.RecordDenied(s.clientName, infos, "", approval.RequestTypeSearch, attributes, senderInfo)
-		return nil, nil, dbustypes.ErrAccessDenied("denied by trust rule: " + rule.Name)
+	if rule := s.approval.CheckTrustRules(s.clientName, senderInfo, infos, approval.RequestTypeSearch, attributes); rule != nil && rule.Action == "deny" {
+		s.approval.RecordDenied(s.clientName, infos, "", approval.RequestTypeSearch, attributes, senderInfo, rule.Label())
+		return nil, nil, dbustypes.ErrAccessDenied("denied by trust rule: " + rule.Label())
 	}
-
-	s.approval.RecordPassthrough(s.clientName, infos, "", approval.RequestTypeSearch, attributes, senderInfo)
-	call := s.upstreamWithContext(UpstreamCallContext{
-		RequestType:   approval.RequestTypeSearch,
-		Items:         infos,
-		ResolveSender: s.senderResolver(sender),
-	}, func() *dbus.Call { return obj.Call(dbustypes.ServiceInterface+".SearchItems", 0, attributes) })
-	if call.Err != nil {
-		s.logger.LogSearchItems(context.Background(), attributes, 0, 0, "error", call.Err)
-		return nil, nil, dbustypes.ErrFailed(call.Err)
+	if s.approval.TemporarilyDenied(senderInfo, infos, approval.RequestTypeSearch) {
+		s.approval.RecordDenied(s.clientName, infos, "", approval.RequestTypeSearch, attributes, senderInfo, approval.TemporaryDenyRule)
+		return nil, nil, dbustypes.ErrAccessDenied("denied by a temporary deny rule")
 	}
 
+	s.approval.RecordPassthrough(s.clientName, infos, "", approval.RequestTypeSearch, attributes, senderInfo)
 	var unlocked, locked []dbus.ObjectPath
-	if err := call.Store(&unlocked, &locked); err != nil {
-		s.logger.LogSearchItems(context.Background(), attributes, 0, 0, "error", err)
-		return nil, nil, dbustypes.ErrFailed(err)
+	var lastErr error
+	answered := 0
+	for _, b := range s.backends {
+		obj := serviceOf(b)
+		call := s.upstreamWithContext(UpstreamCallContext{
+			RequestType:   approval.RequestTypeSearch,
+			Items:         infos,
+			ResolveSender: s.senderResolver(sender),
+		}, func() *dbus.Call { return obj.Call(dbustypes.ServiceInterface+".SearchItems", 0, attributes) })
+		var u, l []dbus.ObjectPath
+		if err := call.Store(&u, &l); err != nil {
+			lastErr = err
+			s.skipBackend(b, "SearchItems", err)
+			continue
+		}
+		answered++
+		unlocked = append(unlocked, s.backends.pathsToFront(b, u)...)
+		locked = append(locked, s.backends.pathsToFront(b, l)...)
+	}
+	if answered == 0 {
+		s.logger.LogSearchItems(context.Background(), attributes, 0, 0, "error", lastErr)
+		return nil, nil, dbustypes.ErrFailed(lastErr)
 	}
 
+	// Hide the items this client may never read, so their metadata does not
+	// leak through the search either.
+	itemCtx := UpstreamCallContext{SenderInfo: senderInfo}
+	itemInfo := func(p dbus.ObjectPath) approval.ItemInfo { return s.getItemInfo(p, itemCtx) }
+	unlocked = visiblePaths(s.approval, s.clientName, senderInfo, unlocked, itemInfo)
+	locked = visiblePaths(s.approval, s.clientName, senderInfo, locked, itemInfo)
+
 	s.logger.LogSearchItems(context.Background(), attributes, len(unlocked), len(locked), "ok", nil)
 	return unlocked, locked, nil
 }
@@ -139,37 +166,55 @@ func (s *Service) GetSecrets(msg dbus.Message, items []dbus.ObjectPath, session
 	// Resolve sender information
 	senderInfo := s.resolver.Resolve(sender)
 
-	// Require approval before accessing secrets
+	// Require approval before accessing secrets. Items are decided one by one,
+	// so only the granted subset is fetched; the others are left out of the
+	// result, as the Secret Service does for items it cannot return.
 	itemStrs := objectPathsToStrings(items)
-	_, err := s.approval.RequireApproval(ctx, s.clientName, itemInfos, string(session), approval.RequestTypeGetSecret, nil, senderInfo)
+	granted, err := s.approval.RequireItemsApproval(ctx, s.clientName, itemInfos, string(session), senderInfo)
 	if err != nil {
 		s.logger.LogGetSecrets(ctx, itemStrs, "denied", err)
 		return nil, dbustypes.ErrAccessDenied(err.Error())
 	}
+	if len(granted) < len(itemInfos) {
+		s.logger.LogGetSecrets(ctx, itemStrs, "partial", nil)
+		items = make([]dbus.ObjectPath, len(granted))
+		for i, info := range granted {
+			items[i] = dbus.ObjectPath(info.Path)
+		}
+		itemInfos = granted
+		itemStrs = objectPathsToStrings(items)
+	}
 
 	// Map remote session to local session
-	localSession, ok := s.sessions.GetLocalSession(session)
-	if !ok {
+	if !s.sessions.Exists(session) {
 		s.logger.LogGetSecrets(context.Background(), itemStrs, "error", dbustypes.ErrSessionNotFound(string(session)))
 		return nil, dbustypes.ErrSessionNotFound(string(session))
 	}
 
-	obj := s.upstream(dbustypes.ServicePath)
-	call := s.upstreamWithContext(UpstreamCallContext{
-		RequestType: approval.RequestTypeGetSecret,
-		Items:       itemInfos,
-		SenderInfo:  senderInfo,
-	}, func() *dbus.Call { return obj.Call(dbustypes.ServiceInterface+".GetSecrets", 0, items, localSession) })
-	if call.Err != nil {
-		s.logger.LogGetSecrets(context.Background(), itemStrs, "error", call.Err)
-		return nil, dbustypes.ErrFailed(call.Err)
-	}
-
-	// The return type is Dict<ObjectPath, Secret> where Secret is (oayays)
-	var secrets map[dbus.ObjectPath]dbustypes.Secret
-	if err := call.Store(&secrets); err != nil {
-		s.logger.LogGetSecrets(context.Background(), itemStrs, "error", err)
-		return nil, dbustypes.ErrFailed(err)
+	// Fetch each backend's items over its own session.
+	secrets := make(map[dbus.ObjectPath]dbustypes.Secret, len(items))
+	for _, g := range s.backends.group(items) {
+		localSession, derr := s.sessions.GetLocalSession(session, g.backend)
+		if derr != nil {
+			s.logger.LogGetSecrets(context.Background(), itemStrs, "error", derr)
+			return nil, derr
+		}
+		obj := serviceOf(g.backend)
+		call := s.upstreamWithContext(UpstreamCallContext{
+			RequestType: approval.RequestTypeGetSecret,
+			Items:       itemInfos,
+			SenderInfo:  senderInfo,
+		}, func() *dbus.Call { return obj.Call(dbustypes.ServiceInterface+".GetSecrets", 0, g.paths, localSession) })
+
+		// The return type is Dict<ObjectPath, Secret> where Secret is (oayays)
+		var got map[dbus.ObjectPath]dbustypes.Secret
+		if err := call.Store(&got); err != nil {
+			s.logger.LogGetSecrets(context.Background(), itemStrs, "error", err)
+			return nil, dbustypes.ErrFailed(err)
+		}
+		for path, secret := range got {
+			secrets[s.backends.toFront(g.backend, path)] = secret
+		}
 	}
 
 	// Rewrite session paths and, for DH sessions, encrypt each secret value for
@@ -187,10 +232,11 @@ func (s *Service) GetSecrets(msg dbus.Message, items []dbus.ObjectPath, session
 	return secrets, nil
 }
 
-// Unlock unlocks the specified objects.
+// Unlock unlocks the specified objects on their backends. If several backends
+// need a prompt, only the first is returned; the objects of the others stay
+// locked for the client to unlock again.
 // Signature: Unlock(objects Array<ObjectPath>) -> (unlocked Array<ObjectPath>, prompt ObjectPath)
 func (s *Service) Unlock(msg dbus.Message, objects []dbus.ObjectPath) ([]dbus.ObjectPath, dbus.ObjectPath, *dbus.Error) {
-	obj := s.upstream(dbustypes.ServicePath)
 	sender := senderOf(msg)
 	senderCtx := UpstreamCallContext{
 		ResolveSender: s.senderResolver(sender),
@@ -199,67 +245,85 @@ func (s *Service) Unlock(msg dbus.Message, objects []dbus.ObjectPath) ([]dbus.Ob
 	senderInfo := s.resolver.Resolve(sender)
 
 	// Check if request should be denied by a trust rule
-	if rule := s.approval.CheckTrustRules(senderInfo, infos, approval.RequestTypeUnlock, nil); rule != nil && rule.Action == "deny" {
-		s.approval.RecordDenied(s.clientName, infos, "", approval.RequestTypeUnlock, nil, senderInfo)
-		return nil, "/", dbustypes.ErrAccessDenied("denied by trust rule: " + rule.Name)
+	if rule := s.approval.CheckTrustRules(s.clientName, senderInfo, infos, approval.RequestTypeUnlock, nil); rule != nil && rule.Action == "deny" {
+		s.approval.RecordDenied(s.clientName, infos, "", approval.RequestTypeUnlock, nil, senderInfo, rule.Label())
+		return nil, "/", dbustypes.ErrAccessDenied("denied by trust rule: " + rule.Label())
+	}
+	if s.approval.TemporarilyDenied(senderInfo, infos, approval.RequestTypeUnlock) {
+		s.approval.RecordDenied(s.clientName, infos, "", approval.RequestTypeUnlock, nil, senderInfo, approval.TemporaryDenyRule)
+		return nil, "/", dbustypes.ErrAccessDenied("denied by a temporary deny rule")
 	}
 
 	s.approval.RecordPassthrough(s.clientName, infos, "", approval.RequestTypeUnlock, nil, senderInfo)
-	call := s.upstreamWithContext(UpstreamCallContext{
+	objStrs := objectPathsToStrings(objects)
+	unlocked, prompt, err := s.lockOrUnlock("Unlock", objects, UpstreamCallContext{
 		RequestType:   approval.RequestTypeUnlock,
 		Items:         infos,
 		ResolveSender: s.senderResolver(sender),
-	}, func() *dbus.Call { return obj.Call(dbustypes.ServiceInterface+".Unlock", 0, objects) })
-	if call.Err != nil {
-		objStrs := objectPathsToStrings(objects)
-		s.logger.LogUnlock(context.Background(), objStrs, 0, "error", call.Err)
-		return nil, "/", dbustypes.ErrFailed(call.Err)
-	}
-
-	var unlocked []dbus.ObjectPath
-	var prompt dbus.ObjectPath
-	if err := call.Store(&unlocked, &prompt); err != nil {
-		objStrs := objectPathsToStrings(objects)
+	})
+	if err != nil {
 		s.logger.LogUnlock(context.Background(), objStrs, 0, "error", err)
 		return nil, "/", dbustypes.ErrFailed(err)
 	}
 
-	objStrs := objectPathsToStrings(objects)
 	s.logger.LogUnlock(context.Background(), objStrs, len(unlocked), "ok", nil)
 	return unlocked, prompt, nil
 }
 
+// lockOrUnlock calls method (Lock or Unlock) on the backends owning objects
+// and merges the results, keeping the first prompt.
+func (s *Service) lockOrUnlock(method string, objects []dbus.ObjectPath, ctx UpstreamCallContext) ([]dbus.ObjectPath, dbus.ObjectPath, error) {
+	var done []dbus.ObjectPath
+	prompt := dbus.ObjectPath("/")
+	for _, g := range s.backends.group(objects) {
+		obj := serviceOf(g.backend)
+		call := s.upstreamWithContext(ctx, func() *dbus.Call { return obj.Call(dbustypes.ServiceInterface+"."+method, 0, g.paths) })
+		var d []dbus.ObjectPath
+		var p dbus.ObjectPath
+		if err := call.Store(&d, &p); err != nil {
+			return nil, "/", err
+		}
+		done = append(done, s.backends.pathsToFront(g.backend, d)...)
+		if p != "/" && prompt == "/" {
+			prompt = s.backends.toFront(g.backend, p)
+		}
+	}
+	return done, prompt, nil
+}
+
+// skipBackend logs a backend left out of a merged result.
+func (s *Service) skipBackend(b *Backend, method string, err error) {
+	if len(s.backends) > 1 {
+		s.logger.Warn("backend failed, leaving it out", "backend", b.Name, "method", method, "error", err)
+	}
+}
+
 // Lock locks the specified objects.
 // Signature: Lock(objects Array<ObjectPath>) -> (locked Array<ObjectPath>, prompt ObjectPath)
 func (s *Service) Lock(msg dbus.Message, objects []dbus.ObjectPath) ([]dbus.ObjectPath, dbus.ObjectPath, *dbus.Error) {
-	obj := s.upstream(dbustypes.ServicePath)
 	sender := senderOf(msg)
 	ctx := UpstreamCallContext{
 		ResolveSender: s.senderResolver(sender),
 	}
-	call := s.upstreamWithContext(ctx, func() *dbus.Call { return obj.Call(dbustypes.ServiceInterface+".Lock", 0, objects) })
-	if call.Err != nil {
-		return nil, "/", dbustypes.ErrFailed(call.Err)
-	}
-
-	var locked []dbus.ObjectPath
-	var prompt dbus.ObjectPath
-	if err := call.Store(&locked, &prompt); err != nil {
+	locked, prompt, err := s.lockOrUnlock("Lock", objects, ctx)
+	if err != nil {
 		return nil, "/", dbustypes.ErrFailed(err)
 	}
 
 	return locked, prompt, nil
 }
 
-// ReadAlias returns the collection with the given alias.
+// ReadAlias returns the collection with the given alias, asking the backend
+// the alias is mapped to.
 // Signature: ReadAlias(name String) -> (collection ObjectPath)
 func (s *Service) ReadAlias(msg dbus.Message, name string) (dbus.ObjectPath, *dbus.Error) {
-	obj := s.upstream(dbustypes.ServicePath)
+	b, alias := s.backends.routeAlias(name)
+	obj := serviceOf(b)
 	sender := senderOf(msg)
 	ctx := UpstreamCallContext{
 		ResolveSender: s.senderResolver(sender),
 	}
-	call := s.upstreamWithContext(ctx, func() *dbus.Call { return obj.Call(dbustypes.ServiceInterface+".ReadAlias", 0, name) })
+	call := s.upstreamWithContext(ctx, func() *dbus.Call { return obj.Call(dbustypes.ServiceInterface+".ReadAlias", 0, alias) })
 	if call.Err != nil {
 		s.logger.LogReadAlias(context.Background(), name, "", "error", call.Err)
 		return "/", dbustypes.ErrFailed(call.Err)
@@ -270,36 +334,46 @@ func (s *Service) ReadAlias(msg dbus.Message, name string) (dbus.ObjectPath, *db
 		s.logger.LogReadAlias(context.Background(), name, "", "error", err)
 		return "/", dbustypes.ErrFailed(err)
 	}
+	collection = s.backends.toFront(b, collection)
 
 	s.logger.LogReadAlias(context.Background(), name, string(collection), "ok", nil)
 	return collection, nil
 }
 
-// SetAlias sets an alias for a collection.
+// SetAlias sets an alias for a collection. The alias must route to the
+// collection's backend (see Upstream.Aliases).
 // Signature: SetAlias(name String, collection ObjectPath)
 func (s *Service) SetAlias(msg dbus.Message, name string, collection dbus.ObjectPath) *dbus.Error {
-	obj := s.upstream(dbustypes.ServicePath)
+	b, alias := s.backends.routeAlias(name)
+	owner, target := s.backends.route(collection)
+	if collection != "/" && owner != b {
+		return dbustypes.NewDBusError(dbustypes.ErrNotSupported,
+			fmt.Sprintf("alias %q is served by backend %s, collection %s is on %s", name, b.Name, collection, owner.Name))
+	}
+	obj := serviceOf(b)
 	sender := senderOf(msg)
 	ctx := UpstreamCallContext{
 		ResolveSender: s.senderResolver(sender),
 	}
-	call := s.upstreamWithContext(ctx, func() *dbus.Call { return obj.Call(dbustypes.ServiceInterface+".SetAlias", 0, name, collection) })
+	call := s.upstreamWithContext(ctx, func() *dbus.Call { return obj.Call(dbustypes.ServiceInterface+".SetAlias", 0, alias, target) })
 	if call.Err != nil {
 		return dbustypes.ErrFailed(call.Err)
 	}
 	return nil
 }
 
-// CreateCollection creates a new collection.
+// CreateCollection creates a new collection on the backend its alias is
+// mapped to, or on the default backend.
 // Signature: CreateCollection(properties Dict<String,Variant>, alias String) -> (collection ObjectPath, prompt ObjectPath)
 func (s *Service) CreateCollection(msg dbus.Message, properties map[string]dbus.Variant, alias string) (dbus.ObjectPath, dbus.ObjectPath, *dbus.Error) {
-	obj := s.upstream(dbustypes.ServicePath)
+	b, backendAlias := s.backends.routeAlias(alias)
+	obj := serviceOf(b)
 	sender := senderOf(msg)
 	ctx := UpstreamCallContext{
 		ResolveSender: s.senderResolver(sender),
 	}
 	call := s.upstreamWithContext(ctx, func() *dbus.Call {
-		return obj.Call(dbustypes.ServiceInterface+".CreateCollection", 0, properties, alias)
+		return obj.Call(dbustypes.ServiceInterface+".CreateCollection", 0, properties, backendAlias)
 	})
 	if call.Err != nil {
 		return "/", "/", dbustypes.ErrFailed(call.Err)
@@ -310,7 +384,7 @@ func (s *Service) CreateCollection(msg dbus.Message, properties map[string]dbus.
 		return "/", "/", dbustypes.ErrFailed(err)
 	}
 
-	return collection, prompt, nil
+	return s.backends.toFront(b, collection), s.backends.toFront(b, prompt), nil
 }
 
 // Get implements org.freedesktop.DBus.Properties.Get
@@ -319,12 +393,18 @@ func (s *Service) Get(msg dbus.Message, iface, property string) (dbus.Variant, *
 		return dbus.Variant{}, &dbus.Error{Name: "org.freedesktop.DBus.Error.UnknownInterface", Body: []any{iface}}
 	}
 
-	obj := s.upstream(dbustypes.ServicePath)
 	sender := senderOf(msg)
 	ctx := UpstreamCallContext{
 		ResolveSender: s.senderResolver(sender),
 	}
-	variant, err := s.upstreamGetProperty(obj, iface+"."+property, ctx)
+	if property == "Collections" {
+		collections, err := s.collections(ctx)
+		if err != nil {
+			return dbus.Variant{}, dbustypes.ErrFailed(err)
+		}
+		return dbus.MakeVariant(collections), nil
+	}
+	variant, err := s.upstreamGetProperty(serviceOf(s.backends.primary()), iface+"."+property, ctx)
 	if err != nil {
 		return dbus.Variant{}, dbustypes.ErrFailed(err)
 	}
@@ -338,7 +418,7 @@ func (s *Service) GetAll(msg dbus.Message, iface string) (map[string]dbus.Varian
 		return nil, &dbus.Error{Name: "org.freedesktop.DBus.Error.UnknownInterface", Body: []any{iface}}
 	}
 
-	obj := s.upstream(dbustypes.ServicePath)
+	obj := serviceOf(s.backends.primary())
 	sender := senderOf(msg)
 	ctx := UpstreamCallContext{
 		ResolveSender: s.senderResolver(sender),
@@ -352,17 +432,47 @@ func (s *Service) GetAll(msg dbus.Message, iface string) (map[string]dbus.Varian
 	if err := call.Store(&props); err != nil {
 		return nil, dbustypes.ErrFailed(err)
 	}
+	if _, ok := props["Collections"]; ok && len(s.backends) > 1 {
+		collections, err := s.collections(ctx)
+		if err != nil {
+			return nil, dbustypes.ErrFailed(err)
+		}
+		props["Collections"] = dbus.MakeVariant(collections)
+	}
 
 	return props, nil
 }
 
+// collections lists the collections of every backend as seen on the front. A
+// backend that fails is left out as long as another answers.
+func (s *Service) collections(ctx UpstreamCallContext) ([]dbus.ObjectPath, error) {
+	all := []dbus.ObjectPath{}
+	var lastErr error
+	answered := 0
+	for _, b := range s.backends {
+		v, err := s.upstreamGetProperty(serviceOf(b), dbustypes.ServiceInterface+".Collections", ctx)
+		if err != nil {
+			lastErr = err
+			s.skipBackend(b, "Collections", err)
+			continue
+		}
+		answered++
+		paths, _ := v.Value().([]dbus.ObjectPath)
+		all = append(all, s.backends.pathsToFront(b, paths)...)
+	}
+	if answered == 0 {
+		return nil, lastErr
+	}
+	return all, nil
+}
+
 // Set implements org.freedesktop.DBus.Properties.Set
 func (s *Service) Set(msg dbus.Message, iface, property string, value dbus.Variant) *dbus.Error {
 	if iface != dbustypes.ServiceInterface {
 		return &dbus.Error{Name: "org.freedesktop.DBus.Error.UnknownInterface", Body: []any{iface}}
 	}
 
-	obj := s.upstream(dbustypes.ServicePath)
+	obj := serviceOf(s.backends.primary())
 	sender := senderOf(msg)
 	ctx := UpstreamCallContext{
 		ResolveSender: s.senderResolver(sender),
@@ -463,9 +573,10 @@ func (s *Service) Introspect() string {
 // any pinentry prompt triggered by a locked keyring shows which process
 // caused the access.
 func (s *Service) getItemInfo(path dbus.ObjectPath, ctx UpstreamCallContext) approval.ItemInfo {
-	info := approval.ItemInfo{Path: string(path)}
+	b, bp := s.backends.route(path)
+	info := approval.ItemInfo{Path: string(path), Backend: b.Name}
 
-	obj := s.upstream(path)
+	obj := b.object(bp)
 
 	// Get Label property
 	if v, err := s.upstreamGetProperty(obj, dbustypes.ItemInterface+".Label", ctx); err == nil {
@@ -507,8 +618,9 @@ func searchAttributesToItemInfo(attributes map[string]string) []approval.ItemInf
 func (s *Service) getUnlockInfo(objects []dbus.ObjectPath, ctx UpstreamCallContext) []approval.ItemInfo {
 	infos := make([]approval.ItemInfo, len(objects))
 	for i, path := range objects {
-		infos[i] = approval.ItemInfo{Path: string(path)}
-		obj := s.upstream(path)
+		b, bp := s.backends.route(path)
+		infos[i] = approval.ItemInfo{Path: string(path), Backend: b.Name}
+		obj := b.object(bp)
 		if v, err := s.upstreamGetProperty(obj, dbustypes.CollectionInterface+".Label", ctx); err == nil {
 			if label, ok := v.Value().(string); ok {
 				infos[i].Label = label
@@ -11,11 +11,20 @@ import (
 	"github.com/nikicat/secrets-dispatcher/internal/dhcrypto"
 )
 
-// sessionEntry records the upstream session backing a client session, plus the
-// negotiated cipher when the client opened an encrypted (DH) session.
+// sessionEntry records the upstream sessions backing a client session, one
+// per backend, plus the negotiated cipher when the client opened an encrypted
+// (DH) session.
 type sessionEntry struct {
-	local  dbus.ObjectPath   // upstream Secret Service session path
-	cipher *dhcrypto.Session // nil for "plain" sessions
+	local  map[string]localSession // backend name -> upstream session
+	cipher *dhcrypto.Session       // nil for "plain" sessions
+}
+
+// localSession is an upstream session and the backend epoch it was opened in.
+// A session from an older epoch died with the upstream service and is
+// reopened on next use, so client sessions survive an upstream restart.
+type localSession struct {
+	path  dbus.ObjectPath
+	epoch uint64
 }
 
 // SessionManager tracks the mapping between remote (client-facing) sessions and
@@ -41,10 +50,10 @@ func NewSessionManager() *SessionManager {
 }
 
 // CreateSession negotiates a client session for the given algorithm, opens a
-// plain session on the upstream Secret Service, and tracks the mapping. It
-// returns the output variant (empty for plain, the service DH public key for
-// DH) and the remote session path to give to the client.
-func (m *SessionManager) CreateSession(localConn *dbus.Conn, algorithm string, input dbus.Variant) (output dbus.Variant, remotePath dbus.ObjectPath, err error) {
+// plain session on every backend, and tracks the mapping. It returns the
+// output variant (empty for plain, the service DH public key for DH) and the
+// remote session path to give to the client.
+func (m *SessionManager) CreateSession(backends backendSet, algorithm string, input dbus.Variant) (output dbus.Variant, remotePath dbus.ObjectPath, err error) {
 	var clientOutput dbus.Variant
 	var cipher *dhcrypto.Session
 
@@ -72,18 +81,21 @@ func (m *SessionManager) CreateSession(localConn *dbus.Conn, algorithm string, i
 		return dbus.Variant{}, "", dbustypes.ErrUnsupportedAlgorithm(algorithm)
 	}
 
-	// Always open a "plain" session with the upstream Secret Service, regardless
-	// of what the client negotiated (see the SessionManager doc comment).
-	obj := localConn.Object(dbustypes.BusName, dbustypes.ServicePath)
-	call := obj.Call(dbustypes.ServiceInterface+".OpenSession", 0, dbustypes.AlgorithmPlain, dbus.MakeVariant(""))
-	if call.Err != nil {
-		return dbus.Variant{}, "", call.Err
+	// Always open "plain" sessions with the upstream Secret Services,
+	// regardless of what the client negotiated (see the SessionManager doc
+	// comment). A backend that fails now gets its session on first use.
+	local := make(map[string]localSession, len(backends))
+	var lastErr error
+	for _, b := range backends {
+		ls, err := openLocal(b)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		local[b.Name] = ls
 	}
-
-	var localOutput dbus.Variant
-	var localPath dbus.ObjectPath
-	if err := call.Store(&localOutput, &localPath); err != nil {
-		return dbus.Variant{}, "", err
+	if len(local) == 0 {
+		return dbus.Variant{}, "", lastErr
 	}
 
 	// Generate remote session path
@@ -91,18 +103,49 @@ func (m *SessionManager) CreateSession(localConn *dbus.Conn, algorithm string, i
 	remotePath = dbus.ObjectPath(fmt.Sprintf("/org/freedesktop/secrets/session/%d", id))
 
 	m.mu.Lock()
-	m.sessions[remotePath] = sessionEntry{local: localPath, cipher: cipher}
+	m.sessions[remotePath] = sessionEntry{local: local, cipher: cipher}
 	m.mu.Unlock()
 
 	return clientOutput, remotePath, nil
 }
 
-// GetLocalSession returns the upstream session path for a remote session.
-func (m *SessionManager) GetLocalSession(remotePath dbus.ObjectPath) (dbus.ObjectPath, bool) {
+// Exists reports whether remotePath is an open client session.
+func (m *SessionManager) Exists(remotePath dbus.ObjectPath) bool {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
+	_, ok := m.sessions[remotePath]
+	return ok
+}
+
+// GetLocalSession returns the session path on backend b for a remote session,
+// opening a new upstream session if b has restarted since the last one. It
+// fails with NoSession if the remote session is unknown.
+func (m *SessionManager) GetLocalSession(remotePath dbus.ObjectPath, b *Backend) (dbus.ObjectPath, *dbus.Error) {
+	m.mu.RLock()
 	entry, ok := m.sessions[remotePath]
-	return entry.local, ok
+	var local localSession
+	var have bool
+	if ok {
+		local, have = entry.local[b.Name]
+	}
+	m.mu.RUnlock()
+	if !ok {
+		return "", dbustypes.ErrSessionNotFound(string(remotePath))
+	}
+	if have && local.epoch == b.epoch.Load() {
+		return local.path, nil
+	}
+
+	fresh, err := openLocal(b)
+	if err != nil {
+		return "", dbustypes.ErrFailed(err)
+	}
+	m.mu.Lock()
+	if entry, ok := m.sessions[remotePath]; ok {
+		entry.local[b.Name] = fresh
+	}
+	m.mu.Unlock()
+	return fresh.path, nil
 }
 
 // ForClient prepares a secret retrieved from upstream for delivery to the client
@@ -127,20 +170,24 @@ func (m *SessionManager) ForClient(remotePath dbus.ObjectPath, secret dbustypes.
 }
 
 // ForUpstream converts a secret received from the client (on its remote session)
-// into the secret to forward to the upstream service: it maps the session to the
-// upstream-local path and, for DH sessions, decrypts the value using the IV in
-// Parameters. The upstream leg is always plain, so Parameters is cleared for DH.
-// ok is false if the remote session is unknown.
-func (m *SessionManager) ForUpstream(secret dbustypes.Secret) (out dbustypes.Secret, ok bool, err error) {
-	m.mu.RLock()
-	entry, found := m.sessions[secret.Session]
-	m.mu.RUnlock()
-	if !found {
+// into the secret to forward to backend b: it maps the session to b's path
+// (see GetLocalSession) and, for DH sessions, decrypts the value using the IV
+// in Parameters. The upstream leg is always plain, so Parameters is cleared
+// for DH. ok is false if the remote session is unknown.
+func (m *SessionManager) ForUpstream(secret dbustypes.Secret, b *Backend) (out dbustypes.Secret, ok bool, err error) {
+	if !m.Exists(secret.Session) {
 		return dbustypes.Secret{}, false, nil
 	}
+	local, derr := m.GetLocalSession(secret.Session, b)
+	if derr != nil {
+		return dbustypes.Secret{}, true, derr
+	}
+	m.mu.RLock()
+	entry := m.sessions[secret.Session]
+	m.mu.RUnlock()
 
 	out = dbustypes.Secret{
-		Session:     entry.local,
+		Session:     local,
 		Parameters:  secret.Parameters,
 		Value:       secret.Value,
 		ContentType: secret.ContentType,
@@ -156,8 +203,8 @@ func (m *SessionManager) ForUpstream(secret dbustypes.Secret) (out dbustypes.Sec
 	return out, true, nil
 }
 
-// CloseSession removes a session mapping and closes the local session.
-func (m *SessionManager) CloseSession(localConn *dbus.Conn, remotePath dbus.ObjectPath) error {
+// CloseSession removes a session mapping and closes the backend sessions.
+func (m *SessionManager) CloseSession(backends backendSet, remotePath dbus.ObjectPath) error {
 	m.mu.Lock()
 	entry, ok := m.sessions[remotePath]
 	if ok {
@@ -169,14 +216,11 @@ func (m *SessionManager) CloseSession(localConn *dbus.Conn, remotePath dbus.Obje
 		return dbustypes.ErrSessionNotFound(string(remotePath))
 	}
 
-	// Close the local session
-	obj := localConn.Object(dbustypes.BusName, entry.local)
-	call := obj.Call(dbustypes.SessionInterface+".Close", 0)
-	return call.Err
+	return closeLocal(backends, entry.local)
 }
 
 // CloseAll closes all sessions.
-func (m *SessionManager) CloseAll(localConn *dbus.Conn) {
+func (m *SessionManager) CloseAll(backends backendSet) {
 	m.mu.Lock()
 	sessions := make(map[dbus.ObjectPath]sessionEntry, len(m.sessions))
 	maps.Copy(sessions, m.sessions)
@@ -184,7 +228,34 @@ func (m *SessionManager) CloseAll(localConn *dbus.Conn) {
 	m.mu.Unlock()
 
 	for _, entry := range sessions {
-		obj := localConn.Object(dbustypes.BusName, entry.local)
-		obj.Call(dbustypes.SessionInterface+".Close", 0)
+		closeLocal(backends, entry.local) //nolint:errcheck // best effort
+	}
+}
+
+// openLocal opens a plain session on backend b.
+func openLocal(b *Backend) (localSession, error) {
+	epoch := b.epoch.Load()
+	call := b.object(dbustypes.ServicePath).Call(dbustypes.ServiceInterface+".OpenSession", 0, dbustypes.AlgorithmPlain, dbus.MakeVariant(""))
+	var output dbus.Variant
+	var path dbus.ObjectPath
+	if err := call.Store(&output, &path); err != nil {
+		return localSession{}, err
+	}
+	return localSession{path: path, epoch: epoch}, nil
+}
+
+// closeLocal closes the backend sessions in local that are still alive and
+// returns the first error.
+func closeLocal(backends backendSet, local map[string]localSession) error {
+	var first error
+	for name, ls := range local {
+		b := backends.byName(name)
+		if b == nil || ls.epoch != b.epoch.Load() {
+			continue
+		}
+		if err := b.object(ls.path).Call(dbustypes.SessionInterface+".Close", 0).Err; err != nil && first == nil {
+			first = err
+		}
 	}
+	return first
 }
@@ -15,18 +15,23 @@ import (
 // Note: Seahorse has a bug where adding an item switches the view to the first
 // collection alphabetically. This is caused by Seahorse's own focus-place action,
 // not by forwarded signals. See https://gitlab.gnome.org/GNOME/seahorse/-/issues/430
+//
+// Object paths in the signals are translated to the front with toFront, so
+// that a mounted backend's collections appear under their prefix.
 type signalForwarder struct {
 	backendConn *dbus.Conn
 	frontConn   *dbus.Conn
+	toFront     func(any) any
 	logger      *logging.Logger
 	ch          chan *dbus.Signal
 	done        chan struct{}
 }
 
-func newSignalForwarder(backendConn, frontConn *dbus.Conn, logger *logging.Logger) (*signalForwarder, error) {
+func newSignalForwarder(backendConn, frontConn *dbus.Conn, toFront func(any) any, logger *logging.Logger) (*signalForwarder, error) {
 	f := &signalForwarder{
 		backendConn: backendConn,
 		frontConn:   frontConn,
+		toFront:     toFront,
 		logger:      logger,
 		ch:          make(chan *dbus.Signal, 64),
 		done:        make(chan struct{}),
@@ -55,10 +60,15 @@ func (f *signalForwarder) run() {
 				!strings.HasPrefix(sig.Name, "org.freedesktop.DBus.Properties.") {
 				continue
 			}
-			if err := f.frontConn.Emit(sig.Path, sig.Name, sig.Body...); err != nil {
-				f.logger.Info("failed to forward signal", "signal", sig.Name, "path", sig.Path, "error", err)
+			path := f.toFront(sig.Path).(dbus.ObjectPath)
+			body := make([]any, len(sig.Body))
+			for i, v := range sig.Body {
+				body[i] = f.toFront(v)
+			}
+			if err := f.frontConn.Emit(path, sig.Name, body...); err != nil {
+				f.logger.Info("failed to forward signal", "signal", sig.Name, "path", path, "error", err)
 			} else {
-				f.logger.Info("forwarded signal", "signal", sig.Name, "path", sig.Path)
+				f.logger.Info("forwarded signal", "signal", sig.Name, "path", path)
 			}
 		case <-f.done:
 			return
@@ -7,25 +7,52 @@ import (
 )
 
 // SubtreePropertiesHandler handles Properties interface for both collections and items.
-// It routes based on path type.
+// It routes based on path type. A collection's Items property is filtered
+// through collection, so it does not list items the caller may never read,
+// and the properties of such items cannot be read either.
 type SubtreePropertiesHandler struct {
-	localConn *dbus.Conn
-	sessions  *SessionManager
-	logger    *logging.Logger
+	backends   backendSet
+	sessions   *SessionManager
+	logger     *logging.Logger
+	collection *CollectionHandler
 }
 
 // NewSubtreePropertiesHandler creates a new handler.
-func NewSubtreePropertiesHandler(localConn *dbus.Conn, sessions *SessionManager, logger *logging.Logger) *SubtreePropertiesHandler {
+func NewSubtreePropertiesHandler(backends backendSet, sessions *SessionManager, logger *logging.Logger, collection *CollectionHandler) *SubtreePropertiesHandler {
 	return &SubtreePropertiesHandler{
-		localConn: localConn,
-		sessions:  sessions,
-		logger:    logger,
+		backends:   backends,
+		sessions:   sessions,
+		logger:     logger,
+		collection: collection,
 	}
 }
 
-// upstream returns the backend object at path on the upstream Secret Service bus.
-func (h *SubtreePropertiesHandler) upstream(path dbus.ObjectPath) dbus.BusObject {
-	return h.localConn.Object(dbustypes.BusName, path)
+// filterItems hides from a collection's Items property value the items the
+// sender of msg may never read. Other values are returned unchanged.
+func (h *SubtreePropertiesHandler) filterItems(msg dbus.Message, v dbus.Variant) dbus.Variant {
+	items, ok := v.Value().([]dbus.ObjectPath)
+	if !ok || h.collection == nil {
+		return v
+	}
+	senderInfo := h.collection.resolver.Resolve(senderOf(msg))
+	return dbus.MakeVariant(h.collection.visibleItems(senderInfo, items))
+}
+
+// hidden reports whether path is an item the sender of msg may never read. It
+// is then answered as if it did not exist: hiding it from listings is no use
+// if its label and attributes can be read by guessing its path.
+func (h *SubtreePropertiesHandler) hidden(msg dbus.Message, path dbus.ObjectPath) bool {
+	if !isItemPath(path) || h.collection == nil {
+		return false
+	}
+	senderInfo := h.collection.resolver.Resolve(senderOf(msg))
+	return len(h.collection.visibleItems(senderInfo, []dbus.ObjectPath{path})) == 0
+}
+
+// upstream returns the object at the front path on the backend that owns it.
+func (h *SubtreePropertiesHandler) upstream(path dbus.ObjectPath) (*Backend, dbus.BusObject) {
+	b, bp := h.backends.route(path)
+	return b, b.object(bp)
 }
 
 // Get implements org.freedesktop.DBus.Properties.Get for collections and items.
@@ -36,12 +63,19 @@ func (h *SubtreePropertiesHandler) Get(msg dbus.Message, iface, property string)
 	if !isCollectionPath(path) && !isItemPath(path) {
 		return dbus.Variant{}, dbustypes.ErrObjectNotFound(string(path))
 	}
+	if h.hidden(msg, path) {
+		return dbus.Variant{}, dbustypes.ErrObjectUnknown(string(path))
+	}
 
-	obj := h.upstream(path)
+	b, obj := h.upstream(path)
 	variant, err := obj.GetProperty(iface + "." + property)
 	if err != nil {
 		return dbus.Variant{}, dbustypes.ErrFailed(err)
 	}
+	variant = h.backends.valueToFront(b, variant).(dbus.Variant)
+	if isCollectionPath(path) && iface == dbustypes.CollectionInterface && property == "Items" {
+		variant = h.filterItems(msg, variant)
+	}
 
 	return variant, nil
 }
@@ -53,8 +87,11 @@ func (h *SubtreePropertiesHandler) GetAll(msg dbus.Message, iface string) (map[s
 	if !isCollectionPath(path) && !isItemPath(path) {
 		return nil, dbustypes.ErrObjectNotFound(string(path))
 	}
+	if h.hidden(msg, path) {
+		return nil, dbustypes.ErrObjectUnknown(string(path))
+	}
 
-	obj := h.upstream(path)
+	b, obj := h.upstream(path)
 	call := obj.Call("org.freedesktop.DBus.Properties.GetAll", 0, iface)
 	if call.Err != nil {
 		return nil, dbustypes.ErrFailed(call.Err)
@@ -64,6 +101,10 @@ func (h *SubtreePropertiesHandler) GetAll(msg dbus.Message, iface string) (map[s
 	if err := call.Store(&props); err != nil {
 		return nil, dbustypes.ErrFailed(err)
 	}
+	props = h.backends.valueToFront(b, props).(map[string]dbus.Variant)
+	if items, ok := props["Items"]; ok && isCollectionPath(path) && (iface == dbustypes.CollectionInterface || iface == "") {
+		props["Items"] = h.filterItems(msg, items)
+	}
 
 	return props, nil
 }
@@ -75,8 +116,11 @@ func (h *SubtreePropertiesHandler) Set(msg dbus.Message, iface, property string,
 	if !isCollectionPath(path) && !isItemPath(path) {
 		return dbustypes.ErrObjectNotFound(string(path))
 	}
+	if h.hidden(msg, path) {
+		return dbustypes.ErrObjectUnknown(string(path))
+	}
 
-	obj := h.upstream(path)
+	_, obj := h.upstream(path)
 	call := obj.Call("org.freedesktop.DBus.Properties.Set", 0, iface, property, value)
 	if call.Err != nil {
 		return dbustypes.ErrFailed(call.Err)
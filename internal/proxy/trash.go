@@ -0,0 +1,33 @@
+package proxy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/nikicat/secrets-dispatcher/internal/trash"
+)
+
+// keepDeleted saves the items at paths to store as one entry, before they are
+// deleted upstream, and returns the entry ID. The deletion must not go ahead
+// if this fails: it would no longer be undoable. The error says so, since the
+// usual cause, a locked item, is not something the caller would expect to
+// stop a deletion.
+func keepDeleted(store *trash.Store, conn *dbus.Conn, entry trash.Entry, paths []dbus.ObjectPath) (string, error) {
+	items, err := trash.Snapshot(conn, paths)
+	if err == nil {
+		entry.Items = items
+		err = store.Put(&entry)
+	}
+	if err != nil {
+		return "", fmt.Errorf("not deleted, as it could not be kept in the trash "+
+			"(unlock it first, or set serve.trash_retention to 0 to delete without a copy): %w", err)
+	}
+	return entry.ID, nil
+}
+
+// parentCollection returns the collection path of an item path.
+func parentCollection(item dbus.ObjectPath) string {
+	p := string(item)
+	return p[:strings.LastIndex(p, "/")]
+}
@@ -0,0 +1,24 @@
+package proxy
+
+import (
+	"github.com/godbus/dbus/v5"
+	"github.com/nikicat/secrets-dispatcher/internal/approval"
+)
+
+// visiblePaths drops from paths the items a deny trust rule keeps client from
+// reading (see approval.Manager.Visible). Item metadata is fetched with
+// itemInfo only when some rule could hide anything; an item whose metadata
+// cannot be read is judged on its path alone.
+func visiblePaths(mgr *approval.Manager, client string, senderInfo approval.SenderInfo,
+	paths []dbus.ObjectPath, itemInfo func(dbus.ObjectPath) approval.ItemInfo) []dbus.ObjectPath {
+	if len(paths) == 0 || !mgr.HidesItems(client, senderInfo) {
+		return paths
+	}
+	visible := make([]dbus.ObjectPath, 0, len(paths))
+	for _, p := range paths {
+		if mgr.Visible(client, senderInfo, itemInfo(p)) {
+			visible = append(visible, p)
+		}
+	}
+	return visible
+}
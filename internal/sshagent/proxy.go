@@ -1,5 +1,5 @@
-// Package sshagent implements an SSH agent proxy that gates SIGN_REQUEST
-// operations through the approval flow.
+// Package sshagent implements an SSH agent proxy that gates signing and key
+// management (add, remove, lock, extensions) through the approval flow.
 package sshagent
 
 import (
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"log/slog"
 	"sync"
+	"time"
 
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/agent"
@@ -15,8 +16,9 @@ import (
 	"github.com/nikicat/secrets-dispatcher/internal/approval"
 )
 
-// proxyAgent wraps an upstream SSH agent, delegating most operations but
-// gating Sign requests through the approval manager.
+// proxyAgent wraps an upstream SSH agent. Listing keys passes straight
+// through; every operation that uses or changes the agent's keys is gated
+// through the approval manager.
 type proxyAgent struct {
 	upstream    agent.Agent
 	approval    *approval.Manager
@@ -59,8 +61,50 @@ func (p *proxyAgent) SignWithFlags(key ssh.PublicKey, data []byte, flags agent.S
 }
 
 func (p *proxyAgent) signWithApproval(key ssh.PublicKey, data []byte, flags agent.SignatureFlags) (*ssh.Signature, error) {
+	item := p.keyItem(key, p.findKeyComment(key))
+	if err := p.requireApproval("sign", approval.RequestTypeSSHSign, []approval.ItemInfo{item}); err != nil {
+		return nil, err
+	}
+	if flags != 0 {
+		if ext, ok := p.upstream.(agent.ExtendedAgent); ok {
+			return ext.SignWithFlags(key, data, flags)
+		}
+	}
+	return p.upstream.Sign(key, data)
+}
+
+// requireApproval runs an agent operation through the approval flow. op names
+// the operation in logs and in the error returned to the client.
+func (p *proxyAgent) requireApproval(op string, reqType approval.RequestType, items []approval.ItemInfo) error {
+	attrs := []any{"op", op, "destination", p.destination, "invoker", p.senderInfo.InvokerName}
+	if len(items) == 1 {
+		attrs = append(attrs, "item", items[0].Label)
+	} else {
+		attrs = append(attrs, "items", len(items))
+	}
+	p.logger.Info("agent request received", attrs...)
+
+	if _, err := p.approval.RequireApproval(
+		context.TODO(),
+		"ssh-agent",
+		items,
+		"",
+		reqType,
+		nil,
+		p.senderInfo,
+	); err != nil {
+		p.logger.Info("agent request denied", "op", op, "error", err)
+		return fmt.Errorf("%s request denied: %w", op, err)
+	}
+
+	p.logger.Info("agent request approved", "op", op)
+	return nil
+}
+
+// keyItem describes a key for an approval request: the path is its SHA256
+// fingerprint, the label its comment (or the fingerprint if it has none).
+func (p *proxyAgent) keyItem(key ssh.PublicKey, comment string) approval.ItemInfo {
 	fingerprint := ssh.FingerprintSHA256(key)
-	comment := p.findKeyComment(key)
 	label := comment
 	if label == "" {
 		label = fingerprint
@@ -69,42 +113,28 @@ func (p *proxyAgent) signWithApproval(key ssh.PublicKey, data []byte, flags agen
 	attrs := map[string]string{
 		"fingerprint": fingerprint,
 	}
+	if comment != "" {
+		attrs["comment"] = comment
+	}
 	if p.destination != "" {
 		attrs["destination"] = p.destination
 	}
 
-	items := []approval.ItemInfo{{
+	return approval.ItemInfo{
 		Path:       fingerprint,
 		Label:      label,
 		Attributes: attrs,
-	}}
-
-	p.logger.Info("sign request received",
-		"fingerprint", fingerprint,
-		"comment", comment,
-		"destination", p.destination,
-		"invoker", p.senderInfo.InvokerName)
-
-	if _, err := p.approval.RequireApproval(
-		context.TODO(),
-		"ssh-agent",
-		items,
-		"",
-		approval.RequestTypeSSHSign,
-		nil,
-		p.senderInfo,
-	); err != nil {
-		p.logger.Info("sign request denied", "fingerprint", fingerprint, "error", err)
-		return nil, fmt.Errorf("sign request denied: %w", err)
 	}
+}
 
-	p.logger.Info("sign request approved", "fingerprint", fingerprint)
-	if flags != 0 {
-		if ext, ok := p.upstream.(agent.ExtendedAgent); ok {
-			return ext.SignWithFlags(key, data, flags)
-		}
+// agentItem describes an operation on the agent as a whole (lock, unlock,
+// extension), which has no key to show.
+func agentItem(op, label string) approval.ItemInfo {
+	return approval.ItemInfo{
+		Path:       op,
+		Label:      label,
+		Attributes: map[string]string{"operation": op},
 	}
-	return p.upstream.Sign(key, data)
 }
 
 // findKeyComment looks up the comment for a key by matching its blob
@@ -126,25 +156,77 @@ func (p *proxyAgent) findKeyComment(key ssh.PublicKey) string {
 	return ""
 }
 
-// Passthrough methods — delegate directly to upstream.
+// ungatedExtensions are extensions that cannot grant access or alter the
+// agent's keys, so they are forwarded without a prompt. OpenSSH sends
+// session-bind on every connection before authenticating; it only narrows what
+// the forwarded agent may be used for.
+var ungatedExtensions = map[string]bool{
+	"session-bind@openssh.com": true,
+	"query":                    true,
+}
 
+// Add asks before a key is loaded into the agent: a rogue key would later be
+// offered (and signed with) on the user's behalf.
 func (p *proxyAgent) Add(key agent.AddedKey) error {
+	signer, err := ssh.NewSignerFromKey(key.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("add: %w", err)
+	}
+	item := p.keyItem(signer.PublicKey(), key.Comment)
+	if key.LifetimeSecs > 0 {
+		item.Attributes["lifetime"] = (time.Duration(key.LifetimeSecs) * time.Second).String()
+	}
+	if key.ConfirmBeforeUse {
+		item.Attributes["confirm"] = "true"
+	}
+	if err := p.requireApproval("add", approval.RequestTypeSSHAdd, []approval.ItemInfo{item}); err != nil {
+		return err
+	}
 	return p.upstream.Add(key)
 }
 
 func (p *proxyAgent) Remove(key ssh.PublicKey) error {
+	// ssh-add -d reads the key from disk without listing first, so refresh the
+	// cache to show the comment the agent holds for it.
+	p.List() //nolint:errcheck // the comment is cosmetic
+	item := p.keyItem(key, p.findKeyComment(key))
+	if err := p.requireApproval("remove", approval.RequestTypeSSHRemove, []approval.ItemInfo{item}); err != nil {
+		return err
+	}
 	return p.upstream.Remove(key)
 }
 
+// RemoveAll lists every key it would delete in the request, so the prompt
+// shows what is at stake.
 func (p *proxyAgent) RemoveAll() error {
+	keys, err := p.List()
+	if err != nil {
+		return err
+	}
+	items := make([]approval.ItemInfo, 0, len(keys))
+	for _, k := range keys {
+		items = append(items, p.keyItem(k, k.Comment))
+	}
+	if len(items) == 0 {
+		items = append(items, agentItem("remove_all", "All keys"))
+	}
+	if err := p.requireApproval("remove all", approval.RequestTypeSSHRemove, items); err != nil {
+		return err
+	}
 	return p.upstream.RemoveAll()
 }
 
 func (p *proxyAgent) Lock(passphrase []byte) error {
+	if err := p.requireApproval("lock", approval.RequestTypeSSHLock, []approval.ItemInfo{agentItem("lock", "Lock agent")}); err != nil {
+		return err
+	}
 	return p.upstream.Lock(passphrase)
 }
 
 func (p *proxyAgent) Unlock(passphrase []byte) error {
+	if err := p.requireApproval("unlock", approval.RequestTypeSSHLock, []approval.ItemInfo{agentItem("unlock", "Unlock agent")}); err != nil {
+		return err
+	}
 	return p.upstream.Unlock(passphrase)
 }
 
@@ -153,8 +235,16 @@ func (p *proxyAgent) Signers() ([]ssh.Signer, error) {
 }
 
 func (p *proxyAgent) Extension(extensionType string, contents []byte) ([]byte, error) {
-	if ext, ok := p.upstream.(agent.ExtendedAgent); ok {
-		return ext.Extension(extensionType, contents)
+	ext, ok := p.upstream.(agent.ExtendedAgent)
+	if !ok {
+		return nil, agent.ErrExtensionUnsupported
+	}
+	if !ungatedExtensions[extensionType] {
+		item := agentItem("extension", extensionType)
+		item.Attributes["extension"] = extensionType
+		if err := p.requireApproval("extension", approval.RequestTypeSSHExtension, []approval.ItemInfo{item}); err != nil {
+			return nil, err
+		}
 	}
-	return nil, agent.ErrExtensionUnsupported
+	return ext.Extension(extensionType, contents)
 }
@@ -5,6 +5,7 @@ import (
 	"crypto/elliptic"
 	"crypto/rand"
 	"log/slog"
+	"strings"
 	"testing"
 	"time"
 
@@ -225,3 +226,169 @@ func TestProxyAgent_ExtensionPassthrough(t *testing.T) {
 		t.Fatal("expected error from keyring extension, got nil")
 	}
 }
+
+// waitForPending polls until the manager has a pending request and returns it.
+func waitForPending(t *testing.T, mgr *approval.Manager) *approval.Request {
+	t.Helper()
+	for range 100 {
+		if pending := mgr.List(); len(pending) > 0 {
+			return pending[0]
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("no pending request appeared")
+	return nil
+}
+
+func TestProxyAgent_AddRequiresApproval(t *testing.T) {
+	upstream := agent.NewKeyring()
+	mgr := approval.NewManager(approval.ManagerConfig{Timeout: 5 * time.Second, HistoryMax: 100})
+	proxy := newProxyAgent(upstream, mgr, approval.SenderInfo{InvokerName: "ssh-add"}, "", slog.Default())
+
+	testKey := newTestKey(t)
+	testKey.LifetimeSecs = 3600
+	ch := make(chan error, 1)
+	go func() { ch <- proxy.Add(testKey) }()
+
+	req := waitForPending(t, mgr)
+	if req.Type != approval.RequestTypeSSHAdd {
+		t.Errorf("expected type %q, got %q", approval.RequestTypeSSHAdd, req.Type)
+	}
+	if len(req.Items) != 1 || req.Items[0].Label != "test-key@localhost" {
+		t.Fatalf("expected the added key as the only item, got %+v", req.Items)
+	}
+	if fp := req.Items[0].Attributes["fingerprint"]; !strings.HasPrefix(fp, "SHA256:") {
+		t.Errorf("expected SHA256 fingerprint attribute, got %q", fp)
+	}
+	if req.Items[0].Attributes["lifetime"] != "1h0m0s" {
+		t.Errorf("expected lifetime attribute, got %q", req.Items[0].Attributes["lifetime"])
+	}
+
+	if err := mgr.Deny(req.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-ch; err == nil {
+		t.Fatal("expected error after denial, got nil")
+	}
+	if keys, _ := upstream.List(); len(keys) != 0 {
+		t.Errorf("denied key was added upstream: %d keys", len(keys))
+	}
+}
+
+func TestProxyAgent_AddApprovedByTrustRule(t *testing.T) {
+	upstream := agent.NewKeyring()
+	mgr := approval.NewManager(approval.ManagerConfig{
+		Timeout:    5 * time.Second,
+		HistoryMax: 100,
+		TrustRules: []approval.TrustRule{{
+			Name:         "ssh-add",
+			RequestTypes: []string{"ssh_add"},
+			Process:      &approval.ProcessMatcher{Exe: "/usr/bin/ssh-add"},
+		}},
+	})
+	sender := approval.SenderInfo{ProcessChain: []approval.ProcessInfo{{Name: "ssh-add", Exe: "/usr/bin/ssh-add"}}}
+	proxy := newProxyAgent(upstream, mgr, sender, "", slog.Default())
+
+	if err := proxy.Add(newTestKey(t)); err != nil {
+		t.Fatalf("add with matching rule failed: %v", err)
+	}
+	keys, _ := upstream.List()
+	if len(keys) != 1 {
+		t.Fatalf("expected 1 key upstream, got %d", len(keys))
+	}
+
+	// The rule is scoped to ssh_add: removing the key still prompts.
+	ch := make(chan error, 1)
+	go func() { ch <- proxy.Remove(keys[0]) }()
+	req := waitForPending(t, mgr)
+	if req.Type != approval.RequestTypeSSHRemove {
+		t.Errorf("expected type %q, got %q", approval.RequestTypeSSHRemove, req.Type)
+	}
+	if req.Items[0].Label != "test-key@localhost" {
+		t.Errorf("expected remove request to show the key comment, got %q", req.Items[0].Label)
+	}
+	if err := mgr.Approve(req.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-ch; err != nil {
+		t.Fatalf("remove after approval failed: %v", err)
+	}
+}
+
+func TestProxyAgent_RemoveAllListsKeys(t *testing.T) {
+	upstream := agent.NewKeyring()
+	for range 2 {
+		if err := upstream.Add(newTestKey(t)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mgr := approval.NewManager(approval.ManagerConfig{Timeout: 5 * time.Second, HistoryMax: 100})
+	proxy := newProxyAgent(upstream, mgr, approval.SenderInfo{}, "", slog.Default())
+
+	ch := make(chan error, 1)
+	go func() { ch <- proxy.RemoveAll() }()
+
+	req := waitForPending(t, mgr)
+	if req.Type != approval.RequestTypeSSHRemove {
+		t.Errorf("expected type %q, got %q", approval.RequestTypeSSHRemove, req.Type)
+	}
+	if len(req.Items) != 2 {
+		t.Errorf("expected both keys in the request, got %d items", len(req.Items))
+	}
+	if err := mgr.Deny(req.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-ch; err == nil {
+		t.Fatal("expected error after denial, got nil")
+	}
+	if keys, _ := upstream.List(); len(keys) != 2 {
+		t.Errorf("denied RemoveAll removed keys: %d left", len(keys))
+	}
+}
+
+func TestProxyAgent_LockRequiresApproval(t *testing.T) {
+	upstream := agent.NewKeyring()
+	mgr := approval.NewManager(approval.ManagerConfig{Timeout: 5 * time.Second, HistoryMax: 100})
+	proxy := newProxyAgent(upstream, mgr, approval.SenderInfo{}, "", slog.Default())
+
+	ch := make(chan error, 1)
+	go func() { ch <- proxy.Lock([]byte("pass")) }()
+
+	req := waitForPending(t, mgr)
+	if req.Type != approval.RequestTypeSSHLock || req.Items[0].Attributes["operation"] != "lock" {
+		t.Errorf("expected ssh_lock request for lock, got %q %+v", req.Type, req.Items)
+	}
+	if err := mgr.Deny(req.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-ch; err == nil {
+		t.Fatal("expected error after denial, got nil")
+	}
+	// The agent is still unlocked, so Add through the keyring works.
+	if err := upstream.Add(newTestKey(t)); err != nil {
+		t.Errorf("agent was locked despite denial: %v", err)
+	}
+}
+
+func TestProxyAgent_SessionBindUngated(t *testing.T) {
+	upstream := agent.NewKeyring()
+	// A manager with no way to approve: any prompt would block until timeout.
+	mgr := approval.NewManager(approval.ManagerConfig{Timeout: 50 * time.Millisecond, HistoryMax: 100})
+	proxy := newProxyAgent(upstream, mgr, approval.SenderInfo{}, "", slog.Default())
+
+	_, err := proxy.Extension("session-bind@openssh.com", []byte{})
+	if err != agent.ErrExtensionUnsupported {
+		t.Fatalf("expected the keyring's unsupported error without a prompt, got %v", err)
+	}
+	if n := len(mgr.History()); n != 0 {
+		t.Errorf("session-bind created %d history entries, want 0", n)
+	}
+
+	if _, err := proxy.Extension("custom@example.com", []byte{}); err == nil {
+		t.Fatal("expected unapproved extension to fail")
+	}
+	history := mgr.History()
+	if len(history) != 1 || history[0].Request.Type != approval.RequestTypeSSHExtension {
+		t.Errorf("expected one ssh_extension request in history, got %+v", history)
+	}
+}
@@ -128,21 +128,24 @@ func (s *Server) extractSenderInfo(conn net.Conn) approval.SenderInfo {
 	processChain := make([]approval.ProcessInfo, len(chain))
 	for i, entry := range chain {
 		processChain[i] = approval.ProcessInfo{
-			Name: entry.Comm,
-			PID:  uint32(entry.PID),
-			Exe:  entry.Exe,
-			Args: entry.Args,
-			CWD:  entry.CWD,
+			Name:      entry.Comm,
+			PID:       uint32(entry.PID),
+			StartTime: entry.StartTime,
+			Exe:       entry.Exe,
+			Args:      entry.Args,
+			CWD:       entry.CWD,
 		}
 	}
 
 	// Resolve invoker (skip shells)
 	comm, invokerPID := procutil.ResolveInvoker(uint32(cred.Pid))
 
-	return approval.SenderInfo{
+	info := approval.SenderInfo{
 		PID:          invokerPID,
 		UID:          uint32(cred.Uid),
 		InvokerName:  comm,
 		ProcessChain: processChain,
 	}
+	info.ReadSandbox(cred.Pid)
+	return info
 }
@@ -0,0 +1,263 @@
+package trash
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	dbustypes "github.com/nikicat/secrets-dispatcher/internal/dbus"
+)
+
+// keyAttributes identify the trash key item in the keyring.
+var keyAttributes = map[string]string{"xdg:schema": "net.mowaka.SecretsDispatcher.TrashKey"}
+
+// KeyringKey keeps the trash key as an item in the default collection of a
+// Secret Service, where the keyring's login password protects it.
+type KeyringKey struct {
+	// Dial connects to the Secret Service; the connection is closed after use.
+	Dial func() (*dbus.Conn, error)
+}
+
+// Load implements KeyStore. It fails if the key's collection is locked.
+func (k KeyringKey) Load() ([]byte, error) {
+	conn, err := k.Dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var unlocked, locked []dbus.ObjectPath
+	call := conn.Object(dbustypes.BusName, dbustypes.ServicePath).Call(dbustypes.ServiceInterface+".SearchItems", 0, keyAttributes)
+	if err := call.Store(&unlocked, &locked); err != nil {
+		return nil, fmt.Errorf("search keyring: %w", err)
+	}
+	if len(unlocked) == 0 {
+		if len(locked) > 0 {
+			return nil, errors.New("the keyring holding it is locked")
+		}
+		return nil, nil
+	}
+	items, err := Snapshot(conn, unlocked[:1])
+	if err != nil {
+		return nil, err
+	}
+	return items[0].Secret, nil
+}
+
+// Save implements KeyStore.
+func (k KeyringKey) Save(key []byte) error {
+	conn, err := k.Dial()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	session, err := openSession(conn)
+	if err != nil {
+		return err
+	}
+	defer closeSession(conn, session)
+
+	props := map[string]dbus.Variant{
+		dbustypes.ItemInterface + ".Label":      dbus.MakeVariant("secrets-dispatcher trash key"),
+		dbustypes.ItemInterface + ".Attributes": dbus.MakeVariant(keyAttributes),
+	}
+	secret := dbustypes.Secret{Session: session, Parameters: []byte{}, Value: key, ContentType: "application/octet-stream"}
+	var path, prompt dbus.ObjectPath
+	call := conn.Object(dbustypes.BusName, "/org/freedesktop/secrets/aliases/default").Call(dbustypes.CollectionInterface+".CreateItem", 0, props, secret, true)
+	if err := call.Store(&path, &prompt); err != nil {
+		return fmt.Errorf("store in keyring: %w", err)
+	}
+	if prompt != "/" {
+		if _, err := runPrompt(conn, prompt); err != nil {
+			return fmt.Errorf("store in keyring: %w", err)
+		}
+	}
+	return nil
+}
+
+// promptTimeout bounds how long Restore waits for the user to answer an
+// upstream prompt (e.g. the password of a recreated collection).
+const promptTimeout = 2 * time.Minute
+
+// Snapshot reads the label, attributes and secret of each item from the
+// Secret Service on conn, through a plain session of its own. It fails if any
+// secret cannot be read, e.g. because its collection is locked.
+func Snapshot(conn *dbus.Conn, paths []dbus.ObjectPath) ([]Item, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	session, err := openSession(conn)
+	if err != nil {
+		return nil, err
+	}
+	defer closeSession(conn, session)
+
+	var secrets map[dbus.ObjectPath]dbustypes.Secret
+	call := conn.Object(dbustypes.BusName, dbustypes.ServicePath).Call(dbustypes.ServiceInterface+".GetSecrets", 0, paths, session)
+	if err := call.Store(&secrets); err != nil {
+		return nil, fmt.Errorf("read secrets: %w", err)
+	}
+
+	items := make([]Item, 0, len(paths))
+	for _, path := range paths {
+		secret, ok := secrets[path]
+		if !ok {
+			if v, err := conn.Object(dbustypes.BusName, path).GetProperty(dbustypes.ItemInterface + ".Locked"); err == nil && v.Value() == true {
+				return nil, fmt.Errorf("read secret of %s: it is locked", path)
+			}
+			return nil, fmt.Errorf("read secret of %s: not returned", path)
+		}
+		item := Item{Path: string(path), Secret: secret.Value, ContentType: secret.ContentType}
+		obj := conn.Object(dbustypes.BusName, path)
+		if v, err := obj.GetProperty(dbustypes.ItemInterface + ".Label"); err == nil {
+			item.Label, _ = v.Value().(string)
+		}
+		if v, err := obj.GetProperty(dbustypes.ItemInterface + ".Attributes"); err == nil {
+			item.Attributes, _ = v.Value().(map[string]string)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// CollectionItems returns the items of the collection at path.
+func CollectionItems(conn *dbus.Conn, path dbus.ObjectPath) ([]dbus.ObjectPath, error) {
+	v, err := conn.Object(dbustypes.BusName, path).GetProperty(dbustypes.CollectionInterface + ".Items")
+	if err != nil {
+		return nil, err
+	}
+	items, ok := v.Value().([]dbus.ObjectPath)
+	if !ok {
+		return nil, fmt.Errorf("unexpected Items type %s", v.Signature())
+	}
+	return items, nil
+}
+
+// Restore recreates e's items in the Secret Service on conn and returns their
+// new paths. Items go back into their collection; a deleted collection is
+// recreated first (under a new path, possibly after a password prompt). Items
+// are added, never replacing an item created since the deletion.
+//
+// On error the returned paths are those of e's leading items that were
+// restored, and e.Collection names the recreated collection, so the rest can
+// be retried into it.
+func Restore(conn *dbus.Conn, e *Entry) ([]dbus.ObjectPath, error) {
+	collection := dbus.ObjectPath(e.Collection)
+	if _, err := conn.Object(dbustypes.BusName, collection).GetProperty(dbustypes.CollectionInterface + ".Label"); err != nil {
+		if e.Kind != KindCollection {
+			return nil, fmt.Errorf("collection %s no longer exists", e.Collection)
+		}
+		if collection, err = createCollection(conn, e.CollectionLabel); err != nil {
+			return nil, err
+		}
+		e.Collection = string(collection)
+	}
+
+	session, err := openSession(conn)
+	if err != nil {
+		return nil, err
+	}
+	defer closeSession(conn, session)
+
+	obj := conn.Object(dbustypes.BusName, collection)
+	restored := make([]dbus.ObjectPath, 0, len(e.Items))
+	for _, item := range e.Items {
+		props := map[string]dbus.Variant{
+			dbustypes.ItemInterface + ".Label":      dbus.MakeVariant(item.Label),
+			dbustypes.ItemInterface + ".Attributes": dbus.MakeVariant(nonNil(item.Attributes)),
+		}
+		secret := dbustypes.Secret{Session: session, Parameters: []byte{}, Value: item.Secret, ContentType: item.ContentType}
+		var path, prompt dbus.ObjectPath
+		if err := obj.Call(dbustypes.CollectionInterface+".CreateItem", 0, props, secret, false).Store(&path, &prompt); err != nil {
+			return restored, fmt.Errorf("recreate %q: %w", item.Label, err)
+		}
+		if prompt != "/" {
+			result, err := runPrompt(conn, prompt)
+			if err != nil {
+				return restored, fmt.Errorf("recreate %q: %w", item.Label, err)
+			}
+			path, _ = result.Value().(dbus.ObjectPath)
+		}
+		restored = append(restored, path)
+	}
+	return restored, nil
+}
+
+func createCollection(conn *dbus.Conn, label string) (dbus.ObjectPath, error) {
+	props := map[string]dbus.Variant{dbustypes.CollectionInterface + ".Label": dbus.MakeVariant(label)}
+	var path, prompt dbus.ObjectPath
+	call := conn.Object(dbustypes.BusName, dbustypes.ServicePath).Call(dbustypes.ServiceInterface+".CreateCollection", 0, props, "")
+	if err := call.Store(&path, &prompt); err != nil {
+		return "", fmt.Errorf("recreate collection %q: %w", label, err)
+	}
+	if prompt != "/" {
+		result, err := runPrompt(conn, prompt)
+		if err != nil {
+			return "", fmt.Errorf("recreate collection %q: %w", label, err)
+		}
+		path, _ = result.Value().(dbus.ObjectPath)
+	}
+	if path == "" || path == "/" {
+		return "", fmt.Errorf("recreate collection %q: no collection created", label)
+	}
+	return path, nil
+}
+
+// runPrompt shows an upstream prompt and waits for its Completed signal.
+func runPrompt(conn *dbus.Conn, prompt dbus.ObjectPath) (dbus.Variant, error) {
+	match := []dbus.MatchOption{
+		dbus.WithMatchObjectPath(prompt),
+		dbus.WithMatchInterface(dbustypes.PromptInterface),
+		dbus.WithMatchMember("Completed"),
+	}
+	if err := conn.AddMatchSignal(match...); err != nil {
+		return dbus.Variant{}, err
+	}
+	defer conn.RemoveMatchSignal(match...) //nolint:errcheck // best effort
+	signals := make(chan *dbus.Signal, 4)
+	conn.Signal(signals)
+	defer conn.RemoveSignal(signals)
+
+	if err := conn.Object(dbustypes.BusName, prompt).Call(dbustypes.PromptInterface+".Prompt", 0, "").Err; err != nil {
+		return dbus.Variant{}, err
+	}
+	timeout := time.After(promptTimeout)
+	for {
+		select {
+		case sig := <-signals:
+			if sig.Path != prompt || sig.Name != dbustypes.PromptInterface+".Completed" || len(sig.Body) < 2 {
+				continue
+			}
+			if dismissed, _ := sig.Body[0].(bool); dismissed {
+				return dbus.Variant{}, errors.New("prompt dismissed")
+			}
+			result, _ := sig.Body[1].(dbus.Variant)
+			return result, nil
+		case <-timeout:
+			return dbus.Variant{}, errors.New("timed out waiting for prompt")
+		}
+	}
+}
+
+func openSession(conn *dbus.Conn) (dbus.ObjectPath, error) {
+	var output dbus.Variant
+	var session dbus.ObjectPath
+	call := conn.Object(dbustypes.BusName, dbustypes.ServicePath).Call(dbustypes.ServiceInterface+".OpenSession", 0, dbustypes.AlgorithmPlain, dbus.MakeVariant(""))
+	if err := call.Store(&output, &session); err != nil {
+		return "", fmt.Errorf("open session: %w", err)
+	}
+	return session, nil
+}
+
+func closeSession(conn *dbus.Conn, session dbus.ObjectPath) {
+	conn.Object(dbustypes.BusName, session).Call(dbustypes.SessionInterface+".Close", 0) //nolint:errcheck // best effort
+}
+
+func nonNil(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+	return m
+}
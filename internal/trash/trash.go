@@ -0,0 +1,366 @@
+// Package trash keeps the secrets of approved deletions for a while, so that a
+// mistaken approval can be undone by recreating the items upstream.
+//
+// Each deletion is one entry file (mode 0600), encrypted with AES-256-GCM
+// under a random key generated on first use. The key is kept away from the
+// entries, in the user's keyring (see KeyringKey), so that reading the trash
+// directory, or a backup of it, does not reveal the deleted secrets.
+package trash
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// expiryInterval is how often RunExpiry looks for expired entries.
+const expiryInterval = time.Hour
+
+const (
+	entrySuffix = ".trash"
+	keySize     = 32
+)
+
+var (
+	// ErrNotFound is returned when no entry has the given ID.
+	ErrNotFound = errors.New("trash entry not found")
+	// ErrAmbiguous is returned when an ID prefix matches several entries.
+	ErrAmbiguous = errors.New("ambiguous trash entry ID")
+)
+
+// Kinds of entry.
+const (
+	KindItem       = "item"
+	KindCollection = "collection"
+)
+
+// Item is a snapshot of one deleted secret item.
+type Item struct {
+	Path        string            `json:"path"`
+	Label       string            `json:"label"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	ContentType string            `json:"content_type,omitempty"`
+	Secret      []byte            `json:"secret"`
+}
+
+// Entry is one deletion: a single item, or a whole collection with its items.
+type Entry struct {
+	ID              string    `json:"id"`
+	DeletedAt       time.Time `json:"deleted_at"`
+	Kind            string    `json:"kind"`
+	Client          string    `json:"client"`            // downstream client that deleted it
+	Backend         string    `json:"backend,omitempty"` // upstream the items were on; empty = the first
+	Collection      string    `json:"collection"`        // path of the collection on that upstream
+	CollectionLabel string    `json:"collection_label,omitempty"`
+	Items           []Item    `json:"items"`
+}
+
+// KeyStore keeps the key the entries are encrypted with.
+type KeyStore interface {
+	// Load returns the key, or nil if none has been saved yet.
+	Load() ([]byte, error)
+	Save(key []byte) error
+}
+
+// Store is a directory of encrypted trash entries.
+type Store struct {
+	dir       string
+	retention time.Duration
+	keys      KeyStore
+
+	mu   sync.Mutex
+	aead cipher.AEAD // loaded on first use
+}
+
+// NewStore returns a store in dir that expires entries after retention, with
+// its key in keys. The directory and key are created on the first write.
+func NewStore(dir string, retention time.Duration, keys KeyStore) *Store {
+	return &Store{dir: dir, retention: retention, keys: keys}
+}
+
+// Dir returns the store's directory.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Retention returns how long entries are kept.
+func (s *Store) Retention() time.Duration {
+	return s.retention
+}
+
+// Put saves e, assigning its ID and deletion time if unset.
+func (s *Store) Put(e *Entry) error {
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	if e.DeletedAt.IsZero() {
+		e.DeletedAt = time.Now()
+	}
+	aead, err := s.cipher(true)
+	if err != nil {
+		return err
+	}
+	plain, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	sealed := aead.Seal(nonce, nonce, plain, []byte(e.ID))
+	return writeFileAtomic(s.entryPath(e.ID), sealed)
+}
+
+// List returns the unexpired entries, newest first. Entries that cannot be
+// read are skipped with a warning.
+func (s *Store) List() ([]Entry, error) {
+	s.Expire(time.Now())
+	ids, err := s.ids()
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(ids))
+	for _, id := range ids {
+		e, err := s.read(id)
+		if err != nil {
+			slog.Warn("skipping unreadable trash entry", "id", id, "error", err)
+			continue
+		}
+		entries = append(entries, *e)
+	}
+	slices.SortFunc(entries, func(a, b Entry) int { return b.DeletedAt.Compare(a.DeletedAt) })
+	return entries, nil
+}
+
+// Get returns the entry whose ID is id or starts with it.
+func (s *Store) Get(id string) (*Entry, error) {
+	full, err := s.resolve(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.read(full)
+}
+
+// Remove deletes the entry whose ID is id or starts with it.
+func (s *Store) Remove(id string) error {
+	full, err := s.resolve(id)
+	if err != nil {
+		return err
+	}
+	return os.Remove(s.entryPath(full))
+}
+
+// Purge deletes every entry and returns how many there were.
+func (s *Store) Purge() (int, error) {
+	ids, err := s.ids()
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		if err := os.Remove(s.entryPath(id)); err != nil && !os.IsNotExist(err) {
+			return 0, err
+		}
+	}
+	return len(ids), nil
+}
+
+// Expire deletes the entries older than the retention and returns how many.
+func (s *Store) Expire(now time.Time) int {
+	ids, err := s.ids()
+	if err != nil {
+		return 0
+	}
+	n := 0
+	for _, id := range ids {
+		info, err := os.Stat(s.entryPath(id))
+		if err != nil || now.Sub(info.ModTime()) <= s.retention {
+			continue
+		}
+		if err := os.Remove(s.entryPath(id)); err == nil {
+			n++
+		}
+	}
+	if n > 0 {
+		slog.Info("expired trash entries", "count", n, "retention", s.retention)
+	}
+	return n
+}
+
+// RunExpiry expires entries now and then hourly until ctx is done.
+func (s *Store) RunExpiry(ctx context.Context) {
+	ticker := time.NewTicker(expiryInterval)
+	defer ticker.Stop()
+	for {
+		s.Expire(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Store) entryPath(id string) string {
+	return filepath.Join(s.dir, id+entrySuffix)
+}
+
+// ids lists the IDs of the stored entries; a missing directory holds none.
+func (s *Store) ids() ([]string, error) {
+	des, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, de := range des {
+		if id, ok := strings.CutSuffix(de.Name(), entrySuffix); ok && !de.IsDir() {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// resolve expands a unique ID prefix to the full entry ID.
+func (s *Store) resolve(prefix string) (string, error) {
+	ids, err := s.ids()
+	if err != nil {
+		return "", err
+	}
+	if prefix == "" {
+		return "", ErrNotFound
+	}
+	var match string
+	for _, id := range ids {
+		if id == prefix {
+			return id, nil
+		}
+		if strings.HasPrefix(id, prefix) {
+			if match != "" {
+				return "", fmt.Errorf("%w: %s", ErrAmbiguous, prefix)
+			}
+			match = id
+		}
+	}
+	if match == "" {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, prefix)
+	}
+	return match, nil
+}
+
+func (s *Store) read(id string) (*Entry, error) {
+	sealed, err := os.ReadFile(s.entryPath(id))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	aead, err := s.cipher(false)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("truncated entry")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, ciphertext, []byte(id))
+	if err != nil {
+		return nil, fmt.Errorf("decrypt: %w", err)
+	}
+	var e Entry
+	if err := json.Unmarshal(plain, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// cipher returns the store's AEAD, loading the key or, with create,
+// generating it if there is none yet.
+func (s *Store) cipher(create bool) (cipher.AEAD, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.aead != nil {
+		return s.aead, nil
+	}
+	key, err := s.keys.Load()
+	if err == nil && key == nil {
+		if !create {
+			return nil, errors.New("trash key: none saved")
+		}
+		key = make([]byte, keySize)
+		if _, err = rand.Read(key); err == nil {
+			err = s.keys.Save(key)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("trash key: %w", err)
+	}
+	if len(key) != keySize {
+		return nil, fmt.Errorf("trash key: want %d bytes, got %d", keySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	s.aead = aead
+	return aead, nil
+}
+
+// FileKey keeps the key in a file (mode 0600). It must not be in the store's
+// directory.
+type FileKey string
+
+// Load implements KeyStore.
+func (f FileKey) Load() ([]byte, error) {
+	key, err := os.ReadFile(string(f))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return key, err
+}
+
+// Save implements KeyStore.
+func (f FileKey) Save(key []byte) error {
+	return writeFileAtomic(string(f), key)
+}
+
+// writeFileAtomic writes data to path (mode 0600) via a temp file.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // no-op after a successful rename
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
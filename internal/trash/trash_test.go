@@ -0,0 +1,189 @@
+package trash
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testEntry(label, secret string) *Entry {
+	return &Entry{
+		Kind:       KindItem,
+		Client:     "test",
+		Collection: "/org/freedesktop/secrets/collection/default",
+		Items: []Item{{
+			Path:       "/org/freedesktop/secrets/collection/default/1",
+			Label:      label,
+			Attributes: map[string]string{"service": "github"},
+			Secret:     []byte(secret),
+		}},
+	}
+}
+
+// testStore returns a store in a fresh directory, with its key in another.
+func testStore(t *testing.T) *Store {
+	return NewStore(t.TempDir(), time.Hour, FileKey(filepath.Join(t.TempDir(), "key")))
+}
+
+func TestStore_PutGetList(t *testing.T) {
+	s := testStore(t)
+
+	older := testEntry("older", "s1")
+	older.DeletedAt = time.Now().Add(-time.Minute)
+	if err := s.Put(older); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	newer := testEntry("newer", "s2")
+	if err := s.Put(newer); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if newer.ID == "" || newer.DeletedAt.IsZero() {
+		t.Fatalf("Put did not assign ID and time: %+v", newer)
+	}
+
+	entries, err := s.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(entries) != 2 || entries[0].ID != newer.ID || entries[1].ID != older.ID {
+		t.Fatalf("List = %+v, want newer then older", entries)
+	}
+
+	got, err := s.Get(newer.ID[:8])
+	if err != nil {
+		t.Fatalf("Get by prefix: %v", err)
+	}
+	if got.Items[0].Label != "newer" || string(got.Items[0].Secret) != "s2" || got.Items[0].Attributes["service"] != "github" {
+		t.Errorf("Get = %+v", got.Items[0])
+	}
+
+	if _, err := s.Get("nonexistent"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(nonexistent) error = %v, want ErrNotFound", err)
+	}
+	if _, err := s.Get(""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(\"\") error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestStore_AmbiguousPrefix(t *testing.T) {
+	s := testStore(t)
+	for _, id := range []string{"abc-1", "abc-2"} {
+		e := testEntry(id, "x")
+		e.ID = id
+		if err := s.Put(e); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+	if _, err := s.Get("abc"); !errors.Is(err, ErrAmbiguous) {
+		t.Errorf("Get(abc) error = %v, want ErrAmbiguous", err)
+	}
+	if e, err := s.Get("abc-2"); err != nil || e.ID != "abc-2" {
+		t.Errorf("Get(abc-2) = %v, %v", e, err)
+	}
+}
+
+func TestStore_RemoveAndPurge(t *testing.T) {
+	s := testStore(t)
+	var ids []string
+	for _, label := range []string{"a", "b", "c"} {
+		e := testEntry(label, "x")
+		if err := s.Put(e); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		ids = append(ids, e.ID)
+	}
+
+	if err := s.Remove(ids[0]); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if _, err := s.Get(ids[0]); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Remove error = %v, want ErrNotFound", err)
+	}
+
+	n, err := s.Purge()
+	if err != nil || n != 2 {
+		t.Fatalf("Purge = %d, %v; want 2", n, err)
+	}
+	entries, _ := s.List()
+	if len(entries) != 0 {
+		t.Errorf("List after Purge = %d entries", len(entries))
+	}
+}
+
+func TestStore_Expire(t *testing.T) {
+	s := testStore(t)
+	old := testEntry("old", "x")
+	fresh := testEntry("fresh", "y")
+	for _, e := range []*Entry{old, fresh} {
+		if err := s.Put(e); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+	past := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(s.entryPath(old.ID), past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := s.Expire(time.Now()); n != 1 {
+		t.Fatalf("Expire = %d, want 1", n)
+	}
+	entries, err := s.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(entries) != 1 || entries[0].ID != fresh.ID {
+		t.Errorf("List after Expire = %+v, want only fresh", entries)
+	}
+}
+
+func TestStore_Encrypted(t *testing.T) {
+	dir := t.TempDir()
+	key := FileKey(filepath.Join(t.TempDir(), "key"))
+	s := NewStore(dir, time.Hour, key)
+	e := testEntry("my-label", "hunter2-secret")
+	if err := s.Put(e); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	data, err := os.ReadFile(s.entryPath(e.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, plain := range []string{"hunter2-secret", "my-label", "github"} {
+		if bytes.Contains(data, []byte(plain)) {
+			t.Errorf("entry file contains %q in plaintext", plain)
+		}
+	}
+	for _, path := range []string{s.entryPath(e.ID), string(key)} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := info.Mode().Perm(); perm != 0o600 {
+			t.Errorf("%s mode = %o, want 600", path, perm)
+		}
+	}
+	if names, _ := os.ReadDir(dir); len(names) != 1 {
+		t.Errorf("trash directory holds %d files, want only the entry", len(names))
+	}
+
+	// A fresh store reads the entry back with the saved key.
+	got, err := NewStore(dir, time.Hour, key).Get(e.ID)
+	if err != nil {
+		t.Fatalf("Get from new store: %v", err)
+	}
+	if string(got.Items[0].Secret) != "hunter2-secret" {
+		t.Errorf("secret = %q", got.Items[0].Secret)
+	}
+
+	// With another key the entry cannot be decrypted.
+	if err := key.Save(bytes.Repeat([]byte{1}, keySize)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewStore(dir, time.Hour, key).Get(e.ID); err == nil {
+		t.Error("Get with wrong key succeeded")
+	}
+}
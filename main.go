@@ -3,14 +3,20 @@ package main
 
 import (
 	"context"
+	"crypto/ed25519"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path/filepath"
 	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -23,11 +29,15 @@ import (
 	"github.com/nikicat/secrets-dispatcher/internal/companion"
 	"github.com/nikicat/secrets-dispatcher/internal/config"
 	"github.com/nikicat/secrets-dispatcher/internal/daemon"
+	"github.com/nikicat/secrets-dispatcher/internal/gpgagent"
 	"github.com/nikicat/secrets-dispatcher/internal/gpgsign"
+	"github.com/nikicat/secrets-dispatcher/internal/history"
 	"github.com/nikicat/secrets-dispatcher/internal/notification"
+	"github.com/nikicat/secrets-dispatcher/internal/pairing"
 	"github.com/nikicat/secrets-dispatcher/internal/proxy"
 	"github.com/nikicat/secrets-dispatcher/internal/service"
 	"github.com/nikicat/secrets-dispatcher/internal/sshagent"
+	"github.com/nikicat/secrets-dispatcher/internal/trash"
 	"gopkg.in/yaml.v3"
 )
 
@@ -75,12 +85,22 @@ func main() {
 		runCLI("history", os.Args[2:])
 	case "config":
 		runConfig(os.Args[2:])
+	case "rule", "rules":
+		runRule(os.Args[2:])
+	case "trash":
+		runTrash(os.Args[2:])
+	case "pair":
+		runPair(os.Args[2:])
+	case "clients":
+		runClients(os.Args[2:])
 	case "try":
 		runTry(os.Args[2:])
 	case "service":
 		runService(os.Args[2:])
 	case "gpg-sign":
 		runGPGSign(os.Args[2:])
+	case "ssh-sign":
+		runSSHSign(os.Args[2:])
 	case "provision":
 		runProvision(os.Args[2:])
 	case "daemon":
@@ -104,14 +124,25 @@ Commands:
   login         Generate a login URL for the web UI
   list          List pending approval requests
   show          Show details of a request (pending or resolved)
-  approve       Approve a pending request
-  deny          Deny a pending request
+  approve       Approve a pending request (--items 1,3: only some of a batch;
+                --until-exit: keep approving that process until it exits;
+                --series: also sign the rest of a rebase, cherry-pick or am)
+  deny          Deny a pending request (--for 15m: also deny similar requests for
+                a while; --mute: everything from that executable)
   history       Show resolved requests
   config        Show or manage configuration
+  rule add      Save a trust rule derived from a request to config.yaml
+  rule test     Show what a candidate config would decide for past or synthetic requests
+  rule stats    Show trust rule hit counts and flag unused or overly broad rules
+  trash         List, restore or purge deleted secrets kept for undo
+  pair          Pair this (remote) host with the dispatcher serving its session bus
+  clients       List or remove paired remote clients
   try           Reversible trial: take over the Secret Service, Ctrl-C restores everything
   service       Manage the systemd user service
   gpg-sign      GPG signing proxy (called by git as gpg.program)
   gpg-sign setup  Configure git to use secrets-dispatcher for GPG signing
+                (--format ssh: for SSH signing, gpg.format=ssh)
+  ssh-sign      SSH signing proxy (called by git as gpg.ssh.program)
   provision     Provision companion user and deployment artifacts (requires root)
   daemon        Run companion daemon (registers on system D-Bus)
   version       Print the version and exit
@@ -187,45 +218,19 @@ func runCLI(cmd string, args []string) {
 	stateDirFlag := fs.String("state-dir", "", "State directory (default: $XDG_STATE_HOME/secrets-dispatcher)")
 	serverAddr := fs.String("server", defaultListenAddr, "API server address")
 	jsonOutput := fs.Bool("json", false, "Output as JSON")
-	fs.Parse(args)
-
-	// Load config and apply values for flags not explicitly set
-	cfg, err := loadConfig(*configPath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
-	}
-	set := setFlags(fs)
-	if !set["state-dir"] && cfg.StateDir != "" {
-		*stateDirFlag = cfg.StateDir
-	}
-	if !set["server"] && cfg.Listen != "" {
-		*serverAddr = cfg.Listen
-	}
-
-	var stateDir string
-	if *stateDirFlag != "" {
-		stateDir = *stateDirFlag
-	} else {
-		stateDir, err = getStateDir()
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "error: %v\n", err)
-			os.Exit(1)
-		}
-	}
-
-	auth, err := api.LoadAuth(stateDir)
-	if err != nil {
-		if os.IsNotExist(err) {
-			fmt.Fprintf(os.Stderr, "error: %s is not running (no cookie file found)\n", progName)
-			fmt.Fprintf(os.Stderr, "Start the service first with: %s serve\n", progName)
-		} else {
-			fmt.Fprintf(os.Stderr, "error loading auth: %v\n", err)
-		}
-		os.Exit(1)
-	}
-
-	client := cli.NewClient(*serverAddr, auth.Token())
+	since := fs.String("since", "", "history: only entries resolved at or after this time (duration ago, e.g. 24h, or RFC 3339)")
+	until := fs.String("until", "", "history: only entries resolved before this time (duration ago, e.g. 1h, or RFC 3339)")
+	limit := fs.Int("limit", 0, "history: maximum number of entries when paging persisted history (0 = no limit)")
+	items := fs.String("items", "", "approve: comma-separated item numbers (as listed by show) to approve; the others are refused")
+	untilExit := fs.Bool("until-exit", false, "approve: also auto-approve similar requests from the requesting process until it exits")
+	pid := fs.Uint("pid", 0, "approve --until-exit: bind to this process of the request's process chain instead of the requester")
+	subtree := fs.Bool("subtree", false, "approve --until-exit: also cover the process's descendants")
+	series := fs.Bool("series", false, "approve: also sign the remaining commits of the rebase, cherry-pick or am a signing request is part of")
+	denyFor := fs.Duration("for", 0, "deny: also deny similar requests for this long (e.g. 15m)")
+	mute := fs.Bool("mute", false, "deny: also deny every request from the same executable, for --for or 15m")
+	positional := parseInterspersed(fs, args)
+
+	client := newCLIClient(fs, *configPath, *stateDirFlag, *serverAddr)
 	formatter := cli.NewFormatter(os.Stdout, *jsonOutput)
 
 	switch cmd {
@@ -238,43 +243,104 @@ func runCLI(cmd string, args []string) {
 		formatter.FormatRequests(requests)
 
 	case "show":
-		if fs.NArg() < 1 {
+		if len(positional) < 1 {
 			fmt.Fprintf(os.Stderr, "usage: %s show <request-id>\n", progName)
 			os.Exit(1)
 		}
-		result, err := client.Show(fs.Arg(0))
+		result, err := client.Show(positional[0])
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "error: %v\n", err)
 			os.Exit(1)
 		}
+		if info := result.Request.GPGSignInfo; info != nil && info.Diff != nil {
+			if info.Diff.Patch, err = client.Patch(result.Request.ID); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: diff not shown: %v\n", err)
+			}
+		}
 		formatter.FormatShowResult(result)
 
 	case "approve":
-		if fs.NArg() < 1 {
-			fmt.Fprintf(os.Stderr, "usage: %s approve <request-id>\n", progName)
+		if len(positional) < 1 {
+			fmt.Fprintf(os.Stderr, "usage: %s approve <request-id> [--items 1,3 | --until-exit [--pid N] [--subtree] | --series]\n", progName)
 			os.Exit(1)
 		}
-		id := fs.Arg(0)
-		if err := client.Approve(id); err != nil {
+		id := positional[0]
+		if *items != "" && *untilExit || *series && (*items != "" || *untilExit) {
+			fmt.Fprintln(os.Stderr, "error: --items, --until-exit and --series cannot be combined")
+			os.Exit(1)
+		}
+		if (*pid != 0 || *subtree) && !*untilExit {
+			fmt.Fprintln(os.Stderr, "error: --pid and --subtree require --until-exit")
+			os.Exit(1)
+		}
+		var err error
+		if *untilExit {
+			err = client.ApproveForProcess(id, uint32(*pid), *subtree)
+		} else if *series {
+			err = client.ApproveSeries(id)
+		} else if *items != "" {
+			var numbers []int
+			for _, f := range splitList(*items) {
+				n, convErr := strconv.Atoi(f)
+				if convErr != nil {
+					fmt.Fprintf(os.Stderr, "error: --items: %q is not an item number\n", f)
+					os.Exit(1)
+				}
+				numbers = append(numbers, n)
+			}
+			err = client.ApproveItems(id, numbers)
+		} else {
+			err = client.Approve(id)
+		}
+		if err != nil {
 			fmt.Fprintf(os.Stderr, "error: %v\n", err)
 			os.Exit(1)
 		}
 		formatter.FormatAction("approved", id)
 
 	case "deny":
-		if fs.NArg() < 1 {
-			fmt.Fprintf(os.Stderr, "usage: %s deny <request-id>\n", progName)
+		if len(positional) < 1 {
+			fmt.Fprintf(os.Stderr, "usage: %s deny <request-id> [--for 15m] [--mute]\n", progName)
 			os.Exit(1)
 		}
-		id := fs.Arg(0)
-		if err := client.Deny(id); err != nil {
+		id := positional[0]
+		var err error
+		if *denyFor != 0 || *mute {
+			if *denyFor < 0 {
+				fmt.Fprintln(os.Stderr, "error: --for must not be negative")
+				os.Exit(1)
+			}
+			err = client.DenyFor(id, *denyFor, *mute)
+		} else {
+			err = client.Deny(id)
+		}
+		if err != nil {
 			fmt.Fprintf(os.Stderr, "error: %v\n", err)
 			os.Exit(1)
 		}
 		formatter.FormatAction("denied", id)
 
 	case "history":
-		entries, err := client.History()
+		var entries []cli.HistoryEntry
+		var err error
+		set := setFlags(fs)
+		if set["since"] || set["until"] || set["limit"] {
+			now := time.Now()
+			var sinceT, untilT time.Time
+			sinceT, err = cli.ParseTimeBound(*since, now)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: --since: %v\n", err)
+				os.Exit(1)
+			}
+			untilT, err = cli.ParseTimeBound(*until, now)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: --until: %v\n", err)
+				os.Exit(1)
+			}
+			entries, err = client.QueryHistory(sinceT, untilT, *limit)
+		} else {
+			entries, err = client.History()
+		}
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "error: %v\n", err)
 			os.Exit(1)
@@ -283,6 +349,49 @@ func runCLI(cmd string, args []string) {
 	}
 }
 
+// newCLIClient returns an API client for the running service, resolving the
+// server address and state directory from flags, then config, then defaults.
+// Exits if the service is not running.
+func newCLIClient(fs *flag.FlagSet, configPath, stateDirFlag, serverAddr string) *cli.Client {
+	// Load config and apply values for flags not explicitly set
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	set := setFlags(fs)
+	if !set["state-dir"] && cfg.StateDir != "" {
+		stateDirFlag = cfg.StateDir
+	}
+	if !set["server"] && cfg.Listen != "" {
+		serverAddr = cfg.Listen
+	}
+
+	var stateDir string
+	if stateDirFlag != "" {
+		stateDir = stateDirFlag
+	} else {
+		stateDir, err = getStateDir()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	auth, err := api.LoadAuth(stateDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "error: %s is not running (no cookie file found)\n", progName)
+			fmt.Fprintf(os.Stderr, "Start the service first with: %s serve\n", progName)
+		} else {
+			fmt.Fprintf(os.Stderr, "error loading auth: %v\n", err)
+		}
+		os.Exit(1)
+	}
+
+	return cli.NewClient(serverAddr, auth.Token())
+}
+
 func runServe(args []string) {
 	fs := flag.NewFlagSet("serve", flag.ExitOnError)
 	configPath := fs.String("config", "", "Path to config file (default: $XDG_CONFIG_HOME/secrets-dispatcher/config.yaml)")
@@ -359,50 +468,61 @@ func runServe(args []string) {
 	}
 	slog.SetDefault(slog.New(handler))
 
-	// Create approval manager
-	var trustedSigners []approval.TrustedSigner
-	for _, ts := range cfg.Serve.TrustedSigners {
-		trustedSigners = append(trustedSigners, approval.TrustedSigner{
-			ExePath:    ts.ExePath,
-			RepoPath:   ts.RepoPath,
-			FilePrefix: ts.FilePrefix,
-		})
-	}
-	var trustRules []approval.TrustRule
-	for _, r := range cfg.Serve.Rules {
-		tr := approval.TrustRule{
-			Name:             r.Name,
-			Action:           r.Action,
-			RequestTypes:     r.RequestTypes,
-			SearchAttributes: r.SearchAttributes,
-		}
-		if r.Process != nil {
-			tr.Process = &approval.ProcessMatcher{
-				Exe:  r.Process.Exe,
-				Name: r.Process.Name,
-				Args: r.Process.Args,
-				CWD:  r.Process.CWD,
-				Unit: r.Process.Unit,
-			}
+	// Set up state directory for cookie and persisted history
+	var stateDir string
+	if *stateDirFlag != "" {
+		stateDir = *stateDirFlag
+	} else {
+		var sdErr error
+		stateDir, sdErr = getStateDir()
+		if sdErr != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", sdErr)
+			os.Exit(1)
 		}
-		if r.Secret != nil {
-			tr.Secret = &approval.SecretMatcher{
-				Collection: r.Secret.Collection,
-				Label:      r.Secret.Label,
-				Attributes: r.Secret.Attributes,
-			}
+	}
+
+	// Open the persistent history store; without it history is in-memory only.
+	var historyStore approval.HistoryStore
+	if *cfg.Serve.HistoryPersist && !*apiOnly {
+		hs, hsErr := history.Open(filepath.Join(stateDir, "history"), 0, 0)
+		if hsErr != nil {
+			slog.Warn("failed to open history store, history will not survive restarts", "error", hsErr)
+		} else {
+			defer hs.Close()
+			historyStore = hs
+			slog.Debug("persistent history enabled", "path", hs.Path())
 		}
-		trustRules = append(trustRules, tr)
 	}
+	// Trust rule hit statistics are kept next to the history, unconditionally:
+	// they hold counts only, never request contents.
+	var ruleStatsStore approval.RuleStatsStore
+	if !*apiOnly {
+		ruleStatsStore = history.NewRuleStatsFile(filepath.Join(stateDir, "rule-stats.json"))
+	}
+	// Approved deletions are kept, encrypted, so they can be undone. The key is
+	// kept in the first upstream's default collection, not with the entries.
+	var trashStore *trash.Store
+	if retention := time.Duration(*cfg.Serve.TrashRetention); retention > 0 && !*apiOnly {
+		keyAddr := proxyUpstreams(cfg.Serve.UpstreamList())[0].Addr
+		trashKey := trash.KeyringKey{Dial: func() (*dbus.Conn, error) { return connectUpstream(keyAddr) }}
+		trashStore = trash.NewStore(filepath.Join(stateDir, "trash"), retention, trashKey)
+	}
+
+	// Create approval manager
+	trustConfig := trustConfigFromConfig(cfg)
 	approvalMgr := approval.NewManager(approval.ManagerConfig{
 		Timeout:             *timeout,
 		HistoryMax:          *historyLimit,
 		ApprovalWindow:      time.Duration(cfg.Serve.ApprovalWindow),
 		AutoApproveDuration: time.Duration(cfg.Serve.AutoApproveDuration),
-		TrustedSigners:      trustedSigners,
+		TrustedSigners:      trustConfig.TrustedSigners,
 		IgnoreChromeDummy:   *cfg.Serve.IgnoreChromeDummySecret,
-		TrustRules:          trustRules,
+		TrustRules:          trustConfig.Rules,
+		ClientPolicies:      trustConfig.ClientPolicies,
+		HistoryStore:        historyStore,
+		RuleStatsStore:      ruleStatsStore,
 	})
+	defer approvalMgr.FlushRuleStats()
 
 	// Set up desktop notifications
 	var desktopNotifier *notification.DBusNotifier
@@ -419,24 +539,23 @@ func runServe(args []string) {
 		}
 	}
 	upstreamSlowThreshold := time.Duration(*cfg.Serve.UpstreamSlowThreshold)
+	// With the SSH agent proxy enabled, SSH-format commit signatures go
+	// straight to its upstream: the request was already approved as a
+	// gpg_sign and must not be prompted again as an ssh_sign.
+	var sshUpstream string
+	if cfg.SSH != nil {
+		sshUpstream = cfg.SSH.Upstream
+		if sshUpstream == "" {
+			sshUpstream = os.Getenv("SSH_AUTH_SOCK")
+		}
+	}
 	if desktopNotifier != nil {
-		notifHandler = notification.NewHandler(desktopNotifier, api.NewResolver(approvalMgr, slowUpstreamNotifier, upstreamSlowThreshold), "http://"+*listenAddr, *cfg.Serve.ShowPIDs, approvalMgr.AutoApproveDuration(), time.Duration(cfg.Serve.NotificationDelay))
+		resolver := api.NewResolver(approvalMgr, slowUpstreamNotifier, upstreamSlowThreshold)
+		resolver.SetSSHAgent(sshUpstream)
+		notifHandler = notification.NewHandler(desktopNotifier, resolver, "http://"+*listenAddr, *cfg.Serve.ShowPIDs, approvalMgr.AutoApproveDuration(), time.Duration(cfg.Serve.NotificationDelay))
 		approvalMgr.Subscribe(notifHandler)
 	}
 
-	// Set up state directory for cookie
-	var stateDir string
-	if *stateDirFlag != "" {
-		stateDir = *stateDirFlag
-	} else {
-		var sdErr error
-		stateDir, sdErr = getStateDir()
-		if sdErr != nil {
-			fmt.Fprintf(os.Stderr, "error: %v\n", sdErr)
-			os.Exit(1)
-		}
-	}
-
 	// Create auth with cookie file
 	auth, err := api.NewAuth(stateDir)
 	if err != nil {
@@ -453,19 +572,59 @@ func runServe(args []string) {
 		defer desktopNotifier.Stop()
 	}
 
-	// Handle signals for graceful shutdown
+	// Trust rules and trusted signers are reloaded from the config file on
+	// SIGHUP, when the file changes, and via POST /api/v1/config/reload.
+	reloadPath := *configPath
+	if reloadPath == "" {
+		reloadPath = config.DefaultPath()
+	}
+	reloadConfig := func() error {
+		return reloadTrustConfig(reloadPath, approvalMgr)
+	}
+	if reloadPath != "" {
+		go func() {
+			err := config.Watch(ctx, reloadPath, config.DefaultWatchDebounce, func() {
+				slog.Info("config file changed, reloading", "path", reloadPath)
+				reloadConfig() //nolint:errcheck // reported via the manager
+			})
+			if err != nil && err != context.Canceled {
+				slog.Warn("not watching config file for changes", "path", reloadPath, "error", err)
+			}
+		}()
+	}
+
+	// Handle signals: SIGHUP reloads the config, SIGINT/SIGTERM shut down
 	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 	go func() {
-		sig := <-sigCh
-		slog.Info("received signal, shutting down", "signal", sig)
-		cancel()
+		for sig := range sigCh {
+			if sig == syscall.SIGHUP {
+				if reloadPath == "" {
+					slog.Warn("received SIGHUP but config path is unknown, ignoring")
+					continue
+				}
+				slog.Info("received SIGHUP, reloading config", "path", reloadPath)
+				reloadConfig() //nolint:errcheck // reported via the manager
+				continue
+			}
+			slog.Info("received signal, shutting down", "signal", sig)
+			cancel()
+			return
+		}
 	}()
 
-	// Resolve upstream address
-	var upstreamAddr string
-	if cfg.Serve.Upstream.Type == "socket" {
-		upstreamAddr = "unix:path=" + cfg.Serve.Upstream.Path
+	// Resolve upstream addresses
+	upstreams := proxyUpstreams(cfg.Serve.UpstreamList())
+	upstreamNames := make([]string, len(upstreams))
+	for i, u := range upstreams {
+		upstreamNames[i] = u.Name
+	}
+	upstreamMonitor := proxy.NewUpstreamMonitor(upstreamNames)
+
+	// Remote sockets only get the Secret Service once their host is paired.
+	var clientStore *pairing.Store
+	if *cfg.Serve.RequirePairing {
+		clientStore = pairing.NewStore(clientsPath(reloadPath))
 	}
 
 	// Build topology from config: create providers and runners for each downstream
@@ -477,11 +636,19 @@ func runServe(args []string) {
 	for _, ds := range cfg.Serve.Downstream {
 		switch ds.Type {
 		case "sockets":
-			mgr, mgrErr := proxy.NewManager(ds.Path, upstreamAddr, approvalMgr, level, *cfg.Serve.TrimProcessChain, slowUpstreamNotifier, upstreamSlowThreshold)
+			mgr, mgrErr := proxy.NewManager(ds.Path, upstreams[0].Addr, approvalMgr, level, *cfg.Serve.TrimProcessChain, slowUpstreamNotifier, upstreamSlowThreshold)
 			if mgrErr != nil {
 				fmt.Fprintf(os.Stderr, "error creating proxy manager: %v\n", mgrErr)
 				os.Exit(1)
 			}
+			mgr.UseUpstreams(upstreams)
+			mgr.MonitorUpstreams(upstreamMonitor)
+			if clientStore != nil {
+				mgr.RequirePairing(clientStore)
+			}
+			if trashStore != nil {
+				mgr.KeepDeleted(trashStore)
+			}
 			providers = append(providers, mgr)
 			runners = append(runners, func(ctx context.Context) error {
 				return mgr.Run(ctx)
@@ -491,7 +658,7 @@ func runServe(args []string) {
 			sp := &staticProvider{info: proxy.ClientInfo{Name: "local", SocketPath: "session_bus"}}
 			providers = append(providers, sp)
 			runners = append(runners, func(ctx context.Context) error {
-				if upstreamAddr == "" {
+				if slices.ContainsFunc(upstreams, func(u proxy.Upstream) bool { return u.Addr == "" }) {
 					return fmt.Errorf("upstream and downstream are both session_bus (should be caught by validation)")
 				}
 				const maxBackoff = 30 * time.Second
@@ -504,15 +671,17 @@ func runServe(args []string) {
 						TrimProcessChain:      *cfg.Serve.TrimProcessChain,
 						UpstreamNotifier:      slowUpstreamNotifier,
 						UpstreamSlowThreshold: upstreamSlowThreshold,
+						Trash:                 trashStore,
+						Upstreams:             upstreamMonitor,
 					})
 					frontConn, err := dbus.ConnectSessionBus()
 					if err == nil {
-						var backendConn *dbus.Conn
-						backendConn, err = dbus.Connect(upstreamAddr)
+						var backends []proxy.Backend
+						backends, err = proxy.DialUpstreams(upstreams)
 						if err != nil {
 							frontConn.Close()
 						} else {
-							err = p.ConnectWith(frontConn, backendConn)
+							err = p.ConnectBackends(frontConn, backends)
 							if err == nil {
 								backoff = time.Second
 								err = p.Run(ctx)
@@ -535,7 +704,7 @@ func runServe(args []string) {
 			})
 
 		case "socket":
-			clientName := filepath.Base(ds.Path)
+			clientName := proxy.ClientNameFromSocket(ds.Path)
 			p := proxy.New(proxy.Config{
 				ClientName:            clientName,
 				LogLevel:              level,
@@ -543,6 +712,9 @@ func runServe(args []string) {
 				TrimProcessChain:      *cfg.Serve.TrimProcessChain,
 				UpstreamNotifier:      slowUpstreamNotifier,
 				UpstreamSlowThreshold: upstreamSlowThreshold,
+				Clients:               clientStore,
+				Trash:                 trashStore,
+				Upstreams:             upstreamMonitor,
 			})
 			sp := &staticProvider{info: proxy.ClientInfo{Name: clientName, SocketPath: ds.Path}}
 			providers = append(providers, sp)
@@ -552,17 +724,12 @@ func runServe(args []string) {
 				if connErr != nil {
 					return fmt.Errorf("connect to downstream socket %s: %w", ds.Path, connErr)
 				}
-				var backendConn *dbus.Conn
-				if upstreamAddr == "" {
-					backendConn, connErr = dbus.ConnectSessionBus()
-				} else {
-					backendConn, connErr = dbus.Connect(upstreamAddr)
-				}
+				backends, connErr := proxy.DialUpstreams(upstreams)
 				if connErr != nil {
 					frontConn.Close()
-					return fmt.Errorf("connect to upstream: %w", connErr)
+					return connErr
 				}
-				if connErr := p.ConnectWith(frontConn, backendConn); connErr != nil {
+				if connErr := p.ConnectBackends(frontConn, backends); connErr != nil {
 					return connErr
 				}
 				defer p.Close()
@@ -573,10 +740,6 @@ func runServe(args []string) {
 
 	// Set up SSH agent proxy if configured
 	if cfg.SSH != nil {
-		sshUpstream := cfg.SSH.Upstream
-		if sshUpstream == "" {
-			sshUpstream = os.Getenv("SSH_AUTH_SOCK")
-		}
 		if sshUpstream == "" {
 			slog.Error("SSH agent proxy enabled but no upstream socket (set ssh.upstream in config or SSH_AUTH_SOCK)")
 		} else {
@@ -599,6 +762,34 @@ func runServe(args []string) {
 		}
 	}
 
+	// Set up gpg-agent proxy if configured
+	if cfg.GPGAgent != nil {
+		gpgUpstream := cfg.GPGAgent.Upstream
+		if gpgUpstream == "" {
+			var err error
+			if gpgUpstream, err = gpgagent.DefaultUpstream(); err != nil {
+				slog.Error("gpg-agent proxy enabled but no upstream socket (set gpg_agent.upstream in config)", "error", err)
+			}
+		}
+		gpgListen := cfg.GPGAgent.Listen
+		if gpgListen == "" {
+			if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+				gpgListen = filepath.Join(runtimeDir, "secrets-dispatcher", "gpg-agent.sock")
+			}
+		}
+		switch {
+		case gpgUpstream == "":
+		case gpgListen == "":
+			slog.Error("gpg-agent proxy: cannot determine listen path (set gpg_agent.listen in config or XDG_RUNTIME_DIR)")
+		default:
+			gpgServer := gpgagent.NewServer(gpgListen, gpgUpstream, approvalMgr, *cfg.Serve.TrimProcessChain, slog.Default())
+			runners = append(runners, func(ctx context.Context) error {
+				return gpgServer.Run(ctx)
+			})
+			slog.Info("gpg-agent proxy configured", "listen", gpgListen, "upstream", gpgUpstream)
+		}
+	}
+
 	// Build composite ClientProvider
 	var provider api.ClientProvider
 	switch len(providers) {
@@ -629,6 +820,38 @@ func runServe(args []string) {
 		os.Exit(1)
 	}
 	apiServer.WSHandler().SetNotificationDelay(int(time.Duration(cfg.Serve.NotificationDelay).Milliseconds()))
+	apiServer.SetUpstreamMonitor(upstreamMonitor)
+	apiServer.SetSSHAgent(sshUpstream)
+	if reloadPath != "" {
+		apiServer.SetConfigReloader(reloadConfig)
+		apiServer.SetRuleAdder(func(rule approval.TrustRule) error {
+			if err := config.AppendRule(reloadPath, configRuleFromTrustRule(rule)); err != nil {
+				return err
+			}
+			slog.Info("trust rule added to config", "name", rule.Name, "path", reloadPath)
+			return reloadConfig()
+		})
+	}
+
+	if trashStore != nil {
+		go trashStore.RunExpiry(ctx)
+		apiServer.SetTrash(trashStore, func(e *trash.Entry) ([]dbus.ObjectPath, error) {
+			u := upstreams[0]
+			if e.Backend != "" {
+				i := slices.IndexFunc(upstreams, func(u proxy.Upstream) bool { return u.Name == e.Backend })
+				if i < 0 {
+					return nil, fmt.Errorf("upstream %q is no longer configured", e.Backend)
+				}
+				u = upstreams[i]
+			}
+			conn, err := connectUpstream(u.Addr)
+			if err != nil {
+				return nil, fmt.Errorf("connect to upstream: %w", err)
+			}
+			defer conn.Close()
+			return trash.Restore(conn, e)
+		})
+	}
 
 	// Enable test mode for API-only mode
 	if *apiOnly {
@@ -668,22 +891,336 @@ func runServe(args []string) {
 		}
 	}
 
-	// Run all downstreams
-	slog.Info("starting proxy topology",
-		"upstream", cfg.Serve.Upstream.Type,
-		"downstreams", len(cfg.Serve.Downstream))
+	// Run all downstreams
+	slog.Info("starting proxy topology",
+		"upstreams", len(upstreams),
+		"downstreams", len(cfg.Serve.Downstream))
+
+	var wg sync.WaitGroup
+	for i, run := range runners {
+		wg.Add(1)
+		go func(idx int, runFn downstreamRunner) {
+			defer wg.Done()
+			if err := runFn(ctx); err != nil && err != context.Canceled {
+				slog.Error("downstream error", "index", idx, "error", err)
+			}
+		}(i, run)
+	}
+	wg.Wait()
+}
+
+// trustConfigFromConfig converts the config-file trust rules, trusted signers
+// and client policies into their approval package equivalents.
+func trustConfigFromConfig(cfg *config.Config) approval.TrustConfig {
+	var trustedSigners []approval.TrustedSigner
+	for _, ts := range cfg.Serve.TrustedSigners {
+		trustedSigners = append(trustedSigners, approval.TrustedSigner{
+			ExePath:    ts.ExePath,
+			RepoPath:   ts.RepoPath,
+			FilePrefix: ts.FilePrefix,
+		})
+	}
+	var trustRules []approval.TrustRule
+	for _, r := range cfg.Serve.Rules {
+		tr := approval.TrustRule{
+			Name:             r.Name,
+			Action:           r.Action,
+			Client:           r.Client,
+			Backend:          r.Backend,
+			RequestTypes:     r.RequestTypes,
+			SearchAttributes: r.SearchAttributes,
+		}
+		if r.Process != nil {
+			tr.Process = &approval.ProcessMatcher{
+				Exe:         r.Process.Exe,
+				ExeSHA256:   r.Process.ExeSHA256,
+				ExePackaged: r.Process.ExePackaged,
+				Name:        r.Process.Name,
+				Args:        r.Process.Args,
+				CWD:         r.Process.CWD,
+				Unit:        r.Process.Unit,
+				AppID:       r.Process.AppID,
+			}
+			if c := r.Process.Container; c != nil {
+				tr.Process.Container = &approval.ContainerMatcher{Runtime: c.Runtime, ID: c.ID, Name: c.Name, Image: c.Image}
+			}
+		}
+		if r.Secret != nil {
+			tr.Secret = &approval.SecretMatcher{
+				Collection: r.Secret.Collection,
+				Label:      r.Secret.Label,
+				Attributes: r.Secret.Attributes,
+			}
+		}
+		if r.SSH != nil {
+			tr.SSH = &approval.SSHMatcher{
+				Fingerprint: r.SSH.Fingerprint,
+				Comment:     r.SSH.Comment,
+				Destination: r.SSH.Destination,
+			}
+		}
+		if r.Signing != nil {
+			tr.Signing = &approval.SigningMatcher{
+				Repo:    r.Signing.Repo,
+				Kind:    r.Signing.Kind,
+				KeyID:   r.Signing.KeyID,
+				Author:  r.Signing.Author,
+				Files:   r.Signing.Files,
+				TagName: r.Signing.TagName,
+			}
+		}
+		trustRules = append(trustRules, tr)
+	}
+	var clientPolicies []approval.ClientPolicy
+	for _, p := range cfg.Serve.ClientPolicies {
+		clientPolicies = append(clientPolicies, approval.ClientPolicy{Client: p.Client, Default: p.Default})
+	}
+	return approval.TrustConfig{Rules: trustRules, TrustedSigners: trustedSigners, ClientPolicies: clientPolicies}
+}
+
+// configRuleFromTrustRule is the inverse of trustConfigFromConfig for a single
+// rule, used when a rule built in the UI or CLI is written to config.yaml.
+func configRuleFromTrustRule(r approval.TrustRule) config.TrustRule {
+	cr := config.TrustRule{
+		Name:             r.Name,
+		Action:           r.Action,
+		Client:           r.Client,
+		Backend:          r.Backend,
+		RequestTypes:     r.RequestTypes,
+		SearchAttributes: r.SearchAttributes,
+	}
+	if r.Process != nil {
+		cr.Process = &config.ProcessMatcher{
+			Exe:         r.Process.Exe,
+			ExeSHA256:   r.Process.ExeSHA256,
+			ExePackaged: r.Process.ExePackaged,
+			Name:        r.Process.Name,
+			Args:        r.Process.Args,
+			CWD:         r.Process.CWD,
+			Unit:        r.Process.Unit,
+			AppID:       r.Process.AppID,
+		}
+		if c := r.Process.Container; c != nil {
+			cr.Process.Container = &config.ContainerMatcher{Runtime: c.Runtime, ID: c.ID, Name: c.Name, Image: c.Image}
+		}
+	}
+	if r.Secret != nil {
+		cr.Secret = &config.SecretMatcher{
+			Collection: r.Secret.Collection,
+			Label:      r.Secret.Label,
+			Attributes: r.Secret.Attributes,
+		}
+	}
+	if r.SSH != nil {
+		cr.SSH = &config.SSHMatcher{
+			Fingerprint: r.SSH.Fingerprint,
+			Comment:     r.SSH.Comment,
+			Destination: r.SSH.Destination,
+		}
+	}
+	if r.Signing != nil {
+		cr.Signing = &config.SigningMatcher{
+			Repo:    r.Signing.Repo,
+			Kind:    r.Signing.Kind,
+			KeyID:   r.Signing.KeyID,
+			Author:  r.Signing.Author,
+			Files:   r.Signing.Files,
+			TagName: r.Signing.TagName,
+		}
+	}
+	return cr
+}
+
+// reloadTrustConfig re-reads the config file and swaps its trust rules,
+// trusted signers and client policies into mgr. A file that is missing, fails to parse or fails
+// validation is rejected: the previous rules stay in effect and the error is
+// recorded on mgr so the UI can show it. Other settings require a restart.
+func reloadTrustConfig(path string, mgr *approval.Manager) error {
+	err := func() error {
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("config file not found: %s", path)
+		}
+		cfg, err := config.Load(path)
+		if err != nil {
+			return fmt.Errorf("load config %s: %w", path, err)
+		}
+		cfg = cfg.WithDefaults()
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("config validation: %w", err)
+		}
+		mgr.SetTrustConfig(trustConfigFromConfig(cfg))
+		return nil
+	}()
+	if err != nil {
+		mgr.SetConfigError(err)
+	}
+	return err
+}
+
+// clientsPath returns the paired-clients file that lives next to config.yaml.
+func clientsPath(configPath string) string {
+	if configPath == "" {
+		configPath = config.DefaultPath()
+	}
+	return filepath.Join(filepath.Dir(configPath), "clients.yaml")
+}
+
+// proxyUpstreams resolves the configured upstreams to D-Bus addresses.
+func proxyUpstreams(list []config.UpstreamConfig) []proxy.Upstream {
+	upstreams := make([]proxy.Upstream, len(list))
+	for i, u := range list {
+		upstreams[i] = proxy.Upstream{Name: u.Name, Prefix: u.Prefix, Aliases: u.Aliases}
+		if u.Type == "socket" {
+			upstreams[i].Addr = "unix:path=" + u.Path
+		}
+	}
+	return upstreams
+}
+
+// connectUpstream opens a private connection to the upstream Secret Service:
+// the bus at addr, or the session bus if addr is empty.
+func connectUpstream(addr string) (*dbus.Conn, error) {
+	if addr == "" {
+		return dbus.ConnectSessionBus()
+	}
+	return dbus.Connect(addr)
+}
+
+// runPair is the remote half of client pairing: run on the server whose
+// session bus the dispatcher serves, it shows a code to compare with the one
+// in the dispatcher's UI and, once approved there, installs the D-Bus
+// activation file through which the dispatcher re-checks the host on every
+// connection. With --respond it is that activated responder.
+func runPair(args []string) {
+	fs := flag.NewFlagSet("pair", flag.ExitOnError)
+	keyPath := fs.String("key", pairing.DefaultIdentityPath(), "Identity key file (created if missing)")
+	respond := fs.Bool("respond", false, "Answer the dispatcher's identity challenge (started by D-Bus activation)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: %s pair [options]
+
+Run on the remote host, with the dispatcher connected to this host's session bus
+(e.g. over an SSH tunnel). Prints a code; check that your desktop shows the same
+code for this client and approve it there.
+
+Options:
+`, progName)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: connect to session bus: %v\n", err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+
+	if *respond {
+		key, err := pairing.LoadIdentity(*keyPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := pairing.Respond(ctx, conn, key, 30*time.Second); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	key, err := pairing.LoadOrCreateIdentity(*keyPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Identity: %s (%s)\n", pairing.Fingerprint(key.Public().(ed25519.PublicKey)), *keyPath)
+
+	err = pairing.Pair(ctx, conn, key, func(code string) {
+		fmt.Printf("\nPairing code: %s\n\nApprove the pairing on your desktop only if it shows the same code.\nWaiting for approval...\n", code)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	activation, err := pairing.InstallActivation(exe)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: install D-Bus activation file: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Paired. Installed %s so the dispatcher can verify this host on reconnect.\n", activation)
+}
+
+// runClients lists or removes paired clients in clients.yaml. The service
+// re-reads the file on every connection, so changes apply without a reload.
+func runClients(args []string) {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+	cmd := args[0]
+	fs := flag.NewFlagSet("clients "+cmd, flag.ExitOnError)
+	configPath := fs.String("config", "", "Path to config file; clients.yaml lives next to it (default: $XDG_CONFIG_HOME/secrets-dispatcher/config.yaml)")
+	jsonOutput := fs.Bool("json", false, "Output as JSON")
+	fs.Usage = printClientsUsage
+	fs.Parse(args[1:])
+
+	store := pairing.NewStore(clientsPath(*configPath))
+	switch cmd {
+	case "list":
+		clients, err := store.List()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		if *jsonOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(clients)
+			return
+		}
+		if len(clients) == 0 {
+			fmt.Printf("No paired clients (%s).\n", store.Path())
+			return
+		}
+		for _, c := range clients {
+			fmt.Printf("%-20s %s  paired %s\n", c.Name, c.Fingerprint(), c.PairedAt.Local().Format(time.DateTime))
+		}
+	case "remove":
+		if fs.NArg() != 1 {
+			printClientsUsage()
+			os.Exit(1)
+		}
+		if err := store.Remove(fs.Arg(0)); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s: %v\n", fs.Arg(0), err)
+			os.Exit(1)
+		}
+		fmt.Printf("Removed %s. Its socket loses the Secret Service on the next reconnect.\n", fs.Arg(0))
+	case "-h", "--help", "help":
+		printClientsUsage()
+	default:
+		fmt.Fprintf(os.Stderr, "unknown clients command: %s\n\n", cmd)
+		printClientsUsage()
+		os.Exit(1)
+	}
+}
+
+func printClientsUsage() {
+	fmt.Fprintf(os.Stderr, `Usage: %s clients <command> [options]
 
-	var wg sync.WaitGroup
-	for i, run := range runners {
-		wg.Add(1)
-		go func(idx int, runFn downstreamRunner) {
-			defer wg.Done()
-			if err := runFn(ctx); err != nil && err != context.Canceled {
-				slog.Error("downstream error", "index", idx, "error", err)
-			}
-		}(i, run)
-	}
-	wg.Wait()
+Commands:
+  list          List paired remote clients (default)
+  remove NAME   Forget a paired client; it must pair again to get secrets
+
+Remote hosts pair by running '%s pair' on the host itself.
+`, progName, progName)
 }
 
 // staticProvider wraps a single client info for the API ClientProvider interface.
@@ -832,23 +1369,34 @@ func runConfigEdit(args []string) {
 		fmt.Fprintln(os.Stderr, "the file was saved but may not load correctly")
 		return
 	}
+	for _, w := range cfg.Serve.Warnings() {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+	}
 
 	fmt.Fprintln(os.Stderr, "config updated")
 
-	// Prompt for service restart
-	fmt.Fprint(os.Stderr, "restart secrets-dispatcher service? [y/N] ")
-	var answer string
-	fmt.Scanln(&answer)
-	if answer == "y" || answer == "Y" {
-		restart := exec.Command("systemctl", "--user", "restart", "secrets-dispatcher.service")
-		restart.Stdout = os.Stdout
-		restart.Stderr = os.Stderr
-		if err := restart.Run(); err != nil {
-			fmt.Fprintf(os.Stderr, "error restarting service: %v\n", err)
-			os.Exit(1)
+	// The service also picks up the change by watching the file; reloading
+	// explicitly confirms the new rules were accepted.
+	stateDir := cfg.StateDir
+	if stateDir == "" {
+		if stateDir, err = getStateDir(); err != nil {
+			return
 		}
-		fmt.Fprintln(os.Stderr, "service restarted")
 	}
+	serverAddr := defaultListenAddr
+	if cfg.Listen != "" {
+		serverAddr = cfg.Listen
+	}
+	auth, err := api.LoadAuth(stateDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "service not running; changes apply on next start")
+		return
+	}
+	if err := cli.NewClient(serverAddr, auth.Token()).ReloadConfig(); err != nil {
+		fmt.Fprintf(os.Stderr, "could not reload running service: %v\n", err)
+		return
+	}
+	fmt.Fprintln(os.Stderr, "service reloaded trust rules")
 }
 
 func runConfigValidate(args []string) {
@@ -866,6 +1414,9 @@ func runConfigValidate(args []string) {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		os.Exit(1)
 	}
+	for _, w := range cfg.Serve.Warnings() {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+	}
 	fmt.Fprintln(os.Stderr, "config ok")
 }
 
@@ -874,7 +1425,7 @@ func printConfigUsage() {
 
 Commands:
   show          Show the current configuration
-  edit          Edit config in $EDITOR and optionally restart service
+  edit          Edit config in $EDITOR and reload the running service
   validate      Validate config syntax and values
 
 Options:
@@ -885,6 +1436,519 @@ Show-only options:
 `, progName)
 }
 
+// runRule handles the "rule" subcommand group.
+func runRule(args []string) {
+	if len(args) == 0 {
+		printRuleUsage()
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "add":
+		runRuleAdd(args[1:])
+	case "test":
+		runRuleTest(args[1:])
+	case "stats":
+		runRuleStats(args[1:])
+	case "-h", "--help", "help":
+		printRuleUsage()
+	default:
+		fmt.Fprintf(os.Stderr, "unknown rule command: %s\n\n", args[0])
+		printRuleUsage()
+		os.Exit(1)
+	}
+}
+
+// runRuleStats lists how often each trust rule matched, flagging rules that
+// look stale or broader than intended.
+func runRuleStats(args []string) {
+	fs := flag.NewFlagSet("rule stats", flag.ExitOnError)
+	configPath := fs.String("config", "", "Path to config file (default: $XDG_CONFIG_HOME/secrets-dispatcher/config.yaml)")
+	stateDirFlag := fs.String("state-dir", "", "State directory (default: $XDG_STATE_HOME/secrets-dispatcher)")
+	serverAddr := fs.String("server", defaultListenAddr, "API server address")
+	jsonOutput := fs.Bool("json", false, "Output as JSON")
+	unusedDays := fs.Int("unused-days", 30, "Flag rules that have not matched for this many days (0: never)")
+	maxExes := fs.Int("max-exes", 5, "Flag rules matched by more than this many distinct executables (0: never)")
+	flagged := fs.Bool("flagged", false, "Only list flagged rules")
+	fs.Parse(args)
+
+	if *unusedDays < 0 || *maxExes < 0 {
+		fmt.Fprintln(os.Stderr, "error: --unused-days and --max-exes must not be negative")
+		os.Exit(1)
+	}
+
+	client := newCLIClient(fs, *configPath, *stateDirFlag, *serverAddr)
+	formatter := cli.NewFormatter(os.Stdout, *jsonOutput)
+
+	stats, err := client.RuleStats(*unusedDays, *maxExes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	if *flagged {
+		stats = slices.DeleteFunc(stats, func(s cli.RuleStat) bool { return !s.Unused && !s.Broad })
+	}
+	if err := formatter.FormatRuleStats(stats); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runRuleAdd derives a trust rule from a pending or past request, applies the
+// narrowing flags, and saves it to the running service's config.yaml.
+func runRuleAdd(args []string) {
+	fs := flag.NewFlagSet("rule add", flag.ExitOnError)
+	configPath := fs.String("config", "", "Path to config file (default: $XDG_CONFIG_HOME/secrets-dispatcher/config.yaml)")
+	stateDirFlag := fs.String("state-dir", "", "State directory (default: $XDG_STATE_HOME/secrets-dispatcher)")
+	serverAddr := fs.String("server", defaultListenAddr, "API server address")
+	jsonOutput := fs.Bool("json", false, "Output as JSON")
+	from := fs.String("from", "", "ID (or unique prefix) of the pending or resolved request to derive the rule from")
+	name := fs.String("name", "", "Rule name (default: derived from the executable and collection)")
+	action := fs.String("action", "", "Rule action: approve or deny (default: approve)")
+	exe := fs.String("exe", "", "Match this executable glob instead of the derived one")
+	clientGlob := fs.String("client", "", "Match this client glob instead of the derived one (empty: any client)")
+	collection := fs.String("collection", "", "Match this collection glob instead of the derived one (empty: any collection)")
+	attrs := fs.String("attrs", "", "Comma-separated attribute keys to keep from the derived rule (empty: drop all)")
+	destination := fs.String("destination", "", "For SSH signing: match this destination host glob instead of the derived one (empty: any host)")
+	anySecret := fs.Bool("any-secret", false, "Drop the secret or SSH key matcher: match any secret or key")
+	anyType := fs.Bool("any-type", false, "Drop request_types: match every request type")
+	dryRun := fs.Bool("dry-run", false, "Print the rule without saving it")
+	yes := fs.Bool("yes", false, "Save without asking for confirmation")
+	fs.Parse(args)
+
+	if *from == "" {
+		fmt.Fprintf(os.Stderr, "usage: %s rule add --from <request-id> [options]\n", progName)
+		os.Exit(1)
+	}
+
+	client := newCLIClient(fs, *configPath, *stateDirFlag, *serverAddr)
+	formatter := cli.NewFormatter(os.Stdout, *jsonOutput)
+
+	rule, err := client.SuggestRule(*from)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+
+	set := setFlags(fs)
+	if *name != "" {
+		rule.Name = *name
+	}
+	if *action != "" {
+		rule.Action = *action
+	}
+	if *exe != "" {
+		if rule.Process == nil {
+			rule.Process = &cli.ProcessMatcher{}
+		}
+		rule.Process.Exe = *exe
+	}
+	if set["client"] {
+		rule.Client = *clientGlob
+	}
+	if *anyType {
+		rule.RequestTypes = nil
+	}
+	if *anySecret {
+		rule.Secret = nil
+		rule.SearchAttributes = nil
+		if rule.SSH != nil {
+			rule.SSH.Fingerprint = ""
+		}
+	}
+	if set["destination"] {
+		if rule.SSH == nil {
+			rule.SSH = &cli.SSHMatcher{}
+		}
+		rule.SSH.Destination = *destination
+	}
+	if rule.Secret != nil && set["collection"] {
+		rule.Secret.Collection = *collection
+	}
+	if set["attrs"] {
+		keep := map[string]bool{}
+		for _, k := range strings.Split(*attrs, ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				keep[k] = true
+			}
+		}
+		if rule.Secret != nil {
+			maps.DeleteFunc(rule.Secret.Attributes, func(k, _ string) bool { return !keep[k] })
+		}
+		maps.DeleteFunc(rule.SearchAttributes, func(k, _ string) bool { return !keep[k] })
+	}
+	if rule.Secret != nil && rule.Secret.Collection == "" && rule.Secret.Label == "" && len(rule.Secret.Attributes) == 0 {
+		rule.Secret = nil
+	}
+	if rule.SSH != nil && *rule.SSH == (cli.SSHMatcher{}) {
+		rule.SSH = nil
+	}
+
+	formatter.FormatRule(*rule)
+	if *dryRun {
+		return
+	}
+	if !*yes {
+		fmt.Fprint(os.Stderr, "add this rule to config.yaml? [y/N] ")
+		var answer string
+		fmt.Scanln(&answer)
+		if answer != "y" && answer != "Y" {
+			fmt.Fprintln(os.Stderr, "not saved")
+			return
+		}
+	}
+	if err := client.AddRule(*rule); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintln(os.Stderr, "rule saved and loaded")
+}
+
+// runRuleTest evaluates requests against the current config and a candidate
+// one, without a running service: either the persisted history or a single
+// synthetic request described by flags.
+func runRuleTest(args []string) {
+	fs := flag.NewFlagSet("rule test", flag.ExitOnError)
+	configPath := fs.String("config", "", "Path to the current config file (default: $XDG_CONFIG_HOME/secrets-dispatcher/config.yaml)")
+	stateDirFlag := fs.String("state-dir", "", "State directory holding the history (default: $XDG_STATE_HOME/secrets-dispatcher)")
+	jsonOutput := fs.Bool("json", false, "Output as JSON")
+	since := fs.String("since", "", "Only replay history resolved at or after this time (duration ago, e.g. 24h, or RFC 3339)")
+	limit := fs.Int("limit", 500, "Maximum number of history entries to replay (0 = no limit)")
+	changedOnly := fs.Bool("changed", false, "Only show requests whose decision changes")
+	reqType := fs.String("type", "", "Test a synthetic request of this type instead of replaying history (get_secret, search, ssh_sign, gpg_sign, ...)")
+	clientName := fs.String("client", "local", "Synthetic request: downstream client name")
+	exe := fs.String("exe", "", "Synthetic request: executable of the requesting process")
+	collection := fs.String("collection", "default", "Synthetic request: collection of the secret")
+	label := fs.String("label", "", "Synthetic request: label of the secret")
+	attrs := fs.String("attrs", "", "Synthetic request: comma-separated key=value attributes (the search criteria for search; fingerprint=, comment=, destination= for SSH)")
+	repo := fs.String("repo", "", "Synthetic gpg_sign request: repository name")
+	files := fs.String("files", "", "Synthetic gpg_sign request: comma-separated changed files")
+	expect := fs.String("expect", "", "Synthetic request: exit 1 unless the candidate decides this (prompt, approve, deny or ignore)")
+	fs.Parse(args)
+
+	switch approval.Decision(*expect) {
+	case "", approval.DecisionPrompt, approval.DecisionApprove, approval.DecisionDeny, approval.DecisionIgnore:
+	default:
+		fmt.Fprintf(os.Stderr, "error: --expect must be prompt, approve, deny or ignore, got %q\n", *expect)
+		os.Exit(1)
+	}
+
+	current, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	candidate := current
+	if fs.NArg() > 0 {
+		if candidate, err = loadConfig(fs.Arg(0)); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	currentMgr, err := policyManager(current)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: current config: %v\n", err)
+		os.Exit(1)
+	}
+	candidateMgr, err := policyManager(candidate)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: candidate config: %v\n", err)
+		os.Exit(1)
+	}
+
+	var entries []approval.HistoryEntry
+	if *reqType != "" {
+		req := syntheticRequest(approval.RequestType(*reqType), *clientName, *exe, *collection, *label,
+			parseKeyValues(*attrs), *repo, splitList(*files))
+		entries = []approval.HistoryEntry{{Request: req}}
+	} else {
+		if *expect != "" {
+			fmt.Fprintln(os.Stderr, "error: --expect needs a synthetic request (--type)")
+			os.Exit(1)
+		}
+		stateDir := *stateDirFlag
+		if stateDir == "" {
+			stateDir = current.StateDir
+		}
+		if stateDir == "" {
+			if stateDir, err = getStateDir(); err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		sinceT, err := cli.ParseTimeBound(*since, time.Now())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: --since: %v\n", err)
+			os.Exit(1)
+		}
+		entries, err = history.Read(filepath.Join(stateDir, "history"), approval.HistoryQuery{Since: sinceT, Limit: *limit})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	var results []cli.RuleTestResult
+	var missed *approval.Evaluation
+	for _, entry := range entries {
+		before := currentMgr.Evaluate(entry.Request)
+		after := candidateMgr.Evaluate(entry.Request)
+		r := cli.RuleTestResult{
+			Request:       cliRequest(entry.Request),
+			Recorded:      string(entry.Resolution),
+			Current:       string(before.Decision),
+			CurrentRule:   before.Rule,
+			Candidate:     string(after.Decision),
+			CandidateRule: after.Rule,
+		}
+		if *expect != "" && string(after.Decision) != *expect {
+			missed = &after
+		}
+		if *changedOnly && !r.Changed() {
+			continue
+		}
+		results = append(results, r)
+	}
+	cli.NewFormatter(os.Stdout, *jsonOutput).FormatRuleTest(results)
+
+	if missed != nil {
+		fmt.Fprintf(os.Stderr, "expected %s, got %s", *expect, missed.Decision)
+		if missed.Rule != "" {
+			fmt.Fprintf(os.Stderr, " (%s)", missed.Rule)
+		}
+		fmt.Fprintln(os.Stderr)
+		os.Exit(1)
+	}
+}
+
+// policyManager validates the policy in cfg and returns a manager holding only
+// that policy, for evaluating requests without serving them.
+func policyManager(cfg *config.Config) (*approval.Manager, error) {
+	cfg = cfg.WithDefaults()
+	if err := cfg.Serve.ValidatePolicy(); err != nil {
+		return nil, err
+	}
+	tc := trustConfigFromConfig(cfg)
+	return approval.NewManager(approval.ManagerConfig{
+		TrustedSigners:    tc.TrustedSigners,
+		IgnoreChromeDummy: *cfg.Serve.IgnoreChromeDummySecret,
+		TrustRules:        tc.Rules,
+		ClientPolicies:    tc.ClientPolicies,
+	}), nil
+}
+
+// syntheticRequest builds the request `rule test --type` evaluates. The
+// process is a single chain entry for exe; secret requests carry one item in
+// collection, and gpg_sign requests are treated as coming from our own
+// gpg-sign helper.
+func syntheticRequest(reqType approval.RequestType, client, exe, collection, label string, attrs map[string]string, repo string, files []string) *approval.Request {
+	req := &approval.Request{Client: client, Type: reqType}
+	if exe != "" {
+		req.SenderInfo.ProcessChain = []approval.ProcessInfo{{Name: filepath.Base(exe), Exe: exe}}
+	}
+	switch reqType {
+	case approval.RequestTypeSearch:
+		req.SearchAttributes = attrs
+		req.Items = []approval.ItemInfo{{Label: formatKeyValues(attrs)}}
+	case approval.RequestTypeGPGSign:
+		req.SenderInfo.PeerTrusted = true
+		req.GPGSignInfo = &approval.GPGSignInfo{Kind: "commit", RepoName: repo, ChangedFiles: files}
+	default:
+		req.Items = []approval.ItemInfo{{
+			Path:       "/org/freedesktop/secrets/collection/" + collection + "/1",
+			Label:      label,
+			Attributes: attrs,
+		}}
+	}
+	return req
+}
+
+// cliRequest converts a request to the CLI's mirror of it; the two share
+// their JSON encoding.
+func cliRequest(req *approval.Request) cli.PendingRequest {
+	var out cli.PendingRequest
+	if data, err := json.Marshal(req); err == nil {
+		json.Unmarshal(data, &out) //nolint:errcheck // same schema
+	}
+	return out
+}
+
+func parseKeyValues(s string) map[string]string {
+	m := map[string]string{}
+	for _, kv := range splitList(s) {
+		k, v, _ := strings.Cut(kv, "=")
+		m[k] = v
+	}
+	return m
+}
+
+func formatKeyValues(m map[string]string) string {
+	keys := slices.Sorted(maps.Keys(m))
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + "=" + m[k]
+	}
+	return strings.Join(parts, ", ")
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func printRuleUsage() {
+	fmt.Fprintf(os.Stderr, `Usage: %s rule <command> [options]
+
+Commands:
+  add           Derive a trust rule from a request and save it to config.yaml
+  test          Show what a candidate config would decide (see below)
+  stats         Show how often each rule matched and flag stale or broad rules
+
+The rule matches the requesting application's executable and the collection and
+attributes of the secrets it asked for (and the client, for requests from a
+remote socket); for SSH signing, the key fingerprint and destination host.
+Narrow or widen it with --name, --action, --exe, --client, --collection,
+--attrs, --destination, --any-secret and --any-type; --dry-run prints it
+without saving. The running service writes it under serve.rules (keeping the
+file's comments) and loads it immediately.
+
+rule test [options] [candidate.yaml] evaluates requests against the current
+config (--config) and the candidate, and marks those whose decision changes.
+It replays the persisted history (--since, --limit, --changed), or with --type
+a single synthetic request built from --exe, --client, --collection, --label,
+--attrs, --repo and --files; --expect makes it exit 1 unless the candidate
+decides that request as given. No running service is needed.
+
+rule stats lists each named rule's hit count, last hit and number of distinct
+executables it matched, counted by the running service since the rule was
+added. A rule is flagged "unused" when it has not matched for --unused-days
+and "broad" when more than --max-exes executables matched it; --flagged lists
+only those.
+
+Examples:
+  %s rule add --from b260def --attrs service
+  %s rule test --since 24h --changed ~/new-config.yaml
+  %s rule test --type get_secret --exe /usr/bin/git --attrs service=github --expect approve
+  %s rule stats --flagged --unused-days 90
+`, progName, progName, progName, progName, progName)
+}
+
+func runTrash(args []string) {
+	if len(args) == 0 {
+		printTrashUsage()
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "list":
+		runTrashList(args[1:])
+	case "restore":
+		runTrashRestore(args[1:])
+	case "purge":
+		runTrashPurge(args[1:])
+	case "-h", "--help", "help":
+		printTrashUsage()
+	default:
+		fmt.Fprintf(os.Stderr, "unknown trash command: %s\n\n", args[0])
+		printTrashUsage()
+		os.Exit(1)
+	}
+}
+
+func runTrashList(args []string) {
+	fs := flag.NewFlagSet("trash list", flag.ExitOnError)
+	configPath := fs.String("config", "", "Path to config file (default: $XDG_CONFIG_HOME/secrets-dispatcher/config.yaml)")
+	stateDirFlag := fs.String("state-dir", "", "State directory (default: $XDG_STATE_HOME/secrets-dispatcher)")
+	serverAddr := fs.String("server", defaultListenAddr, "API server address")
+	jsonOutput := fs.Bool("json", false, "Output as JSON")
+	fs.Parse(args)
+
+	client := newCLIClient(fs, *configPath, *stateDirFlag, *serverAddr)
+	formatter := cli.NewFormatter(os.Stdout, *jsonOutput)
+
+	entries, err := client.Trash()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := formatter.FormatTrash(entries); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runTrashRestore(args []string) {
+	fs := flag.NewFlagSet("trash restore", flag.ExitOnError)
+	configPath := fs.String("config", "", "Path to config file (default: $XDG_CONFIG_HOME/secrets-dispatcher/config.yaml)")
+	stateDirFlag := fs.String("state-dir", "", "State directory (default: $XDG_STATE_HOME/secrets-dispatcher)")
+	serverAddr := fs.String("server", defaultListenAddr, "API server address")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "usage: %s trash restore <id>\n", progName)
+		os.Exit(1)
+	}
+
+	client := newCLIClient(fs, *configPath, *stateDirFlag, *serverAddr)
+	paths, err := client.RestoreTrash(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Restored %d items\n", len(paths))
+	for _, p := range paths {
+		fmt.Printf("  %s\n", p)
+	}
+}
+
+func runTrashPurge(args []string) {
+	fs := flag.NewFlagSet("trash purge", flag.ExitOnError)
+	configPath := fs.String("config", "", "Path to config file (default: $XDG_CONFIG_HOME/secrets-dispatcher/config.yaml)")
+	stateDirFlag := fs.String("state-dir", "", "State directory (default: $XDG_STATE_HOME/secrets-dispatcher)")
+	serverAddr := fs.String("server", defaultListenAddr, "API server address")
+	all := fs.Bool("all", false, "Purge every entry")
+	fs.Parse(args)
+
+	if *all == (fs.NArg() == 1) || fs.NArg() > 1 {
+		fmt.Fprintf(os.Stderr, "usage: %s trash purge <id> | --all\n", progName)
+		os.Exit(1)
+	}
+
+	client := newCLIClient(fs, *configPath, *stateDirFlag, *serverAddr)
+	n, err := client.PurgeTrash(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Purged %d trash entries\n", n)
+}
+
+func printTrashUsage() {
+	fmt.Fprintf(os.Stderr, `Usage: %s trash <command> [options]
+
+Commands:
+  list          List deletions that can still be undone
+  restore <id>  Recreate the deleted item(s) of an entry in the Secret Service
+  purge <id>    Permanently forget an entry (--all: every entry)
+
+When serve.trash_retention is set (default 168h), every approved Delete of an
+item or collection first saves its labels, attributes and secrets, encrypted,
+under the state directory. restore puts the items back into their collection
+(recreating a deleted collection) without overwriting anything created since;
+entries expire after the retention period. IDs may be abbreviated.
+
+Examples:
+  %s trash list
+  %s trash restore 3f2a9c1
+  %s trash purge --all
+`, progName, progName, progName, progName)
+}
+
 // runService handles the "service" subcommand group (install/uninstall/status).
 // runTry handles the `try` command: a reversible trial of the takeover
 // (US-9). Ctrl-C (or SIGTERM) restores the original Secret Service.
@@ -996,7 +2060,7 @@ Install options:
 }
 
 // runGPGSign handles the gpg-sign subcommand.
-// When called as "gpg-sign setup [--local]", it configures git to use this binary.
+// When called as "gpg-sign setup [--local] [--format ssh]", it configures git to use this binary.
 // Otherwise, it acts as a gpg proxy: reads commit object from stdin, sends to daemon,
 // and blocks until the signing request is resolved.
 func runGPGSign(args []string) {
@@ -1007,10 +2071,17 @@ func runGPGSign(args []string) {
 	os.Exit(gpgsign.Run(args, os.Stdin))
 }
 
+// runSSHSign handles the ssh-sign subcommand, the gpg.ssh.program counterpart
+// of gpg-sign for gpg.format=ssh (configured by "gpg-sign setup --format ssh").
+func runSSHSign(args []string) {
+	os.Exit(gpgsign.RunSSH(args, os.Stdin))
+}
+
 // runGPGSignSetup handles the "gpg-sign setup" subcommand.
 func runGPGSignSetup(args []string) {
 	fs := flag.NewFlagSet("gpg-sign setup", flag.ExitOnError)
 	local := fs.Bool("local", false, "Configure per-repo (--local) instead of --global")
+	format := fs.String("format", "openpgp", "Signing format git uses (gpg.format): openpgp or ssh")
 	fs.Parse(args) //nolint:errcheck
 
 	scope := "global"
@@ -1018,7 +2089,7 @@ func runGPGSignSetup(args []string) {
 		scope = "local"
 	}
 
-	if err := gpgsign.SetupGitConfig(scope); err != nil {
+	if err := gpgsign.SetupGitConfig(scope, *format); err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		os.Exit(1)
 	}
@@ -1157,6 +2228,20 @@ func loadConfig(explicitPath string) (*config.Config, error) {
 	return cfg, nil
 }
 
+// parseInterspersed parses args allowing flags after positional arguments
+// (`approve <id> --items 1,3`), and returns the positional arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		if fs.NArg() == 0 {
+			return positional
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
 // setFlags returns the set of flag names that were explicitly provided on the command line.
 func setFlags(fs *flag.FlagSet) map[string]bool {
 	m := make(map[string]bool)
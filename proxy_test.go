@@ -1,11 +1,16 @@
 package main
 
 import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -14,8 +19,10 @@ import (
 	"github.com/nikicat/secrets-dispatcher/internal/approval"
 	dbustypes "github.com/nikicat/secrets-dispatcher/internal/dbus"
 	"github.com/nikicat/secrets-dispatcher/internal/dhcrypto"
+	"github.com/nikicat/secrets-dispatcher/internal/pairing"
 	"github.com/nikicat/secrets-dispatcher/internal/proxy"
 	"github.com/nikicat/secrets-dispatcher/internal/testutil"
+	"github.com/nikicat/secrets-dispatcher/internal/trash"
 )
 
 // testEnv holds the test environment with two isolated D-Bus daemons.
@@ -775,6 +782,111 @@ func TestProxyItemDeleteRequiresApproval(t *testing.T) {
 	}
 }
 
+// TestProxyItemDeleteKeptInTrash tests that an approved Item.Delete is saved
+// to the trash first and can be restored from there.
+func TestProxyItemDeleteKeptInTrash(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.cleanup()
+
+	localConn := env.localConn()
+	defer localConn.Close()
+
+	mock := testutil.NewMockSecretService()
+	if err := mock.Register(localConn); err != nil {
+		t.Fatalf("register mock service: %v", err)
+	}
+
+	itemPath := mock.AddItem("Undo Me", map[string]string{"app": "test"}, []byte("precious"))
+
+	approvalMgr := approval.NewManager(approval.ManagerConfig{Timeout: 30 * time.Second, HistoryMax: 100})
+	trashKey := trash.KeyringKey{Dial: func() (*dbus.Conn, error) { return dbus.Connect(env.localAddr) }}
+	store := trash.NewStore(filepath.Join(env.tmpDir, "trash"), time.Hour, trashKey)
+
+	p := proxy.New(proxy.Config{
+		ClientName: "test-client",
+		LogLevel:   slog.LevelDebug,
+		Approval:   approvalMgr,
+		Trash:      store,
+	})
+
+	if err := connectProxyWithConns(p, env.localAddr, env.remoteSocketPath()); err != nil {
+		t.Fatalf("connect proxy: %v", err)
+	}
+	defer p.Close()
+
+	remoteConn := env.remoteConn()
+	defer remoteConn.Close()
+
+	deleteErr := make(chan error, 1)
+	go func() {
+		deleteErr <- remoteConn.Object(dbustypes.BusName, itemPath).Call(dbustypes.ItemInterface+".Delete", 0).Err
+	}()
+
+	var reqID string
+	for range 50 {
+		if reqs := approvalMgr.List(); len(reqs) > 0 {
+			reqID = reqs[0].ID
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if reqID == "" {
+		t.Fatal("approval request did not appear for Item.Delete")
+	}
+	if err := approvalMgr.Approve(reqID); err != nil {
+		t.Fatalf("approve failed: %v", err)
+	}
+
+	select {
+	case err := <-deleteErr:
+		if err != nil {
+			t.Fatalf("Item.Delete returned error after approval: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for Item.Delete to complete")
+	}
+	// What remains upstream is the trash key, kept in the keyring.
+	if mock.ItemCount() != 1 {
+		t.Fatalf("item was not deleted upstream")
+	}
+	if key, err := trashKey.Load(); err != nil || len(key) != 32 {
+		t.Fatalf("trash key in keyring = %x, %v", key, err)
+	}
+
+	entries, err := store.List()
+	if err != nil {
+		t.Fatalf("list trash: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("trash has %d entries, want 1", len(entries))
+	}
+	entry := entries[0]
+	if entry.Kind != trash.KindItem || entry.Client != "test-client" || len(entry.Items) != 1 {
+		t.Fatalf("unexpected trash entry: %+v", entry)
+	}
+	if got := entry.Items[0]; got.Label != "Undo Me" || string(got.Secret) != "precious" || got.Attributes["app"] != "test" {
+		t.Errorf("unexpected trashed item: %+v", got)
+	}
+
+	restoreConn, err := dbus.Connect(env.localAddr)
+	if err != nil {
+		t.Fatalf("connect upstream: %v", err)
+	}
+	defer restoreConn.Close()
+
+	paths, err := trash.Restore(restoreConn, &entry)
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if len(paths) != 1 || mock.ItemCount() != 2 {
+		t.Fatalf("restore returned %v, mock has %d items", paths, mock.ItemCount())
+	}
+	label, err := restoreConn.Object(dbustypes.BusName, paths[0]).GetProperty(dbustypes.ItemInterface + ".Label")
+	if err != nil || label.Value() != "Undo Me" {
+		t.Errorf("restored item label = %v, %v", label, err)
+	}
+}
+
 // TestProxyItemDeleteDenied tests that denying Item.Delete returns an access denied error.
 func TestProxyItemDeleteDenied(t *testing.T) {
 	env := newTestEnv(t)
@@ -1674,3 +1786,600 @@ func TestSearchItemsDenyRule(t *testing.T) {
 		}
 	})
 }
+
+// TestUnlockTemporaryDenyRule verifies that a muted client cannot make the
+// proxy forward Unlock, which would raise the upstream's unlock prompt. The
+// client is dbus-send: the proxy leaves its own executable, this test, out of
+// the sender's process chain, so the test itself cannot be muted.
+func TestUnlockTemporaryDenyRule(t *testing.T) {
+	dbusSend, err := exec.LookPath("dbus-send")
+	if err != nil {
+		t.Skip("dbus-send not installed")
+	}
+	if dbusSend, err = filepath.EvalSymlinks(dbusSend); err != nil {
+		t.Fatal(err)
+	}
+
+	env := newTestEnv(t)
+	defer env.cleanup()
+
+	localConn := env.localConn()
+	defer localConn.Close()
+
+	mock := testutil.NewMockSecretService()
+	if err := mock.Register(localConn); err != nil {
+		t.Fatalf("register mock service: %v", err)
+	}
+
+	approvalMgr := approval.NewManager(approval.ManagerConfig{Timeout: 30 * time.Second, HistoryMax: 100})
+	p := proxy.New(proxy.Config{
+		ClientName: "test-client",
+		LogLevel:   slog.LevelDebug,
+		Approval:   approvalMgr,
+	})
+	if err := connectProxyWithConns(p, env.localAddr, env.remoteSocketPath()); err != nil {
+		t.Fatalf("connect proxy: %v", err)
+	}
+	defer p.Close()
+
+	unlock := func() (string, error) {
+		cmd := exec.Command(dbusSend, "--bus="+env.remoteAddr, "--print-reply", "--dest="+dbustypes.BusName,
+			string(dbustypes.ServicePath), dbustypes.ServiceInterface+".Unlock",
+			"array:objpath:/org/freedesktop/secrets/collection/default")
+		out, err := cmd.CombinedOutput()
+		return string(out), err
+	}
+	if out, err := unlock(); err != nil {
+		t.Fatalf("Unlock before the mute: %v: %s", err, out)
+	}
+
+	sender := approval.SenderInfo{PID: 1, ProcessChain: []approval.ProcessInfo{{Name: "dbus-send", PID: 1, Exe: dbusSend}}}
+	if _, err := approvalMgr.AddDenyRule(&approval.Request{Type: approval.RequestTypeUnlock, SenderInfo: sender}, 0, true); err != nil {
+		t.Fatalf("AddDenyRule: %v", err)
+	}
+	out, err := unlock()
+	if err == nil || !strings.Contains(out, "AccessDenied") {
+		t.Fatalf("Unlock of a muted client = %v: %s; want access denied", err, out)
+	}
+	if h := approvalMgr.History()[0]; h.Resolution != approval.ResolutionDenied || h.Rule != approval.TemporaryDenyRule {
+		t.Errorf("history = %s by %q, want denied by %q", h.Resolution, h.Rule, approval.TemporaryDenyRule)
+	}
+}
+
+// TestSearchItemsHidesDeniedItems verifies that items a deny rule keeps the
+// client from reading are left out of search results and collection listings.
+func TestSearchItemsHidesDeniedItems(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.cleanup()
+
+	localConn := env.localConn()
+	defer localConn.Close()
+
+	mock := testutil.NewMockSecretService()
+	if err := mock.Register(localConn); err != nil {
+		t.Fatalf("register mock service: %v", err)
+	}
+	open := mock.AddItem("Open", map[string]string{"app": "x", "tier": "open"}, []byte("a"))
+	vault := mock.AddItem("Vault", map[string]string{"app": "x", "tier": "vault"}, []byte("b"))
+
+	approvalMgr := approval.NewManager(approval.ManagerConfig{
+		Timeout:    30 * time.Second,
+		HistoryMax: 100,
+		TrustRules: []approval.TrustRule{
+			{Name: "vault", Action: "deny", RequestTypes: []string{"get_secret"}, Secret: &approval.SecretMatcher{Attributes: map[string]string{"tier": "vault"}}},
+		},
+	})
+
+	p := proxy.New(proxy.Config{
+		ClientName: "test-client",
+		LogLevel:   slog.LevelDebug,
+		Approval:   approvalMgr,
+	})
+	if err := connectProxyWithConns(p, env.localAddr, env.remoteSocketPath()); err != nil {
+		t.Fatalf("connect proxy: %v", err)
+	}
+	defer p.Close()
+
+	remoteConn := env.remoteConn()
+	defer remoteConn.Close()
+
+	want := []dbus.ObjectPath{open}
+	search := map[string]string{"app": "x"}
+
+	t.Run("Service", func(t *testing.T) {
+		var unlocked, locked []dbus.ObjectPath
+		obj := remoteConn.Object(dbustypes.BusName, dbustypes.ServicePath)
+		if err := obj.Call(dbustypes.ServiceInterface+".SearchItems", 0, search).Store(&unlocked, &locked); err != nil {
+			t.Fatalf("SearchItems: %v", err)
+		}
+		if !slices.Equal(unlocked, want) || len(locked) != 0 {
+			t.Errorf("SearchItems = %v, %v; want %v", unlocked, locked, want)
+		}
+	})
+
+	collection := remoteConn.Object(dbustypes.BusName, "/org/freedesktop/secrets/collection/default")
+	t.Run("Collection", func(t *testing.T) {
+		var results []dbus.ObjectPath
+		if err := collection.Call(dbustypes.CollectionInterface+".SearchItems", 0, search).Store(&results); err != nil {
+			t.Fatalf("SearchItems: %v", err)
+		}
+		if !slices.Equal(results, want) {
+			t.Errorf("SearchItems = %v, want %v", results, want)
+		}
+	})
+
+	t.Run("ItemsProperty", func(t *testing.T) {
+		v, err := collection.GetProperty(dbustypes.CollectionInterface + ".Items")
+		if err != nil {
+			t.Fatalf("Get Items: %v", err)
+		}
+		if items, _ := v.Value().([]dbus.ObjectPath); !slices.Equal(items, want) {
+			t.Errorf("Items = %v, want %v", v, want)
+		}
+
+		var props map[string]dbus.Variant
+		if err := collection.Call("org.freedesktop.DBus.Properties.GetAll", 0, dbustypes.CollectionInterface).Store(&props); err != nil {
+			t.Fatalf("GetAll: %v", err)
+		}
+		if items, _ := props["Items"].Value().([]dbus.ObjectPath); !slices.Equal(items, want) {
+			t.Errorf("GetAll Items = %v, want %v", props["Items"], want)
+		}
+	})
+
+	t.Run("ItemProperties", func(t *testing.T) {
+		if _, err := remoteConn.Object(dbustypes.BusName, open).GetProperty(dbustypes.ItemInterface + ".Label"); err != nil {
+			t.Errorf("Get Label of a visible item: %v", err)
+		}
+
+		hidden := remoteConn.Object(dbustypes.BusName, vault)
+		var dbusErr dbus.Error
+		if _, err := hidden.GetProperty(dbustypes.ItemInterface + ".Label"); !errors.As(err, &dbusErr) || dbusErr.Name != dbustypes.ErrUnknownObject {
+			t.Errorf("Get Label of a hidden item = %v, want %s", err, dbustypes.ErrUnknownObject)
+		}
+		var props map[string]dbus.Variant
+		if err := hidden.Call("org.freedesktop.DBus.Properties.GetAll", 0, dbustypes.ItemInterface).Store(&props); !errors.As(err, &dbusErr) || dbusErr.Name != dbustypes.ErrUnknownObject {
+			t.Errorf("GetAll of a hidden item = %v, %v; want %s", props, err, dbustypes.ErrUnknownObject)
+		}
+	})
+}
+
+// TestProxyGetSecretsPartial verifies that GetSecrets decides items one by
+// one: rule-approved items are granted, rule-denied ones dropped, and of the
+// prompted items only those the user ticks are returned.
+func TestProxyGetSecretsPartial(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.cleanup()
+
+	localConn := env.localConn()
+	defer localConn.Close()
+
+	mock := testutil.NewMockSecretService()
+	if err := mock.Register(localConn); err != nil {
+		t.Fatalf("register mock service: %v", err)
+	}
+	allowed := mock.AddItem("Allowed", map[string]string{"tier": "open"}, []byte("a"))
+	blocked := mock.AddItem("Blocked", map[string]string{"tier": "vault"}, []byte("b"))
+	picked := mock.AddItem("Picked", map[string]string{"tier": "ask"}, []byte("c"))
+	skipped := mock.AddItem("Skipped", map[string]string{"tier": "ask"}, []byte("d"))
+
+	approvalMgr := approval.NewManager(approval.ManagerConfig{
+		Timeout:    30 * time.Second,
+		HistoryMax: 100,
+		TrustRules: []approval.TrustRule{
+			{Name: "vault", Action: "deny", Secret: &approval.SecretMatcher{Attributes: map[string]string{"tier": "vault"}}},
+			{Name: "open", Secret: &approval.SecretMatcher{Attributes: map[string]string{"tier": "open"}}},
+		},
+	})
+
+	p := proxy.New(proxy.Config{
+		ClientName: "test-client",
+		LogLevel:   slog.LevelDebug,
+		Approval:   approvalMgr,
+	})
+	if err := connectProxyWithConns(p, env.localAddr, env.remoteSocketPath()); err != nil {
+		t.Fatalf("connect proxy: %v", err)
+	}
+	defer p.Close()
+
+	remoteConn := env.remoteConn()
+	defer remoteConn.Close()
+
+	serviceObj := remoteConn.Object(dbustypes.BusName, dbustypes.ServicePath)
+	call := serviceObj.Call(dbustypes.ServiceInterface+".OpenSession", 0, "plain", dbus.MakeVariant(""))
+	if call.Err != nil {
+		t.Fatalf("OpenSession: %v", call.Err)
+	}
+	var output dbus.Variant
+	var sessionPath dbus.ObjectPath
+	if err := call.Store(&output, &sessionPath); err != nil {
+		t.Fatalf("store result: %v", err)
+	}
+
+	type result struct {
+		secrets map[dbus.ObjectPath]dbustypes.Secret
+		err     error
+	}
+	done := make(chan result, 1)
+	go func() {
+		var r result
+		call := serviceObj.Call(dbustypes.ServiceInterface+".GetSecrets", 0, []dbus.ObjectPath{allowed, blocked, picked, skipped}, sessionPath)
+		if r.err = call.Err; r.err == nil {
+			r.err = call.Store(&r.secrets)
+		}
+		done <- r
+	}()
+
+	var req *approval.Request
+	for range 50 {
+		if reqs := approvalMgr.List(); len(reqs) > 0 {
+			req = reqs[0]
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if req == nil {
+		t.Fatal("approval request did not appear for GetSecrets")
+	}
+	if !req.PerItem || len(req.Items) != 2 {
+		t.Fatalf("prompt = %d items (per_item %v), want only the 2 unruled items", len(req.Items), req.PerItem)
+	}
+	if err := approvalMgr.ApproveItems(req.ID, []string{string(picked)}); err != nil {
+		t.Fatalf("ApproveItems: %v", err)
+	}
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			t.Fatalf("GetSecrets after partial approval: %v", r.err)
+		}
+		if len(r.secrets) != 2 || string(r.secrets[allowed].Value) != "a" || string(r.secrets[picked].Value) != "c" {
+			t.Errorf("secrets = %v, want only the allowed and picked items", r.secrets)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for GetSecrets to complete")
+	}
+}
+
+// waitNameOwned polls until name's ownership on conn matches want.
+func waitNameOwned(t *testing.T, conn *dbus.Conn, name string, want bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		var owned bool
+		err := conn.BusObject().Call("org.freedesktop.DBus.NameHasOwner", 0, name).Store(&owned)
+		if err != nil {
+			t.Fatalf("NameHasOwner(%s): %v", name, err)
+		}
+		if owned == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s owned = %v, want %v", name, owned, want)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// TestProxyPairing walks a remote host through pairing: the Secret Service is
+// withheld until the user approves the code, and a later connection is served
+// only once the host proves it holds the paired key.
+func TestProxyPairing(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.cleanup()
+
+	localConn := env.localConn()
+	defer localConn.Close()
+	mock := testutil.NewMockSecretService()
+	if err := mock.Register(localConn); err != nil {
+		t.Fatalf("register mock service: %v", err)
+	}
+
+	store := pairing.NewStore(filepath.Join(t.TempDir(), "clients.yaml"))
+	approvalMgr := approval.NewManager(approval.ManagerConfig{Timeout: 10 * time.Second, HistoryMax: 100})
+	newProxy := func() *proxy.Proxy {
+		p := proxy.New(proxy.Config{
+			ClientName: "build-01",
+			LogLevel:   slog.LevelDebug,
+			Approval:   approvalMgr,
+			Clients:    store,
+		})
+		if err := connectProxyWithConns(p, env.localAddr, env.remoteSocketPath()); err != nil {
+			t.Fatalf("connect proxy: %v", err)
+		}
+		return p
+	}
+
+	remoteConn := env.remoteConn()
+	defer remoteConn.Close()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := newProxy()
+	waitNameOwned(t, remoteConn, pairing.BusName, true)
+	waitNameOwned(t, remoteConn, dbustypes.BusName, false)
+
+	codeCh := make(chan string, 1)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- pairing.Pair(context.Background(), remoteConn, key, func(code string) { codeCh <- code })
+	}()
+	code := <-codeCh
+
+	var req *approval.Request
+	for deadline := time.Now().Add(3 * time.Second); req == nil; {
+		if pending := approvalMgr.List(); len(pending) > 0 {
+			req = pending[0]
+		} else if time.Now().After(deadline) {
+			t.Fatal("pairing did not create a pending request")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if req.Type != approval.RequestTypePair || req.PairInfo.Code != code {
+		t.Fatalf("pending request = %s %+v, want pair with code %q", req.Type, req.PairInfo, code)
+	}
+	if err := approvalMgr.Approve(req.ID); err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("pair: %v", err)
+	}
+	waitNameOwned(t, remoteConn, dbustypes.BusName, true)
+	if c, err := store.Lookup("build-01"); err != nil || c == nil {
+		t.Fatalf("paired client not stored: %v, %v", c, err)
+	}
+	p.Close()
+	waitNameOwned(t, remoteConn, dbustypes.BusName, false)
+
+	// Reconnect with the responder running: the proof succeeds and the
+	// Secret Service is offered without another prompt.
+	responderConn := env.remoteConn()
+	defer responderConn.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go pairing.Respond(ctx, responderConn, key, time.Minute) //nolint:errcheck // ends with ctx
+	waitNameOwned(t, remoteConn, pairing.ResponderBusName, true)
+
+	p = newProxy()
+	defer p.Close()
+	waitNameOwned(t, remoteConn, dbustypes.BusName, true)
+	if n := approvalMgr.PendingCount(); n != 0 {
+		t.Errorf("reconnect created %d pending requests, want 0", n)
+	}
+}
+
+// TestProxyMultipleUpstreams checks that a second upstream mounted under a
+// prefix is merged into the front: collections, search, secrets and aliases.
+func TestProxyMultipleUpstreams(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.cleanup()
+	workCmd, workAddr := startDBusDaemon(t, filepath.Join(env.tmpDir, "work.sock"))
+	defer func() {
+		workCmd.Process.Kill()
+		workCmd.Wait()
+	}()
+
+	localConn := env.localConn()
+	defer localConn.Close()
+	personal := testutil.NewMockSecretService()
+	if err := personal.Register(localConn); err != nil {
+		t.Fatalf("register personal mock: %v", err)
+	}
+	personal.AddItem("personal token", map[string]string{"service": "github"}, []byte("personal-secret"))
+
+	workConn, err := dbus.Connect(workAddr)
+	if err != nil {
+		t.Fatalf("connect to work dbus: %v", err)
+	}
+	defer workConn.Close()
+	work := testutil.NewMockSecretService()
+	if err := work.Register(workConn); err != nil {
+		t.Fatalf("register work mock: %v", err)
+	}
+	work.AddItem("work token", map[string]string{"service": "github"}, []byte("work-secret"))
+
+	backends, err := proxy.DialUpstreams([]proxy.Upstream{
+		{Name: "personal", Addr: env.localAddr},
+		{Name: "work", Addr: workAddr, Prefix: "work_", Aliases: map[string]string{"work": "default"}},
+	})
+	if err != nil {
+		t.Fatalf("dial upstreams: %v", err)
+	}
+	frontConn, err := dbus.Connect("unix:path=" + env.remoteSocketPath())
+	if err != nil {
+		t.Fatalf("connect to front socket: %v", err)
+	}
+	p := proxy.New(proxy.Config{ClientName: "test-client", LogLevel: slog.LevelDebug})
+	if err := p.ConnectBackends(frontConn, backends); err != nil {
+		t.Fatalf("connect proxy: %v", err)
+	}
+	defer p.Close()
+
+	remoteConn := env.remoteConn()
+	defer remoteConn.Close()
+	service := remoteConn.Object(dbustypes.BusName, dbustypes.ServicePath)
+
+	const (
+		personalItem = dbus.ObjectPath("/org/freedesktop/secrets/collection/default/1")
+		workItem     = dbus.ObjectPath("/org/freedesktop/secrets/collection/work_default/1")
+	)
+
+	t.Run("Collections", func(t *testing.T) {
+		v, err := service.GetProperty(dbustypes.ServiceInterface + ".Collections")
+		if err != nil {
+			t.Fatalf("get Collections: %v", err)
+		}
+		want := []dbus.ObjectPath{
+			"/org/freedesktop/secrets/collection/default",
+			"/org/freedesktop/secrets/collection/work_default",
+		}
+		if got, _ := v.Value().([]dbus.ObjectPath); !slices.Equal(got, want) {
+			t.Errorf("Collections = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("SearchAndGetSecrets", func(t *testing.T) {
+		var unlocked, locked []dbus.ObjectPath
+		if err := service.Call(dbustypes.ServiceInterface+".SearchItems", 0, map[string]string{"service": "github"}).Store(&unlocked, &locked); err != nil {
+			t.Fatalf("SearchItems: %v", err)
+		}
+		if !slices.Equal(unlocked, []dbus.ObjectPath{personalItem, workItem}) {
+			t.Fatalf("SearchItems unlocked = %v", unlocked)
+		}
+
+		var output dbus.Variant
+		var session dbus.ObjectPath
+		if err := service.Call(dbustypes.ServiceInterface+".OpenSession", 0, "plain", dbus.MakeVariant("")).Store(&output, &session); err != nil {
+			t.Fatalf("OpenSession: %v", err)
+		}
+		var secrets map[dbus.ObjectPath]dbustypes.Secret
+		if err := service.Call(dbustypes.ServiceInterface+".GetSecrets", 0, unlocked, session).Store(&secrets); err != nil {
+			t.Fatalf("GetSecrets: %v", err)
+		}
+		if string(secrets[personalItem].Value) != "personal-secret" || string(secrets[workItem].Value) != "work-secret" {
+			t.Errorf("GetSecrets = %v", secrets)
+		}
+
+		var secret dbustypes.Secret
+		item := remoteConn.Object(dbustypes.BusName, workItem)
+		if err := item.Call(dbustypes.ItemInterface+".GetSecret", 0, session).Store(&secret); err != nil {
+			t.Fatalf("Item.GetSecret: %v", err)
+		}
+		if string(secret.Value) != "work-secret" {
+			t.Errorf("Item.GetSecret = %q", secret.Value)
+		}
+		label, err := item.GetProperty(dbustypes.ItemInterface + ".Label")
+		if err != nil || label.Value() != "work token" {
+			t.Errorf("Label = %v, %v", label, err)
+		}
+	})
+
+	t.Run("ReadAlias", func(t *testing.T) {
+		for alias, want := range map[string]dbus.ObjectPath{
+			"default": "/org/freedesktop/secrets/collection/default",
+			"work":    "/org/freedesktop/secrets/collection/work_default",
+		} {
+			var got dbus.ObjectPath
+			if err := service.Call(dbustypes.ServiceInterface+".ReadAlias", 0, alias).Store(&got); err != nil {
+				t.Fatalf("ReadAlias(%s): %v", alias, err)
+			}
+			if got != want {
+				t.Errorf("ReadAlias(%s) = %s, want %s", alias, got, want)
+			}
+		}
+	})
+}
+
+// waitUpstream waits until monitor reports upstream name as connected or not.
+func waitUpstream(t *testing.T, monitor *proxy.UpstreamMonitor, name string, connected bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		for _, s := range monitor.States() {
+			if s.Name == name && s.Connected == connected {
+				return
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("upstream %s connected != %v: %+v", name, connected, monitor.States())
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// TestProxyUpstreamRestart checks that a client's session survives the
+// upstream Secret Service restarting and its bus going away: calls fail fast
+// while the upstream is down and work again, without reopening the session,
+// once it is back.
+func TestProxyUpstreamRestart(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.cleanup()
+
+	register := func() *dbus.Conn {
+		conn := env.localConn()
+		mock := testutil.NewMockSecretService()
+		if err := mock.Register(conn); err != nil {
+			t.Fatalf("register mock: %v", err)
+		}
+		mock.AddItem("token", map[string]string{"service": "github"}, []byte("secret"))
+		return conn
+	}
+	mockConn := register()
+	defer func() { mockConn.Close() }()
+
+	monitor := proxy.NewUpstreamMonitor([]string{proxy.DefaultBackend})
+	backends, err := proxy.DialUpstreams([]proxy.Upstream{{Name: proxy.DefaultBackend, Addr: env.localAddr}})
+	if err != nil {
+		t.Fatalf("dial upstream: %v", err)
+	}
+	frontConn, err := dbus.Connect("unix:path=" + env.remoteSocketPath())
+	if err != nil {
+		t.Fatalf("connect to front socket: %v", err)
+	}
+	p := proxy.New(proxy.Config{ClientName: "test-client", LogLevel: slog.LevelDebug, Upstreams: monitor})
+	if err := p.ConnectBackends(frontConn, backends); err != nil {
+		t.Fatalf("connect proxy: %v", err)
+	}
+	defer p.Close()
+
+	remoteConn := env.remoteConn()
+	defer remoteConn.Close()
+	service := remoteConn.Object(dbustypes.BusName, dbustypes.ServicePath)
+	var output dbus.Variant
+	var session dbus.ObjectPath
+	if err := service.Call(dbustypes.ServiceInterface+".OpenSession", 0, "plain", dbus.MakeVariant("")).Store(&output, &session); err != nil {
+		t.Fatalf("OpenSession: %v", err)
+	}
+	item := remoteConn.Object(dbustypes.BusName, "/org/freedesktop/secrets/collection/default/1")
+	getSecret := func() (dbustypes.Secret, error) {
+		var secret dbustypes.Secret
+		err := item.Call(dbustypes.ItemInterface+".GetSecret", 0, session).Store(&secret)
+		return secret, err
+	}
+	wantServiceUnknown := func(err error) {
+		t.Helper()
+		var dbusErr dbus.Error
+		if !errors.As(err, &dbusErr) || dbusErr.Name != dbustypes.ErrServiceUnknown {
+			t.Fatalf("GetSecret while upstream down: %v, want %s", err, dbustypes.ErrServiceUnknown)
+		}
+	}
+	wantSecret := func() {
+		t.Helper()
+		secret, err := getSecret()
+		if err != nil {
+			t.Fatalf("GetSecret: %v", err)
+		}
+		if string(secret.Value) != "secret" {
+			t.Fatalf("GetSecret = %q", secret.Value)
+		}
+	}
+	wantSecret()
+
+	t.Run("ServiceRestart", func(t *testing.T) {
+		mockConn.Close()
+		waitUpstream(t, monitor, proxy.DefaultBackend, false)
+		_, err := getSecret()
+		wantServiceUnknown(err)
+
+		// The new service knows none of the old sessions.
+		mockConn = register()
+		waitUpstream(t, monitor, proxy.DefaultBackend, true)
+		wantSecret()
+	})
+
+	t.Run("BusRestart", func(t *testing.T) {
+		env.localCmd.Process.Kill()
+		env.localCmd.Wait()
+		waitUpstream(t, monitor, proxy.DefaultBackend, false)
+		_, err := getSecret()
+		wantServiceUnknown(err)
+
+		socket := filepath.Join(env.tmpDir, "local.sock")
+		os.Remove(socket)
+		env.localCmd, _ = startDBusDaemon(t, socket)
+		mockConn = register()
+		waitUpstream(t, monitor, proxy.DefaultBackend, true)
+		wantSecret()
+	})
+}
@@ -2,8 +2,11 @@ import type {
   ActionResponse,
   AutoApproveRule,
   ErrorResponse,
+  PatchResponse,
   PendingListResponse,
+  RuleSuggestResponse,
   StatusResponse,
+  TrustRule,
 } from "./types";
 
 const API_BASE = "/api/v1";
@@ -85,11 +88,16 @@ export async function getPending(): Promise<PendingListResponse> {
 }
 
 /**
- * Approve a pending request by ID.
+ * Approve a pending request by ID. With items (paths), only those are granted
+ * from a per-item request and the rest are refused.
  */
-export async function approve(id: string): Promise<ActionResponse> {
+export async function approve(
+  id: string,
+  items?: string[],
+): Promise<ActionResponse> {
   const result = await request<ActionResponse>(`/pending/${id}/approve`, {
     method: "POST",
+    body: items ? JSON.stringify({ items }) : undefined,
   });
   if (result === null) {
     throw new ApiError(401, "Unauthenticated");
@@ -116,11 +124,54 @@ export async function approveAndAutoApprove(
 }
 
 /**
- * Deny a pending request by ID.
+ * Approve a pending request and auto-approve similar requests from one process
+ * of its chain (pid 0: the requester), or its whole subtree, until it exits.
+ */
+export async function approveForProcess(
+  id: string,
+  pid: number,
+  subtree: boolean,
+): Promise<ActionResponse> {
+  const result = await request<ActionResponse>(
+    `/pending/${id}/approve-for-process`,
+    {
+      method: "POST",
+      body: JSON.stringify({ pid, subtree }),
+    },
+  );
+  if (result === null) {
+    throw new ApiError(401, "Unauthenticated");
+  }
+  return result;
+}
+
+/**
+ * Approve a commit signing request and the remaining commits of the rebase,
+ * cherry-pick or am it is part of.
+ */
+export async function approveSeries(id: string): Promise<ActionResponse> {
+  const result = await request<ActionResponse>(`/pending/${id}/approve-series`, {
+    method: "POST",
+  });
+  if (result === null) {
+    throw new ApiError(401, "Unauthenticated");
+  }
+  return result;
+}
+
+/**
+ * Deny a pending request by ID. With mute, every request from the same
+ * executable is also denied for forSeconds (server default: 15m).
  */
-export async function deny(id: string): Promise<ActionResponse> {
+export async function deny(
+  id: string,
+  mute?: { forSeconds?: number },
+): Promise<ActionResponse> {
   const result = await request<ActionResponse>(`/pending/${id}/deny`, {
     method: "POST",
+    body: mute
+      ? JSON.stringify({ mute: true, for_seconds: mute.forSeconds })
+      : undefined,
   });
   if (result === null) {
     throw new ApiError(401, "Unauthenticated");
@@ -170,4 +221,44 @@ export async function deleteAutoApproveRule(
   return result;
 }
 
+/**
+ * Derive a trust rule from a pending or historical request.
+ */
+export async function suggestRule(requestId: string): Promise<TrustRule> {
+  const result = await request<RuleSuggestResponse>(
+    `/rules/suggest?from=${encodeURIComponent(requestId)}`,
+  );
+  if (result === null) {
+    throw new ApiError(401, "Unauthenticated");
+  }
+  return result.rule;
+}
+
+/**
+ * Fetch the patch of a gpg_sign request, which listings leave out.
+ */
+export async function getPatch(requestId: string): Promise<string> {
+  const result = await request<PatchResponse>(
+    `/requests/${encodeURIComponent(requestId)}/patch`,
+  );
+  if (result === null) {
+    throw new ApiError(401, "Unauthenticated");
+  }
+  return result.patch;
+}
+
+/**
+ * Save a trust rule to config.yaml; the server loads it immediately.
+ */
+export async function addRule(rule: TrustRule): Promise<ActionResponse> {
+  const result = await request<ActionResponse>("/rules", {
+    method: "POST",
+    body: JSON.stringify(rule),
+  });
+  if (result === null) {
+    throw new ApiError(401, "Unauthenticated");
+  }
+  return result;
+}
+
 export { ApiError };
@@ -61,7 +61,13 @@ export function showRequestNotification(request: PendingRequest): void {
 
   const title = request.type === "gpg_sign"
     ? "Commit Signing Request"
-    : "Secret Request";
+    : request.type === "pair"
+      ? "Pairing Request"
+      : request.type.startsWith("ssh_")
+        ? "SSH Agent Request"
+        : request.type.startsWith("pgp_")
+          ? "GPG Agent Request"
+          : "Secret Request";
   const body = formatBody(request);
 
   // Use window.Notification to ensure we use the (potentially mocked) global
@@ -85,11 +91,22 @@ function formatBody(request: PendingRequest): string {
 
   parts.push(`Client: ${request.client}`);
 
-  if (request.sender_info?.invoker_name) {
+  if (request.sender_info?.app_id) {
+    parts.push(`App: ${request.sender_info.app_id} (${request.sender_info.app_sandbox ?? "sandbox"})`);
+  } else if (request.sender_info?.invoker_name) {
     parts.push(`Process: ${request.sender_info.invoker_name}`);
   } else if (request.sender_info?.pid) {
     parts.push(`PID: ${request.sender_info.pid}`);
   }
+  if (request.sender_info?.container) {
+    const c = request.sender_info.container;
+    parts.push(`Container: ${c.name || c.id?.slice(0, 12) || "?"} (${c.runtime}${c.image ? ", " + c.image : ""})`);
+  }
+
+  if (request.type === "pair" && request.pair_info) {
+    parts.push(`Code: ${request.pair_info.code}`);
+    return parts.join("\n");
+  }
 
   if (request.type === "gpg_sign" && request.gpg_sign_info) {
     parts.push(`Repo: ${request.gpg_sign_info.repo_name}`);
@@ -97,6 +114,11 @@ function formatBody(request: PendingRequest): string {
     return parts.join("\n");
   }
 
+  if (request.type.startsWith("ssh_") || request.type.startsWith("pgp_")) {
+    parts.push(`${request.type}: ${request.items.map((i) => i.label || i.path).join(", ")}`);
+    return parts.join("\n");
+  }
+
   if (request.type === "get_secret") {
     if (request.items.length === 1) {
       parts.push(`Secret: ${request.items[0].label || request.items[0].path}`);
@@ -2,16 +2,25 @@ export interface ItemInfo {
   path: string;
   label: string;
   attributes: Record<string, string>;
+  backend?: string;
 }
 
 export interface ProcessInfo {
   name: string;
   pid: number;
+  start_time?: number;
   exe?: string;
   args?: string[];
   cwd?: string;
 }
 
+export interface ContainerInfo {
+  runtime: string;
+  id?: string;
+  name?: string;
+  image?: string;
+}
+
 export interface SenderInfo {
   sender: string;
   pid: number;
@@ -19,6 +28,9 @@ export interface SenderInfo {
   user_name: string;
   invoker_name: string; // invoker process comm (display); spoofable
   systemd_unit?: string; // real systemd unit (authoritative)
+  app_id?: string; // Flatpak/Snap application ID (claimable by unsandboxed processes)
+  app_sandbox?: "flatpak" | "snap";
+  container?: ContainerInfo; // container the caller runs in (claimable by any process of the user)
   process_chain?: ProcessInfo[];
 }
 
@@ -29,16 +41,75 @@ export interface GPGSignInfo {
   // author/tagger/pusher and commit_msg holds the commit message / tag message
   // / pushed ref-updates; the kind-specific fields below carry the rest.
   kind?: "commit" | "tag" | "push" | "unknown";
+  // "ssh" for gpg.format=ssh: key_id is then the key file and fingerprint its
+  // SHA256 fingerprint.
+  format?: "ssh";
   commit_msg: string;
   author: string;
   committer: string;
   key_id: string;
   fingerprint?: string;
+  use_agent?: boolean; // ssh only: the key is held by the SSH agent
   changed_files: string[];
   parent_hash?: string;
   tag_name?: string; // tag only
   target?: string; // tag only: the tagged object hash
   pushee?: string; // push only: destination URL
+  diff?: CommitDiff; // commit only: the signed tree against its first parent
+  verification?: SignVerification; // daemon's check against the caller's repository
+  series?: SignSeries; // commit only: the rebase, cherry-pick or am it is part of
+}
+
+// The multi-commit operation a commit being signed belongs to, read by the
+// daemon from the caller's repository. commits lists the whole series (capped);
+// position is the 1-based place of the commit being signed. steps are the todo
+// lines still to run that create no commit (exec, break, reset…).
+export interface SignSeries {
+  operation: "rebase" | "cherry-pick" | "revert" | "am";
+  position: number;
+  total: number;
+  commits: SeriesCommit[];
+  steps?: string[];
+}
+
+export interface SeriesCommit {
+  hash?: string;
+  subject: string;
+}
+
+// The daemon's own check that a signing request matches the repository its
+// caller runs in. Unverified requests are never auto-approved.
+export interface SignVerification {
+  repo?: string;
+  verified: boolean;
+  problems?: string[];
+}
+
+// What a signed commit changes. files is capped by the thin client;
+// total_files and the line counts always cover the whole commit, and
+// truncated is set when files or patch were cut. Listings and WebSocket
+// messages leave patch out; getPatch fetches it.
+export interface CommitDiff {
+  files: DiffFile[];
+  total_files: number;
+  additions: number;
+  deletions: number;
+  patch?: string;
+  truncated?: boolean;
+}
+
+export interface DiffFile {
+  path: string;
+  additions: number;
+  deletions: number;
+  binary?: boolean;
+}
+
+// Pairing a new remote client: approve only if the code matches the one
+// printed by `secrets-dispatcher pair` on the remote host.
+export interface PairInfo {
+  code: string;
+  fingerprint: string;
 }
 
 export interface PendingRequest {
@@ -48,10 +119,13 @@ export interface PendingRequest {
   session: string;
   created_at: string;
   expires_at: string;
-  type: "get_secret" | "search" | "gpg_sign" | "delete" | "write" | "unlock" | "ssh_sign";
+  type: "get_secret" | "search" | "gpg_sign" | "delete" | "write" | "unlock" | "ssh_sign" | "ssh_add" | "ssh_remove" | "ssh_lock" | "ssh_extension" | "pgp_decrypt" | "pgp_sign" | "pgp_export" | "pair";
   search_attributes?: Record<string, string>;
   sender_info: SenderInfo;
   gpg_sign_info?: GPGSignInfo;
+  pair_info?: PairInfo;
+  per_item?: boolean; // items may be approved individually
+  approved_items?: string[]; // paths granted by a partial approval
 }
 
 export interface ClientInfo {
@@ -59,10 +133,18 @@ export interface ClientInfo {
   socket_path: string;
 }
 
+export interface UpstreamState {
+  name: string;
+  connected: boolean;
+  since: string;
+  error?: string;
+}
+
 export interface StatusResponse {
   running: boolean;
   clients: ClientInfo[];
   pending_count: number;
+  upstreams?: UpstreamState[];
   // Deprecated fields for backward compatibility
   client?: string;
   remote_socket?: string;
@@ -94,15 +176,28 @@ export interface HistoryEntry {
   request: PendingRequest;
   resolution: Resolution;
   resolved_at: string;
+  rule?: string; // trust rule that decided the request without a prompt
 }
 
 export interface AutoApproveRule {
   id: string;
+  action?: "deny"; // temporary deny rule; absent for auto-approve
   invoker_name: string;
   request_type: string;
   collection: string;
   attributes?: Record<string, string>;
-  expires_at: string;
+  expires_at?: string; // absent for process-scoped rules
+  process?: ProcessScope;
+}
+
+// ProcessScope binds an auto-approve rule to one process instance (and, with
+// subtree, its descendants) until it exits.
+export interface ProcessScope {
+  pid: number;
+  start_time: number;
+  name: string;
+  exe?: string;
+  subtree?: boolean;
 }
 
 export interface TrustedSigner {
@@ -113,10 +208,21 @@ export interface TrustedSigner {
 
 export interface ProcessMatcher {
   exe?: string;
+  exe_sha256?: string;
+  exe_packaged?: boolean;
   name?: string;
   args?: string;
   cwd?: string;
   unit?: string;
+  app_id?: string;
+  container?: ContainerMatcher;
+}
+
+export interface ContainerMatcher {
+  runtime?: string;
+  id?: string;
+  name?: string;
+  image?: string;
 }
 
 export interface SecretMatcher {
@@ -125,15 +231,41 @@ export interface SecretMatcher {
   attributes?: Record<string, string>;
 }
 
+export interface SSHMatcher {
+  fingerprint?: string;
+  comment?: string;
+  destination?: string;
+}
+
+export interface SigningMatcher {
+  repo?: string;
+  kind?: string;
+  key_id?: string;
+  author?: string;
+  files?: string[];
+  tag_name?: string;
+}
+
 export interface TrustRule {
   name?: string;
   action?: string;
+  client?: string;
   request_types?: string[];
   process?: ProcessMatcher;
   secret?: SecretMatcher;
+  ssh?: SSHMatcher;
+  signing?: SigningMatcher;
   search_attributes?: Record<string, string>;
 }
 
+export interface RuleSuggestResponse {
+  rule: TrustRule;
+}
+
+export interface PatchResponse {
+  patch: string;
+}
+
 // WebSocket message types
 export type WSMessage =
   | WSSnapshotMessage
@@ -146,6 +278,9 @@ export type WSMessage =
   | WSHistoryEntryMessage
   | WSAutoApproveRuleAddedMessage
   | WSAutoApproveRuleRemovedMessage
+  | WSTrustConfigReloadedMessage
+  | WSConfigErrorMessage
+  | WSUpstreamChangedMessage
   | WSPingMessage;
 
 export interface WSSnapshotMessage {
@@ -159,6 +294,8 @@ export interface WSSnapshotMessage {
   trust_rules: TrustRule[];
   auto_approve_duration_seconds?: number;
   notification_delay_ms?: number;
+  config_error?: string; // last rejected config reload; absent when config.yaml is applied
+  upstreams?: UpstreamState[];
 }
 
 export interface WSRequestCreatedMessage {
@@ -207,6 +344,22 @@ export interface WSAutoApproveRuleRemovedMessage {
   id: string;
 }
 
+export interface WSTrustConfigReloadedMessage {
+  type: "trust_config_reloaded";
+  trusted_signers: TrustedSigner[];
+  trust_rules: TrustRule[];
+}
+
+export interface WSConfigErrorMessage {
+  type: "config_error";
+  config_error: string;
+}
+
+export interface WSUpstreamChangedMessage {
+  type: "upstream_changed";
+  upstream: UpstreamState;
+}
+
 export interface WSPingMessage {
   type: "ping";
 }
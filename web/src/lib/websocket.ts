@@ -5,6 +5,7 @@ import type {
   PendingRequest,
   TrustedSigner,
   TrustRule,
+  UpstreamState,
   WSMessage,
 } from "./types";
 
@@ -19,6 +20,8 @@ export interface ApprovalWebSocketCallbacks {
     trustRules: TrustRule[],
     autoApproveDurationSeconds: number,
     notificationDelayMS: number,
+    configError: string,
+    upstreams: UpstreamState[],
   ) => void;
   onRequestCreated?: (request: PendingRequest) => void;
   onRequestResolved?: (id: string, result: "approved" | "denied") => void;
@@ -29,6 +32,9 @@ export interface ApprovalWebSocketCallbacks {
   onHistoryEntry?: (entry: HistoryEntry) => void;
   onAutoApproveRuleAdded?: (rule: AutoApproveRule) => void;
   onAutoApproveRuleRemoved?: (id: string) => void;
+  onTrustConfigReloaded?: (trustedSigners: TrustedSigner[], trustRules: TrustRule[]) => void;
+  onConfigError?: (error: string) => void;
+  onUpstreamChanged?: (upstream: UpstreamState) => void;
   onConnectionChange?: (isConnected: boolean) => void;
   onAuthError?: () => void;
   onVersionMismatch?: () => void;
@@ -159,6 +165,8 @@ export class ApprovalWebSocket {
           msg.trust_rules ?? [],
           msg.auto_approve_duration_seconds ?? 120,
           msg.notification_delay_ms ?? 0,
+          msg.config_error ?? "",
+          msg.upstreams ?? [],
         );
         break;
       case "request_created":
@@ -188,6 +196,15 @@ export class ApprovalWebSocket {
       case "auto_approve_rule_removed":
         this.callbacks.onAutoApproveRuleRemoved?.(msg.id);
         break;
+      case "trust_config_reloaded":
+        this.callbacks.onTrustConfigReloaded?.(msg.trusted_signers ?? [], msg.trust_rules ?? []);
+        break;
+      case "config_error":
+        this.callbacks.onConfigError?.(msg.config_error);
+        break;
+      case "upstream_changed":
+        this.callbacks.onUpstreamChanged?.(msg.upstream);
+        break;
       case "ping":
         // Server ping, no action needed
         break;